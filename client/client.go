@@ -1,17 +1,29 @@
 package client
 
 import (
+	"GoDissys/common"
 	"GoDissys/mailbox"
 	"GoDissys/proto/proto"
 	"bufio"
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	selfTestTimeout      = 10 * time.Second       // How long selftest waits for the probe to arrive
+	selfTestPollInterval = 200 * time.Millisecond // Delay between mailbox polls during selftest
+	tailWaitSeconds      = 30                     // Long-poll duration of each WaitForMail call made by tail
+	streamMailTimeout    = time.Minute            // Deadline for streaming a whole inbox with StreamMail
 )
 
 // Config holds the necessary addresses for the client to connect to services
@@ -22,29 +34,151 @@ type Config struct {
 		Domain string
 		Addr   string
 	}
+	CredentialsFile string // JSON file of access tokens keyed by email address (optional)
+	// AdminToken authorizes signup to set the first password of a new user at their Mailbox (empty signs up
+	// without a password).
+	AdminToken string
+	// InProcessDelivery delivers mail for recipients whose Mailbox runs in this process directly,
+	// skipping the network and the TransferServer.
+	InProcessDelivery bool
+	// MaxCommandLength rejects longer command lines (0 uses the default of 4096 bytes).
+	MaxCommandLength int
+	// MaxCommandsPerSecond rejects commands arriving faster in batch mode, i.e. when stdin is not a terminal
+	// (0 means unlimited).
+	MaxCommandsPerSecond int
+	// JSONOutput prints each command result as one line of JSON instead of text, for scripting.
+	JSONOutput bool
+	// SessionFile keeps the logged-in user across restarts: it is written on exit and read on startup
+	// (empty disables session persistence).
+	SessionFile string
+	// TLS secures the connections to the services (nil connects in plaintext).
+	TLS *common.TLSConfig
 }
 
-// currentClientState holds the state of the logged-in client
+// dial connects to the service at addr with TLS as configured by tlsCfg, or in plaintext for a nil tlsCfg.
+func dial(ctx context.Context, addr string, tlsCfg *common.TLSConfig) (*grpc.ClientConn, error) {
+	creds, err := common.TransportCredentials(tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not set up TLS: %w", err)
+	}
+	return grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds), common.TracingDialOption())
+}
+
+// currentClientState holds the state of the logged-in client.
+// It is safe for concurrent use, so background tasks (e.g. mail notifications)
+// can read or update it while the CLI loop is running.
 type currentClientState struct {
-	EmailAddress   string
-	MailboxAddress string
+	mu             sync.RWMutex
+	emailAddress   string
+	mailboxAddress string
+	token          string               // Access token attached to authenticated RPCs, empty if none is stored
+	lastMessages   []*proto.MailMessage // Messages shown by the last get, nil if nothing has been retrieved yet
+	lastLabels     []string             // Label filter of the last get
 }
 
-// SendMail connects to the TransferServer and sends a mail message.
-func SendMail(transferServerAddr, senderEmail, recipientEmail, subject, body string) {
-	transferDialCtx, transferDialCancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer transferDialCancel()
-	conn, err := grpc.DialContext(transferDialCtx, transferServerAddr, grpc.WithInsecure()) // Insecure for practice
+// login records emailAddress as the logged-in user, served by the Mailbox at mailboxAddress
+// and authenticated with token.
+func (c *currentClientState) login(emailAddress, mailboxAddress, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.emailAddress = emailAddress
+	c.mailboxAddress = mailboxAddress
+	c.token = token
+	c.lastMessages, c.lastLabels = nil, nil
+}
+
+// logout forgets the logged-in user.
+func (c *currentClientState) logout() {
+	c.login("", "", "")
+}
+
+// setToken replaces the access token of the logged-in user.
+func (c *currentClientState) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// setLastMessages remembers messages as the ones last shown by get with the label filter labels, so other
+// commands can refer to them by number.
+func (c *currentClientState) setLastMessages(messages []*proto.MailMessage, labels []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastMessages, c.lastLabels = messages, labels
+}
+
+// fetchedMessages returns the messages last shown by get, or nil if nothing has been retrieved yet.
+func (c *currentClientState) fetchedMessages() []*proto.MailMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastMessages
+}
+
+// fetchedLabels returns the label filter of the last get.
+func (c *currentClientState) fetchedLabels() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastLabels
+}
+
+// session returns a consistent snapshot of the logged-in user, their Mailbox address and access token.
+// The email address is empty when nobody is logged in.
+func (c *currentClientState) session() (emailAddress, mailboxAddress, token string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.emailAddress, c.mailboxAddress, c.token
+}
+
+// deregisterMailbox removes the registration of emailAddress from the Nameserver at nameserverAddr, connecting
+// as configured by tlsCfg. removed is false, with the Nameserver's explanation in message, if there was nothing
+// to remove.
+func deregisterMailbox(nameserverAddr, emailAddress string, tlsCfg *common.TLSConfig) (removed bool, message string, err error) {
+	ctxDial, cancelDial := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelDial()
+	conn, err := dial(ctxDial, nameserverAddr, tlsCfg)
 	if err != nil {
-		log.Fatalf("Client: Could not connect to TransferServer at %s: %v", transferServerAddr, err)
+		return false, "", fmt.Errorf("could not connect to Nameserver at %s: %w", nameserverAddr, err)
 	}
 	defer conn.Close()
 
-	client := proto.NewTransferServerClient(conn)
+	ctxReq, cancelReq := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelReq()
+	resp, err := proto.NewNameserverClient(conn).DeregisterMailbox(ctxReq, &proto.DeregisterMailboxRequest{EmailAddress: emailAddress})
+	if err != nil {
+		return false, "", err
+	}
+	return resp.GetRemoved(), resp.GetMessage(), nil
+}
 
-	ctxReq, cancelReq := context.WithTimeout(context.Background(), time.Second*10)
+// listMailboxes returns the registrations of domain from the Nameserver at nameserverAddr, connecting as
+// configured by tlsCfg.
+func listMailboxes(nameserverAddr, domain string, tlsCfg *common.TLSConfig) ([]*proto.MailboxEntry, error) {
+	ctxDial, cancelDial := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelDial()
+	conn, err := dial(ctxDial, nameserverAddr, tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to Nameserver at %s: %w", nameserverAddr, err)
+	}
+	defer conn.Close()
+
+	ctxReq, cancelReq := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancelReq()
+	resp, err := proto.NewNameserverClient(conn).ListMailboxes(ctxReq, &proto.ListMailboxesRequest{Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetEntries(), nil
+}
+
+// SendMail connects to the TransferServer in plaintext and sends a mail message. It returns an error if the
+// TransferServer cannot be reached or does not accept the message. Use SendMailWithConfig to connect with TLS.
+func SendMail(transferServerAddr, senderEmail, recipientEmail, subject, body string) error {
+	return SendMailWithConfig(Config{TransferServerAddr: transferServerAddr}, senderEmail, recipientEmail, subject, body)
+}
 
+// SendMailWithConfig sends a mail message using the addresses and options in cfg. It returns an error if the
+// message could not be handed over or was not accepted.
+func SendMailWithConfig(cfg Config, senderEmail, recipientEmail, subject, body string) error {
 	msg := &proto.MailMessage{
 		SenderEmail:    senderEmail,
 		RecipientEmail: recipientEmail,
@@ -53,165 +187,532 @@ func SendMail(transferServerAddr, senderEmail, recipientEmail, subject, body str
 		Timestamp:      time.Now().Unix(),
 	}
 
-	req := &proto.SendMailRequest{Message: msg}
+	resp, err := deliverMessage(cfg, msg)
+	if err != nil {
+		return fmt.Errorf("could not send mail to '%s': %w", recipientEmail, err)
+	}
+	if !resp.GetSuccess() {
+		return fmt.Errorf("mail to '%s' was not accepted: %s", recipientEmail, resp.GetMessage())
+	}
+	slog.Info("Mail sent", "service", "Client", "recipient", recipientEmail, "result", resp.GetMessage())
+	return nil
+}
+
+// deliverMessage hands msg to the in-process Mailbox of its recipient if in-process delivery is enabled, msg
+// has a single recipient and that recipient's Mailbox runs in this process, and to the TransferServer otherwise.
+// The send starts a trace, which the services continue if tracing is set up.
+func deliverMessage(cfg Config, msg *proto.MailMessage) (*proto.SendMailResponse, error) {
+	traceCtx, span := common.StartSpan(context.Background(), "Client.send", common.AttrRecipient.String(msg.RecipientEmail))
+	defer span.End()
+	singleRecipient := len(msg.To)+len(msg.Cc)+len(msg.Bcc) == 0
+	if local, ok := localMailbox(cfg, msg.RecipientEmail); ok && singleRecipient {
+		ctx, cancel := context.WithTimeout(traceCtx, time.Second*5)
+		defer cancel()
+		resp, err := local.ReceiveMail(ctx, &proto.ReceiveMailRequest{Message: msg})
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Delivered mail in-process", "service", "Client", "recipient", msg.RecipientEmail)
+		return &proto.SendMailResponse{Success: resp.GetSuccess(), Message: resp.GetMessage(), MessageId: msg.MessageId}, nil
+	}
+	return sendMessage(traceCtx, cfg.TransferServerAddr, msg, cfg.TLS)
+}
 
-	resp, err := client.SendMail(ctxReq, req)
+// localMailbox returns the in-process Mailbox serving recipient, if in-process delivery is enabled
+// and the recipient's configured Mailbox runs in this process.
+func localMailbox(cfg Config, recipient string) (proto.MailboxServer, bool) {
+	if !cfg.InProcessDelivery {
+		return nil, false
+	}
+	mailboxConfig, ok := cfg.Mailboxes[getDomainFromEmail(recipient)]
+	if !ok {
+		return nil, false
+	}
+	return mailbox.LocalServer(mailboxConfig.Addr)
+}
+
+// sendMessage hands a prepared message to the TransferServer, connecting as configured by tlsCfg, and returns
+// its response. The request continues the trace in ctx.
+func sendMessage(ctx context.Context, transferServerAddr string, msg *proto.MailMessage, tlsCfg *common.TLSConfig) (*proto.SendMailResponse, error) {
+	transferDialCtx, transferDialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer transferDialCancel()
+	conn, err := dial(transferDialCtx, transferServerAddr, tlsCfg)
 	if err != nil {
-		log.Printf("Client: Error sending mail: %v", err)
-		return
+		return nil, fmt.Errorf("could not connect to TransferServer at %s: %w", transferServerAddr, err)
 	}
+	defer conn.Close()
 
-	if resp.GetSuccess() {
-		log.Printf("Client: Mail sent successfully to '%s': %s", recipientEmail, resp.GetMessage())
-	} else {
-		log.Printf("Client: Failed to send mail to '%s': %s", recipientEmail, resp.GetMessage())
+	client := proto.NewTransferServerClient(conn)
+
+	ctxReq, cancelReq := context.WithTimeout(ctx, time.Second*10)
+	defer cancelReq()
+
+	return client.SendMail(ctxReq, &proto.SendMailRequest{Message: msg})
+}
+
+// GetMail connects to a specific Mailbox (e.g., the user's own) as configured by tlsCfg (nil connects in
+// plaintext) and retrieves messages. A non-empty token is sent along to authenticate the request. If labels are given,
+// only messages carrying at least one of them are retrieved. Messages are printed as they arrive and
+// only deleted from the Mailbox once all of them have been printed, so a crash in between loses no mail.
+// It returns an error if the mail cannot be retrieved or, once printed, deleted; either way no mail is lost.
+func GetMail(emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, labels ...string) error {
+	var messages []*proto.MailMessage
+	err := streamMail(emailAddress, mailboxAddr, token, labels, tlsCfg, func(msg *proto.MailMessage) {
+		messages = append(messages, msg)
+		printMessage(os.Stdout, len(messages), msg)
+	})
+	if err != nil {
+		// Nothing is deleted, the Mailbox will hand out the messages again
+		return fmt.Errorf("could not get mail for '%s' after %d messages: %w", emailAddress, len(messages), err)
+	}
+
+	if len(messages) == 0 {
+		slog.Info("No new messages", "service", "Client", "user", emailAddress)
+		return nil
+	}
+
+	slog.Info("Retrieved messages", "service", "Client", "user", emailAddress, "count", len(messages))
+	if err := deleteMail(emailAddress, mailboxAddr, token, messages, tlsCfg); err != nil {
+		return fmt.Errorf("could not delete retrieved mail for '%s', it will be retrieved again: %w", emailAddress, err)
 	}
+	return nil
 }
 
-// GetMail connects to a specific Mailbox (e.g., the user's own) and retrieves messages.
-func GetMail(emailAddress, mailboxAddr string) {
+// fetchMail retrieves the messages for emailAddress from the Mailbox at mailboxAddr, connecting as configured by
+// tlsCfg, authenticated with token and optionally filtered by labels. The messages stay in the Mailbox until
+// they are deleted with deleteMail.
+func fetchMail(emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, labels ...string) ([]*proto.MailMessage, error) {
+	var messages []*proto.MailMessage
+	err := streamMail(emailAddress, mailboxAddr, token, labels, tlsCfg, func(msg *proto.MailMessage) {
+		messages = append(messages, msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// streamMail calls handle for each message for emailAddress at the Mailbox at mailboxAddr as it arrives over
+// the StreamMail RPC. Mailboxes without StreamMail are read with a single GetMail instead. Like fetchMail,
+// it leaves the messages in the Mailbox.
+func streamMail(emailAddress, mailboxAddr, token string, labels []string, tlsCfg *common.TLSConfig, handle func(*proto.MailMessage)) error {
 	mailboxDialCtx, mailboxDialCancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer mailboxDialCancel()
-	conn, err := grpc.DialContext(mailboxDialCtx, mailboxAddr, grpc.WithInsecure()) // Insecure for practice
+	conn, err := dial(mailboxDialCtx, mailboxAddr, tlsCfg)
 	if err != nil {
-		log.Fatalf("Client: Could not connect to Mailbox at %s for '%s': %v", mailboxAddr, emailAddress, err)
+		return fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
 	}
 	defer conn.Close()
 
 	client := proto.NewMailboxClient(conn)
-
-	ctxReq, cancelReq := context.WithTimeout(context.Background(), time.Second*5)
+	ctxReq, cancelReq := context.WithTimeout(withAuthToken(context.Background(), token), streamMailTimeout)
 	defer cancelReq()
 
-	req := &proto.GetMailRequest{EmailAddress: emailAddress}
+	keep := false
+	stream, err := client.StreamMail(ctxReq, &proto.StreamMailRequest{EmailAddress: emailAddress, Labels: labels, AutoAck: &keep})
+	if err != nil {
+		return err
+	}
+	for received := 0; ; received++ {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if status.Code(err) == codes.Unimplemented && received == 0 {
+			break // Older Mailbox, fall back to GetMail
+		}
+		if err != nil {
+			return err
+		}
+		handle(msg)
+	}
+
+	resp, err := client.GetMail(ctxReq, &proto.GetMailRequest{EmailAddress: emailAddress, Labels: labels, AutoAck: &keep})
+	if err != nil {
+		return err
+	}
+	for _, msg := range resp.GetMessages() {
+		handle(msg)
+	}
+	return nil
+}
 
-	resp, err := client.GetMail(ctxReq, req)
+// searchMail returns the messages for emailAddress at the Mailbox at mailboxAddr whose sender, subject or body
+// contain query, ignoring case. The messages stay in the Mailbox.
+func searchMail(emailAddress, mailboxAddr, token, query string, tlsCfg *common.TLSConfig) ([]*proto.MailMessage, error) {
+	mailboxDialCtx, mailboxDialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer mailboxDialCancel()
+	conn, err := dial(mailboxDialCtx, mailboxAddr, tlsCfg)
 	if err != nil {
-		log.Printf("Client: Error getting mail for '%s': %v", emailAddress, err)
-		return
+		return nil, fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
+	}
+	defer conn.Close()
+
+	ctxReq, cancelReq := context.WithTimeout(withAuthToken(context.Background(), token), time.Second*5)
+	defer cancelReq()
+	resp, err := proto.NewMailboxClient(conn).SearchMail(ctxReq, &proto.SearchMailRequest{EmailAddress: emailAddress, Query: query, IncludeBody: true})
+	if err != nil {
+		return nil, err
 	}
+	return resp.GetMessages(), nil
+}
+
+// setPassword sets the password of emailAddress at the Mailbox at mailboxAddr, authenticated with token
+// (the current password, if the user has one).
+func setPassword(emailAddress, mailboxAddr, token, password string, tlsCfg *common.TLSConfig) error {
+	mailboxDialCtx, mailboxDialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer mailboxDialCancel()
+	conn, err := dial(mailboxDialCtx, mailboxAddr, tlsCfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
+	}
+	defer conn.Close()
 
-	messages := resp.GetMessages()
+	ctxReq, cancelReq := context.WithTimeout(withAuthToken(context.Background(), token), time.Second*5)
+	defer cancelReq()
+	_, err = proto.NewMailboxClient(conn).SetPassword(ctxReq, &proto.SetPasswordRequest{EmailAddress: emailAddress, Password: password})
+	return err
+}
+
+// deleteMail acknowledges messages retrieved with fetchMail, removing them from the Mailbox at mailboxAddr.
+func deleteMail(emailAddress, mailboxAddr, token string, messages []*proto.MailMessage, tlsCfg *common.TLSConfig) error {
 	if len(messages) == 0 {
-		log.Printf("Client for '%s': No new messages.", emailAddress)
-		return
+		return nil
+	}
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.GetMessageId())
 	}
 
-	log.Printf("Client for '%s': Retrieved %d messages:", emailAddress, len(messages))
+	mailboxDialCtx, mailboxDialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer mailboxDialCancel()
+	conn, err := dial(mailboxDialCtx, mailboxAddr, tlsCfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
+	}
+	defer conn.Close()
+
+	ctxReq, cancelReq := context.WithTimeout(withAuthToken(context.Background(), token), time.Second*5)
+	defer cancelReq()
+	_, err = proto.NewMailboxClient(conn).DeleteMail(ctxReq, &proto.DeleteMailRequest{EmailAddress: emailAddress, MessageIds: ids})
+	return err
+}
+
+// formatTimestamp formats a Unix timestamp for display; unset or invalid (non-positive) timestamps
+// are shown as "unknown" instead of a 1970 date.
+func formatTimestamp(unix int64) string {
+	return formatTime(common.SentTime("", unix))
+}
+
+// formatSentTime formats the time msg was sent for display, preferring its sent_at to its timestamp.
+func formatSentTime(msg *proto.MailMessage) string {
+	return formatTime(common.SentTime(msg.GetSentAt(), msg.GetTimestamp()))
+}
+
+// formatTime formats t for display, or "unknown" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format(time.RFC822)
+}
+
+// printMessages writes a human-readable listing of messages to w.
+func printMessages(w io.Writer, messages []*proto.MailMessage) {
 	for i, msg := range messages {
-		fmt.Printf("--- Message %d ---\n", i+1)
-		fmt.Printf("From: %s\n", msg.SenderEmail)
-		fmt.Printf("Subject: %s\n", msg.Subject)
-		fmt.Printf("Timestamp: %s\n", time.Unix(msg.Timestamp, 0).Format(time.RFC822))
-		fmt.Printf("Body:\n%s\n", msg.Body)
-		fmt.Println("-----------------")
+		printMessage(w, i+1, msg)
 	}
 }
 
-func StartCLI(cfg Config) {
-	scanner := bufio.NewScanner(os.Stdin)
-	var currentState currentClientState
-
-	fmt.Println("\n--- Distributed Mail Client CLI ---")
-	fmt.Println("Commands:")
-	fmt.Println("  signup <your_email> <your_domain_mailbox_alias> - Register your email (e.g., alice@earth.com earth)")
-	fmt.Println("  login <your_email> - Log in to manage your mail (e.g., alice@earth.com)")
-	fmt.Println("  send <recipient_email> <subject> <body_text> - Send an email")
-	fmt.Println("  get - Retrieve your mail")
-	fmt.Println("  whoami - Show current logged-in user")
-	fmt.Println("  exit - Quit the client")
-	fmt.Print("> ")
+// printMessage writes msg to w as message number n.
+func printMessage(w io.Writer, n int, msg *proto.MailMessage) {
+	fmt.Fprintf(w, "--- Message %d ---\n", n)
+	fmt.Fprintf(w, "From: %s\n", msg.SenderEmail)
+	fmt.Fprintf(w, "Subject: %s\n", msg.Subject)
+	if len(msg.Labels) > 0 {
+		fmt.Fprintf(w, "Labels: %s\n", strings.Join(msg.Labels, ", "))
+	}
+	fmt.Fprintf(w, "Timestamp: %s\n", formatSentTime(msg))
+	if msg.ReceivedTimestamp > 0 {
+		fmt.Fprintf(w, "Received: %s\n", formatTimestamp(msg.ReceivedTimestamp))
+	}
+	fmt.Fprintf(w, "Body:\n%s\n", msg.Body)
+	fmt.Fprintln(w, "-----------------")
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
-			fmt.Print("> ")
-			continue
+// WatchMail prints mail for emailAddress to w as the Mailbox at mailboxAddr, connected to as configured by
+// tlsCfg, pushes it over the WatchMail stream, until ctx is cancelled. Unlike TailMail it does not poll, and
+// shows only mail arriving from now on.
+func WatchMail(ctx context.Context, emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, w io.Writer) error {
+	n := 0
+	return watchMail(ctx, emailAddress, mailboxAddr, token, tlsCfg, func(msg *proto.MailMessage) {
+		n++
+		printMessage(w, n, msg)
+	})
+}
+
+// watchMail calls handle for each message the Mailbox at mailboxAddr pushes for emailAddress over the
+// WatchMail stream, until ctx is cancelled.
+func watchMail(ctx context.Context, emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, handle func(msg *proto.MailMessage)) error {
+	dialCtx, dialCancel := context.WithTimeout(ctx, time.Second*5)
+	defer dialCancel()
+	conn, err := dial(dialCtx, mailboxAddr, tlsCfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
+	}
+	defer conn.Close()
+
+	stream, err := proto.NewMailboxClient(conn).WatchMail(withAuthToken(ctx, token), &proto.WatchMailRequest{EmailAddress: emailAddress})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if ctx.Err() != nil {
+			return nil // Stopped by the user
+		}
+		if err != nil {
+			return err
 		}
+		handle(msg)
+	}
+}
 
-		command := strings.ToLower(parts[0])
+// TailMail prints mail for emailAddress to w as it arrives at the Mailbox at mailboxAddr, connected to as
+// configured by tlsCfg, starting with mail already waiting, until ctx is cancelled. It long-polls with WaitForMail, which leaves the inbox
+// untouched, so tailed mail can still be retrieved with GetMail.
+func TailMail(ctx context.Context, emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, w io.Writer) error {
+	n := 0
+	return tailMail(ctx, emailAddress, mailboxAddr, token, tlsCfg, func(msg *proto.MailMessage) {
+		n++
+		printMessage(w, n, msg)
+	})
+}
 
-		switch command {
-		case "signup":
-			if len(parts) != 3 {
-				fmt.Println("Usage: signup <your_email> <your_domain_mailbox_alias>")
-				fmt.Println("Example: signup alice@earth.com earth")
-				break
-			}
-			email := parts[1]
-			domainAlias := parts[2]
-			mailboxConfig, ok := cfg.Mailboxes[getDomainFromEmail(email)]
-			if !ok || mailboxConfig.Domain != domainAlias {
-				fmt.Printf("Error: Mailbox configuration for domain '%s' (alias '%s') not found in config.json.\n", getDomainFromEmail(email), domainAlias)
-				break
-			}
-			log.Printf("Attempting to sign up %s with mailbox at %s (Nameserver: %s)", email, mailboxConfig.Addr, cfg.NameserverAddr)
-			// Call the mailbox's registration function
-			mailbox.RegisterMailboxWithNameserver(cfg.NameserverAddr, email, mailboxConfig.Addr)
-			fmt.Printf("Signup attempt for %s completed. You can now try to login.\n", email)
-
-		case "login":
-			if len(parts) != 2 {
-				fmt.Println("Usage: login <your_email>")
-				fmt.Println("Example: login alice@earth.com")
-				break
-			}
-			email := parts[1]
-			mailboxConfig, ok := cfg.Mailboxes[getDomainFromEmail(email)]
-			if !ok {
-				fmt.Printf("Error: Mailbox configuration for domain '%s' not found in config.json. Please signup first.\n", getDomainFromEmail(email))
-				break
-			}
-			currentState.EmailAddress = email
-			currentState.MailboxAddress = mailboxConfig.Addr
-			fmt.Printf("Logged in as: %s\n", currentState.EmailAddress)
-
-		case "send":
-			if currentState.EmailAddress == "" {
-				fmt.Println("Error: Please log in first using the 'login' command.")
-				break
-			}
-			if len(parts) < 4 {
-				fmt.Println("Usage: send <recipient_email> <subject> <body_text>")
-				fmt.Println("Example: send bob@saturn.com 'Meeting' 'Let's meet tomorrow.'")
-				break
-			}
-			recipientEmail := parts[1]
-			subject := parts[2]
-			body := strings.Join(parts[3:], " ")
-			SendMail(cfg.TransferServerAddr, currentState.EmailAddress, recipientEmail, subject, body)
-
-		case "get":
-			if currentState.EmailAddress == "" {
-				fmt.Println("Error: Please log in first using the 'login' command.")
-				break
+// tailMail calls handle for each message for emailAddress as it arrives at the Mailbox at mailboxAddr,
+// starting with mail already waiting, until ctx is cancelled.
+func tailMail(ctx context.Context, emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, handle func(msg *proto.MailMessage)) error {
+	dialCtx, dialCancel := context.WithTimeout(ctx, time.Second*5)
+	defer dialCancel()
+	conn, err := dial(dialCtx, mailboxAddr, tlsCfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to Mailbox at %s: %w", mailboxAddr, err)
+	}
+	defer conn.Close()
+	client := proto.NewMailboxClient(conn)
+
+	lastID := ""
+	for {
+		resp, err := client.WaitForMail(withAuthToken(ctx, token), &proto.WaitForMailRequest{
+			EmailAddress:   emailAddress,
+			AfterMessageId: lastID,
+			TimeoutSeconds: tailWaitSeconds,
+		})
+		if ctx.Err() != nil {
+			return nil // Stopped by the user
+		}
+		if err != nil {
+			return err
+		}
+		for _, msg := range resp.GetMessages() {
+			handle(msg)
+			lastID = msg.GetMessageId()
+		}
+	}
+}
+
+// SelfTestResult describes the outcome of an end-to-end delivery self-test.
+type SelfTestResult struct {
+	RoundTrip time.Duration        // Time from sending the probe until it was retrieved
+	Other     []*proto.MailMessage // Unrelated messages retrieved while polling for the probe
+}
+
+// SelfTest sends a probe message from emailAddress to itself through the TransferServer
+// and polls the user's Mailbox until the probe arrives or the timeout expires.
+// Any other mail retrieved while polling is returned so the caller can show it to the user.
+// A non-empty token authenticates the mailbox polls. Both services are connected to as configured by tlsCfg.
+func SelfTest(transferServerAddr, emailAddress, mailboxAddr, token string, tlsCfg *common.TLSConfig, timeout time.Duration) (*SelfTestResult, error) {
+	start := time.Now()
+	probeSubject := fmt.Sprintf("selftest-%d", start.UnixNano())
+	probe := &proto.MailMessage{
+		SenderEmail:    emailAddress,
+		RecipientEmail: emailAddress,
+		Subject:        probeSubject,
+		Body:           "End-to-end delivery self-test.",
+		Timestamp:      start.Unix(),
+	}
+
+	resp, err := sendMessage(context.Background(), transferServerAddr, probe, tlsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("sending probe failed: %w", err)
+	}
+	if !resp.GetSuccess() {
+		return nil, fmt.Errorf("sending probe failed: %s", resp.GetMessage())
+	}
+
+	result := &SelfTestResult{}
+	deadline := start.Add(timeout)
+	for {
+		messages, err := fetchMail(emailAddress, mailboxAddr, token, tlsCfg)
+		if err != nil {
+			return result, fmt.Errorf("retrieving mail failed: %w", err)
+		}
+		if err := deleteMail(emailAddress, mailboxAddr, token, messages, tlsCfg); err != nil {
+			return result, fmt.Errorf("deleting retrieved mail failed: %w", err) // Would be retrieved again on the next poll
+		}
+		found := false
+		for _, msg := range messages {
+			if msg.GetSubject() == probeSubject && msg.GetSenderEmail() == emailAddress {
+				found = true
+				continue
 			}
-			GetMail(currentState.EmailAddress, currentState.MailboxAddress)
+			result.Other = append(result.Other, msg)
+		}
+		if found {
+			result.RoundTrip = time.Since(start)
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return result, fmt.Errorf("probe not received within %s", timeout)
+		}
+		time.Sleep(selfTestPollInterval)
+	}
+}
+
+// StartCLI runs the interactive client on stdin and stdout until the user exits.
+func StartCLI(cfg Config) {
+	runCLI(cfg, os.Stdin, os.Stdout, !stdinIsTerminal())
+}
+
+// Exit codes of RunCommand.
+const (
+	ExitOK             = 0 // The command succeeded
+	ExitFailed         = 1 // The command ran but failed
+	ExitUnknownCommand = 2 // No such command
+)
+
+// RunCommand runs the single command args (e.g. "send", "bob@saturn.com", "Hi", "there") once, without the
+// interactive loop, renders its result to out and returns the exit code for the process. The session saved
+// in cfg.SessionFile is restored beforehand and saved afterwards, so a one-shot login carries over to the
+// next command. tail stops when a line is read from in; delete cannot ask and needs --yes.
+func RunCommand(cfg Config, args []string, in io.Reader, out io.Writer) int {
+	render := renderText
+	if cfg.JSONOutput {
+		render = renderJSON
+	}
+	if len(args) == 0 {
+		render(out, failed("No command given. Type 'help' for available commands."))
+		return ExitUnknownCommand
+	}
+	if _, ok := findCommand(args[0]); !ok {
+		render(out, failed("Unknown command '%s'. Run the 'help' command for available commands.", args[0]))
+		return ExitUnknownCommand
+	}
+
+	if _, err := common.TransportCredentials(cfg.TLS); err != nil { // Reported once here rather than on every connection
+		render(out, failed("Error setting up TLS: %v", err))
+		return ExitFailed
+	}
 
-		case "whoami":
-			if currentState.EmailAddress == "" {
-				fmt.Println("Not logged in.")
-			} else {
-				fmt.Printf("Currently logged in as: %s (Mailbox: %s)\n", currentState.EmailAddress, currentState.MailboxAddress)
+	reader := bufio.NewReader(in)
+	c := &cli{cfg: cfg, state: &currentClientState{}, out: out, waitForEnter: func() { reader.ReadString('\n') }, untilInterrupt: common.InterruptContext}
+	promptOut := out
+	if cfg.JSONOutput {
+		promptOut = io.Discard // Keeps the output a single JSON result
+	}
+	c.readPassword = passwordPrompt(in, promptOut, func() (string, bool) {
+		line, err := reader.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err == nil || line != ""
+	})
+	if message := c.restoreSession(); message != "" {
+		slog.Info(message, "service", "Client") // Keeps out to the command's result
+	}
+	result := c.dispatch(args)
+	render(out, result)
+	if err := c.persistSession(); err != nil {
+		slog.Warn("Could not save the session", "service", "Client", "error", err)
+	}
+	if !result.OK {
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+// runCLI reads commands from in, dispatches them and renders each result to out, as text or, with
+// cfg.JSONOutput, as one line of JSON. batch enables the command rate limit.
+func runCLI(cfg Config, in io.Reader, out io.Writer, batch bool) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxScanBufferSize) // Over-long lines are rejected below instead of ending the CLI
+	guard := newCommandGuard(cfg, batch)
+	c := &cli{cfg: cfg, state: &currentClientState{}, out: out, waitForEnter: func() { scanner.Scan() }, untilInterrupt: common.InterruptContext}
+
+	readLine := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+	render, prompt := renderText, func() { fmt.Fprint(out, "> ") }
+	if cfg.JSONOutput {
+		render, prompt = renderJSON, func() {}
+		c.readPassword = passwordPrompt(in, io.Discard, readLine)
+	} else {
+		c.readPassword = passwordPrompt(in, out, readLine)
+		c.confirm = func(question string) bool {
+			fmt.Fprintf(out, "%s [y/N] ", question)
+			if !scanner.Scan() {
+				return false
 			}
+			answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			return answer == "y" || answer == "yes"
+		}
+		fmt.Fprintln(out, "\n--- Distributed Mail Client CLI ---")
+		fmt.Fprintln(out, helpText())
+	}
+	if _, err := common.TransportCredentials(cfg.TLS); err != nil { // Reported once here rather than on every connection
+		render(out, failed("Error setting up TLS: %v", err))
+		return
+	}
+	if message := c.restoreSession(); message != "" {
+		render(out, succeeded(nil, "%s", message))
+	}
+	prompt()
 
-		case "exit":
-			fmt.Println("Exiting client.")
+	for scanner.Scan() {
+		line := scanner.Text()
+		if err := guard.check(line, time.Now()); err != nil {
+			render(out, failed("%v", err))
+			prompt()
+			continue
+		}
+		parts, err := splitCommandLine(line)
+		if err != nil {
+			render(out, failed("Error: %v", err))
+			prompt()
+			continue
+		}
+		if len(parts) == 0 {
+			prompt()
+			continue
+		}
+		result := c.dispatch(parts)
+		render(out, result)
+		if strings.ToLower(parts[0]) == "exit" {
 			return
-
-		default:
-			fmt.Println("Unknown command. Type 'help' for available commands.")
 		}
-		fmt.Print("> ")
+		prompt()
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading input: %v", err)
+		slog.Error("Could not read input", "service", "Client", "error", err)
+	}
+	// The input ended without 'exit' (e.g. Ctrl-D or a closed stdin); save the session as exit would
+	if err := c.persistSession(); err != nil {
+		slog.Warn("Could not save the session", "service", "Client", "error", err)
 	}
 }
 
-// Helper function to extract domain from an email address
+// getDomainFromEmail returns the domain part of email, or an empty string if it is not a single '@'-separated address.
 func getDomainFromEmail(email string) string {
 	parts := strings.Split(email, "@")
 	if len(parts) == 2 {
@@ -0,0 +1,1137 @@
+package client
+
+import (
+	"GoDissys/common"
+	"GoDissys/mailbox"
+	"GoDissys/nameserver"
+	"GoDissys/proto/proto"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MockTransferServer is a mock implementation of proto.TransferServerServer that
+// delivers every message straight into an in-process Mailbox. Like the real TransferServer it stamps
+// sent_at, so the Mailbox returns messages in the order they were sent.
+type MockTransferServer struct {
+	proto.UnimplementedTransferServerServer
+	mailbox proto.MailboxServer
+}
+
+func (m *MockTransferServer) SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.SendMailResponse, error) {
+	if msg := req.GetMessage(); msg != nil {
+		msg.SentAt = common.FormatSentAt(time.Now())
+	}
+	if _, err := m.mailbox.ReceiveMail(ctx, &proto.ReceiveMailRequest{Message: req.GetMessage()}); err != nil {
+		return &proto.SendMailResponse{Success: false, Message: err.Error()}, nil
+	}
+	return &proto.SendMailResponse{Success: true, Message: "Mock mail sent"}, nil
+}
+
+// MockAuthMailbox is a mock Mailbox whose GetMail requires the access token stored for the requested address.
+type MockAuthMailbox struct {
+	proto.UnimplementedMailboxServer
+	tokens map[string]string // Expected token per email address
+}
+
+func (m *MockAuthMailbox) GetMail(ctx context.Context, req *proto.GetMailRequest) (*proto.GetMailResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(common.AuthTokenMetadataKey)
+	if len(values) != 1 || values[0] != "Bearer "+m.tokens[req.GetEmailAddress()] {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid access token for '%s'", req.GetEmailAddress())
+	}
+	return &proto.GetMailResponse{Messages: []*proto.MailMessage{{Subject: "Authenticated"}}}, nil
+}
+
+// MockStreamingMailbox is a mock Mailbox whose WaitForMail pushes one queued message per call
+// and then blocks until the caller gives up.
+type MockStreamingMailbox struct {
+	proto.UnimplementedMailboxServer
+	mu       sync.Mutex
+	pending  []*proto.MailMessage
+	afterIDs []string      // after_message_id of every call, in order
+	drained  chan struct{} // Closed when a call finds no more messages to push
+}
+
+func (m *MockStreamingMailbox) WaitForMail(ctx context.Context, req *proto.WaitForMailRequest) (*proto.GetMailResponse, error) {
+	m.mu.Lock()
+	m.afterIDs = append(m.afterIDs, req.GetAfterMessageId())
+	if len(m.pending) > 0 {
+		msg := m.pending[0]
+		m.pending = m.pending[1:]
+		m.mu.Unlock()
+		return &proto.GetMailResponse{Messages: []*proto.MailMessage{msg}}, nil
+	}
+	close(m.drained)
+	m.mu.Unlock()
+	<-ctx.Done()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+// WatchMail pushes every queued message and then keeps the stream open until the caller gives up.
+func (m *MockStreamingMailbox) WatchMail(req *proto.WatchMailRequest, stream proto.Mailbox_WatchMailServer) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+	for _, msg := range pending {
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	close(m.drained)
+	<-stream.Context().Done()
+	return nil
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer goroutine and a reading test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serve starts a gRPC server on a random port, lets register attach services to it, and returns its address.
+func serve(t *testing.T, register func(s *grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go func() {
+		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			t.Errorf("Server failed to serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// TestClient_LibraryErrors tests that SendMail and GetMail report unreachable services as errors instead of
+// ending the process.
+func TestClient_LibraryErrors(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	unreachable := lis.Addr().String()
+	lis.Close()
+
+	t.Run("SendMailUnreachable", func(t *testing.T) {
+		if err := SendMail(unreachable, "alice@earth.com", "bob@saturn.com", "Hi", "there"); err == nil {
+			t.Error("Expected an error for an unreachable TransferServer")
+		}
+	})
+	t.Run("SendMailRejected", func(t *testing.T) {
+		addr := serve(t, func(s *grpc.Server) {
+			proto.RegisterTransferServerServer(s, &MockTransferServer{mailbox: &MockAuthMailbox{}})
+		}) // ReceiveMail is unimplemented
+		if err := SendMail(addr, "alice@earth.com", "bob@saturn.com", "Hi", "there"); err == nil || !strings.Contains(err.Error(), "not accepted") {
+			t.Errorf("Expected an error for a rejected message, got %v", err)
+		}
+	})
+	t.Run("GetMailUnreachable", func(t *testing.T) {
+		if err := GetMail("alice@earth.com", unreachable, "", nil); err == nil {
+			t.Error("Expected an error for an unreachable Mailbox")
+		}
+	})
+}
+
+// TestClient_TLSPerConfig tests that the TLS setting of one Config does not leak into sends with another, so
+// a plaintext send works while another Config insists on TLS.
+func TestClient_TLSPerConfig(t *testing.T) {
+	addr := serve(t, func(s *grpc.Server) {
+		proto.RegisterTransferServerServer(s, &MockTransferServer{mailbox: mailbox.NewServer("saturn.com")})
+	}) // Serves plaintext only
+
+	withTLS := Config{TransferServerAddr: addr, TLS: &common.TLSConfig{}}
+	if err := SendMailWithConfig(withTLS, "alice@earth.com", "bob@saturn.com", "Hi", "there"); err == nil {
+		t.Error("Expected a TLS send to a plaintext TransferServer to fail")
+	}
+	if err := SendMailWithConfig(Config{TransferServerAddr: addr}, "alice@earth.com", "bob@saturn.com", "Hi", "there"); err != nil {
+		t.Errorf("Expected a plaintext send after the TLS one to succeed, got %v", err)
+	}
+}
+
+// TestClient_SelfTest tests that the selftest round trip succeeds against in-process servers.
+func TestClient_SelfTest(t *testing.T) {
+	mailboxService := mailbox.NewServer("earth.com")
+	mailboxAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mailboxService) })
+	transferServerAddr := serve(t, func(s *grpc.Server) {
+		proto.RegisterTransferServerServer(s, &MockTransferServer{mailbox: mailboxService})
+	})
+
+	// Pre-existing mail must be handed back rather than lost while polling
+	_, err := mailboxService.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail:    "bob@saturn.com",
+		RecipientEmail: "alice@earth.com",
+		Subject:        "Earlier mail",
+		Timestamp:      time.Now().Unix(),
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	result, err := SelfTest(transferServerAddr, "alice@earth.com", mailboxAddr, "", nil, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SelfTest failed: %v", err)
+	}
+	if result.RoundTrip <= 0 {
+		t.Errorf("Expected a positive round-trip time, got %s", result.RoundTrip)
+	}
+	if len(result.Other) != 1 || result.Other[0].GetSubject() != "Earlier mail" {
+		t.Errorf("Expected the earlier message to be returned in Other, got %v", result.Other)
+	}
+}
+
+// TestClient_StateConcurrentAccess exercises concurrent reads of the client state while a
+// simulated background task updates it. Run with -race to detect unsynchronized access.
+func TestClient_StateConcurrentAccess(t *testing.T) {
+	var state currentClientState
+	state.login("alice@earth.com", "localhost:50054", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				email, mailboxAddr, _ := state.session()
+				// A snapshot must never mix fields from two different logins
+				if (email == "alice@earth.com" && mailboxAddr != "localhost:50054") ||
+					(email == "bob@saturn.com" && mailboxAddr != "localhost:50055") {
+					t.Errorf("Inconsistent session snapshot: %s at %s", email, mailboxAddr)
+					return
+				}
+			}
+		}()
+	}
+
+	// Simulated background update switching between users
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 1000; j++ {
+			if j%2 == 0 {
+				state.login("bob@saturn.com", "localhost:50055", "")
+			} else {
+				state.login("alice@earth.com", "localhost:50054", "")
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// TestClient_CredentialsFile tests that a token saved to the credentials file is loaded and attached to GetMail.
+func TestClient_CredentialsFile(t *testing.T) {
+	mailboxAddr := serve(t, func(s *grpc.Server) {
+		proto.RegisterMailboxServer(s, &MockAuthMailbox{tokens: map[string]string{"alice@earth.com": "s3cret"}})
+	})
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	if err := saveToken(path, "bob@saturn.com", "other"); err != nil {
+		t.Fatalf("saveToken failed: %v", err)
+	}
+	if err := saveToken(path, "alice@earth.com", "s3cret"); err != nil {
+		t.Fatalf("saveToken failed: %v", err)
+	}
+	token, err := loadToken(path, "alice@earth.com")
+	if err != nil || token != "s3cret" {
+		t.Fatalf("Expected token 's3cret', got '%s' (err %v)", token, err)
+	}
+	if other, _ := loadToken(path, "bob@saturn.com"); other != "other" {
+		t.Errorf("Expected the token of another account to be kept, got '%s'", other)
+	}
+
+	messages, err := fetchMail("alice@earth.com", mailboxAddr, token, nil)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Expected authenticated GetMail to succeed, got %v (err %v)", messages, err)
+	}
+
+	// Without a stored token the request is not authenticated
+	missing, _ := loadToken(path, "carol@earth.com")
+	_, err = fetchMail("alice@earth.com", mailboxAddr, missing, nil)
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated error without a token, got %v", err)
+	}
+}
+
+// TestClient_TailMail tests that tail prints messages pushed by the mailbox as they arrive and stops on cancel.
+func TestClient_TailMail(t *testing.T) {
+	mock := &MockStreamingMailbox{
+		pending: []*proto.MailMessage{
+			{MessageId: "m1", SenderEmail: "bob@saturn.com", Subject: "First live message"},
+			{MessageId: "m2", SenderEmail: "carol@earth.com", Subject: "Second live message"},
+		},
+		drained: make(chan struct{}),
+	}
+	mailboxAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mock) })
+
+	var out bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- TailMail(ctx, "alice@earth.com", mailboxAddr, "", nil, &out) }()
+
+	select {
+	case <-mock.drained:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for tail to consume both messages")
+	}
+	cancel() // The user pressed Enter
+	if err := <-done; err != nil {
+		t.Fatalf("TailMail failed: %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "First live message") || !strings.Contains(output, "Second live message") {
+		t.Errorf("Expected both messages in the tail output, got:\n%s", output)
+	}
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if want := []string{"", "m1", "m2"}; strings.Join(mock.afterIDs, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected WaitForMail cursors %v, got %v", want, mock.afterIDs)
+	}
+}
+
+// TestClient_WatchMail tests that watch prints messages pushed over the WatchMail stream and stops on cancel.
+func TestClient_WatchMail(t *testing.T) {
+	mock := &MockStreamingMailbox{
+		pending: []*proto.MailMessage{
+			{MessageId: "m1", SenderEmail: "bob@saturn.com", Subject: "First pushed message"},
+			{MessageId: "m2", SenderEmail: "carol@earth.com", Subject: "Second pushed message"},
+		},
+		drained: make(chan struct{}),
+	}
+	mailboxAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mock) })
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- WatchMail(ctx, "alice@earth.com", mailboxAddr, "", nil, &out) }()
+
+	select {
+	case <-mock.drained:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for watch to receive both messages")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "Second pushed message") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond) // Pushed messages may still be in flight
+	}
+	cancel() // The user pressed Ctrl-C
+	if err := <-done; err != nil {
+		t.Fatalf("WatchMail failed: %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "--- Message 1 ---") || !strings.Contains(output, "First pushed message") || !strings.Contains(output, "Second pushed message") {
+		t.Errorf("Expected both messages in the watch output, got:\n%s", output)
+	}
+}
+
+// MockInfoNameserver is a mock Nameserver that only answers Info.
+type MockInfoNameserver struct {
+	proto.UnimplementedNameserverServer
+}
+
+func (m *MockInfoNameserver) Info(ctx context.Context, req *proto.NameserverInfoRequest) (*proto.NameserverInfoResponse, error) {
+	return &proto.NameserverInfoResponse{Registrations: 3, ManagedDomains: []string{"earth.com", "saturn.com"}}, nil
+}
+
+// MockInfoTransferServer is a mock TransferServer that only answers Info.
+type MockInfoTransferServer struct {
+	proto.UnimplementedTransferServerServer
+}
+
+func (m *MockInfoTransferServer) Info(ctx context.Context, req *proto.TransferServerInfoRequest) (*proto.TransferServerInfoResponse, error) {
+	return &proto.TransferServerInfoResponse{
+		AsyncDelivery:       true,
+		Queue:               &proto.QueueStatusResponse{Queued: 4, Paused: true},
+		MessagesAccepted:    10,
+		DeliveriesSucceeded: 8,
+		DeliveriesFailed:    1,
+	}, nil
+}
+
+// TestClient_SystemStatus tests that the status report is assembled from all services,
+// including one Mailbox that cannot be reached.
+func TestClient_SystemStatus(t *testing.T) {
+	mailboxService := mailbox.NewServer("earth.com")
+	_, err := mailboxService.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Hi",
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+	dead, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	cfg := Config{
+		NameserverAddr:     serve(t, func(s *grpc.Server) { proto.RegisterNameserverServer(s, &MockInfoNameserver{}) }),
+		TransferServerAddr: serve(t, func(s *grpc.Server) { proto.RegisterTransferServerServer(s, &MockInfoTransferServer{}) }),
+		Mailboxes: map[string]struct {
+			Domain string
+			Addr   string
+		}{
+			"earth.com":  {Domain: "earth", Addr: serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mailboxService) })},
+			"saturn.com": {Domain: "saturn", Addr: deadAddr},
+		},
+	}
+
+	st := FetchSystemStatus(cfg)
+	if st.NameserverErr != nil || st.Nameserver.GetRegistrations() != 3 {
+		t.Errorf("Expected Nameserver info with 3 registrations, got %v (err %v)", st.Nameserver, st.NameserverErr)
+	}
+	if st.TransferServerErr != nil || st.TransferServer.GetMessagesAccepted() != 10 {
+		t.Errorf("Expected TransferServer info, got %v (err %v)", st.TransferServer, st.TransferServerErr)
+	}
+	if len(st.Mailboxes) != 2 || st.Mailboxes[0].Domain != "earth.com" || st.Mailboxes[1].Domain != "saturn.com" {
+		t.Fatalf("Expected both mailboxes sorted by domain, got %v", st.Mailboxes)
+	}
+	if earth := st.Mailboxes[0]; earth.Err != nil || earth.Info.GetUsers() != 1 || earth.Info.GetMessages() != 1 {
+		t.Errorf("Expected earth.com with 1 user and 1 message, got %v (err %v)", earth.Info, earth.Err)
+	}
+	if st.Mailboxes[1].Err == nil {
+		t.Errorf("Expected an error for the unreachable saturn.com mailbox")
+	}
+
+	var out bytes.Buffer
+	st.Print(&out)
+	for _, want := range []string{
+		"Nameserver: 3 registrations, managing earth.com, saturn.com",
+		"Mailbox earth.com",
+		"1 users, 1 messages, 0 in trash",
+		"Mailbox saturn.com (" + deadAddr + "): UNAVAILABLE",
+		"asynchronous, 4 queued, 0 in flight, paused",
+		"10 messages accepted, 8 deliveries succeeded, 1 failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected status report to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+// CountingTransferServer is a MockTransferServer that counts the messages it relays.
+type CountingTransferServer struct {
+	MockTransferServer
+	sent sync.Map // Recipient -> true for every relayed message
+}
+
+func (m *CountingTransferServer) SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.SendMailResponse, error) {
+	m.sent.Store(req.GetMessage().GetRecipientEmail(), true)
+	return m.MockTransferServer.SendMail(ctx, req)
+}
+
+// TestClient_InProcessDelivery tests that mail for a Mailbox running in-process skips the TransferServer
+// while mail for a remote Mailbox still goes over the network.
+func TestClient_InProcessDelivery(t *testing.T) {
+	localMailbox := mailbox.NewServer("earth.com")
+	mailbox.RegisterLocalServer("inprocess-earth:50054", localMailbox)
+	t.Cleanup(func() { mailbox.UnregisterLocalServer("inprocess-earth:50054") })
+
+	remoteMailbox := mailbox.NewServer("saturn.com")
+	remoteAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, remoteMailbox) })
+	transfer := &CountingTransferServer{MockTransferServer: MockTransferServer{mailbox: remoteMailbox}}
+	cfg := Config{
+		TransferServerAddr: serve(t, func(s *grpc.Server) { proto.RegisterTransferServerServer(s, transfer) }),
+		Mailboxes: map[string]struct {
+			Domain string
+			Addr   string
+		}{
+			"earth.com":  {Domain: "earth", Addr: "inprocess-earth:50054"},
+			"saturn.com": {Domain: "saturn", Addr: remoteAddr},
+		},
+		InProcessDelivery: true,
+	}
+
+	resp, err := deliverMessage(cfg, &proto.MailMessage{SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Local"})
+	if err != nil || !resp.GetSuccess() || resp.GetMessageId() == "" {
+		t.Fatalf("Expected in-process delivery to succeed with a message ID, got %v (err %v)", resp, err)
+	}
+	if _, relayed := transfer.sent.Load("alice@earth.com"); relayed {
+		t.Errorf("Expected mail for the local recipient to skip the TransferServer")
+	}
+	if got, _ := localMailbox.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "alice@earth.com"}); len(got.GetMessages()) != 1 {
+		t.Errorf("Expected the message in the local mailbox, got %v", got.GetMessages())
+	}
+
+	if _, err := deliverMessage(cfg, &proto.MailMessage{SenderEmail: "alice@earth.com", RecipientEmail: "bob@saturn.com", Subject: "Remote"}); err != nil {
+		t.Fatalf("Network delivery failed: %v", err)
+	}
+	if _, relayed := transfer.sent.Load("bob@saturn.com"); !relayed {
+		t.Errorf("Expected mail for the remote recipient to go through the TransferServer")
+	}
+
+	// Mail for several recipients is relayed even if the first one is local
+	if _, err := deliverMessage(cfg, &proto.MailMessage{SenderEmail: "bob@saturn.com", RecipientEmail: "dave@earth.com", To: []string{"bob@saturn.com"}}); err != nil {
+		t.Fatalf("Network delivery failed: %v", err)
+	}
+	if _, relayed := transfer.sent.Load("dave@earth.com"); !relayed {
+		t.Errorf("Expected mail for several recipients to go through the TransferServer")
+	}
+
+	// With the flag off, local recipients go over the network as well
+	cfg.InProcessDelivery = false
+	cfg.Mailboxes["earth.com"] = struct {
+		Domain string
+		Addr   string
+	}{Domain: "earth", Addr: remoteAddr}
+	if _, err := deliverMessage(cfg, &proto.MailMessage{SenderEmail: "bob@saturn.com", RecipientEmail: "carol@earth.com"}); err != nil {
+		t.Fatalf("Network delivery failed: %v", err)
+	}
+	if _, relayed := transfer.sent.Load("carol@earth.com"); !relayed {
+		t.Errorf("Expected the TransferServer to be used when in-process delivery is disabled")
+	}
+}
+
+// TestCommandGuard tests that over-long commands and over-rate bursts in batch mode are rejected.
+func TestCommandGuard(t *testing.T) {
+	cfg := Config{MaxCommandLength: 32, MaxCommandsPerSecond: 3}
+	now := time.Now()
+
+	t.Run("OverLengthCommand", func(t *testing.T) {
+		guard := newCommandGuard(cfg, true)
+		if err := guard.check("send bob@earth.com hi "+strings.Repeat("x", 64), now); err == nil || !strings.Contains(err.Error(), "maximum command length") {
+			t.Errorf("Expected an over-length command to be rejected, got %v", err)
+		}
+		if err := guard.check("whoami", now); err != nil {
+			t.Errorf("Expected a short command to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("OverRateBurst", func(t *testing.T) {
+		guard := newCommandGuard(cfg, true)
+		rejected := 0
+		for i := 0; i < 5; i++ {
+			if err := guard.check("whoami", now.Add(time.Duration(i)*10*time.Millisecond)); err != nil {
+				rejected++
+			}
+		}
+		if rejected != 2 {
+			t.Errorf("Expected 2 of 5 burst commands to be rejected, got %d", rejected)
+		}
+		if err := guard.check("whoami", now.Add(time.Second)); err != nil {
+			t.Errorf("Expected commands to be accepted again in the next second, got %v", err)
+		}
+	})
+
+	t.Run("InteractiveNotRateLimited", func(t *testing.T) {
+		guard := newCommandGuard(cfg, false)
+		for i := 0; i < 5; i++ {
+			if err := guard.check("whoami", now); err != nil {
+				t.Fatalf("Expected interactive commands not to be rate-limited, got %v", err)
+			}
+		}
+	})
+}
+
+// TestSplitCommandLine tests that quoted segments stay single arguments and escapes are honoured.
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"Plain", "  send bob@x.com  Hi\tthere ", []string{"send", "bob@x.com", "Hi", "there"}},
+		{"DoubleQuotes", `send bob@x.com "Project Update" "Let's talk"`, []string{"send", "bob@x.com", "Project Update", "Let's talk"}},
+		{"SingleQuotes", `send bob@x.com 'Say "hi"' 'a  b'`, []string{"send", "bob@x.com", `Say "hi"`, "a  b"}},
+		{"EscapedQuotes", `send bob@x.com "Say \"hi\"" 'It\'s' don\'t`, []string{"send", "bob@x.com", `Say "hi"`, "It's", "don't"}},
+		{"EscapedSpace", `get my\ label`, []string{"get", "my label"}},
+		{"AdjacentSegments", `send bob@x.com pre"fix 'mid'"post`, []string{"send", "bob@x.com", "prefix 'mid'post"}},
+		{"EmptyQuoted", `send bob@x.com "" ''`, []string{"send", "bob@x.com", "", ""}},
+		{"BackslashInQuotes", `'C:\dir' "a\\b" end\`, []string{`C:\dir`, `a\b`, `end\`}},
+		{"Empty", "   ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommandLine(tt.line)
+			if err != nil {
+				t.Fatalf("splitCommandLine(%q) failed: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("UnterminatedQuote", func(t *testing.T) {
+		for _, line := range []string{`send bob@x.com "Hi`, `send bob@x.com 'Let's talk'`, `send "a\"`} {
+			if got, err := splitCommandLine(line); err == nil {
+				t.Errorf("Expected splitCommandLine(%q) to fail, got %q", line, got)
+			}
+		}
+	})
+}
+
+// TestPrintMessages_Timestamps tests that unset timestamps are shown as unknown, that sent_at is preferred to
+// the timestamp and that the received time is shown when the Mailbox recorded it.
+func TestPrintMessages_Timestamps(t *testing.T) {
+	received := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	sent := time.Date(2024, 5, 1, 11, 30, 0, 500, time.UTC)
+	var out bytes.Buffer
+	printMessages(&out, []*proto.MailMessage{
+		{SenderEmail: "bob@saturn.com", Subject: "No time"},
+		{SenderEmail: "bob@saturn.com", Subject: "Garbage time", Timestamp: -42, ReceivedTimestamp: received.Unix()},
+		{SenderEmail: "bob@saturn.com", Subject: "Sent at", Timestamp: 1, SentAt: sent.Format(time.RFC3339Nano)},
+	})
+
+	got := out.String()
+	if strings.Contains(got, "1970") {
+		t.Errorf("Expected no 1970 dates for invalid timestamps, got:\n%s", got)
+	}
+	if n := strings.Count(got, "Timestamp: unknown\n"); n != 2 {
+		t.Errorf("Expected 2 unknown timestamps, got %d in:\n%s", n, got)
+	}
+	if want := "Received: " + received.Format(time.RFC822) + "\n"; strings.Count(got, "Received: ") != 1 || !strings.Contains(got, want) {
+		t.Errorf("Expected exactly one line %q, got:\n%s", want, got)
+	}
+	if want := "Timestamp: " + sent.Local().Format(time.RFC822) + "\n"; !strings.Contains(got, want) {
+		t.Errorf("Expected sent_at to be shown as %q, got:\n%s", want, got)
+	}
+}
+
+// TestCLI_CommandResults tests the structured results of command handlers, independent of how they are rendered.
+func TestCLI_CommandResults(t *testing.T) {
+	earthMailbox := mailbox.NewServer("earth.com")
+	earthAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, earthMailbox) })
+	transfer := &MockTransferServer{mailbox: earthMailbox}
+	c := &cli{
+		cfg: Config{
+			TransferServerAddr: serve(t, func(s *grpc.Server) { proto.RegisterTransferServerServer(s, transfer) }),
+			Mailboxes: map[string]struct {
+				Domain string
+				Addr   string
+			}{"earth.com": {Domain: "earth", Addr: earthAddr}},
+		},
+		state: &currentClientState{},
+	}
+
+	t.Run("WhoamiLoggedOut", func(t *testing.T) {
+		if r := c.dispatch([]string{"whoami"}); !r.OK || r.Data != nil || r.Message != "Not logged in." {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+	})
+	t.Run("SendRequiresLogin", func(t *testing.T) {
+		if r := c.dispatch([]string{"send", "alice@earth.com", "Hi", "there"}); r.OK {
+			t.Errorf("Expected send to fail before login, got %+v", r)
+		}
+	})
+
+	if r := c.dispatch([]string{"login", "alice@earth.com"}); !r.OK {
+		t.Fatalf("login failed: %+v", r)
+	}
+
+	t.Run("Whoami", func(t *testing.T) {
+		r := c.dispatch([]string{"whoami"})
+		session, isSession := r.Data.(*sessionInfo)
+		if !r.OK || !isSession || session.EmailAddress != "alice@earth.com" || session.MailboxAddress != earthAddr {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+	})
+	t.Run("Send", func(t *testing.T) {
+		r := c.dispatch([]string{"send", "alice@earth.com", "Note", "to", "self"})
+		sent, isSent := r.Data.(*sentMail)
+		if !r.OK || !isSent || sent.RecipientEmail != "alice@earth.com" {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+		if r := c.dispatch([]string{"send", "alice@earth.com"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error for missing arguments, got %+v", r)
+		}
+	})
+	t.Run("Search", func(t *testing.T) {
+		r := c.dispatch([]string{"search", "TO", "SELF"})
+		if messages, isList := r.Data.([]*proto.MailMessage); !r.OK || !isList || len(messages) != 1 || messages[0].GetSubject() != "Note" {
+			t.Errorf("Expected the message to be found by its body, got %+v", r)
+		}
+		r = c.dispatch([]string{"search", "nothing"})
+		if messages, isList := r.Data.([]*proto.MailMessage); !r.OK || !isList || len(messages) != 0 {
+			t.Errorf("Expected an empty list for a search without matches, got %+v", r)
+		}
+		if r := c.dispatch([]string{"search"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error without a term, got %+v", r)
+		}
+	})
+	// clearInbox deletes alice's mail one message at a time.
+	clearInbox := func(t *testing.T) {
+		t.Helper()
+		r := c.dispatch([]string{"get"})
+		for messages, _ := r.Data.([]*proto.MailMessage); len(messages) > 0; messages, _ = r.Data.([]*proto.MailMessage) {
+			if r = c.dispatch([]string{"delete", "1", "--yes"}); !r.OK {
+				t.Fatalf("delete failed: %+v", r)
+			}
+		}
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		r := c.dispatch([]string{"get"})
+		messages, isList := r.Data.([]*proto.MailMessage)
+		if !r.OK || !isList || len(messages) != 1 || messages[0].GetSubject() != "Note" || messages[0].GetBody() != "to self" {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+		r = c.dispatch([]string{"get"})
+		if messages, isList := r.Data.([]*proto.MailMessage); !r.OK || !isList || len(messages) != 1 {
+			t.Fatalf("Expected the message to stay in the inbox until deleted, got %+v", r)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		c.dispatch([]string{"send", "alice@earth.com", "Second", "note"})
+		c.dispatch([]string{"get"})
+		if r := c.dispatch([]string{"delete", "3", "--yes"}); r.OK || !strings.Contains(r.Message, "no message 3") {
+			t.Errorf("Expected an error for an unlisted message, got %+v", r)
+		}
+		if r := c.dispatch([]string{"delete", "1"}); r.OK || !strings.Contains(r.Message, "--yes") {
+			t.Errorf("Expected deleting without a way to confirm to be refused, got %+v", r)
+		}
+
+		var asked string
+		c.confirm = func(question string) bool { asked = question; return false }
+		defer func() { c.confirm = nil }()
+		if r := c.dispatch([]string{"delete", "1"}); r.OK || !strings.Contains(asked, "Subject: Note") {
+			t.Errorf("Expected a declined confirmation to keep the message, got %+v (asked %q)", r, asked)
+		}
+
+		c.confirm = func(string) bool { return true }
+		r := c.dispatch([]string{"delete", "1"})
+		messages, isList := r.Data.([]*proto.MailMessage)
+		if !r.OK || !isList || len(messages) != 1 || messages[0].GetSubject() != "Second" {
+			t.Fatalf("Expected the refreshed list without the deleted message, got %+v", r)
+		}
+		if fetched := c.state.fetchedMessages(); len(fetched) != 1 || fetched[0].GetSubject() != "Second" {
+			t.Errorf("Expected the listed messages to be refreshed, got %v", fetched)
+		}
+		if r := c.dispatch([]string{"delete", "1", "--yes"}); !r.OK {
+			t.Fatalf("delete failed: %+v", r)
+		}
+		r = c.dispatch([]string{"get"})
+		if messages, isList := r.Data.([]*proto.MailMessage); !r.OK || !isList || len(messages) != 0 {
+			t.Errorf("Expected an empty list once the inbox is cleared, got %+v", r)
+		}
+	})
+	t.Run("Reply", func(t *testing.T) {
+		c.dispatch([]string{"login", "alice@earth.com"}) // Logging in again forgets the listed messages
+		if r := c.dispatch([]string{"reply", "1", "Thanks"}); r.OK || !strings.Contains(r.Message, "use 'get'") {
+			t.Errorf("Expected an error before any get, got %+v", r)
+		}
+		c.dispatch([]string{"send", "alice@earth.com", "Lunch", "Noon?"})
+		c.dispatch([]string{"get"})
+		if r := c.dispatch([]string{"reply", "2", "Thanks"}); r.OK || !strings.Contains(r.Message, "no message 2") {
+			t.Errorf("Expected an error for an unlisted message, got %+v", r)
+		}
+		if r := c.dispatch([]string{"reply", "one", "Thanks"}); r.OK {
+			t.Errorf("Expected an error for a non-numeric message number, got %+v", r)
+		}
+		r := c.dispatch([]string{"reply", "1", "Sounds", "good"})
+		if sent, isSent := r.Data.(*sentMail); !r.OK || !isSent || sent.RecipientEmail != "alice@earth.com" {
+			t.Fatalf("Unexpected result: %+v", r)
+		}
+
+		r = c.dispatch([]string{"get"})
+		messages, _ := r.Data.([]*proto.MailMessage)
+		if len(messages) != 2 || messages[1].GetSubject() != "Re: Lunch" || messages[1].GetBody() != "Sounds good" {
+			t.Fatalf("Expected the reply in the inbox, got %+v", r)
+		}
+		c.dispatch([]string{"reply", "2", "Sure"})
+		r = c.dispatch([]string{"get"})
+		if messages, _ := r.Data.([]*proto.MailMessage); len(messages) != 3 || messages[2].GetSubject() != "Re: Lunch" {
+			t.Errorf("Expected a reply to a reply not to add another prefix, got %+v", r)
+		}
+		clearInbox(t)
+	})
+	t.Run("Forward", func(t *testing.T) {
+		c.dispatch([]string{"send", "alice@earth.com", "Agenda", "1. Budget"})
+		c.dispatch([]string{"get"})
+		if r := c.dispatch([]string{"forward", "3", "alice@earth.com"}); r.OK || !strings.Contains(r.Message, "no message 3") {
+			t.Errorf("Expected an error for an unlisted message, got %+v", r)
+		}
+		if r := c.dispatch([]string{"forward", "1"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error without a recipient, got %+v", r)
+		}
+		if r := c.dispatch([]string{"forward", "1", "alice@earth.com"}); !r.OK {
+			t.Fatalf("Unexpected result: %+v", r)
+		}
+
+		r := c.dispatch([]string{"get"})
+		messages, _ := r.Data.([]*proto.MailMessage)
+		if len(messages) != 2 || messages[1].GetSubject() != "Fwd: Agenda" {
+			t.Fatalf("Expected the forwarded message in the inbox, got %+v", r)
+		}
+		if body := messages[1].GetBody(); !strings.Contains(body, "From: alice@earth.com") || !strings.HasSuffix(body, "\n\n1. Budget") {
+			t.Errorf("Expected the original sender and body in the forwarded body, got %q", body)
+		}
+		clearInbox(t)
+	})
+	t.Run("SendBodyFromFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "body.txt")
+		if err := os.WriteFile(path, []byte("Line one\nLine two\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write body file: %v", err)
+		}
+		if r := c.dispatch([]string{"send", "alice@earth.com", "Report", "--file", filepath.Join(path, "missing")}); r.OK || !strings.Contains(r.Message, "Error reading mail body") {
+			t.Errorf("Expected an error for an unreadable file, got %+v", r)
+		}
+		if r := c.dispatch([]string{"send", "alice@earth.com", "Report", "--file"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error without a path, got %+v", r)
+		}
+		r := c.dispatch([]string{"send", "alice@earth.com", "Report", "ignored", "--file", path})
+		if !r.OK || !strings.HasPrefix(r.Message, "Warning:") {
+			t.Errorf("Expected a warning about the ignored inline body, got %+v", r)
+		}
+
+		r = c.dispatch([]string{"get"})
+		messages, _ := r.Data.([]*proto.MailMessage)
+		if len(messages) != 1 || messages[0].GetBody() != "Line one\nLine two\n" {
+			t.Fatalf("Expected the body read from the file, got %+v", r)
+		}
+		clearInbox(t)
+	})
+	t.Run("SendToSeveral", func(t *testing.T) {
+		r := c.dispatch([]string{"send", "alice@earth.com, ,carol@earth.com", "Team", "update"})
+		sent, isSent := r.Data.(*sentMail)
+		if !r.OK || !isSent || sent.RecipientEmail != "alice@earth.com" || strings.Join(sent.Recipients, ",") != "alice@earth.com,carol@earth.com" {
+			t.Errorf("Unexpected result: %+v", r)
+		}
+		if r := c.dispatch([]string{"send", ",", "Team", "update"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error without recipients, got %+v", r)
+		}
+		if r := c.dispatch([]string{"send"}); r.OK || !strings.HasPrefix(r.Message, "Usage:") {
+			t.Errorf("Expected a usage error without arguments, got %+v", r)
+		}
+	})
+	t.Run("UnknownCommand", func(t *testing.T) {
+		if r := c.dispatch([]string{"frobnicate"}); r.OK {
+			t.Errorf("Expected an unknown command to fail, got %+v", r)
+		}
+	})
+}
+
+// TestCLI_Signup tests that signup registers the user with the Nameserver and reports a failed registration
+// as an error result instead of exiting.
+func TestCLI_Signup(t *testing.T) {
+	ns := nameserver.NewServer([]string{"earth.com"})
+	c := &cli{
+		cfg: Config{
+			NameserverAddr: serve(t, func(s *grpc.Server) { proto.RegisterNameserverServer(s, ns) }),
+			Mailboxes: map[string]struct {
+				Domain string
+				Addr   string
+			}{"earth.com": {Domain: "earth", Addr: "localhost:1001"}, "mars.com": {Domain: "mars", Addr: "localhost:1002"}},
+		},
+		state: &currentClientState{},
+	}
+
+	if r := c.dispatch([]string{"signup", "alice@earth.com", "earth"}); !r.OK {
+		t.Fatalf("Expected signup to succeed, got %+v", r)
+	}
+	lookup, err := ns.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+	if err != nil || !lookup.GetFound() {
+		t.Errorf("Expected alice to be registered, got %v (err %v)", lookup, err)
+	}
+
+	if r := c.dispatch([]string{"signup", "bob@mars.com", "mars"}); r.OK || !strings.HasPrefix(r.Message, "Error signing up") {
+		t.Errorf("Expected a signup for an unmanaged domain to fail, got %+v", r)
+	}
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	c.cfg.NameserverAddr = lis.Addr().String()
+	lis.Close()
+	if r := c.dispatch([]string{"signup", "carol@earth.com", "earth"}); r.OK {
+		t.Errorf("Expected a signup with an unreachable Nameserver to fail, got %+v", r)
+	}
+}
+
+// TestCLI_Password tests that signup sets the password entered at the prompt with the admin token, and that
+// login passes the password entered at its prompt on with every request.
+func TestCLI_Password(t *testing.T) {
+	ns := nameserver.NewServer([]string{"earth.com"})
+	mailboxService, err := mailbox.NewServerWithConfig(common.MailboxConfig{Domain: "earth.com", AdminToken: "admin"})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	mailboxAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mailboxService) })
+	var password string // What the user enters at the next prompt
+	c := &cli{
+		cfg: Config{
+			NameserverAddr: serve(t, func(s *grpc.Server) { proto.RegisterNameserverServer(s, ns) }),
+			Mailboxes: map[string]struct {
+				Domain string
+				Addr   string
+			}{"earth.com": {Domain: "earth", Addr: mailboxAddr}},
+			AdminToken: "admin",
+		},
+		state:        &currentClientState{},
+		readPassword: func(string) (string, bool) { return password, true },
+	}
+	if r := c.dispatch([]string{"signup", "alice@earth.com", "earth", "s3cret"}); r.OK {
+		t.Errorf("Expected signup with the password as an argument to be rejected, got %+v", r)
+	}
+	password = "s3cret"
+	if r := c.dispatch([]string{"signup", "alice@earth.com", "earth"}); !r.OK {
+		t.Fatalf("Expected signup with a password to succeed, got %+v", r)
+	}
+
+	password = "guess"
+	if r := c.dispatch([]string{"login", "alice@earth.com"}); !r.OK {
+		t.Fatalf("login failed: %+v", r)
+	}
+	if r := c.dispatch([]string{"get"}); r.OK || !strings.Contains(r.Message, "wrong password") {
+		t.Errorf("Expected get with a wrong password to fail, got %+v", r)
+	}
+	password = ""
+	if r := c.dispatch([]string{"login", "alice@earth.com"}); !r.OK {
+		t.Fatalf("login failed: %+v", r)
+	}
+	if r := c.dispatch([]string{"get"}); r.OK || !strings.Contains(r.Message, "missing password") {
+		t.Errorf("Expected get without a password to fail, got %+v", r)
+	}
+	password = "s3cret"
+	if r := c.dispatch([]string{"login", "alice@earth.com"}); !r.OK {
+		t.Fatalf("login failed: %+v", r)
+	}
+	if r := c.dispatch([]string{"get"}); !r.OK {
+		t.Errorf("Expected get with the password to succeed, got %+v", r)
+	}
+}
+
+// TestCLI_Unregister tests that unregister removes the user from the Nameserver and logs them out.
+func TestCLI_Unregister(t *testing.T) {
+	ns := nameserver.NewServer([]string{"earth.com"})
+	if _, err := ns.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"}); err != nil {
+		t.Fatalf("RegisterMailbox failed: %v", err)
+	}
+	c := &cli{
+		cfg: Config{
+			NameserverAddr: serve(t, func(s *grpc.Server) { proto.RegisterNameserverServer(s, ns) }),
+			Mailboxes: map[string]struct {
+				Domain string
+				Addr   string
+			}{"earth.com": {Domain: "earth", Addr: "localhost:1001"}},
+		},
+		state: &currentClientState{},
+	}
+	if r := c.dispatch([]string{"login", "alice@earth.com"}); !r.OK {
+		t.Fatalf("login failed: %+v", r)
+	}
+
+	if r := c.dispatch([]string{"unregister"}); !r.OK {
+		t.Fatalf("Expected unregister to succeed, got %+v", r)
+	}
+	if r := c.dispatch([]string{"whoami"}); r.Message != "Not logged in." {
+		t.Errorf("Expected to be logged out after unregistering, got %+v", r)
+	}
+	lookup, err := ns.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+	if err != nil || lookup.GetFound() {
+		t.Errorf("Expected the registration to be gone, got %v (err %v)", lookup, err)
+	}
+
+	c.dispatch([]string{"login", "alice@earth.com"})
+	if r := c.dispatch([]string{"unregister"}); r.OK {
+		t.Errorf("Expected a second unregister to report nothing removed, got %+v", r)
+	}
+}
+
+// TestCLI_List tests that list returns a domain's registrations and renders them as a table.
+func TestCLI_List(t *testing.T) {
+	ns := nameserver.NewServer([]string{"earth.com"})
+	for _, email := range []string{"bob@earth.com", "alice@earth.com"} {
+		if _, err := ns.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: email, MailboxAddress: "localhost:1001"}); err != nil {
+			t.Fatalf("RegisterMailbox failed: %v", err)
+		}
+	}
+	c := &cli{cfg: Config{NameserverAddr: serve(t, func(s *grpc.Server) { proto.RegisterNameserverServer(s, ns) })}, state: &currentClientState{}}
+
+	r := c.dispatch([]string{"list", "earth.com"})
+	entries, isList := r.Data.([]*proto.MailboxEntry)
+	if !r.OK || !isList || len(entries) != 2 || entries[0].GetEmailAddress() != "alice@earth.com" {
+		t.Fatalf("Unexpected result: %+v", r)
+	}
+	var out bytes.Buffer
+	renderText(&out, r)
+	if !strings.Contains(out.String(), "EMAIL") || !strings.Contains(out.String(), "bob@earth.com    localhost:1001  -") {
+		t.Errorf("Expected a table of registrations, got:\n%s", out.String())
+	}
+
+	if r := c.dispatch([]string{"list", "mars.com"}); r.OK {
+		t.Errorf("Expected an unmanaged domain to fail, got %+v", r)
+	}
+}
+
+// TestCLI_JSONOutput tests that the JSON mode emits one result object per command and nothing else.
+func TestCLI_JSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	runCLI(Config{JSONOutput: true}, strings.NewReader("whoami\nfrobnicate\nexit\nwhoami\n"), &out, true)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 { // Nothing runs after exit
+		t.Fatalf("Expected 3 JSON lines, got %q", out.String())
+	}
+	want := []bool{true, false, true}
+	for i, line := range lines {
+		var result struct {
+			OK      bool
+			Message string
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("Line %d is not JSON: %q (%v)", i+1, line, err)
+		}
+		if result.OK != want[i] || result.Message == "" {
+			t.Errorf("Line %d: expected OK=%v with a message, got %q", i+1, want[i], line)
+		}
+	}
+}
+
+// TestCLI_JSONTail tests that in JSON mode tail emits every incoming message as a result line of its own, so
+// the output stays one JSON object per line, and that failures share the schema of successful results.
+func TestCLI_JSONTail(t *testing.T) {
+	mock := &MockStreamingMailbox{
+		pending: []*proto.MailMessage{
+			{MessageId: "m1", SenderEmail: "bob@saturn.com", Subject: "First live message"},
+			{MessageId: "m2", SenderEmail: "carol@earth.com", Subject: "Second live message"},
+		},
+		drained: make(chan struct{}),
+	}
+	mailboxAddr := serve(t, func(s *grpc.Server) { proto.RegisterMailboxServer(s, mock) })
+	var out bytes.Buffer
+	c := &cli{cfg: Config{JSONOutput: true}, state: &currentClientState{}, out: &out, waitForEnter: func() { <-mock.drained }}
+	c.state.login("alice@earth.com", mailboxAddr, "")
+
+	renderJSON(&out, c.dispatch([]string{"tail"}))
+	renderJSON(&out, c.dispatch([]string{"reply", "1", "Thanks"}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 JSON lines, got %q", out.String())
+	}
+	for i, line := range lines {
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("Line %d is not JSON: %q (%v)", i+1, line, err)
+		}
+		if _, ok := result["OK"]; !ok {
+			t.Errorf("Line %d lacks OK: %q", i+1, line)
+		}
+		if _, ok := result["Message"]; !ok {
+			t.Errorf("Line %d lacks Message: %q", i+1, line)
+		}
+	}
+	if !strings.Contains(lines[1], "Second live message") || !strings.Contains(lines[2], `"Stopped tailing mail."`) {
+		t.Errorf("Expected both messages and then the tail result, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[3], `{"OK":false,"Message":`) {
+		t.Errorf("Expected the failure as a JSON result, got %q", lines[3])
+	}
+}
+
+// TestCLI_SessionPersistence tests that the logged-in user is saved on exit or at the end of the input and
+// restored on the next start, that logout forgets it, and that a corrupt session file leaves the client logged out.
+func TestCLI_SessionPersistence(t *testing.T) {
+	cfg := Config{
+		SessionFile: filepath.Join(t.TempDir(), "session.json"),
+		Mailboxes: map[string]struct {
+			Domain string
+			Addr   string
+		}{"earth.com": {Domain: "earth", Addr: "localhost:1001"}},
+		JSONOutput: true,
+	}
+	run := func(input string) string {
+		var out bytes.Buffer
+		runCLI(cfg, strings.NewReader(input), &out, true)
+		return out.String()
+	}
+
+	run("login alice@earth.com\n\nexit\n") // The empty line answers the password prompt
+	if out := run("whoami\nexit\n"); !strings.Contains(out, "Restored session") || !strings.Contains(out, `"EmailAddress":"alice@earth.com"`) {
+		t.Errorf("Expected the session to be restored, got %q", out)
+	}
+
+	run("logout\nlogin bob@earth.com\n\n") // The input ends without 'exit', as with Ctrl-D
+	if out := run("whoami\nexit\n"); !strings.Contains(out, `"EmailAddress":"bob@earth.com"`) {
+		t.Errorf("Expected the session to be saved when the input ends, got %q", out)
+	}
+
+	run("logout\nexit\n")
+	if _, err := os.Stat(cfg.SessionFile); !os.IsNotExist(err) {
+		t.Errorf("Expected logout to remove the session file, got %v", err)
+	}
+	if out := run("whoami\n"); !strings.Contains(out, "Not logged in.") {
+		t.Errorf("Expected to start logged out after logout, got %q", out)
+	}
+
+	if err := os.WriteFile(cfg.SessionFile, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	if out := run("whoami\n"); !strings.Contains(out, "starting logged out") || !strings.Contains(out, "Not logged in.") {
+		t.Errorf("Expected a corrupt session file to leave the client logged out, got %q", out)
+	}
+
+	cfg.SessionFile = ""                   // Disabled, e.g. with -no-session
+	run("login alice@earth.com\n\nexit\n") // The empty line answers the password prompt
+	if out := run("whoami\n"); !strings.Contains(out, "Not logged in.") {
+		t.Errorf("Expected no session to be restored when disabled, got %q", out)
+	}
+}
+
+// TestRunCommand tests that a one-shot command maps its outcome to an exit code and carries the session over
+// to the next one-shot command.
+func TestRunCommand(t *testing.T) {
+	cfg := Config{
+		SessionFile: filepath.Join(t.TempDir(), "session.json"),
+		Mailboxes: map[string]struct {
+			Domain string
+			Addr   string
+		}{"earth.com": {Domain: "earth", Addr: "localhost:1001"}},
+		JSONOutput: true,
+	}
+	run := func(args ...string) (int, string) {
+		var out bytes.Buffer
+		code := RunCommand(cfg, args, strings.NewReader(""), &out)
+		return code, out.String()
+	}
+
+	if code, out := run("get"); code != ExitFailed || !strings.Contains(out, "log in first") {
+		t.Errorf("Expected get to fail before login, got %d: %q", code, out)
+	}
+	if code, out := run("frobnicate"); code != ExitUnknownCommand || !strings.HasPrefix(out, `{"OK":false`) {
+		t.Errorf("Expected an unknown command to exit with %d, got %d: %q", ExitUnknownCommand, code, out)
+	}
+	if code, _ := run(); code != ExitUnknownCommand {
+		t.Errorf("Expected no command to exit with %d, got %d", ExitUnknownCommand, code)
+	}
+	if code, out := run("LOGIN", "alice@earth.com"); code != ExitOK || strings.Count(out, "\n") != 1 {
+		t.Errorf("Expected login to succeed with a single result line, got %d: %q", code, out)
+	}
+	if code, out := run("whoami"); code != ExitOK || !strings.Contains(out, `"EmailAddress":"alice@earth.com"`) {
+		t.Errorf("Expected the login to carry over, got %d: %q", code, out)
+	}
+}
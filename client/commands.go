@@ -0,0 +1,575 @@
+package client
+
+import (
+	"GoDissys/mailbox"
+	"GoDissys/proto/proto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// commandResult is the structured outcome of a CLI command. The interactive loop renders it as text,
+// the JSON mode emits it as one JSON object per line.
+type commandResult struct {
+	OK      bool   `json:"OK"`
+	Message string `json:"Message"`
+	Data    any    `json:"Data,omitempty"` // Command-specific payload, e.g. the retrieved messages
+}
+
+// succeeded returns a successful result with a formatted message.
+func succeeded(data any, format string, args ...any) commandResult {
+	return commandResult{OK: true, Message: fmt.Sprintf(format, args...), Data: data}
+}
+
+// failed returns an unsuccessful result with a formatted message.
+func failed(format string, args ...any) commandResult {
+	return commandResult{OK: false, Message: fmt.Sprintf(format, args...)}
+}
+
+// sessionInfo is the result data of whoami and login.
+type sessionInfo struct {
+	EmailAddress   string `json:"EmailAddress"`
+	MailboxAddress string `json:"MailboxAddress"`
+}
+
+// sentMail is the result data of send.
+type sentMail struct {
+	RecipientEmail string   `json:"RecipientEmail"` // The first recipient
+	Recipients     []string `json:"Recipients"`
+	MessageID      string   `json:"MessageId"`
+}
+
+// cli holds what command handlers need: the configuration, the session, for tail and watch a way to wait
+// for the user to press Enter or Ctrl-C, for delete a way to ask the user for confirmation, and for signup
+// and login a way to ask for a password.
+type cli struct {
+	cfg            Config
+	state          *currentClientState
+	out            io.Writer // Receives output streamed while a command runs (tail, watch)
+	waitForEnter   func()
+	untilInterrupt func() (context.Context, context.CancelFunc) // Context cancelled by Ctrl-C instead of the services
+	confirm        func(question string) bool                   // Nil if the user cannot be asked, e.g. in JSON mode
+	readPassword   func(prompt string) (string, bool)           // Nil if no password can be read
+}
+
+// command is an entry of the CLI dispatch table.
+type command struct {
+	name        string
+	usage       string
+	description string
+	needsLogin  bool
+	run         func(c *cli, args []string) commandResult
+}
+
+// commands is the CLI dispatch table, in the order the help lists them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"signup", "signup <your_email> <your_domain_mailbox_alias>", "Register your email and choose a password at the prompt (e.g., alice@earth.com earth)", false, (*cli).signup},
+		{"login", "login <your_email>", "Log in to manage your mail, entering your password at the prompt (e.g., alice@earth.com)", false, (*cli).login},
+		{"logout", "logout", "Log out and forget the saved session", false, (*cli).logout},
+		{"unregister", "unregister", "Remove your email from the Nameserver and log out", true, (*cli).unregister},
+		{"save-token", "save-token <your_email> <token>", "Store your access token in the credentials file", false, (*cli).saveToken},
+		{"send", "send <recipient_email>[,<recipient_email>...] <subject> <body_text>|--file <path>", "Send an email, with the body typed inline or read from a file", true, (*cli).send},
+		{"get", "get [label...]", "List your mail, optionally only messages with one of the labels; it stays in your Mailbox until deleted", true, (*cli).get},
+		{"delete", "delete <message_number> [--yes]", "Delete a message listed by the last get, after asking for confirmation", true, (*cli).delete},
+		{"reply", "reply <message_number> <body_text>", "Reply to a message listed by the last get", true, (*cli).reply},
+		{"forward", "forward <message_number> <recipient_email>[,<recipient_email>...]", "Forward a message listed by the last get", true, (*cli).forward},
+		{"search", "search <term>", "Find mail whose sender, subject or body contains the term, without retrieving it", true, (*cli).search},
+		{"tail", "tail", "Show incoming mail live until you press Enter", true, (*cli).tail},
+		{"watch", "watch", "Show mail pushed by your Mailbox as it arrives until you press Ctrl-C", true, (*cli).watch},
+		{"selftest", "selftest", "Send a message to yourself and report the round-trip time", true, (*cli).selftest},
+		{"list", "list <domain>", "Show all mailboxes registered for a domain (admin)", false, (*cli).list},
+		{"status", "status", "Show the combined status of all services", false, (*cli).status},
+		{"whoami", "whoami", "Show current logged-in user", false, (*cli).whoami},
+		{"help", "help", "Show this list of commands", false, (*cli).help},
+		{"exit", "exit", "Quit the client", false, (*cli).exit},
+	}
+}
+
+// dispatch runs the command named by the first word of parts and returns its result.
+func (c *cli) dispatch(parts []string) commandResult {
+	cmd, ok := findCommand(parts[0])
+	if !ok {
+		return failed("Unknown command. Type 'help' for available commands.")
+	}
+	if userEmail, _, _ := c.state.session(); cmd.needsLogin && userEmail == "" {
+		return failed("Error: Please log in first using the 'login' command.")
+	}
+	return cmd.run(c, parts[1:])
+}
+
+// findCommand returns the command called name, ignoring case.
+func findCommand(name string) (command, bool) {
+	name = strings.ToLower(name)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// helpText lists the available commands.
+func helpText() string {
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\n  %s - %s", cmd.usage, cmd.description)
+	}
+	return b.String()
+}
+
+func (c *cli) signup(args []string) commandResult {
+	if len(args) != 2 {
+		return failed("Usage: signup <your_email> <your_domain_mailbox_alias>\nExample: signup alice@earth.com earth")
+	}
+	email, domainAlias := args[0], args[1]
+	mailboxConfig, found := c.cfg.Mailboxes[getDomainFromEmail(email)]
+	if !found || mailboxConfig.Domain != domainAlias {
+		return failed("Error: Mailbox configuration for domain '%s' (alias '%s') not found in config.json.", getDomainFromEmail(email), domainAlias)
+	}
+	slog.Info("Signing up", "service", "Client", "user", email, "mailbox", mailboxConfig.Addr, "nameserver", c.cfg.NameserverAddr)
+	if err := mailbox.RegisterMailboxWithNameserver(c.cfg.NameserverAddr, email, mailboxConfig.Addr, c.cfg.TLS); err != nil {
+		return failed("Error signing up %s: %v", email, err)
+	}
+	if password := c.askPassword("Password (leave empty for none)"); password != "" {
+		// Only an admin may set a first password, so the client vouches for the user it just registered
+		if err := setPassword(email, mailboxConfig.Addr, c.cfg.AdminToken, password, c.cfg.TLS); err != nil {
+			return failed("Signed up %s, but setting the password failed: %v", email, err)
+		}
+		return succeeded(nil, "Signed up %s with a password. Log in with: login %s", email, email)
+	}
+	message := fmt.Sprintf("Signed up %s without a password. You can now log in if the Mailbox allows passwordless access.", email)
+	if c.cfg.CredentialsFile != "" {
+		message += fmt.Sprintf("\nIf you were issued an access token, store it with: save-token %s <token>", email)
+	}
+	return succeeded(nil, "%s", message)
+}
+
+// askPassword asks the user for a password, returning "" if none was entered or none can be read.
+func (c *cli) askPassword(prompt string) string {
+	if c.readPassword == nil {
+		return ""
+	}
+	password, _ := c.readPassword(prompt)
+	return password
+}
+
+func (c *cli) login(args []string) commandResult {
+	if len(args) != 1 {
+		return failed("Usage: login <your_email>\nExample: login alice@earth.com")
+	}
+	email := args[0]
+	mailboxConfig, found := c.cfg.Mailboxes[getDomainFromEmail(email)]
+	if !found {
+		return failed("Error: Mailbox configuration for domain '%s' not found in config.json. Please signup first.", getDomainFromEmail(email))
+	}
+	message := fmt.Sprintf("Logged in as: %s", email)
+	token := c.askPassword("Password (leave empty for none)") // The password is sent in place of an access token
+	if token == "" && c.cfg.CredentialsFile != "" {
+		var err error
+		if token, err = loadToken(c.cfg.CredentialsFile, email); err != nil {
+			message = fmt.Sprintf("Warning: Could not load access token: %v\n%s", err, message)
+		}
+	}
+	c.state.login(email, mailboxConfig.Addr, token)
+	return succeeded(&sessionInfo{EmailAddress: email, MailboxAddress: mailboxConfig.Addr}, "%s", message)
+}
+
+func (c *cli) logout(args []string) commandResult {
+	userEmail, _, _ := c.state.session()
+	c.state.logout()
+	if c.cfg.SessionFile != "" {
+		if err := removeSession(c.cfg.SessionFile); err != nil {
+			return failed("Error: %v", err)
+		}
+	}
+	if userEmail == "" {
+		return succeeded(nil, "Not logged in.")
+	}
+	return succeeded(nil, "Logged out %s.", userEmail)
+}
+
+func (c *cli) unregister(args []string) commandResult {
+	userEmail, _, _ := c.state.session()
+	removed, message, err := deregisterMailbox(c.cfg.NameserverAddr, userEmail, c.cfg.TLS)
+	if err != nil {
+		return failed("Error unregistering %s: %v", userEmail, err)
+	}
+	if !removed {
+		return failed("Could not unregister %s: %s", userEmail, message)
+	}
+	c.state.logout()
+	return succeeded(nil, "Unregistered %s and logged out. Mail to this address will no longer be delivered.", userEmail)
+}
+
+func (c *cli) saveToken(args []string) commandResult {
+	if len(args) != 2 {
+		return failed("Usage: save-token <your_email> <token>")
+	}
+	if c.cfg.CredentialsFile == "" {
+		return failed("Error: No credentials file configured (set CredentialsFile in config.json).")
+	}
+	email, token := args[0], args[1]
+	if err := saveToken(c.cfg.CredentialsFile, email, token); err != nil {
+		return failed("Error: %v", err)
+	}
+	if userEmail, _, _ := c.state.session(); email == userEmail {
+		c.state.setToken(token) // Use the new token right away
+	}
+	return succeeded(nil, "Access token for %s saved to %s.", email, c.cfg.CredentialsFile)
+}
+
+func (c *cli) send(args []string) commandResult {
+	if len(args) < 3 || len(splitRecipients(args[0])) == 0 {
+		return failed("%s", sendUsage)
+	}
+	inline, path, hasFile := splitFileOption(args[2:])
+	if !hasFile {
+		return c.sendMail(splitRecipients(args[0]), args[1], strings.Join(inline, " "))
+	}
+	if path == "" {
+		return failed("%s", sendUsage)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return failed("Error reading mail body from '%s': %v", path, err)
+	}
+	r := c.sendMail(splitRecipients(args[0]), args[1], string(data))
+	if len(inline) > 0 {
+		r.Message = fmt.Sprintf("Warning: Both an inline body and --file were given, sent the body from '%s'.\n%s", path, r.Message)
+	}
+	return r
+}
+
+// sendUsage is the usage message of send.
+const sendUsage = "Usage: send <recipient_email>[,<recipient_email>...] <subject> <body_text>|--file <path>\n" +
+	"Example: send bob@saturn.com,carol@earth.com 'Meeting' \"Let's meet tomorrow.\""
+
+// splitFileOption separates a "--file <path>" option from the inline body words in args. hasFile reports
+// whether the option was given; path is empty if it lacks its argument.
+func splitFileOption(args []string) (inline []string, path string, hasFile bool) {
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--file" {
+			inline = append(inline, args[i])
+			continue
+		}
+		hasFile = true
+		if i+1 < len(args) {
+			path = args[i+1]
+			i++
+		}
+	}
+	return inline, path, hasFile
+}
+
+// sendMail sends a message from the logged-in user to recipients and reports the outcome.
+func (c *cli) sendMail(recipients []string, subject, body string) commandResult {
+	userEmail, _, _ := c.state.session()
+	resp, err := deliverMessage(c.cfg, &proto.MailMessage{
+		SenderEmail:    userEmail,
+		RecipientEmail: recipients[0],
+		To:             recipients[1:],
+		Subject:        subject,
+		Body:           body,
+		Timestamp:      time.Now().Unix(),
+	})
+	if err != nil {
+		return failed("Error sending mail: %v", err)
+	}
+	recipientList := strings.Join(recipients, ", ")
+	if !resp.GetSuccess() {
+		return failed("Failed to send mail to '%s': %s", recipientList, resp.GetMessage())
+	}
+	return succeeded(&sentMail{RecipientEmail: recipients[0], Recipients: recipients, MessageID: resp.GetMessageId()},
+		"Mail sent successfully to '%s': %s", recipientList, resp.GetMessage())
+}
+
+// splitRecipients splits a comma-separated list of recipients, dropping empty entries.
+func splitRecipients(list string) []string {
+	var recipients []string
+	for _, r := range strings.Split(list, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients
+}
+
+func (c *cli) get(args []string) commandResult {
+	userEmail, userMailbox, userToken := c.state.session()
+	messages, err := fetchMail(userEmail, userMailbox, userToken, c.cfg.TLS, args...)
+	if err != nil {
+		return failed("Error getting mail for '%s': %v", userEmail, err)
+	}
+	if messages == nil {
+		messages = []*proto.MailMessage{} // Always report a list, even an empty one
+	}
+	c.state.setLastMessages(messages, args)
+	if len(messages) == 0 {
+		return succeeded(messages, "No messages.")
+	}
+	return succeeded(messages, "Retrieved %d messages:", len(messages))
+}
+
+func (c *cli) delete(args []string) commandResult {
+	confirmed := len(args) == 2 && args[1] == "--yes"
+	if len(args) != 1 && !confirmed {
+		return failed("Usage: delete <message_number> [--yes]\nExample: delete 1")
+	}
+	msg, err := c.listedMessage(args[0])
+	if err != nil {
+		return failed("Error: %v", err)
+	}
+	if !confirmed {
+		if c.confirm == nil {
+			return failed("Error: Cannot ask for confirmation here, use 'delete %s --yes' to delete the message.", args[0])
+		}
+		if !c.confirm(fmt.Sprintf("Delete message %s from %s (Subject: %s)?", args[0], msg.SenderEmail, msg.Subject)) {
+			return failed("Message %s was not deleted.", args[0])
+		}
+	}
+
+	userEmail, userMailbox, userToken := c.state.session()
+	if err := deleteMail(userEmail, userMailbox, userToken, []*proto.MailMessage{msg}, c.cfg.TLS); err != nil {
+		return failed("Error deleting message %s: %v", args[0], err)
+	}
+	messages, err := fetchMail(userEmail, userMailbox, userToken, c.cfg.TLS, c.state.fetchedLabels()...)
+	if err != nil {
+		c.state.setLastMessages(nil, nil) // The numbers of the old listing are stale now
+		return succeeded(nil, "Deleted message %s, but could not refresh your mail: %v\nUse 'get' to list it again.", args[0], err)
+	}
+	if messages == nil {
+		messages = []*proto.MailMessage{}
+	}
+	c.state.setLastMessages(messages, c.state.fetchedLabels())
+	return succeeded(messages, "Deleted message %s. %d messages remain:", args[0], len(messages))
+}
+
+func (c *cli) reply(args []string) commandResult {
+	if len(args) < 2 {
+		return failed("Usage: reply <message_number> <body_text>\nExample: reply 1 'Sounds good.'")
+	}
+	msg, err := c.listedMessage(args[0])
+	if err != nil {
+		return failed("Error: %v", err)
+	}
+	return c.sendMail([]string{msg.SenderEmail}, prefixSubject("Re:", msg.Subject), strings.Join(args[1:], " "))
+}
+
+func (c *cli) forward(args []string) commandResult {
+	if len(args) != 2 || len(splitRecipients(args[1])) == 0 {
+		return failed("Usage: forward <message_number> <recipient_email>[,<recipient_email>...]\nExample: forward 1 carol@earth.com")
+	}
+	msg, err := c.listedMessage(args[0])
+	if err != nil {
+		return failed("Error: %v", err)
+	}
+	return c.sendMail(splitRecipients(args[1]), prefixSubject("Fwd:", msg.Subject), forwardedBody(msg))
+}
+
+// listedMessage returns the message numbered arg (counting from 1) in the listing of the last get.
+func (c *cli) listedMessage(arg string) (*proto.MailMessage, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a message number, use the number shown by 'get'", arg)
+	}
+	messages := c.state.fetchedMessages()
+	switch {
+	case messages == nil:
+		return nil, fmt.Errorf("no messages retrieved yet, use 'get' to retrieve your mail first")
+	case len(messages) == 0:
+		return nil, fmt.Errorf("the last 'get' found no messages")
+	case n < 1 || n > len(messages):
+		return nil, fmt.Errorf("there is no message %d, the last 'get' listed messages 1 to %d", n, len(messages))
+	}
+	return messages[n-1], nil
+}
+
+// prefixSubject returns subject prefixed with prefix (e.g. "Re:"), unless it already starts with it.
+func prefixSubject(prefix, subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), strings.ToLower(prefix)) {
+		return subject
+	}
+	return prefix + " " + subject
+}
+
+// forwardedBody returns the body of a message forwarding msg: the original body, headed by its sender,
+// date, subject and recipient.
+func forwardedBody(msg *proto.MailMessage) string {
+	var b strings.Builder
+	b.WriteString("---------- Forwarded message ----------\n")
+	fmt.Fprintf(&b, "From: %s\n", msg.SenderEmail)
+	fmt.Fprintf(&b, "Date: %s\n", formatSentTime(msg))
+	fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+	fmt.Fprintf(&b, "To: %s\n\n", msg.RecipientEmail)
+	b.WriteString(msg.Body)
+	return b.String()
+}
+
+func (c *cli) search(args []string) commandResult {
+	if len(args) == 0 {
+		return failed("Usage: search <term>\nExample: search invoice")
+	}
+	userEmail, userMailbox, userToken := c.state.session()
+	messages, err := searchMail(userEmail, userMailbox, userToken, strings.Join(args, " "), c.cfg.TLS)
+	if err != nil {
+		return failed("Error searching mail for '%s': %v", userEmail, err)
+	}
+	if messages == nil {
+		messages = []*proto.MailMessage{} // Always report a list, even an empty one
+	}
+	if len(messages) == 0 {
+		return succeeded(messages, "No matching messages.")
+	}
+	return succeeded(messages, "Found %d matching messages:", len(messages))
+}
+
+func (c *cli) tail(args []string) commandResult {
+	userEmail, userMailbox, userToken := c.state.session()
+	if !c.cfg.JSONOutput {
+		fmt.Fprintf(c.out, "Tailing mail for %s, press Enter to stop...\n", userEmail)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tailMail(ctx, userEmail, userMailbox, userToken, c.cfg.TLS, c.showIncoming()) }()
+	c.waitForEnter()
+	cancel()
+	if err := <-done; err != nil {
+		return failed("Error tailing mail: %v", err)
+	}
+	return succeeded(nil, "Stopped tailing mail.")
+}
+
+func (c *cli) watch(args []string) commandResult {
+	userEmail, userMailbox, userToken := c.state.session()
+	ctx, stop := c.untilInterrupt()
+	defer stop() // Ctrl-C quits the client again
+	if !c.cfg.JSONOutput {
+		fmt.Fprintf(c.out, "Watching mail for %s, press Ctrl-C to stop...\n", userEmail)
+	}
+	if err := watchMail(ctx, userEmail, userMailbox, userToken, c.cfg.TLS, c.showIncoming()); err != nil {
+		return failed("Error watching mail: %v", err)
+	}
+	return succeeded(nil, "Stopped watching mail.")
+}
+
+// showIncoming returns a function that writes each message arriving while tail or watch runs to c.out,
+// numbered from 1: as text, or in JSON mode as a result line of its own with the message as data.
+func (c *cli) showIncoming() func(msg *proto.MailMessage) {
+	n := 0
+	return func(msg *proto.MailMessage) {
+		n++
+		if c.cfg.JSONOutput {
+			renderJSON(c.out, succeeded(msg, "Incoming message %d", n))
+			return
+		}
+		printMessage(c.out, n, msg)
+	}
+}
+
+func (c *cli) selftest(args []string) commandResult {
+	userEmail, userMailbox, userToken := c.state.session()
+	result, err := SelfTest(c.cfg.TransferServerAddr, userEmail, userMailbox, userToken, c.cfg.TLS, selfTestTimeout)
+	if err != nil {
+		r := failed("Self-test FAILED: %v", err)
+		if result != nil && len(result.Other) > 0 {
+			r.Data = result // Still hand over mail retrieved while polling
+		}
+		return r
+	}
+	return succeeded(result, "Self-test OK: round trip took %s", result.RoundTrip)
+}
+
+func (c *cli) list(args []string) commandResult {
+	if len(args) != 1 {
+		return failed("Usage: list <domain>\nExample: list earth.com")
+	}
+	entries, err := listMailboxes(c.cfg.NameserverAddr, args[0], c.cfg.TLS)
+	if err != nil {
+		return failed("Error listing mailboxes for '%s': %v", args[0], err)
+	}
+	if entries == nil {
+		entries = []*proto.MailboxEntry{} // Always report a list, even an empty one
+	}
+	return succeeded(entries, "%d mailboxes registered for %s:", len(entries), args[0])
+}
+
+func (c *cli) status(args []string) commandResult {
+	return succeeded(FetchSystemStatus(c.cfg), "System status collected.")
+}
+
+func (c *cli) whoami(args []string) commandResult {
+	userEmail, userMailbox, _ := c.state.session()
+	if userEmail == "" {
+		return succeeded(nil, "Not logged in.")
+	}
+	return succeeded(&sessionInfo{EmailAddress: userEmail, MailboxAddress: userMailbox},
+		"Currently logged in as: %s (Mailbox: %s)", userEmail, userMailbox)
+}
+
+func (c *cli) help(args []string) commandResult {
+	return succeeded(nil, "%s", helpText())
+}
+
+func (c *cli) exit(args []string) commandResult {
+	if err := c.persistSession(); err != nil {
+		return succeeded(nil, "Warning: Could not save the session: %v\nExiting client.", err)
+	}
+	return succeeded(nil, "Exiting client.")
+}
+
+// renderText writes result to w for the interactive CLI: the message, followed by a human-readable
+// form of the data where there is one.
+func renderText(w io.Writer, result commandResult) {
+	switch data := result.Data.(type) {
+	case *SystemStatus:
+		data.Print(w) // The report speaks for itself
+		return
+	case *SelfTestResult:
+		if len(data.Other) > 0 {
+			fmt.Fprintf(w, "Retrieved %d other message(s) while waiting:\n", len(data.Other))
+			printMessages(w, data.Other)
+		}
+	}
+	fmt.Fprintln(w, result.Message)
+	switch data := result.Data.(type) {
+	case []*proto.MailMessage:
+		printMessages(w, data)
+	case []*proto.MailboxEntry:
+		printMailboxTable(w, data)
+	}
+}
+
+// printMailboxTable writes registrations to w as a table of email address, mailbox address and replicas.
+func printMailboxTable(w io.Writer, entries []*proto.MailboxEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EMAIL\tMAILBOX\tREPLICAS")
+	for _, e := range entries {
+		replicas := strings.Join(e.GetReplicaAddresses(), ", ")
+		if replicas == "" {
+			replicas = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.GetEmailAddress(), e.GetMailboxAddress(), replicas)
+	}
+	tw.Flush()
+}
+
+// renderJSON writes result to w as a single line of JSON.
+func renderJSON(w io.Writer, result commandResult) {
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.Error("Could not encode the command result", "service", "Client", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"GoDissys/common"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// loadCredentials reads the credentials file at path, a JSON object mapping email addresses to access tokens.
+// A missing file yields an empty set of credentials.
+func loadCredentials(path string) (map[string]string, error) {
+	credentials := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credentials file '%s': %w", path, err)
+	}
+	return credentials, nil
+}
+
+// loadToken returns the access token stored for emailAddress in the credentials file at path,
+// or an empty token if there is none.
+func loadToken(path, emailAddress string) (string, error) {
+	credentials, err := loadCredentials(path)
+	if err != nil {
+		return "", err
+	}
+	return credentials[emailAddress], nil
+}
+
+// saveToken stores token for emailAddress in the credentials file at path, keeping the tokens of other accounts.
+// The file is only readable by its owner.
+func saveToken(path, emailAddress, token string) error {
+	credentials, err := loadCredentials(path)
+	if err != nil {
+		return err
+	}
+	credentials[emailAddress] = token
+
+	data, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", path, err)
+	}
+	if err := common.WriteFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write credentials file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// withAuthToken attaches token to the outgoing metadata of ctx. An empty token leaves ctx unchanged.
+func withAuthToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, common.AuthTokenMetadataKey, "Bearer "+token)
+}
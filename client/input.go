@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	defaultMaxCommandLength = 4096    // Longest accepted command line when none is configured
+	maxScanBufferSize       = 1 << 20 // Longest line the CLI can read at all; longer input ends the CLI
+)
+
+// commandGuard rejects command lines that are too long and, in batch mode, commands arriving faster
+// than the configured rate, so pasted garbage or runaway scripts cannot flood the services.
+type commandGuard struct {
+	maxLength int // Maximum command length in bytes
+	perSecond int // Maximum commands per second in batch mode (0 = unlimited)
+	batch     bool
+
+	windowStart time.Time // Start of the current one-second window
+	inWindow    int       // Commands accepted in the current window
+}
+
+// newCommandGuard creates a guard from the CLI limits in cfg. batch enables the rate limit.
+func newCommandGuard(cfg Config, batch bool) *commandGuard {
+	maxLength := cfg.MaxCommandLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxCommandLength
+	}
+	return &commandGuard{maxLength: maxLength, perSecond: cfg.MaxCommandsPerSecond, batch: batch}
+}
+
+// check returns an error describing why line, read at now, must be rejected, or nil if it may run.
+func (g *commandGuard) check(line string, now time.Time) error {
+	if len(line) > g.maxLength {
+		return fmt.Errorf("command rejected: %d bytes exceeds the maximum command length of %d bytes", len(line), g.maxLength)
+	}
+	if !g.batch || g.perSecond <= 0 {
+		return nil
+	}
+	if now.Sub(g.windowStart) >= time.Second {
+		g.windowStart = now
+		g.inWindow = 0
+	}
+	if g.inWindow >= g.perSecond {
+		return fmt.Errorf("command rejected: more than %d commands per second in batch mode", g.perSecond)
+	}
+	g.inWindow++
+	return nil
+}
+
+// splitCommandLine splits a command line into arguments at whitespace, shell-style: text in single or double
+// quotes stays one argument, and a backslash escapes the next character. Inside quotes a backslash only
+// escapes the enclosing quote character and itself, so 'C:\dir' keeps its backslash. It fails on an
+// unterminated quote.
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool // Whether current holds an argument, possibly an empty quoted one
+		quote   rune // Enclosing quote character, 0 outside quotes
+		escaped bool // Whether the previous character was an escaping backslash
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote != 0 && r != quote && r != '\\' {
+				current.WriteRune('\\') // Not an escape inside quotes, keep the backslash
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in command")
+	}
+	if escaped {
+		current.WriteRune('\\') // A trailing backslash escapes nothing
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// stdinIsTerminal reports whether the CLI reads from an interactive terminal rather than a pipe or file.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// passwordPrompt returns a function that asks for a password on out and reads it from in, without echo if in
+// is a terminal and as the next line otherwise (readLine). Its second result is false if no input is left.
+func passwordPrompt(in io.Reader, out io.Writer, readLine func() (string, bool)) func(prompt string) (string, bool) {
+	return func(prompt string) (string, bool) {
+		fmt.Fprintf(out, "%s: ", prompt)
+		if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			password, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(out) // The user's Enter was not echoed
+			return string(password), err == nil
+		}
+		return readLine()
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"GoDissys/common"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSessionFile is the session file used when the configuration names none.
+const DefaultSessionFile = ".godissys_session.json"
+
+// loadSession reads the session saved at path. A missing file yields a nil session, i.e. nobody logged in.
+func loadSession(path string) (*sessionInfo, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file '%s': %w", path, err)
+	}
+	var session sessionInfo
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session file '%s': %w", path, err)
+	}
+	if session.EmailAddress == "" || session.MailboxAddress == "" {
+		return nil, fmt.Errorf("session file '%s' is incomplete", path)
+	}
+	return &session, nil
+}
+
+// saveSession stores session at path, or removes the file if session is nil. The access token is not
+// stored; it is reloaded from the credentials file when the session is restored.
+func saveSession(path string, session *sessionInfo) error {
+	if session == nil {
+		return removeSession(path)
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", path, err)
+	}
+	if err := common.WriteFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write session file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// removeSession deletes the session file at path. A missing file is not an error.
+func removeSession(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// restoreSession logs in the user of the session saved in cfg.SessionFile, if any, and returns a message
+// describing the outcome (empty if there was nothing to restore). A corrupt session file leaves the client
+// logged out.
+func (c *cli) restoreSession() string {
+	if c.cfg.SessionFile == "" {
+		return ""
+	}
+	session, err := loadSession(c.cfg.SessionFile)
+	if err != nil {
+		return fmt.Sprintf("Warning: Could not restore the previous session, starting logged out: %v", err)
+	}
+	if session == nil {
+		return ""
+	}
+	message := fmt.Sprintf("Restored session: logged in as %s", session.EmailAddress)
+	token := ""
+	if c.cfg.CredentialsFile != "" {
+		if token, err = loadToken(c.cfg.CredentialsFile, session.EmailAddress); err != nil {
+			message = fmt.Sprintf("Warning: Could not load access token: %v\n%s", err, message)
+		}
+	}
+	c.state.login(session.EmailAddress, session.MailboxAddress, token)
+	return message
+}
+
+// persistSession saves the current session to cfg.SessionFile, so the next start of the client is logged in
+// as the same user. If nobody is logged in, the file is removed.
+func (c *cli) persistSession() error {
+	if c.cfg.SessionFile == "" {
+		return nil
+	}
+	userEmail, userMailbox, _ := c.state.session()
+	if userEmail == "" {
+		return saveSession(c.cfg.SessionFile, nil)
+	}
+	return saveSession(c.cfg.SessionFile, &sessionInfo{EmailAddress: userEmail, MailboxAddress: userMailbox})
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const statusTimeout = 5 * time.Second // Per-service deadline when collecting system status
+
+// MailboxStatus is the status of one configured Mailbox. Err is set if it could not be queried.
+type MailboxStatus struct {
+	Domain string
+	Addr   string
+	Info   *proto.MailboxInfoResponse
+	Err    error
+}
+
+// SystemStatus is the combined status of all services. For each service either the info
+// or the error describing why it could not be queried is set.
+type SystemStatus struct {
+	Nameserver        *proto.NameserverInfoResponse
+	NameserverErr     error
+	Mailboxes         []MailboxStatus // Sorted by domain
+	TransferServer    *proto.TransferServerInfoResponse
+	TransferServerErr error
+}
+
+// FetchSystemStatus queries the Info RPCs of the Nameserver, every configured Mailbox and the
+// TransferServer concurrently and assembles the results into one report.
+func FetchSystemStatus(cfg Config) *SystemStatus {
+	st := &SystemStatus{}
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		st.NameserverErr = withConn(cfg.NameserverAddr, cfg.TLS, func(ctx context.Context, conn *grpc.ClientConn) (err error) {
+			st.Nameserver, err = proto.NewNameserverClient(conn).Info(ctx, &proto.NameserverInfoRequest{})
+			return err
+		})
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		st.TransferServerErr = withConn(cfg.TransferServerAddr, cfg.TLS, func(ctx context.Context, conn *grpc.ClientConn) (err error) {
+			st.TransferServer, err = proto.NewTransferServerClient(conn).Info(ctx, &proto.TransferServerInfoRequest{})
+			return err
+		})
+	}()
+
+	for domain, mb := range cfg.Mailboxes {
+		st.Mailboxes = append(st.Mailboxes, MailboxStatus{Domain: domain, Addr: mb.Addr})
+	}
+	sort.Slice(st.Mailboxes, func(i, j int) bool { return st.Mailboxes[i].Domain < st.Mailboxes[j].Domain })
+	for i := range st.Mailboxes {
+		wg.Add(1)
+		go func(mb *MailboxStatus) {
+			defer wg.Done()
+			mb.Err = withConn(mb.Addr, cfg.TLS, func(ctx context.Context, conn *grpc.ClientConn) (err error) {
+				mb.Info, err = proto.NewMailboxClient(conn).Info(ctx, &proto.MailboxInfoRequest{})
+				return err
+			})
+		}(&st.Mailboxes[i])
+	}
+
+	wg.Wait()
+	return st
+}
+
+// withConn dials addr as configured by tlsCfg and runs call with the connection and a request deadline.
+func withConn(addr string, tlsCfg *common.TLSConfig, call func(ctx context.Context, conn *grpc.ClientConn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	conn, err := dial(ctx, addr, tlsCfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	return call(ctx, conn)
+}
+
+// MarshalJSON encodes the status with errors as their messages, so failures survive the JSON output mode.
+func (st *SystemStatus) MarshalJSON() ([]byte, error) {
+	type mailboxJSON struct {
+		Domain string                     `json:"Domain"`
+		Addr   string                     `json:"Addr"`
+		Info   *proto.MailboxInfoResponse `json:"Info,omitempty"`
+		Err    string                     `json:"Err,omitempty"`
+	}
+	mailboxes := make([]mailboxJSON, 0, len(st.Mailboxes))
+	for _, mb := range st.Mailboxes {
+		mailboxes = append(mailboxes, mailboxJSON{Domain: mb.Domain, Addr: mb.Addr, Info: mb.Info, Err: errorString(mb.Err)})
+	}
+	return json.Marshal(struct {
+		Nameserver        *proto.NameserverInfoResponse     `json:"Nameserver,omitempty"`
+		NameserverErr     string                            `json:"NameserverErr,omitempty"`
+		Mailboxes         []mailboxJSON                     `json:"Mailboxes"`
+		TransferServer    *proto.TransferServerInfoResponse `json:"TransferServer,omitempty"`
+		TransferServerErr string                            `json:"TransferServerErr,omitempty"`
+	}{st.Nameserver, errorString(st.NameserverErr), mailboxes, st.TransferServer, errorString(st.TransferServerErr)})
+}
+
+// errorString returns the message of err, or an empty string if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Print writes a human-readable report of the system status to w.
+func (st *SystemStatus) Print(w io.Writer) {
+	fmt.Fprintln(w, "--- System Status ---")
+	if st.NameserverErr != nil {
+		fmt.Fprintf(w, "Nameserver: UNAVAILABLE (%v)\n", st.NameserverErr)
+	} else {
+		fmt.Fprintf(w, "Nameserver: %d registrations, managing %s\n",
+			st.Nameserver.GetRegistrations(), strings.Join(st.Nameserver.GetManagedDomains(), ", "))
+	}
+	for _, mb := range st.Mailboxes {
+		if mb.Err != nil {
+			fmt.Fprintf(w, "Mailbox %s (%s): UNAVAILABLE (%v)\n", mb.Domain, mb.Addr, mb.Err)
+			continue
+		}
+		fmt.Fprintf(w, "Mailbox %s (%s): %d users, %d messages, %d in trash\n",
+			mb.Domain, mb.Addr, mb.Info.GetUsers(), mb.Info.GetMessages(), mb.Info.GetTrashed())
+	}
+	if st.TransferServerErr != nil {
+		fmt.Fprintf(w, "TransferServer: UNAVAILABLE (%v)\n", st.TransferServerErr)
+	} else {
+		ts := st.TransferServer
+		mode := "synchronous"
+		if ts.GetAsyncDelivery() {
+			mode = fmt.Sprintf("asynchronous, %d queued, %d in flight", ts.GetQueue().GetQueued(), ts.GetQueue().GetInFlight())
+			if ts.GetQueue().GetPaused() {
+				mode += ", paused"
+			}
+		}
+		fmt.Fprintf(w, "TransferServer: %s delivery; %d messages accepted, %d deliveries succeeded, %d failed\n",
+			mode, ts.GetMessagesAccepted(), ts.GetDeliveriesSucceeded(), ts.GetDeliveriesFailed())
+	}
+	fmt.Fprintln(w, "---------------------")
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator decides whether the caller of an RPC may act on behalf of an email address.
+// Implementations typically read the caller's token with AuthToken.
+type Authenticator interface {
+	// ValidateToken returns nil if the caller in ctx is allowed to access emailAddress.
+	ValidateToken(ctx context.Context, emailAddress string) error
+}
+
+// NoopAuthenticator allows every request. It is the default, keeping services open.
+type NoopAuthenticator struct{}
+
+// ValidateToken implements Authenticator.
+func (NoopAuthenticator) ValidateToken(ctx context.Context, emailAddress string) error {
+	return nil
+}
+
+// AuthToken returns the access token sent by the caller in the incoming metadata of ctx,
+// without its "Bearer " prefix. It returns an empty string if no token was sent.
+func AuthToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(AuthTokenMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
+
+// AuthUnaryInterceptor returns a server interceptor that calls auth for the given full method names
+// with the email address of the request. Other methods pass through unchecked. An error from auth
+// that does not carry a gRPC status is reported as Unauthenticated.
+func AuthUnaryInterceptor(auth Authenticator, methods ...string) grpc.UnaryServerInterceptor {
+	protected := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		protected[m] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !protected[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		var emailAddress string
+		if r, ok := req.(interface{ GetEmailAddress() string }); ok {
+			emailAddress = r.GetEmailAddress()
+		}
+		if err := Authorize(ctx, auth, emailAddress); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Authorize calls auth for emailAddress and turns an error that does not carry a gRPC status into
+// Unauthenticated. Streaming RPCs, whose request the interceptor cannot see, call it themselves.
+func Authorize(ctx context.Context, auth Authenticator, emailAddress string) error {
+	if err := auth.ValidateToken(ctx, emailAddress); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return nil
+}
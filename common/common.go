@@ -1,15 +1,236 @@
 package common
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 )
 
+// Overflow policies for a full per-user inbox.
+const (
+	OverflowReject     = "reject"      // Reject the incoming message (default)
+	OverflowDropOldest = "drop_oldest" // Evict the oldest message to make room for the new one
+)
+
+// AuthTokenMetadataKey is the gRPC metadata key carrying a client's access token.
+const AuthTokenMetadataKey = "authorization"
+
+// Duration is a time.Duration that is written in JSON as a Go duration string such as "30s" or "24h".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string (e.g. "1h30m").
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
 // MailboxConfig holds configuration for a specific mailbox instance
 type MailboxConfig struct {
 	Domain string `json:"Domain"`
 	Addr   string `json:"Addr"`
+
+	// TransferServerAddr is where the Mailbox sends read receipts (empty disables read receipts).
+	TransferServerAddr string `json:"TransferServerAddr"`
+	// TLS secures the Mailbox's server and its connections to other services (nil uses plaintext).
+	TLS *TLSConfig `json:"TLS"`
+
+	// MaxMessagesPerUser caps how many messages a single recipient can hold (0 means unlimited).
+	MaxMessagesPerUser int `json:"MaxMessagesPerUser"`
+	// MaxBytesPerUser caps the total encoded size of the messages a single recipient can hold (0 means unlimited).
+	MaxBytesPerUser int64 `json:"MaxBytesPerUser"`
+	// MaxBodyBytes rejects messages whose body is larger than this many bytes (0 means unlimited).
+	MaxBodyBytes int `json:"MaxBodyBytes"`
+	// OverflowPolicy decides what happens when a full inbox receives mail ("reject" or "drop_oldest").
+	OverflowPolicy string `json:"OverflowPolicy"`
+	// RetainOnGet keeps messages in the inbox after GetMail instead of clearing them.
+	RetainOnGet bool `json:"RetainOnGet"`
+	// Capacity is the total number of messages this Mailbox is sized for (0 = unlimited). It is advertised with
+	// the remaining space in Info, so TransferServers can prefer the emptiest replica; it is not enforced.
+	Capacity int `json:"Capacity"`
+	// BlockedSenders lists sender addresses whose mail is rejected by ReceiveMail.
+	BlockedSenders []string `json:"BlockedSenders"`
+	// TrashRetention moves messages retrieved by GetMail to a per-user trash for this long,
+	// so they can be restored with UndeleteMail (0 discards them immediately).
+	TrashRetention Duration `json:"TrashRetention"`
+	// ClearGracePeriod keeps messages cleared by GetMail recoverable with UndeleteMail for at least this
+	// long, even without a trash retention, so a client crashing right after GetMail loses nothing.
+	ClearGracePeriod Duration `json:"ClearGracePeriod"`
+
+	// StateDir is the directory where inboxes are persisted (empty keeps mail in memory only).
+	StateDir string `json:"StateDir"`
+	// InstanceName prefixes every state file so several instances can share one StateDir.
+	InstanceName string `json:"InstanceName"`
+	// MinFreeDiskBytes rejects new mail while less disk space is free in StateDir (0 disables the check).
+	MinFreeDiskBytes uint64 `json:"MinFreeDiskBytes"`
+	// PersistInterval coalesces state writes over this interval instead of writing on every change
+	// (0 writes immediately). Changes within the interval are lost on a crash, but not on shutdown.
+	PersistInterval Duration `json:"PersistInterval"`
+	// MaxPendingWrites forces a write once this many changes are unsaved during coalescing (0 uses the default).
+	MaxPendingWrites int `json:"MaxPendingWrites"`
+	// EncryptionKey is a base64-encoded AES key (16, 24 or 32 bytes) used to encrypt message bodies in the
+	// state file with AES-GCM; sender, recipients and subject stay readable. EncryptionKeyEnv names an
+	// environment variable holding the key instead, which takes precedence. Both empty stores bodies in plaintext.
+	EncryptionKey    string `json:"EncryptionKey"`
+	EncryptionKeyEnv string `json:"EncryptionKeyEnv"`
+	// LogRequests logs every RPC the Mailbox serves with its method, peer, duration and status code.
+	LogRequests bool `json:"LogRequests"`
+	// AllowPasswordless lets users who have not set a password access their mail without any credential. By
+	// default their mail is inaccessible until a password is set, unless an authenticator is configured (see
+	// mailbox.StartMailboxWithAuthenticator), which then checks them. Users with a password always need it.
+	AllowPasswordless bool `json:"AllowPasswordless"`
+	// AdminToken is the bearer token of administrators: it authorizes ExportMailbox, ImportMailbox and setting or
+	// resetting any user's password (empty disables administrative access).
+	AdminToken string `json:"AdminToken"`
+	// MailDomain is the email domain whose addresses this Mailbox serves, e.g. "earth.com" (empty uses Domain).
+	// Passwords and imported mail for addresses of other domains are rejected.
+	MailDomain string `json:"MailDomain"`
+	// NameserverAddr, if set, is asked whether an address is registered before its first password is set.
+	NameserverAddr string `json:"NameserverAddr"`
+}
+
+// Policies for sender verification when the Nameserver cannot be reached.
+const (
+	SenderVerificationFailClosed = "fail_closed" // Reject the mail (default)
+	SenderVerificationFailOpen   = "fail_open"   // Accept the mail without verification
+)
+
+// Policies for recipients the Nameserver does not know.
+const (
+	RecipientNotFoundBounce = "bounce" // Fail the delivery immediately (default)
+	RecipientNotFoundRetry  = "retry"  // Keep the mail queued and re-resolve the recipient until a TTL expires
+)
+
+// Targets of an address rewrite rule.
+const (
+	RewriteSender    = "sender"
+	RewriteRecipient = "recipient"
+	RewriteBoth      = "both"
+)
+
+// RewriteRule canonicalizes sender and/or recipient addresses before lookup and delivery.
+type RewriteRule struct {
+	// Apply selects which addresses the rule rewrites: "sender", "recipient" or "both".
+	Apply string `json:"Apply"`
+	// StripPlusTag removes a "+tag" suffix from the local part (alice+news@earth.com becomes alice@earth.com).
+	StripPlusTag bool `json:"StripPlusTag"`
+	// FromDomain, if set, is replaced by ToDomain (e.g. earth.com becomes mail.earth.com).
+	FromDomain string `json:"FromDomain"`
+	ToDomain   string `json:"ToDomain"`
+}
+
+// RetryPolicy tunes how often and how fast the TransferServer retries a failed delivery. Zero values use the
+// defaults: 3 retries, waiting 500ms before the first and doubling the wait up to 5s. MaxRetries is a pointer
+// so that an explicit 0 disables retries, while leaving it out keeps the default.
+type RetryPolicy struct {
+	MaxRetries     *int     `json:"MaxRetries"`
+	InitialBackoff Duration `json:"InitialBackoff"`
+	MaxBackoff     Duration `json:"MaxBackoff"`
+}
+
+// TransferServerConfig holds optional settings for the TransferServer
+type TransferServerConfig struct {
+	// TLS secures the TransferServer's server and its connections to other services (nil uses plaintext).
+	TLS *TLSConfig `json:"TLS"`
+	// LogRequests logs every RPC the TransferServer serves with its method, peer, duration and status code.
+	LogRequests bool `json:"LogRequests"`
+	// StateDir is the directory for on-disk state such as delivery reports (empty keeps state in memory only).
+	StateDir string `json:"StateDir"`
+	// InstanceName prefixes every state file so several instances can share one StateDir.
+	InstanceName string `json:"InstanceName"`
+	// MinFreeDiskBytes rejects new mail while less disk space is free in StateDir (0 disables the check).
+	MinFreeDiskBytes uint64 `json:"MinFreeDiskBytes"`
+	// SelfAddr is an additional address of this TransferServer (e.g. behind a proxy); mail resolved
+	// to it, or to the listen address, is refused to avoid delivery loops.
+	SelfAddr string `json:"SelfAddr"`
+	// AsyncDelivery makes SendMail queue mail and return immediately; delivery happens in the background.
+	AsyncDelivery bool `json:"AsyncDelivery"`
+	// QueueDrainTimeout bounds the final delivery pass over queued mail on shutdown when StateDir is
+	// empty (0 uses the default of 10s). With a StateDir the queue is persisted instead.
+	QueueDrainTimeout Duration `json:"QueueDrainTimeout"`
+	// Retry tunes the retries of failed deliveries, both for SendMail and the delivery queue.
+	Retry RetryPolicy `json:"Retry"`
+	// DeadLetterRetryInterval re-attempts every dead letter (a delivery that failed for good) this often
+	// in the background (0 disables it; dead letters can still be re-driven with RetryDeadLetter).
+	DeadLetterRetryInterval Duration `json:"DeadLetterRetryInterval"`
+	// DeadLetterMaxAttempts and DeadLetterMaxAge stop the background retries of a dead letter once it has been
+	// re-attempted that often or is that old (0 uses the defaults of 10 attempts and 72h). It stays listed and
+	// can still be re-driven with RetryDeadLetter.
+	DeadLetterMaxAttempts int      `json:"DeadLetterMaxAttempts"`
+	DeadLetterMaxAge      Duration `json:"DeadLetterMaxAge"`
+	// RewriteRules are applied in order to sender and recipient addresses before lookup.
+	RewriteRules []RewriteRule `json:"RewriteRules"`
+	// Size limits in bytes, enforced before relay (0 = unlimited). MaxAttachmentBytes applies to the total of all
+	// attachments; MaxMessageBytes is a single budget over subject, body and attachments. Whichever is stricter wins.
+	MaxSubjectBytes    int `json:"MaxSubjectBytes"`
+	MaxBodyBytes       int `json:"MaxBodyBytes"`
+	MaxAttachmentBytes int `json:"MaxAttachmentBytes"`
+	MaxMessageBytes    int `json:"MaxMessageBytes"`
+	// DailySenderQuota caps the messages each sender may send per (UTC) day (0 = unlimited).
+	DailySenderQuota int `json:"DailySenderQuota"`
+	// SenderRateLimit caps the messages per second each sender may send on average (0 = unlimited), allowing
+	// bursts of up to SenderRateBurst messages (0 uses the rate rounded up, at least 1).
+	SenderRateLimit float64 `json:"SenderRateLimit"`
+	SenderRateBurst int     `json:"SenderRateBurst"`
+	// Bounces sends a bounce notification to the sender when delivery to a recipient finally fails.
+	Bounces bool `json:"Bounces"`
+	// BounceIncludeOriginal echoes the original subject, body and attachments in bounces instead of only a summary.
+	BounceIncludeOriginal bool `json:"BounceIncludeOriginal"`
+	// PostmasterAddress is the sender of bounces (default "postmaster@<sender's domain>").
+	PostmasterAddress string `json:"PostmasterAddress"`
+	// VerifySenders rejects mail whose sender is not registered with the Nameserver.
+	VerifySenders bool `json:"VerifySenders"`
+	// SenderVerificationPolicy decides what happens when the Nameserver is unreachable during
+	// sender verification: "fail_closed" (default) or "fail_open". A sender whose domain no Nameserver
+	// manages is a definite answer, not an outage, and is rejected under either policy.
+	SenderVerificationPolicy string `json:"SenderVerificationPolicy"`
+	// RecipientNotFoundPolicy decides what happens to queued mail whose recipient is not registered:
+	// "bounce" (default) fails it immediately, "retry" keeps re-resolving the recipient every
+	// RecipientNotFoundRetryInterval (0 uses the default of 30s) for up to RecipientNotFoundTTL
+	// (0 uses the default of 10m) before failing. "retry" requires AsyncDelivery. A recipient whose domain
+	// no Nameserver manages fails immediately under either policy, since registering the user cannot fix it.
+	RecipientNotFoundPolicy        string   `json:"RecipientNotFoundPolicy"`
+	RecipientNotFoundTTL           Duration `json:"RecipientNotFoundTTL"`
+	RecipientNotFoundRetryInterval Duration `json:"RecipientNotFoundRetryInterval"`
+	// LookupCacheTTL is how long the mailbox address resolved for a recipient is reused without asking the
+	// Nameserver again (0 disables the cache). An address whose Mailbox cannot be reached is looked up afresh.
+	LookupCacheTTL Duration `json:"LookupCacheTTL"`
+	// CircuitBreakerThreshold opens the circuit breaker of a mailbox address after this many consecutive
+	// failures to reach it (0 disables circuit breakers). While open, deliveries to the address fail at once;
+	// after CircuitBreakerCooldown (0 uses the default of 30s) a single probe delivery is let through.
+	CircuitBreakerThreshold int      `json:"CircuitBreakerThreshold"`
+	CircuitBreakerCooldown  Duration `json:"CircuitBreakerCooldown"`
+	// PreDeliveryCheck asks the recipient's Mailbox with CanAccept before sending the message, so
+	// refused mail fails fast (or is retried later) without transferring the payload.
+	PreDeliveryCheck bool `json:"PreDeliveryCheck"`
+	// NormalizeRecipients canonicalizes the recipients of SendMail (case, display names) and delivers one copy
+	// per distinct address, while still reporting every recipient entry.
+	NormalizeRecipients bool `json:"NormalizeRecipients"`
+	// Compression gzip-compresses deliveries to Mailboxes whose encoded size is at least CompressionMinBytes
+	// (0 uses the default of 1024 bytes); smaller messages are sent uncompressed.
+	Compression         bool `json:"Compression"`
+	CompressionMinBytes int  `json:"CompressionMinBytes"`
+	// WebhookURL receives an HTTP POST with a JSON delivery event for every final delivery outcome (empty disables it).
+	// Failed calls are retried WebhookRetries times (0 uses the default of 3), starting after WebhookRetryBackoff
+	// (0 uses the default of 500ms) and doubling each time.
+	WebhookURL          string   `json:"WebhookURL"`
+	WebhookRetries      int      `json:"WebhookRetries"`
+	WebhookRetryBackoff Duration `json:"WebhookRetryBackoff"`
 }
 
 // Config holds the entire application configuration
@@ -17,7 +238,42 @@ type Config struct {
 	NameserverAddr           string                   `json:"NameserverAddr"`
 	TransferServerAddr       string                   `json:"TransferServerAddr"`
 	Mailboxes                map[string]MailboxConfig `json:"Mailboxes"`
+	TransferServer           TransferServerConfig     `json:"TransferServer"`
 	NameserverManagedDomains []string                 `json:"NameserverManagedDomains"`
+	// NameserverReferrals maps domains managed elsewhere to the address of their Nameserver.
+	NameserverReferrals map[string]string `json:"NameserverReferrals"`
+	// NameserverStateDir is the directory the Nameserver persists its registrations to (empty keeps them in
+	// memory only).
+	NameserverStateDir string `json:"NameserverStateDir"`
+	// NameserverInstanceName prefixes the Nameserver's state file so several instances can share one state directory.
+	NameserverInstanceName string `json:"NameserverInstanceName"`
+	// LogFormat selects the log output format: "text" or "json" (empty keeps the standard log format).
+	LogFormat string `json:"LogFormat"`
+	// MetricsAddr is where an HTTP server exposes the Prometheus metrics of all services at /metrics
+	// (empty disables it).
+	MetricsAddr string `json:"MetricsAddr"`
+	// LogRequests logs every RPC served by the Nameserver, and by the Mailboxes and the TransferServer in
+	// addition to their own setting, with its method, peer, duration and status code.
+	LogRequests bool `json:"LogRequests"`
+	// Tracing configures the export of OpenTelemetry traces of the send path (nil disables tracing).
+	Tracing *TracingConfig `json:"Tracing"`
+	// AdminToken is the admin token of the Mailboxes without their own, which the client's signup sends to set
+	// the first password of a new user. Empty generates a random token shared by the services and the CLI of
+	// this process; one-shot commands then cannot set passwords.
+	AdminToken string `json:"AdminToken"`
+	// CredentialsFile is the client's JSON file of access tokens keyed by email address.
+	CredentialsFile string `json:"CredentialsFile"`
+	// SessionFile is where the client keeps the logged-in user across restarts (empty uses the client default).
+	SessionFile string `json:"SessionFile"`
+	// InProcessDelivery lets the in-process client deliver directly to Mailboxes running in the same process.
+	InProcessDelivery bool `json:"InProcessDelivery"`
+	// CLIMaxCommandLength rejects longer client command lines (0 uses the client default).
+	CLIMaxCommandLength int `json:"CLIMaxCommandLength"`
+	// CLIMaxCommandsPerSecond rate-limits client commands read in batch mode (0 means unlimited).
+	CLIMaxCommandsPerSecond int `json:"CLIMaxCommandsPerSecond"`
+	// TLS secures every server and connection (nil uses plaintext). Mailboxes and the TransferServer use
+	// their own TLS section instead, if they have one.
+	TLS *TLSConfig `json:"TLS"`
 }
 
 // LoadConfig reads the configuration from a JSON file.
@@ -34,3 +290,42 @@ func LoadConfig(filePath string) (*Config, error) {
 
 	return &cfg, nil
 }
+
+// NewMessageID returns a random (version 4) UUID used to identify a mail message.
+func NewMessageID() string {
+	var b [16]byte
+	rand.Read(b[:])             // crypto/rand.Read never returns an error
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// FormatSentAt formats t as the sent_at of a mail message: RFC 3339 with fractional seconds and time zone.
+func FormatSentAt(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// SentTime returns the time a mail message was sent: its sent_at if that is set and valid, otherwise its
+// Unix timestamp in seconds. The zero time means neither is set.
+func SentTime(sentAt string, timestamp int64) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, sentAt); sentAt != "" && err == nil {
+		return t
+	}
+	if timestamp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0)
+}
+
+// StatePath returns the path of the state file name inside stateDir, prefixed with
+// instanceName (if set) so that several instances can share one directory.
+// It returns an empty path when stateDir is empty, meaning state is kept in memory only.
+func StatePath(stateDir, instanceName, name string) string {
+	if stateDir == "" {
+		return ""
+	}
+	if instanceName != "" {
+		name = instanceName + "-" + name
+	}
+	return filepath.Join(stateDir, name)
+}
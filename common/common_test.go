@@ -0,0 +1,117 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSetupLogging_JSON tests that the JSON log format produces parseable JSON lines.
+func TestSetupLogging_JSON(t *testing.T) {
+	prevLogger, prevFlags := slog.Default(), log.Flags()
+	defer func() {
+		slog.SetDefault(prevLogger)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(prevFlags)
+	}()
+
+	var buf bytes.Buffer
+	if err := SetupLogging(LogFormatJSON, &buf); err != nil {
+		t.Fatalf("SetupLogging failed: %v", err)
+	}
+	log.Printf("Mailbox '%s' listening on %s", "earth", "localhost:50054")
+	slog.Info("structured message", "domain", "earth.com")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 { // Setup confirmation + two sample lines
+		t.Fatalf("Expected 3 log lines, got %d: %q", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Log line is not valid JSON: %q (%v)", line, err)
+		}
+		if _, ok := entry["msg"]; !ok {
+			t.Errorf("Log line is missing the 'msg' key: %q", line)
+		}
+	}
+	var last map[string]any
+	json.Unmarshal([]byte(lines[2]), &last)
+	if last["domain"] != "earth.com" {
+		t.Errorf("Expected structured attribute domain=earth.com, got %v", last["domain"])
+	}
+}
+
+// TestSetupLogging_UnknownFormat tests that an unknown log format is rejected.
+func TestSetupLogging_UnknownFormat(t *testing.T) {
+	if err := SetupLogging("xml", &bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error for unknown log format, got nil")
+	}
+}
+
+// TestStatePath tests that state file paths incorporate the instance name.
+func TestStatePath(t *testing.T) {
+	tests := []struct {
+		dir, instance, name, want string
+	}{
+		{"", "east", "state.json", ""},
+		{"/var/lib/mail", "", "state.json", filepath.Join("/var/lib/mail", "state.json")},
+		{"/var/lib/mail", "east", "state.json", filepath.Join("/var/lib/mail", "east-state.json")},
+	}
+	for _, tc := range tests {
+		if got := StatePath(tc.dir, tc.instance, tc.name); got != tc.want {
+			t.Errorf("StatePath(%q, %q, %q) = %q, want %q", tc.dir, tc.instance, tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestParseEmail tests that addresses are normalized and malformed addresses are rejected.
+func TestParseEmail(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"alice@earth.com", "alice@earth.com", false},
+		{"  Alice@Earth.COM ", "alice@earth.com", false},
+		{"Alice <alice@earth.com>", "alice@earth.com", false},
+		{"alice", "", true},
+		{"@earth.com", "", true},
+		{"", "", true},
+	}
+	for _, tc := range tests {
+		got, err := ParseEmail(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseEmail(%q) = %q, %v; want %q (error %v)", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+// TestSentTime tests that the sent time is taken from sent_at and falls back to the Unix timestamp when
+// sent_at is missing or malformed.
+func TestSentTime(t *testing.T) {
+	sent := time.Date(2024, 5, 1, 11, 30, 0, 250_000_000, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name      string
+		sentAt    string
+		timestamp int64
+		want      time.Time
+	}{
+		{"SentAt", FormatSentAt(sent), sent.Unix(), sent},
+		{"TimestampOnly", "", sent.Unix(), time.Unix(sent.Unix(), 0)},
+		{"InvalidSentAt", "yesterday", sent.Unix(), time.Unix(sent.Unix(), 0)},
+		{"Neither", "", 0, time.Time{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SentTime(tc.sentAt, tc.timestamp); !got.Equal(tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
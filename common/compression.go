@@ -0,0 +1,33 @@
+package common
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor for clients and servers
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultCompressionMinBytes is the request size below which compression is skipped if no threshold is configured.
+const DefaultCompressionMinBytes = 1024
+
+// CompressionPolicy decides per RPC whether a request is sent gzip-compressed. Small requests are sent
+// uncompressed, since compressing them costs more CPU than it saves bandwidth.
+type CompressionPolicy struct {
+	Enabled  bool
+	MinBytes int // Requests smaller than this are not compressed (0 uses DefaultCompressionMinBytes)
+}
+
+// CallOptions returns the call options for sending req: gzip compression if the policy is enabled and
+// the encoded size of req reaches the threshold, none otherwise.
+func (p CompressionPolicy) CallOptions(req proto.Message) []grpc.CallOption {
+	if !p.Enabled {
+		return nil
+	}
+	minBytes := p.MinBytes
+	if minBytes <= 0 {
+		minBytes = DefaultCompressionMinBytes
+	}
+	if proto.Size(req) < minBytes {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(gzip.Name)}
+}
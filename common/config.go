@@ -0,0 +1,158 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment variables overriding addresses and the admin token from the config file.
+const (
+	EnvNameserverAddr     = "GODISSYS_NAMESERVER_ADDR"
+	EnvTransferServerAddr = "GODISSYS_TRANSFER_SERVER_ADDR"
+	EnvAdminToken         = "GODISSYS_ADMIN_TOKEN"
+)
+
+// LoadConfigWithEnv reads the configuration from a JSON file like LoadConfig and then applies the overrides
+// from the environment (see ApplyEnv), which take precedence over the file.
+func LoadConfigWithEnv(filePath string) (*Config, error) {
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ApplyEnv(os.LookupEnv)
+	return cfg, nil
+}
+
+// ApplyEnv overrides addresses in cfg with the non-empty environment variables found by lookup:
+// GODISSYS_NAMESERVER_ADDR, GODISSYS_TRANSFER_SERVER_ADDR and, for each configured Mailbox,
+// MailboxAddrEnv(domain), e.g. GODISSYS_MAILBOX_EARTH_COM_ADDR for earth.com. GODISSYS_ADMIN_TOKEN overrides
+// the AdminToken, which is thereby kept out of the configuration file.
+func (cfg *Config) ApplyEnv(lookup func(key string) (string, bool)) {
+	override := func(key string, field *string) {
+		if value, ok := lookup(key); ok && value != "" {
+			*field = value
+		}
+	}
+	override(EnvNameserverAddr, &cfg.NameserverAddr)
+	override(EnvTransferServerAddr, &cfg.TransferServerAddr)
+	override(EnvAdminToken, &cfg.AdminToken)
+	for domain, mb := range cfg.Mailboxes {
+		override(MailboxAddrEnv(domain), &mb.Addr)
+		cfg.Mailboxes[domain] = mb
+	}
+}
+
+// MailboxAddrEnv returns the name of the environment variable overriding the address of the Mailbox for
+// domain: the domain upper-cased, with every character other than a letter or digit replaced by '_'. Distinct
+// domains such as my-domain.com and my.domain.com share a name; Validate rejects configs where that happens.
+func MailboxAddrEnv(domain string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(domain))
+	return "GODISSYS_MAILBOX_" + name + "_ADDR"
+}
+
+// Validate checks that cfg can run the services: the Nameserver and TransferServer addresses are set, there is
+// at least one Mailbox, every managed domain has a Mailbox entry, no two Mailboxes share an address override
+// variable (see MailboxAddrEnv), all addresses have the form host:port, every TLS section can serve, and the
+// Tracing section names a known exporter.
+// It reports every problem found, each naming the offending config.json field.
+func (cfg *Config) Validate() error {
+	var problems []error
+	checkAddr := func(field, addr string, required bool) {
+		if addr == "" {
+			if required {
+				problems = append(problems, fmt.Errorf("%s is required (e.g. \"localhost:50051\")", field))
+			}
+			return
+		}
+		if err := ValidateAddr(addr); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	checkTLS := func(field string, tlsCfg *TLSConfig) {
+		if tlsCfg == nil {
+			return
+		}
+		if err := tlsCfg.validateServer(); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+
+	checkAddr("NameserverAddr", cfg.NameserverAddr, true)
+	checkAddr("TransferServerAddr", cfg.TransferServerAddr, true)
+	checkAddr("MetricsAddr", cfg.MetricsAddr, false)
+
+	if len(cfg.Mailboxes) == 0 {
+		problems = append(problems, errors.New("Mailboxes is empty, configure at least one Mailbox"))
+	}
+	envDomains := make(map[string]string) // MailboxAddrEnv name -> domain, as two domains may map to the same name
+	for _, domain := range sortedKeys(cfg.Mailboxes) {
+		mb := cfg.Mailboxes[domain]
+		field := fmt.Sprintf("Mailboxes[%q]", domain)
+		if mb.Domain == "" {
+			problems = append(problems, fmt.Errorf("%s.Domain is required (the alias used by signup, e.g. \"earth\")", field))
+		}
+		checkAddr(field+".Addr", mb.Addr, true)
+		checkAddr(field+".TransferServerAddr", mb.TransferServerAddr, false)
+		checkTLS(field+".TLS", mb.TLS)
+		if other, ok := envDomains[MailboxAddrEnv(domain)]; ok {
+			problems = append(problems, fmt.Errorf("%s: the address override %s is shared with Mailboxes[%q], rename one of the domains",
+				field, MailboxAddrEnv(domain), other))
+		} else {
+			envDomains[MailboxAddrEnv(domain)] = domain
+		}
+	}
+	checkTLS("TLS", cfg.TLS)
+	checkTLS("TransferServer.TLS", cfg.TransferServer.TLS)
+	if cfg.Tracing != nil {
+		if err := cfg.Tracing.validate(); err != nil {
+			problems = append(problems, fmt.Errorf("Tracing: %w", err))
+		}
+	}
+
+	for _, domain := range cfg.NameserverManagedDomains {
+		if _, ok := cfg.Mailboxes[domain]; !ok {
+			problems = append(problems, fmt.Errorf("NameserverManagedDomains: domain %q has no entry in Mailboxes", domain))
+		}
+	}
+	for _, domain := range sortedKeys(cfg.NameserverReferrals) {
+		checkAddr(fmt.Sprintf("NameserverReferrals[%q]", domain), cfg.NameserverReferrals[domain], true)
+	}
+
+	return errors.Join(problems...)
+}
+
+// ValidateAddr checks that addr has the form host:port with a port between 1 and 65535. The host may be
+// empty, meaning all interfaces.
+func ValidateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s', expected host:port: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid address '%s': port must be a number between 1 and 65535", addr)
+	}
+	if strings.ContainsAny(addr, " \t") {
+		return fmt.Errorf("invalid address '%s': contains whitespace", addr)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in ascending order, so problems are reported in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// validConfig returns a minimal configuration that passes Validate.
+func validConfig() *Config {
+	return &Config{
+		NameserverAddr:     "localhost:50051",
+		TransferServerAddr: "localhost:50053",
+		Mailboxes: map[string]MailboxConfig{
+			"earth.com":  {Domain: "earth", Addr: "localhost:50054"},
+			"saturn.com": {Domain: "saturn", Addr: ":50055"},
+		},
+		NameserverManagedDomains: []string{"earth.com", "saturn.com"},
+	}
+}
+
+// TestConfig_Validate tests that invalid configurations are rejected with errors naming the offending field.
+func TestConfig_Validate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		if err := validConfig().Validate(); err != nil {
+			t.Errorf("Expected a valid config, got %v", err)
+		}
+	})
+	t.Run("ShippedConfig", func(t *testing.T) {
+		cfg, err := LoadConfig("../config.json")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected config.json to be valid, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   []string // Substrings expected in the error
+	}{
+		{"MissingNameserverAddr", func(cfg *Config) { cfg.NameserverAddr = "" }, []string{"NameserverAddr is required"}},
+		{"NoPort", func(cfg *Config) { cfg.TransferServerAddr = "localhost" }, []string{"TransferServerAddr: invalid address 'localhost'"}},
+		{"BadPort", func(cfg *Config) { cfg.NameserverAddr = "localhost:99999" }, []string{"NameserverAddr", "port must be"}},
+		{"NoMailboxes", func(cfg *Config) { cfg.Mailboxes = nil }, []string{"Mailboxes is empty", `domain "earth.com" has no entry`}},
+		{"MailboxWithoutAddr", func(cfg *Config) {
+			cfg.Mailboxes["earth.com"] = MailboxConfig{Domain: "earth"}
+		}, []string{`Mailboxes["earth.com"].Addr is required`}},
+		{"MailboxWithoutAlias", func(cfg *Config) {
+			cfg.Mailboxes["earth.com"] = MailboxConfig{Addr: "localhost:50054"}
+		}, []string{`Mailboxes["earth.com"].Domain is required`}},
+		{"BadReceiptAddr", func(cfg *Config) {
+			cfg.Mailboxes["earth.com"] = MailboxConfig{Domain: "earth", Addr: "localhost:50054", TransferServerAddr: "host:port"}
+		}, []string{`Mailboxes["earth.com"].TransferServerAddr`}},
+		{"ManagedDomainWithoutMailbox", func(cfg *Config) {
+			cfg.NameserverManagedDomains = append(cfg.NameserverManagedDomains, "mars.com")
+		}, []string{`domain "mars.com" has no entry in Mailboxes`}},
+		{"MailboxAddrEnvCollision", func(cfg *Config) {
+			cfg.Mailboxes["my-domain.com"] = MailboxConfig{Domain: "mine", Addr: "localhost:50056"}
+			cfg.Mailboxes["my.domain.com"] = MailboxConfig{Domain: "ours", Addr: "localhost:50057"}
+		}, []string{`Mailboxes["my.domain.com"]: the address override GODISSYS_MAILBOX_MY_DOMAIN_COM_ADDR is shared with Mailboxes["my-domain.com"]`}},
+		{"BadReferral", func(cfg *Config) {
+			cfg.NameserverReferrals = map[string]string{"venus.com": "venus"}
+		}, []string{`NameserverReferrals["venus.com"]`}},
+		{"TLSWithoutKey", func(cfg *Config) {
+			cfg.TLS = &TLSConfig{CertFile: "cert.pem"}
+		}, []string{"TLS: TLS requires CertFile and KeyFile"}},
+		{"ClientCertWithoutCA", func(cfg *Config) {
+			cfg.TransferServer.TLS = &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", RequireClientCert: true}
+		}, []string{"TransferServer.TLS: TLS RequireClientCert requires a CAFile"}},
+		{"UnknownTraceExporter", func(cfg *Config) {
+			cfg.Tracing = &TracingConfig{Exporter: "jaeger"}
+		}, []string{"Tracing: unknown trace exporter 'jaeger'"}},
+		{"BadMetricsAddr", func(cfg *Config) { cfg.MetricsAddr = "9090" }, []string{"MetricsAddr: invalid address '9090'"}},
+		{"SeveralProblems", func(cfg *Config) {
+			cfg.NameserverAddr = ""
+			cfg.TransferServerAddr = "nowhere"
+		}, []string{"NameserverAddr is required", "TransferServerAddr: invalid address"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected the config to be rejected")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected the error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+// TestLoadConfigWithEnv tests that non-empty environment variables override the addresses from the file.
+func TestLoadConfigWithEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"NameserverAddr": "localhost:50051", "TransferServerAddr": "localhost:50053",
+		"Mailboxes": {"earth.com": {"Domain": "earth", "Addr": "localhost:50054"}, "saturn.com": {"Domain": "saturn", "Addr": "localhost:50055"}}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("GODISSYS_NAMESERVER_ADDR", "nameserver:50051")
+	t.Setenv("GODISSYS_TRANSFER_SERVER_ADDR", "") // Empty values do not override
+	t.Setenv("GODISSYS_MAILBOX_EARTH_COM_ADDR", "earth-mailbox:50054")
+
+	cfg, err := LoadConfigWithEnv(path)
+	if err != nil {
+		t.Fatalf("LoadConfigWithEnv failed: %v", err)
+	}
+	if cfg.NameserverAddr != "nameserver:50051" {
+		t.Errorf("Expected the environment to override NameserverAddr, got %q", cfg.NameserverAddr)
+	}
+	if cfg.TransferServerAddr != "localhost:50053" {
+		t.Errorf("Expected an empty variable to keep TransferServerAddr, got %q", cfg.TransferServerAddr)
+	}
+	if got := cfg.Mailboxes["earth.com"]; got.Addr != "earth-mailbox:50054" || got.Domain != "earth" {
+		t.Errorf("Expected the environment to override only the earth.com Mailbox address, got %+v", got)
+	}
+	if got := cfg.Mailboxes["saturn.com"].Addr; got != "localhost:50055" {
+		t.Errorf("Expected the saturn.com Mailbox address from the file, got %q", got)
+	}
+
+	if got := MailboxAddrEnv("my-domain.co.uk"); got != "GODISSYS_MAILBOX_MY_DOMAIN_CO_UK_ADDR" {
+		t.Errorf("Unexpected variable name %q", got)
+	}
+}
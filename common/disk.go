@@ -0,0 +1,21 @@
+package common
+
+import "log/slog"
+
+// DiskSpaceFunc reports the number of bytes available on the filesystem holding path.
+type DiskSpaceFunc func(path string) (uint64, error)
+
+// LowDiskSpace reports whether the filesystem holding dir has fewer than minFree bytes available,
+// as measured by freeSpace, together with the measured free space. It never reports low space
+// when dir is empty, minFree is 0, or the free space cannot be determined.
+func LowDiskSpace(freeSpace DiskSpaceFunc, dir string, minFree uint64) (bool, uint64) {
+	if dir == "" || minFree == 0 {
+		return false, 0
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		slog.Warn("Could not determine free disk space", "dir", dir, "error", err)
+		return false, 0
+	}
+	return free < minFree, free
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package common
+
+import "errors"
+
+// FreeDiskSpace is not supported on this platform; disk space checks are skipped.
+func FreeDiskSpace(path string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
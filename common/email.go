@@ -0,0 +1,28 @@
+package common
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// ParseEmail parses address, which may carry a display name ("Alice <alice@earth.com>"), and returns
+// the bare address in its canonical lower-case form. It fails unless the address has the form user@domain.
+func ParseEmail(address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return "", fmt.Errorf("invalid email address '%s': %w", address, err)
+	}
+	canonical := strings.ToLower(parsed.Address)
+	user, domain, ok := strings.Cut(canonical, "@")
+	if !ok || user == "" || domain == "" || strings.Contains(domain, "@") {
+		return "", fmt.Errorf("invalid email address '%s'", address)
+	}
+	return canonical, nil
+}
+
+// NormalizeEmail returns address in the form the services key their maps by: trimmed and lower-cased, so
+// "Alice@Earth.com" and "alice@earth.com" name the same user. Unlike ParseEmail it does not validate the address.
+func NormalizeEmail(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFileAtomic tests that files are replaced as a whole and interrupted writes leave the previous content.
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	t.Run("WritesAndReplaces", func(t *testing.T) {
+		for _, content := range []string{"v1", "v2"} {
+			if err := WriteFileAtomic(path, []byte(content), 0o644); err != nil {
+				t.Fatalf("WriteFileAtomic failed: %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != content {
+				t.Errorf("Expected content %q, got %q", content, data)
+			}
+		}
+	})
+
+	t.Run("InterruptedWriteKeepsPrevious", func(t *testing.T) {
+		err := writeFileAtomic(path, 0o644, func(w io.Writer) error {
+			w.Write([]byte(`{"partial`))
+			return errors.New("disk full")
+		})
+		if err == nil {
+			t.Fatalf("Expected an error for the interrupted write, got nil")
+		}
+		if data, _ := os.ReadFile(path); string(data) != "v2" {
+			t.Errorf("Expected the previous content to survive, got %q", data)
+		}
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("Expected no temporary files left behind, got %d entries", len(entries))
+		}
+	})
+}
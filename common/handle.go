@@ -0,0 +1,27 @@
+package common
+
+import "sync"
+
+// ServerHandle controls a service serving in the background, as returned by the Serve functions of the
+// service packages. It is safe for concurrent use.
+type ServerHandle struct {
+	addr     string
+	stop     func()
+	stopOnce sync.Once
+}
+
+// NewServerHandle returns the handle of a service listening on addr, which stop shuts down gracefully.
+func NewServerHandle(addr string, stop func()) *ServerHandle {
+	return &ServerHandle{addr: addr, stop: stop}
+}
+
+// Addr returns the address the service listens on, with the port chosen by the system if it was started
+// on port 0.
+func (h *ServerHandle) Addr() string {
+	return h.addr
+}
+
+// Stop shuts the service down gracefully and returns once it has stopped. Further calls only wait for that.
+func (h *ServerHandle) Stop() {
+	h.stopOnce.Do(h.stop)
+}
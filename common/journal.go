@@ -0,0 +1,137 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Journal is an append-only file of JSON records, one per line, for state that changes in small steps.
+// Each change appends a single record instead of rewriting the whole state; Compact replaces the records
+// by a snapshot once the history has grown well beyond the state it describes.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	perm    os.FileMode
+	file    *os.File
+	records int // Records in the file, replayed or appended
+}
+
+// OpenJournal opens the journal at path, creating it and its directory if needed, and calls replay with
+// each record in order. A partial last record, as left by a crash while appending, is discarded.
+func OpenJournal(path string, perm os.FileMode, replay func(record json.RawMessage) error) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for '%s': %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal '%s': %w", path, err)
+	}
+	j := &Journal{path: path, perm: perm, file: file}
+
+	reader := bufio.NewReader(file)
+	var complete int64 // Bytes up to the end of the last complete record
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break // A non-empty line here was cut short
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read journal '%s': %w", path, err)
+		}
+		complete += int64(len(line))
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if err := replay(line); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to replay journal '%s': %w", path, err)
+		}
+		j.records++
+	}
+	if err := file.Truncate(complete); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate journal '%s': %w", path, err)
+	}
+	if _, err := file.Seek(complete, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek in journal '%s': %w", path, err)
+	}
+	return j, nil
+}
+
+// Append writes record to the end of the journal and syncs it to disk.
+func (j *Journal) Append(record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return fmt.Errorf("journal '%s' is closed", j.path)
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to journal '%s': %w", j.path, err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal '%s': %w", j.path, err)
+	}
+	j.records++
+	return nil
+}
+
+// Len returns the number of records in the journal.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.records
+}
+
+// Compact atomically replaces the journal by records, which must describe the complete current state.
+// If it fails, the previous records are kept.
+func (j *Journal) Compact(records []any) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return fmt.Errorf("journal '%s' is closed", j.path)
+	}
+	err := writeFileAtomic(j.path, j.perm, func(w io.Writer) error {
+		enc := json.NewEncoder(w) // Encode ends every record with a newline
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, j.perm)
+	if err != nil {
+		return fmt.Errorf("failed to reopen journal '%s': %w", j.path, err)
+	}
+	j.file.Close()
+	j.file, j.records = file, len(records)
+	return nil
+}
+
+// Close closes the journal file. Closing a closed journal does nothing.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
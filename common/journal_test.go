@@ -0,0 +1,67 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestJournal tests appending, replaying after a reopen, discarding a partial last record and compacting.
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "journal.jsonl")
+	open := func(t *testing.T) (*Journal, []int) {
+		t.Helper()
+		var replayed []int
+		j, err := OpenJournal(path, 0o644, func(record json.RawMessage) error {
+			var n int
+			if err := json.Unmarshal(record, &n); err != nil {
+				return err
+			}
+			replayed = append(replayed, n)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("OpenJournal failed: %v", err)
+		}
+		return j, replayed
+	}
+
+	j, replayed := open(t)
+	if len(replayed) != 0 {
+		t.Fatalf("Expected a new journal to be empty, got %v", replayed)
+	}
+	for _, n := range []int{1, 2, 3} {
+		if err := j.Append(n); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	j.Close()
+	if err := j.Close(); err != nil {
+		t.Errorf("Expected closing twice to do nothing, got %v", err)
+	}
+
+	// A crash while appending leaves a partial record, which is dropped on reopen
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	f.WriteString("4")
+	f.Close()
+	j, replayed = open(t)
+	if !reflect.DeepEqual(replayed, []int{1, 2, 3}) || j.Len() != 3 {
+		t.Fatalf("Expected records [1 2 3] after reopening, got %v (len %d)", replayed, j.Len())
+	}
+	if err := j.Append(5); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	if err := j.Compact([]any{6}); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if err := j.Append(7); err != nil {
+		t.Fatalf("Append after Compact failed: %v", err)
+	}
+	j.Close()
+	if _, replayed = open(t); !reflect.DeepEqual(replayed, []int{6, 7}) {
+		t.Errorf("Expected records [6 7] after compacting, got %v", replayed)
+	}
+}
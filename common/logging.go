@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Supported log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// NewLogHandler returns the slog handler writing to w in the given format ("text" or "json").
+func NewLogHandler(format string, w io.Writer) (slog.Handler, error) {
+	switch format {
+	case LogFormatText:
+		return slog.NewTextHandler(w, nil), nil
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown log format '%s' (expected '%s' or '%s')", format, LogFormatText, LogFormatJSON)
+	}
+}
+
+// SetupLogging installs a process-wide slog logger writing to w in the given format.
+// Output from the standard log package is routed through the same handler, so every
+// service logs consistently. An empty format leaves the standard logger untouched.
+func SetupLogging(format string, w io.Writer) error {
+	if format == "" {
+		return nil
+	}
+	handler, err := NewLogHandler(format, w)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	slog.Info("Logging configured", "format", format)
+	return nil
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// rpcDuration records the latency of every RPC served by the services.
+var rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "godissys_rpc_duration_seconds",
+	Help:    "Latency of the RPCs served, by service, method and status code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"service", "method", "code"})
+
+// MetricsOptions returns the gRPC server options recording the latency and status code of every RPC served by
+// service in the godissys_rpc_duration_seconds histogram.
+func MetricsOptions(service string) []grpc.ServerOption {
+	observe := func(method string, start time.Time, err error) {
+		rpcDuration.WithLabelValues(service, method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			observe(info.FullMethod, start, err)
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			start := time.Now()
+			err := handler(srv, ss)
+			observe(info.FullMethod, start, err)
+			return err
+		}),
+	}
+}
+
+// NewMetricsServer returns an HTTP server exposing the metrics of all services in this process on addr,
+// in the Prometheus text format at /metrics.
+func NewMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
+
+// StartMetricsServer serves the metrics on addr until the process receives SIGINT or SIGTERM, then shuts
+// the HTTP server down gracefully, like the gRPC servers.
+func StartMetricsServer(addr string) {
+	ctx, stop := SignalContext()
+	defer stop()
+	StartMetricsServerWithContext(ctx, addr, nil)
+}
+
+// StartMetricsServerWithContext serves the metrics on addr like StartMetricsServer, but until ctx is cancelled.
+// Unless nil, ready is called with the listening address once the server is serving.
+func StartMetricsServerWithContext(ctx context.Context, addr string, ready func(addr string)) {
+	handle, err := ServeMetrics(addr)
+	if err != nil {
+		slog.Error("Metrics server failed to start", "service", "Metrics", "error", err)
+		return
+	}
+	if ready != nil {
+		ready(handle.Addr())
+	}
+	<-ctx.Done()
+	handle.Stop()
+}
+
+// ServeMetrics starts serving the metrics on addr in the background and returns once it listens. The
+// returned handle reports the listening address and shuts the HTTP server down gracefully.
+func ServeMetrics(addr string) (*ServerHandle, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	srv := NewMetricsServer(addr)
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server failed to serve", "service", "Metrics", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("Metrics server listening", "service", "Metrics", "addr", lis.Addr().String())
+
+	return NewServerHandle(lis.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Error("Metrics server failed to shut down", "service", "Metrics", "error", err)
+		}
+		slog.Info("Metrics server stopped", "service", "Metrics")
+	}), nil
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestMetrics tests that RPCs served with MetricsOptions show up in the latency histogram on /metrics.
+func TestMetrics(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(MetricsOptions("MetricsTest")...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewMetricsServer("").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected /metrics to answer 200, got %d", rec.Code)
+	}
+	want := `godissys_rpc_duration_seconds_count{code="OK",method="/grpc.health.v1.Health/Check",service="MetricsTest"} 1`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected the metrics to contain %q, got:\n%s", want, rec.Body.String())
+	}
+}
+
+// TestServeMetrics tests that the metrics server started on port 0 is reachable at the address of its handle
+// until it is stopped.
+func TestServeMetrics(t *testing.T) {
+	handle, err := ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ServeMetrics failed: %v", err)
+	}
+	if strings.HasSuffix(handle.Addr(), ":0") {
+		t.Fatalf("Expected the handle to report the chosen port, got %s", handle.Addr())
+	}
+	resp, err := http.Get("http://" + handle.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get the metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /metrics to answer 200, got %d", resp.StatusCode)
+	}
+
+	handle.Stop()
+	handle.Stop() // Stopping again is harmless
+	if _, err := http.Get("http://" + handle.Addr() + "/metrics"); err == nil {
+		t.Error("Expected the stopped metrics server to refuse connections")
+	}
+}
+
+// TestStartMetricsServerWithContext tests that the ready callback reports the listening address of a metrics
+// server started on port 0, and that cancelling the context stops it.
+func TestStartMetricsServerWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		StartMetricsServerWithContext(ctx, "127.0.0.1:0", func(addr string) { ready <- addr })
+	}()
+
+	var addr string
+	select {
+	case addr = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the metrics server to report readiness")
+	}
+	// No retry is needed: the server is serving once ready has been called
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Expected the ready metrics server to answer, got %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the metrics server to stop after the context was cancelled")
+	}
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("Expected the stopped metrics server to refuse connections")
+	}
+}
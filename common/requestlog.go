@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestLogOptions returns the gRPC server options that log every RPC served by service, or none if
+// enabled is false. Put them before other interceptors, so the logged status includes their rejections.
+func RequestLogOptions(service string, enabled bool) []grpc.ServerOption {
+	if !enabled {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(service)),
+		grpc.ChainStreamInterceptor(LoggingStreamInterceptor(service)),
+	}
+}
+
+// LoggingUnaryInterceptor returns a server interceptor that logs every unary RPC of service once it
+// completes, with its method, peer, duration and status code.
+func LoggingUnaryInterceptor(service string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRequest(ctx, service, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor returns a server interceptor that logs every streaming RPC of service once the
+// stream ends, like LoggingUnaryInterceptor.
+func LoggingStreamInterceptor(service string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRequest(ss.Context(), service, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logRequest logs a completed RPC as a structured record: at level INFO if it succeeded, WARN otherwise.
+func logRequest(ctx context.Context, service, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code != codes.OK {
+		level = slog.LevelWarn
+	}
+	attrs := []any{
+		"service", service,
+		"method", method,
+		"peer", peerAddr(ctx),
+		"duration", time.Since(start),
+		"code", code.String(),
+	}
+	if identity := PeerIdentity(ctx); identity != "" {
+		attrs = append(attrs, "identity", identity)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", status.Convert(err).Message())
+	}
+	slog.Log(ctx, level, "RPC", attrs...)
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of the server's goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestRequestLogOptions tests that every RPC is logged once with its method, peer, duration and status code.
+func TestRequestLogOptions(t *testing.T) {
+	if opts := RequestLogOptions("Test", false); opts != nil {
+		t.Errorf("Expected no options with request logging disabled, got %v", opts)
+	}
+
+	var out lockedBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(RequestLogOptions("Test", true)...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound for an unknown service, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one log line per RPC, got %q", out.String())
+	}
+	for i, want := range []string{"level=INFO msg=RPC service=Test method=/grpc.health.v1.Health/Check peer=127.0.0.1:", "level=WARN msg=RPC"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Expected log line %d to contain %q, got %q", i, want, lines[i])
+		}
+	}
+	for i, want := range []string{"code=OK", "code=NotFound"} {
+		if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "duration=") {
+			t.Errorf("Expected log line %d to contain %q and a duration, got %q", i, want, lines[i])
+		}
+	}
+	if !strings.Contains(lines[1], "error=") {
+		t.Errorf("Expected the failed RPC to log its error, got %q", lines[1])
+	}
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	interruptMu     sync.Mutex
+	interruptClaims int // Active InterruptContexts, which take SIGINT away from SignalContext
+)
+
+// SignalContext returns a context that is cancelled when the process receives SIGINT or SIGTERM, for
+// running a service standalone. While an InterruptContext is active, SIGINT goes to it instead, so Ctrl-C
+// ends a running CLI command without shutting the services down. Calling stop releases the signal handler.
+func SignalContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGINT && interruptClaimed() {
+					continue // Handled by the InterruptContext
+				}
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// InterruptContext returns a context that is cancelled when the process receives SIGINT (Ctrl-C). Until
+// stop is called, SIGINT no longer cancels SignalContext or ends the process.
+func InterruptContext() (ctx context.Context, stop context.CancelFunc) {
+	interruptMu.Lock()
+	interruptClaims++
+	interruptMu.Unlock()
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			interruptMu.Lock()
+			interruptClaims--
+			interruptMu.Unlock()
+		})
+	}
+}
+
+// interruptClaimed reports whether an InterruptContext is active.
+func interruptClaimed() bool {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	return interruptClaims > 0
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestInterruptContext tests that SIGINT cancels an active InterruptContext instead of the SignalContext,
+// and the SignalContext again once the InterruptContext is stopped.
+func TestInterruptContext(t *testing.T) {
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess failed: %v", err)
+	}
+	waitDone := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+
+	services, stopServices := SignalContext()
+	defer stopServices()
+	command, stopCommand := InterruptContext()
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skipf("Cannot send SIGINT on this platform: %v", err)
+	}
+	if !waitDone(command) {
+		t.Fatal("Expected SIGINT to cancel the InterruptContext")
+	}
+	if services.Err() != nil {
+		t.Fatal("Expected SIGINT to leave the SignalContext running while an InterruptContext is active")
+	}
+	stopCommand()
+
+	self.Signal(os.Interrupt)
+	if !waitDone(services) {
+		t.Error("Expected SIGINT to cancel the SignalContext once the InterruptContext is stopped")
+	}
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeTestCertificate writes a self-signed certificate for localhost with the common name name, and its key,
+// into dir and returns a TLSConfig using the certificate as its own CA.
+func writeTestCertificate(t *testing.T, dir, name string) *TLSConfig {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	cfg := &TLSConfig{CertFile: filepath.Join(dir, name+"-cert.pem"), KeyFile: filepath.Join(dir, name+"-key.pem")}
+	cfg.CAFile = cfg.CertFile
+	if err := os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return cfg
+}
+
+// checkHealth dials addr with dialOpt and makes one health check RPC.
+func checkHealth(addr string, dialOpt grpc.DialOption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, dialOpt)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+// startHealthServer serves the gRPC health service with TLS as configured by cfg and returns its listener.
+// Unless nil, intercept is called with the context of every unary RPC.
+func startHealthServer(t *testing.T, cfg *TLSConfig, intercept func(ctx context.Context)) net.Listener {
+	t.Helper()
+	serverOpts, err := ServerOptions(cfg)
+	if err != nil {
+		t.Fatalf("ServerOptions failed: %v", err)
+	}
+	if intercept != nil {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercept(ctx)
+			return handler(ctx, req)
+		}))
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis
+}
+
+// TestTLS tests that a server set up with ServerOptions accepts TLS connections using DialOption and
+// refuses plaintext ones.
+func TestTLS(t *testing.T) {
+	cfg := writeTestCertificate(t, t.TempDir(), "localhost")
+	lis := startHealthServer(t, cfg, nil)
+
+	tlsDial, err := DialOption(cfg)
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	if err := checkHealth(lis.Addr().String(), tlsDial); err != nil {
+		t.Errorf("Expected the TLS connection to succeed, got %v", err)
+	}
+	plaintextDial, _ := DialOption(nil)
+	if err := checkHealth(lis.Addr().String(), plaintextDial); err == nil {
+		t.Error("Expected the plaintext connection to fail")
+	}
+	// Without the CA, the self-signed server certificate is not trusted
+	untrusted, err := DialOption(&TLSConfig{})
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	if err := checkHealth(lis.Addr().String(), untrusted); err == nil {
+		t.Error("Expected the connection to fail without the CA")
+	}
+}
+
+// TestTLS_Mutual tests that a server requiring client certificates accepts only clients whose certificate is
+// signed by its CA, and sees their identity.
+func TestTLS_Mutual(t *testing.T) {
+	dir := t.TempDir()
+	trusted := writeTestCertificate(t, dir, "transferserver")
+	serverCfg := *trusted
+	serverCfg.RequireClientCert = true
+	var mu sync.Mutex
+	var identities []string
+	lis := startHealthServer(t, &serverCfg, func(ctx context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		identities = append(identities, PeerIdentity(ctx))
+	})
+	addr := lis.Addr().String()
+
+	// The trusted certificate is its own CA, so it is signed by the CA the server verifies against
+	withCert, err := DialOption(trusted)
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	if err := checkHealth(addr, withCert); err != nil {
+		t.Errorf("Expected a client with a trusted certificate to be accepted, got %v", err)
+	}
+	withoutCert, err := DialOption(&TLSConfig{CAFile: trusted.CAFile})
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	if err := checkHealth(addr, withoutCert); err == nil {
+		t.Error("Expected a client without a certificate to be rejected")
+	}
+	untrusted := writeTestCertificate(t, dir, "intruder")
+	untrusted.CAFile = trusted.CAFile // Trusts the server, but its own certificate is not signed by the server's CA
+	withUntrustedCert, err := DialOption(untrusted)
+	if err != nil {
+		t.Fatalf("DialOption failed: %v", err)
+	}
+	if err := checkHealth(addr, withUntrustedCert); err == nil {
+		t.Error("Expected a client with an untrusted certificate to be rejected")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(identities) != 1 || identities[0] != "transferserver" {
+		t.Errorf("Expected only the trusted client to get through, identified as 'transferserver', got %v", identities)
+	}
+}
+
+// TestTLS_Config tests the handling of absent and incomplete TLS configurations.
+func TestTLS_Config(t *testing.T) {
+	if opts, err := ServerOptions(nil); err != nil || opts != nil {
+		t.Errorf("Expected no server options without TLS, got %v, %v", opts, err)
+	}
+	if creds, err := TransportCredentials(nil); err != nil || creds.Info().SecurityProtocol != "insecure" {
+		t.Errorf("Expected insecure credentials without TLS, got %v, %v", creds, err)
+	}
+	if _, err := ServerOptions(&TLSConfig{CAFile: "ca.pem"}); err == nil {
+		t.Error("Expected serving without a certificate to fail")
+	}
+	if _, err := ServerOptions(&TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", RequireClientCert: true}); err == nil {
+		t.Error("Expected requiring client certificates without a CA to fail")
+	}
+	if got := PeerIdentity(context.Background()); got != "" {
+		t.Errorf("Expected no identity without a peer, got %q", got)
+	}
+	dir := t.TempDir()
+	if _, err := ServerOptions(&TLSConfig{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: filepath.Join(dir, "missing.key")}); err == nil {
+		t.Error("Expected a missing certificate to fail")
+	}
+	if _, err := TransportCredentials(&TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("Expected a missing CA file to fail")
+	}
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Supported trace exporters.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOTLP   = "otlp"
+)
+
+const (
+	tracerName          = "GoDissys"
+	defaultServiceName  = "godissys"
+	defaultOTLPEndpoint = "localhost:4317"
+)
+
+// Span attributes of the send path.
+const (
+	AttrRecipient = attribute.Key("mail.recipient")        // Email address a message is delivered to
+	AttrRetries   = attribute.Key("mail.delivery.retries") // Delivery attempts made after the first one
+)
+
+// TracingConfig configures the export of OpenTelemetry traces. A nil TracingConfig or an empty Exporter
+// disables tracing.
+type TracingConfig struct {
+	// Exporter is "stdout" (spans written as JSON alongside the log), "otlp" (spans sent to an OTLP/gRPC
+	// collector at Endpoint) or "none".
+	Exporter string `json:"Exporter"`
+	// Endpoint is the host:port of the OTLP collector (empty uses "localhost:4317").
+	Endpoint string `json:"Endpoint"`
+	// Insecure sends spans to the OTLP collector in plaintext instead of TLS.
+	Insecure bool `json:"Insecure"`
+	// ServiceName is reported as service.name of every span (empty uses "godissys").
+	ServiceName string `json:"ServiceName"`
+}
+
+// enabled reports whether cfg exports traces.
+func (cfg *TracingConfig) enabled() bool {
+	return cfg != nil && cfg.Exporter != "" && cfg.Exporter != TraceExporterNone
+}
+
+// validate checks that cfg names a known exporter and, for OTLP, a valid endpoint.
+func (cfg *TracingConfig) validate() error {
+	switch cfg.Exporter {
+	case "", TraceExporterNone, TraceExporterStdout:
+		return nil
+	case TraceExporterOTLP:
+		if cfg.Endpoint == "" {
+			return nil
+		}
+		return ValidateAddr(cfg.Endpoint)
+	default:
+		return fmt.Errorf("unknown trace exporter '%s' (expected '%s', '%s' or '%s')", cfg.Exporter,
+			TraceExporterStdout, TraceExporterOTLP, TraceExporterNone)
+	}
+}
+
+// SetupTracing installs a process-wide OpenTelemetry tracer provider exporting spans as configured by cfg,
+// and propagates the trace context in the metadata of gRPC calls (see TracingServerOptions and
+// TracingDialOption). The stdout exporter writes to w. Unless tracing is disabled, in which case every span
+// is a no-op, the returned shutdown function must be called before the process exits to flush pending spans.
+func SetupTracing(cfg *TracingConfig, w io.Writer) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+	if !cfg.enabled() {
+		return shutdown, nil
+	}
+	if err := cfg.validate(); err != nil {
+		return shutdown, err
+	}
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case TraceExporterOTLP:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = defaultOTLPEndpoint
+		}
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		// The client connects lazily, so an unreachable collector only shows up when spans are exported
+		exporter, err = otlptracegrpc.New(context.Background(), opts...)
+	}
+	if err != nil {
+		return shutdown, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.serviceName()))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	slog.Info("Tracing configured", "exporter", cfg.Exporter)
+	return provider.Shutdown, nil
+}
+
+// serviceName returns the service.name reported for the spans of this process.
+func (cfg *TracingConfig) serviceName() string {
+	if cfg.ServiceName != "" {
+		return cfg.ServiceName
+	}
+	return defaultServiceName
+}
+
+// TracingServerOptions returns the gRPC server options that continue the trace of every incoming RPC in a
+// server span, using the tracer provider installed by SetupTracing.
+func TracingServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+}
+
+// TracingDialOption returns the gRPC dial option that records every outgoing RPC in a client span and
+// passes the trace context on to the server.
+func TracingDialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// StartSpan starts a span named name as a child of the span in ctx, if any, with the given attributes.
+// The caller must end the returned span.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// AnnotateSpan adds attrs to the span in ctx, such as the server span of an RPC. Without a span it does nothing.
+func AnnotateSpan(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
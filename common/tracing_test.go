@@ -0,0 +1,108 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// restoreTracing puts back the global tracer provider and propagator when the test ends.
+func restoreTracing(t *testing.T) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+// TestTracing_Propagation tests that the trace of a client span continues in the server span of a gRPC call
+// made with TracingDialOption to a server set up with TracingServerOptions.
+func TestTracing_Propagation(t *testing.T) {
+	restoreTracing(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(TracingServerOptions()...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	plaintext, _ := DialOption(nil)
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), plaintext, TracingDialOption())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, span := StartSpan(ctx, "Test.send", AttrRecipient.String("alice@earth.com"))
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	span.End()
+
+	var serverSpan sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.SpanKind() == trace.SpanKindServer {
+			serverSpan = s
+		}
+	}
+	if serverSpan == nil {
+		t.Fatalf("Expected a server span, got %d spans", len(recorder.Ended()))
+	}
+	if got, want := serverSpan.SpanContext().TraceID(), span.SpanContext().TraceID(); got != want {
+		t.Errorf("Expected the server span to continue trace %s, got %s", want, got)
+	}
+	if !serverSpan.Parent().IsRemote() {
+		t.Error("Expected the server span to have a remote parent")
+	}
+}
+
+// TestSetupTracing tests the stdout exporter and the rejection of unknown exporters.
+func TestSetupTracing(t *testing.T) {
+	restoreTracing(t)
+	for _, cfg := range []*TracingConfig{nil, {}, {Exporter: TraceExporterNone}} {
+		shutdown, err := SetupTracing(cfg, nil)
+		if err != nil || shutdown(context.Background()) != nil {
+			t.Errorf("Expected disabled tracing for %+v, got %v", cfg, err)
+		}
+	}
+	if _, err := SetupTracing(&TracingConfig{Exporter: "jaeger"}, nil); err == nil {
+		t.Error("Expected an unknown exporter to fail")
+	}
+
+	var out bytes.Buffer
+	shutdown, err := SetupTracing(&TracingConfig{Exporter: TraceExporterStdout, ServiceName: "test"}, &out)
+	if err != nil {
+		t.Fatalf("SetupTracing failed: %v", err)
+	}
+	_, span := StartSpan(context.Background(), "Test.send", AttrRecipient.String("alice@earth.com"), AttrRetries.Int(2))
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	for _, want := range []string{`"Name":"Test.send"`, `"mail.recipient"`, `"alice@earth.com"`, `"mail.delivery.retries"`, `"test"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the exported span to contain %s, got: %s", want, out.String())
+		}
+	}
+}
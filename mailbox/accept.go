@@ -0,0 +1,49 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CanAccept implements proto.MailboxServer.
+// It applies the checks of ReceiveMail without storing anything: the recipient must belong to this
+// Mailbox's domain, and there must be disk space and inbox headroom. Missing space is reported as retryable.
+// Callers are not authenticated, so whether a sender is blocked is only revealed by ReceiveMail.
+func (s *server) CanAccept(ctx context.Context, req *proto.CanAcceptRequest) (*proto.CanAcceptResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recipient := common.NormalizeEmail(req.GetRecipientEmail())
+	if recipient == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "recipient email cannot be empty")
+	}
+	if at := strings.LastIndex(recipient, "@"); at < 0 || !strings.EqualFold(recipient[at+1:], s.Domain) {
+		return refuse(false, "recipient '%s' is not served by mailbox '%s'", recipient, s.Domain), nil
+	}
+	if s.maxBodyBytes > 0 && req.GetBodyBytes() > int64(s.maxBodyBytes) {
+		return refuse(false, "body size %d bytes exceeds the mailbox limit of %d bytes", req.GetBodyBytes(), s.maxBodyBytes), nil
+	}
+	if low, _ := common.LowDiskSpace(s.freeDiskSpace, s.stateDir, s.minFreeDiskBytes); low {
+		return refuse(true, "mailbox is low on disk space"), nil
+	}
+	if inbox := s.userInboxes[recipient]; s.maxMessagesPerUser > 0 && len(inbox) >= s.maxMessagesPerUser &&
+		s.overflowPolicy != common.OverflowDropOldest {
+		return refuse(true, "inbox for '%s' is full (limit %d messages)", recipient, s.maxMessagesPerUser), nil
+	}
+	if inbox := s.userInboxes[recipient]; s.maxBytesPerUser > 0 && inboxBytes(inbox) >= s.maxBytesPerUser &&
+		s.overflowPolicy != common.OverflowDropOldest {
+		return refuse(true, "inbox for '%s' is full (limit %d bytes)", recipient, s.maxBytesPerUser), nil
+	}
+	return &proto.CanAcceptResponse{Accept: true}, nil
+}
+
+// refuse builds a negative CanAccept response.
+func refuse(retryable bool, format string, args ...any) *proto.CanAcceptResponse {
+	return &proto.CanAcceptResponse{Retryable: retryable, Reason: fmt.Sprintf(format, args...)}
+}
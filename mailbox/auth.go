@@ -0,0 +1,108 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authenticatedMethods are the RPCs that read or modify a user's mail and therefore require the caller
+// to be authorized for the request's email address. ReceiveMail is called by the TransferServer and Info
+// exposes no user data, so both stay open.
+var authenticatedMethods = []string{
+	proto.Mailbox_GetMail_FullMethodName,
+	proto.Mailbox_DeleteMail_FullMethodName,
+	proto.Mailbox_WaitForMail_FullMethodName,
+	proto.Mailbox_WatchMail_FullMethodName,
+	proto.Mailbox_UndeleteMail_FullMethodName,
+	proto.Mailbox_MarkRead_FullMethodName,
+	proto.Mailbox_SearchMail_FullMethodName,
+}
+
+// SetAuthenticator replaces the authenticator checking the authenticated RPCs for users without a password
+// (users with one are checked against it). nil restores the default, which allows every request. It must be
+// called before the server starts serving.
+func (s *server) SetAuthenticator(auth common.Authenticator) {
+	if auth == nil {
+		auth = common.NoopAuthenticator{}
+	}
+	s.authenticator = auth
+}
+
+// accessChecker returns the authenticator enforced on authenticatedMethods: the users' passwords, then the
+// configured authenticator. Unless passwordless access is allowed, users without a password are rejected while
+// the authenticator is the open default, since nothing else would check them.
+func (s *server) accessChecker() common.Authenticator {
+	_, open := s.authenticator.(common.NoopAuthenticator)
+	return passwordAuthenticator{passwords: s.passwords, required: s.requirePasswords && open, next: s.authenticator}
+}
+
+// authInterceptor returns the server interceptor enforcing accessChecker on authenticatedMethods.
+func (s *server) authInterceptor() grpc.UnaryServerInterceptor {
+	return common.AuthUnaryInterceptor(s.accessChecker(), authenticatedMethods...)
+}
+
+// isAdmin reports whether the caller in ctx sent the configured admin token.
+func (s *server) isAdmin(ctx context.Context) bool {
+	return s.adminToken != "" && subtle.ConstantTimeCompare([]byte(common.AuthToken(ctx)), []byte(s.adminToken)) == 1
+}
+
+// authorizeAdmin fails with PermissionDenied unless the caller in ctx is an administrator.
+func (s *server) authorizeAdmin(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Errorf(codes.PermissionDenied, "administrative access is disabled, no admin token is configured")
+	}
+	if !s.isAdmin(ctx) {
+		return status.Errorf(codes.PermissionDenied, "the admin token is required")
+	}
+	return nil
+}
+
+// servesAddress reports whether emailAddress, normalized, belongs to the mail domain of this Mailbox.
+func (s *server) servesAddress(emailAddress string) bool {
+	_, domain, ok := strings.Cut(emailAddress, "@")
+	return ok && domain == s.mailDomain
+}
+
+// authorizeFirstPassword checks that the caller in ctx may set the first password of emailAddress: an
+// administrator, or a caller that a configured authenticator accepts for the address. The default authenticator
+// accepts everyone and so proves nothing, in which case only administrators can set first passwords.
+func (s *server) authorizeFirstPassword(ctx context.Context, emailAddress string) error {
+	if s.isAdmin(ctx) {
+		return nil
+	}
+	if _, open := s.authenticator.(common.NoopAuthenticator); open {
+		return status.Errorf(codes.PermissionDenied, "setting the first password of '%s' requires the admin token", emailAddress)
+	}
+	return common.Authorize(ctx, s.authenticator, emailAddress)
+}
+
+// checkRegistered fails with FailedPrecondition unless the Nameserver knows emailAddress. Without a configured
+// Nameserver it does nothing.
+func (s *server) checkRegistered(ctx context.Context, emailAddress string) error {
+	if s.nameserverAddr == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, s.nameserverAddr, s.dialOpt, common.TracingDialOption())
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "could not connect to Nameserver at %s: %v", s.nameserverAddr, err)
+	}
+	defer conn.Close()
+	resp, err := proto.NewNameserverClient(conn).LookupMailbox(ctx, &proto.LookupMailboxRequest{EmailAddress: emailAddress})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "could not look up '%s' with the Nameserver: %v", emailAddress, err)
+	}
+	if !resp.GetFound() {
+		return status.Errorf(codes.FailedPrecondition, "'%s' is not registered, sign up first", emailAddress)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"io"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// ExportMailbox implements proto.MailboxServer.
+// It requires the admin token. It snapshots all inboxes under one lock, so the dump is consistent,
+// and streams it afterwards without blocking mail delivery. Users are exported in address order,
+// each inbox in storage order.
+func (s *server) ExportMailbox(req *proto.ExportMailboxRequest, stream proto.Mailbox_ExportMailboxServer) error {
+	if err := s.authorizeAdmin(stream.Context()); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	var entries []*proto.MailboxDumpEntry
+	for emailAddress, messages := range s.userInboxes {
+		for _, msg := range messages {
+			entries = append(entries, &proto.MailboxDumpEntry{
+				EmailAddress: emailAddress,
+				Message:      protobuf.Clone(msg).(*proto.MailMessage),
+			})
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].EmailAddress < entries[j].EmailAddress })
+
+	for _, entry := range entries {
+		if err := stream.Send(entry); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("Exported mailbox", "messages", len(entries))
+	return nil
+}
+
+// ImportMailbox implements proto.MailboxServer.
+// It requires the admin token and rejects dumps with entries for addresses outside the Mailbox's domain.
+// It reads the whole dump before changing anything, then merges it into the inboxes under one lock:
+// each message is appended to its user's inbox unless a message with the same ID is already there,
+// addressed to that user whatever recipient the dump recorded. Inbox limits do not apply, so a restore never drops mail. If the result cannot be persisted,
+// the inboxes are left unchanged.
+func (s *server) ImportMailbox(stream proto.Mailbox_ImportMailboxServer) error {
+	if err := s.authorizeAdmin(stream.Context()); err != nil {
+		return err
+	}
+	var entries []*proto.MailboxDumpEntry
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if entry.GetEmailAddress() == "" || entry.GetMessage() == nil {
+			return status.Errorf(codes.InvalidArgument, "dump entry %d needs an email address and a message", len(entries)+1)
+		}
+		if !s.servesAddress(common.NormalizeEmail(entry.EmailAddress)) {
+			return status.Errorf(codes.InvalidArgument, "dump entry %d is for '%s', which is not an address of the domain '%s'",
+				len(entries)+1, entry.EmailAddress, s.mailDomain)
+		}
+		entries = append(entries, entry)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[string][]*proto.MailMessage)
+	present := make(map[string]map[string]bool) // email address -> IDs already in the inbox
+	var imported, skipped int32
+	for _, entry := range entries {
+		emailAddress, msg := common.NormalizeEmail(entry.EmailAddress), entry.Message
+		if _, seen := present[emailAddress]; !seen {
+			previous[emailAddress] = s.userInboxes[emailAddress]
+			ids := make(map[string]bool)
+			for _, existing := range s.userInboxes[emailAddress] {
+				ids[existing.MessageId] = true
+			}
+			present[emailAddress] = ids
+		}
+		if msg.MessageId == "" {
+			msg.MessageId = common.NewMessageID()
+		}
+		if present[emailAddress][msg.MessageId] {
+			skipped++
+			continue
+		}
+		present[emailAddress][msg.MessageId] = true
+		msg.RecipientEmail = emailAddress // Mail is looked up by recipient, so it must match the inbox it is stored in
+		if msg.Sequence == 0 {
+			msg.Sequence = s.nextSequenceLocked()
+		} else {
+			s.lastSequence = max(s.lastSequence, msg.Sequence) // Mail stored later must still follow it
+		}
+		s.userInboxes[emailAddress] = append(s.userInboxes[emailAddress], msg)
+		imported++
+	}
+
+	if imported > 0 {
+		if err := s.persistLocked(); err != nil {
+			for emailAddress, messages := range previous {
+				if messages == nil {
+					delete(s.userInboxes, emailAddress) // The user had no inbox before the import
+				} else {
+					s.userInboxes[emailAddress] = messages
+				}
+			}
+			s.logger.Error("Failed to persist imported mail", "error", err)
+			return status.Errorf(codes.Internal, "failed to store imported mail")
+		}
+		for emailAddress, messages := range previous {
+			for _, msg := range s.userInboxes[emailAddress][len(messages):] {
+				s.seenLocked(emailAddress).add(msg.MessageId)
+			}
+		}
+		close(s.mailArrived) // Wake up WaitForMail callers
+		s.mailArrived = make(chan struct{})
+	}
+	s.logger.Info("Imported mailbox", "imported", imported, "skipped", skipped)
+	return stream.SendAndClose(&proto.ImportMailboxResponse{Imported: imported, Skipped: skipped})
+}
@@ -0,0 +1,54 @@
+package mailbox
+
+import "GoDissys/proto/proto"
+
+const maxSeenMessageIDs = 1000 // Message IDs remembered per user for dropping redeliveries
+
+// seenMessages remembers the IDs of the latest messages stored for one user, also once they have been
+// retrieved, deleted or evicted, so a redelivery is dropped instead of stored a second time. Only the
+// latest maxSeenMessageIDs are kept; redeliveries follow the original closely, so older IDs are not needed.
+type seenMessages struct {
+	ids   map[string]bool
+	order []string // Oldest first, the next to be forgotten
+}
+
+// contains reports whether the message with ID id was stored recently.
+func (m *seenMessages) contains(id string) bool {
+	return m != nil && m.ids[id]
+}
+
+// add remembers id, forgetting the oldest ID once more than maxSeenMessageIDs are held.
+func (m *seenMessages) add(id string) {
+	if m.ids[id] {
+		return
+	}
+	m.ids[id] = true
+	m.order = append(m.order, id)
+	if len(m.order) > maxSeenMessageIDs {
+		delete(m.ids, m.order[0])
+		m.order = m.order[1:]
+	}
+}
+
+// seenLocked returns the seen message IDs of the user, creating them if needed. s.mu must be held.
+func (s *server) seenLocked(emailAddress string) *seenMessages {
+	seen := s.seenIDs[emailAddress]
+	if seen == nil {
+		seen = &seenMessages{ids: make(map[string]bool)}
+		s.seenIDs[emailAddress] = seen
+	}
+	return seen
+}
+
+// seenFromInboxes returns the seen message IDs of every user, as far as they are still in the inboxes.
+func seenFromInboxes(inboxes map[string][]*proto.MailMessage) map[string]*seenMessages {
+	seenIDs := make(map[string]*seenMessages)
+	for emailAddress, inbox := range inboxes {
+		seen := &seenMessages{ids: make(map[string]bool)}
+		for _, msg := range inbox {
+			seen.add(msg.GetMessageId())
+		}
+		seenIDs[emailAddress] = seen
+	}
+	return seenIDs
+}
@@ -0,0 +1,74 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// newBodyCipher returns the AES-GCM cipher for message bodies at rest configured by cfg, or nil if
+// encryption is not configured. The key in the environment variable cfg.EncryptionKeyEnv takes precedence
+// over cfg.EncryptionKey.
+func newBodyCipher(cfg common.MailboxConfig) (cipher.AEAD, error) {
+	encoded := cfg.EncryptionKey
+	if cfg.EncryptionKeyEnv != "" {
+		encoded = os.Getenv(cfg.EncryptionKeyEnv)
+		if encoded == "" {
+			return nil, fmt.Errorf("encryption key variable '%s' is not set", cfg.EncryptionKeyEnv)
+		}
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptBody returns a copy of msg whose body is replaced by its AES-GCM encryption.
+func encryptBody(aead cipher.AEAD, msg *proto.MailMessage) (*proto.MailMessage, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	encrypted := protobuf.Clone(msg).(*proto.MailMessage)
+	encrypted.EncryptedBody = aead.Seal(nonce, nonce, []byte(msg.Body), nil)
+	encrypted.Body = ""
+	return encrypted, nil
+}
+
+// decryptBody restores the body of msg if it was stored encrypted. Plaintext messages, e.g. persisted
+// before encryption was enabled, are left unchanged.
+func decryptBody(aead cipher.AEAD, msg *proto.MailMessage) error {
+	if len(msg.EncryptedBody) == 0 {
+		return nil
+	}
+	if aead == nil {
+		return errors.New("message body is encrypted, but no encryption key is configured")
+	}
+	if len(msg.EncryptedBody) < aead.NonceSize() {
+		return errors.New("encrypted message body is truncated")
+	}
+	nonce, ciphertext := msg.EncryptedBody[:aead.NonceSize()], msg.EncryptedBody[aead.NonceSize():]
+	body, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt message body: %w", err)
+	}
+	msg.Body = string(body)
+	msg.EncryptedBody = nil
+	return nil
+}
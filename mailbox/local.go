@@ -0,0 +1,35 @@
+package mailbox
+
+import (
+	"GoDissys/proto/proto"
+	"sync"
+)
+
+// localServers holds the Mailboxes running in this process by listen address, so that in-process
+// clients can deliver to them without going over the network.
+var (
+	localMu      sync.RWMutex
+	localServers = make(map[string]proto.MailboxServer)
+)
+
+// RegisterLocalServer makes the Mailbox listening on addr available to in-process clients.
+func RegisterLocalServer(addr string, s proto.MailboxServer) {
+	localMu.Lock()
+	defer localMu.Unlock()
+	localServers[addr] = s
+}
+
+// UnregisterLocalServer removes the Mailbox listening on addr from the in-process registry.
+func UnregisterLocalServer(addr string) {
+	localMu.Lock()
+	defer localMu.Unlock()
+	delete(localServers, addr)
+}
+
+// LocalServer returns the in-process Mailbox listening on addr, if there is one.
+func LocalServer(addr string) (proto.MailboxServer, bool) {
+	localMu.RLock()
+	defer localMu.RUnlock()
+	s, ok := localServers[addr]
+	return s, ok
+}
@@ -1,14 +1,15 @@
 package mailbox
 
 import (
+	"GoDissys/common"
 	"GoDissys/proto/proto"
 	"context"
-	"log"
+	"crypto/cipher"
+	"fmt"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
+	"sort"
 	"sync"
-	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
@@ -23,14 +24,168 @@ type server struct {
 	userInboxes map[string][]*proto.MailMessage
 	mu          sync.RWMutex // Mutex to protect the userInboxes map
 	Domain      string
+
+	// maxMessagesPerUser caps the number of messages per recipient (0 means unlimited).
+	maxMessagesPerUser int
+	// maxBytesPerUser caps the total encoded size of the messages per recipient (0 means unlimited).
+	maxBytesPerUser int64
+	// maxBodyBytes rejects messages with a larger body (0 means unlimited).
+	maxBodyBytes int
+	// overflowPolicy is applied when a full inbox receives mail (common.OverflowReject or common.OverflowDropOldest).
+	overflowPolicy string
+	// retainOnGet keeps messages in the inbox after GetMail instead of clearing them.
+	retainOnGet bool
+	// capacity is the advertised total message capacity (0 = unlimited).
+	capacity int
+	// blockedSenders holds sender addresses whose mail is rejected, normalized (see common.NormalizeEmail).
+	blockedSenders map[string]bool
+
+	// userTrash maps full email address to retrieved messages kept for UndeleteMail (protected by mu)
+	userTrash map[string][]trashedMessage
+	// trashRetention is how long retrieved messages stay in the trash (0 disables the trash).
+	trashRetention time.Duration
+	// clearGracePeriod is the minimum time messages cleared by GetMail stay in the trash.
+	clearGracePeriod time.Duration
+	// logger writes the Mailbox's log records, tagged with the service and domain.
+	logger *slog.Logger
+	// now returns the current time; replaced in tests to control expiry.
+	now func() time.Time
+
+	// statePath is the file the inboxes are persisted to (empty keeps mail in memory only).
+	statePath string
+	// persistInterval coalesces saves over this interval (0 saves on every change); at most
+	// maxPendingWrites changes stay unsaved. pendingWrites and flushTimer are protected by mu.
+	persistInterval  time.Duration
+	maxPendingWrites int
+	pendingWrites    int
+	flushTimer       *time.Timer
+	// bodyCipher encrypts message bodies in the state file (nil stores them in plaintext).
+	bodyCipher cipher.AEAD
+	// stateDir and minFreeDiskBytes drive the low-disk check; freeDiskSpace is replaced in tests.
+	stateDir         string
+	minFreeDiskBytes uint64
+	freeDiskSpace    common.DiskSpaceFunc
+
+	// seenIDs maps full email address to the IDs of its latest stored messages, for dropping redeliveries (protected by mu).
+	seenIDs map[string]*seenMessages
+	// lastSequence is the sequence of the most recently stored message (protected by mu).
+	lastSequence int64
+	// mailArrived is closed and replaced whenever mail is stored, waking WaitForMail callers (protected by mu).
+	mailArrived chan struct{}
+	// watchers maps full email address to the channels of its open WatchMail streams (protected by mu).
+	watchers map[string][]chan *proto.MailMessage
+
+	// sendReceipt sends a read receipt via the TransferServer (nil disables read receipts).
+	sendReceipt func(*proto.MailMessage) error
+
+	// authenticator checks callers of the RPCs listed in authenticatedMethods, for users without a password.
+	authenticator common.Authenticator
+	// passwords holds the users' password hashes, which accessChecker verifies before authenticator.
+	passwords *passwordStore
+	// requirePasswords rejects users without a password while authenticator is the open default.
+	requirePasswords bool
+	// adminToken authorizes administrative RPCs and password resets (empty disables them).
+	adminToken string
+	// mailDomain is the email domain this Mailbox serves; passwords and imports are limited to its addresses.
+	mailDomain string
+	// nameserverAddr is asked whether an address is registered before its first password is set (empty skips it).
+	nameserverAddr string
+	// dialOpt holds the transport credentials for dialing the Nameserver and the TransferServer.
+	dialOpt grpc.DialOption
 }
 
 // NewServer creates a new Mailbox instance, responsible for the given domain.
+// All other options take their default values, so mail is kept in memory only, except that users without a
+// password may access their mail (see common.MailboxConfig.AllowPasswordless), as suits tests and embedding.
 func NewServer(domain string) *server {
-	return &server{
-		userInboxes: make(map[string][]*proto.MailMessage),
-		Domain:      domain,
+	s, _ := NewServerWithConfig(common.MailboxConfig{Domain: domain, AllowPasswordless: true}) // Cannot fail without a state directory
+	return s
+}
+
+// NewServerWithConfig creates a new Mailbox instance from a full mailbox configuration.
+// It fails if persisted inboxes in cfg.StateDir cannot be loaded.
+func NewServerWithConfig(cfg common.MailboxConfig) (*server, error) {
+	logger := slog.With("service", "Mailbox", "domain", cfg.Domain)
+	overflowPolicy := cfg.OverflowPolicy
+	switch overflowPolicy {
+	case common.OverflowReject, common.OverflowDropOldest:
+	case "":
+		overflowPolicy = common.OverflowReject
+	default:
+		logger.Warn("Unknown overflow policy, falling back", "policy", overflowPolicy, "fallback", common.OverflowReject)
+		overflowPolicy = common.OverflowReject
+	}
+	blocked := make(map[string]bool)
+	for _, sender := range cfg.BlockedSenders {
+		blocked[common.NormalizeEmail(sender)] = true
+	}
+	statePath := common.StatePath(cfg.StateDir, cfg.InstanceName, stateFileName(cfg.Domain))
+	bodyCipher, err := newBodyCipher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inboxes := make(map[string][]*proto.MailMessage)
+	if statePath != "" {
+		loaded, err := loadInboxes(statePath, bodyCipher)
+		if err != nil {
+			return nil, err
+		}
+		inboxes = loaded
 	}
+	passwords, err := loadPasswords(common.StatePath(cfg.StateDir, cfg.InstanceName, passwordsFileName(cfg.Domain)))
+	if err != nil {
+		return nil, err
+	}
+	mailDomain := cfg.MailDomain
+	if mailDomain == "" {
+		mailDomain = cfg.Domain
+	}
+	dialOpt, err := common.DialOption(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
+	maxPendingWrites := cfg.MaxPendingWrites
+	if maxPendingWrites <= 0 {
+		maxPendingWrites = defaultMaxPendingWrites
+	}
+	s := &server{
+		userInboxes:        inboxes,
+		Domain:             cfg.Domain,
+		maxMessagesPerUser: cfg.MaxMessagesPerUser,
+		maxBytesPerUser:    cfg.MaxBytesPerUser,
+		maxBodyBytes:       cfg.MaxBodyBytes,
+		overflowPolicy:     overflowPolicy,
+		retainOnGet:        cfg.RetainOnGet,
+		capacity:           cfg.Capacity,
+		blockedSenders:     blocked,
+		userTrash:          make(map[string][]trashedMessage),
+		trashRetention:     time.Duration(cfg.TrashRetention),
+		clearGracePeriod:   time.Duration(cfg.ClearGracePeriod),
+		logger:             logger,
+		now:                time.Now,
+		statePath:          statePath,
+		persistInterval:    time.Duration(cfg.PersistInterval),
+		maxPendingWrites:   maxPendingWrites,
+		bodyCipher:         bodyCipher,
+		stateDir:           cfg.StateDir,
+		minFreeDiskBytes:   cfg.MinFreeDiskBytes,
+		freeDiskSpace:      common.FreeDiskSpace,
+		seenIDs:            seenFromInboxes(inboxes),
+		lastSequence:       assignMissingSequences(inboxes),
+		mailArrived:        make(chan struct{}),
+		watchers:           make(map[string][]chan *proto.MailMessage),
+		authenticator:      common.NoopAuthenticator{},
+		passwords:          passwords,
+		requirePasswords:   !cfg.AllowPasswordless,
+		adminToken:         cfg.AdminToken,
+		mailDomain:         common.NormalizeEmail(mailDomain),
+		nameserverAddr:     cfg.NameserverAddr,
+		dialOpt:            dialOpt,
+	}
+	if cfg.TransferServerAddr != "" {
+		s.sendReceipt = newReceiptSender(cfg.TransferServerAddr, dialOpt)
+	}
+	return s, nil
 }
 
 // ReceiveMail implements proto.MailboxServer.
@@ -43,83 +198,365 @@ func (s *server) ReceiveMail(ctx context.Context, req *proto.ReceiveMailRequest)
 	if msg == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "mail message cannot be empty")
 	}
+	msg.RecipientEmail = common.NormalizeEmail(msg.RecipientEmail) // Inboxes are keyed case-insensitively
+	common.AnnotateSpan(ctx, common.AttrRecipient.String(msg.RecipientEmail))
 	if msg.RecipientEmail == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "recipient email cannot be empty")
 	}
+	if s.maxBodyBytes > 0 && len(msg.Body) > s.maxBodyBytes {
+		s.logger.Warn("Rejecting mail, body exceeds the limit", "user", msg.RecipientEmail, "sender", msg.SenderEmail,
+			"body_bytes", len(msg.Body), "limit", s.maxBodyBytes)
+		return nil, status.Errorf(codes.InvalidArgument, "body size %d bytes exceeds the mailbox limit of %d bytes", len(msg.Body), s.maxBodyBytes)
+	}
+	if msg.MessageId != "" && s.seenIDs[msg.RecipientEmail].contains(msg.MessageId) {
+		// A redelivery, e.g. after the TransferServer timed out waiting for our response; keep one copy
+		s.logger.Info("Ignoring duplicate delivery", "user", msg.RecipientEmail, "message_id", msg.MessageId)
+		return &proto.ReceiveMailResponse{Success: true, Message: "Mail already received"}, nil
+	}
+	if low, free := common.LowDiskSpace(s.freeDiskSpace, s.stateDir, s.minFreeDiskBytes); low {
+		s.logger.Warn("Rejecting mail, low on disk space", "user", msg.RecipientEmail, "free_bytes", free, "min_free_bytes", s.minFreeDiskBytes)
+		return nil, status.Errorf(codes.ResourceExhausted, "mailbox is low on disk space, not accepting new mail")
+	}
+	if s.blockedSenders[common.NormalizeEmail(msg.SenderEmail)] {
+		s.logger.Warn("Rejected mail from blocked sender", "user", msg.RecipientEmail, "sender", msg.SenderEmail)
+		return nil, status.Errorf(codes.PermissionDenied, "sender '%s' is blocked", msg.SenderEmail)
+	}
 
+	if msg.MessageId == "" {
+		msg.MessageId = common.NewMessageID() // Delivered without a TransferServer, assign an ID here
+	}
+	msg.ReceivedTimestamp = s.now().Unix()
+	msg.Sequence = s.nextSequenceLocked()
+	msg.Read = false                              // New mail is unread, whatever the sender claims
+	previous := s.userInboxes[msg.RecipientEmail] // Taken before makeRoomLocked, so a failed save also undoes evictions
+	if err := s.makeRoomLocked(msg); err != nil {
+		// The message is rejected as a whole, nothing of it is stored. A full inbox stays full until the user
+		// empties it, so the sender is told that retrying will not help.
+		return &proto.ReceiveMailResponse{Success: false, Message: status.Convert(err).Message(), MailboxFull: true}, nil
+	}
 	s.userInboxes[msg.RecipientEmail] = append(s.userInboxes[msg.RecipientEmail], msg)
-	log.Printf("Mailbox '%s' for '%s': Received new mail from '%s' (Subject: %s)",
-		s.Domain, msg.RecipientEmail, msg.SenderEmail, msg.Subject) // Used s.Domain in log
+	if err := s.persistLocked(); err != nil {
+		s.userInboxes[msg.RecipientEmail] = previous // Don't acknowledge mail that wasn't stored durably
+		s.logger.Error("Failed to persist mail", "user", msg.RecipientEmail, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to store mail")
+	}
+	s.seenLocked(msg.RecipientEmail).add(msg.MessageId)
+	close(s.mailArrived) // Wake up WaitForMail callers
+	s.mailArrived = make(chan struct{})
+	s.notifyWatchersLocked(msg)
+	mailReceived.WithLabelValues(s.Domain).Inc()
+	s.logger.Info("Received new mail", "user", msg.RecipientEmail, "sender", msg.SenderEmail, "subject", msg.Subject) // Used s.Domain in log
 
 	return &proto.ReceiveMailResponse{Success: true, Message: "Mail received successfully"}, nil
 }
 
 // GetMail implements proto.MailboxServer.
-// It retrieves all messages for a given email address and then clears their inbox.
+// It retrieves all messages for a given email address and then clears their inbox,
+// unless the mailbox is configured to retain messages on retrieval.
+// With an offset or limit only that page is returned and the inbox is left untouched.
+// Messages are returned in chronological order (see sortByTimestamp), whatever order they were stored in.
+// Selecting and removing the returned messages happens under one lock, so concurrent (filtered)
+// calls for the same user never return a message twice or drop one.
 func (s *server) GetMail(ctx context.Context, req *proto.GetMailRequest) (*proto.GetMailResponse, error) {
 	s.mu.Lock() // Use Lock because we modify the map (clearing inbox)
 	defer s.mu.Unlock()
 
-	emailAddress := req.GetEmailAddress()
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
 	if emailAddress == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
 	}
+	if req.GetOffset() < 0 || req.GetLimit() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "offset and limit cannot be negative")
+	}
 
 	messages, found := s.userInboxes[emailAddress]
 	if !found || len(messages) == 0 {
-		log.Printf("Mailbox '%s' for '%s': No new mail to retrieve", s.Domain, emailAddress)
+		s.logger.Info("No new mail to retrieve", "user", emailAddress)
 		return &proto.GetMailResponse{Messages: []*proto.MailMessage{}}, nil
 	}
 
-	// Create a copy of messages to return
-	msgsToReturn := make([]*proto.MailMessage, len(messages))
-	copy(msgsToReturn, messages)
+	// Split the inbox into the messages to return and those left untouched by a label or unread filter
+	msgsToReturn := make([]*proto.MailMessage, 0, len(messages))
+	var remaining []*proto.MailMessage
+	for _, msg := range messages {
+		if hasAnyLabel(msg, req.GetLabels()) && !(req.GetUnreadOnly() && msg.GetRead()) {
+			msgsToReturn = append(msgsToReturn, msg)
+		} else {
+			remaining = append(remaining, msg)
+		}
+	}
+	if len(msgsToReturn) == 0 {
+		s.logger.Info("No mail matching the filter", "user", emailAddress, "labels", req.GetLabels(), "unread_only", req.GetUnreadOnly())
+		return &proto.GetMailResponse{Messages: []*proto.MailMessage{}, UnreadCount: countUnread(messages)}, nil
+	}
+	total := int32(len(msgsToReturn))
+	sortByTimestamp(msgsToReturn, req.GetNewestFirst())
 
-	// Clear the inbox for the user after retrieval
-	s.userInboxes[emailAddress] = []*proto.MailMessage{} // Reset to empty slice
-	log.Printf("Mailbox '%s' for '%s': Retrieved %d messages and cleared inbox", s.Domain, emailAddress, len(msgsToReturn))
+	if paged := req.GetOffset() > 0 || req.GetLimit() > 0; paged {
+		// Clearing a page would shift the ones after it, so pages are always read without acknowledging
+		page := pageOf(msgsToReturn, int(req.GetOffset()), int(req.GetLimit()))
+		page = s.sendReadReceiptsLocked(page)
+		s.logger.Info("Retrieved page of messages, retained in inbox", "user", emailAddress,
+			"messages", len(page), "total", total, "offset", req.GetOffset())
+		return &proto.GetMailResponse{Messages: page, TotalCount: total, UnreadCount: countUnread(messages)}, nil
+	}
+	returned := s.sendReadReceiptsLocked(msgsToReturn) // msgsToReturn keeps the stored messages, without receipt requests
 
-	return &proto.GetMailResponse{Messages: msgsToReturn}, nil
+	if !s.clearOnRead(req.AutoAck) {
+		s.logger.Info("Retrieved messages, retained in inbox", "user", emailAddress, "messages", len(msgsToReturn))
+		return &proto.GetMailResponse{Messages: returned, TotalCount: total, UnreadCount: countUnread(messages)}, nil
+	}
+
+	// Remove the returned messages from the inbox, keeping them in the trash if enabled
+	s.moveToTrashLocked(emailAddress, msgsToReturn, s.clearRetention())
+	if remaining == nil {
+		remaining = []*proto.MailMessage{} // Reset to empty slice
+	}
+	s.userInboxes[emailAddress] = remaining
+	if err := s.persistLocked(); err != nil {
+		s.logger.Error("Failed to persist cleared inbox", "user", emailAddress, "error", err)
+	}
+	s.logger.Info("Retrieved messages", "user", emailAddress, "messages", len(msgsToReturn), "left", len(remaining))
+
+	return &proto.GetMailResponse{Messages: returned, TotalCount: total, UnreadCount: countUnread(remaining)}, nil
+}
+
+// sortByTimestamp sorts messages by the time they were sent (see common.SentTime), oldest first unless
+// newestFirst is set. Messages sent at the same time are ordered by message ID, so the order is deterministic
+// even for mail that only carries a timestamp in seconds.
+func sortByTimestamp(messages []*proto.MailMessage, newestFirst bool) {
+	sentAt := make(map[*proto.MailMessage]time.Time, len(messages))
+	for _, msg := range messages {
+		sentAt[msg] = common.SentTime(msg.GetSentAt(), msg.GetTimestamp())
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if !sentAt[a].Equal(sentAt[b]) {
+			return sentAt[a].Before(sentAt[b])
+		}
+		return a.GetMessageId() < b.GetMessageId()
+	})
+}
+
+// pageOf returns up to limit messages starting at offset (all remaining ones for limit 0).
+func pageOf(messages []*proto.MailMessage, offset, limit int) []*proto.MailMessage {
+	if offset >= len(messages) {
+		return []*proto.MailMessage{}
+	}
+	end := len(messages)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return messages[offset:end]
+}
+
+// clearOnRead reports whether retrieved messages are removed from the inbox. An explicit auto_ack from the
+// client overrides the configured default, so clients using clear-on-read and keep-until-ack can share one mailbox.
+func (s *server) clearOnRead(autoAck *bool) bool {
+	if autoAck != nil {
+		return *autoAck
+	}
+	return !s.retainOnGet
+}
+
+// DeleteMail implements proto.MailboxServer.
+// It acknowledges the given messages, removing them from the user's inbox (into the trash, if enabled).
+// Unknown IDs are ignored.
+func (s *server) DeleteMail(ctx context.Context, req *proto.DeleteMailRequest) (*proto.DeleteMailResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if len(req.GetMessageIds()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one message id is required")
+	}
+
+	ids := make(map[string]bool)
+	for _, id := range req.GetMessageIds() {
+		ids[id] = true
+	}
+	var deleted, kept []*proto.MailMessage
+	for _, msg := range s.userInboxes[emailAddress] {
+		if ids[msg.GetMessageId()] {
+			deleted = append(deleted, msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+	if len(deleted) == 0 {
+		return &proto.DeleteMailResponse{}, nil
+	}
+
+	s.moveToTrashLocked(emailAddress, deleted, s.trashRetention)
+	if kept == nil {
+		kept = []*proto.MailMessage{}
+	}
+	s.userInboxes[emailAddress] = kept
+	if err := s.persistLocked(); err != nil {
+		s.logger.Error("Failed to persist deletion", "user", emailAddress, "error", err)
+	}
+	s.logger.Info("Deleted messages", "user", emailAddress, "messages", len(deleted))
+	return &proto.DeleteMailResponse{Deleted: int32(len(deleted))}, nil
+}
+
+// Info implements proto.MailboxServer.
+// It reports the number of users with an inbox, the number of stored and trashed messages and the
+// configured capacity with the space remaining.
+func (s *server) Info(ctx context.Context, req *proto.MailboxInfoRequest) (*proto.MailboxInfoResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &proto.MailboxInfoResponse{Domain: s.Domain, Users: int32(len(s.userInboxes)), Capacity: int32(s.capacity)}
+	for _, inbox := range s.userInboxes {
+		resp.Messages += int32(len(inbox))
+	}
+	if resp.Capacity > resp.Messages {
+		resp.RemainingCapacity = resp.Capacity - resp.Messages
+	}
+	for _, trash := range s.userTrash {
+		resp.Trashed += int32(len(trash))
+	}
+	return resp, nil
+}
+
+// hasAnyLabel reports whether msg carries at least one of labels. An empty filter matches every message.
+func hasAnyLabel(msg *proto.MailMessage, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, want := range labels {
+		for _, label := range msg.GetLabels() {
+			if label == want {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // StartMailbox starts the gRPC server for the Mailbox on a specific address.
-// It also sets up graceful shutdown.
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
 func StartMailbox(domain, mailboxAddr string) {
+	StartMailboxWithConfig(common.MailboxConfig{Domain: domain, Addr: mailboxAddr})
+}
+
+// StartMailboxWithConfig starts the gRPC server for the Mailbox described by cfg.
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
+func StartMailboxWithConfig(cfg common.MailboxConfig) {
+	StartMailboxWithAuthenticator(cfg, nil)
+}
+
+// StartMailboxWithAuthenticator starts the gRPC server for the Mailbox described by cfg, checking
+// the RPCs that read or modify a user's mail with auth (nil keeps the Mailbox open).
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
+func StartMailboxWithAuthenticator(cfg common.MailboxConfig, auth common.Authenticator) {
+	ctx, stop := common.SignalContext()
+	defer stop()
+	StartMailboxWithContext(ctx, cfg, auth, nil)
+}
+
+// StartMailboxWithContext runs the Mailbox like StartMailboxWithAuthenticator, but until ctx is cancelled
+// instead of until a signal arrives. It then stops the server gracefully and returns, so several services
+// can run in one process (or a test) and be stopped together. Unless nil, ready is called with the listening
+// address once the Mailbox is serving.
+func StartMailboxWithContext(ctx context.Context, cfg common.MailboxConfig, auth common.Authenticator, ready func(addr string)) {
+	handle, err := ServeMailbox(cfg, auth)
+	if err != nil {
+		slog.Error("Mailbox failed to start", "service", "Mailbox", "domain", cfg.Domain, "error", err)
+		return
+	}
+	if ready != nil {
+		ready(handle.Addr())
+	}
+	<-ctx.Done() // Block until the Mailbox is to stop
+	handle.Stop()
+}
+
+// ServeMailbox starts the Mailbox described by cfg serving in the background, checking the RPCs that read or
+// modify a user's mail with auth (nil keeps the Mailbox open), and returns once it listens. The returned
+// handle reports the listening address and stops the Mailbox gracefully.
+func ServeMailbox(cfg common.MailboxConfig, auth common.Authenticator) (*common.ServerHandle, error) {
+	domain, mailboxAddr := cfg.Domain, cfg.Addr
 	lis, err := net.Listen("tcp", mailboxAddr)
 	if err != nil {
-		log.Printf("Mailbox '%s' failed to listen on %s: %v", domain, mailboxAddr, err)
-		return // Return instead of Fatalf, allow main to handle
+		return nil, fmt.Errorf("failed to listen on %s: %w", mailboxAddr, err)
 	}
 
-	s := grpc.NewServer()
-	mailboxService := NewServer(domain) // Pass domain to NewServer
+	mailboxService, err := NewServerWithConfig(cfg)
+	if err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("failed to initialize: %w", err)
+	}
+	mailboxService.SetAuthenticator(auth)
+	serverOpts, err := common.ServerOptions(cfg.TLS)
+	if err != nil {
+		mailboxService.Close()
+		lis.Close()
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
+	opts := append(common.TracingServerOptions(), common.MetricsOptions("Mailbox "+domain)...)
+	opts = append(opts, common.RequestLogOptions("Mailbox "+domain, cfg.LogRequests)...)
+	opts = append(opts, serverOpts...)
+	s := grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(mailboxService.authInterceptor()))...)
 	proto.RegisterMailboxServer(s, mailboxService)
-	log.Printf("Mailbox '%s' listening on %s", domain, mailboxAddr)
+	// Allow the in-process client fast path under the address actually listened on, and under the configured
+	// one that clients are given unless it leaves the port to the system
+	localAddrs := []string{lis.Addr().String()}
+	if _, port, _ := net.SplitHostPort(mailboxAddr); port != "0" && mailboxAddr != localAddrs[0] {
+		localAddrs = append(localAddrs, mailboxAddr)
+	}
+	for _, addr := range localAddrs {
+		RegisterLocalServer(addr, mailboxService)
+	}
+	mailboxService.logger.Info("Mailbox listening", "addr", lis.Addr().String())
 
 	// Goroutine to serve gRPC requests
 	go func() {
 		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
-			log.Printf("Mailbox '%s' failed to serve: %v", domain, err)
+			mailboxService.logger.Error("Mailbox failed to serve", "error", err)
 		}
 	}()
 
-	// Set up graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit // Block until a signal is received
-	log.Printf("Mailbox '%s' received shutdown signal. Shutting down gracefully...", domain)
-	s.GracefulStop() // Gracefully stop the gRPC server
-	log.Printf("Mailbox '%s' server stopped.", domain)
+	// Goroutine to purge expired trash
+	stopJanitor := make(chan struct{})
+	if mailboxService.clearRetention() > 0 {
+		go mailboxService.runTrashJanitor(stopJanitor)
+	}
+
+	return common.NewServerHandle(lis.Addr().String(), func() {
+		mailboxService.logger.Info("Mailbox shutting down gracefully")
+		for _, addr := range localAddrs {
+			UnregisterLocalServer(addr)
+		}
+		s.GracefulStop() // Gracefully stop the gRPC server
+		close(stopJanitor)
+		if err := mailboxService.Close(); err != nil {
+			mailboxService.logger.Error("Mailbox failed to persist pending changes", "error", err)
+		}
+		mailboxService.logger.Info("Mailbox stopped")
+	}), nil
 }
 
 // RegisterMailboxWithNameserver connects to the Nameserver and registers this mailbox for a specific email.
-func RegisterMailboxWithNameserver(nameserverAddr, emailAddress, mailboxAddr string) {
+// The connection uses TLS as configured by tlsCfg (nil connects in plaintext).
+// It returns an error if the Nameserver cannot be reached or rejects the registration.
+func RegisterMailboxWithNameserver(nameserverAddr, emailAddress, mailboxAddr string, tlsCfg *common.TLSConfig) error {
+	dialOpt, err := common.DialOption(tlsCfg)
+	if err != nil {
+		return fmt.Errorf("failed to set up TLS: %w", err)
+	}
 	ctxDial, cancelDial := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancelDial()
 
-	conn, err := grpc.DialContext(ctxDial, nameserverAddr, grpc.WithInsecure()) // Use nameserverAddr
+	conn, err := grpc.DialContext(ctxDial, nameserverAddr, dialOpt, common.TracingDialOption()) // Use nameserverAddr
 	if err != nil {
-		log.Fatalf("Mailbox: Could not connect to Nameserver at %s: %v", nameserverAddr, err)
+		return fmt.Errorf("could not connect to Nameserver at %s: %w", nameserverAddr, err)
 	}
 	defer conn.Close()
 
@@ -135,11 +572,11 @@ func RegisterMailboxWithNameserver(nameserverAddr, emailAddress, mailboxAddr str
 
 	resp, err := client.RegisterMailbox(ctxReq, req)
 	if err != nil {
-		log.Fatalf("Mailbox: Could not register '%s' with Nameserver: %v", emailAddress, err)
+		return fmt.Errorf("could not register '%s' with Nameserver: %w", emailAddress, err)
 	}
-	if resp.GetSuccess() {
-		log.Printf("Mailbox: Successfully registered '%s' with Nameserver: %s", emailAddress, resp.GetMessage())
-	} else {
-		log.Fatalf("Mailbox: Failed to register '%s' with Nameserver: %s", emailAddress, resp.GetMessage())
+	if !resp.GetSuccess() {
+		return fmt.Errorf("registration of '%s' rejected by Nameserver: %s", emailAddress, resp.GetMessage())
 	}
+	slog.Info("Registered mailbox with the Nameserver", "service", "Mailbox", "user", emailAddress, "reply", resp.GetMessage())
+	return nil
 }
@@ -1,15 +1,31 @@
 package mailbox
 
 import (
+	"GoDissys/common"
+	"GoDissys/nameserver"
 	"GoDissys/proto/proto"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 // TestMailbox_ReceiveAndGetMail tests the ReceiveMail and GetMail functionality with email addresses.
@@ -45,6 +61,7 @@ func TestMailbox_ReceiveAndGetMail(t *testing.T) {
 
 	// Test Case 1: Receive a single mail
 	t.Run("ReceiveSingleMail", func(t *testing.T) {
+		receivedBefore := testutil.ToFloat64(mailReceived.WithLabelValues("test.com"))
 		msg := &proto.MailMessage{
 			SenderEmail:    "sender1@domain.com",
 			RecipientEmail: testRecipientEmail,
@@ -60,6 +77,9 @@ func TestMailbox_ReceiveAndGetMail(t *testing.T) {
 		if !resp.GetSuccess() {
 			t.Errorf("ReceiveMail expected success, got false. Message: %s", resp.GetMessage())
 		}
+		if got := testutil.ToFloat64(mailReceived.WithLabelValues("test.com")) - receivedBefore; got != 1 {
+			t.Errorf("Expected the received mail to be counted once, got %v", got)
+		}
 	})
 
 	// Test Case 2: Receive another mail
@@ -69,7 +89,7 @@ func TestMailbox_ReceiveAndGetMail(t *testing.T) {
 			RecipientEmail: testRecipientEmail,
 			Subject:        "Test Subject 2",
 			Body:           "Test Body 2",
-			Timestamp:      time.Now().Unix(),
+			Timestamp:      time.Now().Unix() + 1, // Sent after the first message, so returned after it
 		}
 		req := &proto.ReceiveMailRequest{Message: msg}
 		resp, err := client.ReceiveMail(context.Background(), req)
@@ -135,3 +155,2045 @@ func TestMailbox_ReceiveAndGetMail(t *testing.T) {
 		}
 	})
 }
+
+// newConfiguredServer creates a Mailbox instance from cfg, failing the test on error.
+func newConfiguredServer(t *testing.T, cfg common.MailboxConfig) *server {
+	t.Helper()
+	s, err := NewServerWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	return s
+}
+
+// startTestMailbox serves the given Mailbox instance on a random port and returns a connected client.
+func startTestMailbox(t *testing.T, mailboxService *server) proto.MailboxClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(mailboxService.authInterceptor()))
+	proto.RegisterMailboxServer(s, mailboxService)
+	go func() {
+		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			t.Errorf("Mailbox failed to serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Stop)
+
+	connCtx, connCancel := context.WithTimeout(context.Background(), time.Second)
+	defer connCancel()
+	conn, err := grpc.DialContext(connCtx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Could not connect to Mailbox: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewMailboxClient(conn)
+}
+
+// errMailboxFull is returned by receiveError for a ReceiveMail response reporting mailbox_full.
+var errMailboxFull = errors.New("mailbox full")
+
+// receiveError turns the result of ReceiveMail into an error: errMailboxFull for a full inbox, an error for
+// any other response without success, and err otherwise.
+func receiveError(resp *proto.ReceiveMailResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.GetMailboxFull() {
+		return errMailboxFull
+	}
+	if !resp.GetSuccess() {
+		return fmt.Errorf("ReceiveMail failed: %s", resp.GetMessage())
+	}
+	return nil
+}
+
+// TestMailbox_NewServerWithConfig tests that options passed through MailboxConfig take effect.
+func TestMailbox_NewServerWithConfig(t *testing.T) {
+	client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{
+		Domain:             "test.com",
+		AllowPasswordless:  true,
+		MaxMessagesPerUser: 2,
+		RetainOnGet:        true,
+		BlockedSenders:     []string{"spammer@spam.com", "Phisher@Phish.com"},
+	}))
+	recipient := "carol@test.com"
+
+	send := func(sender, subject string) error {
+		return receiveError(client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail:    sender,
+			RecipientEmail: recipient,
+			Subject:        subject,
+			Timestamp:      time.Now().Unix(),
+		}}))
+	}
+
+	t.Run("BlockedSenderRejected", func(t *testing.T) {
+		// Addresses are case-insensitive, in the config and in the message alike
+		for _, sender := range []string{"spammer@spam.com", "Spammer@Spam.com", "phisher@phish.com"} {
+			err := send(sender, "Buy now")
+			if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+				t.Errorf("Expected PermissionDenied error for blocked sender '%s', got %v", sender, err)
+			}
+		}
+	})
+
+	t.Run("QuotaEnforced", func(t *testing.T) {
+		for i := 1; i <= 2; i++ {
+			if err := send("friend@domain.com", "Hello"); err != nil {
+				t.Fatalf("ReceiveMail %d failed: %v", i, err)
+			}
+		}
+		if err := send("friend@domain.com", "One too many"); err != errMailboxFull {
+			t.Errorf("Expected mailbox_full once the quota is reached, got %v", err)
+		}
+	})
+
+	t.Run("RetainOnGet", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+			if err != nil {
+				t.Fatalf("GetMail failed: %v", err)
+			}
+			if len(resp.GetMessages()) != 2 {
+				t.Errorf("GetMail call %d: expected 2 retained messages, got %d", i+1, len(resp.GetMessages()))
+			}
+		}
+	})
+}
+
+// TestMailbox_OverflowPolicy tests both overflow policies at the quota boundary.
+func TestMailbox_OverflowPolicy(t *testing.T) {
+	// Every message is sent a second after the previous one, so GetMail returns them in the order sent
+	sentAt := time.Now().Unix()
+	receive := func(client proto.MailboxClient, subject string) error {
+		sentAt++
+		return receiveError(client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail:    "sender@domain.com",
+			RecipientEmail: "dave@test.com",
+			Subject:        subject,
+			Timestamp:      sentAt,
+		}}))
+	}
+	subjects := func(client proto.MailboxClient) []string {
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "dave@test.com"})
+		if err != nil {
+			t.Fatalf("GetMail failed: %v", err)
+		}
+		var out []string
+		for _, msg := range resp.GetMessages() {
+			out = append(out, msg.GetSubject())
+		}
+		return out
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{
+			Domain:             "test.com",
+			AllowPasswordless:  true,
+			MaxMessagesPerUser: 2,
+			OverflowPolicy:     common.OverflowReject,
+		}))
+		for _, subject := range []string{"first", "second"} {
+			if err := receive(client, subject); err != nil {
+				t.Fatalf("ReceiveMail failed: %v", err)
+			}
+		}
+		if err := receive(client, "third"); err != errMailboxFull {
+			t.Errorf("Expected mailbox_full for a full inbox, got %v", err)
+		}
+		if got := subjects(client); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+			t.Errorf("Expected [first second] to remain in inbox, got %v", got)
+		}
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{
+			Domain:             "test.com",
+			AllowPasswordless:  true,
+			MaxMessagesPerUser: 2,
+			OverflowPolicy:     common.OverflowDropOldest,
+		}))
+		for _, subject := range []string{"first", "second", "third"} {
+			if err := receive(client, subject); err != nil {
+				t.Fatalf("ReceiveMail for '%s' failed: %v", subject, err)
+			}
+		}
+		if got := subjects(client); len(got) != 2 || got[0] != "second" || got[1] != "third" {
+			t.Errorf("Expected [second third] after dropping the oldest, got %v", got)
+		}
+	})
+
+	// Messages of roughly 1 KB each, so two of them fit into a 2500 byte quota
+	receiveSized := func(client proto.MailboxClient, subject string, bodyBytes int) error {
+		sentAt++
+		return receiveError(client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: "dave@test.com", Subject: subject, Body: strings.Repeat("x", bodyBytes),
+			Timestamp: sentAt,
+		}}))
+	}
+	for _, policy := range []string{common.OverflowReject, common.OverflowDropOldest} {
+		t.Run("ByteQuota_"+policy, func(t *testing.T) {
+			client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{
+				Domain:            "test.com",
+				AllowPasswordless: true,
+				MaxBytesPerUser:   2500,
+				OverflowPolicy:    policy,
+			}))
+			for _, subject := range []string{"first", "second"} {
+				if err := receiveSized(client, subject, 1000); err != nil {
+					t.Fatalf("ReceiveMail for '%s' failed: %v", subject, err)
+				}
+			}
+			if err := receiveSized(client, "huge", 3000); err != errMailboxFull {
+				t.Errorf("Expected mailbox_full for a message larger than the quota, got %v", err)
+			}
+			err := receiveSized(client, "third", 1000)
+			want := []string{"second", "third"}
+			if policy == common.OverflowReject {
+				if err != errMailboxFull {
+					t.Errorf("Expected mailbox_full for a full inbox, got %v", err)
+				}
+				want = []string{"first", "second"}
+			} else if err != nil {
+				t.Fatalf("ReceiveMail failed: %v", err)
+			}
+			if got := subjects(client); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Expected %v in the inbox, got %v", want, got)
+			}
+		})
+	}
+}
+
+// TestMailbox_MaxBodyBytes tests that a body one byte over the limit is rejected with InvalidArgument and not
+// stored, that a body at the limit is accepted, and that CanAccept refuses oversized bodies permanently.
+func TestMailbox_MaxBodyBytes(t *testing.T) {
+	client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", MaxBodyBytes: 16, AllowPasswordless: true}))
+	receive := func(body string) error {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: "liam@test.com", Body: body,
+		}})
+		return err
+	}
+
+	if err := receive(strings.Repeat("x", 17)); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a body one byte over the limit, got %v", err)
+	}
+	if err := receive(strings.Repeat("x", 16)); err != nil {
+		t.Errorf("Expected a body at the limit to be accepted, got %v", err)
+	}
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "liam@test.com"})
+	if err != nil || len(resp.GetMessages()) != 1 {
+		t.Errorf("Expected only the message within the limit to be stored, got %v (err %v)", resp.GetMessages(), err)
+	}
+
+	accept, err := client.CanAccept(context.Background(), &proto.CanAcceptRequest{RecipientEmail: "liam@test.com", BodyBytes: 17})
+	if err != nil || accept.GetAccept() || accept.GetRetryable() {
+		t.Errorf("Expected a permanent refusal of an oversized body, got %v (err %v)", accept, err)
+	}
+}
+
+// TestMailbox_TrashAndUndelete tests restoring retrieved mail from the trash within the retention
+// period and the purge of expired trash.
+func TestMailbox_TrashAndUndelete(t *testing.T) {
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", TrashRetention: common.Duration(time.Hour), AllowPasswordless: true})
+	now := time.Now()
+	mailboxService.now = func() time.Time { return now }
+	client := startTestMailbox(t, mailboxService)
+	recipient := "erin@test.com"
+
+	receiveAndGet := func(t *testing.T, subject string) string {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: recipient, Subject: subject,
+		}})
+		if err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+		if err != nil || len(resp.GetMessages()) != 1 {
+			t.Fatalf("Expected 1 retrieved message, got %v (err %v)", resp.GetMessages(), err)
+		}
+		if resp.GetMessages()[0].GetMessageId() == "" {
+			t.Fatalf("Expected retrieved message to carry an id")
+		}
+		return resp.GetMessages()[0].GetMessageId()
+	}
+
+	t.Run("UndeleteWithinRetention", func(t *testing.T) {
+		id := receiveAndGet(t, "Oops")
+		now = now.Add(30 * time.Minute)
+
+		resp, err := client.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: recipient, MessageIds: []string{id}})
+		if err != nil {
+			t.Fatalf("UndeleteMail failed: %v", err)
+		}
+		if resp.GetRestored() != 1 {
+			t.Errorf("Expected 1 restored message, got %d", resp.GetRestored())
+		}
+		getResp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+		if err != nil || len(getResp.GetMessages()) != 1 || getResp.GetMessages()[0].GetSubject() != "Oops" {
+			t.Errorf("Expected the restored message back in the inbox, got %v (err %v)", getResp.GetMessages(), err)
+		}
+	})
+
+	t.Run("PurgeAfterRetention", func(t *testing.T) {
+		id := receiveAndGet(t, "Gone")
+		now = now.Add(2 * time.Hour)
+		mailboxService.purgeExpiredTrash()
+
+		resp, err := client.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: recipient, MessageIds: []string{id}})
+		if err != nil {
+			t.Fatalf("UndeleteMail failed: %v", err)
+		}
+		if resp.GetRestored() != 0 {
+			t.Errorf("Expected no restored messages after the retention expired, got %d", resp.GetRestored())
+		}
+		if len(mailboxService.userTrash) != 0 {
+			t.Errorf("Expected the trash to be empty after purging, got %v", mailboxService.userTrash)
+		}
+	})
+
+	t.Run("UndeleteWithoutIDs", func(t *testing.T) {
+		_, err := client.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: recipient})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument error without message ids, got %v", err)
+		}
+	})
+
+	t.Run("UndeleteRespectsQuota", func(t *testing.T) {
+		for _, tc := range []struct {
+			policy       string
+			wantCode     codes.Code
+			wantSubjects string
+		}{
+			{common.OverflowReject, codes.ResourceExhausted, "[Newer]"},
+			{common.OverflowDropOldest, codes.OK, "[Trashed]"},
+		} {
+			t.Run(tc.policy, func(t *testing.T) {
+				s := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", TrashRetention: common.Duration(time.Hour),
+					MaxMessagesPerUser: 1, OverflowPolicy: tc.policy, AllowPasswordless: true})
+				receive := func(subject string) {
+					if err := receiveError(s.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+						SenderEmail: "sender@domain.com", RecipientEmail: recipient, Subject: subject,
+					}})); err != nil {
+						t.Fatalf("ReceiveMail failed: %v", err)
+					}
+				}
+				receive("Trashed")
+				resp, err := s.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+				if err != nil || len(resp.GetMessages()) != 1 {
+					t.Fatalf("Expected 1 retrieved message, got %v (err %v)", resp.GetMessages(), err)
+				}
+				receive("Newer")
+
+				_, err = s.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: recipient, MessageIds: []string{resp.GetMessages()[0].GetMessageId()}})
+				if status.Code(err) != tc.wantCode {
+					t.Errorf("Expected %v, got %v", tc.wantCode, err)
+				}
+				var subjects []string
+				for _, msg := range s.userInboxes[recipient] {
+					subjects = append(subjects, msg.GetSubject())
+				}
+				if fmt.Sprint(subjects) != tc.wantSubjects {
+					t.Errorf("Expected inbox %s, got %v", tc.wantSubjects, subjects)
+				}
+				if wantTrash := tc.wantCode != codes.OK; (len(s.userTrash[recipient]) == 1) != wantTrash {
+					t.Errorf("Expected the message in the trash: %v, got trash %v", wantTrash, s.userTrash[recipient])
+				}
+			})
+		}
+	})
+}
+
+// TestMailbox_ClearGracePeriod tests that mail cleared by GetMail can be restored within the grace period
+// even without a trash retention, and that acknowledged mail is not kept.
+func TestMailbox_ClearGracePeriod(t *testing.T) {
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", ClearGracePeriod: common.Duration(30 * time.Second), AllowPasswordless: true})
+	now := time.Now()
+	mailboxService.now = func() time.Time { return now }
+	client := startTestMailbox(t, mailboxService)
+	recipient := "grace@test.com"
+
+	receiveAndGet := func(t *testing.T, subject string) string {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: recipient, Subject: subject,
+		}})
+		if err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+		if err != nil || len(resp.GetMessages()) != 1 {
+			t.Fatalf("Expected 1 retrieved message, got %v (err %v)", resp.GetMessages(), err)
+		}
+		return resp.GetMessages()[0].GetMessageId()
+	}
+	undelete := func(t *testing.T, id string) int32 {
+		resp, err := client.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: recipient, MessageIds: []string{id}})
+		if err != nil {
+			t.Fatalf("UndeleteMail failed: %v", err)
+		}
+		return resp.GetRestored()
+	}
+
+	t.Run("RecoverAfterClientCrash", func(t *testing.T) {
+		id := receiveAndGet(t, "Crashed")
+
+		// The client crashed before processing the mail and retries: the mail is no longer returned
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+		if err != nil || len(resp.GetMessages()) != 0 {
+			t.Fatalf("Expected no mail from a second GetMail within the grace period, got %v (err %v)", resp.GetMessages(), err)
+		}
+
+		now = now.Add(10 * time.Second)
+		if restored := undelete(t, id); restored != 1 {
+			t.Fatalf("Expected 1 restored message within the grace period, got %d", restored)
+		}
+		resp, err = client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient})
+		if err != nil || len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetSubject() != "Crashed" {
+			t.Errorf("Expected the restored message, got %v (err %v)", resp.GetMessages(), err)
+		}
+	})
+
+	t.Run("ExpiredGracePeriod", func(t *testing.T) {
+		id := receiveAndGet(t, "Late")
+		now = now.Add(time.Minute)
+		if restored := undelete(t, id); restored != 0 {
+			t.Errorf("Expected no restored messages after the grace period, got %d", restored)
+		}
+	})
+
+	t.Run("AcknowledgedMailDiscarded", func(t *testing.T) {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: recipient, Subject: "Acked",
+		}})
+		if err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+		keep := false
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: recipient, AutoAck: &keep})
+		if err != nil || len(resp.GetMessages()) != 1 {
+			t.Fatalf("Expected 1 retrieved message, got %v (err %v)", resp.GetMessages(), err)
+		}
+		id := resp.GetMessages()[0].GetMessageId()
+		if _, err := client.DeleteMail(context.Background(), &proto.DeleteMailRequest{EmailAddress: recipient, MessageIds: []string{id}}); err != nil {
+			t.Fatalf("DeleteMail failed: %v", err)
+		}
+		if restored := undelete(t, id); restored != 0 {
+			t.Errorf("Expected explicitly acknowledged mail not to be kept, got %d restored", restored)
+		}
+	})
+}
+
+// tokenAuthenticator is a common.Authenticator accepting only the token stored for each email address.
+type tokenAuthenticator map[string]string
+
+func (a tokenAuthenticator) ValidateToken(ctx context.Context, emailAddress string) error {
+	token := common.AuthToken(ctx)
+	if token == "" {
+		return status.Errorf(codes.Unauthenticated, "missing access token")
+	}
+	if want, ok := a[emailAddress]; !ok || token != want {
+		return status.Errorf(codes.PermissionDenied, "token not valid for '%s'", emailAddress)
+	}
+	return nil
+}
+
+// TestMailbox_Authenticator tests that a configured authenticator guards GetMail while ReceiveMail stays open.
+func TestMailbox_Authenticator(t *testing.T) {
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", RetainOnGet: true})
+	mailboxService.SetAuthenticator(tokenAuthenticator{"gina@test.com": "secret"})
+	client := startTestMailbox(t, mailboxService)
+
+	_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "gina@test.com", Subject: "Private",
+	}})
+	if err != nil {
+		t.Fatalf("Expected ReceiveMail to need no token, got %v", err)
+	}
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer "+token)
+	}
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{"ValidToken", withToken("secret"), codes.OK},
+		{"WrongToken", withToken("guess"), codes.PermissionDenied},
+		{"MissingToken", context.Background(), codes.Unauthenticated},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.GetMail(tc.ctx, &proto.GetMailRequest{EmailAddress: "gina@test.com"})
+			if code := status.Code(err); code != tc.wantCode {
+				t.Fatalf("Expected code %s, got %v", tc.wantCode, err)
+			}
+			if tc.wantCode == codes.OK && len(resp.GetMessages()) != 1 {
+				t.Errorf("Expected 1 message for the authorized caller, got %v", resp.GetMessages())
+			}
+		})
+	}
+
+	t.Run("DefaultIsOpen", func(t *testing.T) {
+		open := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", AllowPasswordless: true}))
+		if _, err := open.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "gina@test.com"}); err != nil {
+			t.Errorf("Expected GetMail without a token to succeed by default, got %v", err)
+		}
+	})
+}
+
+// TestMailbox_Passwords tests that once a user has set a password, GetMail and DeleteMail need it, that only
+// administrators set first passwords, and that the hashes survive a restart.
+func TestMailbox_Passwords(t *testing.T) {
+	cfg := common.MailboxConfig{Domain: "test.com", RetainOnGet: true, StateDir: t.TempDir(), AllowPasswordless: true, AdminToken: "admin"}
+	client := startTestMailbox(t, newConfiguredServer(t, cfg))
+	withPassword := func(password string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer "+password)
+	}
+
+	t.Run("FirstPasswordNeedsAdmin", func(t *testing.T) {
+		for _, ctx := range []context.Context{context.Background(), withPassword("guess")} {
+			_, err := client.SetPassword(ctx, &proto.SetPasswordRequest{EmailAddress: "hana@test.com", Password: "mine"})
+			if status.Code(err) != codes.PermissionDenied {
+				t.Errorf("Expected claiming an address without the admin token to fail, got %v", err)
+			}
+		}
+	})
+	t.Run("ForeignDomain", func(t *testing.T) {
+		_, err := client.SetPassword(withPassword("admin"), &proto.SetPasswordRequest{EmailAddress: "hana@other.com", Password: "pw"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected a password for another domain to be rejected, got %v", err)
+		}
+	})
+
+	resp, err := client.SetPassword(withPassword("admin"), &proto.SetPasswordRequest{EmailAddress: "Hana@test.com", Password: "s3cret"})
+	if err != nil || resp.GetReplaced() {
+		t.Fatalf("Expected the first password to be set, got %v (err %v)", resp, err)
+	}
+	if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "hana@test.com", Subject: "Private",
+	}}); err != nil {
+		t.Fatalf("Expected ReceiveMail to need no password, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{"MissingPassword", context.Background(), codes.Unauthenticated},
+		{"WrongPassword", withPassword("guess"), codes.Unauthenticated},
+		{"RightPassword", withPassword("s3cret"), codes.OK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.GetMail(tc.ctx, &proto.GetMailRequest{EmailAddress: "hana@test.com"})
+			if code := status.Code(err); code != tc.wantCode {
+				t.Fatalf("Expected GetMail to return %s, got %v", tc.wantCode, err)
+			}
+			if tc.wantCode == codes.OK && len(resp.GetMessages()) != 1 {
+				t.Errorf("Expected 1 message with the right password, got %v", resp.GetMessages())
+			}
+			_, err = client.DeleteMail(tc.ctx, &proto.DeleteMailRequest{EmailAddress: "hana@test.com", MessageIds: []string{"no-such-id"}})
+			if code := status.Code(err); code != tc.wantCode {
+				t.Errorf("Expected DeleteMail to return %s, got %v", tc.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("OtherUsersUnaffected", func(t *testing.T) {
+		if _, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "ivan@test.com"}); err != nil {
+			t.Errorf("Expected a user without a password to keep open access, got %v", err)
+		}
+	})
+
+	t.Run("ChangeNeedsCurrentPassword", func(t *testing.T) {
+		_, err := client.SetPassword(withPassword("guess"), &proto.SetPasswordRequest{EmailAddress: "hana@test.com", Password: "taken"})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Expected changing the password with a wrong one to fail, got %v", err)
+		}
+		resp, err := client.SetPassword(withPassword("s3cret"), &proto.SetPasswordRequest{EmailAddress: "hana@test.com", Password: "n3w"})
+		if err != nil || !resp.GetReplaced() {
+			t.Fatalf("Expected the password to be replaced, got %v (err %v)", resp, err)
+		}
+		if _, err := client.GetMail(withPassword("s3cret"), &proto.GetMailRequest{EmailAddress: "hana@test.com"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected the old password to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("AdminResets", func(t *testing.T) {
+		if _, err := client.SetPassword(withPassword("admin"), &proto.SetPasswordRequest{EmailAddress: "hana@test.com", Password: "n3w"}); err != nil {
+			t.Fatalf("Expected the admin to reset the password, got %v", err)
+		}
+	})
+
+	t.Run("PersistedHashed", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(cfg.StateDir, passwordsFileName("test.com")))
+		if err != nil {
+			t.Fatalf("Failed to read the passwords file: %v", err)
+		}
+		if strings.Contains(string(data), "n3w") {
+			t.Errorf("Expected the password to be stored hashed, got %s", data)
+		}
+		restarted := startTestMailbox(t, newConfiguredServer(t, cfg))
+		if _, err := restarted.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "hana@test.com"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected the password to survive a restart, got %v", err)
+		}
+		if _, err := restarted.GetMail(withPassword("n3w"), &proto.GetMailRequest{EmailAddress: "hana@test.com"}); err != nil {
+			t.Errorf("Expected the password to work after a restart, got %v", err)
+		}
+	})
+
+	t.Run("RequiredByDefault", func(t *testing.T) {
+		strict := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", AdminToken: "admin"}))
+		if _, err := strict.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "ivan@test.com"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected a user without a password to be rejected, got %v", err)
+		}
+		if _, err := strict.SetPassword(context.Background(), &proto.SetPasswordRequest{EmailAddress: "ivan@test.com", Password: "pw"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected the first password to need the admin token even when passwords are required, got %v", err)
+		}
+		if _, err := strict.SetPassword(withPassword("admin"), &proto.SetPasswordRequest{EmailAddress: "ivan@test.com", Password: "pw"}); err != nil {
+			t.Fatalf("Expected the admin to set a new user's password, got %v", err)
+		}
+		if _, err := strict.GetMail(withPassword("pw"), &proto.GetMailRequest{EmailAddress: "ivan@test.com"}); err != nil {
+			t.Errorf("Expected access with the password, got %v", err)
+		}
+	})
+}
+
+// TestMailbox_FirstPasswordProof tests that a configured authenticator can prove ownership of an address for
+// its first password, and that with a Nameserver configured only registered addresses get a password.
+func TestMailbox_FirstPasswordProof(t *testing.T) {
+	ns := nameserver.NewServer([]string{"test.com"})
+	if _, err := ns.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "gina@test.com", MailboxAddress: "localhost:1001"}); err != nil {
+		t.Fatalf("RegisterMailbox failed: %v", err)
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	nsServer := grpc.NewServer()
+	proto.RegisterNameserverServer(nsServer, ns)
+	go nsServer.Serve(lis)
+	t.Cleanup(nsServer.Stop)
+
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", NameserverAddr: lis.Addr().String()})
+	mailboxService.SetAuthenticator(tokenAuthenticator{"gina@test.com": "secret", "hugo@test.com": "secret"})
+	client := startTestMailbox(t, mailboxService)
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer "+token)
+	}
+
+	if _, err := client.SetPassword(withToken("wrong"), &proto.SetPasswordRequest{EmailAddress: "gina@test.com", Password: "pw"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected a wrong token to be rejected, got %v", err)
+	}
+	if _, err := client.SetPassword(withToken("secret"), &proto.SetPasswordRequest{EmailAddress: "hugo@test.com", Password: "pw"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected an unregistered address to be rejected, got %v", err)
+	}
+	if _, err := client.SetPassword(withToken("secret"), &proto.SetPasswordRequest{EmailAddress: "gina@test.com", Password: "pw"}); err != nil {
+		t.Errorf("Expected the authenticated, registered user to set a password, got %v", err)
+	}
+}
+
+// TestMailbox_CanAccept tests that CanAccept reports the outcome ReceiveMail would have without storing mail,
+// except that it does not disclose blocked senders.
+func TestMailbox_CanAccept(t *testing.T) {
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{
+		Domain: "test.com", MaxMessagesPerUser: 1, BlockedSenders: []string{"spam@domain.com"},
+	})
+	client := startTestMailbox(t, mailboxService)
+	_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "full@test.com", Subject: "Fills the inbox",
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	tests := []struct {
+		name, recipient, sender string
+		wantAccept, wantRetry   bool
+	}{
+		{"Accepts", "hank@test.com", "sender@domain.com", true, false},
+		{"FullInbox", "full@test.com", "sender@domain.com", false, true},
+		{"BlockedSenderNotDisclosed", "hank@test.com", "spam@domain.com", true, false},
+		{"ForeignDomain", "hank@other.com", "sender@domain.com", false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.CanAccept(context.Background(), &proto.CanAcceptRequest{RecipientEmail: tc.recipient, SenderEmail: tc.sender})
+			if err != nil {
+				t.Fatalf("CanAccept failed: %v", err)
+			}
+			if resp.GetAccept() != tc.wantAccept || resp.GetRetryable() != tc.wantRetry {
+				t.Errorf("Expected accept=%v retryable=%v, got %v", tc.wantAccept, tc.wantRetry, resp)
+			}
+			if !tc.wantAccept && resp.GetReason() == "" {
+				t.Errorf("Expected a reason for the refusal")
+			}
+		})
+	}
+	if n := len(mailboxService.userInboxes["hank@test.com"]); n != 0 {
+		t.Errorf("Expected CanAccept not to store mail, got %d messages", n)
+	}
+}
+
+// TestMailbox_Persistence tests that inboxes survive a restart when a state directory is configured.
+func TestMailbox_Persistence(t *testing.T) {
+	cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir()}
+	first := newConfiguredServer(t, cfg)
+	_, err := first.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "frank@test.com", Subject: "Persistent", Body: "Still here",
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	restarted := newConfiguredServer(t, cfg)
+	resp, err := restarted.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "frank@test.com"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetBody() != "Still here" {
+		t.Errorf("Expected the persisted message after restart, got %v", resp.GetMessages())
+	}
+
+	// The clear performed by GetMail is persisted as well
+	if resp, _ := newConfiguredServer(t, cfg).GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "frank@test.com"}); len(resp.GetMessages()) != 0 {
+		t.Errorf("Expected an empty inbox after the persisted clear, got %v", resp.GetMessages())
+	}
+}
+
+// TestMailbox_InterruptedPersist tests that a save failing partway leaves the previous state file intact and loadable.
+func TestMailbox_InterruptedPersist(t *testing.T) {
+	dir := t.TempDir()
+	first := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", StateDir: dir})
+	_, err := first.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "frank@test.com", Subject: "Committed", Body: "Survives",
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	// With a 250 byte state file name, the name of the temporary file written next to it exceeds the 255 byte
+	// limit of common filesystems, so the next save fails after the previous state has been committed
+	cfg := common.MailboxConfig{Domain: "test.com", StateDir: dir, InstanceName: strings.Repeat("i", 228)}
+	statePath := common.StatePath(cfg.StateDir, cfg.InstanceName, stateFileName(cfg.Domain))
+	committed, err := os.ReadFile(first.statePath)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	if err := os.WriteFile(statePath, committed, 0o644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	interrupted := newConfiguredServer(t, cfg)
+	_, err = interrupted.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "frank@test.com", Subject: "Interrupted", Body: "Lost",
+	}})
+	if err == nil {
+		t.Fatalf("Expected ReceiveMail to fail when its state cannot be saved")
+	}
+	if data, err := os.ReadFile(statePath); err != nil || !bytes.Equal(data, committed) {
+		t.Errorf("Expected the previous state file to survive the failed save, got %q (%v)", data, err)
+	}
+
+	restarted := newConfiguredServer(t, cfg)
+	resp, err := restarted.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "frank@test.com"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetBody() != "Survives" {
+		t.Errorf("Expected only the previously committed message, got %v", resp.GetMessages())
+	}
+}
+
+// TestMailbox_FailedPersistUndoesEviction tests that when saving a message fails, the messages evicted to
+// make room for it are restored along with the rest of the inbox.
+func TestMailbox_FailedPersistUndoesEviction(t *testing.T) {
+	cfg := common.MailboxConfig{
+		Domain: "test.com", StateDir: t.TempDir(), MaxMessagesPerUser: 2, OverflowPolicy: common.OverflowDropOldest, AllowPasswordless: true,
+	}
+	s := newConfiguredServer(t, cfg)
+	receive := func(subject string) error {
+		return receiveError(s.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: "frank@test.com", Subject: subject, Timestamp: time.Now().Unix(),
+		}}))
+	}
+	for _, subject := range []string{"first", "second"} {
+		if err := receive(subject); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	// A non-empty directory in place of the state file makes the next save fail
+	if err := os.Remove(s.statePath); err != nil {
+		t.Fatalf("Failed to remove state file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.statePath, "blocker"), 0o755); err != nil {
+		t.Fatalf("Failed to create blocking directory: %v", err)
+	}
+	if err := receive("third"); status.Code(err) != codes.Internal {
+		t.Fatalf("Expected Internal for a failed save, got %v", err)
+	}
+	var subjects []string
+	for _, msg := range s.userInboxes["frank@test.com"] {
+		subjects = append(subjects, msg.GetSubject())
+	}
+	if !reflect.DeepEqual(subjects, []string{"first", "second"}) {
+		t.Errorf("Expected the evicted message to be restored, got %v", subjects)
+	}
+}
+
+// TestMailbox_EncryptionAtRest tests that message bodies are encrypted in the state file while the metadata
+// stays readable, and that a restart with the same key restores identical messages.
+func TestMailbox_EncryptionAtRest(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	t.Setenv("TEST_MAILBOX_KEY", key)
+	cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), EncryptionKeyEnv: "TEST_MAILBOX_KEY"}
+	original := &proto.MailMessage{
+		SenderEmail: "sender@domain.com", RecipientEmail: "judy@test.com", Subject: "Payroll", Body: "Top secret salary figures",
+	}
+	first := newConfiguredServer(t, cfg)
+	if _, err := first.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: protobuf.Clone(original).(*proto.MailMessage)}); err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+	stored := first.userInboxes["judy@test.com"][0]
+
+	data, err := os.ReadFile(first.statePath)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	if strings.Contains(string(data), original.Body) {
+		t.Errorf("Expected the body to be encrypted, found plaintext in %s", data)
+	}
+	if !strings.Contains(string(data), original.Subject) || !strings.Contains(string(data), original.SenderEmail) {
+		t.Errorf("Expected sender and subject to stay readable, got %s", data)
+	}
+
+	t.Run("ReloadWithKey", func(t *testing.T) {
+		restarted := newConfiguredServer(t, cfg)
+		inbox := restarted.userInboxes["judy@test.com"]
+		if len(inbox) != 1 || !protobuf.Equal(inbox[0], stored) {
+			t.Errorf("Expected the reloaded message to equal %v, got %v", stored, inbox)
+		}
+	})
+
+	t.Run("ReloadWithConfigKey", func(t *testing.T) {
+		withConfigKey := cfg
+		withConfigKey.EncryptionKeyEnv = ""
+		withConfigKey.EncryptionKey = key
+		inbox := newConfiguredServer(t, withConfigKey).userInboxes["judy@test.com"]
+		if len(inbox) != 1 || inbox[0].GetBody() != original.Body {
+			t.Errorf("Expected the decrypted body, got %v", inbox)
+		}
+	})
+
+	t.Run("ReloadWithoutKey", func(t *testing.T) {
+		withoutKey := cfg
+		withoutKey.EncryptionKeyEnv = ""
+		if _, err := NewServerWithConfig(withoutKey); err == nil {
+			t.Error("Expected loading encrypted state without a key to fail")
+		}
+	})
+
+	t.Run("ReloadWithWrongKey", func(t *testing.T) {
+		wrongKey := cfg
+		wrongKey.EncryptionKeyEnv = ""
+		wrongKey.EncryptionKey = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+		if _, err := NewServerWithConfig(wrongKey); err == nil {
+			t.Error("Expected loading encrypted state with the wrong key to fail")
+		}
+	})
+}
+
+// TestMailbox_CoalescedPersistence tests that coalesced writes lose no mail on graceful shutdown and that
+// the pending-write bound forces a save.
+func TestMailbox_CoalescedPersistence(t *testing.T) {
+	receive := func(t *testing.T, s *server, n int) {
+		for i := 0; i < n; i++ {
+			_, err := s.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "sender@domain.com", RecipientEmail: "ivy@test.com", Subject: fmt.Sprintf("Burst %d", i),
+			}})
+			if err != nil {
+				t.Fatalf("ReceiveMail failed: %v", err)
+			}
+		}
+	}
+	stored := func(t *testing.T, cfg common.MailboxConfig) int {
+		inboxes, err := loadInboxes(newConfiguredServer(t, cfg).statePath, nil)
+		if err != nil {
+			t.Fatalf("loadInboxes failed: %v", err)
+		}
+		return len(inboxes["ivy@test.com"])
+	}
+
+	t.Run("FlushOnShutdown", func(t *testing.T) {
+		cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), PersistInterval: common.Duration(time.Hour)}
+		mailboxService := newConfiguredServer(t, cfg)
+		receive(t, mailboxService, 10)
+		if n := stored(t, cfg); n != 0 {
+			t.Errorf("Expected writes to be coalesced, found %d messages on disk", n)
+		}
+		if err := mailboxService.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if n := stored(t, cfg); n != 10 {
+			t.Errorf("Expected all 10 messages on disk after shutdown, got %d", n)
+		}
+	})
+
+	t.Run("PendingWriteBound", func(t *testing.T) {
+		cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), PersistInterval: common.Duration(time.Hour), MaxPendingWrites: 4}
+		mailboxService := newConfiguredServer(t, cfg)
+		t.Cleanup(func() { mailboxService.Close() })
+		receive(t, mailboxService, 5)
+		if n := stored(t, cfg); n != 4 {
+			t.Errorf("Expected a forced save after 4 pending writes, got %d messages on disk", n)
+		}
+	})
+
+	t.Run("FlushAfterInterval", func(t *testing.T) {
+		cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), PersistInterval: common.Duration(20 * time.Millisecond)}
+		mailboxService := newConfiguredServer(t, cfg)
+		t.Cleanup(func() { mailboxService.Close() })
+		receive(t, mailboxService, 3)
+		deadline := time.Now().Add(2 * time.Second)
+		for stored(t, cfg) != 3 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := stored(t, cfg); n != 3 {
+			t.Errorf("Expected the coalesced save after the interval, got %d messages on disk", n)
+		}
+	})
+}
+
+// BenchmarkMailbox_ReceiveMailPersistence compares saving on every ReceiveMail with coalesced saves.
+func BenchmarkMailbox_ReceiveMailPersistence(b *testing.B) {
+	modes := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"PerWrite", 0},
+		{"Coalesced", 50 * time.Millisecond},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			mailboxService, err := NewServerWithConfig(common.MailboxConfig{
+				Domain: "test.com", StateDir: b.TempDir(), PersistInterval: common.Duration(mode.interval),
+			})
+			if err != nil {
+				b.Fatalf("NewServerWithConfig failed: %v", err)
+			}
+			defer mailboxService.Close()
+			msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "ivy@test.com", Subject: "Bench", Body: "Hello"}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Keep the inbox small so the benchmark measures write frequency, not state size
+				if i%100 == 0 {
+					mailboxService.mu.Lock()
+					mailboxService.userInboxes["ivy@test.com"] = nil
+					mailboxService.mu.Unlock()
+				}
+				req := &proto.ReceiveMailRequest{Message: protobuf.Clone(msg).(*proto.MailMessage)}
+				if _, err := mailboxService.ReceiveMail(context.Background(), req); err != nil {
+					b.Fatalf("ReceiveMail failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestMailbox_LowDiskSpace tests that new mail is rejected while disk space is low, but reads are still served.
+func TestMailbox_LowDiskSpace(t *testing.T) {
+	mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), MinFreeDiskBytes: 1 << 20, AllowPasswordless: true})
+	free := uint64(1 << 30)
+	mailboxService.freeDiskSpace = func(string) (uint64, error) { return free, nil }
+	client := startTestMailbox(t, mailboxService)
+
+	receive := func() error {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: "gina@test.com", Subject: "Hi",
+		}})
+		return err
+	}
+	if err := receive(); err != nil {
+		t.Fatalf("ReceiveMail with enough disk space failed: %v", err)
+	}
+
+	free = 1024 // Below the configured minimum
+	err := receive()
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted error on low disk space, got %v", err)
+	}
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "gina@test.com"})
+	if err != nil || len(resp.GetMessages()) != 1 {
+		t.Errorf("Expected GetMail to keep working on low disk space, got %v (err %v)", resp.GetMessages(), err)
+	}
+}
+
+// TestMailbox_Labels tests that labels are stored with messages and that GetMail can filter by label,
+// leaving non-matching messages in the inbox.
+func TestMailbox_Labels(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	for _, msg := range []*proto.MailMessage{
+		{SenderEmail: "news@domain.com", RecipientEmail: "hank@test.com", Subject: "Weekly", Labels: []string{"newsletter"}},
+		{SenderEmail: "shop@domain.com", RecipientEmail: "hank@test.com", Subject: "Receipt", Labels: []string{"transactional", "shop"}},
+		{SenderEmail: "friend@domain.com", RecipientEmail: "hank@test.com", Subject: "Hello"},
+	} {
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "hank@test.com", Labels: []string{"transactional"}})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetSubject() != "Receipt" {
+		t.Fatalf("Expected only the transactional message, got %v", resp.GetMessages())
+	}
+	if labels := resp.GetMessages()[0].GetLabels(); len(labels) != 2 || labels[1] != "shop" {
+		t.Errorf("Expected labels to be stored with the message, got %v", labels)
+	}
+
+	// Unfiltered retrieval returns the messages the filter left behind
+	resp, err = client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "hank@test.com"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 2 {
+		t.Errorf("Expected the 2 remaining messages, got %v", resp.GetMessages())
+	}
+}
+
+// TestMailbox_Pagination tests that offset and limit return a page with the total count, leave the inbox
+// untouched even when auto_ack is requested, and that a request without them still clears the inbox.
+func TestMailbox_Pagination(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	for i := 0; i < 5; i++ {
+		msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "jack@test.com", Subject: fmt.Sprintf("Msg %d", i),
+			Timestamp: int64(i + 1)}
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+	subjects := func(resp *proto.GetMailResponse) []string {
+		var out []string
+		for _, msg := range resp.GetMessages() {
+			out = append(out, msg.GetSubject())
+		}
+		return out
+	}
+
+	ack := true
+	tests := []struct {
+		name          string
+		offset, limit int32
+		want          []string
+	}{
+		{"FirstPage", 0, 2, []string{"Msg 0", "Msg 1"}},
+		{"LastPartialPage", 4, 2, []string{"Msg 4"}},
+		{"OffsetOnly", 3, 0, []string{"Msg 3", "Msg 4"}},
+		{"PastTheEnd", 7, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{
+				EmailAddress: "jack@test.com", Offset: tt.offset, Limit: tt.limit, AutoAck: &ack,
+			})
+			if err != nil {
+				t.Fatalf("GetMail failed: %v", err)
+			}
+			if got := subjects(resp); fmt.Sprint(got) != fmt.Sprint(tt.want) || resp.GetTotalCount() != 5 {
+				t.Errorf("Expected %v of 5, got %v of %d", tt.want, got, resp.GetTotalCount())
+			}
+		})
+	}
+
+	t.Run("NegativeLimit", func(t *testing.T) {
+		_, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "jack@test.com", Limit: -1})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument, got %v", err)
+		}
+	})
+	t.Run("UnpagedClears", func(t *testing.T) {
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "jack@test.com"})
+		if err != nil || len(resp.GetMessages()) != 5 || resp.GetTotalCount() != 5 {
+			t.Fatalf("Expected all 5 messages after paging, got %v (err %v)", subjects(resp), err)
+		}
+		resp, err = client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "jack@test.com", Limit: 2})
+		if err != nil || len(resp.GetMessages()) != 0 || resp.GetTotalCount() != 0 {
+			t.Errorf("Expected an empty inbox, got %v (err %v)", subjects(resp), err)
+		}
+	})
+}
+
+// TestMailbox_ReadFlags tests that new mail is unread, MarkRead flags messages durably, GetMail can filter
+// for unread messages and reports the unread count.
+func TestMailbox_ReadFlags(t *testing.T) {
+	cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir()}
+	mailboxService := newConfiguredServer(t, cfg)
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("read-%d", i)
+		msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "kate@test.com", MessageId: ids[i], Read: i == 0}
+		if _, err := mailboxService.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+	keep := false
+	getMail := func(t *testing.T, server *server, unreadOnly bool) *proto.GetMailResponse {
+		resp, err := server.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "kate@test.com", UnreadOnly: unreadOnly, AutoAck: &keep})
+		if err != nil {
+			t.Fatalf("GetMail failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("NewMailIsUnread", func(t *testing.T) {
+		resp := getMail(t, mailboxService, false)
+		if resp.GetUnreadCount() != 3 || resp.GetMessages()[0].GetRead() {
+			t.Errorf("Expected 3 unread messages, including the one sent as read, got %d: %v", resp.GetUnreadCount(), resp.GetMessages())
+		}
+	})
+	t.Run("MarkRead", func(t *testing.T) {
+		resp, err := mailboxService.MarkRead(context.Background(), &proto.MarkReadRequest{EmailAddress: "kate@test.com", MessageIds: []string{ids[0], ids[2], "unknown"}})
+		if err != nil || resp.GetMarked() != 2 {
+			t.Fatalf("Expected 2 messages marked read, got %v (err %v)", resp, err)
+		}
+		resp, err = mailboxService.MarkRead(context.Background(), &proto.MarkReadRequest{EmailAddress: "kate@test.com", MessageIds: []string{ids[0]}})
+		if err != nil || resp.GetMarked() != 0 {
+			t.Errorf("Expected marking a read message again to change nothing, got %v (err %v)", resp, err)
+		}
+		if _, err := mailboxService.MarkRead(context.Background(), &proto.MarkReadRequest{EmailAddress: "kate@test.com"}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument without message ids, got %v", err)
+		}
+	})
+	t.Run("UnreadOnlySurvivesRestart", func(t *testing.T) {
+		resp := getMail(t, newConfiguredServer(t, cfg), true)
+		if len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetMessageId() != ids[1] || resp.GetUnreadCount() != 1 {
+			t.Errorf("Expected only %s as unread, got %v (unread count %d)", ids[1], resp.GetMessages(), resp.GetUnreadCount())
+		}
+	})
+}
+
+// failingMailStream is a StreamMail server stream whose Send fails once failAfter messages were sent.
+type failingMailStream struct {
+	grpc.ServerStream
+	failAfter int
+	sent      int
+}
+
+func (f *failingMailStream) Context() context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(common.AuthTokenMetadataKey, "Bearer secret"))
+}
+
+func (f *failingMailStream) Send(msg *proto.MailMessage) error {
+	if f.sent >= f.failAfter {
+		return status.Errorf(codes.Unavailable, "client went away")
+	}
+	f.sent++
+	return nil
+}
+
+// TestMailbox_StreamMail tests that StreamMail sends every message in chronological order, clears the inbox
+// only after a complete stream, and checks the caller's token.
+func TestMailbox_StreamMail(t *testing.T) {
+	mailboxService := NewServer("test.com")
+	mailboxService.SetAuthenticator(tokenAuthenticator{"nina@test.com": "secret"})
+	client := startTestMailbox(t, mailboxService)
+	for i := 0; i < 3; i++ {
+		msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "nina@test.com", Subject: fmt.Sprintf("Msg %d", i),
+			Timestamp: int64(1000 - 10*i)} // Received newest first
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+	inboxSize := func() int {
+		mailboxService.mu.RLock()
+		defer mailboxService.mu.RUnlock()
+		return len(mailboxService.userInboxes["nina@test.com"])
+	}
+
+	t.Run("AbortedStreamKeepsInbox", func(t *testing.T) {
+		err := mailboxService.StreamMail(&proto.StreamMailRequest{EmailAddress: "nina@test.com"}, &failingMailStream{failAfter: 1})
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("Expected the send error to be returned, got %v", err)
+		}
+		if n := inboxSize(); n != 3 {
+			t.Errorf("Expected all 3 messages to stay after an aborted stream, got %d", n)
+		}
+	})
+	t.Run("MissingToken", func(t *testing.T) {
+		stream, err := client.StreamMail(context.Background(), &proto.StreamMailRequest{EmailAddress: "nina@test.com"})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated, got %v", err)
+		}
+	})
+	t.Run("CompleteStreamClears", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer secret")
+		stream, err := client.StreamMail(ctx, &proto.StreamMailRequest{EmailAddress: "nina@test.com"})
+		if err != nil {
+			t.Fatalf("StreamMail failed: %v", err)
+		}
+		var subjects []string
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Recv failed: %v", err)
+			}
+			subjects = append(subjects, msg.GetSubject())
+		}
+		if fmt.Sprint(subjects) != "[Msg 2 Msg 1 Msg 0]" {
+			t.Errorf("Expected all messages oldest first, got %v", subjects)
+		}
+		if n := inboxSize(); n != 0 {
+			t.Errorf("Expected the inbox to be cleared after the stream, got %d messages", n)
+		}
+	})
+}
+
+// TestMailbox_WatchMail tests that WatchMail pushes only the watched user's new mail and drops the subscriber
+// once the stream is cancelled.
+func TestMailbox_WatchMail(t *testing.T) {
+	mailboxService := NewServer("test.com")
+	client := startTestMailbox(t, mailboxService)
+	watchers := func() int {
+		mailboxService.mu.RLock()
+		defer mailboxService.mu.RUnlock()
+		return len(mailboxService.watchers["olga@test.com"])
+	}
+	waitForWatchers := func(t *testing.T, want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for watchers() != want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := watchers(); n != want {
+			t.Fatalf("Expected %d watchers, got %d", want, n)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchMail(ctx, &proto.WatchMailRequest{EmailAddress: "olga@test.com"})
+	if err != nil {
+		t.Fatalf("WatchMail failed: %v", err)
+	}
+	waitForWatchers(t, 1)
+
+	for _, msg := range []*proto.MailMessage{
+		{SenderEmail: "sender@domain.com", RecipientEmail: "someone@test.com", Subject: "Not for Olga"},
+		{SenderEmail: "sender@domain.com", RecipientEmail: "olga@test.com", Subject: "Live"},
+	} {
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+	msg, err := stream.Recv()
+	if err != nil || msg.GetSubject() != "Live" {
+		t.Fatalf("Expected the pushed message 'Live', got %v (err %v)", msg, err)
+	}
+
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "olga@test.com"})
+	if err != nil || len(resp.GetMessages()) != 1 {
+		t.Errorf("Expected the pushed message to stay in the inbox, got %v (err %v)", resp.GetMessages(), err)
+	}
+
+	cancel()
+	waitForWatchers(t, 0)
+}
+
+// TestMailbox_SearchMail tests case-insensitive matching on sender, subject and optionally body, and that
+// searching neither clears the inbox nor fails for an empty one.
+func TestMailbox_SearchMail(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	search := func(t *testing.T, query string, includeBody bool) []string {
+		resp, err := client.SearchMail(context.Background(), &proto.SearchMailRequest{EmailAddress: "mia@test.com", Query: query, IncludeBody: includeBody})
+		if err != nil {
+			t.Fatalf("SearchMail failed: %v", err)
+		}
+		var subjects []string
+		for _, msg := range resp.GetMessages() {
+			subjects = append(subjects, msg.GetSubject())
+		}
+		return subjects
+	}
+
+	t.Run("EmptyInbox", func(t *testing.T) {
+		if got := search(t, "anything", true); len(got) != 0 {
+			t.Errorf("Expected no matches in an empty inbox, got %v", got)
+		}
+	})
+
+	for _, msg := range []*proto.MailMessage{
+		{SenderEmail: "Billing@Shop.com", RecipientEmail: "mia@test.com", Subject: "Your invoice", Body: "Amount due"},
+		{SenderEmail: "friend@domain.com", RecipientEmail: "mia@test.com", Subject: "Lunch?", Body: "About that INVOICE..."},
+		{SenderEmail: "news@domain.com", RecipientEmail: "mia@test.com", Subject: "Weekly digest", Body: "Nothing new"},
+	} {
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		includeBody bool
+		want        []string
+	}{
+		{"Subject", "INVOICE", false, []string{"Your invoice"}},
+		{"SubjectAndBody", "invoice", true, []string{"Your invoice", "Lunch?"}},
+		{"Sender", "shop.COM", false, []string{"Your invoice"}},
+		{"NoMatch", "vacation", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(t, tt.query, tt.includeBody); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("EmptyQuery", func(t *testing.T) {
+		_, err := client.SearchMail(context.Background(), &proto.SearchMailRequest{EmailAddress: "mia@test.com"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an empty query, got %v", err)
+		}
+	})
+	t.Run("InboxUntouched", func(t *testing.T) {
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "mia@test.com"})
+		if err != nil || len(resp.GetMessages()) != 3 {
+			t.Errorf("Expected all 3 messages to remain after searching, got %v (err %v)", resp.GetMessages(), err)
+		}
+	})
+}
+
+// TestMailbox_DuplicateDelivery tests that a message delivered twice with the same ID is stored once, also
+// once it was retrieved, that both deliveries are acknowledged as successful, and that the IDs kept are bounded.
+func TestMailbox_DuplicateDelivery(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "ivy@test.com", Subject: "Once", MessageId: "dup-1"}
+	for i := 0; i < 2; i++ {
+		resp, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("Delivery %d: expected success, got %v (err %v)", i+1, resp, err)
+		}
+	}
+	other := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: "ivy@test.com", Subject: "Twice", MessageId: "dup-2"}
+	if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: other}); err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "ivy@test.com"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 2 || resp.GetMessages()[0].GetMessageId() != "dup-1" || resp.GetMessages()[1].GetMessageId() != "dup-2" {
+		t.Errorf("Expected each message exactly once, got %v", resp.GetMessages())
+	}
+
+	t.Run("AfterRetrieval", func(t *testing.T) {
+		resp, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("Expected the redelivery to be acknowledged, got %v (err %v)", resp, err)
+		}
+		get, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "ivy@test.com"})
+		if err != nil || len(get.GetMessages()) != 0 {
+			t.Errorf("Expected the redelivery of retrieved mail to be dropped, got %v (err %v)", get.GetMessages(), err)
+		}
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		seen := &seenMessages{ids: make(map[string]bool)}
+		for i := 0; i <= maxSeenMessageIDs; i++ {
+			seen.add(fmt.Sprintf("id-%d", i))
+		}
+		if seen.contains("id-0") || !seen.contains("id-1") || len(seen.ids) != maxSeenMessageIDs {
+			t.Errorf("Expected only the latest %d IDs to be kept, got %d", maxSeenMessageIDs, len(seen.ids))
+		}
+	})
+}
+
+// TestMailbox_CaseInsensitive tests that mail for differently-cased addresses ends up in one inbox.
+func TestMailbox_CaseInsensitive(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	for i, recipient := range []string{"Jo@Test.com", "jo@test.com", "JO@TEST.COM"} {
+		msg := &proto.MailMessage{SenderEmail: "sender@domain.com", RecipientEmail: recipient, Subject: fmt.Sprintf("Mail %d", i)}
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail for '%s' failed: %v", recipient, err)
+		}
+	}
+
+	accept, err := client.CanAccept(context.Background(), &proto.CanAcceptRequest{RecipientEmail: "JO@test.com"})
+	if err != nil || !accept.GetAccept() {
+		t.Errorf("Expected CanAccept to accept a differently-cased recipient, got %v (err %v)", accept, err)
+	}
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "jO@tEsT.cOm"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	if len(resp.GetMessages()) != 3 {
+		t.Fatalf("Expected all 3 messages in one inbox, got %d", len(resp.GetMessages()))
+	}
+	if got := resp.GetMessages()[0].GetRecipientEmail(); got != "jo@test.com" {
+		t.Errorf("Expected the stored recipient to be normalized, got '%s'", got)
+	}
+}
+
+// TestMailbox_AutoAck tests that GetMail clears the inbox for auto_ack true or unset (legacy behavior)
+// and keeps messages pending a DeleteMail acknowledgement for auto_ack false.
+func TestMailbox_AutoAck(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	var sentAt int64
+	deliver := func(email, subject string) {
+		sentAt++ // Keeps the messages in the order delivered
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "sender@domain.com", RecipientEmail: email, Subject: subject, Timestamp: sentAt,
+		}})
+		if err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+	get := func(email string, autoAck *bool) []*proto.MailMessage {
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: email, AutoAck: autoAck})
+		if err != nil {
+			t.Fatalf("GetMail failed: %v", err)
+		}
+		return resp.GetMessages()
+	}
+	yes, no := true, false
+
+	t.Run("AutoAckTrue", func(t *testing.T) {
+		deliver("ivan@test.com", "Cleared")
+		if got := get("ivan@test.com", &yes); len(got) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(got))
+		}
+		if got := get("ivan@test.com", &yes); len(got) != 0 {
+			t.Errorf("Expected inbox to be cleared with auto_ack true, got %d messages", len(got))
+		}
+	})
+
+	t.Run("AutoAckUnsetIsLegacy", func(t *testing.T) {
+		deliver("judy@test.com", "Legacy")
+		get("judy@test.com", nil)
+		if got := get("judy@test.com", nil); len(got) != 0 {
+			t.Errorf("Expected legacy clear-on-read when auto_ack is unset, got %d messages", len(got))
+		}
+	})
+
+	t.Run("AutoAckFalse", func(t *testing.T) {
+		deliver("kim@test.com", "Keep 1")
+		deliver("kim@test.com", "Keep 2")
+		first := get("kim@test.com", &no)
+		if len(first) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(first))
+		}
+		if got := get("kim@test.com", &no); len(got) != 2 {
+			t.Fatalf("Expected messages to be retained pending ack, got %d", len(got))
+		}
+
+		resp, err := client.DeleteMail(context.Background(), &proto.DeleteMailRequest{
+			EmailAddress: "kim@test.com",
+			MessageIds:   []string{first[0].GetMessageId(), "unknown-id"},
+		})
+		if err != nil {
+			t.Fatalf("DeleteMail failed: %v", err)
+		}
+		if resp.GetDeleted() != 1 {
+			t.Errorf("Expected 1 message deleted, got %d", resp.GetDeleted())
+		}
+		if got := get("kim@test.com", &no); len(got) != 1 || got[0].GetSubject() != "Keep 2" {
+			t.Errorf("Expected only the unacknowledged message to remain, got %v", got)
+		}
+	})
+
+	t.Run("DeleteMailRequiresIDs", func(t *testing.T) {
+		_, err := client.DeleteMail(context.Background(), &proto.DeleteMailRequest{EmailAddress: "kim@test.com"})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument error, got %v", err)
+		}
+	})
+}
+
+// TestMailbox_ConcurrentFilteredGetMail hammers one user's inbox with concurrent filtered and unfiltered
+// GetMail calls and checks that every message is returned exactly once.
+func TestMailbox_ConcurrentFilteredGetMail(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	const messages = 200
+	labels := []string{"red", "green", "blue"}
+	for i := 0; i < messages; i++ {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			MessageId:      fmt.Sprintf("msg-%d", i),
+			SenderEmail:    "sender@domain.com",
+			RecipientEmail: "leo@test.com",
+			Labels:         []string{labels[i%len(labels)]},
+		}})
+		if err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		returned = make(map[string]int)
+		wg       sync.WaitGroup
+	)
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var filter []string
+			if worker%4 != 3 { // Every fourth worker retrieves unfiltered
+				filter = []string{labels[worker%len(labels)]}
+			}
+			for i := 0; i < 20; i++ {
+				resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "leo@test.com", Labels: filter})
+				if err != nil {
+					t.Errorf("GetMail failed: %v", err)
+					return
+				}
+				mu.Lock()
+				for _, msg := range resp.GetMessages() {
+					returned[msg.GetMessageId()]++
+				}
+				mu.Unlock()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// Collect whatever is left after the concurrent phase
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "leo@test.com"})
+	if err != nil {
+		t.Fatalf("GetMail failed: %v", err)
+	}
+	for _, msg := range resp.GetMessages() {
+		returned[msg.GetMessageId()]++
+	}
+
+	if len(returned) != messages {
+		t.Errorf("Expected all %d messages to be returned, got %d", messages, len(returned))
+	}
+	for id, count := range returned {
+		if count != 1 {
+			t.Errorf("Message '%s' returned %d times", id, count)
+		}
+	}
+}
+
+// TestMailbox_WaitForMail tests that WaitForMail returns mail arriving while it waits, respects the
+// after_message_id and after_sequence cursors, leaves the inbox untouched and returns empty on timeout.
+func TestMailbox_WaitForMail(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	deliver := func(id string) {
+		_, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+			MessageId: id, SenderEmail: "sender@domain.com", RecipientEmail: "mia@test.com",
+		}})
+		if err != nil {
+			t.Errorf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	t.Run("WakesOnArrival", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			deliver("w1")
+		}()
+		resp, err := client.WaitForMail(context.Background(), &proto.WaitForMailRequest{EmailAddress: "mia@test.com", TimeoutSeconds: 5})
+		if err != nil {
+			t.Fatalf("WaitForMail failed: %v", err)
+		}
+		if len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetMessageId() != "w1" {
+			t.Errorf("Expected the newly arrived message, got %v", resp.GetMessages())
+		}
+	})
+
+	t.Run("CursorAndTimeout", func(t *testing.T) {
+		start := time.Now()
+		resp, err := client.WaitForMail(context.Background(), &proto.WaitForMailRequest{
+			EmailAddress: "mia@test.com", AfterMessageId: "w1", TimeoutSeconds: 1,
+		})
+		if err != nil {
+			t.Fatalf("WaitForMail failed: %v", err)
+		}
+		if len(resp.GetMessages()) != 0 || time.Since(start) < 900*time.Millisecond {
+			t.Errorf("Expected an empty response after the timeout, got %v after %s", resp.GetMessages(), time.Since(start))
+		}
+
+		// The inbox was not cleared by waiting
+		get, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "mia@test.com"})
+		if err != nil || len(get.GetMessages()) != 1 {
+			t.Errorf("Expected the waited-for message to remain in the inbox, got %v (err %v)", get.GetMessages(), err)
+		}
+	})
+
+	t.Run("SequenceCursorAfterDeletion", func(t *testing.T) {
+		deliver("w2")
+		deliver("w3")
+		resp, err := client.WaitForMail(context.Background(), &proto.WaitForMailRequest{EmailAddress: "mia@test.com", TimeoutSeconds: 1})
+		if err != nil || len(resp.GetMessages()) != 2 || resp.GetMessages()[0].GetSequence() >= resp.GetMessages()[1].GetSequence() {
+			t.Fatalf("Expected w2 and w3 with increasing sequences, got %v (err %v)", resp.GetMessages(), err)
+		}
+		cursor := resp.GetMessages()[1].GetSequence()
+		if _, err := client.DeleteMail(context.Background(), &proto.DeleteMailRequest{EmailAddress: "mia@test.com", MessageIds: []string{"w3"}}); err != nil {
+			t.Fatalf("DeleteMail failed: %v", err)
+		}
+
+		// The cursor message is gone, but the older w2 must not be returned again
+		resp, err = client.WaitForMail(context.Background(), &proto.WaitForMailRequest{EmailAddress: "mia@test.com", AfterSequence: cursor, TimeoutSeconds: 1})
+		if err != nil || len(resp.GetMessages()) != 0 {
+			t.Errorf("Expected no mail after the deleted cursor message, got %v (err %v)", resp.GetMessages(), err)
+		}
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			deliver("w4")
+		}()
+		resp, err = client.WaitForMail(context.Background(), &proto.WaitForMailRequest{EmailAddress: "mia@test.com", AfterSequence: cursor, TimeoutSeconds: 5})
+		if err != nil || len(resp.GetMessages()) != 1 || resp.GetMessages()[0].GetMessageId() != "w4" {
+			t.Errorf("Expected only the newly arrived w4, got %v (err %v)", resp.GetMessages(), err)
+		}
+	})
+}
+
+// MockReceiptTransferServer is a mock TransferServer that delivers every message into one Mailbox.
+type MockReceiptTransferServer struct {
+	proto.UnimplementedTransferServerServer
+	mailbox proto.MailboxServer
+	sent    atomic.Int32
+}
+
+func (m *MockReceiptTransferServer) SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.SendMailResponse, error) {
+	m.sent.Add(1)
+	if _, err := m.mailbox.ReceiveMail(ctx, &proto.ReceiveMailRequest{Message: req.GetMessage()}); err != nil {
+		return nil, err
+	}
+	return &proto.SendMailResponse{Success: true, Message: "Mock mail sent"}, nil
+}
+
+// TestMailbox_ReadReceipt tests that retrieving a receipt-requested message sends a receipt to the
+// sender's mailbox exactly once, and that receipts do not trigger receipts themselves.
+func TestMailbox_ReadReceipt(t *testing.T) {
+	senderMailbox := NewServer("saturn.com")
+	transfer := &MockReceiptTransferServer{mailbox: senderMailbox}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterTransferServerServer(s, transfer)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	recipientMailbox := newConfiguredServer(t, common.MailboxConfig{Domain: "earth.com", RetainOnGet: true, TransferServerAddr: lis.Addr().String(), AllowPasswordless: true})
+	client := startTestMailbox(t, recipientMailbox)
+	_, err = client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		MessageId: "original", SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com",
+		Subject: "Please confirm", RequestReadReceipt: true,
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ { // Retained mail is retrieved twice, but only one receipt may be sent
+		resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "alice@earth.com"})
+		if err != nil {
+			t.Fatalf("GetMail failed: %v", err)
+		}
+		if i == 0 && !resp.GetMessages()[0].GetRequestReadReceipt() {
+			t.Errorf("Expected the retrieved message to still show the receipt request")
+		}
+	}
+
+	var receipts []*proto.MailMessage
+	deadline := time.Now().Add(5 * time.Second)
+	for len(receipts) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		resp, _ := senderMailbox.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "bob@saturn.com"})
+		receipts = resp.GetMessages()
+	}
+	if len(receipts) != 1 {
+		t.Fatalf("Expected one read receipt in the sender's mailbox, got %d", len(receipts))
+	}
+	receipt := receipts[0]
+	if receipt.GetReceiptForMessageId() != "original" || receipt.GetSenderEmail() != "alice@earth.com" || receipt.GetRequestReadReceipt() {
+		t.Errorf("Unexpected read receipt: %v", receipt)
+	}
+
+	// Retrieving a receipt that (wrongly) requests a receipt must not bounce another one back
+	_, err = client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Read: Hi",
+		RequestReadReceipt: true, ReceiptForMessageId: "other",
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+	client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "alice@earth.com"})
+	time.Sleep(100 * time.Millisecond)
+	if sent := transfer.sent.Load(); sent != 1 {
+		t.Errorf("Expected exactly 1 receipt sent, got %d", sent)
+	}
+}
+
+// TestMailbox_ReadReceiptAfterUndelete tests that a message retrieved into the trash and restored with
+// UndeleteMail does not send a second read receipt when it is retrieved again.
+func TestMailbox_ReadReceiptAfterUndelete(t *testing.T) {
+	transfer := &MockReceiptTransferServer{mailbox: NewServer("saturn.com")}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterTransferServerServer(s, transfer)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := startTestMailbox(t, newConfiguredServer(t, common.MailboxConfig{
+		Domain: "earth.com", TrashRetention: common.Duration(time.Hour), TransferServerAddr: lis.Addr().String(), AllowPasswordless: true,
+	}))
+	_, err = client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+		MessageId: "original", SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com",
+		Subject: "Please confirm", RequestReadReceipt: true,
+	}})
+	if err != nil {
+		t.Fatalf("ReceiveMail failed: %v", err)
+	}
+
+	resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "alice@earth.com"})
+	if err != nil || len(resp.GetMessages()) != 1 || !resp.GetMessages()[0].GetRequestReadReceipt() {
+		t.Fatalf("Expected the message with its receipt request, got %v (err %v)", resp.GetMessages(), err)
+	}
+	undeleted, err := client.UndeleteMail(context.Background(), &proto.UndeleteMailRequest{EmailAddress: "alice@earth.com", MessageIds: []string{"original"}})
+	if err != nil || undeleted.GetRestored() != 1 {
+		t.Fatalf("UndeleteMail failed: %v (err %v)", undeleted, err)
+	}
+	if resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{EmailAddress: "alice@earth.com"}); err != nil || len(resp.GetMessages()) != 1 {
+		t.Fatalf("Expected the restored message, got %v (err %v)", resp.GetMessages(), err)
+	}
+
+	time.Sleep(100 * time.Millisecond) // Receipts are sent in the background
+	if sent := transfer.sent.Load(); sent != 1 {
+		t.Errorf("Expected exactly 1 receipt sent, got %d", sent)
+	}
+}
+
+// TestMailbox_ExportImport tests that a dump of a populated mailbox imported into a fresh one reproduces
+// identical inboxes, that importing it again adds nothing, and that both RPCs are reserved for admins.
+func TestMailbox_ExportImport(t *testing.T) {
+	admin := metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer admin")
+	source := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", AdminToken: "admin"})
+	for _, msg := range []*proto.MailMessage{
+		{SenderEmail: "sender@domain.com", RecipientEmail: "kim@test.com", Subject: "First", Body: "One"},
+		{SenderEmail: "sender@domain.com", RecipientEmail: "kim@test.com", Subject: "Second", Body: "Two", Labels: []string{"work"}},
+		{SenderEmail: "other@domain.com", RecipientEmail: "lee@test.com", Subject: "Report",
+			Attachments: []*proto.Attachment{{Filename: "report.txt", ContentType: "text/plain", Data: []byte("figures")}}},
+	} {
+		if _, err := source.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	sourceClient := startTestMailbox(t, source)
+	wrongToken := metadata.AppendToOutgoingContext(context.Background(), common.AuthTokenMetadataKey, "Bearer guess")
+	for _, ctx := range []context.Context{context.Background(), wrongToken} {
+		stream, err := sourceClient.ExportMailbox(ctx, &proto.ExportMailboxRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected ExportMailbox without the admin token to fail with PermissionDenied, got %v", err)
+		}
+	}
+	stream, err := sourceClient.ExportMailbox(admin, &proto.ExportMailboxRequest{})
+	if err != nil {
+		t.Fatalf("ExportMailbox failed: %v", err)
+	}
+	var dump []*proto.MailboxDumpEntry
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Receiving export failed: %v", err)
+		}
+		dump = append(dump, entry)
+	}
+	if len(dump) != 3 {
+		t.Fatalf("Expected 3 exported messages, got %d", len(dump))
+	}
+
+	cfg := common.MailboxConfig{Domain: "test.com", StateDir: t.TempDir(), AdminToken: "admin"}
+	target := newConfiguredServer(t, cfg)
+	targetClient := startTestMailbox(t, target)
+	importDump := func(t *testing.T) *proto.ImportMailboxResponse {
+		t.Helper()
+		upload, err := targetClient.ImportMailbox(admin)
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		for _, entry := range dump {
+			if err := upload.Send(entry); err != nil {
+				t.Fatalf("Sending dump entry failed: %v", err)
+			}
+		}
+		resp, err := upload.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("IdenticalContents", func(t *testing.T) {
+		if resp := importDump(t); resp.GetImported() != 3 || resp.GetSkipped() != 0 {
+			t.Errorf("Expected 3 imported and 0 skipped, got %v", resp)
+		}
+		restarted := newConfiguredServer(t, cfg) // The import must also be persisted
+		for _, imported := range []*server{target, restarted} {
+			if len(imported.userInboxes) != len(source.userInboxes) {
+				t.Fatalf("Expected %d users, got %d", len(source.userInboxes), len(imported.userInboxes))
+			}
+			for emailAddress, want := range source.userInboxes {
+				got := imported.userInboxes[emailAddress]
+				if len(got) != len(want) {
+					t.Fatalf("Expected %d messages for '%s', got %d", len(want), emailAddress, len(got))
+				}
+				for i := range want {
+					if !protobuf.Equal(got[i], want[i]) {
+						t.Errorf("Message %d for '%s' differs: got %v, want %v", i, emailAddress, got[i], want[i])
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("ReimportSkipsDuplicates", func(t *testing.T) {
+		if resp := importDump(t); resp.GetImported() != 0 || resp.GetSkipped() != 3 {
+			t.Errorf("Expected 0 imported and 3 skipped, got %v", resp)
+		}
+		if n := len(target.userInboxes["kim@test.com"]); n != 2 {
+			t.Errorf("Expected 2 messages for 'kim@test.com' after reimport, got %d", n)
+		}
+	})
+
+	t.Run("InvalidEntry", func(t *testing.T) {
+		upload, err := targetClient.ImportMailbox(admin)
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		upload.Send(&proto.MailboxDumpEntry{Message: &proto.MailMessage{Subject: "Orphan"}})
+		if _, err := upload.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an entry without email address, got %v", err)
+		}
+	})
+
+	t.Run("ForeignDomain", func(t *testing.T) {
+		upload, err := targetClient.ImportMailbox(admin)
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		upload.Send(&proto.MailboxDumpEntry{EmailAddress: "mallory@other.com", Message: &proto.MailMessage{Subject: "Planted"}})
+		if _, err := upload.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an entry of another domain, got %v", err)
+		}
+		if _, found := target.userInboxes["mallory@other.com"]; found {
+			t.Error("Expected no inbox for the foreign address")
+		}
+	})
+
+	t.Run("RecipientFollowsEntry", func(t *testing.T) {
+		upload, err := targetClient.ImportMailbox(admin)
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		upload.Send(&proto.MailboxDumpEntry{EmailAddress: "Max@Test.com", Message: &proto.MailMessage{
+			MessageId: "misaddressed", SenderEmail: "sender@domain.com", RecipientEmail: "kim@test.com", Subject: "Moved",
+		}})
+		if resp, err := upload.CloseAndRecv(); err != nil || resp.GetImported() != 1 {
+			t.Fatalf("Expected 1 imported message, got %v (err %v)", resp, err)
+		}
+		inbox := target.userInboxes["max@test.com"]
+		if len(inbox) != 1 || inbox[0].GetRecipientEmail() != "max@test.com" {
+			t.Errorf("Expected the message stored for and addressed to 'max@test.com', got %v", inbox)
+		}
+	})
+
+	t.Run("NeedsAdmin", func(t *testing.T) {
+		upload, err := targetClient.ImportMailbox(context.Background())
+		if err != nil {
+			t.Fatalf("ImportMailbox failed: %v", err)
+		}
+		upload.Send(dump[0])
+		if _, err := upload.CloseAndRecv(); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected ImportMailbox without the admin token to fail with PermissionDenied, got %v", err)
+		}
+	})
+}
+
+// TestMailbox_InfoCapacity tests that Info advertises the configured capacity and the space remaining.
+func TestMailbox_InfoCapacity(t *testing.T) {
+	tests := []struct {
+		name          string
+		capacity      int
+		stored        int
+		wantRemaining int32
+	}{
+		{"Unlimited", 0, 3, 0},
+		{"PartlyUsed", 10, 3, 7},
+		{"OverCapacity", 2, 3, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mailboxService := newConfiguredServer(t, common.MailboxConfig{Domain: "test.com", Capacity: tc.capacity})
+			for i := 0; i < tc.stored; i++ {
+				_, err := mailboxService.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: &proto.MailMessage{
+					SenderEmail: "sender@domain.com", RecipientEmail: "mia@test.com", Subject: fmt.Sprintf("Message %d", i),
+				}})
+				if err != nil {
+					t.Fatalf("ReceiveMail failed: %v", err)
+				}
+			}
+			info, err := startTestMailbox(t, mailboxService).Info(context.Background(), &proto.MailboxInfoRequest{})
+			if err != nil {
+				t.Fatalf("Info failed: %v", err)
+			}
+			if info.GetCapacity() != int32(tc.capacity) || info.GetRemainingCapacity() != tc.wantRemaining || info.GetMessages() != int32(tc.stored) {
+				t.Errorf("Expected capacity %d with %d remaining, got %v", tc.capacity, tc.wantRemaining, info)
+			}
+		})
+	}
+}
+
+// TestServeMailbox tests that a Mailbox started on port 0 is reachable, over the network and in-process, at the
+// address of its handle until it is stopped.
+func TestServeMailbox(t *testing.T) {
+	handle, err := ServeMailbox(common.MailboxConfig{Domain: "earth.com", Addr: "localhost:0"}, nil)
+	if err != nil {
+		t.Fatalf("ServeMailbox failed: %v", err)
+	}
+	defer handle.Stop()
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, handle.Addr(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Could not connect to the Mailbox: %v", err)
+	}
+	defer conn.Close()
+	if _, err := proto.NewMailboxClient(conn).Info(dialCtx, &proto.MailboxInfoRequest{}); err != nil {
+		t.Fatalf("Expected the Mailbox to be serving, got %v", err)
+	}
+	if _, ok := LocalServer(handle.Addr()); !ok {
+		t.Errorf("Expected the Mailbox to be registered in-process under %s", handle.Addr())
+	}
+	if _, ok := LocalServer("localhost:0"); ok {
+		t.Error("Expected the Mailbox not to be registered under the configured port 0")
+	}
+
+	handle.Stop()
+	handle.Stop() // Stopping again is harmless
+	if _, err := proto.NewMailboxClient(conn).Info(context.Background(), &proto.MailboxInfoRequest{}); err == nil {
+		t.Error("Expected the stopped Mailbox to refuse requests")
+	}
+	if _, ok := LocalServer(handle.Addr()); ok {
+		t.Error("Expected the stopped Mailbox to be unregistered as an in-process server")
+	}
+}
+
+// TestStartMailboxWithContext tests that the Mailbox serves at the address passed to ready until its context is
+// cancelled, and then stops and returns without a signal.
+func TestStartMailboxWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		StartMailboxWithContext(ctx, common.MailboxConfig{Domain: "earth.com", Addr: "localhost:0"}, nil, func(addr string) { ready <- addr })
+	}()
+
+	var addr string
+	select {
+	case addr = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the Mailbox to report readiness")
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Could not connect to the Mailbox: %v", err)
+	}
+	defer conn.Close()
+	// No retry is needed: the Mailbox is serving once ready has been called
+	if _, err := proto.NewMailboxClient(conn).Info(ctx, &proto.MailboxInfoRequest{}); err != nil {
+		t.Fatalf("Expected the ready Mailbox to answer, got %v", err)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the Mailbox to stop once its context was cancelled")
+	}
+	if _, ok := LocalServer(addr); ok {
+		t.Error("Expected the stopped Mailbox to be unregistered as an in-process server")
+	}
+	if _, err := proto.NewMailboxClient(conn).Info(context.Background(), &proto.MailboxInfoRequest{}); err == nil {
+		t.Error("Expected the stopped Mailbox to refuse requests")
+	}
+}
+
+// TestMailbox_GetMailOrder tests that GetMail returns messages stored out of order by timestamp, oldest first
+// or with newest_first newest first, and orders messages with the same timestamp by message ID unless their
+// sent_at tells them apart.
+func TestMailbox_GetMailOrder(t *testing.T) {
+	client := startTestMailbox(t, NewServer("test.com"))
+	for _, msg := range []*proto.MailMessage{
+		{MessageId: "c", Subject: "Noon", Timestamp: 1200},
+		{MessageId: "b", Subject: "Morning", Timestamp: 900},
+		{MessageId: "d", Subject: "Evening", Timestamp: 1800},
+		{MessageId: "a", Subject: "Also noon", Timestamp: 1200},
+		{MessageId: "e", Subject: "Night", Timestamp: 2000, SentAt: "1970-01-01T00:33:20.1Z"},
+		{MessageId: "f", Subject: "Midnight", Timestamp: 2000, SentAt: "1970-01-01T00:33:20.05Z"},
+	} {
+		msg.SenderEmail, msg.RecipientEmail = "sender@domain.com", "nora@test.com"
+		if _, err := client.ReceiveMail(context.Background(), &proto.ReceiveMailRequest{Message: msg}); err != nil {
+			t.Fatalf("ReceiveMail failed: %v", err)
+		}
+	}
+
+	no := false
+	tests := []struct {
+		name        string
+		newestFirst bool
+		want        []string
+	}{
+		{"OldestFirst", false, []string{"Morning", "Also noon", "Noon", "Evening", "Midnight", "Night"}},
+		{"NewestFirst", true, []string{"Night", "Midnight", "Evening", "Noon", "Also noon", "Morning"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.GetMail(context.Background(), &proto.GetMailRequest{
+				EmailAddress: "nora@test.com", AutoAck: &no, NewestFirst: tc.newestFirst,
+			})
+			if err != nil {
+				t.Fatalf("GetMail failed: %v", err)
+			}
+			var got []string
+			for _, msg := range resp.GetMessages() {
+				got = append(got, msg.GetSubject())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package mailbox
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// mailReceived counts the messages stored by ReceiveMail, by Mailbox domain.
+var mailReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "godissys_mailbox_mail_received_total",
+	Help: "Messages stored by ReceiveMail, by Mailbox domain.",
+}, []string{"domain"})
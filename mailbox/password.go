@@ -0,0 +1,222 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	passwordHashIterations = 100000 // PBKDF2-SHA256 iterations of new password hashes
+	passwordSaltBytes      = 16
+	passwordKeyBytes       = 32
+)
+
+// passwordsFileName returns the name of the file holding the password hashes of domain.
+func passwordsFileName(domain string) string {
+	return "passwords-" + domain + ".json"
+}
+
+// passwordStore holds the password hashes of a Mailbox's users, keyed by normalized email address.
+// It is safe for concurrent use.
+type passwordStore struct {
+	mu     sync.RWMutex
+	hashes map[string]string
+	path   string // File the hashes are persisted to, empty keeps them in memory only
+}
+
+// loadPasswords reads the password hashes persisted at path. An empty path or a missing file yields an
+// empty store.
+func loadPasswords(path string) (*passwordStore, error) {
+	p := &passwordStore{hashes: make(map[string]string), path: path}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passwords '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &p.hashes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal passwords from '%s': %w", path, err)
+	}
+	return p, nil
+}
+
+// set stores the hash of password for emailAddress and persists the store. replaced reports whether the user
+// had a password before.
+func (p *passwordStore) set(emailAddress, password string) (replaced bool, err error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous, replaced := p.hashes[emailAddress]
+	p.hashes[emailAddress] = hash
+	if err := p.saveLocked(); err != nil {
+		if replaced {
+			p.hashes[emailAddress] = previous
+		} else {
+			delete(p.hashes, emailAddress)
+		}
+		return false, err
+	}
+	return replaced, nil
+}
+
+// hash returns the password hash of emailAddress, if the user has a password.
+func (p *passwordStore) hash(emailAddress string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	hash, ok := p.hashes[emailAddress]
+	return hash, ok
+}
+
+// saveLocked writes the hashes to p.path, readable only by the owner. p.mu must be held.
+func (p *passwordStore) saveLocked() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.hashes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal passwords: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory for '%s': %w", p.path, err)
+	}
+	if err := common.WriteFileAtomic(p.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write passwords '%s': %w", p.path, err)
+	}
+	return nil
+}
+
+// hashPassword returns a salted PBKDF2-SHA256 hash of password in the form
+// "pbkdf2-sha256$<iterations>$<salt>$<key>", with salt and key base64-encoded.
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordHashIterations, passwordKeyBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordHashIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// checkPassword reports whether password matches hash, as produced by hashPassword.
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// passwordAuthenticator is the common.Authenticator of a Mailbox: users with a password must send it as their
+// bearer token; all others are checked by next, or rejected if passwords are required.
+type passwordAuthenticator struct {
+	passwords *passwordStore
+	required  bool // Reject users without a password instead of asking next
+	next      common.Authenticator
+}
+
+// ValidateToken implements common.Authenticator.
+func (a passwordAuthenticator) ValidateToken(ctx context.Context, emailAddress string) error {
+	emailAddress = common.NormalizeEmail(emailAddress)
+	hash, ok := a.passwords.hash(emailAddress)
+	if !ok {
+		if a.required {
+			return status.Errorf(codes.Unauthenticated, "'%s' has no password, sign up with a password first", emailAddress)
+		}
+		return a.next.ValidateToken(ctx, emailAddress)
+	}
+	password := common.AuthToken(ctx)
+	if password == "" {
+		return status.Errorf(codes.Unauthenticated, "missing password for '%s'", emailAddress)
+	}
+	if !checkPassword(hash, password) {
+		return status.Errorf(codes.Unauthenticated, "wrong password for '%s'", emailAddress)
+	}
+	return nil
+}
+
+// SetPassword implements proto.MailboxServer.
+// Only addresses of the Mailbox's domain can have a password. Replacing a password requires the current one as
+// the caller's token. Setting the first password requires the admin token or, if one is configured, the
+// approval of the authenticator, and the address must be registered with the Nameserver, if one is configured.
+// Administrators can also reset passwords.
+func (s *server) SetPassword(ctx context.Context, req *proto.SetPasswordRequest) (*proto.SetPasswordResponse, error) {
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if req.GetPassword() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "password cannot be empty")
+	}
+	if !s.servesAddress(emailAddress) {
+		return nil, status.Errorf(codes.InvalidArgument, "'%s' is not an address of the domain '%s'", emailAddress, s.mailDomain)
+	}
+	if _, ok := s.passwords.hash(emailAddress); ok {
+		if !s.isAdmin(ctx) {
+			if err := common.Authorize(ctx, s.accessChecker(), emailAddress); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := s.authorizeFirstPassword(ctx, emailAddress); err != nil {
+			return nil, err
+		}
+		if err := s.checkRegistered(ctx, emailAddress); err != nil {
+			return nil, err
+		}
+	}
+	replaced, err := s.passwords.set(emailAddress, req.GetPassword())
+	if err != nil {
+		s.logger.Error("Failed to store password", "user", emailAddress, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to store password")
+	}
+	if replaced {
+		s.logger.Info("Changed password", "user", emailAddress)
+	} else {
+		s.logger.Info("Set password", "user", emailAddress)
+	}
+	return &proto.SetPasswordResponse{Replaced: replaced}, nil
+}
@@ -0,0 +1,61 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// makeRoomLocked enforces the per-recipient message and byte quotas before msg is stored. If msg does not
+// fit, the drop_oldest policy evicts the oldest messages until it does; otherwise msg is rejected with
+// ResourceExhausted. A message larger than the whole byte quota is always rejected. s.mu must be held.
+func (s *server) makeRoomLocked(msg *proto.MailMessage) error {
+	recipient := msg.GetRecipientEmail()
+	size := int64(protobuf.Size(msg))
+	if s.maxBytesPerUser > 0 && size > s.maxBytesPerUser {
+		s.logger.Warn("Rejecting mail, message exceeds the inbox quota", "user", recipient, "sender", msg.GetSenderEmail(),
+			"bytes", size, "limit", s.maxBytesPerUser)
+		return status.Errorf(codes.ResourceExhausted, "message of %d bytes exceeds the inbox quota for '%s' (limit %d bytes)",
+			size, recipient, s.maxBytesPerUser)
+	}
+
+	inbox := s.userInboxes[recipient]
+	used := inboxBytes(inbox)
+	evicted := 0
+	tooMany := func() bool { return s.maxMessagesPerUser > 0 && len(inbox)-evicted >= s.maxMessagesPerUser }
+	tooLarge := func() bool { return s.maxBytesPerUser > 0 && used+size > s.maxBytesPerUser }
+	if !tooMany() && !tooLarge() {
+		return nil
+	}
+
+	if s.overflowPolicy != common.OverflowDropOldest {
+		if tooMany() {
+			s.logger.Warn("Inbox full, rejecting mail", "user", recipient, "sender", msg.GetSenderEmail(), "limit", s.maxMessagesPerUser)
+			return status.Errorf(codes.ResourceExhausted, "inbox for '%s' is full (limit %d messages)", recipient, s.maxMessagesPerUser)
+		}
+		s.logger.Warn("Inbox full, rejecting mail", "user", recipient, "sender", msg.GetSenderEmail(),
+			"used_bytes", used, "limit_bytes", s.maxBytesPerUser, "bytes", size)
+		return status.Errorf(codes.ResourceExhausted, "inbox for '%s' is full (limit %d bytes)", recipient, s.maxBytesPerUser)
+	}
+
+	// Evict the oldest messages until the new one fits within both limits
+	for tooMany() || tooLarge() {
+		used -= int64(protobuf.Size(inbox[evicted]))
+		evicted++
+	}
+	s.logger.Info("Inbox full, dropping oldest messages", "user", recipient, "evicted", evicted)
+	s.userInboxes[recipient] = append([]*proto.MailMessage{}, inbox[evicted:]...)
+	return nil
+}
+
+// inboxBytes returns the total encoded size of the messages in inbox.
+func inboxBytes(inbox []*proto.MailMessage) int64 {
+	total := int64(0)
+	for _, msg := range inbox {
+		total += int64(protobuf.Size(msg))
+	}
+	return total
+}
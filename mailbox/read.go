@@ -0,0 +1,66 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// MarkRead implements proto.MailboxServer.
+// It flags the given messages in the user's inbox as read. Unknown and already read IDs are ignored.
+func (s *server) MarkRead(ctx context.Context, req *proto.MarkReadRequest) (*proto.MarkReadResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if len(req.GetMessageIds()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one message id is required")
+	}
+
+	ids := make(map[string]bool)
+	for _, id := range req.GetMessageIds() {
+		ids[id] = true
+	}
+	inbox := s.userInboxes[emailAddress]
+	updated := append([]*proto.MailMessage{}, inbox...)
+	marked := 0
+	for i, msg := range updated {
+		if !ids[msg.GetMessageId()] || msg.GetRead() {
+			continue
+		}
+		// Replace rather than modify the message: earlier responses may still reference it
+		updated[i] = protobuf.Clone(msg).(*proto.MailMessage)
+		updated[i].Read = true
+		marked++
+	}
+	if marked == 0 {
+		return &proto.MarkReadResponse{}, nil
+	}
+
+	s.userInboxes[emailAddress] = updated
+	if err := s.persistLocked(); err != nil {
+		s.userInboxes[emailAddress] = inbox
+		s.logger.Error("Failed to persist read flags", "user", emailAddress, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to store read flags")
+	}
+	s.logger.Info("Marked messages read", "user", emailAddress, "marked", marked)
+	return &proto.MarkReadResponse{Marked: int32(marked)}, nil
+}
+
+// countUnread returns the number of messages not yet marked read.
+func countUnread(messages []*proto.MailMessage) int32 {
+	unread := int32(0)
+	for _, msg := range messages {
+		if !msg.GetRead() {
+			unread++
+		}
+	}
+	return unread
+}
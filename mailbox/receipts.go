@@ -0,0 +1,89 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// newReceiptSender returns a function that sends read receipts through the TransferServer at transferServerAddr,
+// dialing it with dialOpt.
+func newReceiptSender(transferServerAddr string, dialOpt grpc.DialOption) func(*proto.MailMessage) error {
+	return func(receipt *proto.MailMessage) error {
+		dialCtx, dialCancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer dialCancel()
+		conn, err := grpc.DialContext(dialCtx, transferServerAddr, dialOpt, common.TracingDialOption())
+		if err != nil {
+			return fmt.Errorf("could not connect to TransferServer at %s: %w", transferServerAddr, err)
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		resp, err := proto.NewTransferServerClient(conn).SendMail(ctx, &proto.SendMailRequest{Message: receipt})
+		if err != nil {
+			return err
+		}
+		if !resp.GetSuccess() {
+			return fmt.Errorf("%s", resp.GetMessage())
+		}
+		return nil
+	}
+}
+
+// sendReadReceiptsLocked sends a read receipt in the background for every retrieved message that
+// requested one and returns the messages to hand to the client. The request flag is cleared on the
+// stored messages, which the caller keeps in the inbox or moves to the trash, so that a message
+// retrieved again (retained, or restored from the trash) does not trigger a second receipt; the
+// returned slice holds copies with the flag intact in their place. Receipts never request receipts
+// themselves, and messages that are receipts never trigger one, so receipts cannot loop. s.mu must be held.
+func (s *server) sendReadReceiptsLocked(messages []*proto.MailMessage) []*proto.MailMessage {
+	if s.sendReceipt == nil {
+		return messages
+	}
+	returned := make([]*proto.MailMessage, len(messages))
+	copy(returned, messages)
+	changed := false
+	for i, msg := range messages {
+		if !msg.GetRequestReadReceipt() || msg.GetReceiptForMessageId() != "" || msg.GetSenderEmail() == "" {
+			continue
+		}
+		returned[i] = protobuf.Clone(msg).(*proto.MailMessage)
+		msg.RequestReadReceipt = false
+		changed = true
+
+		receipt := newReadReceipt(msg, s.now())
+		go func() {
+			if err := s.sendReceipt(receipt); err != nil {
+				s.logger.Warn("Failed to send read receipt", "user", receipt.SenderEmail, "recipient", receipt.RecipientEmail, "error", err)
+				return
+			}
+			s.logger.Info("Sent read receipt", "user", receipt.SenderEmail, "recipient", receipt.RecipientEmail)
+		}()
+	}
+	if !changed {
+		return returned
+	}
+	if err := s.persistLocked(); err != nil {
+		s.logger.Error("Failed to persist read receipt state", "error", err)
+	}
+	return returned
+}
+
+// newReadReceipt builds the receipt telling the sender of msg that it was read at readAt.
+func newReadReceipt(msg *proto.MailMessage, readAt time.Time) *proto.MailMessage {
+	return &proto.MailMessage{
+		SenderEmail:    msg.GetRecipientEmail(),
+		RecipientEmail: msg.GetSenderEmail(),
+		Subject:        "Read: " + msg.GetSubject(),
+		Body: fmt.Sprintf("Your message '%s' was read by %s at %s.",
+			msg.GetSubject(), msg.GetRecipientEmail(), readAt.Format(time.RFC1123)),
+		Timestamp:           readAt.Unix(),
+		ReceiptForMessageId: msg.GetMessageId(),
+	}
+}
@@ -0,0 +1,46 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SearchMail implements proto.MailboxServer.
+// It returns the messages in the user's inbox whose sender or subject (and, if requested, body) contain
+// the query, ignoring case. Unlike GetMail it never clears the inbox.
+func (s *server) SearchMail(ctx context.Context, req *proto.SearchMailRequest) (*proto.SearchMailResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	query := strings.ToLower(req.GetQuery())
+	if query == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "search query cannot be empty")
+	}
+
+	matches := []*proto.MailMessage{} // Empty, not nil, for an inbox without matches
+	for _, msg := range s.userInboxes[emailAddress] {
+		if matchesQuery(msg, query, req.GetIncludeBody()) {
+			matches = append(matches, msg)
+		}
+	}
+	s.logger.Info("Searched mail", "user", emailAddress, "query", req.GetQuery(), "matches", len(matches))
+	return &proto.SearchMailResponse{Messages: matches}, nil
+}
+
+// matchesQuery reports whether msg's sender or subject, or its body if includeBody is set, contain the
+// lower-case query.
+func matchesQuery(msg *proto.MailMessage, query string, includeBody bool) bool {
+	if strings.Contains(strings.ToLower(msg.GetSenderEmail()), query) || strings.Contains(strings.ToLower(msg.GetSubject()), query) {
+		return true
+	}
+	return includeBody && strings.Contains(strings.ToLower(msg.GetBody()), query)
+}
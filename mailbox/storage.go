@@ -0,0 +1,142 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const defaultMaxPendingWrites = 100 // Unsaved changes after which write coalescing saves immediately
+
+// stateFileName returns the name of the state file holding the inboxes of domain.
+func stateFileName(domain string) string {
+	return "mailbox-" + domain + ".json"
+}
+
+// loadInboxes reads persisted inboxes from path, decrypting message bodies with aead.
+// A missing file yields empty inboxes.
+func loadInboxes(path string, aead cipher.AEAD) (map[string][]*proto.MailMessage, error) {
+	inboxes := make(map[string][]*proto.MailMessage)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return inboxes, nil // First run, nothing persisted yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mailbox state '%s': %w", path, err)
+	}
+
+	var stored map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mailbox state from '%s': %w", path, err)
+	}
+	for emailAddress, rawMessages := range stored {
+		emailAddress = common.NormalizeEmail(emailAddress) // Merges inboxes saved under differently-cased keys
+		for _, raw := range rawMessages {
+			msg := &proto.MailMessage{}
+			if err := protojson.Unmarshal(raw, msg); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal message for '%s' from '%s': %w", emailAddress, path, err)
+			}
+			if err := decryptBody(aead, msg); err != nil {
+				return nil, fmt.Errorf("failed to load message for '%s' from '%s': %w", emailAddress, path, err)
+			}
+			msg.RecipientEmail = common.NormalizeEmail(msg.RecipientEmail)
+			inboxes[emailAddress] = append(inboxes[emailAddress], msg)
+		}
+	}
+	return inboxes, nil
+}
+
+// saveInboxes writes inboxes to path. If aead is not nil, message bodies are encrypted with it.
+func saveInboxes(path string, inboxes map[string][]*proto.MailMessage, aead cipher.AEAD) error {
+	stored := make(map[string][]json.RawMessage)
+	for emailAddress, messages := range inboxes {
+		if len(messages) == 0 {
+			continue
+		}
+		for _, msg := range messages {
+			if aead != nil {
+				encrypted, err := encryptBody(aead, msg)
+				if err != nil {
+					return fmt.Errorf("failed to encrypt message for '%s': %w", emailAddress, err)
+				}
+				msg = encrypted
+			}
+			raw, err := protojson.Marshal(msg)
+			if err != nil {
+				return fmt.Errorf("failed to marshal message for '%s': %w", emailAddress, err)
+			}
+			stored[emailAddress] = append(stored[emailAddress], raw)
+		}
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mailbox state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory for '%s': %w", path, err)
+	}
+	if err := common.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write mailbox state '%s': %w", path, err)
+	}
+	return nil
+}
+
+// persistLocked saves the inboxes if persistence is enabled. s.mu must be held.
+// With write coalescing, the save is deferred by up to s.persistInterval so a burst of changes results
+// in a single write; once s.maxPendingWrites changes are unsaved, it saves immediately.
+func (s *server) persistLocked() error {
+	if s.statePath == "" {
+		return nil
+	}
+	if s.persistInterval <= 0 {
+		return saveInboxes(s.statePath, s.userInboxes, s.bodyCipher)
+	}
+	s.pendingWrites++
+	if s.pendingWrites >= s.maxPendingWrites {
+		return s.flushLocked()
+	}
+	if s.flushTimer == nil {
+		s.flushTimer = time.AfterFunc(s.persistInterval, s.flush)
+	}
+	return nil
+}
+
+// flush saves coalesced changes; it runs when the coalescing interval expires.
+func (s *server) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flushLocked(); err != nil {
+		s.logger.Error("Failed to persist coalesced changes", "error", err)
+	}
+}
+
+// flushLocked saves the inboxes if changes are pending. s.mu must be held.
+func (s *server) flushLocked() error {
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if s.pendingWrites == 0 {
+		return nil
+	}
+	if err := saveInboxes(s.statePath, s.userInboxes, s.bodyCipher); err != nil {
+		return err
+	}
+	s.pendingWrites = 0
+	return nil
+}
+
+// Close saves changes still pending from write coalescing. The Mailbox must not be used afterwards.
+func (s *server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
@@ -0,0 +1,68 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamMail implements proto.MailboxServer.
+// It sends a snapshot of the user's (label-filtered) inbox one message at a time, oldest first like GetMail
+// (see sortByTimestamp), so large inboxes need not fit into a single response. With clear-on-read the streamed messages are removed only once all of them
+// were sent; a failed stream leaves the inbox untouched. Mail arriving meanwhile stays for the next call.
+func (s *server) StreamMail(req *proto.StreamMailRequest, stream proto.Mailbox_StreamMailServer) error {
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if err := common.Authorize(stream.Context(), s.accessChecker(), emailAddress); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	var snapshot []*proto.MailMessage
+	for _, msg := range s.userInboxes[emailAddress] {
+		if hasAnyLabel(msg, req.GetLabels()) {
+			snapshot = append(snapshot, msg)
+		}
+	}
+	snapshot = s.sendReadReceiptsLocked(snapshot) // Copies; the inbox is cleared by message ID below
+	s.mu.Unlock()
+	sortByTimestamp(snapshot, false)
+
+	for i, msg := range snapshot {
+		if err := stream.Send(msg); err != nil {
+			s.logger.Warn("Stream aborted, inbox kept", "user", emailAddress, "sent", i, "messages", len(snapshot), "error", err)
+			return err
+		}
+	}
+	if len(snapshot) == 0 || !s.clearOnRead(req.AutoAck) {
+		s.logger.Info("Streamed messages, retained in inbox", "user", emailAddress, "messages", len(snapshot))
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	streamed := make(map[string]bool, len(snapshot))
+	for _, msg := range snapshot {
+		streamed[msg.GetMessageId()] = true
+	}
+	var cleared []*proto.MailMessage
+	remaining := []*proto.MailMessage{}
+	for _, msg := range s.userInboxes[emailAddress] {
+		if streamed[msg.GetMessageId()] {
+			cleared = append(cleared, msg)
+		} else {
+			remaining = append(remaining, msg)
+		}
+	}
+	s.moveToTrashLocked(emailAddress, cleared, s.clearRetention())
+	s.userInboxes[emailAddress] = remaining
+	if err := s.persistLocked(); err != nil {
+		s.logger.Error("Failed to persist cleared inbox", "user", emailAddress, "error", err)
+	}
+	s.logger.Info("Streamed messages", "user", emailAddress, "messages", len(snapshot), "left", len(remaining))
+	return nil
+}
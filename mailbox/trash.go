@@ -0,0 +1,128 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const trashPurgeInterval = time.Minute // How often the janitor purges expired trash
+
+// trashedMessage is a retrieved message kept in the trash until it expires.
+type trashedMessage struct {
+	msg       *proto.MailMessage
+	expiresAt time.Time
+}
+
+// clearRetention returns how long messages cleared by GetMail stay in the trash: the trash retention,
+// but at least the grace period.
+func (s *server) clearRetention() time.Duration {
+	return max(s.trashRetention, s.clearGracePeriod)
+}
+
+// moveToTrashLocked keeps removed messages in the user's trash for retention (0 discards them).
+// s.mu must be held.
+func (s *server) moveToTrashLocked(emailAddress string, messages []*proto.MailMessage, retention time.Duration) {
+	if retention <= 0 || len(messages) == 0 {
+		return
+	}
+	expiresAt := s.now().Add(retention)
+	for _, msg := range messages {
+		s.userTrash[emailAddress] = append(s.userTrash[emailAddress], trashedMessage{msg: msg, expiresAt: expiresAt})
+	}
+}
+
+// UndeleteMail implements proto.MailboxServer.
+// It moves the given messages from the user's trash back into their inbox, as long as they haven't expired.
+// Restored messages count against the inbox quotas like new mail: with the reject overflow policy, nothing is
+// restored unless every message fits, and the call fails with ResourceExhausted.
+func (s *server) UndeleteMail(ctx context.Context, req *proto.UndeleteMailRequest) (*proto.UndeleteMailResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if len(req.GetMessageIds()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one message id is required")
+	}
+
+	wanted := make(map[string]bool)
+	for _, id := range req.GetMessageIds() {
+		wanted[id] = true
+	}
+	now := s.now()
+	previous := s.userInboxes[emailAddress]
+	var kept []trashedMessage
+	restored := 0
+	for _, t := range s.userTrash[emailAddress] {
+		switch {
+		case !now.Before(t.expiresAt):
+			// Expired, drop it even if the janitor hasn't run yet
+		case wanted[t.msg.MessageId]:
+			if err := s.makeRoomLocked(t.msg); err != nil {
+				s.userInboxes[emailAddress] = previous // The trash is left untouched as well
+				return nil, err
+			}
+			s.userInboxes[emailAddress] = append(s.userInboxes[emailAddress], t.msg)
+			restored++
+		default:
+			kept = append(kept, t)
+		}
+	}
+	s.setTrashLocked(emailAddress, kept)
+	if err := s.persistLocked(); err != nil {
+		s.logger.Error("Failed to persist restored mail", "user", emailAddress, "error", err)
+	}
+
+	s.logger.Info("Restored messages from trash", "user", emailAddress, "restored", restored, "requested", len(wanted))
+	return &proto.UndeleteMailResponse{Restored: int32(restored)}, nil
+}
+
+// purgeExpiredTrash permanently removes all trashed messages whose retention has expired.
+func (s *server) purgeExpiredTrash() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for emailAddress, trash := range s.userTrash {
+		var kept []trashedMessage
+		for _, t := range trash {
+			if now.Before(t.expiresAt) {
+				kept = append(kept, t)
+			}
+		}
+		if purged := len(trash) - len(kept); purged > 0 {
+			s.logger.Info("Purged expired messages from trash", "user", emailAddress, "purged", purged)
+		}
+		s.setTrashLocked(emailAddress, kept)
+	}
+}
+
+// setTrashLocked replaces the user's trash, dropping the entry when it is empty. s.mu must be held.
+func (s *server) setTrashLocked(emailAddress string, trash []trashedMessage) {
+	if len(trash) == 0 {
+		delete(s.userTrash, emailAddress)
+		return
+	}
+	s.userTrash[emailAddress] = trash
+}
+
+// runTrashJanitor purges expired trash periodically until stop is closed.
+func (s *server) runTrashJanitor(stop <-chan struct{}) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.purgeExpiredTrash()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultWaitForMailTimeout = 30 * time.Second // Wait used when the request sets no timeout
+	maxWaitForMailTimeout     = 60 * time.Second // Upper bound on the wait a client may request
+)
+
+// WaitForMail implements proto.MailboxServer.
+// It blocks until the user's inbox holds mail stored after the request's cursor, then returns that mail.
+// The inbox is left untouched, so WaitForMail works as a notification alongside GetMail.
+// An empty response is returned if no mail arrives before the timeout.
+func (s *server) WaitForMail(ctx context.Context, req *proto.WaitForMailRequest) (*proto.GetMailResponse, error) {
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	timeout := defaultWaitForMailTimeout
+	if req.GetTimeoutSeconds() > 0 {
+		timeout = time.Duration(req.GetTimeoutSeconds()) * time.Second
+	}
+	if timeout > maxWaitForMailTimeout {
+		timeout = maxWaitForMailTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	after := req.GetAfterSequence()
+	if after == 0 && req.GetAfterMessageId() != "" {
+		s.mu.RLock()
+		after = s.sequenceOfLocked(emailAddress, req.GetAfterMessageId())
+		s.mu.RUnlock()
+	}
+	for {
+		s.mu.RLock()
+		messages := messagesAfter(s.userInboxes[emailAddress], after)
+		arrived := s.mailArrived
+		s.mu.RUnlock()
+		if len(messages) > 0 {
+			return &proto.GetMailResponse{Messages: messages}, nil
+		}
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return &proto.GetMailResponse{Messages: []*proto.MailMessage{}}, nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// sequenceOfLocked returns the sequence of the message with ID id in the user's inbox or trash, or 0 if
+// it is in neither. s.mu must be held.
+func (s *server) sequenceOfLocked(emailAddress, id string) int64 {
+	for _, msg := range s.userInboxes[emailAddress] {
+		if msg.GetMessageId() == id {
+			return msg.GetSequence()
+		}
+	}
+	for _, t := range s.userTrash[emailAddress] {
+		if t.msg.GetMessageId() == id {
+			return t.msg.GetSequence()
+		}
+	}
+	return 0
+}
+
+// messagesAfter returns a copy of the messages with a sequence higher than after.
+func messagesAfter(inbox []*proto.MailMessage, after int64) []*proto.MailMessage {
+	var messages []*proto.MailMessage
+	for _, msg := range inbox {
+		if msg.GetSequence() > after {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// nextSequenceLocked returns the sequence for a newly stored message. It follows the clock in nanoseconds,
+// so sequences keep increasing after a restart even if the latest messages were retrieved before it,
+// and it always exceeds the previous one. s.mu must be held.
+func (s *server) nextSequenceLocked() int64 {
+	next := s.now().UnixNano()
+	if next <= s.lastSequence {
+		next = s.lastSequence + 1
+	}
+	s.lastSequence = next
+	return next
+}
+
+// assignMissingSequences gives the loaded messages stored before sequences existed one following the
+// highest loaded sequence, and returns the highest sequence afterwards.
+func assignMissingSequences(inboxes map[string][]*proto.MailMessage) int64 {
+	var last int64
+	for _, inbox := range inboxes {
+		for _, msg := range inbox {
+			last = max(last, msg.GetSequence())
+		}
+	}
+	for _, inbox := range inboxes {
+		for _, msg := range inbox {
+			if msg.GetSequence() == 0 {
+				last++
+				msg.Sequence = last
+			}
+		}
+	}
+	return last
+}
@@ -0,0 +1,78 @@
+package mailbox
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const watchBufferSize = 16 // Messages queued per WatchMail stream before further pushes to it are dropped
+
+// WatchMail implements proto.MailboxServer.
+// It keeps the stream open and pushes every message ReceiveMail stores for the user until the client
+// cancels. The inbox is left untouched; a stream too slow to keep up misses pushes, but not the mail itself.
+func (s *server) WatchMail(req *proto.WatchMailRequest, stream proto.Mailbox_WatchMailServer) error {
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	if err := common.Authorize(stream.Context(), s.accessChecker(), emailAddress); err != nil {
+		return err
+	}
+
+	ch := s.subscribe(emailAddress)
+	defer s.unsubscribe(emailAddress, ch)
+	s.logger.Info("Watching for new mail", "user", emailAddress)
+	for {
+		select {
+		case msg := <-ch:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			s.logger.Info("Stopped watching for new mail", "user", emailAddress)
+			return nil
+		}
+	}
+}
+
+// subscribe registers a new WatchMail channel for emailAddress.
+func (s *server) subscribe(emailAddress string) chan *proto.MailMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan *proto.MailMessage, watchBufferSize)
+	s.watchers[emailAddress] = append(s.watchers[emailAddress], ch)
+	return ch
+}
+
+// unsubscribe removes a channel registered with subscribe.
+func (s *server) unsubscribe(emailAddress string, ch chan *proto.MailMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watchers := s.watchers[emailAddress]
+	for i, w := range watchers {
+		if w == ch {
+			watchers = append(watchers[:i:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(s.watchers, emailAddress)
+		return
+	}
+	s.watchers[emailAddress] = watchers
+}
+
+// notifyWatchersLocked pushes msg to the open WatchMail streams of its recipient without blocking.
+// s.mu must be held.
+func (s *server) notifyWatchersLocked(msg *proto.MailMessage) {
+	for _, ch := range s.watchers[msg.GetRecipientEmail()] {
+		select {
+		case ch <- msg:
+		default:
+			s.logger.Warn("Watcher is falling behind, not pushing message", "user", msg.GetRecipientEmail(), "message_id", msg.GetMessageId())
+		}
+	}
+}
@@ -6,86 +6,196 @@ import (
 	"GoDissys/mailbox"
 	"GoDissys/nameserver"
 	"GoDissys/transferserver"
+	"context"
+	"flag"
+	"fmt"
 	"log"
-	"sync"
+	"log/slog"
+	"os"
+	"sort"
 	"time"
 )
 
-func main() {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-
-	// Load configuration from file
-	cfg, err := common.LoadConfig("config.json")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+// runFrontend runs the foreground part of the binary after all services are started. Unless daemon is set it
+// runs startCLI until the user exits and then calls stop to shut the services down. Either way it then calls
+// wait, which blocks until the services have stopped. In daemon mode they stop on SIGINT or SIGTERM.
+func runFrontend(daemon bool, startCLI func(), stop func(), wait func()) {
+	if daemon {
+		slog.Info("Running in daemon mode. Send SIGINT or SIGTERM to stop.")
+	} else {
+		startCLI()
+		slog.Info("Client CLI exited. Stopping all services...")
+		stop()
 	}
+	wait()
+}
 
-	var wg sync.WaitGroup // Use WaitGroup to keep main goroutine alive until all servers are stopped
-
-	// Start Nameserver in a goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done() // Signal when this goroutine is done
-		nameserver.StartNameserver(cfg.NameserverAddr, cfg.NameserverManagedDomains...)
-	}()
-	time.Sleep(time.Millisecond * 500) // Give Nameserver a moment to start
-
-	// Start Mailbox for earth.com in a goroutine
-	earthMailboxConfig, ok := cfg.Mailboxes["earth.com"]
-	if !ok {
-		log.Fatalf("Earth.com mailbox configuration not found")
-	}
-	wg.Add(1)
-	go func() {
-		defer wg.Done() // Signal when this goroutine is done
-		mailbox.StartMailbox(earthMailboxConfig.Domain, earthMailboxConfig.Addr)
-	}()
-	time.Sleep(time.Millisecond * 500) // Give Mailbox a moment to start
-
-	// Start Mailbox for saturn.com in a goroutine
-	saturnMailboxConfig, ok := cfg.Mailboxes["saturn.com"]
-	if !ok {
-		log.Fatalf("Saturn.com mailbox configuration not found")
-	}
-	wg.Add(1)
-	go func() {
-		defer wg.Done() // Signal when this goroutine is done
-		mailbox.StartMailbox(saturnMailboxConfig.Domain, saturnMailboxConfig.Addr)
-	}()
-	time.Sleep(time.Millisecond * 500) // Give Mailbox a moment to start
-
-	// Start TransferServer in a goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done() // Signal when this goroutine is done
-		transferserver.StartTransferServer(cfg.NameserverAddr, cfg.TransferServerAddr)
-	}()
-	time.Sleep(time.Millisecond * 500) // Give TransferServer a moment to start
-
-	log.Println("\n--- All services initialized. Starting client CLI... ---")
-
-	// Start the client CLI in the main goroutine
-	// The CLI will handle user interactions for signup, login, send, and get mail.
-	// We need to pass the relevant parts of the config to the client CLI.
+// newClientConfig passes the parts of cfg the client needs, together with the command-line options, to the client.
+func newClientConfig(cfg *common.Config, jsonOutput, noSession bool) client.Config {
 	clientConfig := client.Config{
-		NameserverAddr:     cfg.NameserverAddr,
-		TransferServerAddr: cfg.TransferServerAddr,
+		NameserverAddr:       cfg.NameserverAddr,
+		TransferServerAddr:   cfg.TransferServerAddr,
+		CredentialsFile:      cfg.CredentialsFile,
+		AdminToken:           cfg.AdminToken,
+		InProcessDelivery:    cfg.InProcessDelivery,
+		MaxCommandLength:     cfg.CLIMaxCommandLength,
+		MaxCommandsPerSecond: cfg.CLIMaxCommandsPerSecond,
+		JSONOutput:           jsonOutput,
+		SessionFile:          cfg.SessionFile,
+		TLS:                  cfg.TLS,
 		Mailboxes: make(map[string]struct {
 			Domain string
 			Addr   string
 		}),
 	}
+	if clientConfig.SessionFile == "" {
+		clientConfig.SessionFile = client.DefaultSessionFile
+	}
+	if noSession {
+		clientConfig.SessionFile = ""
+	}
 	for domain, mbCfg := range cfg.Mailboxes {
 		clientConfig.Mailboxes[domain] = struct {
 			Domain string
 			Addr   string
 		}{Domain: mbCfg.Domain, Addr: mbCfg.Addr}
 	}
+	return clientConfig
+}
 
-	client.StartCLI(clientConfig) // This call blocks until the user exits the CLI
+// mailboxDomains returns the domains of all Mailboxes configured in cfg, sorted.
+func mailboxDomains(cfg *common.Config) []string {
+	domains := make([]string, 0, len(cfg.Mailboxes))
+	for domain := range cfg.Mailboxes {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// mailboxConfig returns the configuration of the Mailbox for domain, with the settings it leaves unset taken
+// from the shared ones in cfg.
+func mailboxConfig(cfg *common.Config, domain string) common.MailboxConfig {
+	mbCfg := cfg.Mailboxes[domain]
+	if mbCfg.TransferServerAddr == "" {
+		mbCfg.TransferServerAddr = cfg.TransferServerAddr // Read receipts go through the shared TransferServer
+	}
+	if mbCfg.TLS == nil {
+		mbCfg.TLS = cfg.TLS
+	}
+	if mbCfg.AdminToken == "" {
+		mbCfg.AdminToken = cfg.AdminToken
+	}
+	if mbCfg.MailDomain == "" {
+		mbCfg.MailDomain = domain // Domain is only the alias users sign up with
+	}
+	if mbCfg.NameserverAddr == "" {
+		mbCfg.NameserverAddr = cfg.NameserverAddr // First passwords are only set for registered users
+	}
+	mbCfg.LogRequests = mbCfg.LogRequests || cfg.LogRequests
+	return mbCfg
+}
+
+func main() {
+	daemon := flag.Bool("daemon", false, "Run the services headless without starting the interactive CLI")
+	jsonOutput := flag.Bool("json", false, "Print each CLI command result as one line of JSON (for scripting)")
+	noSession := flag.Bool("no-session", false, "Start the CLI logged out and do not save the session on exit")
+	flag.Parse()
+
+	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+
+	// Load configuration from file, with addresses overridden by the environment
+	cfg, err := common.LoadConfigWithEnv("config.json")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration (config.json and environment overrides):\n%v", err)
+	}
+	if err := common.SetupLogging(cfg.LogFormat, os.Stderr); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	// Spans of the stdout exporter go to stderr with the log, so they do not mix with the CLI output
+	shutdownTracing, err := common.SetupTracing(cfg.Tracing, os.Stderr)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	flushTraces := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}
+
+	// A command on the command line runs once against already running services (e.g. started with -daemon)
+	if flag.NArg() > 0 {
+		code := client.RunCommand(newClientConfig(cfg, *jsonOutput, *noSession), flag.Args(), os.Stdin, os.Stdout)
+		flushTraces()
+		os.Exit(code)
+	}
+
+	// Without a configured admin token, the Mailboxes and the CLI started here share one for this run, so
+	// signup can set first passwords
+	if cfg.AdminToken == "" {
+		cfg.AdminToken = common.NewMessageID()
+	}
+
+	// All services run until ctx is cancelled: on SIGINT or SIGTERM, or when the CLI exits
+	ctx, stop := common.SignalContext()
+	defer stop()
+
+	// Each service is serving once its Serve function returns, so the next one can rely on it right away.
+	// They are stopped in reverse order, the TransferServer before the Mailboxes and the Nameserver it uses.
+	var handles []*common.ServerHandle
+	stopServices := func() {
+		for i := len(handles) - 1; i >= 0; i-- {
+			handles[i].Stop()
+		}
+	}
+	started := func(name string, handle *common.ServerHandle, err error) {
+		if err != nil {
+			stopServices()
+			log.Fatalf("%s failed to start: %v", name, err)
+		}
+		handles = append(handles, handle)
+	}
+
+	// Start the metrics server, if configured
+	if cfg.MetricsAddr != "" {
+		handle, err := common.ServeMetrics(cfg.MetricsAddr)
+		started("Metrics server", handle, err)
+	}
+
+	// Start Nameserver
+	handle, err := nameserver.ServeNameserver(*cfg)
+	started("Nameserver", handle, err)
+
+	// Start one Mailbox per configured domain, in a stable order
+	for _, domain := range mailboxDomains(cfg) {
+		handle, err = mailbox.ServeMailbox(mailboxConfig(cfg, domain), nil)
+		started(fmt.Sprintf("Mailbox '%s'", domain), handle, err)
+	}
+
+	// Start TransferServer
+	if cfg.TransferServer.TLS == nil {
+		cfg.TransferServer.TLS = cfg.TLS
+	}
+	cfg.TransferServer.LogRequests = cfg.TransferServer.LogRequests || cfg.LogRequests
+	handle, err = transferserver.ServeTransferServer(cfg.NameserverAddr, cfg.TransferServerAddr, cfg.TransferServer)
+	started("TransferServer", handle, err)
+
+	slog.Info("All services initialized")
+
+	// Start the client CLI in the main goroutine
+	// The CLI will handle user interactions for signup, login, send, and get mail.
+	clientConfig := newClientConfig(cfg, *jsonOutput, *noSession)
 
-	// After the client CLI exits, wait for all server goroutines to complete their graceful shutdown
-	log.Println("Client CLI exited. Waiting for all services to stop...")
-	wg.Wait()
-	log.Println("All services have stopped.")
+	// The CLI blocks until the user exits it; afterwards stop the services and wait for their graceful shutdown
+	runFrontend(*daemon, func() { client.StartCLI(clientConfig) }, stop, func() {
+		<-ctx.Done()
+		stopServices()
+	})
+	slog.Info("All services have stopped")
+	flushTraces()
 }
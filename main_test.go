@@ -0,0 +1,91 @@
+package main
+
+import (
+	"GoDissys/client"
+	"GoDissys/common"
+	"strings"
+	"testing"
+)
+
+// TestRunFrontend tests that the CLI runs and then stops the services unless in daemon mode, and that the
+// frontend always waits for the services to stop.
+func TestRunFrontend(t *testing.T) {
+	tests := []struct {
+		name    string
+		daemon  bool
+		wantCLI bool
+	}{
+		{"Interactive", false, true},
+		{"Daemon", true, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			runFrontend(tc.daemon, func() { calls = append(calls, "cli") }, func() { calls = append(calls, "stop") },
+				func() { calls = append(calls, "wait") })
+
+			want := []string{"wait"}
+			if tc.wantCLI {
+				want = []string{"cli", "stop", "wait"}
+			}
+			if len(calls) != len(want) {
+				t.Fatalf("Expected calls %v, got %v", want, calls)
+			}
+			for i := range want {
+				if calls[i] != want[i] {
+					t.Errorf("Expected calls %v, got %v", want, calls)
+				}
+			}
+		})
+	}
+}
+
+// TestNewClientConfig tests that the client config carries the addresses and output mode over from the
+// service config, and that -no-session disables the session file.
+func TestNewClientConfig(t *testing.T) {
+	cfg := &common.Config{
+		NameserverAddr: "localhost:50051",
+		Mailboxes:      map[string]common.MailboxConfig{"earth.com": {Domain: "earth", Addr: "localhost:50054"}},
+	}
+
+	clientConfig := newClientConfig(cfg, true, false)
+	if !clientConfig.JSONOutput || clientConfig.NameserverAddr != cfg.NameserverAddr || clientConfig.Mailboxes["earth.com"].Addr != "localhost:50054" {
+		t.Errorf("Unexpected client config: %+v", clientConfig)
+	}
+	if clientConfig.SessionFile != client.DefaultSessionFile {
+		t.Errorf("Expected the default session file, got %q", clientConfig.SessionFile)
+	}
+	if clientConfig := newClientConfig(cfg, false, true); clientConfig.SessionFile != "" {
+		t.Errorf("Expected -no-session to disable the session file, got %q", clientConfig.SessionFile)
+	}
+}
+
+// TestMailboxConfig tests that the Mailboxes are started in domain order and that the shared settings fill in
+// each Mailbox's config without overriding the ones it sets itself.
+func TestMailboxConfig(t *testing.T) {
+	sharedTLS := &common.TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}
+	cfg := &common.Config{
+		NameserverAddr:     "localhost:50051",
+		TransferServerAddr: "localhost:50053",
+		AdminToken:         "admin",
+		TLS:                sharedTLS,
+		LogRequests:        true,
+		Mailboxes: map[string]common.MailboxConfig{
+			"saturn.com": {Domain: "saturn", Addr: "localhost:50055", TransferServerAddr: "relay:50053"},
+			"earth.com":  {Domain: "earth", Addr: "localhost:50054"},
+			"mars.com":   {Domain: "mars", Addr: "localhost:50056"},
+		},
+	}
+
+	if got, want := mailboxDomains(cfg), []string{"earth.com", "mars.com", "saturn.com"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected the domains %v, got %v", want, got)
+	}
+	earth := mailboxConfig(cfg, "earth.com")
+	if earth.Addr != "localhost:50054" || earth.TransferServerAddr != cfg.TransferServerAddr || earth.TLS != sharedTLS || !earth.LogRequests ||
+		earth.AdminToken != "admin" || earth.MailDomain != "earth.com" || earth.NameserverAddr != cfg.NameserverAddr {
+		t.Errorf("Expected the shared settings to fill in the earth.com Mailbox, got %+v", earth)
+	}
+	if saturn := mailboxConfig(cfg, "saturn.com"); saturn.TransferServerAddr != "relay:50053" {
+		t.Errorf("Expected the saturn.com Mailbox to keep its own TransferServerAddr, got %q", saturn.TransferServerAddr)
+	}
+}
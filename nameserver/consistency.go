@@ -0,0 +1,114 @@
+package nameserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const reachabilityTimeout = 2 * time.Second // How long CheckConsistency waits when dialing a mailbox address
+
+// CheckConsistency implements proto.NameserverServer.
+// It scans all registrations and reports email addresses that do not parse, mailbox addresses that are not
+// a valid host:port, emails whose domain is not managed by this Nameserver and, if requested, mailbox
+// addresses that cannot be reached.
+func (s *server) CheckConsistency(ctx context.Context, req *proto.CheckConsistencyRequest) (*proto.CheckConsistencyResponse, error) {
+	s.mu.RLock()
+	resp := &proto.CheckConsistencyResponse{Checked: int32(len(s.mailboxes))}
+	dialable := make(map[string][]string) // Valid mailbox address -> emails registered to it
+	for email, addr := range s.mailboxes {
+		domain, ok := emailDomain(email)
+		if !ok {
+			resp.Issues = append(resp.Issues, newIssue(email, addr, proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_EMAIL,
+				"email address is not of the form user@domain"))
+		} else if !s.responsibleDomains[domain] {
+			resp.Issues = append(resp.Issues, newIssue(email, addr, proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_UNMANAGED_DOMAIN,
+				fmt.Sprintf("domain '%s' is not managed by this Nameserver", domain)))
+		}
+		if err := validateMailboxAddress(addr); err != nil {
+			resp.Issues = append(resp.Issues, newIssue(email, addr, proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS, err.Error()))
+			continue
+		}
+		dialable[addr] = append(dialable[addr], email)
+	}
+	s.mu.RUnlock()
+
+	if req.GetVerifyReachability() {
+		// Dial outside the lock so registrations and lookups are not blocked by slow mailboxes
+		resp.Issues = append(resp.Issues, checkReachability(ctx, dialable)...)
+	}
+
+	sort.Slice(resp.Issues, func(i, j int) bool {
+		a, b := resp.Issues[i], resp.Issues[j]
+		if a.EmailAddress != b.EmailAddress {
+			return a.EmailAddress < b.EmailAddress
+		}
+		return a.Kind < b.Kind
+	})
+	s.logger.Info("Consistency check finished", "checked", resp.Checked, "issues", len(resp.Issues))
+	return resp, nil
+}
+
+// checkReachability dials every mailbox address concurrently and reports an issue for each email
+// registered to an address that cannot be reached.
+func checkReachability(ctx context.Context, dialable map[string][]string) []*proto.ConsistencyIssue {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		issues []*proto.ConsistencyIssue
+	)
+	dialer := &net.Dialer{Timeout: reachabilityTimeout}
+	for addr, emails := range dialable {
+		wg.Add(1)
+		go func(addr string, emails []string) {
+			defer wg.Done()
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, email := range emails {
+				issues = append(issues, newIssue(email, addr, proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX, err.Error()))
+			}
+		}(addr, emails)
+	}
+	wg.Wait()
+	return issues
+}
+
+// validateMailboxAddress checks that addr is a host:port pair with a non-empty host and a valid port.
+func validateMailboxAddress(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid host:port: %v", err)
+	}
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port '%s'", port)
+	}
+	return nil
+}
+
+// emailDomain returns the domain of a user@domain email address.
+func emailDomain(email string) (string, bool) {
+	parts := strings.Split(email, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// newIssue builds a consistency issue for the registration of email at addr.
+func newIssue(email, addr string, kind proto.ConsistencyIssueKind, detail string) *proto.ConsistencyIssue {
+	return &proto.ConsistencyIssue{EmailAddress: email, MailboxAddress: addr, Kind: kind, Detail: detail}
+}
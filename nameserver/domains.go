@@ -0,0 +1,101 @@
+package nameserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AddManagedDomain implements proto.NameserverServer.
+// It makes this Nameserver responsible for a further domain, so addresses under it can be registered.
+func (s *server) AddManagedDomain(ctx context.Context, req *proto.AddManagedDomainRequest) (*proto.ManagedDomainResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain := strings.ToLower(strings.TrimSpace(req.GetDomain()))
+	if err := validateDomain(domain); err != nil {
+		return nil, err
+	}
+	if s.responsibleDomains[domain] {
+		return &proto.ManagedDomainResponse{ManagedDomains: s.managedDomainsLocked()}, nil
+	}
+	s.responsibleDomains[domain] = true
+	s.logger.Info("Now managing domain", "domain", domain)
+	return &proto.ManagedDomainResponse{Changed: true, ManagedDomains: s.managedDomainsLocked()}, nil
+}
+
+// RemoveManagedDomain implements proto.NameserverServer.
+// It ends responsibility for a domain: registrations, compare-and-swaps and lists under it are rejected from
+// now on. Existing registrations stay resolvable by LookupMailbox, so mail to them keeps flowing while they are
+// migrated, and CheckConsistency reports them as unmanaged. With purge they are deleted instead.
+func (s *server) RemoveManagedDomain(ctx context.Context, req *proto.RemoveManagedDomainRequest) (*proto.ManagedDomainResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain := strings.ToLower(strings.TrimSpace(req.GetDomain()))
+	if err := validateDomain(domain); err != nil {
+		return nil, err
+	}
+	resp := &proto.ManagedDomainResponse{Changed: s.responsibleDomains[domain]}
+	delete(s.responsibleDomains, domain)
+	if req.GetPurge() {
+		before := s.snapshotLocked()
+		resp.Purged = int32(s.purgeDomainLocked(domain))
+		if err := s.persistLocked(before); err != nil {
+			if resp.Changed {
+				s.responsibleDomains[domain] = true // Undo the removal along with the purge
+			}
+			return nil, err
+		}
+	}
+	if resp.Changed || resp.Purged > 0 {
+		s.logger.Info("No longer managing domain", "domain", domain, "purged", resp.Purged)
+	}
+	resp.ManagedDomains = s.managedDomainsLocked()
+	return resp, nil
+}
+
+// purgeDomainLocked deletes the registrations, replicas and lists under domain and returns how many
+// registrations and lists were deleted. s.mu must be held.
+func (s *server) purgeDomainLocked(domain string) int {
+	purged := 0
+	for emailAddress := range s.mailboxes {
+		if d, ok := emailDomain(emailAddress); ok && d == domain {
+			delete(s.mailboxes, emailAddress)
+			delete(s.replicas, emailAddress)
+			purged++
+		}
+	}
+	for listAddress := range s.lists {
+		if d, ok := emailDomain(listAddress); ok && d == domain {
+			delete(s.lists, listAddress)
+			purged++
+		}
+	}
+	return purged
+}
+
+// managedDomainsLocked returns the managed domains, sorted. s.mu must be held.
+func (s *server) managedDomainsLocked() []string {
+	domains := make([]string, 0, len(s.responsibleDomains))
+	for domain := range s.responsibleDomains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// validateDomain rejects empty domains and full email addresses passed as a domain.
+func validateDomain(domain string) error {
+	if domain == "" {
+		return status.Errorf(codes.InvalidArgument, "domain cannot be empty")
+	}
+	if strings.Contains(domain, "@") {
+		return status.Errorf(codes.InvalidArgument, "invalid domain '%s', expected e.g. 'earth.com'", domain)
+	}
+	return nil
+}
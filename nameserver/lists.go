@@ -0,0 +1,123 @@
+package nameserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxListDepth = 5 // Deepest level of nested lists that ExpandList follows
+
+// RegisterList implements proto.NameserverServer.
+// It registers a distribution list address with its members, if the list's domain is managed by this
+// Nameserver and the address is not already registered as a mailbox.
+func (s *server) RegisterList(ctx context.Context, req *proto.RegisterListRequest) (*proto.RegisterListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listAddress := common.NormalizeEmail(req.GetListAddress())
+	if listAddress == "" || len(req.GetMemberEmails()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "list address and at least one member are required")
+	}
+	domain, ok := emailDomain(listAddress)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address format: %s", listAddress)
+	}
+	if !s.responsibleDomains[domain] {
+		s.logger.Warn("List registration rejected, domain not managed", "list", listAddress, "domain", domain)
+		return &proto.RegisterListResponse{
+			Success: false,
+			Message: fmt.Sprintf("Domain '%s' is not managed by this Nameserver.", domain),
+		}, nil
+	}
+	if _, isMailbox := s.mailboxes[listAddress]; isMailbox {
+		return &proto.RegisterListResponse{
+			Success: false,
+			Message: fmt.Sprintf("'%s' is already registered as a mailbox.", listAddress),
+		}, nil
+	}
+	members := make([]string, 0, len(req.GetMemberEmails()))
+	for _, member := range req.GetMemberEmails() {
+		if _, ok := emailDomain(member); !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid member email address format: %s", member)
+		}
+		members = append(members, common.NormalizeEmail(member))
+	}
+
+	before := s.snapshotLocked()
+	s.lists[listAddress] = members
+	if err := s.persistLocked(before); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Registered list", "list", listAddress, "members", len(req.GetMemberEmails()))
+	return &proto.RegisterListResponse{Success: true, Message: "List registered successfully"}, nil
+}
+
+// ExpandList implements proto.NameserverServer.
+// It resolves a list to its members, replacing nested lists by their members. Every address is visited
+// at most once, so cyclic lists terminate; lists nested deeper than maxListDepth are skipped.
+func (s *server) ExpandList(ctx context.Context, req *proto.ExpandListRequest) (*proto.ExpandListResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	listAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if listAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	return s.expandListLocked(listAddress), nil
+}
+
+// ExpandLists implements proto.NameserverServer.
+// It expands every requested address like ExpandList, so a sender's recipients are resolved in one call.
+func (s *server) ExpandLists(ctx context.Context, req *proto.ExpandListsRequest) (*proto.ExpandListsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &proto.ExpandListsResponse{Expansions: make([]*proto.ExpandListResponse, 0, len(req.GetEmailAddresses()))}
+	for _, address := range req.GetEmailAddresses() {
+		listAddress := common.NormalizeEmail(address)
+		if listAddress == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+		}
+		resp.Expansions = append(resp.Expansions, s.expandListLocked(listAddress))
+	}
+	return resp, nil
+}
+
+// expandListLocked resolves the normalized listAddress to its members. s.mu must be held.
+func (s *server) expandListLocked(listAddress string) *proto.ExpandListResponse {
+	if _, ok := s.lists[listAddress]; !ok {
+		return &proto.ExpandListResponse{IsList: false}
+	}
+
+	resp := &proto.ExpandListResponse{IsList: true}
+	visited := map[string]bool{listAddress: true}
+	s.expandLocked(listAddress, 1, visited, resp)
+	if resp.Truncated {
+		s.logger.Warn("List expansion exceeded the nesting limit", "list", listAddress, "max_depth", maxListDepth)
+	}
+	return resp
+}
+
+// expandLocked appends the members of listAddress, found at nesting level depth, to resp. s.mu must be held.
+func (s *server) expandLocked(listAddress string, depth int, visited map[string]bool, resp *proto.ExpandListResponse) {
+	for _, member := range s.lists[listAddress] {
+		if visited[member] {
+			continue // Duplicate member or a cycle back to a list being expanded
+		}
+		visited[member] = true
+		if _, isList := s.lists[member]; !isList {
+			resp.Members = append(resp.Members, member)
+			continue
+		}
+		if depth >= maxListDepth {
+			resp.Truncated = true
+			continue
+		}
+		s.expandLocked(member, depth+1, visited, resp)
+	}
+}
@@ -0,0 +1,19 @@
+package nameserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Results of LookupMailbox, as labels of the lookups counter.
+const (
+	lookupHit      = "hit"
+	lookupMiss     = "miss"
+	lookupReferral = "referral"
+)
+
+// lookups counts LookupMailbox calls by result.
+var lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "godissys_nameserver_lookups_total",
+	Help: "Mailbox lookups by result: hit, miss or referral to another Nameserver.",
+}, []string{"result"})
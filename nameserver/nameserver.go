@@ -1,16 +1,16 @@
 package nameserver
 
 import (
+	"GoDissys/common"
 	"GoDissys/proto/proto"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
+	"sort"
 	"strings"
 	"sync"
-	"syscall"
+	"unicode"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -22,110 +22,383 @@ type server struct {
 	proto.UnimplementedNameserverServer
 	// mailboxes maps full email address to their mailbox address
 	mailboxes map[string]string
-	mu        sync.RWMutex // Mutex to protect the mailboxes map
+	mu        sync.RWMutex // Mutex to protect the mailboxes, replicas, lists, responsibleDomains and referrals maps
+	// replicas maps full email address to further mailbox addresses serving it besides the primary one
+	replicas map[string][]string
+	// lists maps distribution list addresses to their member addresses
+	lists map[string][]string
 
-	// responsibleDomains stores the domains this Nameserver is responsible for.
+	// responsibleDomains stores the domains this Nameserver is responsible for; it changes at runtime
+	// through AddManagedDomain and RemoveManagedDomain.
 	responsibleDomains map[string]bool
+	// referrals maps domains managed by other Nameservers to their addresses
+	referrals map[string]string
+
+	statePath  string       // File the registrations are persisted to (empty keeps them in memory only)
+	persistErr error        // Error of the last failed save, nil once a save succeeds again
+	logger     *slog.Logger // Log records of the Nameserver, tagged with the service
 }
 
 // NewServer creates a new Nameserver instance, responsible for the given domains.
 func NewServer(domains []string) *server {
+	s, _ := NewServerWithStorage(domains, "") // Cannot fail without a state file
+	return s
+}
+
+// NewServerWithStorage creates a new Nameserver instance, responsible for the given domains, that persists
+// its registrations to statePath and restores them from there. An empty statePath keeps them in memory only.
+// It fails if an existing state file cannot be loaded.
+func NewServerWithStorage(domains []string, statePath string) (*server, error) {
 	rd := make(map[string]bool)
 	for _, d := range domains {
-		rd[d] = true
+		rd[strings.ToLower(d)] = true // Domains of normalized email addresses are lower-case
+	}
+	logger := slog.With("service", "Nameserver")
+	reg := &registry{Mailboxes: make(map[string]string), Replicas: make(map[string][]string), Lists: make(map[string][]string)}
+	if statePath != "" {
+		loaded, err := loadRegistry(statePath)
+		if err != nil {
+			return nil, err
+		}
+		reg = loaded
+		logger.Info("Restored registrations", "registrations", len(reg.Mailboxes), "lists", len(reg.Lists), "path", statePath)
 	}
 	return &server{
-		mailboxes:          make(map[string]string),
+		mailboxes:          reg.Mailboxes,
+		replicas:           reg.Replicas,
+		lists:              reg.Lists,
 		responsibleDomains: rd,
-	}
+		statePath:          statePath,
+		logger:             logger,
+	}, nil
 }
 
 // RegisterMailbox implements proto.NameserverServer.
-// It registers a user's full email address with their mailbox address,
+// It registers a user's full email address with their mailbox address (or several, for failover),
 // but only if the email's domain is managed by this Nameserver.
 func (s *server) RegisterMailbox(ctx context.Context, req *proto.RegisterMailboxRequest) (*proto.RegisterMailboxResponse, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	emailAddress := req.GetEmailAddress()
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
 	mailboxAddr := req.GetMailboxAddress()
+	mailboxAddrs, err := registrationAddrs(req)
+	if err != nil {
+		return nil, err
+	}
 
-	if emailAddress == "" || mailboxAddr == "" {
+	if emailAddress == "" || len(mailboxAddrs) == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "email address and mailbox address cannot be empty")
 	}
 
-	// Extract domain from email address
-	parts := strings.Split(emailAddress, "@")
-	if len(parts) != 2 {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid email address format: %s", emailAddress)
+	if err := validateEmail(emailAddress); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address '%s': %v", emailAddress, err)
 	}
-	domain := parts[1]
+	domain, _ := emailDomain(emailAddress)
 
 	// Check if this Nameserver is responsible for the domain
 	if !s.responsibleDomains[domain] {
-		log.Printf("Nameserver: Registration rejected for '%s'. Domain '%s' is not managed by this Nameserver.", emailAddress, domain)
+		s.logger.Warn("Registration rejected, domain not managed", "email", emailAddress, "domain", domain)
 		return &proto.RegisterMailboxResponse{
 			Success: false,
 			Message: fmt.Sprintf("Domain '%s' is not managed by this Nameserver.", domain),
 		}, nil
 	}
 
-	if _, exists := s.mailboxes[emailAddress]; exists {
-		log.Printf("Nameserver: Email '%s' already registered, updating address to '%s'", emailAddress, mailboxAddr)
+	if _, isList := s.lists[emailAddress]; isList {
+		return &proto.RegisterMailboxResponse{
+			Success: false,
+			Message: fmt.Sprintf("'%s' is already registered as a distribution list.", emailAddress),
+		}, nil
+	}
+
+	before := s.snapshotLocked()
+	if req.GetReplica() {
+		return s.persistRegistrationLocked(before, s.registerReplicasLocked(emailAddress, mailboxAddrs))
+	}
+	if len(req.GetMailboxAddresses()) > 0 {
+		return s.persistRegistrationLocked(before, s.replaceAddressesLocked(emailAddress, mailboxAddrs))
+	}
+	if current, exists := s.mailboxes[emailAddress]; exists && current == mailboxAddr {
+		// Re-registration of an identical mapping, e.g. a heartbeat: nothing to update
+		return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox already registered", Unchanged: true}, nil
+	} else if exists {
+		s.logger.Info("Updating registration", "email", emailAddress, "old_addr", current, "addr", mailboxAddr)
 	} else {
-		log.Printf("Nameserver: Registering email '%s' with mailbox at '%s'", emailAddress, mailboxAddr)
+		s.logger.Info("Registering email", "email", emailAddress, "addr", mailboxAddr)
+	}
+	s.promoteLocked(emailAddress, mailboxAddr)
+	if err := s.persistLocked(before); err != nil {
+		return nil, err
 	}
-	s.mailboxes[emailAddress] = mailboxAddr
 
 	return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox registered successfully"}, nil
 }
 
 // LookupMailbox implements proto.NameserverServer.
-// It looks up the mailbox address for a given email address.
+// It looks up the mailbox address for a given email address. An unknown address under an unmanaged domain
+// with a configured referral is answered with the address of the Nameserver responsible for it.
 func (s *server) LookupMailbox(ctx context.Context, req *proto.LookupMailboxRequest) (*proto.LookupMailboxResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	emailAddress := req.GetEmailAddress()
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	common.AnnotateSpan(ctx, common.AttrRecipient.String(emailAddress))
 	if emailAddress == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
 	}
+	if err := validateEmail(emailAddress); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address '%s': %v", emailAddress, err)
+	}
 
 	addr, found := s.mailboxes[emailAddress]
 	if !found {
-		log.Printf("Nameserver: Mailbox for email '%s' not found", emailAddress)
+		if referral := s.referralLocked(emailAddress); referral != "" {
+			s.logger.Info("Referring lookup", "email", emailAddress, "referral", referral)
+			lookups.WithLabelValues(lookupReferral).Inc()
+			return &proto.LookupMailboxResponse{Found: false, ReferralAddress: referral}, nil
+		}
+		domain, _ := emailDomain(emailAddress)
+		if !s.responsibleDomains[domain] {
+			s.logger.Info("Mailbox not found, domain not managed", "email", emailAddress, "domain", domain)
+			lookups.WithLabelValues(lookupMiss).Inc()
+			return &proto.LookupMailboxResponse{Found: false, DomainNotManaged: true}, nil
+		}
+		s.logger.Info("Mailbox not found", "email", emailAddress)
+		lookups.WithLabelValues(lookupMiss).Inc()
 		return &proto.LookupMailboxResponse{Found: false, MailboxAddress: ""}, nil
 	}
 
-	log.Printf("Nameserver: Found mailbox for email '%s' at '%s'", emailAddress, addr)
-	return &proto.LookupMailboxResponse{Found: true, MailboxAddress: addr}, nil
+	s.logger.Info("Found mailbox", "email", emailAddress, "addr", addr)
+	lookups.WithLabelValues(lookupHit).Inc()
+	return &proto.LookupMailboxResponse{Found: true, MailboxAddress: addr, ReplicaAddresses: s.replicas[emailAddress]}, nil
+}
+
+// DeregisterMailbox implements proto.NameserverServer.
+// It removes the registration of an email address along with its replicas, but only if the email's
+// domain is managed by this Nameserver.
+func (s *server) DeregisterMailbox(ctx context.Context, req *proto.DeregisterMailboxRequest) (*proto.DeregisterMailboxResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	if emailAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address cannot be empty")
+	}
+	domain, ok := emailDomain(emailAddress)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address format: %s", emailAddress)
+	}
+	if !s.responsibleDomains[domain] {
+		s.logger.Warn("Deregistration rejected, domain not managed", "email", emailAddress, "domain", domain)
+		return &proto.DeregisterMailboxResponse{
+			Removed: false,
+			Message: fmt.Sprintf("Domain '%s' is not managed by this Nameserver.", domain),
+		}, nil
+	}
+
+	addr, found := s.mailboxes[emailAddress]
+	if !found {
+		return &proto.DeregisterMailboxResponse{Removed: false, Message: "Mailbox not registered"}, nil
+	}
+	before := s.snapshotLocked()
+	delete(s.mailboxes, emailAddress)
+	delete(s.replicas, emailAddress)
+	if err := s.persistLocked(before); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Deregistered email", "email", emailAddress, "addr", addr)
+	return &proto.DeregisterMailboxResponse{Removed: true, Message: "Mailbox deregistered successfully"}, nil
+}
+
+// ListMailboxes implements proto.NameserverServer.
+// It returns every registration whose email domain is the requested one, which must be managed by this
+// Nameserver. Entries are sorted by email address.
+func (s *server) ListMailboxes(ctx context.Context, req *proto.ListMailboxesRequest) (*proto.ListMailboxesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domain := strings.ToLower(req.GetDomain())
+	if domain == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "domain cannot be empty")
+	}
+	if !s.responsibleDomains[domain] {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain '%s' is not managed by this Nameserver", domain)
+	}
+
+	entries := []*proto.MailboxEntry{}
+	for emailAddress, addr := range s.mailboxes {
+		if d, ok := emailDomain(emailAddress); !ok || d != domain {
+			continue
+		}
+		entries = append(entries, &proto.MailboxEntry{
+			EmailAddress:     emailAddress,
+			MailboxAddress:   addr,
+			ReplicaAddresses: append([]string(nil), s.replicas[emailAddress]...),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].EmailAddress < entries[j].EmailAddress })
+	s.logger.Info("Listed mailboxes", "domain", domain, "mailboxes", len(entries))
+	return &proto.ListMailboxesResponse{Entries: entries}, nil
+}
+
+// CompareAndSwapMailbox implements proto.NameserverServer.
+// It updates the mailbox address of an email address only if the current registration still matches
+// the expected address, so concurrent migrations cannot clobber each other.
+func (s *server) CompareAndSwapMailbox(ctx context.Context, req *proto.CompareAndSwapMailboxRequest) (*proto.CompareAndSwapMailboxResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	emailAddress := common.NormalizeEmail(req.GetEmailAddress())
+	newAddr := req.GetNewAddress()
+	if emailAddress == "" || newAddr == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "email address and new mailbox address cannot be empty")
+	}
+	if err := validateEmail(emailAddress); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address '%s': %v", emailAddress, err)
+	}
+	domain, _ := emailDomain(emailAddress)
+	if !s.responsibleDomains[domain] {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain '%s' is not managed by this Nameserver", domain)
+	}
+	if _, isList := s.lists[emailAddress]; isList { // As in RegisterMailbox
+		return nil, status.Errorf(codes.FailedPrecondition, "'%s' is already registered as a distribution list", emailAddress)
+	}
+
+	current := s.mailboxes[emailAddress] // Empty if not registered
+	if current != req.GetExpectedOldAddress() {
+		s.logger.Warn("Compare-and-swap rejected", "email", emailAddress, "expected_addr", req.GetExpectedOldAddress(), "current_addr", current)
+		return nil, status.Errorf(codes.FailedPrecondition, "mailbox address of '%s' is '%s', not the expected '%s'",
+			emailAddress, current, req.GetExpectedOldAddress())
+	}
+	before := s.snapshotLocked()
+	s.promoteLocked(emailAddress, newAddr)
+	if err := s.persistLocked(before); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Swapped mailbox", "email", emailAddress, "old_addr", current, "addr", newAddr)
+	return &proto.CompareAndSwapMailboxResponse{MailboxAddress: newAddr}, nil
+}
+
+// Info implements proto.NameserverServer.
+// It reports the number of registrations and the managed domains (sorted).
+func (s *server) Info(ctx context.Context, req *proto.NameserverInfoRequest) (*proto.NameserverInfoResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &proto.NameserverInfoResponse{Registrations: int32(len(s.mailboxes)), ManagedDomains: s.managedDomainsLocked()}, nil
+}
+
+// Health implements proto.NameserverServer.
+// It reports the managed domains and whether the Nameserver is serving; it is not while the last attempt
+// to persist its registrations failed, as further changes would be lost on restart.
+func (s *server) Health(ctx context.Context, req *proto.NameserverHealthRequest) (*proto.NameserverHealthResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &proto.NameserverHealthResponse{Serving: s.persistErr == nil, ManagedDomains: s.managedDomainsLocked()}
+	if s.persistErr != nil {
+		resp.Detail = fmt.Sprintf("registrations cannot be persisted: %v", s.persistErr)
+	}
+	return resp, nil
+}
+
+// validateEmail checks that email has the form local@domain: a non-empty local part, a domain of at least
+// two dot-separated labels, exactly one "@" and no whitespace.
+func validateEmail(email string) error {
+	if strings.IndexFunc(email, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("must not contain whitespace")
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return fmt.Errorf("missing '@'")
+	}
+	if strings.Contains(domain, "@") {
+		return fmt.Errorf("more than one '@'")
+	}
+	if local == "" {
+		return fmt.Errorf("empty local part")
+	}
+	if !strings.Contains(domain, ".") {
+		return fmt.Errorf("domain '%s' needs at least one dot", domain)
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" {
+			return fmt.Errorf("domain '%s' has an empty label", domain)
+		}
+	}
+	return nil
 }
 
 // StartNameserver starts the gRPC server for the Nameserver, responsible for the given domains.
-// It also sets up graceful shutdown.
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
 func StartNameserver(nameserverAddr string, domains ...string) {
+	StartNameserverWithConfig(common.Config{NameserverAddr: nameserverAddr, NameserverManagedDomains: domains})
+}
+
+// StartNameserverWithConfig is StartNameserver for the Nameserver settings of cfg: besides its address and
+// domains, the referrals to other Nameservers and the file its registrations are persisted to.
+func StartNameserverWithConfig(cfg common.Config) {
+	ctx, stop := common.SignalContext()
+	defer stop()
+	StartNameserverWithContext(ctx, cfg, nil)
+}
+
+// StartNameserverWithContext runs the Nameserver configured by cfg like StartNameserverWithConfig, but until
+// ctx is cancelled instead of until a signal arrives. It then stops the server gracefully and returns, so
+// several services can run in one process (or a test) and be stopped together. Unless nil, ready is called
+// with the listening address once the Nameserver is serving.
+func StartNameserverWithContext(ctx context.Context, cfg common.Config, ready func(addr string)) {
+	handle, err := ServeNameserver(cfg)
+	if err != nil {
+		slog.Error("Nameserver failed to start", "service", "Nameserver", "error", err)
+		return
+	}
+	if ready != nil {
+		ready(handle.Addr())
+	}
+	<-ctx.Done() // Block until the Nameserver is to stop
+	handle.Stop()
+}
+
+// ServeNameserver starts the Nameserver configured by cfg serving in the background and returns once it
+// listens. The returned handle reports the listening address and stops the Nameserver gracefully.
+func ServeNameserver(cfg common.Config) (*common.ServerHandle, error) {
+	nameserverAddr, domains := cfg.NameserverAddr, cfg.NameserverManagedDomains
+	// TLS and the listener are set up first, so a failure leaves no service behind to close
+	serverOpts, err := common.ServerOptions(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
 	lis, err := net.Listen("tcp", nameserverAddr)
 	if err != nil {
-		log.Printf("Nameserver failed to listen on %s: %v", nameserverAddr, err)
-		return // Return instead of Fatalf, allow main to handle
+		return nil, fmt.Errorf("failed to listen on %s: %w", nameserverAddr, err)
 	}
-	s := grpc.NewServer()
-	nameserverService := NewServer(domains) // Pass domains to NewServer
+	nameserverService, err := NewServerWithStorage(domains, common.StatePath(cfg.NameserverStateDir, cfg.NameserverInstanceName, stateFile))
+	if err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	nameserverService.SetReferrals(cfg.NameserverReferrals)
+	opts := append(common.TracingServerOptions(), common.MetricsOptions("Nameserver")...)
+	opts = append(opts, common.RequestLogOptions("Nameserver", cfg.LogRequests)...)
+	s := grpc.NewServer(append(opts, serverOpts...)...)
 	proto.RegisterNameserverServer(s, nameserverService)
-	log.Printf("Nameserver listening on %s, responsible for domains: %v", nameserverAddr, domains)
+	nameserverService.logger.Info("Nameserver listening", "addr", lis.Addr().String(), "domains", domains)
 
 	// Goroutine to serve gRPC requests
 	go func() {
 		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
-			log.Printf("Nameserver failed to serve: %v", err)
+			nameserverService.logger.Error("Nameserver failed to serve", "error", err)
 		}
 	}()
 
-	// Set up graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit // Block until a signal is received
-	log.Printf("Nameserver received shutdown signal. Shutting down gracefully...")
-	s.GracefulStop() // Gracefully stop the gRPC server
-	log.Println("Nameserver server stopped.")
+	return common.NewServerHandle(lis.Addr().String(), func() {
+		nameserverService.logger.Info("Nameserver shutting down gracefully")
+		s.GracefulStop() // Gracefully stop the gRPC server
+		if err := nameserverService.Close(); err != nil {
+			nameserverService.logger.Error("Failed to save registrations on shutdown", "error", err)
+		}
+		nameserverService.logger.Info("Nameserver stopped")
+	}), nil
 }
@@ -1,12 +1,19 @@
 package nameserver
 
 import (
+	"GoDissys/common"
 	"GoDissys/proto/proto"
 	"context"
+	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -190,3 +197,862 @@ func TestNameserver_RegisterAndLookup(t *testing.T) {
 		}
 	})
 }
+
+// TestNameserver_MalformedEmail tests that RegisterMailbox and LookupMailbox reject malformed addresses.
+func TestNameserver_MalformedEmail(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	tests := []struct {
+		name, email string
+	}{
+		{"NoAt", "alice.earth.com"},
+		{"DoubleAt", "a@@earth.com"},
+		{"TwoAts", "a@b@earth.com"},
+		{"EmptyLocalPart", "@earth.com"},
+		{"EmptyDomain", "alice@"},
+		{"DomainWithoutDot", "alice@earth"},
+		{"EmptyDomainLabel", "alice@earth..com"},
+		{"LeadingDot", "alice@.earth.com"},
+		{"InnerSpace", "al ice@earth.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: tc.email, MailboxAddress: "localhost:1001"})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("RegisterMailbox('%s'): expected InvalidArgument, got %v", tc.email, err)
+			}
+			_, err = client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: tc.email})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("LookupMailbox('%s'): expected InvalidArgument, got %v", tc.email, err)
+			}
+		})
+	}
+
+	if resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice.b@earth.com", MailboxAddress: "localhost:1001"}); err != nil || !resp.GetSuccess() {
+		t.Errorf("Expected a well-formed address to be accepted, got %v (err %v)", resp, err)
+	}
+}
+
+// TestNameserver_Referral tests that lookups of unknown addresses under unmanaged domains carry the
+// configured referral, and that managed domains and local registrations take precedence over it.
+func TestNameserver_Referral(t *testing.T) {
+	ns := NewServer([]string{"earth.com"})
+	ns.SetReferrals(map[string]string{"Mars.com": "localhost:6001", "earth.com": "localhost:6002"})
+	client := startTestNameserver(t, ns)
+	if _, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"}); err != nil {
+		t.Fatalf("RegisterMailbox failed: %v", err)
+	}
+
+	before := map[string]float64{}
+	for _, result := range []string{lookupHit, lookupMiss, lookupReferral} {
+		before[result] = testutil.ToFloat64(lookups.WithLabelValues(result))
+	}
+	t.Cleanup(func() {
+		for result, want := range map[string]float64{lookupHit: 1, lookupMiss: 2, lookupReferral: 1} {
+			if got := testutil.ToFloat64(lookups.WithLabelValues(result)) - before[result]; got != want {
+				t.Errorf("Expected %v lookups counted as %s, got %v", want, result, got)
+			}
+		}
+	})
+
+	tests := []struct {
+		name, email, wantAddr, wantReferral string
+		wantNotManaged                      bool
+	}{
+		{"UnmanagedWithReferral", "zoe@mars.com", "", "localhost:6001", false},
+		{"UnmanagedWithoutReferral", "zoe@venus.com", "", "", true},
+		{"ManagedUnknown", "bob@earth.com", "", "", false},
+		{"ManagedRegistered", "alice@earth.com", "localhost:1001", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: tc.email})
+			if err != nil {
+				t.Fatalf("LookupMailbox failed: %v", err)
+			}
+			if resp.GetMailboxAddress() != tc.wantAddr || resp.GetReferralAddress() != tc.wantReferral {
+				t.Errorf("Expected address '%s' and referral '%s', got %v", tc.wantAddr, tc.wantReferral, resp)
+			}
+			if resp.GetDomainNotManaged() != tc.wantNotManaged {
+				t.Errorf("Expected domain_not_managed %v, got %v", tc.wantNotManaged, resp)
+			}
+		})
+	}
+}
+
+// TestNameserver_Persistence tests that registrations, replicas and lists survive a restart, starting
+// from a missing state file, and that a corrupt state file is reported.
+func TestNameserver_Persistence(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state", "nameserver.json")
+	ns, err := NewServerWithStorage([]string{"earth.com"}, statePath)
+	if err != nil {
+		t.Fatalf("NewServerWithStorage failed on first run: %v", err)
+	}
+	client := startTestNameserver(t, ns)
+	for _, req := range []*proto.RegisterMailboxRequest{
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"},
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1002", Replica: true},
+		{EmailAddress: "bob@earth.com", MailboxAddress: "localhost:1001"},
+	} {
+		if resp, err := client.RegisterMailbox(context.Background(), req); err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+	}
+	if _, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "team@earth.com", MemberEmails: []string{"alice@earth.com"}}); err != nil {
+		t.Fatalf("RegisterList failed: %v", err)
+	}
+	if _, err := client.DeregisterMailbox(context.Background(), &proto.DeregisterMailboxRequest{EmailAddress: "bob@earth.com"}); err != nil {
+		t.Fatalf("DeregisterMailbox failed: %v", err)
+	}
+
+	restarted, err := NewServerWithStorage([]string{"earth.com"}, statePath)
+	if err != nil {
+		t.Fatalf("NewServerWithStorage failed to restore: %v", err)
+	}
+	client = startTestNameserver(t, restarted)
+	t.Run("Restored", func(t *testing.T) {
+		resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+		if err != nil || resp.GetMailboxAddress() != "localhost:1001" || fmt.Sprint(resp.GetReplicaAddresses()) != "[localhost:1002]" {
+			t.Errorf("Expected alice's addresses to be restored, got %v (err %v)", resp, err)
+		}
+		if resp, _ := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "bob@earth.com"}); resp.GetFound() {
+			t.Errorf("Expected the deregistration to be persisted, got %v", resp)
+		}
+		if resp, _ := client.ExpandList(context.Background(), &proto.ExpandListRequest{EmailAddress: "team@earth.com"}); !resp.GetIsList() {
+			t.Errorf("Expected the list to be restored, got %v", resp)
+		}
+	})
+	t.Run("NoTemporaryFiles", func(t *testing.T) {
+		entries, err := os.ReadDir(filepath.Dir(statePath))
+		if err != nil || len(entries) != 1 {
+			t.Errorf("Expected only the state file, got %v (err %v)", entries, err)
+		}
+	})
+	t.Run("NormalizesKeys", func(t *testing.T) {
+		mixedCase := filepath.Join(t.TempDir(), "nameserver.json")
+		state := `{"Mailboxes": {"Alice@Earth.com": "localhost:1001"}, "Replicas": {"Alice@Earth.com": ["localhost:1002"]},
+			"Lists": {"Team@Earth.com": ["ALICE@earth.com"]}}`
+		if err := os.WriteFile(mixedCase, []byte(state), 0o644); err != nil {
+			t.Fatalf("Failed to write state: %v", err)
+		}
+		ns, err := NewServerWithStorage([]string{"earth.com"}, mixedCase)
+		if err != nil {
+			t.Fatalf("NewServerWithStorage failed: %v", err)
+		}
+		resp, err := ns.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+		if err != nil || resp.GetMailboxAddress() != "localhost:1001" || fmt.Sprint(resp.GetReplicaAddresses()) != "[localhost:1002]" {
+			t.Errorf("Expected the mixed-case registration to resolve, got %v (err %v)", resp, err)
+		}
+		list, err := ns.ExpandList(context.Background(), &proto.ExpandListRequest{EmailAddress: "team@earth.com"})
+		if err != nil || fmt.Sprint(list.GetMembers()) != "[alice@earth.com]" {
+			t.Errorf("Expected the mixed-case list to expand, got %v (err %v)", list, err)
+		}
+	})
+	t.Run("CorruptFile", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "nameserver.json")
+		if err := os.WriteFile(corrupt, []byte("{not json"), 0o644); err != nil {
+			t.Fatalf("Failed to write corrupt state: %v", err)
+		}
+		if _, err := NewServerWithStorage([]string{"earth.com"}, corrupt); err == nil {
+			t.Errorf("Expected an error for a corrupt state file")
+		}
+	})
+}
+
+// TestNameserver_Health tests that Health reports the domains the Nameserver was created with, and that it
+// stops serving while registrations cannot be persisted, which fails and undoes the change.
+func TestNameserver_Health(t *testing.T) {
+	ns, err := NewServerWithStorage([]string{"saturn.com", "Earth.com"}, filepath.Join(t.TempDir(), "nameserver.json"))
+	if err != nil {
+		t.Fatalf("NewServerWithStorage failed: %v", err)
+	}
+	client := startTestNameserver(t, ns)
+	health := func(t *testing.T) *proto.NameserverHealthResponse {
+		t.Helper()
+		resp, err := client.Health(context.Background(), &proto.NameserverHealthRequest{})
+		if err != nil {
+			t.Fatalf("Health failed: %v", err)
+		}
+		return resp
+	}
+	register := func(email string) error {
+		_, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: email, MailboxAddress: "localhost:1001"})
+		return err
+	}
+
+	if resp := health(t); !resp.GetServing() || fmt.Sprint(resp.GetManagedDomains()) != "[earth.com saturn.com]" {
+		t.Errorf("Expected a serving Nameserver managing [earth.com saturn.com], got %v", resp)
+	}
+
+	t.Run("PersistenceFailing", func(t *testing.T) {
+		blocker := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		ns.mu.Lock()
+		goodPath := ns.statePath
+		ns.statePath = filepath.Join(blocker, "nameserver.json") // A directory that cannot be created
+		ns.mu.Unlock()
+		if err := register("alice@earth.com"); status.Code(err) != codes.Internal {
+			t.Errorf("Expected Internal for a registration that cannot be persisted, got %v", err)
+		}
+		if resp := health(t); resp.GetServing() || resp.GetDetail() == "" {
+			t.Errorf("Expected not serving with a reason, got %v", resp)
+		}
+		if resp, _ := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"}); resp.GetFound() {
+			t.Errorf("Expected the failed registration to be undone, got %v", resp)
+		}
+
+		ns.mu.Lock()
+		ns.statePath = goodPath
+		ns.mu.Unlock()
+		if err := register("bob@earth.com"); err != nil {
+			t.Fatalf("RegisterMailbox failed: %v", err)
+		}
+		ns.mu.Lock()
+		ns.statePath = filepath.Join(blocker, "nameserver.json")
+		ns.mu.Unlock()
+		_, err := client.DeregisterMailbox(context.Background(), &proto.DeregisterMailboxRequest{EmailAddress: "bob@earth.com"})
+		if status.Code(err) != codes.Internal {
+			t.Errorf("Expected Internal for a deregistration that cannot be persisted, got %v", err)
+		}
+		if resp, _ := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "bob@earth.com"}); !resp.GetFound() {
+			t.Errorf("Expected the failed deregistration to be undone, got %v", resp)
+		}
+
+		ns.mu.Lock()
+		ns.statePath = goodPath
+		ns.mu.Unlock()
+		register("carol@earth.com")
+		if resp := health(t); !resp.GetServing() {
+			t.Errorf("Expected serving again after a successful save, got %v", resp)
+		}
+	})
+}
+
+// startTestNameserver serves nameserverService on a random local port and returns a connected client.
+func startTestNameserver(t *testing.T, nameserverService *server) proto.NameserverClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterNameserverServer(s, nameserverService)
+	go func() {
+		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			t.Errorf("Nameserver failed to serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Could not connect to Nameserver: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewNameserverClient(conn)
+}
+
+// TestNameserver_CheckConsistency tests that corrupted registrations are flagged by the consistency check.
+func TestNameserver_CheckConsistency(t *testing.T) {
+	nameserverService := NewServer([]string{"earth.com"})
+	client := startTestNameserver(t, nameserverService)
+
+	// A live listener and an address nobody listens on
+	live, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer live.Close()
+	closed, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	deadAddr := closed.Addr().String()
+	closed.Close()
+
+	// Seed the registry directly, bypassing RegisterMailbox validation
+	nameserverService.mu.Lock()
+	nameserverService.mailboxes["alice@earth.com"] = live.Addr().String()
+	nameserverService.mailboxes["bob@earth.com"] = "not-an-address"
+	nameserverService.mailboxes["eve@mars.com"] = live.Addr().String()
+	nameserverService.mailboxes["broken"] = live.Addr().String()
+	nameserverService.mailboxes["carol@earth.com"] = deadAddr
+	nameserverService.mu.Unlock()
+
+	issueKinds := func(resp *proto.CheckConsistencyResponse) map[string][]proto.ConsistencyIssueKind {
+		kinds := make(map[string][]proto.ConsistencyIssueKind)
+		for _, issue := range resp.GetIssues() {
+			kinds[issue.GetEmailAddress()] = append(kinds[issue.GetEmailAddress()], issue.GetKind())
+		}
+		return kinds
+	}
+
+	t.Run("StaticChecks", func(t *testing.T) {
+		resp, err := client.CheckConsistency(context.Background(), &proto.CheckConsistencyRequest{})
+		if err != nil {
+			t.Fatalf("CheckConsistency failed: %v", err)
+		}
+		if resp.GetChecked() != 5 {
+			t.Errorf("Expected 5 registrations checked, got %d", resp.GetChecked())
+		}
+		kinds := issueKinds(resp)
+		want := map[string]proto.ConsistencyIssueKind{
+			"bob@earth.com": proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS,
+			"eve@mars.com":  proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_UNMANAGED_DOMAIN,
+			"broken":        proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_EMAIL,
+		}
+		if len(kinds) != len(want) {
+			t.Errorf("Expected issues for %d registrations, got %v", len(want), kinds)
+		}
+		for email, kind := range want {
+			if len(kinds[email]) != 1 || kinds[email][0] != kind {
+				t.Errorf("Expected issue %v for '%s', got %v", kind, email, kinds[email])
+			}
+		}
+	})
+
+	t.Run("VerifyReachability", func(t *testing.T) {
+		resp, err := client.CheckConsistency(context.Background(), &proto.CheckConsistencyRequest{VerifyReachability: true})
+		if err != nil {
+			t.Fatalf("CheckConsistency failed: %v", err)
+		}
+		kinds := issueKinds(resp)
+		if got := kinds["carol@earth.com"]; len(got) != 1 || got[0] != proto.ConsistencyIssueKind_CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX {
+			t.Errorf("Expected unreachable mailbox issue for 'carol@earth.com', got %v", got)
+		}
+		if got := kinds["alice@earth.com"]; len(got) != 0 {
+			t.Errorf("Expected no issues for reachable 'alice@earth.com', got %v", got)
+		}
+	})
+}
+
+// TestNameserver_DistributionLists tests registering lists and expanding flat, nested and cyclic lists.
+func TestNameserver_DistributionLists(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	register := func(t *testing.T, list string, members ...string) {
+		t.Helper()
+		resp, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: list, MemberEmails: members})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterList(%s) failed: %v (err %v)", list, resp, err)
+		}
+	}
+	expand := func(t *testing.T, list string) *proto.ExpandListResponse {
+		t.Helper()
+		resp, err := client.ExpandList(context.Background(), &proto.ExpandListRequest{EmailAddress: list})
+		if err != nil {
+			t.Fatalf("ExpandList(%s) failed: %v", list, err)
+		}
+		return resp
+	}
+
+	t.Run("FlatList", func(t *testing.T) {
+		register(t, "team@earth.com", "alice@earth.com", "bob@earth.com", "alice@earth.com")
+		resp := expand(t, "team@earth.com")
+		if !resp.GetIsList() || resp.GetTruncated() || strings.Join(resp.GetMembers(), ",") != "alice@earth.com,bob@earth.com" {
+			t.Errorf("Expected members [alice bob] without duplicates, got %v", resp)
+		}
+	})
+
+	t.Run("NestedListWithCycle", func(t *testing.T) {
+		register(t, "eng@earth.com", "carol@earth.com", "ops@earth.com")
+		register(t, "ops@earth.com", "dave@earth.com", "eng@earth.com") // Cycle back to eng
+		resp := expand(t, "eng@earth.com")
+		if !resp.GetIsList() || strings.Join(resp.GetMembers(), ",") != "carol@earth.com,dave@earth.com" {
+			t.Errorf("Expected members [carol dave], got %v", resp)
+		}
+	})
+
+	t.Run("DepthLimit", func(t *testing.T) {
+		for i := 0; i < maxListDepth+1; i++ {
+			register(t, fmt.Sprintf("level%d@earth.com", i), fmt.Sprintf("user%d@earth.com", i), fmt.Sprintf("level%d@earth.com", i+1))
+		}
+		register(t, fmt.Sprintf("level%d@earth.com", maxListDepth+1), "deepest@earth.com")
+		resp := expand(t, "level0@earth.com")
+		if !resp.GetTruncated() || len(resp.GetMembers()) != maxListDepth {
+			t.Errorf("Expected %d members and truncation at the depth limit, got %v", maxListDepth, resp)
+		}
+	})
+
+	t.Run("NotAList", func(t *testing.T) {
+		if resp := expand(t, "alice@earth.com"); resp.GetIsList() {
+			t.Errorf("Expected a plain address not to be a list, got %v", resp)
+		}
+	})
+
+	t.Run("RejectedRegistrations", func(t *testing.T) {
+		client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "frank@earth.com", MailboxAddress: "localhost:1"})
+		if resp, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "frank@earth.com", MemberEmails: []string{"alice@earth.com"}}); err != nil || resp.GetSuccess() {
+			t.Errorf("Expected a list over a mailbox registration to be rejected, got %v (err %v)", resp, err)
+		}
+		if resp, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "team@mars.com", MemberEmails: []string{"alice@earth.com"}}); err != nil || resp.GetSuccess() {
+			t.Errorf("Expected a list in an unmanaged domain to be rejected, got %v (err %v)", resp, err)
+		}
+		if _, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "empty@earth.com"}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for a list without members, got %v", err)
+		}
+		if resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "Team@earth.com", MailboxAddress: "localhost:1"}); err != nil || resp.GetSuccess() {
+			t.Errorf("Expected a mailbox over a list registration to be rejected, got %v (err %v)", resp, err)
+		}
+		if resp := expand(t, "team@earth.com"); !resp.GetIsList() {
+			t.Errorf("Expected the list to survive the rejected registration, got %v", resp)
+		}
+	})
+
+	t.Run("ExpandLists", func(t *testing.T) {
+		resp, err := client.ExpandLists(context.Background(), &proto.ExpandListsRequest{EmailAddresses: []string{"alice@earth.com", "Team@Earth.com"}})
+		if err != nil || len(resp.GetExpansions()) != 2 {
+			t.Fatalf("Expected one expansion per address, got %v (err %v)", resp, err)
+		}
+		if plain, team := resp.GetExpansions()[0], resp.GetExpansions()[1]; plain.GetIsList() || !team.GetIsList() ||
+			strings.Join(team.GetMembers(), ",") != "alice@earth.com,bob@earth.com" {
+			t.Errorf("Expected only the second address to expand to [alice bob], got %v", resp)
+		}
+		if _, err := client.ExpandLists(context.Background(), &proto.ExpandListsRequest{EmailAddresses: []string{""}}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an empty address, got %v", err)
+		}
+	})
+}
+
+// TestNameserver_CompareAndSwapMailbox tests that a registration is only swapped when it matches the expected address.
+func TestNameserver_CompareAndSwapMailbox(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"})
+	lookup := func(t *testing.T) string {
+		t.Helper()
+		resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+		if err != nil {
+			t.Fatalf("LookupMailbox failed: %v", err)
+		}
+		return resp.GetMailboxAddress()
+	}
+
+	t.Run("SuccessfulSwap", func(t *testing.T) {
+		resp, err := client.CompareAndSwapMailbox(context.Background(), &proto.CompareAndSwapMailboxRequest{
+			EmailAddress: "alice@earth.com", ExpectedOldAddress: "localhost:1001", NewAddress: "localhost:2002",
+		})
+		if err != nil {
+			t.Fatalf("CompareAndSwapMailbox failed: %v", err)
+		}
+		if resp.GetMailboxAddress() != "localhost:2002" || lookup(t) != "localhost:2002" {
+			t.Errorf("Expected the registration to point to localhost:2002, got %v / %s", resp, lookup(t))
+		}
+	})
+
+	t.Run("MismatchedExpectedValue", func(t *testing.T) {
+		_, err := client.CompareAndSwapMailbox(context.Background(), &proto.CompareAndSwapMailboxRequest{
+			EmailAddress: "alice@earth.com", ExpectedOldAddress: "localhost:1001", NewAddress: "localhost:3003",
+		})
+		if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), "localhost:2002") {
+			t.Errorf("Expected FailedPrecondition naming the current address, got %v", err)
+		}
+		if got := lookup(t); got != "localhost:2002" {
+			t.Errorf("Expected the registration to be unchanged, got %s", got)
+		}
+	})
+
+	t.Run("CreateIfAbsent", func(t *testing.T) {
+		_, err := client.CompareAndSwapMailbox(context.Background(), &proto.CompareAndSwapMailboxRequest{
+			EmailAddress: "bob@earth.com", NewAddress: "localhost:4004",
+		})
+		if err != nil {
+			t.Errorf("Expected an empty expected address to register a new email, got %v", err)
+		}
+		_, err = client.CompareAndSwapMailbox(context.Background(), &proto.CompareAndSwapMailboxRequest{
+			EmailAddress: "bob@earth.com", NewAddress: "localhost:5005",
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition for an already registered email, got %v", err)
+		}
+	})
+
+	t.Run("ListAddress", func(t *testing.T) {
+		list, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "team@earth.com", MemberEmails: []string{"alice@earth.com"}})
+		if err != nil || !list.GetSuccess() {
+			t.Fatalf("RegisterList failed: %v (err %v)", list, err)
+		}
+		_, err = client.CompareAndSwapMailbox(context.Background(), &proto.CompareAndSwapMailboxRequest{
+			EmailAddress: "team@earth.com", NewAddress: "localhost:6006",
+		})
+		if status.Code(err) != codes.FailedPrecondition || !strings.Contains(err.Error(), "distribution list") {
+			t.Errorf("Expected FailedPrecondition for a distribution list address, got %v", err)
+		}
+		resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "team@earth.com"})
+		if err != nil || resp.GetFound() {
+			t.Errorf("Expected no mailbox at the list address, got %v (err %v)", resp, err)
+		}
+	})
+}
+
+// TestNameserver_RegisterUnchanged tests that registering an address with the mailbox address it already holds
+// is reported as unchanged, while a different mailbox address updates the registration.
+func TestNameserver_RegisterUnchanged(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	register := func(t *testing.T, addr string) *proto.RegisterMailboxResponse {
+		t.Helper()
+		resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: addr})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+		return resp
+	}
+
+	if resp := register(t, "localhost:1001"); resp.GetUnchanged() {
+		t.Errorf("Expected the first registration to be a change, got %v", resp)
+	}
+	t.Run("SamePairTwice", func(t *testing.T) {
+		resp := register(t, "localhost:1001")
+		if !resp.GetUnchanged() || resp.GetMessage() != "Mailbox already registered" {
+			t.Errorf("Expected an unchanged registration, got %v", resp)
+		}
+	})
+	t.Run("DifferentAddress", func(t *testing.T) {
+		if resp := register(t, "localhost:2002"); resp.GetUnchanged() || resp.GetMessage() != "Mailbox registered successfully" {
+			t.Errorf("Expected the address to be updated, got %v", resp)
+		}
+	})
+}
+
+// TestNameserver_CaseInsensitive tests that email addresses and domains are matched regardless of case.
+func TestNameserver_CaseInsensitive(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"Earth.com"}))
+	resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "Alice@EARTH.com", MailboxAddress: "localhost:1001"})
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+	}
+
+	for _, email := range []string{"alice@earth.com", "ALICE@Earth.Com", " Alice@EARTH.com "} {
+		lookup, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: email})
+		if err != nil || !lookup.GetFound() || lookup.GetMailboxAddress() != "localhost:1001" {
+			t.Errorf("Expected '%s' to resolve to localhost:1001, got %v (err %v)", email, lookup, err)
+		}
+	}
+	t.Run("ReRegisterOtherCase", func(t *testing.T) {
+		resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"})
+		if err != nil || !resp.GetUnchanged() {
+			t.Errorf("Expected the same registration in another case to be unchanged, got %v (err %v)", resp, err)
+		}
+	})
+	t.Run("Lists", func(t *testing.T) {
+		if _, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "Team@Earth.com", MemberEmails: []string{"ALICE@earth.com"}}); err != nil {
+			t.Fatalf("RegisterList failed: %v", err)
+		}
+		resp, err := client.ExpandList(context.Background(), &proto.ExpandListRequest{EmailAddress: "team@earth.com"})
+		if err != nil || !resp.GetIsList() || fmt.Sprint(resp.GetMembers()) != "[alice@earth.com]" {
+			t.Errorf("Expected the list to expand to the normalized member, got %v (err %v)", resp, err)
+		}
+	})
+	t.Run("Deregister", func(t *testing.T) {
+		resp, err := client.DeregisterMailbox(context.Background(), &proto.DeregisterMailboxRequest{EmailAddress: "aLiCe@earth.COM"})
+		if err != nil || !resp.GetRemoved() {
+			t.Errorf("Expected the registration to be removed, got %v (err %v)", resp, err)
+		}
+	})
+}
+
+// TestNameserver_DeregisterMailbox tests that deregistration removes a registration and its replicas,
+// reports whether anything was removed and leaves unmanaged domains alone.
+func TestNameserver_DeregisterMailbox(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	for _, req := range []*proto.RegisterMailboxRequest{
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"},
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1002", Replica: true},
+	} {
+		if resp, err := client.RegisterMailbox(context.Background(), req); err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+	}
+	deregister := func(t *testing.T, email string) *proto.DeregisterMailboxResponse {
+		t.Helper()
+		resp, err := client.DeregisterMailbox(context.Background(), &proto.DeregisterMailboxRequest{EmailAddress: email})
+		if err != nil {
+			t.Fatalf("DeregisterMailbox failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("Registered", func(t *testing.T) {
+		if resp := deregister(t, "alice@earth.com"); !resp.GetRemoved() {
+			t.Fatalf("Expected the registration to be removed, got %v", resp)
+		}
+		lookup, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+		if err != nil || lookup.GetFound() || len(lookup.GetReplicaAddresses()) != 0 {
+			t.Errorf("Expected no registration after deregistering, got %v (err %v)", lookup, err)
+		}
+	})
+	t.Run("AlreadyGone", func(t *testing.T) {
+		if resp := deregister(t, "alice@earth.com"); resp.GetRemoved() {
+			t.Errorf("Expected nothing to remove, got %v", resp)
+		}
+	})
+	t.Run("UnmanagedDomain", func(t *testing.T) {
+		if resp := deregister(t, "bob@saturn.com"); resp.GetRemoved() || !strings.Contains(resp.GetMessage(), "not managed") {
+			t.Errorf("Expected a rejection for an unmanaged domain, got %v", resp)
+		}
+	})
+	t.Run("EmptyEmail", func(t *testing.T) {
+		_, err := client.DeregisterMailbox(context.Background(), &proto.DeregisterMailboxRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument, got %v", err)
+		}
+	})
+}
+
+// TestNameserver_ListMailboxes tests that ListMailboxes returns the sorted registrations of one managed domain.
+func TestNameserver_ListMailboxes(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com", "saturn.com"}))
+	for _, req := range []*proto.RegisterMailboxRequest{
+		{EmailAddress: "carol@earth.com", MailboxAddress: "localhost:1001"},
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1001"},
+		{EmailAddress: "alice@earth.com", MailboxAddress: "localhost:1002", Replica: true},
+		{EmailAddress: "bob@saturn.com", MailboxAddress: "localhost:2001"},
+	} {
+		if resp, err := client.RegisterMailbox(context.Background(), req); err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+	}
+
+	t.Run("ManagedDomain", func(t *testing.T) {
+		resp, err := client.ListMailboxes(context.Background(), &proto.ListMailboxesRequest{Domain: "earth.com"})
+		if err != nil {
+			t.Fatalf("ListMailboxes failed: %v", err)
+		}
+		var got []string
+		for _, e := range resp.GetEntries() {
+			got = append(got, fmt.Sprintf("%s=%s%v", e.GetEmailAddress(), e.GetMailboxAddress(), e.GetReplicaAddresses()))
+		}
+		if want := "[alice@earth.com=localhost:1001[localhost:1002] carol@earth.com=localhost:1001[]]"; fmt.Sprint(got) != want {
+			t.Errorf("Expected %s, got %v", want, got)
+		}
+	})
+	t.Run("UnmanagedDomain", func(t *testing.T) {
+		_, err := client.ListMailboxes(context.Background(), &proto.ListMailboxesRequest{Domain: "mars.com"})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition, got %v", err)
+		}
+	})
+	t.Run("EmptyDomain", func(t *testing.T) {
+		_, err := client.ListMailboxes(context.Background(), &proto.ListMailboxesRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument, got %v", err)
+		}
+	})
+}
+
+// TestNameserver_ManagedDomains tests adding and removing managed domains at runtime, keeping or purging the
+// registrations of a removed domain.
+func TestNameserver_ManagedDomains(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	register := func(email string) *proto.RegisterMailboxResponse {
+		resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: email, MailboxAddress: "localhost:3001"})
+		if err != nil {
+			t.Fatalf("RegisterMailbox failed: %v", err)
+		}
+		return resp
+	}
+	found := func(email string) bool {
+		resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: email})
+		if err != nil {
+			t.Fatalf("LookupMailbox failed: %v", err)
+		}
+		return resp.GetFound()
+	}
+
+	if register("zoe@mars.com").GetSuccess() {
+		t.Fatalf("Expected a registration for an unmanaged domain to be rejected")
+	}
+	t.Run("Add", func(t *testing.T) {
+		resp, err := client.AddManagedDomain(context.Background(), &proto.AddManagedDomainRequest{Domain: "mars.com"})
+		if err != nil || !resp.GetChanged() || fmt.Sprint(resp.GetManagedDomains()) != "[earth.com mars.com]" {
+			t.Fatalf("Expected mars.com to be added, got %v (err %v)", resp, err)
+		}
+		if resp, _ := client.AddManagedDomain(context.Background(), &proto.AddManagedDomainRequest{Domain: "mars.com"}); resp.GetChanged() {
+			t.Errorf("Expected adding a managed domain again to change nothing, got %v", resp)
+		}
+		if !register("zoe@mars.com").GetSuccess() {
+			t.Errorf("Expected registrations for the added domain to succeed")
+		}
+		if resp, err := client.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "crew@mars.com", MemberEmails: []string{"zoe@mars.com"}}); err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterList failed: %v (err %v)", resp, err)
+		}
+	})
+	t.Run("RemoveKeepsRegistrations", func(t *testing.T) {
+		resp, err := client.RemoveManagedDomain(context.Background(), &proto.RemoveManagedDomainRequest{Domain: "mars.com"})
+		if err != nil || !resp.GetChanged() || resp.GetPurged() != 0 || fmt.Sprint(resp.GetManagedDomains()) != "[earth.com]" {
+			t.Fatalf("Expected mars.com to be removed, got %v (err %v)", resp, err)
+		}
+		if !found("zoe@mars.com") {
+			t.Errorf("Expected the existing registration to stay resolvable")
+		}
+		if register("yan@mars.com").GetSuccess() {
+			t.Errorf("Expected new registrations for the removed domain to be rejected")
+		}
+	})
+	t.Run("Purge", func(t *testing.T) {
+		resp, err := client.RemoveManagedDomain(context.Background(), &proto.RemoveManagedDomainRequest{Domain: "mars.com", Purge: true})
+		if err != nil || resp.GetChanged() || resp.GetPurged() != 2 {
+			t.Fatalf("Expected the registration and the list to be purged, got %v (err %v)", resp, err)
+		}
+		if found("zoe@mars.com") {
+			t.Errorf("Expected the purged registration to be gone")
+		}
+	})
+	t.Run("InvalidDomain", func(t *testing.T) {
+		for _, domain := range []string{"", "zoe@mars.com"} {
+			if _, err := client.AddManagedDomain(context.Background(), &proto.AddManagedDomainRequest{Domain: domain}); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for '%s', got %v", domain, err)
+			}
+		}
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				client.AddManagedDomain(context.Background(), &proto.AddManagedDomainRequest{Domain: "venus.com"})
+				client.RemoveManagedDomain(context.Background(), &proto.RemoveManagedDomainRequest{Domain: "venus.com"})
+			}()
+			go func(i int) {
+				defer wg.Done()
+				client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: fmt.Sprintf("u%d@venus.com", i), MailboxAddress: "localhost:3001"})
+				client.Info(context.Background(), &proto.NameserverInfoRequest{})
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+// TestNameserver_Replicas tests that replica registrations add mailbox addresses behind the primary one, that a
+// plain registration replaces them, and that lists of addresses replace or append as a whole.
+func TestNameserver_Replicas(t *testing.T) {
+	client := startTestNameserver(t, NewServer([]string{"earth.com"}))
+	register := func(t *testing.T, addr string, replica bool) *proto.RegisterMailboxResponse {
+		t.Helper()
+		resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: addr, Replica: replica})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+		return resp
+	}
+	lookup := func(t *testing.T) *proto.LookupMailboxResponse {
+		t.Helper()
+		resp, err := client.LookupMailbox(context.Background(), &proto.LookupMailboxRequest{EmailAddress: "alice@earth.com"})
+		if err != nil {
+			t.Fatalf("LookupMailbox failed: %v", err)
+		}
+		return resp
+	}
+
+	register(t, "localhost:1001", true) // The first registration becomes the primary address
+	register(t, "localhost:2002", true)
+	if resp := register(t, "localhost:2002", true); !resp.GetUnchanged() {
+		t.Errorf("Expected a repeated replica registration to be unchanged, got %v", resp)
+	}
+	if resp := lookup(t); resp.GetMailboxAddress() != "localhost:1001" ||
+		len(resp.GetReplicaAddresses()) != 1 || resp.GetReplicaAddresses()[0] != "localhost:2002" {
+		t.Errorf("Expected primary 'localhost:1001' with replica 'localhost:2002', got %v", resp)
+	}
+
+	t.Run("PromoteReplica", func(t *testing.T) {
+		register(t, "localhost:2002", false)
+		if resp := lookup(t); resp.GetMailboxAddress() != "localhost:2002" || len(resp.GetReplicaAddresses()) != 0 {
+			t.Errorf("Expected the promoted replica to be the only address, got %v", resp)
+		}
+	})
+
+	registerAll := func(t *testing.T, replica bool, addrs ...string) *proto.RegisterMailboxResponse {
+		t.Helper()
+		resp, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddresses: addrs, Replica: replica})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("RegisterMailbox failed: %v (err %v)", resp, err)
+		}
+		return resp
+	}
+	t.Run("ReplaceWithList", func(t *testing.T) {
+		registerAll(t, false, "localhost:3003", "localhost:4004", "localhost:3003")
+		if resp := lookup(t); resp.GetMailboxAddress() != "localhost:3003" || fmt.Sprint(resp.GetReplicaAddresses()) != "[localhost:4004]" {
+			t.Errorf("Expected the list to replace all addresses, got %v", resp)
+		}
+		if resp := registerAll(t, false, "localhost:3003", "localhost:4004"); !resp.GetUnchanged() {
+			t.Errorf("Expected registering the same list again to be unchanged, got %v", resp)
+		}
+	})
+	t.Run("AppendList", func(t *testing.T) {
+		registerAll(t, true, "localhost:4004", "localhost:5005", "localhost:6006")
+		if resp := lookup(t); resp.GetMailboxAddress() != "localhost:3003" || fmt.Sprint(resp.GetReplicaAddresses()) != "[localhost:4004 localhost:5005 localhost:6006]" {
+			t.Errorf("Expected the new addresses to be appended as replicas, got %v", resp)
+		}
+	})
+	t.Run("EmptyListEntry", func(t *testing.T) {
+		_, err := client.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddresses: []string{"localhost:3003", ""}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an empty address, got %v", err)
+		}
+	})
+}
+
+// TestServeNameserver tests that a Nameserver started on port 0 is reachable at the address of its handle until it
+// is stopped.
+func TestServeNameserver(t *testing.T) {
+	handle, err := ServeNameserver(common.Config{NameserverAddr: "localhost:0", NameserverManagedDomains: []string{"earth.com"}})
+	if err != nil {
+		t.Fatalf("ServeNameserver failed: %v", err)
+	}
+	defer handle.Stop()
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, handle.Addr(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Could not connect to the Nameserver: %v", err)
+	}
+	defer conn.Close()
+	if resp, err := proto.NewNameserverClient(conn).Health(dialCtx, &proto.NameserverHealthRequest{}); err != nil || !resp.GetServing() {
+		t.Fatalf("Expected the Nameserver to be serving, got %v, %v", resp, err)
+	}
+
+	handle.Stop()
+	handle.Stop() // Stopping again is harmless
+	if _, err := proto.NewNameserverClient(conn).Health(context.Background(), &proto.NameserverHealthRequest{}); err == nil {
+		t.Error("Expected the stopped Nameserver to refuse requests")
+	}
+}
+
+// TestStartNameserverWithContext_Ready tests that the ready callback reports the listening address of a
+// Nameserver started on port 0, and that cancelling the context stops it without a signal.
+func TestStartNameserverWithContext_Ready(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		StartNameserverWithContext(ctx, common.Config{NameserverAddr: "localhost:0"}, func(addr string) { ready <- addr })
+	}()
+
+	var addr string
+	select {
+	case addr = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the Nameserver to report readiness")
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Could not connect to the Nameserver: %v", err)
+	}
+	defer conn.Close()
+	// No retry is needed: the Nameserver is serving once ready has been called
+	if _, err := proto.NewNameserverClient(conn).Health(ctx, &proto.NameserverHealthRequest{}); err != nil {
+		t.Fatalf("Expected the ready Nameserver to answer, got %v", err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the Nameserver to stop after the context was cancelled")
+	}
+	if _, err := proto.NewNameserverClient(conn).Health(context.Background(), &proto.NameserverHealthRequest{}); err == nil {
+		t.Error("Expected the stopped Nameserver to refuse requests")
+	}
+}
@@ -0,0 +1,26 @@
+package nameserver
+
+import (
+	"strings"
+)
+
+// SetReferrals sets the Nameservers responsible for domains this one does not manage, keyed by domain.
+// LookupMailbox refers callers asking for an unknown address under one of these domains to that Nameserver.
+func (s *server) SetReferrals(referrals map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.referrals = make(map[string]string, len(referrals))
+	for domain, addr := range referrals {
+		s.referrals[strings.ToLower(domain)] = addr
+	}
+}
+
+// referralLocked returns the address of the Nameserver to refer a lookup of emailAddress to, or an empty
+// string if its domain is managed here or no referral is configured for it. s.mu must be held.
+func (s *server) referralLocked(emailAddress string) string {
+	domain, ok := emailDomain(emailAddress)
+	if !ok || s.responsibleDomains[domain] {
+		return ""
+	}
+	return s.referrals[domain]
+}
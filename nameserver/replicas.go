@@ -0,0 +1,111 @@
+package nameserver
+
+import (
+	"GoDissys/proto/proto"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// registrationAddrs returns the mailbox addresses of a registration in failover order: mailbox_address,
+// if set, followed by mailbox_addresses. Repeated addresses are kept once; empty list entries are an error.
+func registrationAddrs(req *proto.RegisterMailboxRequest) ([]string, error) {
+	var addrs []string
+	if req.GetMailboxAddress() != "" {
+		addrs = append(addrs, req.GetMailboxAddress())
+	}
+	for _, addr := range req.GetMailboxAddresses() {
+		if addr == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "mailbox addresses cannot be empty")
+		}
+		if !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// registerReplicaLocked adds mailboxAddr as a further replica serving emailAddress. The first
+// registration of an email address becomes its primary address. Registering an address that already
+// serves emailAddress is reported as unchanged. s.mu must be held.
+func (s *server) registerReplicaLocked(emailAddress, mailboxAddr string) *proto.RegisterMailboxResponse {
+	primary, exists := s.mailboxes[emailAddress]
+	if !exists {
+		s.logger.Info("Registering email", "email", emailAddress, "addr", mailboxAddr)
+		s.mailboxes[emailAddress] = mailboxAddr
+		return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox registered successfully"}
+	}
+	if primary == mailboxAddr {
+		return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox already registered", Unchanged: true}
+	}
+	for _, replica := range s.replicas[emailAddress] {
+		if replica == mailboxAddr {
+			return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox already registered", Unchanged: true}
+		}
+	}
+	s.replicas[emailAddress] = append(s.replicas[emailAddress], mailboxAddr)
+	s.logger.Info("Added replica", "email", emailAddress, "addr", mailboxAddr, "replicas", len(s.replicas[emailAddress]))
+	return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox replica registered successfully"}
+}
+
+// promoteLocked records mailboxAddr as the primary address of emailAddress, removing it from the
+// replicas so no address is listed twice. s.mu must be held.
+func (s *server) promoteLocked(emailAddress, mailboxAddr string) {
+	s.mailboxes[emailAddress] = mailboxAddr
+	replicas := s.replicas[emailAddress]
+	for i, replica := range replicas {
+		if replica == mailboxAddr {
+			replicas = append(replicas[:i:i], replicas[i+1:]...)
+			break
+		}
+	}
+	if len(replicas) == 0 {
+		delete(s.replicas, emailAddress)
+	} else {
+		s.replicas[emailAddress] = replicas
+	}
+}
+
+// registerReplicasLocked adds each of mailboxAddrs as registerReplicaLocked does. The registration is reported
+// as unchanged only if every address already served emailAddress. s.mu must be held.
+func (s *server) registerReplicasLocked(emailAddress string, mailboxAddrs []string) *proto.RegisterMailboxResponse {
+	var resp *proto.RegisterMailboxResponse
+	unchanged := true
+	for _, addr := range mailboxAddrs {
+		resp = s.registerReplicaLocked(emailAddress, addr)
+		unchanged = unchanged && resp.GetUnchanged()
+	}
+	if len(mailboxAddrs) > 1 && !unchanged {
+		return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox replicas registered successfully"}
+	}
+	return resp
+}
+
+// replaceAddressesLocked makes mailboxAddrs the complete set of addresses serving emailAddress: the first
+// becomes the primary address, the others its replicas in the given order. s.mu must be held.
+func (s *server) replaceAddressesLocked(emailAddress string, mailboxAddrs []string) *proto.RegisterMailboxResponse {
+	if s.mailboxes[emailAddress] == mailboxAddrs[0] && slices.Equal(s.replicas[emailAddress], mailboxAddrs[1:]) {
+		return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox already registered", Unchanged: true}
+	}
+	s.mailboxes[emailAddress] = mailboxAddrs[0]
+	if len(mailboxAddrs) > 1 {
+		s.replicas[emailAddress] = append([]string(nil), mailboxAddrs[1:]...)
+	} else {
+		delete(s.replicas, emailAddress)
+	}
+	s.logger.Info("Registering email", "email", emailAddress, "addrs", mailboxAddrs)
+	return &proto.RegisterMailboxResponse{Success: true, Message: "Mailbox registered successfully"}
+}
+
+// persistRegistrationLocked persists the registrations if resp reports a change, and returns resp. If saving
+// fails, the registrations are restored to before (see persistLocked) and the error is returned instead.
+// s.mu must be held.
+func (s *server) persistRegistrationLocked(before *registry, resp *proto.RegisterMailboxResponse) (*proto.RegisterMailboxResponse, error) {
+	if resp.GetSuccess() && !resp.GetUnchanged() {
+		if err := s.persistLocked(before); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
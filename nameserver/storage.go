@@ -0,0 +1,126 @@
+package nameserver
+
+import (
+	"GoDissys/common"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// registry is the on-disk form of the Nameserver's registrations. The managed domains are not part of it,
+// they come from the configuration on every start.
+type registry struct {
+	Mailboxes map[string]string   `json:"Mailboxes"`
+	Replicas  map[string][]string `json:"Replicas,omitempty"`
+	Lists     map[string][]string `json:"Lists,omitempty"`
+}
+
+// stateFile is the name of the file the Nameserver persists its registrations to inside its state directory.
+const stateFile = "nameserver.json"
+
+// loadRegistry reads persisted registrations from path. A missing file yields an empty registry. Addresses
+// are normalized with common.NormalizeEmail, so state written with differently-cased keys still resolves.
+func loadRegistry(path string) (*registry, error) {
+	reg := &registry{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data = nil // First run, nothing persisted yet
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read nameserver state '%s': %w", path, err)
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, reg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal nameserver state from '%s': %w", path, err)
+		}
+	}
+	return normalizeRegistry(reg), nil
+}
+
+// normalizeRegistry returns reg with every email address normalized. Of several keys that normalize to the
+// same address, the one already in normalized form wins.
+func normalizeRegistry(reg *registry) *registry {
+	normalized := &registry{Mailboxes: make(map[string]string), Replicas: make(map[string][]string), Lists: make(map[string][]string)}
+	for emailAddress, addr := range reg.Mailboxes {
+		key := common.NormalizeEmail(emailAddress)
+		if _, exists := normalized.Mailboxes[key]; !exists || emailAddress == key {
+			normalized.Mailboxes[key] = addr
+			if replicas, ok := reg.Replicas[emailAddress]; ok {
+				normalized.Replicas[key] = replicas
+			} else {
+				delete(normalized.Replicas, key)
+			}
+		}
+	}
+	for listAddress, members := range reg.Lists {
+		key := common.NormalizeEmail(listAddress)
+		if _, exists := normalized.Lists[key]; exists && listAddress != key {
+			continue
+		}
+		normalizedMembers := make([]string, 0, len(members))
+		for _, member := range members {
+			normalizedMembers = append(normalizedMembers, common.NormalizeEmail(member))
+		}
+		normalized.Lists[key] = normalizedMembers
+	}
+	return normalized
+}
+
+// saveRegistry writes reg to path, replacing the previous file atomically.
+func saveRegistry(path string, reg *registry) error {
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal nameserver state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory for '%s': %w", path, err)
+	}
+	if err := common.WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write nameserver state '%s': %w", path, err)
+	}
+	return nil
+}
+
+// saveLocked writes the registrations to s.statePath, if persistence is enabled. s.mu must be held.
+func (s *server) saveLocked() error {
+	if s.statePath == "" {
+		return nil
+	}
+	return saveRegistry(s.statePath, &registry{Mailboxes: s.mailboxes, Replicas: s.replicas, Lists: s.lists})
+}
+
+// snapshotLocked returns a copy of the registrations, for persistLocked to restore if saving a change fails.
+// It returns nil if persistence is disabled. s.mu must be held.
+func (s *server) snapshotLocked() *registry {
+	if s.statePath == "" {
+		return nil
+	}
+	return &registry{Mailboxes: maps.Clone(s.mailboxes), Replicas: maps.Clone(s.replicas), Lists: maps.Clone(s.lists)}
+}
+
+// persistLocked saves the registrations after a change. If that fails, the registrations are restored to
+// before, the snapshot taken by snapshotLocked, and an Internal error is returned, so no change is reported
+// that a restart would lose. The failure is also reported by Health until a save succeeds. s.mu must be held.
+func (s *server) persistLocked(before *registry) error {
+	s.persistErr = s.saveLocked()
+	if s.persistErr == nil {
+		return nil
+	}
+	s.logger.Error("Failed to persist registrations, change undone", "error", s.persistErr)
+	if before != nil {
+		s.mailboxes, s.replicas, s.lists = before.Mailboxes, before.Replicas, before.Lists
+	}
+	return status.Errorf(codes.Internal, "failed to persist registrations")
+}
+
+// Close saves the registrations a final time. The Nameserver must not be used afterwards.
+func (s *server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
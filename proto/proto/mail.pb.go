@@ -21,16 +21,141 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ConsistencyIssueKind int32
+
+const (
+	ConsistencyIssueKind_CONSISTENCY_ISSUE_UNSPECIFIED             ConsistencyIssueKind = 0
+	ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_EMAIL           ConsistencyIssueKind = 1 // Registered email address does not parse
+	ConsistencyIssueKind_CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS ConsistencyIssueKind = 2 // Mailbox address is not a valid host:port
+	ConsistencyIssueKind_CONSISTENCY_ISSUE_UNMANAGED_DOMAIN        ConsistencyIssueKind = 3 // Email domain is not managed by this Nameserver
+	ConsistencyIssueKind_CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX     ConsistencyIssueKind = 4 // Mailbox address could not be reached
+)
+
+// Enum value maps for ConsistencyIssueKind.
+var (
+	ConsistencyIssueKind_name = map[int32]string{
+		0: "CONSISTENCY_ISSUE_UNSPECIFIED",
+		1: "CONSISTENCY_ISSUE_INVALID_EMAIL",
+		2: "CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS",
+		3: "CONSISTENCY_ISSUE_UNMANAGED_DOMAIN",
+		4: "CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX",
+	}
+	ConsistencyIssueKind_value = map[string]int32{
+		"CONSISTENCY_ISSUE_UNSPECIFIED":             0,
+		"CONSISTENCY_ISSUE_INVALID_EMAIL":           1,
+		"CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS": 2,
+		"CONSISTENCY_ISSUE_UNMANAGED_DOMAIN":        3,
+		"CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX":     4,
+	}
+)
+
+func (x ConsistencyIssueKind) Enum() *ConsistencyIssueKind {
+	p := new(ConsistencyIssueKind)
+	*p = x
+	return p
+}
+
+func (x ConsistencyIssueKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ConsistencyIssueKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_mail_proto_enumTypes[0].Descriptor()
+}
+
+func (ConsistencyIssueKind) Type() protoreflect.EnumType {
+	return &file_proto_mail_proto_enumTypes[0]
+}
+
+func (x ConsistencyIssueKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ConsistencyIssueKind.Descriptor instead.
+func (ConsistencyIssueKind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{0}
+}
+
+type DeliveryStatus int32
+
+const (
+	DeliveryStatus_DELIVERY_STATUS_UNKNOWN   DeliveryStatus = 0 // No send with this ID is known
+	DeliveryStatus_DELIVERY_STATUS_PENDING   DeliveryStatus = 1 // Copies are still queued for delivery
+	DeliveryStatus_DELIVERY_STATUS_DELIVERED DeliveryStatus = 2 // Delivered to every recipient
+	DeliveryStatus_DELIVERY_STATUS_FAILED    DeliveryStatus = 3 // Delivery failed for at least one recipient
+)
+
+// Enum value maps for DeliveryStatus.
+var (
+	DeliveryStatus_name = map[int32]string{
+		0: "DELIVERY_STATUS_UNKNOWN",
+		1: "DELIVERY_STATUS_PENDING",
+		2: "DELIVERY_STATUS_DELIVERED",
+		3: "DELIVERY_STATUS_FAILED",
+	}
+	DeliveryStatus_value = map[string]int32{
+		"DELIVERY_STATUS_UNKNOWN":   0,
+		"DELIVERY_STATUS_PENDING":   1,
+		"DELIVERY_STATUS_DELIVERED": 2,
+		"DELIVERY_STATUS_FAILED":    3,
+	}
+)
+
+func (x DeliveryStatus) Enum() *DeliveryStatus {
+	p := new(DeliveryStatus)
+	*p = x
+	return p
+}
+
+func (x DeliveryStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeliveryStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_mail_proto_enumTypes[1].Descriptor()
+}
+
+func (DeliveryStatus) Type() protoreflect.EnumType {
+	return &file_proto_mail_proto_enumTypes[1]
+}
+
+func (x DeliveryStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeliveryStatus.Descriptor instead.
+func (DeliveryStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{1}
+}
+
 // MailMessage represents a simplified email message.
 type MailMessage struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	SenderEmail    string                 `protobuf:"bytes,1,opt,name=sender_email,json=senderEmail,proto3" json:"sender_email,omitempty"`
-	RecipientEmail string                 `protobuf:"bytes,2,opt,name=recipient_email,json=recipientEmail,proto3" json:"recipient_email,omitempty"`
-	Subject        string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
-	Body           string                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
-	Timestamp      int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix timestamp
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	SenderEmail         string                 `protobuf:"bytes,1,opt,name=sender_email,json=senderEmail,proto3" json:"sender_email,omitempty"`
+	RecipientEmail      string                 `protobuf:"bytes,2,opt,name=recipient_email,json=recipientEmail,proto3" json:"recipient_email,omitempty"`
+	Subject             string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Body                string                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	Timestamp           int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                 // Unix timestamp
+	MessageId           string                 `protobuf:"bytes,6,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // Unique ID, assigned by the TransferServer if empty
+	To                  []string               `protobuf:"bytes,7,rep,name=to,proto3" json:"to,omitempty"`                                // Additional primary recipients
+	Cc                  []string               `protobuf:"bytes,8,rep,name=cc,proto3" json:"cc,omitempty"`                                // Carbon-copy recipients
+	Bcc                 []string               `protobuf:"bytes,9,rep,name=bcc,proto3" json:"bcc,omitempty"`                              // Blind carbon-copy recipients, never included in delivered copies
+	Labels              []string               `protobuf:"bytes,10,rep,name=labels,proto3" json:"labels,omitempty"`                       // Sender-assigned tags such as "newsletter" or "transactional"
+	Attachments         []*Attachment          `protobuf:"bytes,11,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	RequestReadReceipt  bool                   `protobuf:"varint,12,opt,name=request_read_receipt,json=requestReadReceipt,proto3" json:"request_read_receipt,omitempty"`     // Ask the recipient's Mailbox to notify the sender when the message is retrieved
+	ReceiptForMessageId string                 `protobuf:"bytes,13,opt,name=receipt_for_message_id,json=receiptForMessageId,proto3" json:"receipt_for_message_id,omitempty"` // Set on read receipts: ID of the message that was read
+	BounceForMessageId  string                 `protobuf:"bytes,14,opt,name=bounce_for_message_id,json=bounceForMessageId,proto3" json:"bounce_for_message_id,omitempty"`    // Set on bounces: ID of the message that could not be delivered
+	ReceivedTimestamp   int64                  `protobuf:"varint,15,opt,name=received_timestamp,json=receivedTimestamp,proto3" json:"received_timestamp,omitempty"`          // Unix timestamp when the recipient's Mailbox stored the message
+	EncryptedBody       []byte                 `protobuf:"bytes,16,opt,name=encrypted_body,json=encryptedBody,proto3" json:"encrypted_body,omitempty"`                       // Only in persisted Mailbox state: AES-GCM nonce and ciphertext replacing body
+	Read                bool                   `protobuf:"varint,17,opt,name=read,proto3" json:"read,omitempty"`                                                             // Set by the recipient's Mailbox once the message is marked read with MarkRead
+	// RFC 3339 time with fractional seconds and time zone at which the TransferServer accepted the message.
+	// More precise than timestamp, which is kept for older clients; empty for mail that bypassed a TransferServer.
+	SentAt string `protobuf:"bytes,18,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	// Set by the recipient's Mailbox when it stores the message and increasing with every stored message, also
+	// across restarts, so WaitForMail can resume after it even once the message has left the inbox.
+	Sequence      int64 `protobuf:"varint,19,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *MailMessage) Reset() {
@@ -98,17 +223,179 @@ func (x *MailMessage) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *MailMessage) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *MailMessage) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *MailMessage) GetCc() []string {
+	if x != nil {
+		return x.Cc
+	}
+	return nil
+}
+
+func (x *MailMessage) GetBcc() []string {
+	if x != nil {
+		return x.Bcc
+	}
+	return nil
+}
+
+func (x *MailMessage) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *MailMessage) GetAttachments() []*Attachment {
+	if x != nil {
+		return x.Attachments
+	}
+	return nil
+}
+
+func (x *MailMessage) GetRequestReadReceipt() bool {
+	if x != nil {
+		return x.RequestReadReceipt
+	}
+	return false
+}
+
+func (x *MailMessage) GetReceiptForMessageId() string {
+	if x != nil {
+		return x.ReceiptForMessageId
+	}
+	return ""
+}
+
+func (x *MailMessage) GetBounceForMessageId() string {
+	if x != nil {
+		return x.BounceForMessageId
+	}
+	return ""
+}
+
+func (x *MailMessage) GetReceivedTimestamp() int64 {
+	if x != nil {
+		return x.ReceivedTimestamp
+	}
+	return 0
+}
+
+func (x *MailMessage) GetEncryptedBody() []byte {
+	if x != nil {
+		return x.EncryptedBody
+	}
+	return nil
+}
+
+func (x *MailMessage) GetRead() bool {
+	if x != nil {
+		return x.Read
+	}
+	return false
+}
+
+func (x *MailMessage) GetSentAt() string {
+	if x != nil {
+		return x.SentAt
+	}
+	return ""
+}
+
+func (x *MailMessage) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+type Attachment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attachment) Reset() {
+	*x = Attachment{}
+	mi := &file_proto_mail_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attachment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attachment) ProtoMessage() {}
+
+func (x *Attachment) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attachment.ProtoReflect.Descriptor instead.
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Attachment) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Attachment) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Attachment) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 type RegisterMailboxRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	EmailAddress   string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
 	MailboxAddress string                 `protobuf:"bytes,2,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	Replica        bool                   `protobuf:"varint,3,opt,name=replica,proto3" json:"replica,omitempty"` // Add mailbox_address as a further replica instead of replacing the primary address
+	// Further addresses in failover order, following mailbox_address if that is set. Without replica, a non-empty
+	// list replaces all addresses of the email address: the first becomes the primary, the others its replicas.
+	MailboxAddresses []string `protobuf:"bytes,4,rep,name=mailbox_addresses,json=mailboxAddresses,proto3" json:"mailbox_addresses,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *RegisterMailboxRequest) Reset() {
 	*x = RegisterMailboxRequest{}
-	mi := &file_proto_mail_proto_msgTypes[1]
+	mi := &file_proto_mail_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -119,8 +406,3327 @@ func (x *RegisterMailboxRequest) String() string {
 
 func (*RegisterMailboxRequest) ProtoMessage() {}
 
-func (x *RegisterMailboxRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[1]
+func (x *RegisterMailboxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterMailboxRequest.ProtoReflect.Descriptor instead.
+func (*RegisterMailboxRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RegisterMailboxRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *RegisterMailboxRequest) GetMailboxAddress() string {
+	if x != nil {
+		return x.MailboxAddress
+	}
+	return ""
+}
+
+func (x *RegisterMailboxRequest) GetReplica() bool {
+	if x != nil {
+		return x.Replica
+	}
+	return false
+}
+
+func (x *RegisterMailboxRequest) GetMailboxAddresses() []string {
+	if x != nil {
+		return x.MailboxAddresses
+	}
+	return nil
+}
+
+type RegisterMailboxResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Unchanged     bool                   `protobuf:"varint,3,opt,name=unchanged,proto3" json:"unchanged,omitempty"` // Set if the email address was already registered with the same mailbox address
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterMailboxResponse) Reset() {
+	*x = RegisterMailboxResponse{}
+	mi := &file_proto_mail_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterMailboxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterMailboxResponse) ProtoMessage() {}
+
+func (x *RegisterMailboxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterMailboxResponse.ProtoReflect.Descriptor instead.
+func (*RegisterMailboxResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RegisterMailboxResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterMailboxResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RegisterMailboxResponse) GetUnchanged() bool {
+	if x != nil {
+		return x.Unchanged
+	}
+	return false
+}
+
+type LookupMailboxRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupMailboxRequest) Reset() {
+	*x = LookupMailboxRequest{}
+	mi := &file_proto_mail_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupMailboxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupMailboxRequest) ProtoMessage() {}
+
+func (x *LookupMailboxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupMailboxRequest.ProtoReflect.Descriptor instead.
+func (*LookupMailboxRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LookupMailboxRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+type LookupMailboxResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	MailboxAddress   string                 `protobuf:"bytes,1,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"`
+	Found            bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	ReplicaAddresses []string               `protobuf:"bytes,3,rep,name=replica_addresses,json=replicaAddresses,proto3" json:"replica_addresses,omitempty"` // Further Mailboxes serving the email address besides mailbox_address
+	ReferralAddress  string                 `protobuf:"bytes,4,opt,name=referral_address,json=referralAddress,proto3" json:"referral_address,omitempty"`    // For an unknown address of an unmanaged domain: the Nameserver responsible for it, if known
+	// Set if the address is unknown because its domain is neither managed by this Nameserver nor referred to
+	// another one, as opposed to an unknown user of a managed domain.
+	DomainNotManaged bool `protobuf:"varint,5,opt,name=domain_not_managed,json=domainNotManaged,proto3" json:"domain_not_managed,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LookupMailboxResponse) Reset() {
+	*x = LookupMailboxResponse{}
+	mi := &file_proto_mail_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupMailboxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupMailboxResponse) ProtoMessage() {}
+
+func (x *LookupMailboxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupMailboxResponse.ProtoReflect.Descriptor instead.
+func (*LookupMailboxResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LookupMailboxResponse) GetMailboxAddress() string {
+	if x != nil {
+		return x.MailboxAddress
+	}
+	return ""
+}
+
+func (x *LookupMailboxResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *LookupMailboxResponse) GetReplicaAddresses() []string {
+	if x != nil {
+		return x.ReplicaAddresses
+	}
+	return nil
+}
+
+func (x *LookupMailboxResponse) GetReferralAddress() string {
+	if x != nil {
+		return x.ReferralAddress
+	}
+	return ""
+}
+
+func (x *LookupMailboxResponse) GetDomainNotManaged() bool {
+	if x != nil {
+		return x.DomainNotManaged
+	}
+	return false
+}
+
+type DeregisterMailboxRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterMailboxRequest) Reset() {
+	*x = DeregisterMailboxRequest{}
+	mi := &file_proto_mail_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterMailboxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterMailboxRequest) ProtoMessage() {}
+
+func (x *DeregisterMailboxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterMailboxRequest.ProtoReflect.Descriptor instead.
+func (*DeregisterMailboxRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeregisterMailboxRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+type DeregisterMailboxResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       bool                   `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"` // False if the email address was not registered or its domain is not managed here
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterMailboxResponse) Reset() {
+	*x = DeregisterMailboxResponse{}
+	mi := &file_proto_mail_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterMailboxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterMailboxResponse) ProtoMessage() {}
+
+func (x *DeregisterMailboxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterMailboxResponse.ProtoReflect.Descriptor instead.
+func (*DeregisterMailboxResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeregisterMailboxResponse) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *DeregisterMailboxResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ListMailboxesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMailboxesRequest) Reset() {
+	*x = ListMailboxesRequest{}
+	mi := &file_proto_mail_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMailboxesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMailboxesRequest) ProtoMessage() {}
+
+func (x *ListMailboxesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMailboxesRequest.ProtoReflect.Descriptor instead.
+func (*ListMailboxesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListMailboxesRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type MailboxEntry struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress     string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	MailboxAddress   string                 `protobuf:"bytes,2,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"`
+	ReplicaAddresses []string               `protobuf:"bytes,3,rep,name=replica_addresses,json=replicaAddresses,proto3" json:"replica_addresses,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *MailboxEntry) Reset() {
+	*x = MailboxEntry{}
+	mi := &file_proto_mail_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailboxEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailboxEntry) ProtoMessage() {}
+
+func (x *MailboxEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailboxEntry.ProtoReflect.Descriptor instead.
+func (*MailboxEntry) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MailboxEntry) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *MailboxEntry) GetMailboxAddress() string {
+	if x != nil {
+		return x.MailboxAddress
+	}
+	return ""
+}
+
+func (x *MailboxEntry) GetReplicaAddresses() []string {
+	if x != nil {
+		return x.ReplicaAddresses
+	}
+	return nil
+}
+
+type ListMailboxesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*MailboxEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMailboxesResponse) Reset() {
+	*x = ListMailboxesResponse{}
+	mi := &file_proto_mail_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMailboxesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMailboxesResponse) ProtoMessage() {}
+
+func (x *ListMailboxesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMailboxesResponse.ProtoReflect.Descriptor instead.
+func (*ListMailboxesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListMailboxesResponse) GetEntries() []*MailboxEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type CompareAndSwapMailboxRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress       string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	ExpectedOldAddress string                 `protobuf:"bytes,2,opt,name=expected_old_address,json=expectedOldAddress,proto3" json:"expected_old_address,omitempty"` // Empty means the email address must not be registered yet
+	NewAddress         string                 `protobuf:"bytes,3,opt,name=new_address,json=newAddress,proto3" json:"new_address,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CompareAndSwapMailboxRequest) Reset() {
+	*x = CompareAndSwapMailboxRequest{}
+	mi := &file_proto_mail_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndSwapMailboxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndSwapMailboxRequest) ProtoMessage() {}
+
+func (x *CompareAndSwapMailboxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndSwapMailboxRequest.ProtoReflect.Descriptor instead.
+func (*CompareAndSwapMailboxRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CompareAndSwapMailboxRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *CompareAndSwapMailboxRequest) GetExpectedOldAddress() string {
+	if x != nil {
+		return x.ExpectedOldAddress
+	}
+	return ""
+}
+
+func (x *CompareAndSwapMailboxRequest) GetNewAddress() string {
+	if x != nil {
+		return x.NewAddress
+	}
+	return ""
+}
+
+type CompareAndSwapMailboxResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MailboxAddress string                 `protobuf:"bytes,1,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"` // The address now registered
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CompareAndSwapMailboxResponse) Reset() {
+	*x = CompareAndSwapMailboxResponse{}
+	mi := &file_proto_mail_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndSwapMailboxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndSwapMailboxResponse) ProtoMessage() {}
+
+func (x *CompareAndSwapMailboxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndSwapMailboxResponse.ProtoReflect.Descriptor instead.
+func (*CompareAndSwapMailboxResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CompareAndSwapMailboxResponse) GetMailboxAddress() string {
+	if x != nil {
+		return x.MailboxAddress
+	}
+	return ""
+}
+
+type CheckConsistencyRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	VerifyReachability bool                   `protobuf:"varint,1,opt,name=verify_reachability,json=verifyReachability,proto3" json:"verify_reachability,omitempty"` // Also dial every registered mailbox address
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CheckConsistencyRequest) Reset() {
+	*x = CheckConsistencyRequest{}
+	mi := &file_proto_mail_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckConsistencyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckConsistencyRequest) ProtoMessage() {}
+
+func (x *CheckConsistencyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckConsistencyRequest.ProtoReflect.Descriptor instead.
+func (*CheckConsistencyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CheckConsistencyRequest) GetVerifyReachability() bool {
+	if x != nil {
+		return x.VerifyReachability
+	}
+	return false
+}
+
+type ConsistencyIssue struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress   string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	MailboxAddress string                 `protobuf:"bytes,2,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"`
+	Kind           ConsistencyIssueKind   `protobuf:"varint,3,opt,name=kind,proto3,enum=mail.ConsistencyIssueKind" json:"kind,omitempty"`
+	Detail         string                 `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ConsistencyIssue) Reset() {
+	*x = ConsistencyIssue{}
+	mi := &file_proto_mail_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsistencyIssue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsistencyIssue) ProtoMessage() {}
+
+func (x *ConsistencyIssue) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsistencyIssue.ProtoReflect.Descriptor instead.
+func (*ConsistencyIssue) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ConsistencyIssue) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *ConsistencyIssue) GetMailboxAddress() string {
+	if x != nil {
+		return x.MailboxAddress
+	}
+	return ""
+}
+
+func (x *ConsistencyIssue) GetKind() ConsistencyIssueKind {
+	if x != nil {
+		return x.Kind
+	}
+	return ConsistencyIssueKind_CONSISTENCY_ISSUE_UNSPECIFIED
+}
+
+func (x *ConsistencyIssue) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type CheckConsistencyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Checked       int32                  `protobuf:"varint,1,opt,name=checked,proto3" json:"checked,omitempty"` // Number of registrations scanned
+	Issues        []*ConsistencyIssue    `protobuf:"bytes,2,rep,name=issues,proto3" json:"issues,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckConsistencyResponse) Reset() {
+	*x = CheckConsistencyResponse{}
+	mi := &file_proto_mail_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckConsistencyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckConsistencyResponse) ProtoMessage() {}
+
+func (x *CheckConsistencyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckConsistencyResponse.ProtoReflect.Descriptor instead.
+func (*CheckConsistencyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CheckConsistencyResponse) GetChecked() int32 {
+	if x != nil {
+		return x.Checked
+	}
+	return 0
+}
+
+func (x *CheckConsistencyResponse) GetIssues() []*ConsistencyIssue {
+	if x != nil {
+		return x.Issues
+	}
+	return nil
+}
+
+type RegisterListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ListAddress   string                 `protobuf:"bytes,1,opt,name=list_address,json=listAddress,proto3" json:"list_address,omitempty"`
+	MemberEmails  []string               `protobuf:"bytes,2,rep,name=member_emails,json=memberEmails,proto3" json:"member_emails,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterListRequest) Reset() {
+	*x = RegisterListRequest{}
+	mi := &file_proto_mail_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterListRequest) ProtoMessage() {}
+
+func (x *RegisterListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterListRequest.ProtoReflect.Descriptor instead.
+func (*RegisterListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RegisterListRequest) GetListAddress() string {
+	if x != nil {
+		return x.ListAddress
+	}
+	return ""
+}
+
+func (x *RegisterListRequest) GetMemberEmails() []string {
+	if x != nil {
+		return x.MemberEmails
+	}
+	return nil
+}
+
+type RegisterListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterListResponse) Reset() {
+	*x = RegisterListResponse{}
+	mi := &file_proto_mail_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterListResponse) ProtoMessage() {}
+
+func (x *RegisterListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterListResponse.ProtoReflect.Descriptor instead.
+func (*RegisterListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RegisterListResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterListResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ExpandListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandListRequest) Reset() {
+	*x = ExpandListRequest{}
+	mi := &file_proto_mail_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandListRequest) ProtoMessage() {}
+
+func (x *ExpandListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandListRequest.ProtoReflect.Descriptor instead.
+func (*ExpandListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ExpandListRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+type ExpandListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsList        bool                   `protobuf:"varint,1,opt,name=is_list,json=isList,proto3" json:"is_list,omitempty"` // False if the address is not a registered list
+	Members       []string               `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`              // Member addresses that are not lists themselves, without duplicates
+	Truncated     bool                   `protobuf:"varint,3,opt,name=truncated,proto3" json:"truncated,omitempty"`         // Set if nested lists deeper than the server's limit were skipped
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandListResponse) Reset() {
+	*x = ExpandListResponse{}
+	mi := &file_proto_mail_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandListResponse) ProtoMessage() {}
+
+func (x *ExpandListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandListResponse.ProtoReflect.Descriptor instead.
+func (*ExpandListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ExpandListResponse) GetIsList() bool {
+	if x != nil {
+		return x.IsList
+	}
+	return false
+}
+
+func (x *ExpandListResponse) GetMembers() []string {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *ExpandListResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type ExpandListsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddresses []string               `protobuf:"bytes,1,rep,name=email_addresses,json=emailAddresses,proto3" json:"email_addresses,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ExpandListsRequest) Reset() {
+	*x = ExpandListsRequest{}
+	mi := &file_proto_mail_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandListsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandListsRequest) ProtoMessage() {}
+
+func (x *ExpandListsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandListsRequest.ProtoReflect.Descriptor instead.
+func (*ExpandListsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ExpandListsRequest) GetEmailAddresses() []string {
+	if x != nil {
+		return x.EmailAddresses
+	}
+	return nil
+}
+
+type ExpandListsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expansions    []*ExpandListResponse  `protobuf:"bytes,1,rep,name=expansions,proto3" json:"expansions,omitempty"` // One per requested address, in request order
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpandListsResponse) Reset() {
+	*x = ExpandListsResponse{}
+	mi := &file_proto_mail_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpandListsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpandListsResponse) ProtoMessage() {}
+
+func (x *ExpandListsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpandListsResponse.ProtoReflect.Descriptor instead.
+func (*ExpandListsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ExpandListsResponse) GetExpansions() []*ExpandListResponse {
+	if x != nil {
+		return x.Expansions
+	}
+	return nil
+}
+
+type AddManagedDomainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddManagedDomainRequest) Reset() {
+	*x = AddManagedDomainRequest{}
+	mi := &file_proto_mail_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddManagedDomainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddManagedDomainRequest) ProtoMessage() {}
+
+func (x *AddManagedDomainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddManagedDomainRequest.ProtoReflect.Descriptor instead.
+func (*AddManagedDomainRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *AddManagedDomainRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type RemoveManagedDomainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Purge         bool                   `protobuf:"varint,2,opt,name=purge,proto3" json:"purge,omitempty"` // Also delete the domain's registrations, replicas and distribution lists
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveManagedDomainRequest) Reset() {
+	*x = RemoveManagedDomainRequest{}
+	mi := &file_proto_mail_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveManagedDomainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveManagedDomainRequest) ProtoMessage() {}
+
+func (x *RemoveManagedDomainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveManagedDomainRequest.ProtoReflect.Descriptor instead.
+func (*RemoveManagedDomainRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RemoveManagedDomainRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *RemoveManagedDomainRequest) GetPurge() bool {
+	if x != nil {
+		return x.Purge
+	}
+	return false
+}
+
+type ManagedDomainResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Changed        bool                   `protobuf:"varint,1,opt,name=changed,proto3" json:"changed,omitempty"`                                    // False if the domain already was (or was not) managed
+	Purged         int32                  `protobuf:"varint,2,opt,name=purged,proto3" json:"purged,omitempty"`                                      // Registrations and lists deleted by a purge
+	ManagedDomains []string               `protobuf:"bytes,3,rep,name=managed_domains,json=managedDomains,proto3" json:"managed_domains,omitempty"` // Managed domains after the change, sorted
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ManagedDomainResponse) Reset() {
+	*x = ManagedDomainResponse{}
+	mi := &file_proto_mail_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManagedDomainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManagedDomainResponse) ProtoMessage() {}
+
+func (x *ManagedDomainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManagedDomainResponse.ProtoReflect.Descriptor instead.
+func (*ManagedDomainResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ManagedDomainResponse) GetChanged() bool {
+	if x != nil {
+		return x.Changed
+	}
+	return false
+}
+
+func (x *ManagedDomainResponse) GetPurged() int32 {
+	if x != nil {
+		return x.Purged
+	}
+	return 0
+}
+
+func (x *ManagedDomainResponse) GetManagedDomains() []string {
+	if x != nil {
+		return x.ManagedDomains
+	}
+	return nil
+}
+
+type NameserverInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameserverInfoRequest) Reset() {
+	*x = NameserverInfoRequest{}
+	mi := &file_proto_mail_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameserverInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameserverInfoRequest) ProtoMessage() {}
+
+func (x *NameserverInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameserverInfoRequest.ProtoReflect.Descriptor instead.
+func (*NameserverInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{25}
+}
+
+type NameserverInfoResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Registrations  int32                  `protobuf:"varint,1,opt,name=registrations,proto3" json:"registrations,omitempty"`
+	ManagedDomains []string               `protobuf:"bytes,2,rep,name=managed_domains,json=managedDomains,proto3" json:"managed_domains,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NameserverInfoResponse) Reset() {
+	*x = NameserverInfoResponse{}
+	mi := &file_proto_mail_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameserverInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameserverInfoResponse) ProtoMessage() {}
+
+func (x *NameserverInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameserverInfoResponse.ProtoReflect.Descriptor instead.
+func (*NameserverInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *NameserverInfoResponse) GetRegistrations() int32 {
+	if x != nil {
+		return x.Registrations
+	}
+	return 0
+}
+
+func (x *NameserverInfoResponse) GetManagedDomains() []string {
+	if x != nil {
+		return x.ManagedDomains
+	}
+	return nil
+}
+
+type NameserverHealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameserverHealthRequest) Reset() {
+	*x = NameserverHealthRequest{}
+	mi := &file_proto_mail_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameserverHealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameserverHealthRequest) ProtoMessage() {}
+
+func (x *NameserverHealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameserverHealthRequest.ProtoReflect.Descriptor instead.
+func (*NameserverHealthRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{27}
+}
+
+type NameserverHealthResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Serving        bool                   `protobuf:"varint,1,opt,name=serving,proto3" json:"serving,omitempty"`                                    // False while registrations cannot be persisted
+	ManagedDomains []string               `protobuf:"bytes,2,rep,name=managed_domains,json=managedDomains,proto3" json:"managed_domains,omitempty"` // Sorted
+	Detail         string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`                                       // Why the Nameserver is not serving
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NameserverHealthResponse) Reset() {
+	*x = NameserverHealthResponse{}
+	mi := &file_proto_mail_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameserverHealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameserverHealthResponse) ProtoMessage() {}
+
+func (x *NameserverHealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameserverHealthResponse.ProtoReflect.Descriptor instead.
+func (*NameserverHealthResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *NameserverHealthResponse) GetServing() bool {
+	if x != nil {
+		return x.Serving
+	}
+	return false
+}
+
+func (x *NameserverHealthResponse) GetManagedDomains() []string {
+	if x != nil {
+		return x.ManagedDomains
+	}
+	return nil
+}
+
+func (x *NameserverHealthResponse) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type ReceiveMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *MailMessage           `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiveMailRequest) Reset() {
+	*x = ReceiveMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiveMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveMailRequest) ProtoMessage() {}
+
+func (x *ReceiveMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveMailRequest.ProtoReflect.Descriptor instead.
+func (*ReceiveMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ReceiveMailRequest) GetMessage() *MailMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type ReceiveMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	MailboxFull   bool                   `protobuf:"varint,3,opt,name=mailbox_full,json=mailboxFull,proto3" json:"mailbox_full,omitempty"` // With success false: the recipient's inbox is full, retrying will not help until it is emptied
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiveMailResponse) Reset() {
+	*x = ReceiveMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiveMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiveMailResponse) ProtoMessage() {}
+
+func (x *ReceiveMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiveMailResponse.ProtoReflect.Descriptor instead.
+func (*ReceiveMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ReceiveMailResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReceiveMailResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ReceiveMailResponse) GetMailboxFull() bool {
+	if x != nil {
+		return x.MailboxFull
+	}
+	return false
+}
+
+type GetMailRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	Labels       []string               `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty"` // If set, only messages carrying at least one of these labels are returned
+	// auto_ack true clears returned messages (legacy clear-on-read), false keeps them until DeleteMail
+	// acknowledges them. When unset, the mailbox's configured default applies.
+	AutoAck *bool `protobuf:"varint,3,opt,name=auto_ack,json=autoAck,proto3,oneof" json:"auto_ack,omitempty"`
+	// offset and limit select a page of the (label-filtered) inbox. Paged requests never clear the inbox,
+	// regardless of auto_ack, so later pages don't shift; acknowledge with DeleteMail instead. limit 0 returns all.
+	Offset     int32 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit      int32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	UnreadOnly bool  `protobuf:"varint,6,opt,name=unread_only,json=unreadOnly,proto3" json:"unread_only,omitempty"` // Only return messages not yet marked read
+	// Messages are returned oldest first by timestamp (ties broken by message_id); newest_first reverses that.
+	// offset and limit apply to this order.
+	NewestFirst   bool `protobuf:"varint,7,opt,name=newest_first,json=newestFirst,proto3" json:"newest_first,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMailRequest) Reset() {
+	*x = GetMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMailRequest) ProtoMessage() {}
+
+func (x *GetMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMailRequest.ProtoReflect.Descriptor instead.
+func (*GetMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *GetMailRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *GetMailRequest) GetAutoAck() bool {
+	if x != nil && x.AutoAck != nil {
+		return *x.AutoAck
+	}
+	return false
+}
+
+func (x *GetMailRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetMailRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetMailRequest) GetUnreadOnly() bool {
+	if x != nil {
+		return x.UnreadOnly
+	}
+	return false
+}
+
+func (x *GetMailRequest) GetNewestFirst() bool {
+	if x != nil {
+		return x.NewestFirst
+	}
+	return false
+}
+
+type GetMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*MailMessage         `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`    // Messages matching the request before offset and limit were applied
+	UnreadCount   int32                  `protobuf:"varint,3,opt,name=unread_count,json=unreadCount,proto3" json:"unread_count,omitempty"` // Unread messages left in the user's inbox after the call, regardless of filters
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMailResponse) Reset() {
+	*x = GetMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMailResponse) ProtoMessage() {}
+
+func (x *GetMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMailResponse.ProtoReflect.Descriptor instead.
+func (*GetMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *GetMailResponse) GetMessages() []*MailMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *GetMailResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *GetMailResponse) GetUnreadCount() int32 {
+	if x != nil {
+		return x.UnreadCount
+	}
+	return 0
+}
+
+type StreamMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	Labels        []string               `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty"`                         // If set, only messages carrying at least one of these labels are streamed
+	AutoAck       *bool                  `protobuf:"varint,3,opt,name=auto_ack,json=autoAck,proto3,oneof" json:"auto_ack,omitempty"` // As for GetMail: clear-on-read if true, keep until DeleteMail if false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMailRequest) Reset() {
+	*x = StreamMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMailRequest) ProtoMessage() {}
+
+func (x *StreamMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMailRequest.ProtoReflect.Descriptor instead.
+func (*StreamMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *StreamMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *StreamMailRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *StreamMailRequest) GetAutoAck() bool {
+	if x != nil && x.AutoAck != nil {
+		return *x.AutoAck
+	}
+	return false
+}
+
+type WaitForMailRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	// Only mail stored after this message is returned; empty means any mail. The message must still be in the
+	// inbox or the trash, otherwise all mail is returned: prefer after_sequence.
+	AfterMessageId string `protobuf:"bytes,2,opt,name=after_message_id,json=afterMessageId,proto3" json:"after_message_id,omitempty"`
+	TimeoutSeconds int32  `protobuf:"varint,3,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // Maximum wait, capped by the server; 0 uses the server default
+	AfterSequence  int64  `protobuf:"varint,4,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"`    // Only mail with a higher sequence is returned; takes precedence over after_message_id
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WaitForMailRequest) Reset() {
+	*x = WaitForMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WaitForMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitForMailRequest) ProtoMessage() {}
+
+func (x *WaitForMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitForMailRequest.ProtoReflect.Descriptor instead.
+func (*WaitForMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *WaitForMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *WaitForMailRequest) GetAfterMessageId() string {
+	if x != nil {
+		return x.AfterMessageId
+	}
+	return ""
+}
+
+func (x *WaitForMailRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *WaitForMailRequest) GetAfterSequence() int64 {
+	if x != nil {
+		return x.AfterSequence
+	}
+	return 0
+}
+
+type WatchMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchMailRequest) Reset() {
+	*x = WatchMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchMailRequest) ProtoMessage() {}
+
+func (x *WatchMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchMailRequest.ProtoReflect.Descriptor instead.
+func (*WatchMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *WatchMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+type DeleteMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	MessageIds    []string               `protobuf:"bytes,2,rep,name=message_ids,json=messageIds,proto3" json:"message_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMailRequest) Reset() {
+	*x = DeleteMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMailRequest) ProtoMessage() {}
+
+func (x *DeleteMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMailRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *DeleteMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *DeleteMailRequest) GetMessageIds() []string {
+	if x != nil {
+		return x.MessageIds
+	}
+	return nil
+}
+
+type DeleteMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       int32                  `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"` // Number of messages removed from the inbox
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMailResponse) Reset() {
+	*x = DeleteMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMailResponse) ProtoMessage() {}
+
+func (x *DeleteMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMailResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *DeleteMailResponse) GetDeleted() int32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+type SetPasswordRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"` // The new password, must not be empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPasswordRequest) Reset() {
+	*x = SetPasswordRequest{}
+	mi := &file_proto_mail_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPasswordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPasswordRequest) ProtoMessage() {}
+
+func (x *SetPasswordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPasswordRequest.ProtoReflect.Descriptor instead.
+func (*SetPasswordRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *SetPasswordRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *SetPasswordRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type SetPasswordResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Replaced      bool                   `protobuf:"varint,1,opt,name=replaced,proto3" json:"replaced,omitempty"` // Whether the user had a password before
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPasswordResponse) Reset() {
+	*x = SetPasswordResponse{}
+	mi := &file_proto_mail_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPasswordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPasswordResponse) ProtoMessage() {}
+
+func (x *SetPasswordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPasswordResponse.ProtoReflect.Descriptor instead.
+func (*SetPasswordResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SetPasswordResponse) GetReplaced() bool {
+	if x != nil {
+		return x.Replaced
+	}
+	return false
+}
+
+type UndeleteMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	MessageIds    []string               `protobuf:"bytes,2,rep,name=message_ids,json=messageIds,proto3" json:"message_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteMailRequest) Reset() {
+	*x = UndeleteMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteMailRequest) ProtoMessage() {}
+
+func (x *UndeleteMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteMailRequest.ProtoReflect.Descriptor instead.
+func (*UndeleteMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *UndeleteMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *UndeleteMailRequest) GetMessageIds() []string {
+	if x != nil {
+		return x.MessageIds
+	}
+	return nil
+}
+
+type UndeleteMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Restored      int32                  `protobuf:"varint,1,opt,name=restored,proto3" json:"restored,omitempty"` // Number of messages moved back into the inbox
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteMailResponse) Reset() {
+	*x = UndeleteMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteMailResponse) ProtoMessage() {}
+
+func (x *UndeleteMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteMailResponse.ProtoReflect.Descriptor instead.
+func (*UndeleteMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *UndeleteMailResponse) GetRestored() int32 {
+	if x != nil {
+		return x.Restored
+	}
+	return 0
+}
+
+type MarkReadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	MessageIds    []string               `protobuf:"bytes,2,rep,name=message_ids,json=messageIds,proto3" json:"message_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkReadRequest) Reset() {
+	*x = MarkReadRequest{}
+	mi := &file_proto_mail_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkReadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkReadRequest) ProtoMessage() {}
+
+func (x *MarkReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkReadRequest.ProtoReflect.Descriptor instead.
+func (*MarkReadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *MarkReadRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *MarkReadRequest) GetMessageIds() []string {
+	if x != nil {
+		return x.MessageIds
+	}
+	return nil
+}
+
+type MarkReadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Marked        int32                  `protobuf:"varint,1,opt,name=marked,proto3" json:"marked,omitempty"` // Number of messages newly marked read
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkReadResponse) Reset() {
+	*x = MarkReadResponse{}
+	mi := &file_proto_mail_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkReadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkReadResponse) ProtoMessage() {}
+
+func (x *MarkReadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkReadResponse.ProtoReflect.Descriptor instead.
+func (*MarkReadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *MarkReadResponse) GetMarked() int32 {
+	if x != nil {
+		return x.Marked
+	}
+	return 0
+}
+
+type SearchMailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	IncludeBody   bool                   `protobuf:"varint,3,opt,name=include_body,json=includeBody,proto3" json:"include_body,omitempty"` // Also match the message body
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchMailRequest) Reset() {
+	*x = SearchMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchMailRequest) ProtoMessage() {}
+
+func (x *SearchMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchMailRequest.ProtoReflect.Descriptor instead.
+func (*SearchMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *SearchMailRequest) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *SearchMailRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchMailRequest) GetIncludeBody() bool {
+	if x != nil {
+		return x.IncludeBody
+	}
+	return false
+}
+
+type SearchMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*MailMessage         `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"` // Matches in inbox order
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchMailResponse) Reset() {
+	*x = SearchMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchMailResponse) ProtoMessage() {}
+
+func (x *SearchMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchMailResponse.ProtoReflect.Descriptor instead.
+func (*SearchMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *SearchMailResponse) GetMessages() []*MailMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type CanAcceptRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RecipientEmail string                 `protobuf:"bytes,1,opt,name=recipient_email,json=recipientEmail,proto3" json:"recipient_email,omitempty"`
+	SenderEmail    string                 `protobuf:"bytes,2,opt,name=sender_email,json=senderEmail,proto3" json:"sender_email,omitempty"` // Not checked against the blocked senders, so the block list cannot be probed
+	BodyBytes      int64                  `protobuf:"varint,3,opt,name=body_bytes,json=bodyBytes,proto3" json:"body_bytes,omitempty"`      // Size of the message body, checked against the Mailbox's body size limit
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CanAcceptRequest) Reset() {
+	*x = CanAcceptRequest{}
+	mi := &file_proto_mail_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CanAcceptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CanAcceptRequest) ProtoMessage() {}
+
+func (x *CanAcceptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CanAcceptRequest.ProtoReflect.Descriptor instead.
+func (*CanAcceptRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *CanAcceptRequest) GetRecipientEmail() string {
+	if x != nil {
+		return x.RecipientEmail
+	}
+	return ""
+}
+
+func (x *CanAcceptRequest) GetSenderEmail() string {
+	if x != nil {
+		return x.SenderEmail
+	}
+	return ""
+}
+
+func (x *CanAcceptRequest) GetBodyBytes() int64 {
+	if x != nil {
+		return x.BodyBytes
+	}
+	return 0
+}
+
+type CanAcceptResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accept        bool                   `protobuf:"varint,1,opt,name=accept,proto3" json:"accept,omitempty"`
+	Retryable     bool                   `protobuf:"varint,2,opt,name=retryable,proto3" json:"retryable,omitempty"` // Set when refused for a temporary reason (e.g. full inbox), so delivery may be retried later
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`        // Why the mail would be refused
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CanAcceptResponse) Reset() {
+	*x = CanAcceptResponse{}
+	mi := &file_proto_mail_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CanAcceptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CanAcceptResponse) ProtoMessage() {}
+
+func (x *CanAcceptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CanAcceptResponse.ProtoReflect.Descriptor instead.
+func (*CanAcceptResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *CanAcceptResponse) GetAccept() bool {
+	if x != nil {
+		return x.Accept
+	}
+	return false
+}
+
+func (x *CanAcceptResponse) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+func (x *CanAcceptResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ExportMailboxRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportMailboxRequest) Reset() {
+	*x = ExportMailboxRequest{}
+	mi := &file_proto_mail_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportMailboxRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportMailboxRequest) ProtoMessage() {}
+
+func (x *ExportMailboxRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportMailboxRequest.ProtoReflect.Descriptor instead.
+func (*ExportMailboxRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{48}
+}
+
+type MailboxDumpEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"` // User whose inbox holds the message
+	Message       *MailMessage           `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MailboxDumpEntry) Reset() {
+	*x = MailboxDumpEntry{}
+	mi := &file_proto_mail_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailboxDumpEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailboxDumpEntry) ProtoMessage() {}
+
+func (x *MailboxDumpEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailboxDumpEntry.ProtoReflect.Descriptor instead.
+func (*MailboxDumpEntry) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *MailboxDumpEntry) GetEmailAddress() string {
+	if x != nil {
+		return x.EmailAddress
+	}
+	return ""
+}
+
+func (x *MailboxDumpEntry) GetMessage() *MailMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type ImportMailboxResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Imported      int32                  `protobuf:"varint,1,opt,name=imported,proto3" json:"imported,omitempty"` // Messages added to inboxes
+	Skipped       int32                  `protobuf:"varint,2,opt,name=skipped,proto3" json:"skipped,omitempty"`   // Messages already present
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportMailboxResponse) Reset() {
+	*x = ImportMailboxResponse{}
+	mi := &file_proto_mail_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportMailboxResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportMailboxResponse) ProtoMessage() {}
+
+func (x *ImportMailboxResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportMailboxResponse.ProtoReflect.Descriptor instead.
+func (*ImportMailboxResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ImportMailboxResponse) GetImported() int32 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ImportMailboxResponse) GetSkipped() int32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+type MailboxInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MailboxInfoRequest) Reset() {
+	*x = MailboxInfoRequest{}
+	mi := &file_proto_mail_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailboxInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailboxInfoRequest) ProtoMessage() {}
+
+func (x *MailboxInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailboxInfoRequest.ProtoReflect.Descriptor instead.
+func (*MailboxInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{51}
+}
+
+type MailboxInfoResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Domain            string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Users             int32                  `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`                                                  // Users with an inbox on this Mailbox
+	Messages          int32                  `protobuf:"varint,3,opt,name=messages,proto3" json:"messages,omitempty"`                                            // Messages currently stored across all inboxes
+	Trashed           int32                  `protobuf:"varint,4,opt,name=trashed,proto3" json:"trashed,omitempty"`                                              // Retrieved messages kept in the trash
+	Capacity          int32                  `protobuf:"varint,5,opt,name=capacity,proto3" json:"capacity,omitempty"`                                            // Total messages this Mailbox is sized for (0 = unlimited)
+	RemainingCapacity int32                  `protobuf:"varint,6,opt,name=remaining_capacity,json=remainingCapacity,proto3" json:"remaining_capacity,omitempty"` // Messages that still fit within capacity (0 if full or unlimited)
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *MailboxInfoResponse) Reset() {
+	*x = MailboxInfoResponse{}
+	mi := &file_proto_mail_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MailboxInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MailboxInfoResponse) ProtoMessage() {}
+
+func (x *MailboxInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MailboxInfoResponse.ProtoReflect.Descriptor instead.
+func (*MailboxInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *MailboxInfoResponse) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *MailboxInfoResponse) GetUsers() int32 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+func (x *MailboxInfoResponse) GetMessages() int32 {
+	if x != nil {
+		return x.Messages
+	}
+	return 0
+}
+
+func (x *MailboxInfoResponse) GetTrashed() int32 {
+	if x != nil {
+		return x.Trashed
+	}
+	return 0
+}
+
+func (x *MailboxInfoResponse) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *MailboxInfoResponse) GetRemainingCapacity() int32 {
+	if x != nil {
+		return x.RemainingCapacity
+	}
+	return 0
+}
+
+type SendMailRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message *MailMessage           `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Overrides the server's AsyncDelivery setting for this message: false delivers before returning,
+	// true queues the message and returns at once (requires AsyncDelivery). Unset uses the server setting.
+	Async         *bool `protobuf:"varint,2,opt,name=async,proto3,oneof" json:"async,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMailRequest) Reset() {
+	*x = SendMailRequest{}
+	mi := &file_proto_mail_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMailRequest) ProtoMessage() {}
+
+func (x *SendMailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMailRequest.ProtoReflect.Descriptor instead.
+func (*SendMailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *SendMailRequest) GetMessage() *MailMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *SendMailRequest) GetAsync() bool {
+	if x != nil && x.Async != nil {
+		return *x.Async
+	}
+	return false
+}
+
+type SendMailResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // ID of the sent message, usable with DeliveryReport
+	Results       []*RecipientResult     `protobuf:"bytes,4,rep,name=results,proto3" json:"results,omitempty"`                      // Outcome per recipient entry of a synchronous send, empty if queued
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMailResponse) Reset() {
+	*x = SendMailResponse{}
+	mi := &file_proto_mail_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMailResponse) ProtoMessage() {}
+
+func (x *SendMailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMailResponse.ProtoReflect.Descriptor instead.
+func (*SendMailResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *SendMailResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SendMailResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SendMailResponse) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *SendMailResponse) GetResults() []*RecipientResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type SendMailBulkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*SendMailBulkRequest_Message
+	//	*SendMailBulkRequest_RecipientEmail
+	Payload       isSendMailBulkRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMailBulkRequest) Reset() {
+	*x = SendMailBulkRequest{}
+	mi := &file_proto_mail_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMailBulkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMailBulkRequest) ProtoMessage() {}
+
+func (x *SendMailBulkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMailBulkRequest.ProtoReflect.Descriptor instead.
+func (*SendMailBulkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *SendMailBulkRequest) GetPayload() isSendMailBulkRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SendMailBulkRequest) GetMessage() *MailMessage {
+	if x != nil {
+		if x, ok := x.Payload.(*SendMailBulkRequest_Message); ok {
+			return x.Message
+		}
+	}
+	return nil
+}
+
+func (x *SendMailBulkRequest) GetRecipientEmail() string {
+	if x != nil {
+		if x, ok := x.Payload.(*SendMailBulkRequest_RecipientEmail); ok {
+			return x.RecipientEmail
+		}
+	}
+	return ""
+}
+
+type isSendMailBulkRequest_Payload interface {
+	isSendMailBulkRequest_Payload()
+}
+
+type SendMailBulkRequest_Message struct {
+	Message *MailMessage `protobuf:"bytes,1,opt,name=message,proto3,oneof"` // Must be sent first, exactly once
+}
+
+type SendMailBulkRequest_RecipientEmail struct {
+	RecipientEmail string `protobuf:"bytes,2,opt,name=recipient_email,json=recipientEmail,proto3,oneof"`
+}
+
+func (*SendMailBulkRequest_Message) isSendMailBulkRequest_Payload() {}
+
+func (*SendMailBulkRequest_RecipientEmail) isSendMailBulkRequest_Payload() {}
+
+// RecipientResult is the delivery outcome for a single recipient of a send.
+type RecipientResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RecipientEmail string                 `protobuf:"bytes,1,opt,name=recipient_email,json=recipientEmail,proto3" json:"recipient_email,omitempty"`
+	Success        bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Message        string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp      int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix timestamp of the final delivery attempt
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RecipientResult) Reset() {
+	*x = RecipientResult{}
+	mi := &file_proto_mail_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecipientResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecipientResult) ProtoMessage() {}
+
+func (x *RecipientResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecipientResult.ProtoReflect.Descriptor instead.
+func (*RecipientResult) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *RecipientResult) GetRecipientEmail() string {
+	if x != nil {
+		return x.RecipientEmail
+	}
+	return ""
+}
+
+func (x *RecipientResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RecipientResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RecipientResult) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type DeliveryReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryReportRequest) Reset() {
+	*x = DeliveryReportRequest{}
+	mi := &file_proto_mail_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryReportRequest) ProtoMessage() {}
+
+func (x *DeliveryReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryReportRequest.ProtoReflect.Descriptor instead.
+func (*DeliveryReportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *DeliveryReportRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+type DeliveryReportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderEmail   string                 `protobuf:"bytes,3,opt,name=sender_email,json=senderEmail,proto3" json:"sender_email,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix timestamp when the send was accepted
+	Results       []*RecipientResult     `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryReportResponse) Reset() {
+	*x = DeliveryReportResponse{}
+	mi := &file_proto_mail_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryReportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryReportResponse) ProtoMessage() {}
+
+func (x *DeliveryReportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryReportResponse.ProtoReflect.Descriptor instead.
+func (*DeliveryReportResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *DeliveryReportResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *DeliveryReportResponse) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *DeliveryReportResponse) GetSenderEmail() string {
+	if x != nil {
+		return x.SenderEmail
+	}
+	return ""
+}
+
+func (x *DeliveryReportResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *DeliveryReportResponse) GetResults() []*RecipientResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type GetDeliveryStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId    string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"` // The message_id returned by SendMail
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeliveryStatusRequest) Reset() {
+	*x = GetDeliveryStatusRequest{}
+	mi := &file_proto_mail_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeliveryStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeliveryStatusRequest) ProtoMessage() {}
+
+func (x *GetDeliveryStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeliveryStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetDeliveryStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *GetDeliveryStatusRequest) GetTrackingId() string {
+	if x != nil {
+		return x.TrackingId
+	}
+	return ""
+}
+
+type GetDeliveryStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        DeliveryStatus         `protobuf:"varint,1,opt,name=status,proto3,enum=mail.DeliveryStatus" json:"status,omitempty"`
+	Pending       int32                  `protobuf:"varint,2,opt,name=pending,proto3" json:"pending,omitempty"`     // Copies still queued or being delivered
+	Delivered     int32                  `protobuf:"varint,3,opt,name=delivered,proto3" json:"delivered,omitempty"` // Recipients delivered to
+	Failed        int32                  `protobuf:"varint,4,opt,name=failed,proto3" json:"failed,omitempty"`       // Recipients whose delivery failed
+	Results       []*RecipientResult     `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`      // Latest outcome per recipient
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeliveryStatusResponse) Reset() {
+	*x = GetDeliveryStatusResponse{}
+	mi := &file_proto_mail_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeliveryStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeliveryStatusResponse) ProtoMessage() {}
+
+func (x *GetDeliveryStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeliveryStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetDeliveryStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *GetDeliveryStatusResponse) GetStatus() DeliveryStatus {
+	if x != nil {
+		return x.Status
+	}
+	return DeliveryStatus_DELIVERY_STATUS_UNKNOWN
+}
+
+func (x *GetDeliveryStatusResponse) GetPending() int32 {
+	if x != nil {
+		return x.Pending
+	}
+	return 0
+}
+
+func (x *GetDeliveryStatusResponse) GetDelivered() int32 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *GetDeliveryStatusResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *GetDeliveryStatusResponse) GetResults() []*RecipientResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type PauseDeliveryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseDeliveryRequest) Reset() {
+	*x = PauseDeliveryRequest{}
+	mi := &file_proto_mail_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseDeliveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseDeliveryRequest) ProtoMessage() {}
+
+func (x *PauseDeliveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseDeliveryRequest.ProtoReflect.Descriptor instead.
+func (*PauseDeliveryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{61}
+}
+
+type ResumeDeliveryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeDeliveryRequest) Reset() {
+	*x = ResumeDeliveryRequest{}
+	mi := &file_proto_mail_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeDeliveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeDeliveryRequest) ProtoMessage() {}
+
+func (x *ResumeDeliveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeDeliveryRequest.ProtoReflect.Descriptor instead.
+func (*ResumeDeliveryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{62}
+}
+
+type FlushQueueRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushQueueRequest) Reset() {
+	*x = FlushQueueRequest{}
+	mi := &file_proto_mail_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushQueueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushQueueRequest) ProtoMessage() {}
+
+func (x *FlushQueueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushQueueRequest.ProtoReflect.Descriptor instead.
+func (*FlushQueueRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{63}
+}
+
+type QueueStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueStatusRequest) Reset() {
+	*x = QueueStatusRequest{}
+	mi := &file_proto_mail_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueStatusRequest) ProtoMessage() {}
+
+func (x *QueueStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -131,48 +3737,35 @@ func (x *RegisterMailboxRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterMailboxRequest.ProtoReflect.Descriptor instead.
-func (*RegisterMailboxRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{1}
-}
-
-func (x *RegisterMailboxRequest) GetEmailAddress() string {
-	if x != nil {
-		return x.EmailAddress
-	}
-	return ""
-}
-
-func (x *RegisterMailboxRequest) GetMailboxAddress() string {
-	if x != nil {
-		return x.MailboxAddress
-	}
-	return ""
+// Deprecated: Use QueueStatusRequest.ProtoReflect.Descriptor instead.
+func (*QueueStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{64}
 }
 
-type RegisterMailboxResponse struct {
+type QueueStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Paused        bool                   `protobuf:"varint,1,opt,name=paused,proto3" json:"paused,omitempty"`
+	Queued        int32                  `protobuf:"varint,2,opt,name=queued,proto3" json:"queued,omitempty"`                     // Messages waiting for a delivery attempt
+	InFlight      int32                  `protobuf:"varint,3,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"` // Delivery attempts currently in progress
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterMailboxResponse) Reset() {
-	*x = RegisterMailboxResponse{}
-	mi := &file_proto_mail_proto_msgTypes[2]
+func (x *QueueStatusResponse) Reset() {
+	*x = QueueStatusResponse{}
+	mi := &file_proto_mail_proto_msgTypes[65]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterMailboxResponse) String() string {
+func (x *QueueStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterMailboxResponse) ProtoMessage() {}
+func (*QueueStatusResponse) ProtoMessage() {}
 
-func (x *RegisterMailboxResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[2]
+func (x *QueueStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[65]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -183,47 +3776,53 @@ func (x *RegisterMailboxResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterMailboxResponse.ProtoReflect.Descriptor instead.
-func (*RegisterMailboxResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use QueueStatusResponse.ProtoReflect.Descriptor instead.
+func (*QueueStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{65}
 }
 
-func (x *RegisterMailboxResponse) GetSuccess() bool {
+func (x *QueueStatusResponse) GetPaused() bool {
 	if x != nil {
-		return x.Success
+		return x.Paused
 	}
 	return false
 }
 
-func (x *RegisterMailboxResponse) GetMessage() string {
+func (x *QueueStatusResponse) GetQueued() int32 {
 	if x != nil {
-		return x.Message
+		return x.Queued
 	}
-	return ""
+	return 0
 }
 
-type LookupMailboxRequest struct {
+func (x *QueueStatusResponse) GetInFlight() int32 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+type TransferServerInfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LookupMailboxRequest) Reset() {
-	*x = LookupMailboxRequest{}
-	mi := &file_proto_mail_proto_msgTypes[3]
+func (x *TransferServerInfoRequest) Reset() {
+	*x = TransferServerInfoRequest{}
+	mi := &file_proto_mail_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LookupMailboxRequest) String() string {
+func (x *TransferServerInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LookupMailboxRequest) ProtoMessage() {}
+func (*TransferServerInfoRequest) ProtoMessage() {}
 
-func (x *LookupMailboxRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[3]
+func (x *TransferServerInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -234,41 +3833,37 @@ func (x *LookupMailboxRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LookupMailboxRequest.ProtoReflect.Descriptor instead.
-func (*LookupMailboxRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{3}
-}
-
-func (x *LookupMailboxRequest) GetEmailAddress() string {
-	if x != nil {
-		return x.EmailAddress
-	}
-	return ""
+// Deprecated: Use TransferServerInfoRequest.ProtoReflect.Descriptor instead.
+func (*TransferServerInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{66}
 }
 
-type LookupMailboxResponse struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	MailboxAddress string                 `protobuf:"bytes,1,opt,name=mailbox_address,json=mailboxAddress,proto3" json:"mailbox_address,omitempty"`
-	Found          bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type TransferServerInfoResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	AsyncDelivery       bool                   `protobuf:"varint,1,opt,name=async_delivery,json=asyncDelivery,proto3" json:"async_delivery,omitempty"`
+	Queue               *QueueStatusResponse   `protobuf:"bytes,2,opt,name=queue,proto3" json:"queue,omitempty"`
+	MessagesAccepted    int64                  `protobuf:"varint,3,opt,name=messages_accepted,json=messagesAccepted,proto3" json:"messages_accepted,omitempty"`          // Messages accepted by SendMail and SendMailBulk since start
+	DeliveriesSucceeded int64                  `protobuf:"varint,4,opt,name=deliveries_succeeded,json=deliveriesSucceeded,proto3" json:"deliveries_succeeded,omitempty"` // Per-recipient deliveries that succeeded since start
+	DeliveriesFailed    int64                  `protobuf:"varint,5,opt,name=deliveries_failed,json=deliveriesFailed,proto3" json:"deliveries_failed,omitempty"`          // Per-recipient deliveries that failed permanently since start
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
-func (x *LookupMailboxResponse) Reset() {
-	*x = LookupMailboxResponse{}
-	mi := &file_proto_mail_proto_msgTypes[4]
+func (x *TransferServerInfoResponse) Reset() {
+	*x = TransferServerInfoResponse{}
+	mi := &file_proto_mail_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LookupMailboxResponse) String() string {
+func (x *TransferServerInfoResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LookupMailboxResponse) ProtoMessage() {}
+func (*TransferServerInfoResponse) ProtoMessage() {}
 
-func (x *LookupMailboxResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[4]
+func (x *TransferServerInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -279,47 +3874,75 @@ func (x *LookupMailboxResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LookupMailboxResponse.ProtoReflect.Descriptor instead.
-func (*LookupMailboxResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use TransferServerInfoResponse.ProtoReflect.Descriptor instead.
+func (*TransferServerInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{67}
 }
 
-func (x *LookupMailboxResponse) GetMailboxAddress() string {
+func (x *TransferServerInfoResponse) GetAsyncDelivery() bool {
 	if x != nil {
-		return x.MailboxAddress
+		return x.AsyncDelivery
 	}
-	return ""
+	return false
 }
 
-func (x *LookupMailboxResponse) GetFound() bool {
+func (x *TransferServerInfoResponse) GetQueue() *QueueStatusResponse {
 	if x != nil {
-		return x.Found
+		return x.Queue
 	}
-	return false
+	return nil
 }
 
-type ReceiveMailRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       *MailMessage           `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *TransferServerInfoResponse) GetMessagesAccepted() int64 {
+	if x != nil {
+		return x.MessagesAccepted
+	}
+	return 0
 }
 
-func (x *ReceiveMailRequest) Reset() {
-	*x = ReceiveMailRequest{}
-	mi := &file_proto_mail_proto_msgTypes[5]
+func (x *TransferServerInfoResponse) GetDeliveriesSucceeded() int64 {
+	if x != nil {
+		return x.DeliveriesSucceeded
+	}
+	return 0
+}
+
+func (x *TransferServerInfoResponse) GetDeliveriesFailed() int64 {
+	if x != nil {
+		return x.DeliveriesFailed
+	}
+	return 0
+}
+
+// DeadLetter is a copy of a message for a single recipient whose delivery failed for good.
+type DeadLetter struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Message        *MailMessage           `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"` // Addressed to recipient_email only
+	RecipientEmail string                 `protobuf:"bytes,3,opt,name=recipient_email,json=recipientEmail,proto3" json:"recipient_email,omitempty"`
+	LastError      string                 `protobuf:"bytes,4,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	Attempts       int32                  `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"`                                     // Re-drive attempts made since the message was dead-lettered
+	DeadLetteredAt int64                  `protobuf:"varint,6,opt,name=dead_lettered_at,json=deadLetteredAt,proto3" json:"dead_lettered_at,omitempty"` // Unix time
+	LastAttemptAt  int64                  `protobuf:"varint,7,opt,name=last_attempt_at,json=lastAttemptAt,proto3" json:"last_attempt_at,omitempty"`    // Unix time of the last re-drive attempt, 0 if none
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeadLetter) Reset() {
+	*x = DeadLetter{}
+	mi := &file_proto_mail_proto_msgTypes[68]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ReceiveMailRequest) String() string {
+func (x *DeadLetter) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ReceiveMailRequest) ProtoMessage() {}
+func (*DeadLetter) ProtoMessage() {}
 
-func (x *ReceiveMailRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[5]
+func (x *DeadLetter) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[68]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -330,92 +3953,81 @@ func (x *ReceiveMailRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ReceiveMailRequest.ProtoReflect.Descriptor instead.
-func (*ReceiveMailRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use DeadLetter.ProtoReflect.Descriptor instead.
+func (*DeadLetter) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{68}
 }
 
-func (x *ReceiveMailRequest) GetMessage() *MailMessage {
+func (x *DeadLetter) GetId() string {
 	if x != nil {
-		return x.Message
+		return x.Id
 	}
-	return nil
-}
-
-type ReceiveMailResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	return ""
 }
 
-func (x *ReceiveMailResponse) Reset() {
-	*x = ReceiveMailResponse{}
-	mi := &file_proto_mail_proto_msgTypes[6]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *DeadLetter) GetMessage() *MailMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
 }
 
-func (x *ReceiveMailResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *DeadLetter) GetRecipientEmail() string {
+	if x != nil {
+		return x.RecipientEmail
+	}
+	return ""
 }
 
-func (*ReceiveMailResponse) ProtoMessage() {}
-
-func (x *ReceiveMailResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[6]
+func (x *DeadLetter) GetLastError() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.LastError
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use ReceiveMailResponse.ProtoReflect.Descriptor instead.
-func (*ReceiveMailResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{6}
+func (x *DeadLetter) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
 }
 
-func (x *ReceiveMailResponse) GetSuccess() bool {
+func (x *DeadLetter) GetDeadLetteredAt() int64 {
 	if x != nil {
-		return x.Success
+		return x.DeadLetteredAt
 	}
-	return false
+	return 0
 }
 
-func (x *ReceiveMailResponse) GetMessage() string {
+func (x *DeadLetter) GetLastAttemptAt() int64 {
 	if x != nil {
-		return x.Message
+		return x.LastAttemptAt
 	}
-	return ""
+	return 0
 }
 
-type GetMailRequest struct {
+type ListDeadLettersRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	EmailAddress  string                 `protobuf:"bytes,1,opt,name=email_address,json=emailAddress,proto3" json:"email_address,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetMailRequest) Reset() {
-	*x = GetMailRequest{}
-	mi := &file_proto_mail_proto_msgTypes[7]
+func (x *ListDeadLettersRequest) Reset() {
+	*x = ListDeadLettersRequest{}
+	mi := &file_proto_mail_proto_msgTypes[69]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetMailRequest) String() string {
+func (x *ListDeadLettersRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetMailRequest) ProtoMessage() {}
+func (*ListDeadLettersRequest) ProtoMessage() {}
 
-func (x *GetMailRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[7]
+func (x *ListDeadLettersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[69]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -426,40 +4038,33 @@ func (x *GetMailRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetMailRequest.ProtoReflect.Descriptor instead.
-func (*GetMailRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{7}
-}
-
-func (x *GetMailRequest) GetEmailAddress() string {
-	if x != nil {
-		return x.EmailAddress
-	}
-	return ""
+// Deprecated: Use ListDeadLettersRequest.ProtoReflect.Descriptor instead.
+func (*ListDeadLettersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{69}
 }
 
-type GetMailResponse struct {
+type ListDeadLettersResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Messages      []*MailMessage         `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	DeadLetters   []*DeadLetter          `protobuf:"bytes,1,rep,name=dead_letters,json=deadLetters,proto3" json:"dead_letters,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetMailResponse) Reset() {
-	*x = GetMailResponse{}
-	mi := &file_proto_mail_proto_msgTypes[8]
+func (x *ListDeadLettersResponse) Reset() {
+	*x = ListDeadLettersResponse{}
+	mi := &file_proto_mail_proto_msgTypes[70]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetMailResponse) String() string {
+func (x *ListDeadLettersResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetMailResponse) ProtoMessage() {}
+func (*ListDeadLettersResponse) ProtoMessage() {}
 
-func (x *GetMailResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[8]
+func (x *ListDeadLettersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[70]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -470,40 +4075,40 @@ func (x *GetMailResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetMailResponse.ProtoReflect.Descriptor instead.
-func (*GetMailResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use ListDeadLettersResponse.ProtoReflect.Descriptor instead.
+func (*ListDeadLettersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{70}
 }
 
-func (x *GetMailResponse) GetMessages() []*MailMessage {
+func (x *ListDeadLettersResponse) GetDeadLetters() []*DeadLetter {
 	if x != nil {
-		return x.Messages
+		return x.DeadLetters
 	}
 	return nil
 }
 
-type SendMailRequest struct {
+type RetryDeadLetterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       *MailMessage           `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendMailRequest) Reset() {
-	*x = SendMailRequest{}
-	mi := &file_proto_mail_proto_msgTypes[9]
+func (x *RetryDeadLetterRequest) Reset() {
+	*x = RetryDeadLetterRequest{}
+	mi := &file_proto_mail_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendMailRequest) String() string {
+func (x *RetryDeadLetterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendMailRequest) ProtoMessage() {}
+func (*RetryDeadLetterRequest) ProtoMessage() {}
 
-func (x *SendMailRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[9]
+func (x *RetryDeadLetterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -514,41 +4119,42 @@ func (x *SendMailRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendMailRequest.ProtoReflect.Descriptor instead.
-func (*SendMailRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use RetryDeadLetterRequest.ProtoReflect.Descriptor instead.
+func (*RetryDeadLetterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{71}
 }
 
-func (x *SendMailRequest) GetMessage() *MailMessage {
+func (x *RetryDeadLetterRequest) GetId() string {
 	if x != nil {
-		return x.Message
+		return x.Id
 	}
-	return nil
+	return ""
 }
 
-type SendMailResponse struct {
+type RetryDeadLetterResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	DeadLetter    *DeadLetter            `protobuf:"bytes,3,opt,name=dead_letter,json=deadLetter,proto3" json:"dead_letter,omitempty"` // The updated dead letter if the attempt failed
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendMailResponse) Reset() {
-	*x = SendMailResponse{}
-	mi := &file_proto_mail_proto_msgTypes[10]
+func (x *RetryDeadLetterResponse) Reset() {
+	*x = RetryDeadLetterResponse{}
+	mi := &file_proto_mail_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendMailResponse) String() string {
+func (x *RetryDeadLetterResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendMailResponse) ProtoMessage() {}
+func (*RetryDeadLetterResponse) ProtoMessage() {}
 
-func (x *SendMailResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mail_proto_msgTypes[10]
+func (x *RetryDeadLetterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mail_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -559,70 +4165,365 @@ func (x *SendMailResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendMailResponse.ProtoReflect.Descriptor instead.
-func (*SendMailResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mail_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use RetryDeadLetterResponse.ProtoReflect.Descriptor instead.
+func (*RetryDeadLetterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mail_proto_rawDescGZIP(), []int{72}
 }
 
-func (x *SendMailResponse) GetSuccess() bool {
+func (x *RetryDeadLetterResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *SendMailResponse) GetMessage() string {
+func (x *RetryDeadLetterResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
+func (x *RetryDeadLetterResponse) GetDeadLetter() *DeadLetter {
+	if x != nil {
+		return x.DeadLetter
+	}
+	return nil
+}
+
 var File_proto_mail_proto protoreflect.FileDescriptor
 
 const file_proto_mail_proto_rawDesc = "" +
 	"\n" +
-	"\x10proto/mail.proto\x12\x04mail\"\xa5\x01\n" +
+	"\x10proto/mail.proto\x12\x04mail\"\xfb\x04\n" +
 	"\vMailMessage\x12!\n" +
 	"\fsender_email\x18\x01 \x01(\tR\vsenderEmail\x12'\n" +
 	"\x0frecipient_email\x18\x02 \x01(\tR\x0erecipientEmail\x12\x18\n" +
 	"\asubject\x18\x03 \x01(\tR\asubject\x12\x12\n" +
 	"\x04body\x18\x04 \x01(\tR\x04body\x12\x1c\n" +
-	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"f\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x06 \x01(\tR\tmessageId\x12\x0e\n" +
+	"\x02to\x18\a \x03(\tR\x02to\x12\x0e\n" +
+	"\x02cc\x18\b \x03(\tR\x02cc\x12\x10\n" +
+	"\x03bcc\x18\t \x03(\tR\x03bcc\x12\x16\n" +
+	"\x06labels\x18\n" +
+	" \x03(\tR\x06labels\x122\n" +
+	"\vattachments\x18\v \x03(\v2\x10.mail.AttachmentR\vattachments\x120\n" +
+	"\x14request_read_receipt\x18\f \x01(\bR\x12requestReadReceipt\x123\n" +
+	"\x16receipt_for_message_id\x18\r \x01(\tR\x13receiptForMessageId\x121\n" +
+	"\x15bounce_for_message_id\x18\x0e \x01(\tR\x12bounceForMessageId\x12-\n" +
+	"\x12received_timestamp\x18\x0f \x01(\x03R\x11receivedTimestamp\x12%\n" +
+	"\x0eencrypted_body\x18\x10 \x01(\fR\rencryptedBody\x12\x12\n" +
+	"\x04read\x18\x11 \x01(\bR\x04read\x12\x17\n" +
+	"\asent_at\x18\x12 \x01(\tR\x06sentAt\x12\x1a\n" +
+	"\bsequence\x18\x13 \x01(\x03R\bsequence\"_\n" +
+	"\n" +
+	"Attachment\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"\xad\x01\n" +
 	"\x16RegisterMailboxRequest\x12#\n" +
 	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12'\n" +
-	"\x0fmailbox_address\x18\x02 \x01(\tR\x0emailboxAddress\"M\n" +
+	"\x0fmailbox_address\x18\x02 \x01(\tR\x0emailboxAddress\x12\x18\n" +
+	"\areplica\x18\x03 \x01(\bR\areplica\x12+\n" +
+	"\x11mailbox_addresses\x18\x04 \x03(\tR\x10mailboxAddresses\"k\n" +
 	"\x17RegisterMailboxResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\";\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1c\n" +
+	"\tunchanged\x18\x03 \x01(\bR\tunchanged\";\n" +
 	"\x14LookupMailboxRequest\x12#\n" +
-	"\remail_address\x18\x01 \x01(\tR\femailAddress\"V\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\"\xdc\x01\n" +
 	"\x15LookupMailboxResponse\x12'\n" +
 	"\x0fmailbox_address\x18\x01 \x01(\tR\x0emailboxAddress\x12\x14\n" +
-	"\x05found\x18\x02 \x01(\bR\x05found\"A\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12+\n" +
+	"\x11replica_addresses\x18\x03 \x03(\tR\x10replicaAddresses\x12)\n" +
+	"\x10referral_address\x18\x04 \x01(\tR\x0freferralAddress\x12,\n" +
+	"\x12domain_not_managed\x18\x05 \x01(\bR\x10domainNotManaged\"?\n" +
+	"\x18DeregisterMailboxRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\"O\n" +
+	"\x19DeregisterMailboxResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\bR\aremoved\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\".\n" +
+	"\x14ListMailboxesRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\"\x89\x01\n" +
+	"\fMailboxEntry\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12'\n" +
+	"\x0fmailbox_address\x18\x02 \x01(\tR\x0emailboxAddress\x12+\n" +
+	"\x11replica_addresses\x18\x03 \x03(\tR\x10replicaAddresses\"E\n" +
+	"\x15ListMailboxesResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.mail.MailboxEntryR\aentries\"\x96\x01\n" +
+	"\x1cCompareAndSwapMailboxRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x120\n" +
+	"\x14expected_old_address\x18\x02 \x01(\tR\x12expectedOldAddress\x12\x1f\n" +
+	"\vnew_address\x18\x03 \x01(\tR\n" +
+	"newAddress\"H\n" +
+	"\x1dCompareAndSwapMailboxResponse\x12'\n" +
+	"\x0fmailbox_address\x18\x01 \x01(\tR\x0emailboxAddress\"J\n" +
+	"\x17CheckConsistencyRequest\x12/\n" +
+	"\x13verify_reachability\x18\x01 \x01(\bR\x12verifyReachability\"\xa8\x01\n" +
+	"\x10ConsistencyIssue\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12'\n" +
+	"\x0fmailbox_address\x18\x02 \x01(\tR\x0emailboxAddress\x12.\n" +
+	"\x04kind\x18\x03 \x01(\x0e2\x1a.mail.ConsistencyIssueKindR\x04kind\x12\x16\n" +
+	"\x06detail\x18\x04 \x01(\tR\x06detail\"d\n" +
+	"\x18CheckConsistencyResponse\x12\x18\n" +
+	"\achecked\x18\x01 \x01(\x05R\achecked\x12.\n" +
+	"\x06issues\x18\x02 \x03(\v2\x16.mail.ConsistencyIssueR\x06issues\"]\n" +
+	"\x13RegisterListRequest\x12!\n" +
+	"\flist_address\x18\x01 \x01(\tR\vlistAddress\x12#\n" +
+	"\rmember_emails\x18\x02 \x03(\tR\fmemberEmails\"J\n" +
+	"\x14RegisterListResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"8\n" +
+	"\x11ExpandListRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\"e\n" +
+	"\x12ExpandListResponse\x12\x17\n" +
+	"\ais_list\x18\x01 \x01(\bR\x06isList\x12\x18\n" +
+	"\amembers\x18\x02 \x03(\tR\amembers\x12\x1c\n" +
+	"\ttruncated\x18\x03 \x01(\bR\ttruncated\"=\n" +
+	"\x12ExpandListsRequest\x12'\n" +
+	"\x0femail_addresses\x18\x01 \x03(\tR\x0eemailAddresses\"O\n" +
+	"\x13ExpandListsResponse\x128\n" +
+	"\n" +
+	"expansions\x18\x01 \x03(\v2\x18.mail.ExpandListResponseR\n" +
+	"expansions\"1\n" +
+	"\x17AddManagedDomainRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\"J\n" +
+	"\x1aRemoveManagedDomainRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x14\n" +
+	"\x05purge\x18\x02 \x01(\bR\x05purge\"r\n" +
+	"\x15ManagedDomainResponse\x12\x18\n" +
+	"\achanged\x18\x01 \x01(\bR\achanged\x12\x16\n" +
+	"\x06purged\x18\x02 \x01(\x05R\x06purged\x12'\n" +
+	"\x0fmanaged_domains\x18\x03 \x03(\tR\x0emanagedDomains\"\x17\n" +
+	"\x15NameserverInfoRequest\"g\n" +
+	"\x16NameserverInfoResponse\x12$\n" +
+	"\rregistrations\x18\x01 \x01(\x05R\rregistrations\x12'\n" +
+	"\x0fmanaged_domains\x18\x02 \x03(\tR\x0emanagedDomains\"\x19\n" +
+	"\x17NameserverHealthRequest\"u\n" +
+	"\x18NameserverHealthResponse\x12\x18\n" +
+	"\aserving\x18\x01 \x01(\bR\aserving\x12'\n" +
+	"\x0fmanaged_domains\x18\x02 \x03(\tR\x0emanagedDomains\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\"A\n" +
 	"\x12ReceiveMailRequest\x12+\n" +
-	"\amessage\x18\x01 \x01(\v2\x11.mail.MailMessageR\amessage\"I\n" +
+	"\amessage\x18\x01 \x01(\v2\x11.mail.MailMessageR\amessage\"l\n" +
 	"\x13ReceiveMailResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"5\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\fmailbox_full\x18\x03 \x01(\bR\vmailboxFull\"\xec\x01\n" +
 	"\x0eGetMailRequest\x12#\n" +
-	"\remail_address\x18\x01 \x01(\tR\femailAddress\"@\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x16\n" +
+	"\x06labels\x18\x02 \x03(\tR\x06labels\x12\x1e\n" +
+	"\bauto_ack\x18\x03 \x01(\bH\x00R\aautoAck\x88\x01\x01\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\x12\x1f\n" +
+	"\vunread_only\x18\x06 \x01(\bR\n" +
+	"unreadOnly\x12!\n" +
+	"\fnewest_first\x18\a \x01(\bR\vnewestFirstB\v\n" +
+	"\t_auto_ack\"\x84\x01\n" +
 	"\x0fGetMailResponse\x12-\n" +
-	"\bmessages\x18\x01 \x03(\v2\x11.mail.MailMessageR\bmessages\">\n" +
+	"\bmessages\x18\x01 \x03(\v2\x11.mail.MailMessageR\bmessages\x12\x1f\n" +
+	"\vtotal_count\x18\x02 \x01(\x05R\n" +
+	"totalCount\x12!\n" +
+	"\funread_count\x18\x03 \x01(\x05R\vunreadCount\"}\n" +
+	"\x11StreamMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x16\n" +
+	"\x06labels\x18\x02 \x03(\tR\x06labels\x12\x1e\n" +
+	"\bauto_ack\x18\x03 \x01(\bH\x00R\aautoAck\x88\x01\x01B\v\n" +
+	"\t_auto_ack\"\xb3\x01\n" +
+	"\x12WaitForMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12(\n" +
+	"\x10after_message_id\x18\x02 \x01(\tR\x0eafterMessageId\x12'\n" +
+	"\x0ftimeout_seconds\x18\x03 \x01(\x05R\x0etimeoutSeconds\x12%\n" +
+	"\x0eafter_sequence\x18\x04 \x01(\x03R\rafterSequence\"7\n" +
+	"\x10WatchMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\"Y\n" +
+	"\x11DeleteMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x1f\n" +
+	"\vmessage_ids\x18\x02 \x03(\tR\n" +
+	"messageIds\".\n" +
+	"\x12DeleteMailResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\x05R\adeleted\"U\n" +
+	"\x12SetPasswordRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"1\n" +
+	"\x13SetPasswordResponse\x12\x1a\n" +
+	"\breplaced\x18\x01 \x01(\bR\breplaced\"[\n" +
+	"\x13UndeleteMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x1f\n" +
+	"\vmessage_ids\x18\x02 \x03(\tR\n" +
+	"messageIds\"2\n" +
+	"\x14UndeleteMailResponse\x12\x1a\n" +
+	"\brestored\x18\x01 \x01(\x05R\brestored\"W\n" +
+	"\x0fMarkReadRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x1f\n" +
+	"\vmessage_ids\x18\x02 \x03(\tR\n" +
+	"messageIds\"*\n" +
+	"\x10MarkReadResponse\x12\x16\n" +
+	"\x06marked\x18\x01 \x01(\x05R\x06marked\"q\n" +
+	"\x11SearchMailRequest\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12!\n" +
+	"\finclude_body\x18\x03 \x01(\bR\vincludeBody\"C\n" +
+	"\x12SearchMailResponse\x12-\n" +
+	"\bmessages\x18\x01 \x03(\v2\x11.mail.MailMessageR\bmessages\"}\n" +
+	"\x10CanAcceptRequest\x12'\n" +
+	"\x0frecipient_email\x18\x01 \x01(\tR\x0erecipientEmail\x12!\n" +
+	"\fsender_email\x18\x02 \x01(\tR\vsenderEmail\x12\x1d\n" +
+	"\n" +
+	"body_bytes\x18\x03 \x01(\x03R\tbodyBytes\"a\n" +
+	"\x11CanAcceptResponse\x12\x16\n" +
+	"\x06accept\x18\x01 \x01(\bR\x06accept\x12\x1c\n" +
+	"\tretryable\x18\x02 \x01(\bR\tretryable\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\x16\n" +
+	"\x14ExportMailboxRequest\"d\n" +
+	"\x10MailboxDumpEntry\x12#\n" +
+	"\remail_address\x18\x01 \x01(\tR\femailAddress\x12+\n" +
+	"\amessage\x18\x02 \x01(\v2\x11.mail.MailMessageR\amessage\"M\n" +
+	"\x15ImportMailboxResponse\x12\x1a\n" +
+	"\bimported\x18\x01 \x01(\x05R\bimported\x12\x18\n" +
+	"\askipped\x18\x02 \x01(\x05R\askipped\"\x14\n" +
+	"\x12MailboxInfoRequest\"\xc4\x01\n" +
+	"\x13MailboxInfoResponse\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x14\n" +
+	"\x05users\x18\x02 \x01(\x05R\x05users\x12\x1a\n" +
+	"\bmessages\x18\x03 \x01(\x05R\bmessages\x12\x18\n" +
+	"\atrashed\x18\x04 \x01(\x05R\atrashed\x12\x1a\n" +
+	"\bcapacity\x18\x05 \x01(\x05R\bcapacity\x12-\n" +
+	"\x12remaining_capacity\x18\x06 \x01(\x05R\x11remainingCapacity\"c\n" +
 	"\x0fSendMailRequest\x12+\n" +
-	"\amessage\x18\x01 \x01(\v2\x11.mail.MailMessageR\amessage\"F\n" +
+	"\amessage\x18\x01 \x01(\v2\x11.mail.MailMessageR\amessage\x12\x19\n" +
+	"\x05async\x18\x02 \x01(\bH\x00R\x05async\x88\x01\x01B\b\n" +
+	"\x06_async\"\x96\x01\n" +
 	"\x10SendMailResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xa6\x01\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12/\n" +
+	"\aresults\x18\x04 \x03(\v2\x15.mail.RecipientResultR\aresults\"z\n" +
+	"\x13SendMailBulkRequest\x12-\n" +
+	"\amessage\x18\x01 \x01(\v2\x11.mail.MailMessageH\x00R\amessage\x12)\n" +
+	"\x0frecipient_email\x18\x02 \x01(\tH\x00R\x0erecipientEmailB\t\n" +
+	"\apayload\"\x8c\x01\n" +
+	"\x0fRecipientResult\x12'\n" +
+	"\x0frecipient_email\x18\x01 \x01(\tR\x0erecipientEmail\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"6\n" +
+	"\x15DeliveryReportRequest\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\"\xc0\x01\n" +
+	"\x16DeliveryReportResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12!\n" +
+	"\fsender_email\x18\x03 \x01(\tR\vsenderEmail\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\x12/\n" +
+	"\aresults\x18\x05 \x03(\v2\x15.mail.RecipientResultR\aresults\";\n" +
+	"\x18GetDeliveryStatusRequest\x12\x1f\n" +
+	"\vtracking_id\x18\x01 \x01(\tR\n" +
+	"trackingId\"\xca\x01\n" +
+	"\x19GetDeliveryStatusResponse\x12,\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x14.mail.DeliveryStatusR\x06status\x12\x18\n" +
+	"\apending\x18\x02 \x01(\x05R\apending\x12\x1c\n" +
+	"\tdelivered\x18\x03 \x01(\x05R\tdelivered\x12\x16\n" +
+	"\x06failed\x18\x04 \x01(\x05R\x06failed\x12/\n" +
+	"\aresults\x18\x05 \x03(\v2\x15.mail.RecipientResultR\aresults\"\x16\n" +
+	"\x14PauseDeliveryRequest\"\x17\n" +
+	"\x15ResumeDeliveryRequest\"\x13\n" +
+	"\x11FlushQueueRequest\"\x14\n" +
+	"\x12QueueStatusRequest\"b\n" +
+	"\x13QueueStatusResponse\x12\x16\n" +
+	"\x06paused\x18\x01 \x01(\bR\x06paused\x12\x16\n" +
+	"\x06queued\x18\x02 \x01(\x05R\x06queued\x12\x1b\n" +
+	"\tin_flight\x18\x03 \x01(\x05R\binFlight\"\x1b\n" +
+	"\x19TransferServerInfoRequest\"\x81\x02\n" +
+	"\x1aTransferServerInfoResponse\x12%\n" +
+	"\x0easync_delivery\x18\x01 \x01(\bR\rasyncDelivery\x12/\n" +
+	"\x05queue\x18\x02 \x01(\v2\x19.mail.QueueStatusResponseR\x05queue\x12+\n" +
+	"\x11messages_accepted\x18\x03 \x01(\x03R\x10messagesAccepted\x121\n" +
+	"\x14deliveries_succeeded\x18\x04 \x01(\x03R\x13deliveriesSucceeded\x12+\n" +
+	"\x11deliveries_failed\x18\x05 \x01(\x03R\x10deliveriesFailed\"\xff\x01\n" +
+	"\n" +
+	"DeadLetter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\amessage\x18\x02 \x01(\v2\x11.mail.MailMessageR\amessage\x12'\n" +
+	"\x0frecipient_email\x18\x03 \x01(\tR\x0erecipientEmail\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x04 \x01(\tR\tlastError\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x05R\battempts\x12(\n" +
+	"\x10dead_lettered_at\x18\x06 \x01(\x03R\x0edeadLetteredAt\x12&\n" +
+	"\x0flast_attempt_at\x18\a \x01(\x03R\rlastAttemptAt\"\x18\n" +
+	"\x16ListDeadLettersRequest\"N\n" +
+	"\x17ListDeadLettersResponse\x123\n" +
+	"\fdead_letters\x18\x01 \x03(\v2\x10.mail.DeadLetterR\vdeadLetters\"(\n" +
+	"\x16RetryDeadLetterRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x80\x01\n" +
+	"\x17RetryDeadLetterResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x121\n" +
+	"\vdead_letter\x18\x03 \x01(\v2\x10.mail.DeadLetterR\n" +
+	"deadLetter*\xe0\x01\n" +
+	"\x14ConsistencyIssueKind\x12!\n" +
+	"\x1dCONSISTENCY_ISSUE_UNSPECIFIED\x10\x00\x12#\n" +
+	"\x1fCONSISTENCY_ISSUE_INVALID_EMAIL\x10\x01\x12-\n" +
+	")CONSISTENCY_ISSUE_INVALID_MAILBOX_ADDRESS\x10\x02\x12&\n" +
+	"\"CONSISTENCY_ISSUE_UNMANAGED_DOMAIN\x10\x03\x12)\n" +
+	"%CONSISTENCY_ISSUE_UNREACHABLE_MAILBOX\x10\x04*\x85\x01\n" +
+	"\x0eDeliveryStatus\x12\x1b\n" +
+	"\x17DELIVERY_STATUS_UNKNOWN\x10\x00\x12\x1b\n" +
+	"\x17DELIVERY_STATUS_PENDING\x10\x01\x12\x1d\n" +
+	"\x19DELIVERY_STATUS_DELIVERED\x10\x02\x12\x1a\n" +
+	"\x16DELIVERY_STATUS_FAILED\x10\x032\xf9\a\n" +
 	"\n" +
 	"Nameserver\x12N\n" +
 	"\x0fRegisterMailbox\x12\x1c.mail.RegisterMailboxRequest\x1a\x1d.mail.RegisterMailboxResponse\x12H\n" +
-	"\rLookupMailbox\x12\x1a.mail.LookupMailboxRequest\x1a\x1b.mail.LookupMailboxResponse2\x85\x01\n" +
+	"\rLookupMailbox\x12\x1a.mail.LookupMailboxRequest\x1a\x1b.mail.LookupMailboxResponse\x12T\n" +
+	"\x11DeregisterMailbox\x12\x1e.mail.DeregisterMailboxRequest\x1a\x1f.mail.DeregisterMailboxResponse\x12H\n" +
+	"\rListMailboxes\x12\x1a.mail.ListMailboxesRequest\x1a\x1b.mail.ListMailboxesResponse\x12`\n" +
+	"\x15CompareAndSwapMailbox\x12\".mail.CompareAndSwapMailboxRequest\x1a#.mail.CompareAndSwapMailboxResponse\x12Q\n" +
+	"\x10CheckConsistency\x12\x1d.mail.CheckConsistencyRequest\x1a\x1e.mail.CheckConsistencyResponse\x12A\n" +
+	"\x04Info\x12\x1b.mail.NameserverInfoRequest\x1a\x1c.mail.NameserverInfoResponse\x12G\n" +
+	"\x06Health\x12\x1d.mail.NameserverHealthRequest\x1a\x1e.mail.NameserverHealthResponse\x12N\n" +
+	"\x10AddManagedDomain\x12\x1d.mail.AddManagedDomainRequest\x1a\x1b.mail.ManagedDomainResponse\x12T\n" +
+	"\x13RemoveManagedDomain\x12 .mail.RemoveManagedDomainRequest\x1a\x1b.mail.ManagedDomainResponse\x12E\n" +
+	"\fRegisterList\x12\x19.mail.RegisterListRequest\x1a\x1a.mail.RegisterListResponse\x12?\n" +
+	"\n" +
+	"ExpandList\x12\x17.mail.ExpandListRequest\x1a\x18.mail.ExpandListResponse\x12B\n" +
+	"\vExpandLists\x12\x18.mail.ExpandListsRequest\x1a\x19.mail.ExpandListsResponse2\x8d\a\n" +
 	"\aMailbox\x12B\n" +
 	"\vReceiveMail\x12\x18.mail.ReceiveMailRequest\x1a\x19.mail.ReceiveMailResponse\x126\n" +
-	"\aGetMail\x12\x14.mail.GetMailRequest\x1a\x15.mail.GetMailResponse2K\n" +
+	"\aGetMail\x12\x14.mail.GetMailRequest\x1a\x15.mail.GetMailResponse\x12:\n" +
+	"\n" +
+	"StreamMail\x12\x17.mail.StreamMailRequest\x1a\x11.mail.MailMessage0\x01\x12?\n" +
+	"\n" +
+	"DeleteMail\x12\x17.mail.DeleteMailRequest\x1a\x18.mail.DeleteMailResponse\x12>\n" +
+	"\vWaitForMail\x12\x18.mail.WaitForMailRequest\x1a\x15.mail.GetMailResponse\x128\n" +
+	"\tWatchMail\x12\x16.mail.WatchMailRequest\x1a\x11.mail.MailMessage0\x01\x12E\n" +
+	"\fUndeleteMail\x12\x19.mail.UndeleteMailRequest\x1a\x1a.mail.UndeleteMailResponse\x129\n" +
+	"\bMarkRead\x12\x15.mail.MarkReadRequest\x1a\x16.mail.MarkReadResponse\x12?\n" +
+	"\n" +
+	"SearchMail\x12\x17.mail.SearchMailRequest\x1a\x18.mail.SearchMailResponse\x12;\n" +
+	"\x04Info\x12\x18.mail.MailboxInfoRequest\x1a\x19.mail.MailboxInfoResponse\x12<\n" +
+	"\tCanAccept\x12\x16.mail.CanAcceptRequest\x1a\x17.mail.CanAcceptResponse\x12E\n" +
+	"\rExportMailbox\x12\x1a.mail.ExportMailboxRequest\x1a\x16.mail.MailboxDumpEntry0\x01\x12F\n" +
+	"\rImportMailbox\x12\x16.mail.MailboxDumpEntry\x1a\x1b.mail.ImportMailboxResponse(\x01\x12B\n" +
+	"\vSetPassword\x12\x18.mail.SetPasswordRequest\x1a\x19.mail.SetPasswordResponse2\xb7\x06\n" +
 	"\x0eTransferServer\x129\n" +
-	"\bSendMail\x12\x15.mail.SendMailRequest\x1a\x16.mail.SendMailResponseB\tZ\a./protob\x06proto3"
+	"\bSendMail\x12\x15.mail.SendMailRequest\x1a\x16.mail.SendMailResponse\x12D\n" +
+	"\fSendMailBulk\x12\x19.mail.SendMailBulkRequest\x1a\x15.mail.RecipientResult(\x010\x01\x12K\n" +
+	"\x0eDeliveryReport\x12\x1b.mail.DeliveryReportRequest\x1a\x1c.mail.DeliveryReportResponse\x12T\n" +
+	"\x11GetDeliveryStatus\x12\x1e.mail.GetDeliveryStatusRequest\x1a\x1f.mail.GetDeliveryStatusResponse\x12F\n" +
+	"\rPauseDelivery\x12\x1a.mail.PauseDeliveryRequest\x1a\x19.mail.QueueStatusResponse\x12H\n" +
+	"\x0eResumeDelivery\x12\x1b.mail.ResumeDeliveryRequest\x1a\x19.mail.QueueStatusResponse\x12@\n" +
+	"\n" +
+	"FlushQueue\x12\x17.mail.FlushQueueRequest\x1a\x19.mail.QueueStatusResponse\x12B\n" +
+	"\vQueueStatus\x12\x18.mail.QueueStatusRequest\x1a\x19.mail.QueueStatusResponse\x12I\n" +
+	"\x04Info\x12\x1f.mail.TransferServerInfoRequest\x1a .mail.TransferServerInfoResponse\x12N\n" +
+	"\x0fListDeadLetters\x12\x1c.mail.ListDeadLettersRequest\x1a\x1d.mail.ListDeadLettersResponse\x12N\n" +
+	"\x0fRetryDeadLetter\x12\x1c.mail.RetryDeadLetterRequest\x1a\x1d.mail.RetryDeadLetterResponseB\tZ\a./protob\x06proto3"
 
 var (
 	file_proto_mail_proto_rawDescOnce sync.Once
@@ -636,39 +4537,186 @@ func file_proto_mail_proto_rawDescGZIP() []byte {
 	return file_proto_mail_proto_rawDescData
 }
 
-var file_proto_mail_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_mail_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_mail_proto_msgTypes = make([]protoimpl.MessageInfo, 73)
 var file_proto_mail_proto_goTypes = []any{
-	(*MailMessage)(nil),             // 0: mail.MailMessage
-	(*RegisterMailboxRequest)(nil),  // 1: mail.RegisterMailboxRequest
-	(*RegisterMailboxResponse)(nil), // 2: mail.RegisterMailboxResponse
-	(*LookupMailboxRequest)(nil),    // 3: mail.LookupMailboxRequest
-	(*LookupMailboxResponse)(nil),   // 4: mail.LookupMailboxResponse
-	(*ReceiveMailRequest)(nil),      // 5: mail.ReceiveMailRequest
-	(*ReceiveMailResponse)(nil),     // 6: mail.ReceiveMailResponse
-	(*GetMailRequest)(nil),          // 7: mail.GetMailRequest
-	(*GetMailResponse)(nil),         // 8: mail.GetMailResponse
-	(*SendMailRequest)(nil),         // 9: mail.SendMailRequest
-	(*SendMailResponse)(nil),        // 10: mail.SendMailResponse
+	(ConsistencyIssueKind)(0),             // 0: mail.ConsistencyIssueKind
+	(DeliveryStatus)(0),                   // 1: mail.DeliveryStatus
+	(*MailMessage)(nil),                   // 2: mail.MailMessage
+	(*Attachment)(nil),                    // 3: mail.Attachment
+	(*RegisterMailboxRequest)(nil),        // 4: mail.RegisterMailboxRequest
+	(*RegisterMailboxResponse)(nil),       // 5: mail.RegisterMailboxResponse
+	(*LookupMailboxRequest)(nil),          // 6: mail.LookupMailboxRequest
+	(*LookupMailboxResponse)(nil),         // 7: mail.LookupMailboxResponse
+	(*DeregisterMailboxRequest)(nil),      // 8: mail.DeregisterMailboxRequest
+	(*DeregisterMailboxResponse)(nil),     // 9: mail.DeregisterMailboxResponse
+	(*ListMailboxesRequest)(nil),          // 10: mail.ListMailboxesRequest
+	(*MailboxEntry)(nil),                  // 11: mail.MailboxEntry
+	(*ListMailboxesResponse)(nil),         // 12: mail.ListMailboxesResponse
+	(*CompareAndSwapMailboxRequest)(nil),  // 13: mail.CompareAndSwapMailboxRequest
+	(*CompareAndSwapMailboxResponse)(nil), // 14: mail.CompareAndSwapMailboxResponse
+	(*CheckConsistencyRequest)(nil),       // 15: mail.CheckConsistencyRequest
+	(*ConsistencyIssue)(nil),              // 16: mail.ConsistencyIssue
+	(*CheckConsistencyResponse)(nil),      // 17: mail.CheckConsistencyResponse
+	(*RegisterListRequest)(nil),           // 18: mail.RegisterListRequest
+	(*RegisterListResponse)(nil),          // 19: mail.RegisterListResponse
+	(*ExpandListRequest)(nil),             // 20: mail.ExpandListRequest
+	(*ExpandListResponse)(nil),            // 21: mail.ExpandListResponse
+	(*ExpandListsRequest)(nil),            // 22: mail.ExpandListsRequest
+	(*ExpandListsResponse)(nil),           // 23: mail.ExpandListsResponse
+	(*AddManagedDomainRequest)(nil),       // 24: mail.AddManagedDomainRequest
+	(*RemoveManagedDomainRequest)(nil),    // 25: mail.RemoveManagedDomainRequest
+	(*ManagedDomainResponse)(nil),         // 26: mail.ManagedDomainResponse
+	(*NameserverInfoRequest)(nil),         // 27: mail.NameserverInfoRequest
+	(*NameserverInfoResponse)(nil),        // 28: mail.NameserverInfoResponse
+	(*NameserverHealthRequest)(nil),       // 29: mail.NameserverHealthRequest
+	(*NameserverHealthResponse)(nil),      // 30: mail.NameserverHealthResponse
+	(*ReceiveMailRequest)(nil),            // 31: mail.ReceiveMailRequest
+	(*ReceiveMailResponse)(nil),           // 32: mail.ReceiveMailResponse
+	(*GetMailRequest)(nil),                // 33: mail.GetMailRequest
+	(*GetMailResponse)(nil),               // 34: mail.GetMailResponse
+	(*StreamMailRequest)(nil),             // 35: mail.StreamMailRequest
+	(*WaitForMailRequest)(nil),            // 36: mail.WaitForMailRequest
+	(*WatchMailRequest)(nil),              // 37: mail.WatchMailRequest
+	(*DeleteMailRequest)(nil),             // 38: mail.DeleteMailRequest
+	(*DeleteMailResponse)(nil),            // 39: mail.DeleteMailResponse
+	(*SetPasswordRequest)(nil),            // 40: mail.SetPasswordRequest
+	(*SetPasswordResponse)(nil),           // 41: mail.SetPasswordResponse
+	(*UndeleteMailRequest)(nil),           // 42: mail.UndeleteMailRequest
+	(*UndeleteMailResponse)(nil),          // 43: mail.UndeleteMailResponse
+	(*MarkReadRequest)(nil),               // 44: mail.MarkReadRequest
+	(*MarkReadResponse)(nil),              // 45: mail.MarkReadResponse
+	(*SearchMailRequest)(nil),             // 46: mail.SearchMailRequest
+	(*SearchMailResponse)(nil),            // 47: mail.SearchMailResponse
+	(*CanAcceptRequest)(nil),              // 48: mail.CanAcceptRequest
+	(*CanAcceptResponse)(nil),             // 49: mail.CanAcceptResponse
+	(*ExportMailboxRequest)(nil),          // 50: mail.ExportMailboxRequest
+	(*MailboxDumpEntry)(nil),              // 51: mail.MailboxDumpEntry
+	(*ImportMailboxResponse)(nil),         // 52: mail.ImportMailboxResponse
+	(*MailboxInfoRequest)(nil),            // 53: mail.MailboxInfoRequest
+	(*MailboxInfoResponse)(nil),           // 54: mail.MailboxInfoResponse
+	(*SendMailRequest)(nil),               // 55: mail.SendMailRequest
+	(*SendMailResponse)(nil),              // 56: mail.SendMailResponse
+	(*SendMailBulkRequest)(nil),           // 57: mail.SendMailBulkRequest
+	(*RecipientResult)(nil),               // 58: mail.RecipientResult
+	(*DeliveryReportRequest)(nil),         // 59: mail.DeliveryReportRequest
+	(*DeliveryReportResponse)(nil),        // 60: mail.DeliveryReportResponse
+	(*GetDeliveryStatusRequest)(nil),      // 61: mail.GetDeliveryStatusRequest
+	(*GetDeliveryStatusResponse)(nil),     // 62: mail.GetDeliveryStatusResponse
+	(*PauseDeliveryRequest)(nil),          // 63: mail.PauseDeliveryRequest
+	(*ResumeDeliveryRequest)(nil),         // 64: mail.ResumeDeliveryRequest
+	(*FlushQueueRequest)(nil),             // 65: mail.FlushQueueRequest
+	(*QueueStatusRequest)(nil),            // 66: mail.QueueStatusRequest
+	(*QueueStatusResponse)(nil),           // 67: mail.QueueStatusResponse
+	(*TransferServerInfoRequest)(nil),     // 68: mail.TransferServerInfoRequest
+	(*TransferServerInfoResponse)(nil),    // 69: mail.TransferServerInfoResponse
+	(*DeadLetter)(nil),                    // 70: mail.DeadLetter
+	(*ListDeadLettersRequest)(nil),        // 71: mail.ListDeadLettersRequest
+	(*ListDeadLettersResponse)(nil),       // 72: mail.ListDeadLettersResponse
+	(*RetryDeadLetterRequest)(nil),        // 73: mail.RetryDeadLetterRequest
+	(*RetryDeadLetterResponse)(nil),       // 74: mail.RetryDeadLetterResponse
 }
 var file_proto_mail_proto_depIdxs = []int32{
-	0,  // 0: mail.ReceiveMailRequest.message:type_name -> mail.MailMessage
-	0,  // 1: mail.GetMailResponse.messages:type_name -> mail.MailMessage
-	0,  // 2: mail.SendMailRequest.message:type_name -> mail.MailMessage
-	1,  // 3: mail.Nameserver.RegisterMailbox:input_type -> mail.RegisterMailboxRequest
-	3,  // 4: mail.Nameserver.LookupMailbox:input_type -> mail.LookupMailboxRequest
-	5,  // 5: mail.Mailbox.ReceiveMail:input_type -> mail.ReceiveMailRequest
-	7,  // 6: mail.Mailbox.GetMail:input_type -> mail.GetMailRequest
-	9,  // 7: mail.TransferServer.SendMail:input_type -> mail.SendMailRequest
-	2,  // 8: mail.Nameserver.RegisterMailbox:output_type -> mail.RegisterMailboxResponse
-	4,  // 9: mail.Nameserver.LookupMailbox:output_type -> mail.LookupMailboxResponse
-	6,  // 10: mail.Mailbox.ReceiveMail:output_type -> mail.ReceiveMailResponse
-	8,  // 11: mail.Mailbox.GetMail:output_type -> mail.GetMailResponse
-	10, // 12: mail.TransferServer.SendMail:output_type -> mail.SendMailResponse
-	8,  // [8:13] is the sub-list for method output_type
-	3,  // [3:8] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	3,  // 0: mail.MailMessage.attachments:type_name -> mail.Attachment
+	11, // 1: mail.ListMailboxesResponse.entries:type_name -> mail.MailboxEntry
+	0,  // 2: mail.ConsistencyIssue.kind:type_name -> mail.ConsistencyIssueKind
+	16, // 3: mail.CheckConsistencyResponse.issues:type_name -> mail.ConsistencyIssue
+	21, // 4: mail.ExpandListsResponse.expansions:type_name -> mail.ExpandListResponse
+	2,  // 5: mail.ReceiveMailRequest.message:type_name -> mail.MailMessage
+	2,  // 6: mail.GetMailResponse.messages:type_name -> mail.MailMessage
+	2,  // 7: mail.SearchMailResponse.messages:type_name -> mail.MailMessage
+	2,  // 8: mail.MailboxDumpEntry.message:type_name -> mail.MailMessage
+	2,  // 9: mail.SendMailRequest.message:type_name -> mail.MailMessage
+	58, // 10: mail.SendMailResponse.results:type_name -> mail.RecipientResult
+	2,  // 11: mail.SendMailBulkRequest.message:type_name -> mail.MailMessage
+	58, // 12: mail.DeliveryReportResponse.results:type_name -> mail.RecipientResult
+	1,  // 13: mail.GetDeliveryStatusResponse.status:type_name -> mail.DeliveryStatus
+	58, // 14: mail.GetDeliveryStatusResponse.results:type_name -> mail.RecipientResult
+	67, // 15: mail.TransferServerInfoResponse.queue:type_name -> mail.QueueStatusResponse
+	2,  // 16: mail.DeadLetter.message:type_name -> mail.MailMessage
+	70, // 17: mail.ListDeadLettersResponse.dead_letters:type_name -> mail.DeadLetter
+	70, // 18: mail.RetryDeadLetterResponse.dead_letter:type_name -> mail.DeadLetter
+	4,  // 19: mail.Nameserver.RegisterMailbox:input_type -> mail.RegisterMailboxRequest
+	6,  // 20: mail.Nameserver.LookupMailbox:input_type -> mail.LookupMailboxRequest
+	8,  // 21: mail.Nameserver.DeregisterMailbox:input_type -> mail.DeregisterMailboxRequest
+	10, // 22: mail.Nameserver.ListMailboxes:input_type -> mail.ListMailboxesRequest
+	13, // 23: mail.Nameserver.CompareAndSwapMailbox:input_type -> mail.CompareAndSwapMailboxRequest
+	15, // 24: mail.Nameserver.CheckConsistency:input_type -> mail.CheckConsistencyRequest
+	27, // 25: mail.Nameserver.Info:input_type -> mail.NameserverInfoRequest
+	29, // 26: mail.Nameserver.Health:input_type -> mail.NameserverHealthRequest
+	24, // 27: mail.Nameserver.AddManagedDomain:input_type -> mail.AddManagedDomainRequest
+	25, // 28: mail.Nameserver.RemoveManagedDomain:input_type -> mail.RemoveManagedDomainRequest
+	18, // 29: mail.Nameserver.RegisterList:input_type -> mail.RegisterListRequest
+	20, // 30: mail.Nameserver.ExpandList:input_type -> mail.ExpandListRequest
+	22, // 31: mail.Nameserver.ExpandLists:input_type -> mail.ExpandListsRequest
+	31, // 32: mail.Mailbox.ReceiveMail:input_type -> mail.ReceiveMailRequest
+	33, // 33: mail.Mailbox.GetMail:input_type -> mail.GetMailRequest
+	35, // 34: mail.Mailbox.StreamMail:input_type -> mail.StreamMailRequest
+	38, // 35: mail.Mailbox.DeleteMail:input_type -> mail.DeleteMailRequest
+	36, // 36: mail.Mailbox.WaitForMail:input_type -> mail.WaitForMailRequest
+	37, // 37: mail.Mailbox.WatchMail:input_type -> mail.WatchMailRequest
+	42, // 38: mail.Mailbox.UndeleteMail:input_type -> mail.UndeleteMailRequest
+	44, // 39: mail.Mailbox.MarkRead:input_type -> mail.MarkReadRequest
+	46, // 40: mail.Mailbox.SearchMail:input_type -> mail.SearchMailRequest
+	53, // 41: mail.Mailbox.Info:input_type -> mail.MailboxInfoRequest
+	48, // 42: mail.Mailbox.CanAccept:input_type -> mail.CanAcceptRequest
+	50, // 43: mail.Mailbox.ExportMailbox:input_type -> mail.ExportMailboxRequest
+	51, // 44: mail.Mailbox.ImportMailbox:input_type -> mail.MailboxDumpEntry
+	40, // 45: mail.Mailbox.SetPassword:input_type -> mail.SetPasswordRequest
+	55, // 46: mail.TransferServer.SendMail:input_type -> mail.SendMailRequest
+	57, // 47: mail.TransferServer.SendMailBulk:input_type -> mail.SendMailBulkRequest
+	59, // 48: mail.TransferServer.DeliveryReport:input_type -> mail.DeliveryReportRequest
+	61, // 49: mail.TransferServer.GetDeliveryStatus:input_type -> mail.GetDeliveryStatusRequest
+	63, // 50: mail.TransferServer.PauseDelivery:input_type -> mail.PauseDeliveryRequest
+	64, // 51: mail.TransferServer.ResumeDelivery:input_type -> mail.ResumeDeliveryRequest
+	65, // 52: mail.TransferServer.FlushQueue:input_type -> mail.FlushQueueRequest
+	66, // 53: mail.TransferServer.QueueStatus:input_type -> mail.QueueStatusRequest
+	68, // 54: mail.TransferServer.Info:input_type -> mail.TransferServerInfoRequest
+	71, // 55: mail.TransferServer.ListDeadLetters:input_type -> mail.ListDeadLettersRequest
+	73, // 56: mail.TransferServer.RetryDeadLetter:input_type -> mail.RetryDeadLetterRequest
+	5,  // 57: mail.Nameserver.RegisterMailbox:output_type -> mail.RegisterMailboxResponse
+	7,  // 58: mail.Nameserver.LookupMailbox:output_type -> mail.LookupMailboxResponse
+	9,  // 59: mail.Nameserver.DeregisterMailbox:output_type -> mail.DeregisterMailboxResponse
+	12, // 60: mail.Nameserver.ListMailboxes:output_type -> mail.ListMailboxesResponse
+	14, // 61: mail.Nameserver.CompareAndSwapMailbox:output_type -> mail.CompareAndSwapMailboxResponse
+	17, // 62: mail.Nameserver.CheckConsistency:output_type -> mail.CheckConsistencyResponse
+	28, // 63: mail.Nameserver.Info:output_type -> mail.NameserverInfoResponse
+	30, // 64: mail.Nameserver.Health:output_type -> mail.NameserverHealthResponse
+	26, // 65: mail.Nameserver.AddManagedDomain:output_type -> mail.ManagedDomainResponse
+	26, // 66: mail.Nameserver.RemoveManagedDomain:output_type -> mail.ManagedDomainResponse
+	19, // 67: mail.Nameserver.RegisterList:output_type -> mail.RegisterListResponse
+	21, // 68: mail.Nameserver.ExpandList:output_type -> mail.ExpandListResponse
+	23, // 69: mail.Nameserver.ExpandLists:output_type -> mail.ExpandListsResponse
+	32, // 70: mail.Mailbox.ReceiveMail:output_type -> mail.ReceiveMailResponse
+	34, // 71: mail.Mailbox.GetMail:output_type -> mail.GetMailResponse
+	2,  // 72: mail.Mailbox.StreamMail:output_type -> mail.MailMessage
+	39, // 73: mail.Mailbox.DeleteMail:output_type -> mail.DeleteMailResponse
+	34, // 74: mail.Mailbox.WaitForMail:output_type -> mail.GetMailResponse
+	2,  // 75: mail.Mailbox.WatchMail:output_type -> mail.MailMessage
+	43, // 76: mail.Mailbox.UndeleteMail:output_type -> mail.UndeleteMailResponse
+	45, // 77: mail.Mailbox.MarkRead:output_type -> mail.MarkReadResponse
+	47, // 78: mail.Mailbox.SearchMail:output_type -> mail.SearchMailResponse
+	54, // 79: mail.Mailbox.Info:output_type -> mail.MailboxInfoResponse
+	49, // 80: mail.Mailbox.CanAccept:output_type -> mail.CanAcceptResponse
+	51, // 81: mail.Mailbox.ExportMailbox:output_type -> mail.MailboxDumpEntry
+	52, // 82: mail.Mailbox.ImportMailbox:output_type -> mail.ImportMailboxResponse
+	41, // 83: mail.Mailbox.SetPassword:output_type -> mail.SetPasswordResponse
+	56, // 84: mail.TransferServer.SendMail:output_type -> mail.SendMailResponse
+	58, // 85: mail.TransferServer.SendMailBulk:output_type -> mail.RecipientResult
+	60, // 86: mail.TransferServer.DeliveryReport:output_type -> mail.DeliveryReportResponse
+	62, // 87: mail.TransferServer.GetDeliveryStatus:output_type -> mail.GetDeliveryStatusResponse
+	67, // 88: mail.TransferServer.PauseDelivery:output_type -> mail.QueueStatusResponse
+	67, // 89: mail.TransferServer.ResumeDelivery:output_type -> mail.QueueStatusResponse
+	67, // 90: mail.TransferServer.FlushQueue:output_type -> mail.QueueStatusResponse
+	67, // 91: mail.TransferServer.QueueStatus:output_type -> mail.QueueStatusResponse
+	69, // 92: mail.TransferServer.Info:output_type -> mail.TransferServerInfoResponse
+	72, // 93: mail.TransferServer.ListDeadLetters:output_type -> mail.ListDeadLettersResponse
+	74, // 94: mail.TransferServer.RetryDeadLetter:output_type -> mail.RetryDeadLetterResponse
+	57, // [57:95] is the sub-list for method output_type
+	19, // [19:57] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_proto_mail_proto_init() }
@@ -676,18 +4724,26 @@ func file_proto_mail_proto_init() {
 	if File_proto_mail_proto != nil {
 		return
 	}
+	file_proto_mail_proto_msgTypes[31].OneofWrappers = []any{}
+	file_proto_mail_proto_msgTypes[33].OneofWrappers = []any{}
+	file_proto_mail_proto_msgTypes[53].OneofWrappers = []any{}
+	file_proto_mail_proto_msgTypes[55].OneofWrappers = []any{
+		(*SendMailBulkRequest_Message)(nil),
+		(*SendMailBulkRequest_RecipientEmail)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_mail_proto_rawDesc), len(file_proto_mail_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      2,
+			NumMessages:   73,
 			NumExtensions: 0,
 			NumServices:   3,
 		},
 		GoTypes:           file_proto_mail_proto_goTypes,
 		DependencyIndexes: file_proto_mail_proto_depIdxs,
+		EnumInfos:         file_proto_mail_proto_enumTypes,
 		MessageInfos:      file_proto_mail_proto_msgTypes,
 	}.Build()
 	File_proto_mail_proto = out.File
@@ -19,8 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Nameserver_RegisterMailbox_FullMethodName = "/mail.Nameserver/RegisterMailbox"
-	Nameserver_LookupMailbox_FullMethodName   = "/mail.Nameserver/LookupMailbox"
+	Nameserver_RegisterMailbox_FullMethodName       = "/mail.Nameserver/RegisterMailbox"
+	Nameserver_LookupMailbox_FullMethodName         = "/mail.Nameserver/LookupMailbox"
+	Nameserver_DeregisterMailbox_FullMethodName     = "/mail.Nameserver/DeregisterMailbox"
+	Nameserver_ListMailboxes_FullMethodName         = "/mail.Nameserver/ListMailboxes"
+	Nameserver_CompareAndSwapMailbox_FullMethodName = "/mail.Nameserver/CompareAndSwapMailbox"
+	Nameserver_CheckConsistency_FullMethodName      = "/mail.Nameserver/CheckConsistency"
+	Nameserver_Info_FullMethodName                  = "/mail.Nameserver/Info"
+	Nameserver_Health_FullMethodName                = "/mail.Nameserver/Health"
+	Nameserver_AddManagedDomain_FullMethodName      = "/mail.Nameserver/AddManagedDomain"
+	Nameserver_RemoveManagedDomain_FullMethodName   = "/mail.Nameserver/RemoveManagedDomain"
+	Nameserver_RegisterList_FullMethodName          = "/mail.Nameserver/RegisterList"
+	Nameserver_ExpandList_FullMethodName            = "/mail.Nameserver/ExpandList"
+	Nameserver_ExpandLists_FullMethodName           = "/mail.Nameserver/ExpandLists"
 )
 
 // NameserverClient is the client API for Nameserver service.
@@ -33,6 +44,31 @@ type NameserverClient interface {
 	RegisterMailbox(ctx context.Context, in *RegisterMailboxRequest, opts ...grpc.CallOption) (*RegisterMailboxResponse, error)
 	// LookupMailbox looks up the mailbox address for a given email address.
 	LookupMailbox(ctx context.Context, in *LookupMailboxRequest, opts ...grpc.CallOption) (*LookupMailboxResponse, error)
+	// DeregisterMailbox removes the registration of an email address, including its replicas.
+	DeregisterMailbox(ctx context.Context, in *DeregisterMailboxRequest, opts ...grpc.CallOption) (*DeregisterMailboxResponse, error)
+	// ListMailboxes (admin) returns all registrations of a managed domain, sorted by email address.
+	ListMailboxes(ctx context.Context, in *ListMailboxesRequest, opts ...grpc.CallOption) (*ListMailboxesResponse, error)
+	// CompareAndSwapMailbox atomically sets the mailbox address of an email address to new_address, but only
+	// if it currently is expected_old_address; otherwise it fails with FailedPrecondition naming the current value.
+	CompareAndSwapMailbox(ctx context.Context, in *CompareAndSwapMailboxRequest, opts ...grpc.CallOption) (*CompareAndSwapMailboxResponse, error)
+	// CheckConsistency scans all registrations and reports anomalies.
+	CheckConsistency(ctx context.Context, in *CheckConsistencyRequest, opts ...grpc.CallOption) (*CheckConsistencyResponse, error)
+	// Info reports the number of registrations and the managed domains.
+	Info(ctx context.Context, in *NameserverInfoRequest, opts ...grpc.CallOption) (*NameserverInfoResponse, error)
+	// Health reports whether the Nameserver is fully serving, and the domains it manages.
+	Health(ctx context.Context, in *NameserverHealthRequest, opts ...grpc.CallOption) (*NameserverHealthResponse, error)
+	// AddManagedDomain (admin) makes this Nameserver responsible for a further domain.
+	AddManagedDomain(ctx context.Context, in *AddManagedDomainRequest, opts ...grpc.CallOption) (*ManagedDomainResponse, error)
+	// RemoveManagedDomain (admin) ends responsibility for a domain. Its registrations stay resolvable
+	// unless purge is set, but no new ones are accepted.
+	RemoveManagedDomain(ctx context.Context, in *RemoveManagedDomainRequest, opts ...grpc.CallOption) (*ManagedDomainResponse, error)
+	// RegisterList registers (or replaces) a distribution list address expanding to the given members,
+	// which may be mailboxes or other lists.
+	RegisterList(ctx context.Context, in *RegisterListRequest, opts ...grpc.CallOption) (*RegisterListResponse, error)
+	// ExpandList resolves a distribution list, including nested lists, to the addresses of its members.
+	ExpandList(ctx context.Context, in *ExpandListRequest, opts ...grpc.CallOption) (*ExpandListResponse, error)
+	// ExpandLists resolves several addresses with a single call, as ExpandList does for one.
+	ExpandLists(ctx context.Context, in *ExpandListsRequest, opts ...grpc.CallOption) (*ExpandListsResponse, error)
 }
 
 type nameserverClient struct {
@@ -63,6 +99,116 @@ func (c *nameserverClient) LookupMailbox(ctx context.Context, in *LookupMailboxR
 	return out, nil
 }
 
+func (c *nameserverClient) DeregisterMailbox(ctx context.Context, in *DeregisterMailboxRequest, opts ...grpc.CallOption) (*DeregisterMailboxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeregisterMailboxResponse)
+	err := c.cc.Invoke(ctx, Nameserver_DeregisterMailbox_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) ListMailboxes(ctx context.Context, in *ListMailboxesRequest, opts ...grpc.CallOption) (*ListMailboxesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMailboxesResponse)
+	err := c.cc.Invoke(ctx, Nameserver_ListMailboxes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) CompareAndSwapMailbox(ctx context.Context, in *CompareAndSwapMailboxRequest, opts ...grpc.CallOption) (*CompareAndSwapMailboxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompareAndSwapMailboxResponse)
+	err := c.cc.Invoke(ctx, Nameserver_CompareAndSwapMailbox_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) CheckConsistency(ctx context.Context, in *CheckConsistencyRequest, opts ...grpc.CallOption) (*CheckConsistencyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckConsistencyResponse)
+	err := c.cc.Invoke(ctx, Nameserver_CheckConsistency_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) Info(ctx context.Context, in *NameserverInfoRequest, opts ...grpc.CallOption) (*NameserverInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NameserverInfoResponse)
+	err := c.cc.Invoke(ctx, Nameserver_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) Health(ctx context.Context, in *NameserverHealthRequest, opts ...grpc.CallOption) (*NameserverHealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NameserverHealthResponse)
+	err := c.cc.Invoke(ctx, Nameserver_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) AddManagedDomain(ctx context.Context, in *AddManagedDomainRequest, opts ...grpc.CallOption) (*ManagedDomainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ManagedDomainResponse)
+	err := c.cc.Invoke(ctx, Nameserver_AddManagedDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) RemoveManagedDomain(ctx context.Context, in *RemoveManagedDomainRequest, opts ...grpc.CallOption) (*ManagedDomainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ManagedDomainResponse)
+	err := c.cc.Invoke(ctx, Nameserver_RemoveManagedDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) RegisterList(ctx context.Context, in *RegisterListRequest, opts ...grpc.CallOption) (*RegisterListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterListResponse)
+	err := c.cc.Invoke(ctx, Nameserver_RegisterList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) ExpandList(ctx context.Context, in *ExpandListRequest, opts ...grpc.CallOption) (*ExpandListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpandListResponse)
+	err := c.cc.Invoke(ctx, Nameserver_ExpandList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nameserverClient) ExpandLists(ctx context.Context, in *ExpandListsRequest, opts ...grpc.CallOption) (*ExpandListsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpandListsResponse)
+	err := c.cc.Invoke(ctx, Nameserver_ExpandLists_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NameserverServer is the server API for Nameserver service.
 // All implementations must embed UnimplementedNameserverServer
 // for forward compatibility.
@@ -73,6 +219,31 @@ type NameserverServer interface {
 	RegisterMailbox(context.Context, *RegisterMailboxRequest) (*RegisterMailboxResponse, error)
 	// LookupMailbox looks up the mailbox address for a given email address.
 	LookupMailbox(context.Context, *LookupMailboxRequest) (*LookupMailboxResponse, error)
+	// DeregisterMailbox removes the registration of an email address, including its replicas.
+	DeregisterMailbox(context.Context, *DeregisterMailboxRequest) (*DeregisterMailboxResponse, error)
+	// ListMailboxes (admin) returns all registrations of a managed domain, sorted by email address.
+	ListMailboxes(context.Context, *ListMailboxesRequest) (*ListMailboxesResponse, error)
+	// CompareAndSwapMailbox atomically sets the mailbox address of an email address to new_address, but only
+	// if it currently is expected_old_address; otherwise it fails with FailedPrecondition naming the current value.
+	CompareAndSwapMailbox(context.Context, *CompareAndSwapMailboxRequest) (*CompareAndSwapMailboxResponse, error)
+	// CheckConsistency scans all registrations and reports anomalies.
+	CheckConsistency(context.Context, *CheckConsistencyRequest) (*CheckConsistencyResponse, error)
+	// Info reports the number of registrations and the managed domains.
+	Info(context.Context, *NameserverInfoRequest) (*NameserverInfoResponse, error)
+	// Health reports whether the Nameserver is fully serving, and the domains it manages.
+	Health(context.Context, *NameserverHealthRequest) (*NameserverHealthResponse, error)
+	// AddManagedDomain (admin) makes this Nameserver responsible for a further domain.
+	AddManagedDomain(context.Context, *AddManagedDomainRequest) (*ManagedDomainResponse, error)
+	// RemoveManagedDomain (admin) ends responsibility for a domain. Its registrations stay resolvable
+	// unless purge is set, but no new ones are accepted.
+	RemoveManagedDomain(context.Context, *RemoveManagedDomainRequest) (*ManagedDomainResponse, error)
+	// RegisterList registers (or replaces) a distribution list address expanding to the given members,
+	// which may be mailboxes or other lists.
+	RegisterList(context.Context, *RegisterListRequest) (*RegisterListResponse, error)
+	// ExpandList resolves a distribution list, including nested lists, to the addresses of its members.
+	ExpandList(context.Context, *ExpandListRequest) (*ExpandListResponse, error)
+	// ExpandLists resolves several addresses with a single call, as ExpandList does for one.
+	ExpandLists(context.Context, *ExpandListsRequest) (*ExpandListsResponse, error)
 	mustEmbedUnimplementedNameserverServer()
 }
 
@@ -89,6 +260,39 @@ func (UnimplementedNameserverServer) RegisterMailbox(context.Context, *RegisterM
 func (UnimplementedNameserverServer) LookupMailbox(context.Context, *LookupMailboxRequest) (*LookupMailboxResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LookupMailbox not implemented")
 }
+func (UnimplementedNameserverServer) DeregisterMailbox(context.Context, *DeregisterMailboxRequest) (*DeregisterMailboxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeregisterMailbox not implemented")
+}
+func (UnimplementedNameserverServer) ListMailboxes(context.Context, *ListMailboxesRequest) (*ListMailboxesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMailboxes not implemented")
+}
+func (UnimplementedNameserverServer) CompareAndSwapMailbox(context.Context, *CompareAndSwapMailboxRequest) (*CompareAndSwapMailboxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwapMailbox not implemented")
+}
+func (UnimplementedNameserverServer) CheckConsistency(context.Context, *CheckConsistencyRequest) (*CheckConsistencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckConsistency not implemented")
+}
+func (UnimplementedNameserverServer) Info(context.Context, *NameserverInfoRequest) (*NameserverInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedNameserverServer) Health(context.Context, *NameserverHealthRequest) (*NameserverHealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedNameserverServer) AddManagedDomain(context.Context, *AddManagedDomainRequest) (*ManagedDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddManagedDomain not implemented")
+}
+func (UnimplementedNameserverServer) RemoveManagedDomain(context.Context, *RemoveManagedDomainRequest) (*ManagedDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveManagedDomain not implemented")
+}
+func (UnimplementedNameserverServer) RegisterList(context.Context, *RegisterListRequest) (*RegisterListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterList not implemented")
+}
+func (UnimplementedNameserverServer) ExpandList(context.Context, *ExpandListRequest) (*ExpandListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpandList not implemented")
+}
+func (UnimplementedNameserverServer) ExpandLists(context.Context, *ExpandListsRequest) (*ExpandListsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpandLists not implemented")
+}
 func (UnimplementedNameserverServer) mustEmbedUnimplementedNameserverServer() {}
 func (UnimplementedNameserverServer) testEmbeddedByValue()                    {}
 
@@ -146,6 +350,204 @@ func _Nameserver_LookupMailbox_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Nameserver_DeregisterMailbox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterMailboxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).DeregisterMailbox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_DeregisterMailbox_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).DeregisterMailbox(ctx, req.(*DeregisterMailboxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_ListMailboxes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMailboxesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).ListMailboxes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_ListMailboxes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).ListMailboxes(ctx, req.(*ListMailboxesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_CompareAndSwapMailbox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapMailboxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).CompareAndSwapMailbox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_CompareAndSwapMailbox_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).CompareAndSwapMailbox(ctx, req.(*CompareAndSwapMailboxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_CheckConsistency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckConsistencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).CheckConsistency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_CheckConsistency_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).CheckConsistency(ctx, req.(*CheckConsistencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NameserverInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).Info(ctx, req.(*NameserverInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NameserverHealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).Health(ctx, req.(*NameserverHealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_AddManagedDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddManagedDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).AddManagedDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_AddManagedDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).AddManagedDomain(ctx, req.(*AddManagedDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_RemoveManagedDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveManagedDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).RemoveManagedDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_RemoveManagedDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).RemoveManagedDomain(ctx, req.(*RemoveManagedDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_RegisterList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).RegisterList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_RegisterList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).RegisterList(ctx, req.(*RegisterListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_ExpandList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpandListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).ExpandList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_ExpandList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).ExpandList(ctx, req.(*ExpandListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Nameserver_ExpandLists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpandListsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NameserverServer).ExpandLists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Nameserver_ExpandLists_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NameserverServer).ExpandLists(ctx, req.(*ExpandListsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Nameserver_ServiceDesc is the grpc.ServiceDesc for Nameserver service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,14 +563,70 @@ var Nameserver_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "LookupMailbox",
 			Handler:    _Nameserver_LookupMailbox_Handler,
 		},
+		{
+			MethodName: "DeregisterMailbox",
+			Handler:    _Nameserver_DeregisterMailbox_Handler,
+		},
+		{
+			MethodName: "ListMailboxes",
+			Handler:    _Nameserver_ListMailboxes_Handler,
+		},
+		{
+			MethodName: "CompareAndSwapMailbox",
+			Handler:    _Nameserver_CompareAndSwapMailbox_Handler,
+		},
+		{
+			MethodName: "CheckConsistency",
+			Handler:    _Nameserver_CheckConsistency_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Nameserver_Info_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _Nameserver_Health_Handler,
+		},
+		{
+			MethodName: "AddManagedDomain",
+			Handler:    _Nameserver_AddManagedDomain_Handler,
+		},
+		{
+			MethodName: "RemoveManagedDomain",
+			Handler:    _Nameserver_RemoveManagedDomain_Handler,
+		},
+		{
+			MethodName: "RegisterList",
+			Handler:    _Nameserver_RegisterList_Handler,
+		},
+		{
+			MethodName: "ExpandList",
+			Handler:    _Nameserver_ExpandList_Handler,
+		},
+		{
+			MethodName: "ExpandLists",
+			Handler:    _Nameserver_ExpandLists_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/mail.proto",
 }
 
 const (
-	Mailbox_ReceiveMail_FullMethodName = "/mail.Mailbox/ReceiveMail"
-	Mailbox_GetMail_FullMethodName     = "/mail.Mailbox/GetMail"
+	Mailbox_ReceiveMail_FullMethodName   = "/mail.Mailbox/ReceiveMail"
+	Mailbox_GetMail_FullMethodName       = "/mail.Mailbox/GetMail"
+	Mailbox_StreamMail_FullMethodName    = "/mail.Mailbox/StreamMail"
+	Mailbox_DeleteMail_FullMethodName    = "/mail.Mailbox/DeleteMail"
+	Mailbox_WaitForMail_FullMethodName   = "/mail.Mailbox/WaitForMail"
+	Mailbox_WatchMail_FullMethodName     = "/mail.Mailbox/WatchMail"
+	Mailbox_UndeleteMail_FullMethodName  = "/mail.Mailbox/UndeleteMail"
+	Mailbox_MarkRead_FullMethodName      = "/mail.Mailbox/MarkRead"
+	Mailbox_SearchMail_FullMethodName    = "/mail.Mailbox/SearchMail"
+	Mailbox_Info_FullMethodName          = "/mail.Mailbox/Info"
+	Mailbox_CanAccept_FullMethodName     = "/mail.Mailbox/CanAccept"
+	Mailbox_ExportMailbox_FullMethodName = "/mail.Mailbox/ExportMailbox"
+	Mailbox_ImportMailbox_FullMethodName = "/mail.Mailbox/ImportMailbox"
+	Mailbox_SetPassword_FullMethodName   = "/mail.Mailbox/SetPassword"
 )
 
 // MailboxClient is the client API for Mailbox service.
@@ -181,6 +639,37 @@ type MailboxClient interface {
 	ReceiveMail(ctx context.Context, in *ReceiveMailRequest, opts ...grpc.CallOption) (*ReceiveMailResponse, error)
 	// GetMail retrieves mail messages for a user.
 	GetMail(ctx context.Context, in *GetMailRequest, opts ...grpc.CallOption) (*GetMailResponse, error)
+	// StreamMail is a streaming GetMail for large inboxes, sending the messages one at a time. Clear-on-read
+	// removes them from the inbox only after all of them have been sent.
+	StreamMail(ctx context.Context, in *StreamMailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailMessage], error)
+	// DeleteMail acknowledges messages by ID, removing them from the user's inbox.
+	DeleteMail(ctx context.Context, in *DeleteMailRequest, opts ...grpc.CallOption) (*DeleteMailResponse, error)
+	// WaitForMail long-polls until the user's inbox holds mail newer than after_message_id or the timeout
+	// expires, then returns that mail without removing it from the inbox.
+	WaitForMail(ctx context.Context, in *WaitForMailRequest, opts ...grpc.CallOption) (*GetMailResponse, error)
+	// WatchMail keeps the stream open and pushes every message stored for the user from now on, without
+	// removing it from the inbox.
+	WatchMail(ctx context.Context, in *WatchMailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailMessage], error)
+	// UndeleteMail restores retrieved messages from the user's trash before their retention expires.
+	UndeleteMail(ctx context.Context, in *UndeleteMailRequest, opts ...grpc.CallOption) (*UndeleteMailResponse, error)
+	// MarkRead flags messages in the user's inbox as read by ID. New mail starts out unread.
+	MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error)
+	// SearchMail returns the messages in the user's inbox whose sender or subject (and optionally body)
+	// contain the query, ignoring case. The inbox is left untouched.
+	SearchMail(ctx context.Context, in *SearchMailRequest, opts ...grpc.CallOption) (*SearchMailResponse, error)
+	// Info reports the number of users and stored messages of this Mailbox.
+	Info(ctx context.Context, in *MailboxInfoRequest, opts ...grpc.CallOption) (*MailboxInfoResponse, error)
+	// CanAccept reports, without transferring the message, whether ReceiveMail would currently accept
+	// mail for the recipient. It is unauthenticated, so blocked senders are left to ReceiveMail.
+	CanAccept(ctx context.Context, in *CanAcceptRequest, opts ...grpc.CallOption) (*CanAcceptResponse, error)
+	// ExportMailbox (admin) streams a point-in-time dump of all users' messages, one entry per message.
+	ExportMailbox(ctx context.Context, in *ExportMailboxRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailboxDumpEntry], error)
+	// ImportMailbox (admin) loads a dump produced by ExportMailbox, appending each message to its user's
+	// inbox. Messages whose ID is already in that inbox are skipped, so importing a dump twice is harmless.
+	ImportMailbox(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[MailboxDumpEntry, ImportMailboxResponse], error)
+	// SetPassword sets the password of a user, which the Mailbox stores hashed. Once set, the RPCs that read
+	// or modify the user's mail require it as the caller's bearer token, and so does changing it.
+	SetPassword(ctx context.Context, in *SetPasswordRequest, opts ...grpc.CallOption) (*SetPasswordResponse, error)
 }
 
 type mailboxClient struct {
@@ -211,9 +700,159 @@ func (c *mailboxClient) GetMail(ctx context.Context, in *GetMailRequest, opts ..
 	return out, nil
 }
 
-// MailboxServer is the server API for Mailbox service.
-// All implementations must embed UnimplementedMailboxServer
-// for forward compatibility.
+func (c *mailboxClient) StreamMail(ctx context.Context, in *StreamMailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Mailbox_ServiceDesc.Streams[0], Mailbox_StreamMail_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMailRequest, MailMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_StreamMailClient = grpc.ServerStreamingClient[MailMessage]
+
+func (c *mailboxClient) DeleteMail(ctx context.Context, in *DeleteMailRequest, opts ...grpc.CallOption) (*DeleteMailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMailResponse)
+	err := c.cc.Invoke(ctx, Mailbox_DeleteMail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) WaitForMail(ctx context.Context, in *WaitForMailRequest, opts ...grpc.CallOption) (*GetMailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMailResponse)
+	err := c.cc.Invoke(ctx, Mailbox_WaitForMail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) WatchMail(ctx context.Context, in *WatchMailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Mailbox_ServiceDesc.Streams[1], Mailbox_WatchMail_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchMailRequest, MailMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_WatchMailClient = grpc.ServerStreamingClient[MailMessage]
+
+func (c *mailboxClient) UndeleteMail(ctx context.Context, in *UndeleteMailRequest, opts ...grpc.CallOption) (*UndeleteMailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UndeleteMailResponse)
+	err := c.cc.Invoke(ctx, Mailbox_UndeleteMail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarkReadResponse)
+	err := c.cc.Invoke(ctx, Mailbox_MarkRead_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) SearchMail(ctx context.Context, in *SearchMailRequest, opts ...grpc.CallOption) (*SearchMailResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchMailResponse)
+	err := c.cc.Invoke(ctx, Mailbox_SearchMail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) Info(ctx context.Context, in *MailboxInfoRequest, opts ...grpc.CallOption) (*MailboxInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MailboxInfoResponse)
+	err := c.cc.Invoke(ctx, Mailbox_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) CanAccept(ctx context.Context, in *CanAcceptRequest, opts ...grpc.CallOption) (*CanAcceptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CanAcceptResponse)
+	err := c.cc.Invoke(ctx, Mailbox_CanAccept_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailboxClient) ExportMailbox(ctx context.Context, in *ExportMailboxRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MailboxDumpEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Mailbox_ServiceDesc.Streams[2], Mailbox_ExportMailbox_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportMailboxRequest, MailboxDumpEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_ExportMailboxClient = grpc.ServerStreamingClient[MailboxDumpEntry]
+
+func (c *mailboxClient) ImportMailbox(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[MailboxDumpEntry, ImportMailboxResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Mailbox_ServiceDesc.Streams[3], Mailbox_ImportMailbox_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MailboxDumpEntry, ImportMailboxResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_ImportMailboxClient = grpc.ClientStreamingClient[MailboxDumpEntry, ImportMailboxResponse]
+
+func (c *mailboxClient) SetPassword(ctx context.Context, in *SetPasswordRequest, opts ...grpc.CallOption) (*SetPasswordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPasswordResponse)
+	err := c.cc.Invoke(ctx, Mailbox_SetPassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MailboxServer is the server API for Mailbox service.
+// All implementations must embed UnimplementedMailboxServer
+// for forward compatibility.
 //
 // Mailbox Service
 type MailboxServer interface {
@@ -221,6 +860,37 @@ type MailboxServer interface {
 	ReceiveMail(context.Context, *ReceiveMailRequest) (*ReceiveMailResponse, error)
 	// GetMail retrieves mail messages for a user.
 	GetMail(context.Context, *GetMailRequest) (*GetMailResponse, error)
+	// StreamMail is a streaming GetMail for large inboxes, sending the messages one at a time. Clear-on-read
+	// removes them from the inbox only after all of them have been sent.
+	StreamMail(*StreamMailRequest, grpc.ServerStreamingServer[MailMessage]) error
+	// DeleteMail acknowledges messages by ID, removing them from the user's inbox.
+	DeleteMail(context.Context, *DeleteMailRequest) (*DeleteMailResponse, error)
+	// WaitForMail long-polls until the user's inbox holds mail newer than after_message_id or the timeout
+	// expires, then returns that mail without removing it from the inbox.
+	WaitForMail(context.Context, *WaitForMailRequest) (*GetMailResponse, error)
+	// WatchMail keeps the stream open and pushes every message stored for the user from now on, without
+	// removing it from the inbox.
+	WatchMail(*WatchMailRequest, grpc.ServerStreamingServer[MailMessage]) error
+	// UndeleteMail restores retrieved messages from the user's trash before their retention expires.
+	UndeleteMail(context.Context, *UndeleteMailRequest) (*UndeleteMailResponse, error)
+	// MarkRead flags messages in the user's inbox as read by ID. New mail starts out unread.
+	MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error)
+	// SearchMail returns the messages in the user's inbox whose sender or subject (and optionally body)
+	// contain the query, ignoring case. The inbox is left untouched.
+	SearchMail(context.Context, *SearchMailRequest) (*SearchMailResponse, error)
+	// Info reports the number of users and stored messages of this Mailbox.
+	Info(context.Context, *MailboxInfoRequest) (*MailboxInfoResponse, error)
+	// CanAccept reports, without transferring the message, whether ReceiveMail would currently accept
+	// mail for the recipient. It is unauthenticated, so blocked senders are left to ReceiveMail.
+	CanAccept(context.Context, *CanAcceptRequest) (*CanAcceptResponse, error)
+	// ExportMailbox (admin) streams a point-in-time dump of all users' messages, one entry per message.
+	ExportMailbox(*ExportMailboxRequest, grpc.ServerStreamingServer[MailboxDumpEntry]) error
+	// ImportMailbox (admin) loads a dump produced by ExportMailbox, appending each message to its user's
+	// inbox. Messages whose ID is already in that inbox are skipped, so importing a dump twice is harmless.
+	ImportMailbox(grpc.ClientStreamingServer[MailboxDumpEntry, ImportMailboxResponse]) error
+	// SetPassword sets the password of a user, which the Mailbox stores hashed. Once set, the RPCs that read
+	// or modify the user's mail require it as the caller's bearer token, and so does changing it.
+	SetPassword(context.Context, *SetPasswordRequest) (*SetPasswordResponse, error)
 	mustEmbedUnimplementedMailboxServer()
 }
 
@@ -237,6 +907,42 @@ func (UnimplementedMailboxServer) ReceiveMail(context.Context, *ReceiveMailReque
 func (UnimplementedMailboxServer) GetMail(context.Context, *GetMailRequest) (*GetMailResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMail not implemented")
 }
+func (UnimplementedMailboxServer) StreamMail(*StreamMailRequest, grpc.ServerStreamingServer[MailMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMail not implemented")
+}
+func (UnimplementedMailboxServer) DeleteMail(context.Context, *DeleteMailRequest) (*DeleteMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMail not implemented")
+}
+func (UnimplementedMailboxServer) WaitForMail(context.Context, *WaitForMailRequest) (*GetMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitForMail not implemented")
+}
+func (UnimplementedMailboxServer) WatchMail(*WatchMailRequest, grpc.ServerStreamingServer[MailMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMail not implemented")
+}
+func (UnimplementedMailboxServer) UndeleteMail(context.Context, *UndeleteMailRequest) (*UndeleteMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UndeleteMail not implemented")
+}
+func (UnimplementedMailboxServer) MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkRead not implemented")
+}
+func (UnimplementedMailboxServer) SearchMail(context.Context, *SearchMailRequest) (*SearchMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMail not implemented")
+}
+func (UnimplementedMailboxServer) Info(context.Context, *MailboxInfoRequest) (*MailboxInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedMailboxServer) CanAccept(context.Context, *CanAcceptRequest) (*CanAcceptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CanAccept not implemented")
+}
+func (UnimplementedMailboxServer) ExportMailbox(*ExportMailboxRequest, grpc.ServerStreamingServer[MailboxDumpEntry]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportMailbox not implemented")
+}
+func (UnimplementedMailboxServer) ImportMailbox(grpc.ClientStreamingServer[MailboxDumpEntry, ImportMailboxResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportMailbox not implemented")
+}
+func (UnimplementedMailboxServer) SetPassword(context.Context, *SetPasswordRequest) (*SetPasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPassword not implemented")
+}
 func (UnimplementedMailboxServer) mustEmbedUnimplementedMailboxServer() {}
 func (UnimplementedMailboxServer) testEmbeddedByValue()                 {}
 
@@ -294,6 +1000,190 @@ func _Mailbox_GetMail_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mailbox_StreamMail_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MailboxServer).StreamMail(m, &grpc.GenericServerStream[StreamMailRequest, MailMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_StreamMailServer = grpc.ServerStreamingServer[MailMessage]
+
+func _Mailbox_DeleteMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).DeleteMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_DeleteMail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).DeleteMail(ctx, req.(*DeleteMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_WaitForMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitForMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).WaitForMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_WaitForMail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).WaitForMail(ctx, req.(*WaitForMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_WatchMail_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MailboxServer).WatchMail(m, &grpc.GenericServerStream[WatchMailRequest, MailMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_WatchMailServer = grpc.ServerStreamingServer[MailMessage]
+
+func _Mailbox_UndeleteMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndeleteMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).UndeleteMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_UndeleteMail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).UndeleteMail(ctx, req.(*UndeleteMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_MarkRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).MarkRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_MarkRead_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).MarkRead(ctx, req.(*MarkReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_SearchMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).SearchMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_SearchMail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).SearchMail(ctx, req.(*SearchMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MailboxInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).Info(ctx, req.(*MailboxInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_CanAccept_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CanAcceptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).CanAccept(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_CanAccept_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).CanAccept(ctx, req.(*CanAcceptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailbox_ExportMailbox_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportMailboxRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MailboxServer).ExportMailbox(m, &grpc.GenericServerStream[ExportMailboxRequest, MailboxDumpEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_ExportMailboxServer = grpc.ServerStreamingServer[MailboxDumpEntry]
+
+func _Mailbox_ImportMailbox_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MailboxServer).ImportMailbox(&grpc.GenericServerStream[MailboxDumpEntry, ImportMailboxResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Mailbox_ImportMailboxServer = grpc.ClientStreamingServer[MailboxDumpEntry, ImportMailboxResponse]
+
+func _Mailbox_SetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailboxServer).SetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mailbox_SetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailboxServer).SetPassword(ctx, req.(*SetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Mailbox_ServiceDesc is the grpc.ServiceDesc for Mailbox service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -309,13 +1199,76 @@ var Mailbox_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMail",
 			Handler:    _Mailbox_GetMail_Handler,
 		},
+		{
+			MethodName: "DeleteMail",
+			Handler:    _Mailbox_DeleteMail_Handler,
+		},
+		{
+			MethodName: "WaitForMail",
+			Handler:    _Mailbox_WaitForMail_Handler,
+		},
+		{
+			MethodName: "UndeleteMail",
+			Handler:    _Mailbox_UndeleteMail_Handler,
+		},
+		{
+			MethodName: "MarkRead",
+			Handler:    _Mailbox_MarkRead_Handler,
+		},
+		{
+			MethodName: "SearchMail",
+			Handler:    _Mailbox_SearchMail_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Mailbox_Info_Handler,
+		},
+		{
+			MethodName: "CanAccept",
+			Handler:    _Mailbox_CanAccept_Handler,
+		},
+		{
+			MethodName: "SetPassword",
+			Handler:    _Mailbox_SetPassword_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMail",
+			Handler:       _Mailbox_StreamMail_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchMail",
+			Handler:       _Mailbox_WatchMail_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportMailbox",
+			Handler:       _Mailbox_ExportMailbox_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportMailbox",
+			Handler:       _Mailbox_ImportMailbox_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/mail.proto",
 }
 
 const (
-	TransferServer_SendMail_FullMethodName = "/mail.TransferServer/SendMail"
+	TransferServer_SendMail_FullMethodName          = "/mail.TransferServer/SendMail"
+	TransferServer_SendMailBulk_FullMethodName      = "/mail.TransferServer/SendMailBulk"
+	TransferServer_DeliveryReport_FullMethodName    = "/mail.TransferServer/DeliveryReport"
+	TransferServer_GetDeliveryStatus_FullMethodName = "/mail.TransferServer/GetDeliveryStatus"
+	TransferServer_PauseDelivery_FullMethodName     = "/mail.TransferServer/PauseDelivery"
+	TransferServer_ResumeDelivery_FullMethodName    = "/mail.TransferServer/ResumeDelivery"
+	TransferServer_FlushQueue_FullMethodName        = "/mail.TransferServer/FlushQueue"
+	TransferServer_QueueStatus_FullMethodName       = "/mail.TransferServer/QueueStatus"
+	TransferServer_Info_FullMethodName              = "/mail.TransferServer/Info"
+	TransferServer_ListDeadLetters_FullMethodName   = "/mail.TransferServer/ListDeadLetters"
+	TransferServer_RetryDeadLetter_FullMethodName   = "/mail.TransferServer/RetryDeadLetter"
 )
 
 // TransferServerClient is the client API for TransferServer service.
@@ -326,6 +1279,28 @@ const (
 type TransferServerClient interface {
 	// SendMail sends a mail message from a client.
 	SendMail(ctx context.Context, in *SendMailRequest, opts ...grpc.CallOption) (*SendMailResponse, error)
+	// SendMailBulk delivers one message to a large recipient list. The client first streams the
+	// message, then any number of recipient addresses; the server streams back one result per recipient.
+	SendMailBulk(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendMailBulkRequest, RecipientResult], error)
+	// DeliveryReport returns the recorded per-recipient outcome of a send.
+	DeliveryReport(ctx context.Context, in *DeliveryReportRequest, opts ...grpc.CallOption) (*DeliveryReportResponse, error)
+	// GetDeliveryStatus reports whether a send, identified by its message ID, is still pending,
+	// delivered to every recipient or failed for at least one.
+	GetDeliveryStatus(ctx context.Context, in *GetDeliveryStatusRequest, opts ...grpc.CallOption) (*GetDeliveryStatusResponse, error)
+	// PauseDelivery (admin) halts delivery attempts from the outbound queue; queued mail accumulates.
+	PauseDelivery(ctx context.Context, in *PauseDeliveryRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error)
+	// ResumeDelivery (admin) restarts delivery attempts from the outbound queue.
+	ResumeDelivery(ctx context.Context, in *ResumeDeliveryRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error)
+	// FlushQueue (admin) makes every queued message due immediately, skipping the remaining retry backoff.
+	FlushQueue(ctx context.Context, in *FlushQueueRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error)
+	// QueueStatus reports the state of the outbound delivery queue.
+	QueueStatus(ctx context.Context, in *QueueStatusRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error)
+	// Info reports the delivery mode, queue state and delivery counters.
+	Info(ctx context.Context, in *TransferServerInfoRequest, opts ...grpc.CallOption) (*TransferServerInfoResponse, error)
+	// ListDeadLetters (admin) returns the deliveries that failed for good, oldest first.
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error)
+	// RetryDeadLetter (admin) makes one more delivery attempt for a dead letter, removing it on success.
+	RetryDeadLetter(ctx context.Context, in *RetryDeadLetterRequest, opts ...grpc.CallOption) (*RetryDeadLetterResponse, error)
 }
 
 type transferServerClient struct {
@@ -346,6 +1321,109 @@ func (c *transferServerClient) SendMail(ctx context.Context, in *SendMailRequest
 	return out, nil
 }
 
+func (c *transferServerClient) SendMailBulk(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendMailBulkRequest, RecipientResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TransferServer_ServiceDesc.Streams[0], TransferServer_SendMailBulk_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SendMailBulkRequest, RecipientResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TransferServer_SendMailBulkClient = grpc.BidiStreamingClient[SendMailBulkRequest, RecipientResult]
+
+func (c *transferServerClient) DeliveryReport(ctx context.Context, in *DeliveryReportRequest, opts ...grpc.CallOption) (*DeliveryReportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeliveryReportResponse)
+	err := c.cc.Invoke(ctx, TransferServer_DeliveryReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) GetDeliveryStatus(ctx context.Context, in *GetDeliveryStatusRequest, opts ...grpc.CallOption) (*GetDeliveryStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDeliveryStatusResponse)
+	err := c.cc.Invoke(ctx, TransferServer_GetDeliveryStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) PauseDelivery(ctx context.Context, in *PauseDeliveryRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueStatusResponse)
+	err := c.cc.Invoke(ctx, TransferServer_PauseDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) ResumeDelivery(ctx context.Context, in *ResumeDeliveryRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueStatusResponse)
+	err := c.cc.Invoke(ctx, TransferServer_ResumeDelivery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) FlushQueue(ctx context.Context, in *FlushQueueRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueStatusResponse)
+	err := c.cc.Invoke(ctx, TransferServer_FlushQueue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) QueueStatus(ctx context.Context, in *QueueStatusRequest, opts ...grpc.CallOption) (*QueueStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueStatusResponse)
+	err := c.cc.Invoke(ctx, TransferServer_QueueStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) Info(ctx context.Context, in *TransferServerInfoRequest, opts ...grpc.CallOption) (*TransferServerInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferServerInfoResponse)
+	err := c.cc.Invoke(ctx, TransferServer_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDeadLettersResponse)
+	err := c.cc.Invoke(ctx, TransferServer_ListDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transferServerClient) RetryDeadLetter(ctx context.Context, in *RetryDeadLetterRequest, opts ...grpc.CallOption) (*RetryDeadLetterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RetryDeadLetterResponse)
+	err := c.cc.Invoke(ctx, TransferServer_RetryDeadLetter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TransferServerServer is the server API for TransferServer service.
 // All implementations must embed UnimplementedTransferServerServer
 // for forward compatibility.
@@ -354,6 +1432,28 @@ func (c *transferServerClient) SendMail(ctx context.Context, in *SendMailRequest
 type TransferServerServer interface {
 	// SendMail sends a mail message from a client.
 	SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error)
+	// SendMailBulk delivers one message to a large recipient list. The client first streams the
+	// message, then any number of recipient addresses; the server streams back one result per recipient.
+	SendMailBulk(grpc.BidiStreamingServer[SendMailBulkRequest, RecipientResult]) error
+	// DeliveryReport returns the recorded per-recipient outcome of a send.
+	DeliveryReport(context.Context, *DeliveryReportRequest) (*DeliveryReportResponse, error)
+	// GetDeliveryStatus reports whether a send, identified by its message ID, is still pending,
+	// delivered to every recipient or failed for at least one.
+	GetDeliveryStatus(context.Context, *GetDeliveryStatusRequest) (*GetDeliveryStatusResponse, error)
+	// PauseDelivery (admin) halts delivery attempts from the outbound queue; queued mail accumulates.
+	PauseDelivery(context.Context, *PauseDeliveryRequest) (*QueueStatusResponse, error)
+	// ResumeDelivery (admin) restarts delivery attempts from the outbound queue.
+	ResumeDelivery(context.Context, *ResumeDeliveryRequest) (*QueueStatusResponse, error)
+	// FlushQueue (admin) makes every queued message due immediately, skipping the remaining retry backoff.
+	FlushQueue(context.Context, *FlushQueueRequest) (*QueueStatusResponse, error)
+	// QueueStatus reports the state of the outbound delivery queue.
+	QueueStatus(context.Context, *QueueStatusRequest) (*QueueStatusResponse, error)
+	// Info reports the delivery mode, queue state and delivery counters.
+	Info(context.Context, *TransferServerInfoRequest) (*TransferServerInfoResponse, error)
+	// ListDeadLetters (admin) returns the deliveries that failed for good, oldest first.
+	ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error)
+	// RetryDeadLetter (admin) makes one more delivery attempt for a dead letter, removing it on success.
+	RetryDeadLetter(context.Context, *RetryDeadLetterRequest) (*RetryDeadLetterResponse, error)
 	mustEmbedUnimplementedTransferServerServer()
 }
 
@@ -367,6 +1467,36 @@ type UnimplementedTransferServerServer struct{}
 func (UnimplementedTransferServerServer) SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendMail not implemented")
 }
+func (UnimplementedTransferServerServer) SendMailBulk(grpc.BidiStreamingServer[SendMailBulkRequest, RecipientResult]) error {
+	return status.Errorf(codes.Unimplemented, "method SendMailBulk not implemented")
+}
+func (UnimplementedTransferServerServer) DeliveryReport(context.Context, *DeliveryReportRequest) (*DeliveryReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeliveryReport not implemented")
+}
+func (UnimplementedTransferServerServer) GetDeliveryStatus(context.Context, *GetDeliveryStatusRequest) (*GetDeliveryStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeliveryStatus not implemented")
+}
+func (UnimplementedTransferServerServer) PauseDelivery(context.Context, *PauseDeliveryRequest) (*QueueStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseDelivery not implemented")
+}
+func (UnimplementedTransferServerServer) ResumeDelivery(context.Context, *ResumeDeliveryRequest) (*QueueStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeDelivery not implemented")
+}
+func (UnimplementedTransferServerServer) FlushQueue(context.Context, *FlushQueueRequest) (*QueueStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FlushQueue not implemented")
+}
+func (UnimplementedTransferServerServer) QueueStatus(context.Context, *QueueStatusRequest) (*QueueStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueueStatus not implemented")
+}
+func (UnimplementedTransferServerServer) Info(context.Context, *TransferServerInfoRequest) (*TransferServerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedTransferServerServer) ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedTransferServerServer) RetryDeadLetter(context.Context, *RetryDeadLetterRequest) (*RetryDeadLetterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryDeadLetter not implemented")
+}
 func (UnimplementedTransferServerServer) mustEmbedUnimplementedTransferServerServer() {}
 func (UnimplementedTransferServerServer) testEmbeddedByValue()                        {}
 
@@ -406,6 +1536,175 @@ func _TransferServer_SendMail_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TransferServer_SendMailBulk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TransferServerServer).SendMailBulk(&grpc.GenericServerStream[SendMailBulkRequest, RecipientResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TransferServer_SendMailBulkServer = grpc.BidiStreamingServer[SendMailBulkRequest, RecipientResult]
+
+func _TransferServer_DeliveryReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeliveryReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).DeliveryReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_DeliveryReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).DeliveryReport(ctx, req.(*DeliveryReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_GetDeliveryStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeliveryStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).GetDeliveryStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_GetDeliveryStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).GetDeliveryStatus(ctx, req.(*GetDeliveryStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_PauseDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).PauseDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_PauseDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).PauseDelivery(ctx, req.(*PauseDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_ResumeDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeDeliveryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).ResumeDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_ResumeDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).ResumeDelivery(ctx, req.(*ResumeDeliveryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_FlushQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushQueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).FlushQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_FlushQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).FlushQueue(ctx, req.(*FlushQueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_QueueStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).QueueStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_QueueStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).QueueStatus(ctx, req.(*QueueStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferServerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).Info(ctx, req.(*TransferServerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).ListDeadLetters(ctx, req.(*ListDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TransferServer_RetryDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetryDeadLetterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransferServerServer).RetryDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TransferServer_RetryDeadLetter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransferServerServer).RetryDeadLetter(ctx, req.(*RetryDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TransferServer_ServiceDesc is the grpc.ServiceDesc for TransferServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -417,7 +1716,50 @@ var TransferServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendMail",
 			Handler:    _TransferServer_SendMail_Handler,
 		},
+		{
+			MethodName: "DeliveryReport",
+			Handler:    _TransferServer_DeliveryReport_Handler,
+		},
+		{
+			MethodName: "GetDeliveryStatus",
+			Handler:    _TransferServer_GetDeliveryStatus_Handler,
+		},
+		{
+			MethodName: "PauseDelivery",
+			Handler:    _TransferServer_PauseDelivery_Handler,
+		},
+		{
+			MethodName: "ResumeDelivery",
+			Handler:    _TransferServer_ResumeDelivery_Handler,
+		},
+		{
+			MethodName: "FlushQueue",
+			Handler:    _TransferServer_FlushQueue_Handler,
+		},
+		{
+			MethodName: "QueueStatus",
+			Handler:    _TransferServer_QueueStatus_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _TransferServer_Info_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _TransferServer_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "RetryDeadLetter",
+			Handler:    _TransferServer_RetryDeadLetter_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendMailBulk",
+			Handler:       _TransferServer_SendMailBulk_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/mail.proto",
 }
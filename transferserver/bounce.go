@@ -0,0 +1,98 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// finishOutcome accounts for the final delivery outcome of one recipient of msg, reports it to the
+// webhook if configured and, if delivery failed and bounces are enabled, notifies the sender in the background.
+// The bounce continues the trace in ctx, but outlives its cancellation.
+func (s *server) finishOutcome(ctx context.Context, msg *proto.MailMessage, outcome recipientOutcome) {
+	s.stats.countOutcome(outcome)
+	s.webhook.notify(deliveryEvent{
+		MessageID:      msg.MessageId,
+		SenderEmail:    msg.SenderEmail,
+		RecipientEmail: outcome.RecipientEmail,
+		Success:        outcome.Success,
+		Message:        outcome.Message,
+		Timestamp:      outcome.Timestamp,
+	})
+	if outcome.Success || !s.bounces {
+		return
+	}
+	bounce := s.newBounce(msg, outcome)
+	if bounce == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.bouncing.Add(1)
+	go func() {
+		defer s.bouncing.Done()
+		if !s.senderResolvable(ctx, bounce.RecipientEmail) {
+			logger().Info("Not bouncing, sender cannot be resolved", "message_id", msg.MessageId, "sender", bounce.RecipientEmail)
+			return
+		}
+		resp, err := s.deliver(ctx, bounce)
+		if err == nil && !resp.GetSuccess() {
+			err = fmt.Errorf("%s", resp.GetMessage())
+		}
+		if err != nil {
+			// Bounces are never bounced, so a sender that cannot be reached ends here
+			logger().Warn("Failed to bounce", "message_id", msg.MessageId, "sender", bounce.RecipientEmail, "error", err)
+			return
+		}
+		logger().Info("Bounced mail", "message_id", msg.MessageId, "recipient", outcome.RecipientEmail, "sender", bounce.RecipientEmail)
+	}()
+}
+
+// newBounce builds the bounce telling the sender of msg that delivery to outcome.RecipientEmail failed.
+// It returns nil for messages that must not bounce: bounces themselves (to prevent loops), mail without a
+// sender and mail sent by the postmaster.
+func (s *server) newBounce(msg *proto.MailMessage, outcome recipientOutcome) *proto.MailMessage {
+	postmaster := s.postmasterAddress
+	if postmaster == "" {
+		postmaster = "postmaster@" + domainOf(msg.SenderEmail)
+	}
+	if msg.BounceForMessageId != "" || msg.SenderEmail == "" || msg.SenderEmail == postmaster {
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your message '%s' (ID %s) could not be delivered to %s.\nReason: %s\n",
+		msg.Subject, msg.MessageId, outcome.RecipientEmail, outcome.Message)
+	bounce := &proto.MailMessage{
+		SenderEmail:        postmaster,
+		RecipientEmail:     msg.SenderEmail,
+		Subject:            "Undeliverable: " + msg.Subject,
+		Timestamp:          s.now().Unix(),
+		BounceForMessageId: msg.MessageId,
+	}
+	if s.bounceIncludeOriginal {
+		fmt.Fprintf(&body, "\n--- Original message ---\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n",
+			msg.SenderEmail, outcome.RecipientEmail, msg.Subject, msg.Body)
+		bounce.Attachments = msg.Attachments
+	}
+	bounce.Body = body.String()
+	return bounce
+}
+
+// senderResolvable reports whether the Nameserver knows a mailbox for sender. Bounces are only sent to such
+// senders, so mail from unknown or unresolvable addresses ends with the failed delivery.
+func (s *server) senderResolvable(ctx context.Context, sender string) bool {
+	_, found, err := s.resolveMailbox(ctx, sender)
+	if err != nil {
+		logger().Warn("Could not resolve sender for a bounce", "sender", sender, "error", err)
+	}
+	return err == nil && found
+}
+
+// domainOf returns the domain part of an email address, or an empty string.
+func domainOf(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package transferserver
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultBreakerCooldown = 30 * time.Second // How long a tripped circuit breaker stays open before probing
+
+// circuitBreakers keeps a circuit breaker per mailbox address, shared by all deliveries. A breaker opens after
+// threshold consecutive failures to reach its Mailbox; while open, deliveries fail at once. After cooldown it
+// half-opens and lets a single probe through, which closes it on success and reopens it on failure.
+// A nil *circuitBreakers never trips.
+type circuitBreakers struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	breakers  map[string]*circuitBreaker // mailbox address -> breaker
+}
+
+// circuitBreaker is the state of the breaker of one mailbox address.
+type circuitBreaker struct {
+	failures int       // Consecutive failures to reach the Mailbox
+	openedAt time.Time // When the breaker last opened, zero while closed
+	probing  bool      // A half-open probe delivery is in progress
+}
+
+// newCircuitBreakers returns breakers opening after threshold consecutive failures for cooldown as measured
+// by now (0 uses the default), or nil if threshold is not positive.
+func newCircuitBreakers(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreakers {
+	if threshold <= 0 {
+		return nil
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &circuitBreakers{threshold: threshold, cooldown: cooldown, now: now, breakers: make(map[string]*circuitBreaker)}
+}
+
+// allow returns an Unavailable error if the breaker of addr is open, so delivery to it is skipped. Once the
+// cooldown has passed, the first caller is let through as a probe; others keep failing until it finishes.
+func (c *circuitBreakers) allow(addr string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[addr]
+	if !ok || b.openedAt.IsZero() {
+		return nil
+	}
+	if b.probing || c.now().Sub(b.openedAt) < c.cooldown {
+		return status.Errorf(codes.Unavailable, "circuit breaker for mailbox '%s' is open", addr)
+	}
+	b.probing = true
+	logger().Info("Circuit breaker half-open, probing", "mailbox_addr", addr)
+	return nil
+}
+
+// record accounts for a delivery attempt to addr that failed with err (nil on success). Only failures to reach
+// the Mailbox count; a Mailbox that answers, even with a refusal, closes the breaker.
+func (c *circuitBreakers) record(addr string, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[addr]
+	if !mailboxUnreachable(err) {
+		if ok && !b.openedAt.IsZero() {
+			logger().Info("Circuit breaker closed, the mailbox is reachable again", "mailbox_addr", addr)
+		}
+		delete(c.breakers, addr)
+		return
+	}
+	if !ok {
+		b = &circuitBreaker{}
+		c.breakers[addr] = b
+	}
+	b.failures++
+	switch {
+	case b.probing:
+		b.probing = false
+		b.openedAt = c.now()
+		logger().Warn("Circuit breaker reopened, probe failed", "mailbox_addr", addr, "error", err)
+	case b.openedAt.IsZero() && b.failures >= c.threshold:
+		b.openedAt = c.now()
+		logger().Warn("Circuit breaker opened", "mailbox_addr", addr, "failures", b.failures, "cooldown", c.cooldown, "error", err)
+	}
+}
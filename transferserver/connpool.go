@@ -0,0 +1,72 @@
+package transferserver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// connPool shares one gRPC connection per Mailbox address between all deliveries, instead of dialing
+// for every message. A connection in transient failure stays pooled, since other deliveries may still use it,
+// and reconnects on its own; connections that were shut down are replaced on their next use.
+type connPool struct {
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn // mailbox address -> connection
+	closed  bool
+	dialOpt grpc.DialOption // Transport credentials of new connections
+}
+
+// newConnPool returns an empty connection pool that dials with dialOpt.
+func newConnPool(dialOpt grpc.DialOption) *connPool {
+	return &connPool{conns: make(map[string]*grpc.ClientConn), dialOpt: dialOpt}
+}
+
+// get returns the pooled connection to the Mailbox at addr, dialing it if there is none yet or the pooled one
+// was shut down. A connection in transient failure is told to reconnect without waiting for its backoff.
+// It fails once the pool is closed.
+func (p *connPool) get(addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, status.Errorf(codes.Unavailable, "transfer server is shutting down")
+	}
+	if conn, ok := p.conns[addr]; ok {
+		switch conn.GetState() {
+		case connectivity.Shutdown:
+			logger().Info("Replacing closed connection to mailbox", "mailbox_addr", addr)
+			delete(p.conns, addr)
+		case connectivity.TransientFailure:
+			conn.ResetConnectBackoff() // Closing it would fail the deliveries still using it
+			return conn, nil
+		default:
+			return conn, nil
+		}
+	}
+	conn, err := dialMailbox(addr, p.dialOpt) // Does not block, the connection is established on first use
+	if err != nil {
+		return nil, err
+	}
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+// size returns the number of pooled connections.
+func (p *connPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// close closes every pooled connection. Later calls to get fail.
+func (p *connPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	p.closed = true
+}
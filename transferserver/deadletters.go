@@ -0,0 +1,341 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	deadLettersFile = "dead_letters.jsonl" // Journal of the dead-letter store inside the state directory
+
+	defaultDeadLetterMaxAttempts = 10             // Background re-drive attempts per dead letter
+	defaultDeadLetterMaxAge      = 72 * time.Hour // Age after which dead letters are no longer re-driven in the background
+	deadLetterCompactSlack       = 64             // Journal records tolerated beyond twice the dead letters before compacting
+)
+
+// deadLetter is a copy of a message for a single recipient whose delivery failed for good.
+type deadLetter struct {
+	id          string
+	msg         *proto.MailMessage
+	lastError   string
+	attempts    int       // Re-drive attempts made so far
+	deadAt      time.Time // When the delivery was dead-lettered
+	lastAttempt time.Time // Zero until the first re-drive attempt
+}
+
+// storedDeadLetter is the on-disk form of a dead letter.
+type storedDeadLetter struct {
+	ID          string          `json:"ID"`
+	Message     json.RawMessage `json:"Message"` // protojson-encoded MailMessage
+	LastError   string          `json:"LastError"`
+	Attempts    int             `json:"Attempts"`
+	DeadAt      int64           `json:"DeadAt"`
+	LastAttempt int64           `json:"LastAttempt,omitempty"`
+}
+
+// deadLetterRecord is one change to the dead-letter store, as journaled on disk.
+type deadLetterRecord struct {
+	Letter  *storedDeadLetter `json:"Letter,omitempty"`  // A new dead letter, or one updated by a failed re-drive
+	Removed string            `json:"Removed,omitempty"` // ID of a dead letter that was delivered
+}
+
+// toProto converts the dead letter into its proto representation.
+func (d *deadLetter) toProto() *proto.DeadLetter {
+	pd := &proto.DeadLetter{
+		Id:             d.id,
+		Message:        d.msg,
+		RecipientEmail: d.msg.RecipientEmail,
+		LastError:      d.lastError,
+		Attempts:       int32(d.attempts),
+		DeadLetteredAt: d.deadAt.Unix(),
+	}
+	if !d.lastAttempt.IsZero() {
+		pd.LastAttemptAt = d.lastAttempt.Unix()
+	}
+	return pd
+}
+
+// stored returns the on-disk form of the dead letter.
+func (d *deadLetter) stored() (*storedDeadLetter, error) {
+	raw, err := protojson.Marshal(d.msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dead letter '%s': %w", d.id, err)
+	}
+	sd := &storedDeadLetter{ID: d.id, Message: raw, LastError: d.lastError, Attempts: d.attempts, DeadAt: d.deadAt.Unix()}
+	if !d.lastAttempt.IsZero() {
+		sd.LastAttempt = d.lastAttempt.Unix()
+	}
+	return sd, nil
+}
+
+// deadLetterStore keeps dead letters in the order they failed, optionally journaled to a file so every
+// change writes a single record instead of the whole store.
+type deadLetterStore struct {
+	mu       sync.Mutex
+	journal  *common.Journal // Nil keeps dead letters in memory only
+	letters  []*deadLetter
+	retrying map[string]bool // IDs of dead letters with a re-drive attempt in progress
+}
+
+// newDeadLetterStore creates a dead-letter store journaled at path, replaying any existing dead letters.
+// An empty path creates an in-memory store.
+func newDeadLetterStore(path string) (*deadLetterStore, error) {
+	st := &deadLetterStore{retrying: make(map[string]bool)}
+	if path == "" {
+		return st, nil
+	}
+
+	journal, err := common.OpenJournal(path, 0o644, func(raw json.RawMessage) error {
+		var record deadLetterRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		if record.Removed != "" {
+			st.removeLocked(record.Removed)
+			return nil
+		}
+		if record.Letter == nil {
+			return fmt.Errorf("empty dead-letter record")
+		}
+		sd := record.Letter
+		msg := &proto.MailMessage{}
+		if err := protojson.Unmarshal(sd.Message, msg); err != nil {
+			return fmt.Errorf("failed to unmarshal dead-lettered message '%s': %w", sd.ID, err)
+		}
+		d := &deadLetter{id: sd.ID, msg: msg, lastError: sd.LastError, attempts: sd.Attempts, deadAt: time.Unix(sd.DeadAt, 0)}
+		if sd.LastAttempt != 0 {
+			d.lastAttempt = time.Unix(sd.LastAttempt, 0)
+		}
+		if existing := st.findLocked(d.id); existing != nil {
+			*existing = *d // Updated by a re-drive attempt
+		} else {
+			st.letters = append(st.letters, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dead letters: %w", err)
+	}
+	st.journal = journal
+	return st, nil
+}
+
+// add dead-letters msg, which failed with lastError at now, and persists it.
+func (st *deadLetterStore) add(msg *proto.MailMessage, lastError string, now time.Time) (*deadLetter, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	d := &deadLetter{id: common.NewMessageID(), msg: msg, lastError: lastError, deadAt: now}
+	st.letters = append(st.letters, d)
+	return d, st.saveLetterLocked(d)
+}
+
+// list returns all dead letters, oldest first.
+func (st *deadLetterStore) list() []*proto.DeadLetter {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	letters := make([]*proto.DeadLetter, 0, len(st.letters))
+	for _, d := range st.letters {
+		letters = append(letters, d.toProto())
+	}
+	return letters
+}
+
+// due returns the IDs of the dead letters to re-drive in the background at now, oldest first: those with
+// fewer than maxAttempts re-drive attempts that were dead-lettered less than maxAge ago.
+func (st *deadLetterStore) due(now time.Time, maxAttempts int, maxAge time.Duration) []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var ids []string
+	for _, d := range st.letters {
+		if d.attempts < maxAttempts && now.Sub(d.deadAt) < maxAge {
+			ids = append(ids, d.id)
+		}
+	}
+	return ids
+}
+
+// begin marks the dead letter id as being re-driven and returns its message. It fails with NotFound for
+// an unknown ID and with FailedPrecondition if the dead letter is already being re-driven.
+func (st *deadLetterStore) begin(id string) (*proto.MailMessage, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	d := st.findLocked(id)
+	if d == nil {
+		return nil, status.Errorf(codes.NotFound, "dead letter '%s' not found", id)
+	}
+	if st.retrying[id] {
+		return nil, status.Errorf(codes.FailedPrecondition, "dead letter '%s' is already being retried", id)
+	}
+	st.retrying[id] = true
+	return d.msg, nil
+}
+
+// end finishes the re-drive attempt begun for id, made at now. A successful attempt (err nil) removes the dead
+// letter; otherwise it is kept with err as its last error. It returns the updated dead letter, or nil if removed.
+func (st *deadLetterStore) end(id string, err error, now time.Time) (*proto.DeadLetter, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.retrying, id)
+	d := st.findLocked(id)
+	if d == nil {
+		return nil, nil
+	}
+	if err == nil {
+		st.removeLocked(id)
+		return nil, st.appendLocked(deadLetterRecord{Removed: id})
+	}
+	d.attempts++
+	d.lastAttempt = now
+	d.lastError = err.Error()
+	return d.toProto(), st.saveLetterLocked(d)
+}
+
+// close closes the store's journal, if any.
+func (st *deadLetterStore) close() error {
+	if st.journal == nil {
+		return nil
+	}
+	return st.journal.Close()
+}
+
+// findLocked returns the dead letter id, or nil if there is none. st.mu must be held.
+func (st *deadLetterStore) findLocked(id string) *deadLetter {
+	for _, d := range st.letters {
+		if d.id == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// removeLocked removes the dead letter id, if present. st.mu must be held.
+func (st *deadLetterStore) removeLocked(id string) {
+	for i, d := range st.letters {
+		if d.id == id {
+			st.letters = append(st.letters[:i], st.letters[i+1:]...)
+			return
+		}
+	}
+}
+
+// saveLetterLocked journals the current state of d. st.mu must be held.
+func (st *deadLetterStore) saveLetterLocked(d *deadLetter) error {
+	if st.journal == nil {
+		return nil
+	}
+	sd, err := d.stored()
+	if err != nil {
+		return err
+	}
+	return st.appendLocked(deadLetterRecord{Letter: sd})
+}
+
+// appendLocked journals record, compacting the journal to one record per dead letter once it has grown
+// well beyond that. st.mu must be held.
+func (st *deadLetterStore) appendLocked(record deadLetterRecord) error {
+	if st.journal == nil {
+		return nil
+	}
+	if err := st.journal.Append(record); err != nil {
+		return err
+	}
+	if st.journal.Len() <= 2*len(st.letters)+deadLetterCompactSlack {
+		return nil
+	}
+	records := make([]any, 0, len(st.letters))
+	for _, d := range st.letters {
+		sd, err := d.stored()
+		if err != nil {
+			return err
+		}
+		records = append(records, deadLetterRecord{Letter: sd})
+	}
+	return st.journal.Compact(records)
+}
+
+// deadLetter records msg, a copy for a single recipient whose delivery failed with lastError after all
+// retries of transient failures, so an operator can inspect and re-drive it.
+func (s *server) deadLetter(msg *proto.MailMessage, lastError string) {
+	d, err := s.deadLetters.add(msg, lastError, s.now())
+	if err != nil {
+		logger().Error("Failed to persist dead letter", "message_id", msg.MessageId, "recipient", msg.RecipientEmail, "error", err)
+	}
+	logger().Warn("Dead-lettered mail", "message_id", msg.MessageId, "recipient", msg.RecipientEmail, "dead_letter_id", d.id)
+}
+
+// retryDeadLetter makes one delivery attempt for the dead letter id. On success the dead letter is removed
+// and the delivery is recorded in the message's delivery report.
+func (s *server) retryDeadLetter(ctx context.Context, id string) (*proto.RetryDeadLetterResponse, error) {
+	msg, err := s.deadLetters.begin(id)
+	if err != nil {
+		return nil, err
+	}
+	_, deliveryErr := s.attemptDelivery(ctx, msg, 0)
+	updated, err := s.deadLetters.end(id, deliveryErr, s.now())
+	if err != nil {
+		logger().Error("Failed to persist dead letters", "error", err)
+	}
+	if deliveryErr != nil {
+		logger().Warn("Retry of dead letter failed", "dead_letter_id", id, "recipient", msg.RecipientEmail, "error", deliveryErr)
+		return &proto.RetryDeadLetterResponse{Success: false, Message: deliveryErr.Error(), DeadLetter: updated}, nil
+	}
+
+	logger().Info("Dead letter delivered", "dead_letter_id", id, "recipient", msg.RecipientEmail)
+	outcome := newRecipientOutcome(msg.RecipientEmail, &proto.SendMailResponse{Success: true, Message: "Mail sent successfully"}, nil)
+	s.finishOutcome(ctx, msg, outcome)
+	if err := s.reports.recordOutcome(msg.MessageId, msg.SenderEmail, outcome); err != nil {
+		logger().Error("Failed to record delivery report", "message_id", msg.MessageId, "error", err)
+	}
+	return &proto.RetryDeadLetterResponse{Success: true, Message: "Mail sent successfully"}, nil
+}
+
+// runDeadLetterRetries re-attempts the dead letters once per interval until stop is closed. Dead letters that
+// used up their attempts or are too old stay listed for an operator, but are no longer re-driven here.
+func (s *server) runDeadLetterRetries(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, id := range s.deadLetters.due(s.now(), s.deadLetterMaxAttempts, s.deadLetterMaxAge) {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s.retryDeadLetter(context.Background(), id) // Outcomes are logged; dead letters being re-driven by an operator are skipped
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ListDeadLetters implements proto.TransferServerServer.
+// It returns every delivery that failed for good and has not been re-driven successfully, oldest first.
+func (s *server) ListDeadLetters(ctx context.Context, req *proto.ListDeadLettersRequest) (*proto.ListDeadLettersResponse, error) {
+	return &proto.ListDeadLettersResponse{DeadLetters: s.deadLetters.list()}, nil
+}
+
+// RetryDeadLetter implements proto.TransferServerServer.
+// It makes one more delivery attempt for a dead letter, removing it if the delivery succeeds.
+func (s *server) RetryDeadLetter(ctx context.Context, req *proto.RetryDeadLetterRequest) (*proto.RetryDeadLetterResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "dead letter id cannot be empty")
+	}
+	return s.retryDeadLetter(ctx, req.GetId())
+}
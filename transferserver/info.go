@@ -0,0 +1,41 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"sync/atomic"
+)
+
+// deliveryStats counts accepted messages and per-recipient delivery outcomes since start.
+type deliveryStats struct {
+	accepted  atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+}
+
+// countOutcome adds a final per-recipient delivery outcome to the counters.
+func (st *deliveryStats) countOutcome(outcome recipientOutcome) {
+	if outcome.Success {
+		st.succeeded.Add(1)
+		mailDelivered.Inc()
+	} else {
+		st.failed.Add(1)
+		mailFailed.Inc()
+	}
+}
+
+// Info implements proto.TransferServerServer.
+// It reports the delivery mode, the queue state and the delivery counters.
+func (s *server) Info(ctx context.Context, req *proto.TransferServerInfoRequest) (*proto.TransferServerInfoResponse, error) {
+	queueStatus, err := s.QueueStatus(ctx, &proto.QueueStatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.TransferServerInfoResponse{
+		AsyncDelivery:       s.queue != nil,
+		Queue:               queueStatus,
+		MessagesAccepted:    s.stats.accepted.Load(),
+		DeliveriesSucceeded: s.stats.succeeded.Load(),
+		DeliveriesFailed:    s.stats.failed.Load(),
+	}, nil
+}
@@ -0,0 +1,52 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sizeLimits holds the configured message size limits in bytes; zero disables a limit.
+type sizeLimits struct {
+	subject     int
+	body        int
+	attachments int // Total over all attachments
+	message     int // Budget over subject, body and attachments together
+}
+
+// newSizeLimits takes the size limits from cfg.
+func newSizeLimits(cfg common.TransferServerConfig) sizeLimits {
+	return sizeLimits{
+		subject:     cfg.MaxSubjectBytes,
+		body:        cfg.MaxBodyBytes,
+		attachments: cfg.MaxAttachmentBytes,
+		message:     cfg.MaxMessageBytes,
+	}
+}
+
+// check returns an InvalidArgument error naming the offending size if msg exceeds any limit.
+// Individual limits and the total message budget apply side by side, so the stricter one wins.
+func (l sizeLimits) check(msg *proto.MailMessage) error {
+	subject, body := len(msg.GetSubject()), len(msg.GetBody())
+	attachments := 0
+	for _, a := range msg.GetAttachments() {
+		attachments += len(a.GetData())
+	}
+
+	if l.subject > 0 && subject > l.subject {
+		return status.Errorf(codes.InvalidArgument, "subject size %d bytes exceeds the limit of %d bytes", subject, l.subject)
+	}
+	if l.body > 0 && body > l.body {
+		return status.Errorf(codes.InvalidArgument, "body size %d bytes exceeds the limit of %d bytes", body, l.body)
+	}
+	if l.attachments > 0 && attachments > l.attachments {
+		return status.Errorf(codes.InvalidArgument, "attachment size %d bytes exceeds the limit of %d bytes", attachments, l.attachments)
+	}
+	if total := subject + body + attachments; l.message > 0 && total > l.message {
+		return status.Errorf(codes.InvalidArgument,
+			"message size %d bytes (subject+body+attachments) exceeds the limit of %d bytes", total, l.message)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"time"
+)
+
+// expandLists replaces recipient groups addressing a distribution list by one group per list member,
+// as resolved by the Nameserver in a single call. Members that are also addressed directly or via another
+// list receive a single copy. Addresses the Nameserver does not know as lists, or cannot expand, are kept
+// unchanged. The Nameserver is asked within the trace in ctx.
+func (s *server) expandLists(ctx context.Context, groups []recipientGroup) []recipientGroup {
+	var addresses []string
+	var indexes []int // Position in groups of each address
+	for i, g := range groups {
+		if g.err == nil {
+			addresses = append(addresses, g.address)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(addresses) == 0 {
+		return groups
+	}
+	expansions := s.lookupLists(ctx, addresses)
+	members := make(map[int][]string) // Group index -> members, for groups addressing a list
+	for j, expansion := range expansions {
+		if expansion.GetIsList() {
+			members[indexes[j]] = expansion.GetMembers()
+		}
+	}
+	if len(members) == 0 {
+		return groups
+	}
+
+	seen := make(map[string]bool)
+	for i, g := range groups {
+		if _, isList := members[i]; !isList {
+			seen[g.address] = true
+		}
+	}
+	expanded := make([]recipientGroup, 0, len(groups))
+	for i, g := range groups {
+		listMembers, isList := members[i]
+		if !isList {
+			expanded = append(expanded, g)
+			continue
+		}
+		if len(listMembers) == 0 {
+			g.err = fmt.Errorf("distribution list '%s' has no deliverable members", g.address)
+			expanded = append(expanded, g)
+			continue
+		}
+		for _, member := range listMembers {
+			if seen[member] {
+				continue
+			}
+			seen[member] = true
+			expanded = append(expanded, recipientGroup{address: member, entries: []string{member}})
+		}
+	}
+	return expanded
+}
+
+// lookupLists asks the Nameserver for the expansion of each address, in order. If the Nameserver cannot
+// expand them, nil is returned and every address is treated as not being a list.
+func (s *server) lookupLists(ctx context.Context, addresses []string) []*proto.ExpandListResponse {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	resp, err := s.nameserverClient.ExpandLists(ctx, &proto.ExpandListsRequest{EmailAddresses: addresses})
+	if err != nil {
+		logger().Warn("Could not expand recipients as distribution lists", "recipients", len(addresses), "error", err)
+		return nil
+	}
+	expansions := resp.GetExpansions()
+	if len(expansions) != len(addresses) {
+		logger().Warn("Nameserver returned the wrong number of list expansions, ignoring them", "expansions", len(expansions), "recipients", len(addresses))
+		return nil
+	}
+	for i, expansion := range expansions {
+		if !expansion.GetIsList() {
+			continue
+		}
+		if expansion.GetTruncated() {
+			logger().Warn("Distribution list nested too deeply, some members were skipped", "list", addresses[i])
+		}
+		logger().Info("Expanded distribution list", "list", addresses[i], "members", len(expansion.GetMembers()))
+	}
+	return expansions
+}
@@ -0,0 +1,111 @@
+package transferserver
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxLookupCacheEntries = 10000 // Recipients whose mailbox address is cached at most
+
+// lookupCache remembers the mailbox addresses resolved for each recipient for a limited time, sparing a
+// Nameserver round trip per message. The zero TTL disables it; a nil *lookupCache is a disabled cache.
+type lookupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]lookupCacheEntry // recipient -> resolved mailbox addresses
+}
+
+// lookupCacheEntry holds cached mailbox addresses, in failover order, and the time they stop being used.
+type lookupCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// newLookupCache returns a cache keeping addresses for ttl as measured by now, or nil if ttl is not positive.
+func newLookupCache(ttl time.Duration, now func() time.Time) *lookupCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &lookupCache{ttl: ttl, now: now, entries: make(map[string]lookupCacheEntry)}
+}
+
+// get returns the cached mailbox addresses of recipient, unless there are none or they have expired.
+func (c *lookupCache) get(recipient string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[recipient]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, recipient)
+		return nil, false
+	}
+	return entry.addrs, true
+}
+
+// put caches addrs as the mailbox addresses of recipient. When the cache is full, expired entries are dropped
+// first; if that frees no room, addrs are not cached.
+func (c *lookupCache) put(recipient string, addrs []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, exists := c.entries[recipient]; !exists && len(c.entries) >= maxLookupCacheEntries {
+		for r, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, r)
+			}
+		}
+		if len(c.entries) >= maxLookupCacheEntries {
+			return
+		}
+	}
+	c.entries[recipient] = lookupCacheEntry{addrs: addrs, expires: now.Add(c.ttl)}
+}
+
+// invalidate drops the cached addresses of recipient if they are still addrs, and reports whether it did.
+func (c *lookupCache) invalidate(recipient string, addrs []string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[recipient]; !ok || !slices.Equal(entry.addrs, addrs) {
+		return false
+	}
+	delete(c.entries, recipient)
+	return true
+}
+
+// relookupStale is called after no Mailbox among addrs, the resolved addresses of recipient, could be reached.
+// If addrs came from the cache they may be stale: the entry is dropped and the recipient looked up afresh.
+// The fresh addresses are returned if they differ from addrs.
+func (s *server) relookupStale(ctx context.Context, recipient string, addrs []string) (fresh []string, changed bool) {
+	if !s.lookupCache.invalidate(recipient, addrs) {
+		return nil, false
+	}
+	fresh, found, err := s.resolveMailbox(ctx, recipient)
+	if err != nil || !found || slices.Equal(fresh, addrs) {
+		return nil, false
+	}
+	logger().Info("Cached mailbox addresses were stale", "recipient", recipient, "cached_addrs", addrs, "addrs", fresh)
+	return fresh, true
+}
+
+// mailboxUnreachable reports whether err, possibly wrapped, shows that a Mailbox did not answer at all.
+func mailboxUnreachable(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
@@ -0,0 +1,28 @@
+package transferserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// mailDelivered and mailFailed count final per-recipient delivery outcomes.
+	mailDelivered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "godissys_transferserver_mail_delivered_total",
+		Help: "Recipients whose mail was delivered.",
+	})
+	mailFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "godissys_transferserver_mail_failed_total",
+		Help: "Recipients whose mail could not be delivered for good.",
+	})
+	// deliveryRetries counts delivery attempts after the first, synchronous and queued alike.
+	deliveryRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "godissys_transferserver_delivery_retries_total",
+		Help: "Delivery attempts retried after a failed attempt.",
+	})
+	// lookupCacheResults counts recipient lookups answered by the lookup cache (hit) or the Nameserver (miss).
+	lookupCacheResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "godissys_transferserver_lookup_cache_total",
+		Help: "Recipient lookups by lookup cache result: hit or miss.",
+	}, []string{"result"})
+)
@@ -0,0 +1,35 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkRecipientAccepts asks the recipient's Mailbox with CanAccept whether it would accept msg, if the
+// pre-delivery check is enabled. It returns an error if the mail should not be sent now; permanent
+// reports that retrying is pointless. Mailboxes without CanAccept are assumed to accept.
+func (s *server) checkRecipientAccepts(ctx context.Context, mailboxClient proto.MailboxClient, msg *proto.MailMessage) (permanent bool, err error) {
+	if !s.preDeliveryCheck {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	resp, err := mailboxClient.CanAccept(ctx, &proto.CanAcceptRequest{
+		RecipientEmail: msg.RecipientEmail, SenderEmail: msg.SenderEmail, BodyBytes: int64(len(msg.Body)),
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return false, nil // Older Mailbox, fall back to sending the message directly
+	}
+	if err != nil {
+		return false, fmt.Errorf("pre-delivery check for '%s' failed: %w", msg.RecipientEmail, err)
+	}
+	if !resp.GetAccept() {
+		return !resp.GetRetryable(), fmt.Errorf("mailbox cannot accept mail for '%s': %s", msg.RecipientEmail, resp.GetReason())
+	}
+	return false, nil
+}
@@ -0,0 +1,347 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	queueWorkers        = 4                // Maximum number of concurrent delivery attempts from the queue
+	defaultDrainTimeout = 10 * time.Second // How long close tries to deliver queued mail that is not persisted
+	outboundQueueFile   = "outbound_queue.json"
+)
+
+// queuedDelivery is a message copy addressed to a single recipient, waiting in the delivery queue.
+type queuedDelivery struct {
+	msg           *proto.MailMessage
+	attempts      int       // Delivery attempts made so far
+	nextAttempt   time.Time // Earliest time of the next attempt
+	deferredSince time.Time // When attempts started returning a deferredError, zero otherwise
+
+	// spanContext is the span of the SendMail call that queued the message, which its delivery attempts
+	// continue. It is not persisted, so attempts after a restart start a trace of their own.
+	spanContext trace.SpanContext
+}
+
+// context returns the context of a delivery attempt for item, carrying the span it was queued in.
+func (item *queuedDelivery) context() context.Context {
+	return trace.ContextWithSpanContext(context.Background(), item.spanContext)
+}
+
+// deferredError is returned by a delivery attempt that cannot succeed yet but may later, such as for a
+// recipient that is not registered yet. The queue retries it after retryAfter without counting the
+// attempt against the retry limit, until the message has been deferred for longer than ttl.
+type deferredError struct {
+	err        error
+	retryAfter time.Duration
+	ttl        time.Duration
+}
+
+func (e *deferredError) Error() string { return e.err.Error() }
+
+func (e *deferredError) Unwrap() error { return e.err }
+
+// deliveryQueue delivers queued messages in the background. Each delivery attempt is made by
+// attempt; transient failures are retried with exponential backoff as set by retry,
+// after which (or after a permanent failure) finish is called with the last error and whether it was permanent.
+type deliveryQueue struct {
+	attempt func(ctx context.Context, msg *proto.MailMessage, retries int) (permanent bool, err error)
+	finish  func(ctx context.Context, msg *proto.MailMessage, err error, permanent bool) // err is nil if the message was delivered
+	retry   retryPolicy
+
+	mu       sync.Mutex
+	pending  []*queuedDelivery
+	inFlight map[*queuedDelivery]bool // Deliveries currently being attempted
+	paused   bool
+
+	statePath    string        // File the queue is persisted to (empty keeps it in memory only)
+	drainTimeout time.Duration // Final delivery pass on close when the queue is not persisted
+
+	wake       chan struct{} // Signals the dispatcher that the queue state changed
+	stop       chan struct{}
+	dispatcher sync.WaitGroup
+	deliveries sync.WaitGroup
+}
+
+// newDeliveryQueue creates a delivery queue and starts its dispatcher. If statePath is set, mail queued
+// before a restart is loaded from it and every change is persisted there; otherwise close makes a final
+// delivery pass of up to drainTimeout (0 uses the default).
+func newDeliveryQueue(attempt func(context.Context, *proto.MailMessage, int) (bool, error), finish func(context.Context, *proto.MailMessage, error, bool),
+	retry retryPolicy, statePath string, drainTimeout time.Duration) (*deliveryQueue, error) {
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+	pending, err := loadQueue(statePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		logger().Info("Loaded queued deliveries", "deliveries", len(pending), "path", statePath)
+	}
+	q := &deliveryQueue{
+		attempt:      attempt,
+		finish:       finish,
+		retry:        retry,
+		pending:      pending,
+		inFlight:     make(map[*queuedDelivery]bool),
+		statePath:    statePath,
+		drainTimeout: drainTimeout,
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}
+	q.dispatcher.Add(1)
+	go q.run()
+	return q, nil
+}
+
+// enqueue adds msg for immediate delivery. Its delivery attempts continue the span in ctx, if any.
+func (q *deliveryQueue) enqueue(ctx context.Context, msg *proto.MailMessage) {
+	q.mu.Lock()
+	q.pending = append(q.pending, &queuedDelivery{msg: msg, nextAttempt: time.Now(), spanContext: trace.SpanContextFromContext(ctx)})
+	q.persistLocked()
+	q.mu.Unlock()
+	q.signal()
+}
+
+// pause stops new delivery attempts; queued messages accumulate until resume is called.
+// Attempts already in flight are allowed to finish.
+func (q *deliveryQueue) pause() {
+	q.mu.Lock()
+	q.paused = true
+	q.mu.Unlock()
+	logger().Info("Delivery queue paused")
+}
+
+// resume restarts delivery attempts after pause.
+func (q *deliveryQueue) resume() {
+	q.mu.Lock()
+	q.paused = false
+	q.mu.Unlock()
+	logger().Info("Delivery queue resumed")
+	q.signal()
+}
+
+// flush makes every pending message due now, so waiting retries are attempted without their backoff.
+// A paused queue stays paused; the flushed messages are attempted on resume.
+func (q *deliveryQueue) flush() {
+	q.mu.Lock()
+	now := time.Now()
+	for _, item := range q.pending {
+		item.nextAttempt = now
+	}
+	flushed := len(q.pending)
+	q.mu.Unlock()
+	logger().Info("Flushed queued messages for immediate delivery", "messages", flushed)
+	q.signal()
+}
+
+// status reports whether the queue is paused, how many messages wait and how many are being delivered.
+func (q *deliveryQueue) status() *proto.QueueStatusResponse {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &proto.QueueStatusResponse{
+		Paused:   q.paused,
+		Queued:   int32(len(q.pending)),
+		InFlight: int32(len(q.inFlight)),
+	}
+}
+
+// countMessage returns the number of copies of messageID that are queued or being delivered.
+func (q *deliveryQueue) countMessage(messageID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, item := range q.pending {
+		if item.msg.MessageId == messageID {
+			n++
+		}
+	}
+	for item := range q.inFlight {
+		if item.msg.MessageId == messageID {
+			n++
+		}
+	}
+	return n
+}
+
+// close stops the dispatcher and waits for in-flight attempts. Mail still queued is then flushed to
+// disk if the queue is persisted, so it is delivered after a restart. Otherwise close makes one final,
+// best-effort delivery attempt for each message within the drain timeout; mail that still could not be
+// delivered is logged and finished as failed.
+func (q *deliveryQueue) close() {
+	close(q.stop)
+	q.dispatcher.Wait()
+	q.deliveries.Wait()
+
+	q.mu.Lock()
+	pending := q.pending
+	if q.statePath != "" {
+		q.persistLocked()
+		q.mu.Unlock()
+		if len(pending) > 0 {
+			logger().Info("Persisted queued deliveries for the next start", "deliveries", len(pending))
+		}
+		return
+	}
+	q.pending = nil
+	q.mu.Unlock()
+	q.drain(pending)
+}
+
+// drain attempts delivery of items once each, in parallel, until the drain timeout expires.
+func (q *deliveryQueue) drain(items []*queuedDelivery) {
+	if len(items) == 0 {
+		return
+	}
+	logger().Info("Draining queued deliveries before shutdown", "deliveries", len(items), "timeout", q.drainTimeout)
+	deadline := time.Now().Add(q.drainTimeout)
+	work := make(chan *queuedDelivery)
+	var workers sync.WaitGroup
+	for i := 0; i < queueWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for item := range work {
+				permanent, err := q.attempt(item.context(), item.msg, item.attempts)
+				if err != nil {
+					logger().Warn("Could not deliver queued mail before shutdown", "message_id", item.msg.MessageId,
+						"recipient", item.msg.RecipientEmail, "error", err)
+				}
+				q.finish(item.context(), item.msg, err, permanent)
+			}
+		}()
+	}
+	for i, item := range items {
+		if time.Now().After(deadline) {
+			for _, skipped := range items[i:] {
+				logger().Warn("Drain timeout expired, dropping queued mail", "message_id", skipped.msg.MessageId,
+					"recipient", skipped.msg.RecipientEmail)
+				q.finish(skipped.context(), skipped.msg, fmt.Errorf("not delivered before shutdown"), false)
+			}
+			break
+		}
+		work <- item
+	}
+	close(work)
+	workers.Wait()
+}
+
+// signal wakes the dispatcher without blocking.
+func (q *deliveryQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run dispatches due messages to delivery goroutines until the queue is closed.
+func (q *deliveryQueue) run() {
+	defer q.dispatcher.Done()
+	for {
+		var timer <-chan time.Time
+		q.mu.Lock()
+		for !q.paused && len(q.inFlight) < queueWorkers {
+			item := q.takeDueLocked(time.Now())
+			if item == nil {
+				break
+			}
+			q.inFlight[item] = true
+			q.deliveries.Add(1)
+			go q.process(item)
+		}
+		if !q.paused && len(q.inFlight) < queueWorkers && len(q.pending) > 0 {
+			timer = time.After(time.Until(q.earliestLocked()))
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.wake:
+		case <-timer:
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// takeDueLocked removes and returns the first message due at now, or nil. q.mu must be held.
+func (q *deliveryQueue) takeDueLocked(now time.Time) *queuedDelivery {
+	for i, item := range q.pending {
+		if !item.nextAttempt.After(now) {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return item
+		}
+	}
+	return nil
+}
+
+// earliestLocked returns the earliest next-attempt time of the pending messages. q.mu must be held.
+func (q *deliveryQueue) earliestLocked() time.Time {
+	earliest := q.pending[0].nextAttempt
+	for _, item := range q.pending[1:] {
+		if item.nextAttempt.Before(earliest) {
+			earliest = item.nextAttempt
+		}
+	}
+	return earliest
+}
+
+// process makes one delivery attempt for item and either finishes it or schedules a retry. The item's fields
+// are only changed under q.mu, since persistLocked reads every in-flight item.
+func (q *deliveryQueue) process(item *queuedDelivery) {
+	defer q.deliveries.Done()
+	permanent, err := q.attempt(item.context(), item.msg, item.attempts)
+	now := time.Now()
+	var deferred *deferredError
+	if errors.As(err, &deferred) && !permanent {
+		q.mu.Lock()
+		if item.deferredSince.IsZero() {
+			item.deferredSince = now
+		}
+		deferredFor := now.Sub(item.deferredSince)
+		if deferredFor < deferred.ttl {
+			delete(q.inFlight, item)
+			item.nextAttempt = now.Add(deferred.retryAfter)
+			q.pending = append(q.pending, item)
+			q.persistLocked()
+			q.mu.Unlock()
+			logger().Info("Queued delivery deferred", "message_id", item.msg.MessageId, "retry_in", deferred.retryAfter,
+				"deferred_for", deferredFor.Round(time.Second), "ttl", deferred.ttl, "error", err)
+			q.signal()
+			return
+		}
+		q.mu.Unlock()
+		permanent = true // Deferred for too long
+		err = fmt.Errorf("%w (gave up after %s)", err, deferred.ttl)
+	}
+	q.mu.Lock()
+	if deferred == nil {
+		item.deferredSince = time.Time{}
+	}
+	item.attempts++
+	attempts := item.attempts
+	q.mu.Unlock()
+
+	done := err == nil || permanent || attempts > q.retry.maxRetries
+	if done {
+		q.finish(item.context(), item.msg, err, permanent) // While still in flight, so the message is always either queued or finished
+	}
+	q.mu.Lock()
+	delete(q.inFlight, item)
+	if !done {
+		backoff := q.retry.backoff(attempts)
+		item.nextAttempt = time.Now().Add(backoff)
+		q.pending = append(q.pending, item)
+		deliveryRetries.Inc()
+		logger().Warn("Queued delivery failed, retrying", "recipient", item.msg.RecipientEmail, "attempt", attempts,
+			"attempts", q.retry.maxRetries+1, "retry_in", backoff, "error", err)
+	}
+	q.persistLocked()
+	q.mu.Unlock()
+	q.signal()
+}
@@ -0,0 +1,94 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// storedDelivery is the on-disk form of a queued delivery.
+type storedDelivery struct {
+	Message       json.RawMessage `json:"Message"` // protojson-encoded MailMessage
+	Attempts      int             `json:"Attempts"`
+	DeferredSince int64           `json:"DeferredSince,omitempty"` // Unix time the delivery was first deferred
+}
+
+// loadQueue reads persisted deliveries from path, all due immediately. A missing file or empty path
+// yields an empty queue.
+func loadQueue(path string) ([]*queuedDelivery, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbound queue '%s': %w", path, err)
+	}
+	var stored []storedDelivery
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbound queue from '%s': %w", path, err)
+	}
+	now := time.Now()
+	items := make([]*queuedDelivery, 0, len(stored))
+	for _, sd := range stored {
+		msg := &proto.MailMessage{}
+		if err := protojson.Unmarshal(sd.Message, msg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal queued message from '%s': %w", path, err)
+		}
+		item := &queuedDelivery{msg: msg, attempts: sd.Attempts, nextAttempt: now}
+		if sd.DeferredSince != 0 {
+			item.deferredSince = time.Unix(sd.DeferredSince, 0) // Keep the TTL running across restarts
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// persistLocked saves the pending and in-flight deliveries if the queue is persisted, so a crash loses
+// no accepted mail. Failures are logged; delivery continues from memory. q.mu must be held.
+func (q *deliveryQueue) persistLocked() {
+	if q.statePath == "" {
+		return
+	}
+	stored := make([]storedDelivery, 0, len(q.pending)+len(q.inFlight))
+	add := func(item *queuedDelivery) error {
+		raw, err := protojson.Marshal(item.msg)
+		if err != nil {
+			return err
+		}
+		sd := storedDelivery{Message: raw, Attempts: item.attempts}
+		if !item.deferredSince.IsZero() {
+			sd.DeferredSince = item.deferredSince.Unix()
+		}
+		stored = append(stored, sd)
+		return nil
+	}
+	for item := range q.inFlight {
+		if err := add(item); err != nil {
+			logger().Error("Failed to marshal queued message", "message_id", item.msg.MessageId, "error", err)
+		}
+	}
+	for _, item := range q.pending {
+		if err := add(item); err != nil {
+			logger().Error("Failed to marshal queued message", "message_id", item.msg.MessageId, "error", err)
+		}
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(q.statePath), 0o755); err == nil {
+			err = common.WriteFileAtomic(q.statePath, data, 0o644)
+		}
+	}
+	if err != nil {
+		logger().Error("Failed to persist outbound queue", "path", q.statePath, "error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// senderQuotasFile is the name of the sender quota counters inside the state directory.
+const senderQuotasFile = "sender_quotas.json"
+
+// senderCount is the number of messages a sender has sent on one (UTC) day.
+type senderCount struct {
+	Day   string `json:"Day"` // Date in YYYY-MM-DD form
+	Count int    `json:"Count"`
+}
+
+// senderQuotaStore enforces a daily cap on messages per sender, optionally backed by a JSON file
+// so that a restart does not reset the counters mid-day.
+type senderQuotaStore struct {
+	mu       sync.Mutex
+	path     string // Empty path keeps counters in memory only
+	dailyCap int    // 0 means unlimited
+	counts   map[string]*senderCount
+}
+
+// newSenderQuotaStore creates a quota store allowing dailyCap messages per sender and day,
+// persisted at path and loading any existing counters. An empty path creates an in-memory store.
+func newSenderQuotaStore(path string, dailyCap int) (*senderQuotaStore, error) {
+	st := &senderQuotaStore{
+		path:     path,
+		dailyCap: dailyCap,
+		counts:   make(map[string]*senderCount),
+	}
+	if path == "" || dailyCap <= 0 {
+		return st, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sender quotas '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &st.counts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sender quotas from '%s': %w", path, err)
+	}
+	return st, nil
+}
+
+// take counts one message from sender at now. It reports false, without counting, if the sender
+// has already reached the daily cap. Counters reset when the UTC day changes.
+func (st *senderQuotaStore) take(sender string, now time.Time) (bool, error) {
+	if st.dailyCap <= 0 {
+		return true, nil
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	day := now.UTC().Format("2006-01-02")
+	count, found := st.counts[sender]
+	if !found || count.Day != day {
+		count = &senderCount{Day: day}
+		st.counts[sender] = count
+	}
+	if count.Count >= st.dailyCap {
+		return false, nil
+	}
+	count.Count++
+	return true, st.saveLocked(day)
+}
+
+// saveLocked writes the counters of today to the store's file, if any, dropping those of past days.
+// st.mu must be held.
+func (st *senderQuotaStore) saveLocked(today string) error {
+	for sender, count := range st.counts {
+		if count.Day != today {
+			delete(st.counts, sender)
+		}
+	}
+	if st.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(st.counts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sender quotas: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory for '%s': %w", st.path, err)
+	}
+	if err := common.WriteFileAtomic(st.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write sender quotas '%s': %w", st.path, err)
+	}
+	return nil
+}
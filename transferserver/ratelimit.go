@@ -0,0 +1,93 @@
+package transferserver
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const rateLimitSweepInterval = time.Minute // How often buckets of idle senders are dropped
+
+// senderRateLimiter is a token-bucket rate limiter per sender. Each sender's bucket holds up to burst tokens
+// and refills at rate tokens per second; every message takes one. A nil *senderRateLimiter allows everything.
+type senderRateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is the state of one sender's bucket as of last.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newSenderRateLimiter returns a limiter allowing rate messages per second and bursts of burst messages per
+// sender, or nil if rate is 0. A zero burst uses the rate rounded up, at least 1.
+func newSenderRateLimiter(rate float64, burst int) (*senderRateLimiter, error) {
+	if rate < 0 || burst < 0 {
+		return nil, fmt.Errorf("sender rate limit and burst cannot be negative")
+	}
+	if rate == 0 {
+		return nil, nil
+	}
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &senderRateLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*tokenBucket)}, nil
+}
+
+// allow takes a token from the bucket of sender at now, reporting false if the bucket is empty.
+func (l *senderRateLimiter) allow(sender string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweepLocked(now)
+	}
+	bucket, found := l.buckets[sender]
+	if !found {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[sender] = bucket
+	}
+	bucket.tokens = l.refilled(bucket, now)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// refilled returns the tokens in bucket at now, capped at the burst size.
+func (l *senderRateLimiter) refilled(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0 // The clock went backwards
+	}
+	return math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+}
+
+// sweepLocked drops the buckets that have refilled completely: a full bucket is no different from a new one.
+// l.mu must be held.
+func (l *senderRateLimiter) sweepLocked(now time.Time) {
+	for sender, bucket := range l.buckets {
+		if l.refilled(bucket, now) >= l.burst {
+			delete(l.buckets, sender)
+		}
+	}
+	l.lastSweep = now
+}
+
+// size returns the number of tracked senders.
+func (l *senderRateLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,108 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recipientGroup is one delivery target of a send together with the recipient entries of the message
+// that resolved to it. Without normalization every group holds exactly one entry.
+type recipientGroup struct {
+	address string   // Address the copy is delivered to (the entry itself if it could not be parsed)
+	entries []string // Recipient entries as written by the sender, each reported separately
+	err     error    // Set if the entries could not be parsed; nothing is delivered then
+}
+
+// groupRecipients returns the delivery targets of msg. Without normalize, exact duplicates are dropped as
+// before. With normalize, entries are canonicalized with common.ParseEmail, so entries differing only in
+// case or display name are delivered once while every original entry is kept for reporting.
+func groupRecipients(msg *proto.MailMessage, normalize bool) []recipientGroup {
+	if !normalize {
+		var groups []recipientGroup
+		for _, r := range messageRecipients(msg) {
+			groups = append(groups, recipientGroup{address: r, entries: []string{r}})
+		}
+		return groups
+	}
+
+	var groups []recipientGroup
+	index := make(map[string]int) // Address -> position in groups
+	for _, list := range [][]string{{msg.RecipientEmail}, msg.To, msg.Cc, msg.Bcc} {
+		for _, entry := range list {
+			if entry == "" {
+				continue
+			}
+			address, err := common.ParseEmail(entry)
+			if err != nil {
+				address = entry // Reported as given, never delivered
+			}
+			if i, ok := index[address]; ok {
+				groups[i].entries = append(groups[i].entries, entry)
+				continue
+			}
+			index[address] = len(groups)
+			groups = append(groups, recipientGroup{address: address, entries: []string{entry}, err: err})
+		}
+	}
+	return groups
+}
+
+// groupError returns the error reported for the entries of a group that could not be parsed.
+func (g recipientGroup) groupError() error {
+	return status.Errorf(codes.InvalidArgument, "%v", g.err)
+}
+
+// countEntries returns the number of recipient entries across groups.
+func countEntries(groups []recipientGroup) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g.entries)
+	}
+	return n
+}
+
+// entryOutcomes returns a copy of outcome for each entry of the group, so every entry is reported.
+func (g recipientGroup) entryOutcomes(outcome recipientOutcome) []recipientOutcome {
+	outcomes := make([]recipientOutcome, 0, len(g.entries))
+	for _, entry := range g.entries {
+		o := outcome
+		o.RecipientEmail = entry
+		outcomes = append(outcomes, o)
+	}
+	return outcomes
+}
+
+// queuedEntries remembers the original recipient entries of queued copies, so the outcome of an
+// asynchronous delivery can be reported per entry.
+type queuedEntries struct {
+	mu      sync.Mutex
+	entries map[string][]string // messageID + "\x00" + delivery address -> entries
+}
+
+// remember records the entries of a copy of messageID queued for address.
+func (q *queuedEntries) remember(messageID, address string, entries []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.entries == nil {
+		q.entries = make(map[string][]string)
+	}
+	q.entries[messageID+"\x00"+address] = entries
+}
+
+// take returns and forgets the entries of the copy of messageID delivered to address.
+// It returns address itself if nothing was remembered.
+func (q *queuedEntries) take(messageID, address string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := messageID + "\x00" + address
+	entries, ok := q.entries[key]
+	if !ok {
+		return []string{address}
+	}
+	delete(q.entries, key)
+	return entries
+}
@@ -0,0 +1,91 @@
+package transferserver
+
+import (
+	"GoDissys/proto/proto"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const capacityProbeTimeout = time.Second // Timeout for asking a replica for its remaining capacity
+
+// resolveMailbox looks up the mailbox addresses to deliver mail for recipient to, in the order they are to be
+// tried. If several replicas serve the recipient, the one advertising the most remaining capacity comes first,
+// followed by the others in the Nameserver's order as failover targets. The result is served from the lookup
+// cache while it is fresh.
+func (s *server) resolveMailbox(ctx context.Context, recipient string) (addrs []string, found bool, err error) {
+	if addrs, ok := s.lookupCache.get(recipient); ok {
+		lookupCacheResults.WithLabelValues("hit").Inc()
+		return addrs, true, nil
+	}
+	if s.lookupCache != nil {
+		lookupCacheResults.WithLabelValues("miss").Inc()
+	}
+	addrs, found, err = s.lookupMailbox(ctx, recipient)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	addrs = failoverOrder(addrs, s.selectReplica(recipient, addrs))
+	s.lookupCache.put(recipient, addrs)
+	return addrs, true, nil
+}
+
+// failoverOrder returns addrs with preferred moved to the front.
+func failoverOrder(addrs []string, preferred string) []string {
+	ordered := []string{preferred}
+	for _, addr := range addrs {
+		if addr != preferred {
+			ordered = append(ordered, addr)
+		}
+	}
+	return ordered
+}
+
+// selectReplica returns the address among addrs whose Mailbox reports the most remaining capacity, with
+// unlimited capacity counting as the most. Replicas that cannot be asked are skipped; ties and the case
+// that no replica answers fall back to the earliest address, i.e. the primary one.
+func (s *server) selectReplica(recipient string, addrs []string) string {
+	if len(addrs) == 1 {
+		return addrs[0]
+	}
+	free := make([]int64, len(addrs))
+	var probes sync.WaitGroup
+	for i, addr := range addrs {
+		probes.Add(1)
+		go func(i int, addr string) {
+			defer probes.Done()
+			free[i] = s.remainingCapacity(addr)
+		}(i, addr)
+	}
+	probes.Wait()
+
+	best := 0
+	for i := range addrs {
+		if free[i] > free[best] {
+			best = i
+		}
+	}
+	logger().Info("Selected replica", "recipient", recipient, "mailbox_addr", addrs[best], "replicas", len(addrs), "free", free)
+	return addrs[best]
+}
+
+// remainingCapacity asks the Mailbox at addr for the number of messages it still has room for.
+// It returns math.MaxInt64 for unlimited capacity and -1 if the Mailbox cannot be asked.
+func (s *server) remainingCapacity(addr string) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), capacityProbeTimeout)
+	defer cancel()
+	conn, err := s.conns.get(addr)
+	if err != nil {
+		return -1
+	}
+	info, err := proto.NewMailboxClient(conn).Info(ctx, &proto.MailboxInfoRequest{})
+	if err != nil {
+		logger().Warn("Could not ask replica for its capacity", "mailbox_addr", addr, "error", err)
+		return -1
+	}
+	if info.GetCapacity() == 0 {
+		return math.MaxInt64
+	}
+	return int64(info.GetRemainingCapacity())
+}
@@ -0,0 +1,209 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	deliveryReportsFile = "delivery_reports.jsonl" // Journal of the delivery report store inside the state directory
+
+	reportCompactSlack = 256 // Journal records tolerated beyond twice the reports before compacting
+)
+
+// recipientOutcome is the persisted delivery outcome for a single recipient.
+type recipientOutcome struct {
+	RecipientEmail string `json:"RecipientEmail"`
+	Success        bool   `json:"Success"`
+	Message        string `json:"Message"`
+	Timestamp      int64  `json:"Timestamp"`
+}
+
+// deliveryReport is the persisted record of a single send and its per-recipient outcomes.
+type deliveryReport struct {
+	MessageID   string             `json:"MessageID"`
+	SenderEmail string             `json:"SenderEmail"`
+	CreatedAt   int64              `json:"CreatedAt"`
+	Recipients  []recipientOutcome `json:"Recipients"`
+}
+
+// reportRecord is one change to the delivery report store, as journaled on disk: either a complete report
+// or a single outcome added to one.
+type reportRecord struct {
+	Report      *deliveryReport   `json:"Report,omitempty"`
+	MessageID   string            `json:"MessageID,omitempty"`
+	SenderEmail string            `json:"SenderEmail,omitempty"`
+	Outcome     *recipientOutcome `json:"Outcome,omitempty"`
+}
+
+// deliveryReportStore keeps delivery reports by message ID, optionally journaled to a file so each change
+// writes only the affected report or outcome.
+type deliveryReportStore struct {
+	mu      sync.RWMutex
+	journal *common.Journal // Nil keeps reports in memory only
+	reports map[string]*deliveryReport
+}
+
+// newDeliveryReportStore creates a report store journaled at path, replaying any existing reports.
+// An empty path creates an in-memory store.
+func newDeliveryReportStore(path string) (*deliveryReportStore, error) {
+	st := &deliveryReportStore{reports: make(map[string]*deliveryReport)}
+	if path == "" {
+		return st, nil
+	}
+
+	journal, err := common.OpenJournal(path, 0o644, func(raw json.RawMessage) error {
+		var record reportRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		switch {
+		case record.Report != nil:
+			st.reports[record.Report.MessageID] = record.Report
+		case record.Outcome != nil:
+			st.addOutcomeLocked(record.MessageID, record.SenderEmail, *record.Outcome)
+		default:
+			return fmt.Errorf("empty delivery report record")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery reports: %w", err)
+	}
+	st.journal = journal
+	return st, nil
+}
+
+// record stores report, replacing any previous report for the same message ID, and persists it.
+func (st *deliveryReportStore) record(report *deliveryReport) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.reports[report.MessageID] = report
+	return st.appendLocked(reportRecord{Report: report})
+}
+
+// recordOutcome sets the outcome for one recipient in the report of messageID, creating the report if needed,
+// and persists the outcome. It replaces an earlier outcome for the recipient, such as the failure of a
+// dead letter that has now been re-driven.
+func (st *deliveryReportStore) recordOutcome(messageID, senderEmail string, outcome recipientOutcome) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.addOutcomeLocked(messageID, senderEmail, outcome)
+	return st.appendLocked(reportRecord{MessageID: messageID, SenderEmail: senderEmail, Outcome: &outcome})
+}
+
+// addOutcomeLocked sets outcome in the report of messageID, replacing any earlier outcome for the same
+// recipient and creating the report if needed. st.mu must be held.
+func (st *deliveryReportStore) addOutcomeLocked(messageID, senderEmail string, outcome recipientOutcome) {
+	report, found := st.reports[messageID]
+	if !found {
+		report = &deliveryReport{MessageID: messageID, SenderEmail: senderEmail, CreatedAt: outcome.Timestamp}
+		st.reports[messageID] = report
+	}
+	for i, o := range report.Recipients {
+		if o.RecipientEmail == outcome.RecipientEmail {
+			report.Recipients[i] = outcome
+			return
+		}
+	}
+	report.Recipients = append(report.Recipients, outcome)
+}
+
+// appendLocked journals record, compacting the journal to one record per report once it has grown well
+// beyond that. st.mu must be held.
+func (st *deliveryReportStore) appendLocked(record reportRecord) error {
+	if st.journal == nil {
+		return nil
+	}
+	if err := st.journal.Append(record); err != nil {
+		return err
+	}
+	if st.journal.Len() <= 2*len(st.reports)+reportCompactSlack {
+		return nil
+	}
+	records := make([]any, 0, len(st.reports))
+	for _, report := range st.reports {
+		records = append(records, reportRecord{Report: report})
+	}
+	return st.journal.Compact(records)
+}
+
+// close closes the store's journal, if any.
+func (st *deliveryReportStore) close() error {
+	if st.journal == nil {
+		return nil
+	}
+	return st.journal.Close()
+}
+
+// get returns the report for messageID as a proto response, or nil if none was recorded.
+func (st *deliveryReportStore) get(messageID string) *proto.DeliveryReportResponse {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	report, found := st.reports[messageID]
+	if !found {
+		return nil
+	}
+	resp := &proto.DeliveryReportResponse{
+		Found:       true,
+		MessageId:   report.MessageID,
+		SenderEmail: report.SenderEmail,
+		CreatedAt:   report.CreatedAt,
+	}
+	for _, r := range report.Recipients {
+		resp.Results = append(resp.Results, r.toProto())
+	}
+	return resp
+}
+
+// latestOutcomes returns the latest outcome recorded for each recipient of messageID, in the order the
+// recipients were first reported. A later outcome, such as a re-driven dead letter, replaces an earlier one.
+func (st *deliveryReportStore) latestOutcomes(messageID string) []recipientOutcome {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	report, found := st.reports[messageID]
+	if !found {
+		return nil
+	}
+	var outcomes []recipientOutcome
+	index := make(map[string]int)
+	for _, o := range report.Recipients {
+		if i, seen := index[o.RecipientEmail]; seen {
+			outcomes[i] = o
+			continue
+		}
+		index[o.RecipientEmail] = len(outcomes)
+		outcomes = append(outcomes, o)
+	}
+	return outcomes
+}
+
+// newRecipientOutcome builds the outcome of delivering to recipient from the delivery response or error.
+func newRecipientOutcome(recipient string, resp *proto.SendMailResponse, err error) recipientOutcome {
+	outcome := recipientOutcome{RecipientEmail: recipient, Timestamp: time.Now().Unix()}
+	if err != nil {
+		outcome.Message = err.Error()
+	} else {
+		outcome.Success = resp.GetSuccess()
+		outcome.Message = resp.GetMessage()
+	}
+	return outcome
+}
+
+// toProto converts the outcome into its proto representation.
+func (o recipientOutcome) toProto() *proto.RecipientResult {
+	return &proto.RecipientResult{
+		RecipientEmail: o.RecipientEmail,
+		Success:        o.Success,
+		Message:        o.Message,
+		Timestamp:      o.Timestamp,
+	}
+}
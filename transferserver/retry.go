@@ -0,0 +1,67 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMaxRetries     = 3                      // Maximum number of retries for mail delivery to mailbox
+	defaultInitialBackoff = 500 * time.Millisecond // Initial delay before retrying
+	defaultMaxBackoff     = 5 * time.Second        // Maximum delay between retries
+)
+
+// retryPolicy is the common.RetryPolicy of a TransferServer with defaults filled in.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// newRetryPolicy fills in the defaults for the zero fields of cfg and an unset MaxRetries. It fails for negative
+// values and for an initial backoff above the maximum.
+func newRetryPolicy(cfg common.RetryPolicy) (retryPolicy, error) {
+	p := retryPolicy{maxRetries: defaultMaxRetries, initialBackoff: time.Duration(cfg.InitialBackoff), maxBackoff: time.Duration(cfg.MaxBackoff)}
+	if cfg.MaxRetries != nil {
+		p.maxRetries = *cfg.MaxRetries // 0 disables retries
+	}
+	if p.maxRetries < 0 || p.initialBackoff < 0 || p.maxBackoff < 0 {
+		return retryPolicy{}, fmt.Errorf("retry policy values cannot be negative")
+	}
+	if p.initialBackoff == 0 {
+		p.initialBackoff = defaultInitialBackoff
+	}
+	if p.maxBackoff == 0 {
+		p.maxBackoff = defaultMaxBackoff
+	}
+	if p.initialBackoff > p.maxBackoff {
+		return retryPolicy{}, fmt.Errorf("initial backoff %s exceeds the maximum backoff %s", p.initialBackoff, p.maxBackoff)
+	}
+	return p, nil
+}
+
+// backoff returns the delay before the retry following the given number of failed attempts.
+func (p retryPolicy) backoff(attempts int) time.Duration {
+	backoff := p.initialBackoff
+	for i := 1; i < attempts && backoff < p.maxBackoff; i++ {
+		backoff *= 2 // Exponential backoff
+	}
+	if backoff > p.maxBackoff {
+		backoff = p.maxBackoff
+	}
+	return backoff
+}
+
+// retryable reports whether a failed ReceiveMail call may succeed when repeated. Only transient gRPC codes
+// qualify; anything else (e.g. a rejected message or blocked sender) fails the delivery immediately.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package transferserver
+
+import (
+	"GoDissys/common"
+	"GoDissys/proto/proto"
+	"fmt"
+	"strings"
+)
+
+// addressRewriter applies the configured rewrite rules to sender and recipient addresses.
+type addressRewriter struct {
+	rules []common.RewriteRule
+}
+
+// newAddressRewriter validates rules and returns a rewriter applying them in order.
+func newAddressRewriter(rules []common.RewriteRule) (*addressRewriter, error) {
+	for i, rule := range rules {
+		switch rule.Apply {
+		case common.RewriteSender, common.RewriteRecipient, common.RewriteBoth:
+		default:
+			return nil, fmt.Errorf("rewrite rule %d: unknown target '%s' (expected '%s', '%s' or '%s')",
+				i, rule.Apply, common.RewriteSender, common.RewriteRecipient, common.RewriteBoth)
+		}
+		if (rule.FromDomain == "") != (rule.ToDomain == "") {
+			return nil, fmt.Errorf("rewrite rule %d: FromDomain and ToDomain must be set together", i)
+		}
+	}
+	return &addressRewriter{rules: rules}, nil
+}
+
+// rewriteMessage rewrites the sender and all recipients of msg in place.
+func (rw *addressRewriter) rewriteMessage(msg *proto.MailMessage) {
+	msg.SenderEmail = rw.rewrite(msg.SenderEmail, common.RewriteSender)
+	msg.RecipientEmail = rw.rewrite(msg.RecipientEmail, common.RewriteRecipient)
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for i := range list {
+			list[i] = rw.rewrite(list[i], common.RewriteRecipient)
+		}
+	}
+}
+
+// rewrite applies every rule targeting kind ("sender" or "recipient") to address.
+func (rw *addressRewriter) rewrite(address, kind string) string {
+	local, domain, found := strings.Cut(address, "@")
+	if !found || len(rw.rules) == 0 {
+		return address
+	}
+	for _, rule := range rw.rules {
+		if rule.Apply != kind && rule.Apply != common.RewriteBoth {
+			continue
+		}
+		if rule.StripPlusTag {
+			local, _, _ = strings.Cut(local, "+")
+		}
+		if rule.FromDomain != "" && strings.EqualFold(domain, rule.FromDomain) {
+			domain = rule.ToDomain
+		}
+	}
+	rewritten := local + "@" + domain
+	if rewritten != address {
+		logger().Info("Rewrote address", "kind", kind, "address", address, "rewritten", rewritten)
+	}
+	return rewritten
+}
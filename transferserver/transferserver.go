@@ -1,173 +1,1128 @@
 package transferserver
 
 import (
+	"GoDissys/common"
 	"GoDissys/proto/proto"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
-	"syscall"
+	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 const (
-	maxRetries     = 3                      // Maximum number of retries for mail delivery to mailbox
-	initialBackoff = 500 * time.Millisecond // Initial delay before retrying
-	maxBackoff     = 5 * time.Second        // Maximum delay between retries
+	bulkDeliveryWorkers = 8  // Concurrent deliveries per SendMailBulk stream
+	bulkListBatch       = 64 // SendMailBulk recipients expanded as distribution lists per Nameserver call
+
+	maxReferralHops = 1 // Nameserver referrals followed per lookup, so referral loops terminate
+
+	defaultNotFoundTTL           = 10 * time.Minute // How long unregistered recipients are re-resolved under the retry policy
+	defaultNotFoundRetryInterval = 30 * time.Second // Delay between re-resolving an unregistered recipient
 )
 
+// logger returns the logger for the TransferServer's records, tagged with the service. It is looked up on
+// every call, so records follow the process-wide logger installed by common.SetupLogging.
+func logger() *slog.Logger {
+	return slog.With("service", "TransferServer")
+}
+
 // server is used to implement proto.TransferServerServer.
 type server struct {
 	proto.UnimplementedTransferServerServer
 	nameserverClient proto.NameserverClient
+	reports          *deliveryReportStore // Per-send delivery outcomes, queryable via DeliveryReport
+	rewriter         *addressRewriter     // Canonicalizes addresses before lookup
+	queue            *deliveryQueue       // Background delivery queue, nil unless async delivery is enabled
+	selfAddrs        []string             // Addresses of this TransferServer, never valid delivery targets
+	lookupCache      *lookupCache         // Recently resolved mailbox addresses, nil unless a lookup cache TTL is set
+	retry            retryPolicy          // Retries of failed deliveries, for SendMail and the queue
+
+	// stateDir and minFreeDiskBytes drive the low-disk check; freeDiskSpace is replaced in tests.
+	stateDir         string
+	minFreeDiskBytes uint64
+	freeDiskSpace    common.DiskSpaceFunc
+
+	verifySenders  bool // Reject mail from senders not registered with the Nameserver
+	senderFailOpen bool // Accept mail when sender verification cannot reach the Nameserver
+
+	// notFoundTTL is how long queued mail for an unregistered recipient is kept and re-resolved every
+	// notFoundRetryInterval (0 fails it immediately).
+	notFoundTTL           time.Duration
+	notFoundRetryInterval time.Duration
+
+	limits           sizeLimits               // Message size limits enforced before relay
+	preDeliveryCheck bool                     // Ask the recipient's Mailbox with CanAccept before sending the message
+	compression      common.CompressionPolicy // When deliveries to Mailboxes are gzip-compressed
+
+	normalizeRecipients bool          // Canonicalize recipients with common.ParseEmail and deliver duplicates once
+	queuedEntries       queuedEntries // Original recipient entries of queued copies, for per-entry reports
+
+	quotas      *senderQuotaStore  // Daily per-sender message caps
+	rateLimiter *senderRateLimiter // Per-sender token buckets, nil unless a sender rate limit is set
+	now         func() time.Time   // Current time; replaced in tests to control quota resets and rate limits
+	stats       deliveryStats      // Counters reported by Info
+
+	bounces               bool           // Notify senders of failed deliveries
+	bounceIncludeOriginal bool           // Echo the original message in bounces
+	postmasterAddress     string         // Sender of bounces; empty uses postmaster@<sender's domain>
+	bouncing              sync.WaitGroup // Bounces being delivered in the background
+
+	webhook *webhookNotifier // Receives an event per final delivery outcome, nil if not configured
+	conns   *connPool        // Connections to Mailboxes, shared by all deliveries
+	dialOpt grpc.DialOption  // Transport credentials for dialing Mailboxes and referred Nameservers
+
+	breakers *circuitBreakers // Per mailbox address, nil unless a circuit breaker threshold is set
+
+	deadLetters           *deadLetterStore // Deliveries whose retries were exhausted, for inspection and re-drive
+	deadLetterMaxAttempts int              // Background re-drive attempts per dead letter
+	deadLetterMaxAge      time.Duration    // Age after which dead letters are no longer re-driven in the background
+	deadLetterStop        chan struct{}    // Closed by Close to stop the background dead-letter retries
+	deadLetterRetrier     sync.WaitGroup
+
+	closeOnce sync.Once
 }
 
-// NewServer creates a new TransferServer instance.
+// NewServer creates a new TransferServer instance that keeps all state in memory.
 func NewServer(nameserverClient proto.NameserverClient) *server {
-	return &server{
+	s, _ := NewServerWithConfig(nameserverClient, common.TransferServerConfig{}) // Cannot fail without a state directory
+	return s
+}
+
+// NewServerWithConfig creates a new TransferServer instance from a full TransferServer configuration.
+// It fails if persisted state in cfg.StateDir cannot be loaded.
+func NewServerWithConfig(nameserverClient proto.NameserverClient, cfg common.TransferServerConfig) (*server, error) {
+	rewriter, err := newAddressRewriter(cfg.RewriteRules)
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := newSenderQuotaStore(common.StatePath(cfg.StateDir, cfg.InstanceName, senderQuotasFile), cfg.DailySenderQuota)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := newRetryPolicy(cfg.Retry)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter, err := newSenderRateLimiter(cfg.SenderRateLimit, cfg.SenderRateBurst)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.SenderVerificationPolicy {
+	case "", common.SenderVerificationFailClosed, common.SenderVerificationFailOpen:
+	default:
+		return nil, fmt.Errorf("unknown sender verification policy '%s'", cfg.SenderVerificationPolicy)
+	}
+	var notFoundTTL, notFoundRetryInterval time.Duration
+	switch cfg.RecipientNotFoundPolicy {
+	case "", common.RecipientNotFoundBounce:
+	case common.RecipientNotFoundRetry:
+		if !cfg.AsyncDelivery {
+			return nil, fmt.Errorf("recipient not found policy '%s' requires AsyncDelivery", cfg.RecipientNotFoundPolicy)
+		}
+		notFoundTTL, notFoundRetryInterval = time.Duration(cfg.RecipientNotFoundTTL), time.Duration(cfg.RecipientNotFoundRetryInterval)
+		if notFoundTTL <= 0 {
+			notFoundTTL = defaultNotFoundTTL
+		}
+		if notFoundRetryInterval <= 0 {
+			notFoundRetryInterval = defaultNotFoundRetryInterval
+		}
+	default:
+		return nil, fmt.Errorf("unknown recipient not found policy '%s'", cfg.RecipientNotFoundPolicy)
+	}
+	dialOpt, err := common.DialOption(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
+	reports, err := newDeliveryReportStore(common.StatePath(cfg.StateDir, cfg.InstanceName, deliveryReportsFile))
+	if err != nil {
+		return nil, err
+	}
+	deadLetters, err := newDeadLetterStore(common.StatePath(cfg.StateDir, cfg.InstanceName, deadLettersFile))
+	if err != nil {
+		reports.close()
+		return nil, err
+	}
+	deadLetterMaxAttempts, deadLetterMaxAge := cfg.DeadLetterMaxAttempts, time.Duration(cfg.DeadLetterMaxAge)
+	if deadLetterMaxAttempts <= 0 {
+		deadLetterMaxAttempts = defaultDeadLetterMaxAttempts
+	}
+	if deadLetterMaxAge <= 0 {
+		deadLetterMaxAge = defaultDeadLetterMaxAge
+	}
+	s := &server{
 		nameserverClient: nameserverClient,
+		retry:            retry,
+		reports:          reports,
+		rewriter:         rewriter,
+		stateDir:         cfg.StateDir,
+		minFreeDiskBytes: cfg.MinFreeDiskBytes,
+		freeDiskSpace:    common.FreeDiskSpace,
+		verifySenders:    cfg.VerifySenders,
+		senderFailOpen:   cfg.SenderVerificationPolicy == common.SenderVerificationFailOpen,
+
+		notFoundTTL:           notFoundTTL,
+		notFoundRetryInterval: notFoundRetryInterval,
+
+		limits:           newSizeLimits(cfg),
+		preDeliveryCheck: cfg.PreDeliveryCheck,
+		compression:      common.CompressionPolicy{Enabled: cfg.Compression, MinBytes: cfg.CompressionMinBytes},
+
+		normalizeRecipients: cfg.NormalizeRecipients,
+		quotas:              quotas,
+		rateLimiter:         rateLimiter,
+		now:                 time.Now,
+
+		bounces:               cfg.Bounces,
+		bounceIncludeOriginal: cfg.BounceIncludeOriginal,
+		postmasterAddress:     cfg.PostmasterAddress,
+
+		webhook: newWebhookNotifier(cfg.WebhookURL, cfg.WebhookRetries, time.Duration(cfg.WebhookRetryBackoff)),
+		conns:   newConnPool(dialOpt),
+		dialOpt: dialOpt,
+
+		deadLetters:           deadLetters,
+		deadLetterMaxAttempts: deadLetterMaxAttempts,
+		deadLetterMaxAge:      deadLetterMaxAge,
+		deadLetterStop:        make(chan struct{}),
+	}
+	s.lookupCache = newLookupCache(time.Duration(cfg.LookupCacheTTL), func() time.Time { return s.now() })
+	s.breakers = newCircuitBreakers(cfg.CircuitBreakerThreshold, time.Duration(cfg.CircuitBreakerCooldown), func() time.Time { return s.now() })
+	if cfg.SelfAddr != "" {
+		s.selfAddrs = append(s.selfAddrs, cfg.SelfAddr)
 	}
+	if cfg.AsyncDelivery {
+		queuePath := common.StatePath(cfg.StateDir, cfg.InstanceName, outboundQueueFile)
+		s.queue, err = newDeliveryQueue(s.attemptDelivery, s.finishQueued, s.retry, queuePath, time.Duration(cfg.QueueDrainTimeout))
+		if err != nil {
+			reports.close()
+			deadLetters.close()
+			return nil, err
+		}
+	}
+	if interval := time.Duration(cfg.DeadLetterRetryInterval); interval > 0 {
+		s.deadLetterRetrier.Add(1)
+		go func() {
+			defer s.deadLetterRetrier.Done()
+			s.runDeadLetterRetries(interval, s.deadLetterStop)
+		}()
+	}
+	return s, nil
+}
+
+// Close stops the background delivery queue and dead-letter retries, if any, waiting for in-flight attempts
+// and bounces to finish, and then closes the pooled Mailbox connections and the report and dead-letter stores. Queued
+// mail is persisted, or without a state directory delivered in a final pass (see deliveryQueue.close).
+// Calling Close again does nothing.
+func (s *server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.deadLetterStop)
+		s.deadLetterRetrier.Wait()
+		if s.queue != nil {
+			s.queue.close()
+		}
+		s.bouncing.Wait()
+		s.webhook.wait()
+		s.conns.close()
+		if err := s.deadLetters.close(); err != nil {
+			logger().Error("Failed to close dead letters", "error", err)
+		}
+		if err := s.reports.close(); err != nil {
+			logger().Error("Failed to close delivery reports", "error", err)
+		}
+	})
 }
 
 // StartTransferServer starts the gRPC server for the TransferServer.
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
 func StartTransferServer(nameserverAddr, transferServerAddr string) {
+	StartTransferServerWithConfig(nameserverAddr, transferServerAddr, common.TransferServerConfig{})
+}
+
+// StartTransferServerWithConfig starts the gRPC server for the TransferServer using the given configuration.
+// It also sets up graceful shutdown on SIGINT or SIGTERM.
+func StartTransferServerWithConfig(nameserverAddr, transferServerAddr string, cfg common.TransferServerConfig) {
+	ctx, stop := common.SignalContext()
+	defer stop()
+	StartTransferServerWithContext(ctx, nameserverAddr, transferServerAddr, cfg, nil)
+}
+
+// StartTransferServerWithContext runs the TransferServer like StartTransferServerWithConfig, but until ctx is
+// cancelled instead of until a signal arrives. It then stops the server gracefully and returns, so several
+// services can run in one process (or a test) and be stopped together. Unless nil, ready is called with the
+// listening address once the TransferServer is serving.
+func StartTransferServerWithContext(ctx context.Context, nameserverAddr, transferServerAddr string, cfg common.TransferServerConfig,
+	ready func(addr string)) {
+	handle, err := ServeTransferServer(nameserverAddr, transferServerAddr, cfg)
+	if err != nil {
+		logger().Error("TransferServer failed to start", "error", err)
+		return
+	}
+	if ready != nil {
+		ready(handle.Addr())
+	}
+	<-ctx.Done() // Block until the TransferServer is to stop
+	handle.Stop()
+}
+
+// ServeTransferServer starts the TransferServer serving in the background, using the Nameserver at
+// nameserverAddr, and returns once it listens. The returned handle reports the listening address and stops
+// the TransferServer gracefully.
+func ServeTransferServer(nameserverAddr, transferServerAddr string, cfg common.TransferServerConfig) (*common.ServerHandle, error) {
+	dialOpt, err := common.DialOption(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
+
 	// Connect to Nameserver to get its client
 	nameserverDialCtx, nameserverDialCancel := context.WithTimeout(context.Background(), time.Second*5)
-	nameserverConn, err := grpc.DialContext(nameserverDialCtx, nameserverAddr, grpc.WithInsecure()) // Insecure for practice
-	nameserverDialCancel()                                                                          // Ensure context is cancelled after DialContext returns
-
+	nameserverConn, err := grpc.DialContext(nameserverDialCtx, nameserverAddr, dialOpt, common.TracingDialOption())
+	nameserverDialCancel() // Ensure context is cancelled after DialContext returns
 	if err != nil {
-		log.Printf("TransferServer: Could not connect to Nameserver at %s: %v", nameserverAddr, err)
-		return // Return instead of Fatalf
+		return nil, fmt.Errorf("could not connect to Nameserver at %s: %w", nameserverAddr, err)
 	}
 
 	nameserverClient := proto.NewNameserverClient(nameserverConn)
+	checkNameserverHealth(nameserverClient) // Only warns: the Nameserver may still be starting
 
 	lis, err := net.Listen("tcp", transferServerAddr) // Use transferServerAddr
 	if err != nil {
-		log.Printf("TransferServer failed to listen on %s: %v", transferServerAddr, err)
 		nameserverConn.Close() // Close client connection if listen fails
-		return                 // Return instead of Fatalf
+		return nil, fmt.Errorf("failed to listen on %s: %w", transferServerAddr, err)
 	}
-	s := grpc.NewServer()
-	transferServerService := NewServer(nameserverClient)
+	transferServerService, err := NewServerWithConfig(nameserverClient, cfg)
+	if err != nil {
+		lis.Close()
+		nameserverConn.Close()
+		return nil, fmt.Errorf("failed to initialize: %w", err)
+	}
+	transferServerService.selfAddrs = append(transferServerService.selfAddrs, transferServerAddr, lis.Addr().String())
+	serverOpts, err := common.ServerOptions(cfg.TLS)
+	if err != nil {
+		transferServerService.Close()
+		lis.Close()
+		nameserverConn.Close()
+		return nil, fmt.Errorf("failed to set up TLS: %w", err)
+	}
+	opts := append(common.TracingServerOptions(), common.MetricsOptions("TransferServer")...)
+	opts = append(opts, common.RequestLogOptions("TransferServer", cfg.LogRequests)...)
+	s := grpc.NewServer(append(opts, serverOpts...)...)
 	proto.RegisterTransferServerServer(s, transferServerService)
-	log.Printf("TransferServer listening on %s", transferServerAddr)
+	logger().Info("TransferServer listening", "addr", lis.Addr().String())
 
 	// Goroutine to serve gRPC requests
 	go func() {
 		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
-			log.Printf("TransferServer failed to serve: %v", err)
+			logger().Error("TransferServer failed to serve", "error", err)
 		}
 	}()
 
-	// Set up graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit // Block until a signal is received
-	log.Printf("TransferServer received shutdown signal. Shutting down gracefully...")
-	s.GracefulStop() // Gracefully stop the gRPC server
-	log.Println("TransferServer server stopped.")
+	return common.NewServerHandle(lis.Addr().String(), func() {
+		logger().Info("TransferServer shutting down gracefully")
+		s.GracefulStop() // Gracefully stop the gRPC server
+		transferServerService.Close()
+		logger().Info("TransferServer stopped")
 
-	// Explicitly close the Nameserver client connection AFTER the server has stopped
-	nameserverConn.Close()
+		// Explicitly close the Nameserver client connection AFTER the server has stopped
+		nameserverConn.Close()
+	}), nil
 }
 
 // SendMail implements proto.TransferServerServer.
-// It receives a mail message from a client, looks up the recipient's mailbox,
-// and forwards the message to the appropriate mailbox with retry logic.
+// It receives a mail message from a client, looks up each recipient's mailbox,
+// and forwards a copy of the message to the appropriate mailbox with retry logic.
+// The per-recipient outcome is recorded and can be queried with DeliveryReport.
 func (s *server) SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.SendMailResponse, error) {
 	msg := req.GetMessage()
 	if msg == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "mail message cannot be empty")
 	}
-	if msg.RecipientEmail == "" {
+	async := s.queue != nil
+	if req.Async != nil {
+		async = req.GetAsync()
+	}
+	if async && s.queue == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "asynchronous delivery is not enabled")
+	}
+	if err := s.limits.check(msg); err != nil {
+		return nil, err
+	}
+	if err := s.checkDiskSpace(); err != nil {
+		return nil, err
+	}
+	s.rewriter.rewriteMessage(msg)
+	if err := s.verifySender(ctx, msg.SenderEmail); err != nil {
+		return nil, err
+	}
+	groups := s.expandLists(ctx, groupRecipients(msg, s.normalizeRecipients))
+	if len(groups) == 0 {
+		// Rejected before the rate limit and the quota, so a malformed send costs the sender nothing
 		return nil, status.Errorf(codes.InvalidArgument, "recipient email cannot be empty")
 	}
+	if err := s.checkSenderRate(msg.SenderEmail); err != nil {
+		return nil, err
+	}
+	if err := s.takeSenderQuota(msg.SenderEmail); err != nil {
+		return nil, err
+	}
+	if msg.MessageId == "" {
+		msg.MessageId = common.NewMessageID()
+	}
+	msg.SentAt = common.FormatSentAt(s.now()) // The time of acceptance, whatever the sender claims
+	recipients := make([]string, 0, len(groups))
+	for _, g := range groups {
+		recipients = append(recipients, g.address)
+	}
+	entries := countEntries(groups)
 
-	log.Printf("TransferServer: Received mail from '%s' for '%s' (Subject: %s)",
-		msg.SenderEmail, msg.RecipientEmail, msg.Subject)
+	logger().Info("Received mail", "message_id", msg.MessageId, "sender", msg.SenderEmail, "recipients", recipients, "subject", msg.Subject)
+	s.stats.accepted.Add(1)
 
-	// 1. Lookup recipient's mailbox address from Nameserver using the full email address
-	lookupCtx, lookupCancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer lookupCancel()
+	if async {
+		for _, g := range groups {
+			if g.err != nil {
+				s.finishEntries(ctx, msg, g, newRecipientOutcome(g.address, nil, g.groupError()))
+				continue
+			}
+			if s.normalizeRecipients {
+				s.queuedEntries.remember(msg.MessageId, g.address, g.entries)
+			}
+			s.queue.enqueue(ctx, copyForRecipient(msg, g.address))
+		}
+		return &proto.SendMailResponse{
+			Success:   true,
+			Message:   fmt.Sprintf("Mail queued for delivery to %d recipient(s)", len(recipients)),
+			MessageId: msg.MessageId,
+		}, nil
+	}
 
-	lookupReq := &proto.LookupMailboxRequest{EmailAddress: msg.RecipientEmail}
-	lookupResp, err := s.nameserverClient.LookupMailbox(lookupCtx, lookupReq)
-	if err != nil {
-		log.Printf("TransferServer: Error looking up mailbox for '%s': %v", msg.RecipientEmail, err)
-		return nil, status.Errorf(codes.Internal, "failed to lookup recipient mailbox: %v", err)
+	report := &deliveryReport{
+		MessageID:   msg.MessageId,
+		SenderEmail: msg.SenderEmail,
+		CreatedAt:   s.now().Unix(),
+	}
+	var failures []string
+	var firstResp *proto.SendMailResponse
+	var firstErr error
+	for _, g := range groups {
+		resp, outcome, err := s.deliverGroup(ctx, msg, g)
+		if len(report.Recipients) == 0 {
+			firstResp, firstErr = resp, err
+		}
+		for _, o := range g.entryOutcomes(outcome) {
+			if !o.Success {
+				failures = append(failures, fmt.Sprintf("%s: %s", o.RecipientEmail, o.Message))
+			}
+			report.Recipients = append(report.Recipients, o)
+		}
+	}
+	if err := s.reports.record(report); err != nil {
+		logger().Error("Failed to record delivery report", "message_id", msg.MessageId, "error", err)
 	}
 
-	if !lookupResp.GetFound() {
-		log.Printf("TransferServer: Recipient '%s' not found by Nameserver.", msg.RecipientEmail)
-		return &proto.SendMailResponse{Success: false, Message: fmt.Sprintf("Recipient '%s' not found", msg.RecipientEmail)}, nil
+	results := make([]*proto.RecipientResult, 0, len(report.Recipients))
+	for _, o := range report.Recipients {
+		results = append(results, o.toProto())
 	}
 
-	recipientMailboxAddr := lookupResp.GetMailboxAddress()
-	log.Printf("TransferServer: Found recipient '%s' at mailbox address '%s'", msg.RecipientEmail, recipientMailboxAddr)
+	// A single recipient keeps the plain per-delivery response (including gRPC errors)
+	if entries == 1 {
+		if firstResp != nil {
+			firstResp.MessageId = msg.MessageId
+			firstResp.Results = results
+		}
+		return firstResp, firstErr
+	}
+	if len(failures) > 0 {
+		return &proto.SendMailResponse{Success: false, MessageId: msg.MessageId, Results: results, Message: fmt.Sprintf("Mail delivered to %d of %d recipients; failed: %s",
+			entries-len(failures), entries, strings.Join(failures, "; "))}, nil
+	}
+	return &proto.SendMailResponse{Success: true, MessageId: msg.MessageId, Results: results, Message: fmt.Sprintf("Mail sent successfully to %d recipients", entries)}, nil
+}
 
-	// 2. Establish connection to recipient's Mailbox once for all retry attempts
-	recipientDialCtx, recipientDialCancel := context.WithTimeout(context.Background(), time.Second*5)
-	conn, err := grpc.DialContext(recipientDialCtx, recipientMailboxAddr, grpc.WithInsecure()) // Insecure for practice, use TLS in production
-	recipientDialCancel()                                                                      // Ensure context is cancelled after DialContext returns
+// deliverGroup delivers a copy of msg to g, unless the group's entries could not be parsed, and accounts for
+// the outcome. A copy whose retries were exhausted is dead-lettered; permanent failures are only reported.
+func (s *server) deliverGroup(ctx context.Context, msg *proto.MailMessage, g recipientGroup) (*proto.SendMailResponse, recipientOutcome, error) {
+	if g.err != nil {
+		err := g.groupError()
+		outcome := newRecipientOutcome(g.address, nil, err)
+		s.finishOutcome(ctx, msg, outcome)
+		return nil, outcome, err
+	}
+	resp, exhausted, err := s.deliverWithRetries(ctx, copyForRecipient(msg, g.address))
+	outcome := newRecipientOutcome(g.address, resp, err)
+	s.finishOutcome(ctx, msg, outcome)
+	if exhausted {
+		s.deadLetter(copyForRecipient(msg, g.address), outcome.Message)
+	}
+	return resp, outcome, err
+}
 
+// finishQueued records the final outcome of a queued delivery, made within the trace in ctx. permanent reports
+// a failure that retrying cannot fix; other failures exhausted their retries and are dead-lettered.
+func (s *server) finishQueued(ctx context.Context, msg *proto.MailMessage, err error, permanent bool) {
+	resp := &proto.SendMailResponse{Success: true, Message: "Mail sent successfully"}
 	if err != nil {
-		log.Printf("TransferServer: Initial connection to recipient mailbox at %s failed: %v", recipientMailboxAddr, err)
-		return nil, status.Errorf(codes.Unavailable, "failed to connect to recipient mailbox: %v", err)
+		resp = nil
+		if permanent {
+			logger().Warn("Queued delivery rejected permanently", "message_id", msg.MessageId, "recipient", msg.RecipientEmail, "error", err)
+		} else {
+			logger().Warn("Queued delivery dead-lettered after retries", "message_id", msg.MessageId, "recipient", msg.RecipientEmail, "error", err)
+			s.deadLetter(msg, err.Error())
+		}
 	}
-	defer conn.Close() // Close connection when SendMail function exits
+	group := recipientGroup{address: msg.RecipientEmail, entries: s.queuedEntries.take(msg.MessageId, msg.RecipientEmail)}
+	s.finishEntries(ctx, msg, group, newRecipientOutcome(msg.RecipientEmail, resp, err))
+}
 
-	mailboxClient := proto.NewMailboxClient(conn)
+// finishEntries accounts for the final outcome of a delivery to group and records it for each of its entries.
+func (s *server) finishEntries(ctx context.Context, msg *proto.MailMessage, group recipientGroup, outcome recipientOutcome) {
+	s.finishOutcome(ctx, msg, outcome)
+	for _, o := range group.entryOutcomes(outcome) {
+		if err := s.reports.recordOutcome(msg.MessageId, msg.SenderEmail, o); err != nil {
+			logger().Error("Failed to record delivery report", "message_id", msg.MessageId, "error", err)
+		}
+	}
+}
 
-	// Loop for initial attempt + maxRetries retries
-	var lastErr error
-	backoff := initialBackoff
-	for i := 0; i <= maxRetries; i++ { // Loop for initial attempt (i=0) + maxRetries additional retries
-		log.Printf("TransferServer: Attempt %d/%d to deliver mail to '%s' at '%s'", i+1, maxRetries+1, msg.RecipientEmail, recipientMailboxAddr)
+// PauseDelivery implements proto.TransferServerServer.
+// It halts delivery attempts from the outbound queue without dropping queued mail.
+func (s *server) PauseDelivery(ctx context.Context, req *proto.PauseDeliveryRequest) (*proto.QueueStatusResponse, error) {
+	if s.queue == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "asynchronous delivery is not enabled")
+	}
+	s.queue.pause()
+	return s.queue.status(), nil
+}
+
+// ResumeDelivery implements proto.TransferServerServer.
+// It restarts delivery attempts from the outbound queue.
+func (s *server) ResumeDelivery(ctx context.Context, req *proto.ResumeDeliveryRequest) (*proto.QueueStatusResponse, error) {
+	if s.queue == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "asynchronous delivery is not enabled")
+	}
+	s.queue.resume()
+	return s.queue.status(), nil
+}
 
-		sendToMailboxCtx, sendToMailboxCancel := context.WithTimeout(context.Background(), time.Second*5)
-		receiveMailReq := &proto.ReceiveMailRequest{Message: msg}
-		receiveMailResp, err := mailboxClient.ReceiveMail(sendToMailboxCtx, receiveMailReq)
-		sendToMailboxCancel() // Ensure context is cancelled after RPC returns
+// FlushQueue implements proto.TransferServerServer.
+// It attempts delivery of all queued mail immediately instead of waiting for the retry backoff.
+func (s *server) FlushQueue(ctx context.Context, req *proto.FlushQueueRequest) (*proto.QueueStatusResponse, error) {
+	if s.queue == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "asynchronous delivery is not enabled")
+	}
+	s.queue.flush()
+	return s.queue.status(), nil
+}
 
-		if err != nil {
-			lastErr = fmt.Errorf("error sending mail to mailbox '%s': %v", recipientMailboxAddr, err)
-			log.Printf("TransferServer: Mail delivery RPC failed: %v", lastErr)
-			if i < maxRetries { // Only sleep if more retries are available
-				time.Sleep(backoff)
-				backoff *= 2 // Exponential backoff
-				if backoff > maxBackoff {
-					backoff = maxBackoff
+// QueueStatus implements proto.TransferServerServer.
+// It reports whether the outbound queue is paused and how much mail it holds.
+func (s *server) QueueStatus(ctx context.Context, req *proto.QueueStatusRequest) (*proto.QueueStatusResponse, error) {
+	if s.queue == nil {
+		return &proto.QueueStatusResponse{}, nil // Synchronous delivery never queues mail
+	}
+	return s.queue.status(), nil
+}
+
+// SendMailBulk implements proto.TransferServerServer.
+// The first request on the stream carries the message; every following request names one recipient.
+// Recipients are delivered concurrently and each outcome is streamed back as soon as it is known.
+// Distribution lists are expanded as in SendMail, for up to bulkListBatch recipients at a time, so
+// delivery starts once a batch is complete or the stream ends.
+// The complete outcome is recorded and can be queried with DeliveryReport.
+func (s *server) SendMailBulk(stream proto.TransferServer_SendMailBulkServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "mail message cannot be empty")
+	}
+	if err != nil {
+		return err
+	}
+	msg := first.GetMessage()
+	if msg == nil {
+		return status.Errorf(codes.InvalidArgument, "the first request must carry the mail message")
+	}
+	if err := s.limits.check(msg); err != nil {
+		return err
+	}
+	if err := s.checkDiskSpace(); err != nil {
+		return err
+	}
+	s.rewriter.rewriteMessage(msg)
+	if err := s.verifySender(stream.Context(), msg.SenderEmail); err != nil {
+		return err
+	}
+	// The sender is only charged once a recipient has arrived, so an empty stream costs nothing, as in SendMail
+	firstRecipient, err := s.nextBulkRecipient(stream)
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "recipient email cannot be empty")
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkSenderRate(msg.SenderEmail); err != nil {
+		return err
+	}
+	if err := s.takeSenderQuota(msg.SenderEmail); err != nil {
+		return err
+	}
+	if msg.MessageId == "" {
+		msg.MessageId = common.NewMessageID()
+	}
+	msg.SentAt = common.FormatSentAt(s.now())
+	logger().Info("Receiving bulk mail", "message_id", msg.MessageId, "sender", msg.SenderEmail, "subject", msg.Subject)
+	s.stats.accepted.Add(1)
+
+	recipients := make(chan recipientGroup)
+	outcomes := make(chan recipientOutcome)
+	var workers sync.WaitGroup
+	for i := 0; i < bulkDeliveryWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for g := range recipients {
+				_, outcome, _ := s.deliverGroup(stream.Context(), msg, g)
+				outcomes <- outcome
+			}
+		}()
+	}
+
+	// Read recipients until the client closes its side of the stream
+	var recvErr error
+	go func() {
+		defer close(recipients)
+		seen := make(map[string]bool)
+		var batch []recipientGroup
+		flush := func() {
+			for _, g := range s.expandLists(stream.Context(), batch) {
+				if seen[g.address] {
+					continue
 				}
+				seen[g.address] = true
+				recipients <- g
 			}
-			continue
+			batch = batch[:0]
 		}
+		defer flush()
+		batch = append(batch, recipientGroup{address: firstRecipient, entries: []string{firstRecipient}})
+		for {
+			recipient, err := s.nextBulkRecipient(stream)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				recvErr = err
+				return
+			}
+			if seen[recipient] {
+				continue
+			}
+			batch = append(batch, recipientGroup{address: recipient, entries: []string{recipient}})
+			if len(batch) == bulkListBatch {
+				flush()
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(outcomes)
+	}()
 
-		if receiveMailResp.GetSuccess() {
-			log.Printf("TransferServer: Mail successfully delivered to '%s' (Mailbox: %s)", msg.RecipientEmail, recipientMailboxAddr)
-			return &proto.SendMailResponse{Success: true, Message: "Mail sent successfully"}, nil
+	report := &deliveryReport{
+		MessageID:   msg.MessageId,
+		SenderEmail: msg.SenderEmail,
+		CreatedAt:   s.now().Unix(),
+	}
+	var sendErr error
+	delivered := 0
+	for outcome := range outcomes {
+		report.Recipients = append(report.Recipients, outcome)
+		if outcome.Success {
+			delivered++
+		}
+		if sendErr == nil {
+			sendErr = stream.Send(outcome.toProto()) // Keep draining outcomes even if the client went away
+		}
+	}
+	if err := s.reports.record(report); err != nil {
+		logger().Error("Failed to record delivery report", "message_id", msg.MessageId, "error", err)
+	}
+	logger().Info("Bulk mail delivered", "message_id", msg.MessageId, "delivered", delivered, "recipients", len(report.Recipients))
+
+	if recvErr != nil {
+		return recvErr
+	}
+	return sendErr
+}
+
+// nextBulkRecipient reads the next recipient from a SendMailBulk stream, skipping requests whose recipient is
+// empty after rewriting. It returns io.EOF once the client has closed its side of the stream.
+func (s *server) nextBulkRecipient(stream proto.TransferServer_SendMailBulkServer) (string, error) {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		if req.GetMessage() != nil {
+			return "", status.Errorf(codes.InvalidArgument, "only the first request may carry the mail message")
+		}
+		if recipient := s.rewriter.rewrite(req.GetRecipientEmail(), common.RewriteRecipient); recipient != "" {
+			return recipient, nil
+		}
+	}
+}
+
+// DeliveryReport implements proto.TransferServerServer.
+// It returns the recorded per-recipient delivery outcome of a previously sent message.
+func (s *server) DeliveryReport(ctx context.Context, req *proto.DeliveryReportRequest) (*proto.DeliveryReportResponse, error) {
+	if req.GetMessageId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "message id cannot be empty")
+	}
+	if resp := s.reports.get(req.GetMessageId()); resp != nil {
+		return resp, nil
+	}
+	return &proto.DeliveryReportResponse{Found: false, MessageId: req.GetMessageId()}, nil
+}
+
+// GetDeliveryStatus implements proto.TransferServerServer.
+// It reports whether the send with the given tracking (message) ID is pending, delivered or failed,
+// based on the copies still queued and the latest recorded outcome for each recipient.
+func (s *server) GetDeliveryStatus(ctx context.Context, req *proto.GetDeliveryStatusRequest) (*proto.GetDeliveryStatusResponse, error) {
+	if req.GetTrackingId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "tracking id cannot be empty")
+	}
+	resp := &proto.GetDeliveryStatusResponse{}
+	if s.queue != nil {
+		resp.Pending = int32(s.queue.countMessage(req.GetTrackingId()))
+	}
+	for _, o := range s.reports.latestOutcomes(req.GetTrackingId()) {
+		if o.Success {
+			resp.Delivered++
 		} else {
-			lastErr = fmt.Errorf("mail delivery to '%s' failed: %s", msg.RecipientEmail, receiveMailResp.GetMessage())
-			log.Printf("TransferServer: Mail delivery response indicated failure: %v", lastErr)
-			if i < maxRetries { // Only sleep if more retries are available
-				time.Sleep(backoff)
-				backoff *= 2
-				if backoff > maxBackoff {
-					backoff = maxBackoff
-				}
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, o.toProto())
+	}
+	switch {
+	case resp.Pending > 0:
+		resp.Status = proto.DeliveryStatus_DELIVERY_STATUS_PENDING
+	case resp.Failed > 0:
+		resp.Status = proto.DeliveryStatus_DELIVERY_STATUS_FAILED
+	case resp.Delivered > 0:
+		resp.Status = proto.DeliveryStatus_DELIVERY_STATUS_DELIVERED
+	}
+	return resp, nil
+}
+
+// checkDiskSpace returns a ResourceExhausted error if the state directory is low on disk space.
+// Read-only RPCs such as DeliveryReport are served regardless.
+func (s *server) checkDiskSpace() error {
+	if low, free := common.LowDiskSpace(s.freeDiskSpace, s.stateDir, s.minFreeDiskBytes); low {
+		logger().Warn("Rejecting mail, low on disk space", "free_bytes", free, "min_free_bytes", s.minFreeDiskBytes)
+		return status.Errorf(codes.ResourceExhausted, "transfer server is low on disk space, not accepting new mail")
+	}
+	return nil
+}
+
+// verifySender checks that sender is registered with the Nameserver, if sender verification is enabled.
+// When the Nameserver cannot be reached the configured policy decides: fail-open accepts the mail,
+// fail-closed rejects it with Unavailable. A sender of an unrouted domain is rejected under either policy,
+// since the Nameserver did answer.
+func (s *server) verifySender(ctx context.Context, sender string) error {
+	if !s.verifySenders {
+		return nil
+	}
+	if sender == "" {
+		return status.Errorf(codes.PermissionDenied, "sender email is required when sender verification is enabled")
+	}
+	_, found, err := s.lookupMailbox(ctx, sender)
+	if isUnroutedDomain(err) {
+		logger().Warn("Rejecting mail from a sender of an unrouted domain", "sender", sender)
+		return status.Errorf(codes.PermissionDenied, "sender '%s' is not registered: its domain is not routed", sender)
+	}
+	if err != nil {
+		if s.senderFailOpen {
+			logger().Warn("Sender verification failed, accepting mail (fail-open)", "sender", sender, "error", err)
+			return nil
+		}
+		logger().Warn("Sender verification failed, rejecting mail (fail-closed)", "sender", sender, "error", err)
+		return status.Errorf(codes.Unavailable, "could not verify sender '%s': nameserver unavailable", sender)
+	}
+	if !found {
+		logger().Warn("Rejecting mail from an unregistered sender", "sender", sender)
+		return status.Errorf(codes.PermissionDenied, "sender '%s' is not registered", sender)
+	}
+	return nil
+}
+
+// checkSenderRate takes one message from the rate limit of sender, returning a ResourceExhausted error if the
+// sender is sending too fast.
+func (s *server) checkSenderRate(sender string) error {
+	if !s.rateLimiter.allow(sender, s.now()) {
+		logger().Warn("Throttling sender", "sender", sender, "rate", s.rateLimiter.rate)
+		return status.Errorf(codes.ResourceExhausted, "sender '%s' exceeded the rate limit, try again later", sender)
+	}
+	return nil
+}
+
+// takeSenderQuota counts one message against the daily quota of sender, returning a ResourceExhausted
+// error if the sender has already reached it.
+func (s *server) takeSenderQuota(sender string) error {
+	ok, err := s.quotas.take(sender, s.now())
+	if err != nil {
+		logger().Error("Failed to persist sender quota", "sender", sender, "error", err)
+	}
+	if !ok {
+		logger().Warn("Sender reached the daily quota", "sender", sender, "quota", s.quotas.dailyCap)
+		return status.Errorf(codes.ResourceExhausted, "sender '%s' exceeded the daily quota of %d messages", sender, s.quotas.dailyCap)
+	}
+	return nil
+}
+
+// messageRecipients returns every distinct, non-empty recipient of msg (recipient, To, Cc and Bcc) in order.
+func messageRecipients(msg *proto.MailMessage) []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{{msg.RecipientEmail}, msg.To, msg.Cc, msg.Bcc} {
+		for _, r := range list {
+			if r == "" || seen[r] {
+				continue
 			}
-			continue
+			seen[r] = true
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients
+}
+
+// copyForRecipient returns the copy of msg delivered to recipient. Bcc recipients are never disclosed.
+func copyForRecipient(msg *proto.MailMessage, recipient string) *proto.MailMessage {
+	c := protobuf.Clone(msg).(*proto.MailMessage)
+	c.RecipientEmail = recipient
+	c.Bcc = nil
+	return c
+}
+
+// deliver looks up the mailboxes of msg.RecipientEmail and forwards msg with retry logic. Each attempt tries
+// the recipient's mailbox addresses in failover order until one of them takes the message.
+// The delivery runs to completion even if the caller goes away; ctx only carries its trace, which the lookup
+// and ReceiveMail calls continue.
+func (s *server) deliver(ctx context.Context, msg *proto.MailMessage) (*proto.SendMailResponse, error) {
+	resp, _, err := s.deliverWithRetries(ctx, msg)
+	return resp, err
+}
+
+// deliverWithRetries implements deliver. exhausted reports a failure that was transient but outlasted every
+// retry (or every mailbox's circuit breaker), as opposed to one that retrying cannot fix.
+func (s *server) deliverWithRetries(ctx context.Context, msg *proto.MailMessage) (resp *proto.SendMailResponse, exhausted bool, err error) {
+	retries := 0
+	ctx, span := common.StartSpan(context.WithoutCancel(ctx), "TransferServer.deliver", common.AttrRecipient.String(msg.RecipientEmail))
+	defer func() {
+		span.SetAttributes(common.AttrRetries.Int(retries))
+		span.End()
+	}()
+
+	// 1. Lookup recipient's mailbox addresses from Nameserver using the full email address
+	resolved, found, err := s.resolveMailbox(ctx, msg.RecipientEmail)
+	if isUnroutedDomain(err) {
+		return &proto.SendMailResponse{Success: false, Message: err.Error()}, false, nil
+	}
+	if err != nil {
+		return nil, false, status.Errorf(codes.Internal, "failed to lookup recipient mailbox: %v", err)
+	}
+	if !found {
+		return &proto.SendMailResponse{Success: false, Message: fmt.Sprintf("Recipient '%s' not found", msg.RecipientEmail)}, false, nil
+	}
+	addrs := s.withoutSelfAddrs(msg.RecipientEmail, resolved)
+	if len(addrs) == 0 {
+		return nil, false, status.Errorf(codes.FailedPrecondition, "mailbox address '%s' of '%s' points to the TransferServer itself", resolved[0], msg.RecipientEmail)
+	}
+
+	// 2. Deliver with retries; connections to the recipient's Mailboxes come from the pool shared by all deliveries
+	maxRetries := s.retry.maxRetries
+	var lastErr error
+	for i := 0; i <= maxRetries; i++ { // Loop for initial attempt (i=0) + maxRetries additional retries
+		if i > 0 {
+			retries = i
+			deliveryRetries.Inc()
+		}
+		unreachable, tripped := true, 0
+		for _, addr := range addrs {
+			if err := s.breakers.allow(addr); err != nil {
+				lastErr = err
+				tripped++
+				continue
+			}
+			logger().Info("Delivering mail", "recipient", msg.RecipientEmail, "mailbox_addr", addr, "attempt", i+1, "attempts", maxRetries+1)
+			conn, err := s.conns.get(addr)
+			var permanent bool
+			if err == nil {
+				permanent, err = s.sendToMailbox(ctx, proto.NewMailboxClient(conn), addr, msg)
+			}
+			s.breakers.record(addr, err)
+			if err == nil {
+				logger().Info("Mail delivered", "recipient", msg.RecipientEmail, "mailbox_addr", addr)
+				return &proto.SendMailResponse{Success: true, Message: "Mail sent successfully"}, false, nil
+			}
+			if permanent {
+				logger().Warn("Mailbox refused mail", "recipient", msg.RecipientEmail, "mailbox_addr", addr, "error", err)
+				return &proto.SendMailResponse{Success: false, Message: err.Error()}, false, nil
+			}
+			lastErr = err
+			unreachable = unreachable && mailboxUnreachable(err)
+			logger().Warn("Mail delivery failed", "recipient", msg.RecipientEmail, "mailbox_addr", addr, "error", lastErr)
+		}
+		if tripped == len(addrs) {
+			// Every Mailbox of the recipient is tripped, waiting out the backoff here cannot help
+			logger().Warn("Not delivering mail", "recipient", msg.RecipientEmail, "error", lastErr)
+			return &proto.SendMailResponse{Success: false, Message: fmt.Sprintf("Mail delivery failed: %v", lastErr)}, true, nil
+		}
+		if unreachable {
+			if fresh, changed := s.relookupStale(ctx, msg.RecipientEmail, resolved); changed {
+				resolved, addrs = fresh, s.withoutSelfAddrs(msg.RecipientEmail, fresh)
+			}
+		}
+		if i < maxRetries { // Only sleep if more retries are available
+			time.Sleep(s.retry.backoff(i + 1)) // Exponential backoff
 		}
 	}
 
 	// If we reach here, all retries failed
-	log.Printf("TransferServer: All %d attempts to deliver mail to '%s' failed. Last error: %v", maxRetries+1, msg.RecipientEmail, lastErr)
-	return &proto.SendMailResponse{Success: false, Message: fmt.Sprintf("Mail delivery failed after %d retries: %v", maxRetries, lastErr)}, nil
+	logger().Warn("All delivery attempts failed", "recipient", msg.RecipientEmail, "attempts", maxRetries+1, "error", lastErr)
+	return &proto.SendMailResponse{Success: false, Message: fmt.Sprintf("Mail delivery failed after %d retries: %v", maxRetries, lastErr)}, true, nil
+}
+
+// dialMailbox connects to the Mailbox at addr, with the transport credentials in dialOpt.
+func dialMailbox(addr string, dialOpt grpc.DialOption) (*grpc.ClientConn, error) {
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, addr, dialOpt, common.TracingDialOption())
+	if err != nil {
+		logger().Warn("Connection to recipient mailbox failed", "mailbox_addr", addr, "error", err)
+		return nil, status.Errorf(codes.Unavailable, "failed to connect to recipient mailbox: %v", err)
+	}
+	return conn, nil
+}
+
+// sendToMailbox hands msg to the Mailbox at addr, asking it with CanAccept first if configured.
+// permanent reports whether a failure is final, so neither retrying nor another mailbox can help.
+func (s *server) sendToMailbox(ctx context.Context, mailboxClient proto.MailboxClient, addr string, msg *proto.MailMessage) (permanent bool, err error) {
+	if permanent, err := s.checkRecipientAccepts(ctx, mailboxClient, msg); err != nil {
+		return permanent, err // Refused (or deferred) without sending the payload
+	}
+
+	sendToMailboxCtx, sendToMailboxCancel := context.WithTimeout(ctx, time.Second*5)
+	defer sendToMailboxCancel()
+	receiveMailReq := &proto.ReceiveMailRequest{Message: msg}
+	receiveMailResp, err := mailboxClient.ReceiveMail(sendToMailboxCtx, receiveMailReq, s.compression.CallOptions(receiveMailReq)...)
+	if status.Code(err) == codes.InvalidArgument {
+		// The mailbox rejected the message itself (e.g. its body size limit), resending cannot help
+		return true, fmt.Errorf("mailbox rejected the message: %v", status.Convert(err).Message())
+	}
+	if err != nil {
+		return !retryable(err), fmt.Errorf("error sending mail to mailbox '%s': %w", addr, err)
+	}
+	if !receiveMailResp.GetSuccess() {
+		if receiveMailResp.GetMailboxFull() {
+			return true, fmt.Errorf("mailbox of '%s' is full: %s", msg.RecipientEmail, receiveMailResp.GetMessage())
+		}
+		return false, fmt.Errorf("mail delivery to '%s' failed, try again: %s", msg.RecipientEmail, receiveMailResp.GetMessage())
+	}
+	return false, nil
+}
+
+// withoutSelfAddrs returns addrs without the addresses referring to this TransferServer.
+func (s *server) withoutSelfAddrs(recipient string, addrs []string) []string {
+	var deliverable []string
+	for _, addr := range addrs {
+		if s.isSelfAddr(addr) {
+			logger().Warn("Skipping mailbox address pointing to this TransferServer", "recipient", recipient, "mailbox_addr", addr)
+			continue
+		}
+		deliverable = append(deliverable, addr)
+	}
+	return deliverable
+}
+
+// checkNameserverHealth asks the Nameserver once whether it is serving and logs a warning if it is not, or
+// cannot be reached. It reports whether the Nameserver is serving; one without Health counts as serving.
+func checkNameserverHealth(nameserverClient proto.NameserverClient) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	resp, err := nameserverClient.Health(ctx, &proto.NameserverHealthRequest{})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		logger().Info("Nameserver does not support health checks, skipping")
+		return true
+	case err != nil:
+		logger().Warn("Nameserver health check failed", "error", err)
+		return false
+	case !resp.GetServing():
+		logger().Warn("Nameserver is not serving", "detail", resp.GetDetail())
+		return false
+	}
+	logger().Info("Nameserver is serving", "domains", resp.GetManagedDomains())
+	return true
+}
+
+// unroutedDomainError reports that a recipient is unknown because no Nameserver manages its domain, rather
+// than because the domain has no such user. Like a recipient that is not found, it is permanent.
+type unroutedDomainError struct {
+	recipient string
+}
+
+func (e *unroutedDomainError) Error() string {
+	return fmt.Sprintf("Recipient domain '%s' of '%s' is not routed: no Nameserver manages it", domainOf(e.recipient), e.recipient)
+}
+
+// isUnroutedDomain reports whether err, possibly wrapped, is an *unroutedDomainError.
+func isUnroutedDomain(err error) bool {
+	var unrouted *unroutedDomainError
+	return errors.As(err, &unrouted)
+}
+
+// lookupMailbox asks the Nameserver for the mailbox addresses of recipient, the primary address first,
+// followed by any replicas. found is false if the recipient is not registered; if that is because no
+// Nameserver manages its domain, err is an *unroutedDomainError instead. If the Nameserver refers the lookup
+// to another Nameserver, up to maxReferralHops referrals are followed.
+func (s *server) lookupMailbox(ctx context.Context, recipient string) (addrs []string, found bool, err error) {
+	lookupResp, err := lookupWith(ctx, s.nameserverClient, recipient)
+	for hops := 0; err == nil && !lookupResp.GetFound() && lookupResp.GetReferralAddress() != ""; hops++ {
+		referral := lookupResp.GetReferralAddress()
+		if hops == maxReferralHops {
+			logger().Warn("Not following referral, hop limit reached", "recipient", recipient, "referral", referral, "max_hops", maxReferralHops)
+			break
+		}
+		logger().Info("Following referral", "recipient", recipient, "referral", referral)
+		lookupResp, err = s.lookupReferral(ctx, referral, recipient)
+	}
+	if err != nil {
+		logger().Warn("Error looking up mailbox", "recipient", recipient, "error", err)
+		return nil, false, err
+	}
+	if !lookupResp.GetFound() && lookupResp.GetDomainNotManaged() {
+		logger().Info("Recipient not found, its domain is not managed by the Nameserver", "recipient", recipient)
+		return nil, false, &unroutedDomainError{recipient: recipient}
+	}
+	if !lookupResp.GetFound() {
+		logger().Info("Recipient not found by the Nameserver", "recipient", recipient)
+		return nil, false, nil
+	}
+	logger().Info("Found recipient", "recipient", recipient, "mailbox_addr", lookupResp.GetMailboxAddress())
+	return append([]string{lookupResp.GetMailboxAddress()}, lookupResp.GetReplicaAddresses()...), true, nil
+}
+
+// lookupWith looks up recipient with nameserverClient. A malformed address can never be registered, so the
+// Nameserver rejecting it is reported as an empty, not-found response rather than an error worth retrying.
+func lookupWith(ctx context.Context, nameserverClient proto.NameserverClient, recipient string) (*proto.LookupMailboxResponse, error) {
+	lookupCtx, lookupCancel := context.WithTimeout(ctx, time.Second*5)
+	defer lookupCancel()
+
+	lookupResp, err := nameserverClient.LookupMailbox(lookupCtx, &proto.LookupMailboxRequest{EmailAddress: recipient})
+	if status.Code(err) == codes.InvalidArgument {
+		logger().Warn("Nameserver rejected recipient", "recipient", recipient, "error", err)
+		return &proto.LookupMailboxResponse{}, nil
+	}
+	return lookupResp, err
+}
+
+// lookupReferral looks up recipient with the Nameserver at addr, to which another Nameserver referred it.
+func (s *server) lookupReferral(ctx context.Context, addr, recipient string) (*proto.LookupMailboxResponse, error) {
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, addr, s.dialOpt, common.TracingDialOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to referred Nameserver '%s': %w", addr, err)
+	}
+	defer conn.Close()
+	return lookupWith(ctx, proto.NewNameserverClient(conn), recipient)
+}
+
+// attemptDelivery makes a single lookup and delivery attempt for msg, as used by the delivery queue, after
+// retries earlier attempts. The recipient's mailbox addresses are tried in failover order. permanent reports
+// whether a failure is final and must not be retried.
+func (s *server) attemptDelivery(ctx context.Context, msg *proto.MailMessage, retries int) (permanent bool, err error) {
+	ctx, span := common.StartSpan(ctx, "TransferServer.attemptDelivery",
+		common.AttrRecipient.String(msg.RecipientEmail), common.AttrRetries.Int(retries))
+	defer span.End()
+	resolved, found, err := s.resolveMailbox(ctx, msg.RecipientEmail)
+	if isUnroutedDomain(err) {
+		return true, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lookup recipient mailbox: %v", err)
+	}
+	if !found {
+		err := fmt.Errorf("Recipient '%s' not found", msg.RecipientEmail)
+		if s.notFoundTTL > 0 {
+			return false, &deferredError{err: err, retryAfter: s.notFoundRetryInterval, ttl: s.notFoundTTL} // May register shortly
+		}
+		return true, err
+	}
+	addrs := s.withoutSelfAddrs(msg.RecipientEmail, resolved)
+	if len(addrs) == 0 {
+		return true, fmt.Errorf("mailbox address '%s' of '%s' points to the TransferServer itself", resolved[0], msg.RecipientEmail)
+	}
+
+	unreachable := true
+	for _, addr := range addrs {
+		if err = s.breakers.allow(addr); err != nil {
+			continue
+		}
+		var conn *grpc.ClientConn
+		if conn, err = s.conns.get(addr); err == nil {
+			permanent, err = s.sendToMailbox(ctx, proto.NewMailboxClient(conn), addr, msg)
+		}
+		s.breakers.record(addr, err)
+		if err == nil {
+			logger().Info("Mail delivered", "recipient", msg.RecipientEmail, "mailbox_addr", addr)
+			return false, nil
+		}
+		if permanent {
+			return true, err
+		}
+		unreachable = unreachable && mailboxUnreachable(err)
+	}
+	if unreachable {
+		s.lookupCache.invalidate(msg.RecipientEmail, resolved) // The next attempt looks the recipient up afresh
+	}
+	return false, err
+}
+
+// isSelfAddr reports whether addr refers to this TransferServer.
+func (s *server) isSelfAddr(addr string) bool {
+	for _, self := range s.selfAddrs {
+		if sameAddr(addr, self) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameAddr reports whether two host:port addresses are equal, treating all loopback
+// and unspecified hosts (localhost, 127.0.0.1, ::1, 0.0.0.0, empty) as the same host.
+func sameAddr(a, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return portA == portB && (strings.EqualFold(hostA, hostB) || (isLocalHost(hostA) && isLocalHost(hostB)))
+}
+
+// isLocalHost reports whether host is a loopback or unspecified host.
+func isLocalHost(host string) bool {
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
 }
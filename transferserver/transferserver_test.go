@@ -1,37 +1,65 @@
 package transferserver
 
 import (
+	"GoDissys/common"
+	"GoDissys/mailbox"
 	"GoDissys/proto/proto"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings" // Import for strings.Contains
 	"sync"
 	"sync/atomic" // For atomic counter in mock
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 // MockNameserverClient is a mock implementation of proto.NameserverClient for testing.
 type MockNameserverClient struct {
 	mu        sync.RWMutex
-	mailboxes map[string]string // email_address -> mailbox address
+	mailboxes map[string]string   // email_address -> mailbox address
+	replicas  map[string][]string // email_address -> further mailbox addresses
+	lists     map[string][]string // list address -> already expanded members
+	managed   map[string]bool     // Managed domains; if set, unknown addresses of other domains are reported as such
+
+	expandCalls int // ExpandLists calls
 }
 
 func NewMockNameserverClient() *MockNameserverClient {
 	return &MockNameserverClient{
 		mailboxes: make(map[string]string),
+		replicas:  make(map[string][]string),
+		lists:     make(map[string][]string),
 	}
 }
 
 func (m *MockNameserverClient) RegisterMailbox(ctx context.Context, in *proto.RegisterMailboxRequest, opts ...grpc.CallOption) (*proto.RegisterMailboxResponse, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.mailboxes[in.GetEmailAddress()] = in.GetMailboxAddress()
+	if _, exists := m.mailboxes[in.GetEmailAddress()]; exists && in.GetReplica() {
+		m.replicas[in.GetEmailAddress()] = append(m.replicas[in.GetEmailAddress()], in.GetMailboxAddress())
+	} else {
+		m.mailboxes[in.GetEmailAddress()] = in.GetMailboxAddress()
+	}
 	return &proto.RegisterMailboxResponse{Success: true, Message: "Mock registered"}, nil
 }
 
@@ -39,7 +67,82 @@ func (m *MockNameserverClient) LookupMailbox(ctx context.Context, in *proto.Look
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	addr, found := m.mailboxes[in.GetEmailAddress()]
-	return &proto.LookupMailboxResponse{Found: found, MailboxAddress: addr}, nil
+	notManaged := !found && m.managed != nil && !m.managed[domainOf(in.GetEmailAddress())]
+	return &proto.LookupMailboxResponse{Found: found, MailboxAddress: addr, ReplicaAddresses: m.replicas[in.GetEmailAddress()],
+		DomainNotManaged: notManaged}, nil
+}
+
+func (m *MockNameserverClient) DeregisterMailbox(ctx context.Context, in *proto.DeregisterMailboxRequest, opts ...grpc.CallOption) (*proto.DeregisterMailboxResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, found := m.mailboxes[in.GetEmailAddress()]
+	delete(m.mailboxes, in.GetEmailAddress())
+	delete(m.replicas, in.GetEmailAddress())
+	return &proto.DeregisterMailboxResponse{Removed: found}, nil
+}
+
+func (m *MockNameserverClient) ListMailboxes(ctx context.Context, in *proto.ListMailboxesRequest, opts ...grpc.CallOption) (*proto.ListMailboxesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "not needed by the TransferServer")
+}
+
+func (m *MockNameserverClient) AddManagedDomain(ctx context.Context, in *proto.AddManagedDomainRequest, opts ...grpc.CallOption) (*proto.ManagedDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "not needed by the TransferServer")
+}
+
+func (m *MockNameserverClient) RemoveManagedDomain(ctx context.Context, in *proto.RemoveManagedDomainRequest, opts ...grpc.CallOption) (*proto.ManagedDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "not needed by the TransferServer")
+}
+
+func (m *MockNameserverClient) CompareAndSwapMailbox(ctx context.Context, in *proto.CompareAndSwapMailboxRequest, opts ...grpc.CallOption) (*proto.CompareAndSwapMailboxResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mailboxes[in.GetEmailAddress()] != in.GetExpectedOldAddress() {
+		return nil, status.Errorf(codes.FailedPrecondition, "mock mailbox address mismatch")
+	}
+	m.mailboxes[in.GetEmailAddress()] = in.GetNewAddress()
+	return &proto.CompareAndSwapMailboxResponse{MailboxAddress: in.GetNewAddress()}, nil
+}
+
+func (m *MockNameserverClient) CheckConsistency(ctx context.Context, in *proto.CheckConsistencyRequest, opts ...grpc.CallOption) (*proto.CheckConsistencyResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &proto.CheckConsistencyResponse{Checked: int32(len(m.mailboxes))}, nil
+}
+
+func (m *MockNameserverClient) Health(ctx context.Context, in *proto.NameserverHealthRequest, opts ...grpc.CallOption) (*proto.NameserverHealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+
+func (m *MockNameserverClient) Info(ctx context.Context, in *proto.NameserverInfoRequest, opts ...grpc.CallOption) (*proto.NameserverInfoResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &proto.NameserverInfoResponse{Registrations: int32(len(m.mailboxes))}, nil
+}
+
+func (m *MockNameserverClient) RegisterList(ctx context.Context, in *proto.RegisterListRequest, opts ...grpc.CallOption) (*proto.RegisterListResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lists[in.GetListAddress()] = in.GetMemberEmails()
+	return &proto.RegisterListResponse{Success: true, Message: "Mock registered"}, nil
+}
+
+func (m *MockNameserverClient) ExpandList(ctx context.Context, in *proto.ExpandListRequest, opts ...grpc.CallOption) (*proto.ExpandListResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	members, isList := m.lists[in.GetEmailAddress()]
+	return &proto.ExpandListResponse{IsList: isList, Members: members}, nil
+}
+
+func (m *MockNameserverClient) ExpandLists(ctx context.Context, in *proto.ExpandListsRequest, opts ...grpc.CallOption) (*proto.ExpandListsResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expandCalls++
+	resp := &proto.ExpandListsResponse{}
+	for _, address := range in.GetEmailAddresses() {
+		members, isList := m.lists[address]
+		resp.Expansions = append(resp.Expansions, &proto.ExpandListResponse{IsList: isList, Members: members})
+	}
+	return resp, nil
 }
 
 // MockMailboxServer is a mock implementation of proto.MailboxServer for testing.
@@ -136,6 +239,7 @@ func TestTransferServer_SendMail(t *testing.T) {
 			Subject:        "Hello Recipient1",
 			Body:           "This is a test email.",
 			Timestamp:      time.Now().Unix(),
+			SentAt:         "2000-01-01T00:00:00Z", // Overwritten with the time of acceptance
 		}
 		req := &proto.SendMailRequest{Message: msg}
 		resp, err := client.SendMail(context.Background(), req)
@@ -155,6 +259,9 @@ func TestTransferServer_SendMail(t *testing.T) {
 		if mockMailbox.receivedMessages[0].GetSubject() != "Hello Recipient1" {
 			t.Errorf("Received message subject mismatch: got %s", mockMailbox.receivedMessages[0].GetSubject())
 		}
+		if sentAt := mockMailbox.receivedMessages[0].GetSentAt(); common.SentTime(sentAt, 0).Unix() < msg.Timestamp {
+			t.Errorf("Expected the TransferServer to overwrite sent_at with the time of acceptance, got '%s'", sentAt)
+		}
 		if mockMailbox.callCount != 1 {
 			t.Errorf("Expected 1 call to ReceiveMail, got %d", mockMailbox.callCount)
 		}
@@ -215,8 +322,8 @@ func TestTransferServer_SendMail(t *testing.T) {
 
 	// Test Case 3: Send mail fails after all retries are exhausted
 	t.Run("SendMailFailureAfterRetries", func(t *testing.T) {
-		// Start Mock Mailbox Server that always fails (more than maxRetries)
-		mockMailbox := NewMockMailboxServer(maxRetries + 1) // Fails more than maxRetries
+		// Start Mock Mailbox Server that always fails (more than defaultMaxRetries)
+		mockMailbox := NewMockMailboxServer(defaultMaxRetries + 1) // Fails more than defaultMaxRetries
 		mailboxLis, err := net.Listen("tcp", "localhost:0")
 		if err != nil {
 			t.Fatalf("Failed to listen for mock mailbox: %v", err)
@@ -251,7 +358,7 @@ func TestTransferServer_SendMail(t *testing.T) {
 			t.Errorf("SendMail expected failure, got success")
 		}
 		// Check if the message contains the expected parts
-		expectedPart1 := fmt.Sprintf("Mail delivery failed after %d retries:", maxRetries)
+		expectedPart1 := fmt.Sprintf("Mail delivery failed after %d retries:", defaultMaxRetries)
 		expectedPart2 := "mock mailbox unavailable (simulated transient error)"
 		if !strings.Contains(resp.GetMessage(), expectedPart1) || !strings.Contains(resp.GetMessage(), expectedPart2) {
 			t.Errorf("Unexpected error message.\nExpected to contain: '%s' and '%s'\nActual: '%s'",
@@ -264,9 +371,9 @@ func TestTransferServer_SendMail(t *testing.T) {
 		if len(mockMailbox.receivedMessages) != 0 {
 			t.Errorf("Expected 0 messages in mock mailbox, got %d", len(mockMailbox.receivedMessages))
 		}
-		// Expected calls: maxRetries + 1 (initial attempt + retries)
-		if mockMailbox.callCount != maxRetries+1 {
-			t.Errorf("Expected %d calls to ReceiveMail, got %d", maxRetries+1, mockMailbox.callCount)
+		// Expected calls: defaultMaxRetries + 1 (initial attempt + retries)
+		if mockMailbox.callCount != defaultMaxRetries+1 {
+			t.Errorf("Expected %d calls to ReceiveMail, got %d", defaultMaxRetries+1, mockMailbox.callCount)
 		}
 	})
 
@@ -308,3 +415,2788 @@ func TestTransferServer_SendMail(t *testing.T) {
 		}
 	})
 }
+
+// startTestTransferServer serves the given TransferServer instance on a random port and returns a connected client.
+func startTestTransferServer(t *testing.T, transferServerService *server) proto.TransferServerClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for transfer server: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterTransferServerServer(s, transferServerService)
+	go func() {
+		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			t.Errorf("TransferServer failed to serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Stop)
+
+	connCtx, connCancel := context.WithTimeout(context.Background(), time.Second)
+	defer connCancel()
+	conn, err := grpc.DialContext(connCtx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Could not connect to TransferServer: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewTransferServerClient(conn)
+}
+
+// startMockMailbox serves a MockMailboxServer failing the first failBeforeSuccess calls and returns it with its address.
+func startMockMailbox(t *testing.T, failBeforeSuccess int32) (*MockMailboxServer, string) {
+	t.Helper()
+	mockMailbox := NewMockMailboxServer(failBeforeSuccess)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mock mailbox: %v", err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterMailboxServer(s, mockMailbox)
+	go func() {
+		if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			t.Errorf("Mock Mailbox failed to serve: %v", err)
+		}
+	}()
+	t.Cleanup(s.Stop)
+	return mockMailbox, lis.Addr().String()
+}
+
+// TestTransferServer_DeliveryReport tests that per-recipient outcomes of a multi-recipient send
+// are recorded durably and can be queried by message ID.
+func TestTransferServer_DeliveryReport(t *testing.T) {
+	stateDir := t.TempDir()
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	for _, email := range []string{"alice@earth.com", "carol@earth.com", "dave@earth.com"} {
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: email, MailboxAddress: mailboxAddr})
+	}
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{StateDir: stateDir})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	msg := &proto.MailMessage{
+		MessageId:      "report-test-1",
+		SenderEmail:    "bob@saturn.com",
+		RecipientEmail: "alice@earth.com",
+		To:             []string{"ghost@earth.com"},
+		Cc:             []string{"carol@earth.com"},
+		Bcc:            []string{"dave@earth.com"},
+		Subject:        "Team update",
+		Body:           "Hello team",
+		Timestamp:      time.Now().Unix(),
+	}
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: msg})
+	if err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+	if resp.GetSuccess() {
+		t.Errorf("SendMail expected partial failure, got success")
+	}
+	if !strings.Contains(resp.GetMessage(), "3 of 4 recipients") {
+		t.Errorf("Unexpected summary message: %s", resp.GetMessage())
+	}
+
+	mockMailbox.mu.Lock()
+	for _, received := range mockMailbox.receivedMessages {
+		if len(received.GetBcc()) != 0 {
+			t.Errorf("Copy delivered to '%s' disclosed Bcc recipients %v", received.GetRecipientEmail(), received.GetBcc())
+		}
+	}
+	mockMailbox.mu.Unlock()
+
+	expected := map[string]bool{
+		"alice@earth.com": true,
+		"ghost@earth.com": false,
+		"carol@earth.com": true,
+		"dave@earth.com":  true,
+	}
+	checkReport := func(t *testing.T, report *proto.DeliveryReportResponse) {
+		if !report.GetFound() || report.GetSenderEmail() != "bob@saturn.com" {
+			t.Fatalf("Unexpected report: %v", report)
+		}
+		if len(report.GetResults()) != len(expected) {
+			t.Fatalf("Expected %d recipient results, got %d", len(expected), len(report.GetResults()))
+		}
+		for _, r := range report.GetResults() {
+			if want, ok := expected[r.GetRecipientEmail()]; !ok || r.GetSuccess() != want {
+				t.Errorf("Recipient '%s': expected success=%v, got %v (%s)", r.GetRecipientEmail(), want, r.GetSuccess(), r.GetMessage())
+			}
+		}
+	}
+
+	t.Run("ResponseResults", func(t *testing.T) {
+		checkReport(t, &proto.DeliveryReportResponse{Found: true, SenderEmail: "bob@saturn.com", Results: resp.GetResults()})
+	})
+
+	t.Run("QueryReport", func(t *testing.T) {
+		report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: "report-test-1"})
+		if err != nil {
+			t.Fatalf("DeliveryReport failed: %v", err)
+		}
+		checkReport(t, report)
+	})
+
+	t.Run("ReportSurvivesRestart", func(t *testing.T) {
+		restarted, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{StateDir: stateDir})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		report, err := restarted.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: "report-test-1"})
+		if err != nil {
+			t.Fatalf("DeliveryReport failed: %v", err)
+		}
+		checkReport(t, report)
+	})
+
+	t.Run("UnknownMessageID", func(t *testing.T) {
+		report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: "does-not-exist"})
+		if err != nil {
+			t.Fatalf("DeliveryReport failed: %v", err)
+		}
+		if report.GetFound() {
+			t.Errorf("Expected no report for unknown message id")
+		}
+	})
+}
+
+// TestDeliveryReportStore_Journal tests that every report and outcome is appended as a single record instead
+// of rewriting the store, and that replaying the records restores the reports.
+func TestDeliveryReportStore_Journal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), deliveryReportsFile)
+	st, err := newDeliveryReportStore(path)
+	if err != nil {
+		t.Fatalf("newDeliveryReportStore failed: %v", err)
+	}
+	report := &deliveryReport{MessageID: "m1", SenderEmail: "bob@saturn.com", Recipients: []recipientOutcome{{RecipientEmail: "alice@earth.com", Success: true}}}
+	if err := st.record(report); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	for _, id := range []string{"m1", "m2"} {
+		if err := st.recordOutcome(id, "bob@saturn.com", recipientOutcome{RecipientEmail: "carol@earth.com"}); err != nil {
+			t.Fatalf("recordOutcome failed: %v", err)
+		}
+	}
+	st.close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Errorf("Expected 3 journal records, got %d:\n%s", n, data)
+	}
+	restored, err := newDeliveryReportStore(path)
+	if err != nil {
+		t.Fatalf("newDeliveryReportStore failed to restore: %v", err)
+	}
+	defer restored.close()
+	if got := restored.get("m1").GetResults(); len(got) != 2 || !got[0].GetSuccess() || got[1].GetRecipientEmail() != "carol@earth.com" {
+		t.Errorf("Expected both outcomes of m1 after restore, got %v", got)
+	}
+	if got := restored.get("m2").GetResults(); len(got) != 1 {
+		t.Errorf("Expected the outcome of m2 after restore, got %v", got)
+	}
+}
+
+// TestTransferServer_StateInstancePrefix tests that instances with different instance names
+// sharing one state directory write to distinct files and load independently.
+func TestTransferServer_StateInstancePrefix(t *testing.T) {
+	stateDir := t.TempDir()
+	mockNameserver := NewMockNameserverClient()
+	newInstance := func(name string) *server {
+		s, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{StateDir: stateDir, InstanceName: name})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig(%s) failed: %v", name, err)
+		}
+		return s
+	}
+
+	// Each instance records a (failed) send to an unregistered recipient
+	for _, name := range []string{"east", "west"} {
+		_, err := newInstance(name).SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			MessageId:      name + "-msg",
+			SenderEmail:    "bob@saturn.com",
+			RecipientEmail: "nobody@earth.com",
+		}})
+		if err != nil {
+			t.Fatalf("SendMail on '%s' failed: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"east", "west"} {
+		if _, err := os.Stat(common.StatePath(stateDir, name, deliveryReportsFile)); err != nil {
+			t.Errorf("Expected state file for instance '%s': %v", name, err)
+		}
+	}
+
+	for _, tc := range []struct{ instance, own, other string }{
+		{"east", "east-msg", "west-msg"},
+		{"west", "west-msg", "east-msg"},
+	} {
+		reloaded := newInstance(tc.instance)
+		if resp, _ := reloaded.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: tc.own}); !resp.GetFound() {
+			t.Errorf("Instance '%s' did not load its own report '%s'", tc.instance, tc.own)
+		}
+		if resp, _ := reloaded.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: tc.other}); resp.GetFound() {
+			t.Errorf("Instance '%s' unexpectedly loaded report '%s' of another instance", tc.instance, tc.other)
+		}
+	}
+}
+
+// TestTransferServer_SendMailBulk tests streaming a large recipient list to fast mock mailboxes.
+func TestTransferServer_SendMailBulk(t *testing.T) {
+	const numRecipients = 200
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	for i := 0; i < numRecipients; i++ {
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{
+			EmailAddress:   fmt.Sprintf("user%d@earth.com", i),
+			MailboxAddress: mailboxAddr,
+		})
+	}
+	client := startTestTransferServer(t, NewServer(mockNameserver))
+
+	stream, err := client.SendMailBulk(context.Background())
+	if err != nil {
+		t.Fatalf("SendMailBulk failed: %v", err)
+	}
+	msg := &proto.MailMessage{MessageId: "bulk-1", SenderEmail: "news@saturn.com", Subject: "Newsletter", Body: "Monthly news"}
+	if err := stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_Message{Message: msg}}); err != nil {
+		t.Fatalf("Sending message failed: %v", err)
+	}
+
+	// Stream the recipients while concurrently collecting results
+	go func() {
+		for i := 0; i < numRecipients; i++ {
+			recipient := &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: fmt.Sprintf("user%d@earth.com", i)}
+			if err := stream.Send(&proto.SendMailBulkRequest{Payload: recipient}); err != nil {
+				t.Errorf("Sending recipient %d failed: %v", i, err)
+				return
+			}
+		}
+		// Plus one unknown recipient, whose failure must be reported individually
+		unknown := &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: "ghost@earth.com"}
+		stream.Send(&proto.SendMailBulkRequest{Payload: unknown})
+		stream.CloseSend()
+	}()
+
+	results := make(map[string]bool)
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Receiving result failed: %v", err)
+		}
+		results[result.GetRecipientEmail()] = result.GetSuccess()
+	}
+
+	if len(results) != numRecipients+1 {
+		t.Fatalf("Expected %d results, got %d", numRecipients+1, len(results))
+	}
+	for recipient, success := range results {
+		if success == (recipient == "ghost@earth.com") {
+			t.Errorf("Recipient '%s': unexpected success=%v", recipient, success)
+		}
+	}
+	mockMailbox.mu.Lock()
+	if len(mockMailbox.receivedMessages) != numRecipients {
+		t.Errorf("Expected %d delivered messages, got %d", numRecipients, len(mockMailbox.receivedMessages))
+	}
+	mockMailbox.mu.Unlock()
+
+	t.Run("MissingMessage", func(t *testing.T) {
+		stream, err := client.SendMailBulk(context.Background())
+		if err != nil {
+			t.Fatalf("SendMailBulk failed: %v", err)
+		}
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: "user1@earth.com"}})
+		stream.CloseSend()
+		_, err = stream.Recv()
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument error when the message is missing, got %v", err)
+		}
+	})
+
+	t.Run("SecondMessage", func(t *testing.T) {
+		stream, err := client.SendMailBulk(context.Background())
+		if err != nil {
+			t.Fatalf("SendMailBulk failed: %v", err)
+		}
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_Message{Message: msg}})
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: "user1@earth.com"}})
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_Message{Message: &proto.MailMessage{Subject: "Other"}}})
+		stream.CloseSend()
+		for err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for a second message mid-stream, got %v", err)
+		}
+	})
+}
+
+// TestTransferServer_RewriteRules tests recipient plus-tag stripping and sender domain rewriting.
+func TestTransferServer_RewriteRules(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{RewriteRules: []common.RewriteRule{
+		{Apply: common.RewriteRecipient, StripPlusTag: true},
+		{Apply: common.RewriteSender, FromDomain: "saturn.com", ToDomain: "mail.saturn.com"},
+	}})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail:    "bob+work@saturn.com",
+		RecipientEmail: "alice+newsletter@earth.com",
+		Subject:        "Rewritten",
+	}})
+	if err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+	if !resp.GetSuccess() {
+		t.Fatalf("SendMail expected success after plus-tag stripping, got: %s", resp.GetMessage())
+	}
+
+	mockMailbox.mu.Lock()
+	defer mockMailbox.mu.Unlock()
+	if len(mockMailbox.receivedMessages) != 1 {
+		t.Fatalf("Expected 1 delivered message, got %d", len(mockMailbox.receivedMessages))
+	}
+	received := mockMailbox.receivedMessages[0]
+	if received.GetRecipientEmail() != "alice@earth.com" {
+		t.Errorf("Expected recipient 'alice@earth.com', got '%s'", received.GetRecipientEmail())
+	}
+	// The sender keeps its plus-tag since only the recipient rule strips tags
+	if received.GetSenderEmail() != "bob+work@mail.saturn.com" {
+		t.Errorf("Expected sender 'bob+work@mail.saturn.com', got '%s'", received.GetSenderEmail())
+	}
+
+	t.Run("InvalidRule", func(t *testing.T) {
+		_, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{RewriteRules: []common.RewriteRule{{Apply: "everyone"}}})
+		if err == nil {
+			t.Errorf("Expected an error for an unknown rewrite target")
+		}
+	})
+}
+
+// waitFor polls cond until it returns true or the timeout expires, reporting whether it became true.
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// receivedCount returns the number of messages the mock mailbox has stored.
+func (m *MockMailboxServer) receivedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.receivedMessages)
+}
+
+// TestTransferServer_PauseResumeDelivery tests that a paused queue accumulates mail and delivers it after resuming.
+func TestTransferServer_PauseResumeDelivery(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: true})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+	client := startTestTransferServer(t, transferServerService)
+
+	queueStatus, err := client.PauseDelivery(context.Background(), &proto.PauseDeliveryRequest{})
+	if err != nil {
+		t.Fatalf("PauseDelivery failed: %v", err)
+	}
+	if !queueStatus.GetPaused() {
+		t.Errorf("Expected queue to report paused")
+	}
+
+	var messageIDs []string
+	for i := 0; i < 3; i++ {
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail:    "bob@saturn.com",
+			RecipientEmail: "alice@earth.com",
+			Subject:        fmt.Sprintf("Queued %d", i),
+		}})
+		if err != nil {
+			t.Fatalf("SendMail failed: %v", err)
+		}
+		if !resp.GetSuccess() || resp.GetMessageId() == "" {
+			t.Fatalf("Expected mail to be queued with a message id, got %v", resp)
+		}
+		messageIDs = append(messageIDs, resp.GetMessageId())
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if n := mockMailbox.receivedCount(); n != 0 {
+		t.Errorf("Expected no deliveries while paused, got %d", n)
+	}
+	queueStatus, err = client.QueueStatus(context.Background(), &proto.QueueStatusRequest{})
+	if err != nil {
+		t.Fatalf("QueueStatus failed: %v", err)
+	}
+	if !queueStatus.GetPaused() || queueStatus.GetQueued() != 3 {
+		t.Errorf("Expected paused queue holding 3 messages, got %v", queueStatus)
+	}
+
+	if _, err := client.ResumeDelivery(context.Background(), &proto.ResumeDeliveryRequest{}); err != nil {
+		t.Fatalf("ResumeDelivery failed: %v", err)
+	}
+	if !waitFor(2*time.Second, func() bool { return mockMailbox.receivedCount() == 3 }) {
+		t.Fatalf("Expected 3 deliveries after resuming, got %d", mockMailbox.receivedCount())
+	}
+	for _, id := range messageIDs {
+		report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: id})
+		if err != nil || !report.GetFound() || len(report.GetResults()) != 1 || !report.GetResults()[0].GetSuccess() {
+			t.Errorf("Expected a successful delivery report for '%s', got %v (err %v)", id, report, err)
+		}
+	}
+
+	t.Run("PauseWithoutQueue", func(t *testing.T) {
+		_, err := NewServer(mockNameserver).PauseDelivery(context.Background(), &proto.PauseDeliveryRequest{})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition without async delivery, got %v", err)
+		}
+	})
+}
+
+// TestTransferServer_FlushQueue tests that flushing the queue delivers mail waiting on a long retry backoff immediately.
+func TestTransferServer_FlushQueue(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 1) // The first attempt fails, the mailbox is healthy afterwards
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: true})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+	client := startTestTransferServer(t, transferServerService)
+
+	_, err = client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Retry me",
+	}})
+	if err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+
+	// Wait for the failed first attempt, then push the scheduled retry far into the future
+	queue := transferServerService.queue
+	rescheduled := waitFor(2*time.Second, func() bool {
+		queue.mu.Lock()
+		defer queue.mu.Unlock()
+		if len(queue.pending) != 1 || queue.pending[0].attempts != 1 {
+			return false
+		}
+		queue.pending[0].nextAttempt = time.Now().Add(time.Hour)
+		return true
+	})
+	if !rescheduled {
+		t.Fatalf("Expected the failed delivery to be queued for retry")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := mockMailbox.receivedCount(); n != 0 {
+		t.Fatalf("Expected no delivery before flushing, got %d", n)
+	}
+
+	if _, err := client.FlushQueue(context.Background(), &proto.FlushQueueRequest{}); err != nil {
+		t.Fatalf("FlushQueue failed: %v", err)
+	}
+	if !waitFor(time.Second, func() bool { return mockMailbox.receivedCount() == 1 }) {
+		t.Fatalf("Expected immediate delivery after flushing, got %d deliveries", mockMailbox.receivedCount())
+	}
+
+	t.Run("FlushWithoutQueue", func(t *testing.T) {
+		_, err := NewServer(mockNameserver).FlushQueue(context.Background(), &proto.FlushQueueRequest{})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition without async delivery, got %v", err)
+		}
+	})
+}
+
+// FullMockMailboxServer is a MockMailboxServer whose CanAccept reports the inbox as over quota.
+type FullMockMailboxServer struct {
+	*MockMailboxServer
+	canAcceptCalls atomic.Int32
+}
+
+func (m *FullMockMailboxServer) CanAccept(ctx context.Context, req *proto.CanAcceptRequest) (*proto.CanAcceptResponse, error) {
+	m.canAcceptCalls.Add(1)
+	return &proto.CanAcceptResponse{Retryable: true, Reason: "inbox is over quota"}, nil
+}
+
+// TestTransferServer_PreDeliveryCheck tests that mail refused by CanAccept is deferred without sending the payload.
+func TestTransferServer_PreDeliveryCheck(t *testing.T) {
+	mockMailbox := &FullMockMailboxServer{MockMailboxServer: NewMockMailboxServer(0)}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mock mailbox: %v", err)
+	}
+	mailboxGrpc := grpc.NewServer()
+	proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+	go mailboxGrpc.Serve(lis)
+	t.Cleanup(mailboxGrpc.Stop)
+
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: true, PreDeliveryCheck: true})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+	client := startTestTransferServer(t, transferServerService)
+
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Large",
+		Attachments: []*proto.Attachment{{Filename: "big.bin", Data: make([]byte, 1<<16)}},
+	}})
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("Expected the mail to be queued, got %v (err %v)", resp, err)
+	}
+
+	if !waitFor(2*time.Second, func() bool { return mockMailbox.canAcceptCalls.Load() >= 2 }) {
+		t.Fatalf("Expected repeated CanAccept checks, got %d", mockMailbox.canAcceptCalls.Load())
+	}
+	if n := mockMailbox.receivedCount(); n != 0 {
+		t.Errorf("Expected no payload to be sent to an over-quota mailbox, got %d deliveries", n)
+	}
+	if n := atomic.LoadInt32(&mockMailbox.callCount); n != 0 {
+		t.Errorf("Expected ReceiveMail not to be called, got %d calls", n)
+	}
+	report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: resp.GetMessageId()})
+	if err != nil {
+		t.Fatalf("DeliveryReport failed: %v", err)
+	}
+	if len(report.GetResults()) != 0 {
+		t.Errorf("Expected the delivery to be deferred, not finished, got %v", report.GetResults())
+	}
+}
+
+// RejectingMockMailboxServer refuses every message as invalid, like a Mailbox enforcing a body size limit.
+type RejectingMockMailboxServer struct {
+	*MockMailboxServer
+}
+
+func (m *RejectingMockMailboxServer) ReceiveMail(ctx context.Context, req *proto.ReceiveMailRequest) (*proto.ReceiveMailResponse, error) {
+	atomic.AddInt32(&m.callCount, 1)
+	return nil, status.Errorf(codes.InvalidArgument, "body size 17 bytes exceeds the mailbox limit of 16 bytes")
+}
+
+// TestTransferServer_MailboxRejectsMessage tests that a message the Mailbox rejects as invalid fails at once
+// instead of being retried.
+func TestTransferServer_MailboxRejectsMessage(t *testing.T) {
+	mockMailbox := &RejectingMockMailboxServer{MockMailboxServer: NewMockMailboxServer(0)}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mock mailbox: %v", err)
+	}
+	mailboxGrpc := grpc.NewServer()
+	proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+	go mailboxGrpc.Serve(lis)
+	t.Cleanup(mailboxGrpc.Stop)
+
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+	client := startTestTransferServer(t, NewServer(mockNameserver))
+
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Body: strings.Repeat("x", 17),
+	}})
+	if err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+	if resp.GetSuccess() || !strings.Contains(resp.GetMessage(), "exceeds the mailbox limit") {
+		t.Errorf("Expected the mailbox's rejection to be reported, got %v", resp)
+	}
+	if n := atomic.LoadInt32(&mockMailbox.callCount); n != 1 {
+		t.Errorf("Expected exactly one delivery attempt, got %d", n)
+	}
+}
+
+// TestTransferServer_NormalizeRecipients tests that duplicate recipients across To and Cc are delivered once
+// while every original entry is reported.
+func TestTransferServer_NormalizeRecipients(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Async=%v", async), func(t *testing.T) {
+			mockNameserver := NewMockNameserverClient()
+			mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+			mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+			transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: async, NormalizeRecipients: true})
+			if err != nil {
+				t.Fatalf("NewServerWithConfig failed: %v", err)
+			}
+			t.Cleanup(transferServerService.Close)
+			client := startTestTransferServer(t, transferServerService)
+
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail:    "bob@saturn.com",
+				RecipientEmail: "alice@earth.com",
+				To:             []string{"Alice@Earth.com"},
+				Cc:             []string{"Alice <alice@earth.com>", "not-an-address"},
+				Subject:        "Dedupe",
+			}})
+			if err != nil {
+				t.Fatalf("SendMail failed: %v", err)
+			}
+
+			var report *proto.DeliveryReportResponse
+			waitFor(2*time.Second, func() bool { // Queued deliveries finish in the background
+				report, err = client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: resp.GetMessageId()})
+				return err == nil && len(report.GetResults()) == 4
+			})
+			if err != nil {
+				t.Fatalf("DeliveryReport failed: %v", err)
+			}
+			if n := mockMailbox.receivedCount(); n != 1 {
+				t.Errorf("Expected exactly one delivered copy, got %d", n)
+			}
+			got := make(map[string]bool)
+			for _, r := range report.GetResults() {
+				got[r.GetRecipientEmail()] = r.GetSuccess()
+			}
+			want := map[string]bool{"alice@earth.com": true, "Alice@Earth.com": true, "Alice <alice@earth.com>": true, "not-an-address": false}
+			if len(got) != len(want) || len(report.GetResults()) != len(want) {
+				t.Fatalf("Expected one result per original entry %v, got %v", want, report.GetResults())
+			}
+			for entry, success := range want {
+				if s, ok := got[entry]; !ok || s != success {
+					t.Errorf("Expected result for '%s' with success=%v, got %v (present %v)", entry, success, s, ok)
+				}
+			}
+		})
+	}
+}
+
+// TestTransferServer_DistributionList tests that mail to a list is delivered once to each member, including
+// members also addressed directly.
+func TestTransferServer_DistributionList(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	for _, member := range []string{"alice@earth.com", "carol@earth.com", "dave@earth.com"} {
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: member, MailboxAddress: mailboxAddr})
+	}
+	mockNameserver.RegisterList(context.Background(), &proto.RegisterListRequest{
+		ListAddress: "team@earth.com", MemberEmails: []string{"alice@earth.com", "carol@earth.com", "dave@earth.com"},
+	})
+	mockNameserver.RegisterList(context.Background(), &proto.RegisterListRequest{ListAddress: "empty@earth.com"})
+	client := startTestTransferServer(t, NewServer(mockNameserver))
+
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "team@earth.com", Cc: []string{"carol@earth.com"}, Subject: "Team update",
+	}})
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("Expected delivery to the list to succeed, got %v (err %v)", resp, err)
+	}
+	mockMailbox.mu.Lock()
+	got := make(map[string]int)
+	for _, msg := range mockMailbox.receivedMessages {
+		got[msg.GetRecipientEmail()]++
+	}
+	mockMailbox.mu.Unlock()
+	want := map[string]int{"alice@earth.com": 1, "carol@earth.com": 1, "dave@earth.com": 1}
+	if len(got) != len(want) {
+		t.Fatalf("Expected one copy per member %v, got %v", want, got)
+	}
+	for member, n := range want {
+		if got[member] != n {
+			t.Errorf("Expected %d copy for '%s', got %d", n, member, got[member])
+		}
+	}
+	mockNameserver.mu.RLock()
+	if mockNameserver.expandCalls != 1 {
+		t.Errorf("Expected the recipients to be expanded in 1 Nameserver call, got %d", mockNameserver.expandCalls)
+	}
+	mockNameserver.mu.RUnlock()
+
+	t.Run("Bulk", func(t *testing.T) {
+		mockMailbox.mu.Lock()
+		mockMailbox.receivedMessages = nil
+		mockMailbox.mu.Unlock()
+		stream, err := client.SendMailBulk(context.Background())
+		if err != nil {
+			t.Fatalf("SendMailBulk failed: %v", err)
+		}
+		msg := &proto.MailMessage{SenderEmail: "bob@saturn.com", Subject: "Team update"}
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_Message{Message: msg}})
+		for _, recipient := range []string{"carol@earth.com", "team@earth.com"} {
+			stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: recipient}})
+		}
+		stream.CloseSend()
+		results := make(map[string]bool)
+		for {
+			result, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Receiving result failed: %v", err)
+			}
+			results[result.GetRecipientEmail()] = result.GetSuccess()
+		}
+		if len(results) != len(want) || !results["alice@earth.com"] || !results["carol@earth.com"] || !results["dave@earth.com"] {
+			t.Errorf("Expected one successful result per member, got %v", results)
+		}
+		mockMailbox.mu.Lock()
+		if n := len(mockMailbox.receivedMessages); n != len(want) {
+			t.Errorf("Expected %d delivered copies, got %d", len(want), n)
+		}
+		mockMailbox.mu.Unlock()
+	})
+
+	t.Run("EmptyList", func(t *testing.T) {
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "empty@earth.com", Subject: "Nobody",
+		}})
+		if err == nil && resp.GetSuccess() {
+			t.Errorf("Expected delivery to a list without members to fail, got %v", resp)
+		}
+	})
+}
+
+// compressionRecorder is a stats.Handler recording the compression of every incoming RPC.
+type compressionRecorder struct {
+	mu          sync.Mutex
+	compression []string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		r.mu.Lock()
+		r.compression = append(r.compression, h.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+// TestTransferServer_CompressionThreshold tests that small deliveries are sent uncompressed and large ones gzip-compressed.
+func TestTransferServer_CompressionThreshold(t *testing.T) {
+	recorder := &compressionRecorder{}
+	mockMailbox := NewMockMailboxServer(0)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mock mailbox: %v", err)
+	}
+	mailboxGrpc := grpc.NewServer(grpc.StatsHandler(recorder))
+	proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+	go mailboxGrpc.Serve(lis)
+	t.Cleanup(mailboxGrpc.Stop)
+
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Compression: true, CompressionMinBytes: 4096})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantComp string
+	}{
+		{"SmallUncompressed", "Hi Alice", ""},
+		{"LargeCompressed", strings.Repeat("A long report line. ", 1000), "gzip"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.mu.Lock()
+			recorder.compression = nil
+			recorder.mu.Unlock()
+
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: tc.name, Body: tc.body,
+			}})
+			if err != nil || !resp.GetSuccess() {
+				t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+			}
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			if len(recorder.compression) != 1 || recorder.compression[0] != tc.wantComp {
+				t.Errorf("Expected one delivery with compression %q, got %q", tc.wantComp, recorder.compression)
+			}
+		})
+	}
+}
+
+// TestTransferServer_ShutdownWithQueuedMail tests that queued mail is delivered on shutdown without persistence
+// and survives a restart with persistence.
+func TestTransferServer_ShutdownWithQueuedMail(t *testing.T) {
+	queueMail := func(t *testing.T, cfg common.TransferServerConfig, mailboxAddr string, n int) (*server, []string) {
+		t.Helper()
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, cfg)
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		transferServerService.queue.pause() // Keep the mail queued until shutdown
+		var ids []string
+		for i := 0; i < n; i++ {
+			resp, err := transferServerService.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: fmt.Sprintf("Queued %d", i),
+			}})
+			if err != nil || !resp.GetSuccess() {
+				t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+			}
+			ids = append(ids, resp.GetMessageId())
+		}
+		return transferServerService, ids
+	}
+
+	t.Run("DrainWithoutPersistence", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+		transferServerService, ids := queueMail(t, common.TransferServerConfig{AsyncDelivery: true}, mailboxAddr, 3)
+		transferServerService.Close()
+
+		if n := mockMailbox.receivedCount(); n != 3 {
+			t.Errorf("Expected all 3 queued messages to be delivered on shutdown, got %d", n)
+		}
+		for _, id := range ids {
+			report, err := transferServerService.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: id})
+			if err != nil || len(report.GetResults()) != 1 || !report.GetResults()[0].GetSuccess() {
+				t.Errorf("Expected a successful delivery report for '%s', got %v (err %v)", id, report, err)
+			}
+		}
+	})
+
+	t.Run("PersistAndRestart", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+		cfg := common.TransferServerConfig{AsyncDelivery: true, StateDir: t.TempDir()}
+		transferServerService, _ := queueMail(t, cfg, mailboxAddr, 2)
+		transferServerService.Close()
+		if n := mockMailbox.receivedCount(); n != 0 {
+			t.Fatalf("Expected persisted mail not to be delivered on shutdown, got %d deliveries", n)
+		}
+
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		restarted, err := NewServerWithConfig(mockNameserver, cfg)
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed after restart: %v", err)
+		}
+		t.Cleanup(restarted.Close)
+		if !waitFor(2*time.Second, func() bool { return mockMailbox.receivedCount() == 2 }) {
+			t.Errorf("Expected the 2 persisted messages to be delivered after restart, got %d", mockMailbox.receivedCount())
+		}
+	})
+}
+
+// TestTransferServer_SelfDeliveryGuard tests that mail resolved to the TransferServer's own address is refused.
+func TestTransferServer_SelfDeliveryGuard(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "loop@earth.com", MailboxAddress: "127.0.0.1:50053"})
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{SelfAddr: "localhost:50053"})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	_, err = client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail:    "bob@saturn.com",
+		RecipientEmail: "loop@earth.com",
+		Subject:        "Loop",
+	}})
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition error for a self-pointing mailbox, got %v", err)
+	}
+
+	t.Run("SameAddr", func(t *testing.T) {
+		tests := []struct {
+			a, b string
+			want bool
+		}{
+			{"localhost:50053", "127.0.0.1:50053", true},
+			{"[::1]:50053", "0.0.0.0:50053", true},
+			{"localhost:50053", "localhost:50054", false},
+			{"mail.earth.com:50053", "localhost:50053", false},
+		}
+		for _, tc := range tests {
+			if got := sameAddr(tc.a, tc.b); got != tc.want {
+				t.Errorf("sameAddr(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+		}
+	})
+}
+
+// TestTransferServer_LowDiskSpace tests that new mail is rejected while the state directory is low on
+// disk space, while delivery reports can still be read.
+func TestTransferServer_LowDiskSpace(t *testing.T) {
+	transferServerService, err := NewServerWithConfig(NewMockNameserverClient(), common.TransferServerConfig{
+		StateDir:         t.TempDir(),
+		MinFreeDiskBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	free := uint64(1 << 30)
+	transferServerService.freeDiskSpace = func(string) (uint64, error) { return free, nil }
+	client := startTestTransferServer(t, transferServerService)
+
+	send := func() error {
+		_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			MessageId: "disk-test", SenderEmail: "bob@saturn.com", RecipientEmail: "nobody@earth.com",
+		}})
+		return err
+	}
+	if err := send(); err != nil {
+		t.Fatalf("SendMail with enough disk space failed: %v", err)
+	}
+
+	free = 1024 // Below the configured minimum
+	err = send()
+	if s, ok := status.FromError(err); !ok || s.Code() != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted error on low disk space, got %v", err)
+	}
+	report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: "disk-test"})
+	if err != nil || !report.GetFound() {
+		t.Errorf("Expected delivery report to remain readable on low disk space, got %v (err %v)", report, err)
+	}
+}
+
+// unreachableNameserverClient is a MockNameserverClient whose lookups fail with Unavailable for selected addresses,
+// simulating a Nameserver outage during sender verification.
+type unreachableNameserverClient struct {
+	*MockNameserverClient
+	unreachable map[string]bool
+}
+
+func (m *unreachableNameserverClient) LookupMailbox(ctx context.Context, in *proto.LookupMailboxRequest, opts ...grpc.CallOption) (*proto.LookupMailboxResponse, error) {
+	if m.unreachable[in.GetEmailAddress()] {
+		return nil, status.Errorf(codes.Unavailable, "nameserver unreachable")
+	}
+	return m.MockNameserverClient.LookupMailbox(ctx, in, opts...)
+}
+
+// TestTransferServer_SenderVerification tests sender verification and the fail-open/fail-closed policies
+// applied when the Nameserver cannot be reached, which do not admit a sender of an unrouted domain.
+func TestTransferServer_SenderVerification(t *testing.T) {
+	mockNameserver := &unreachableNameserverClient{
+		MockNameserverClient: NewMockNameserverClient(),
+		unreachable:          map[string]bool{"carol@saturn.com": true},
+	}
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "bob@saturn.com", MailboxAddress: mailboxAddr})
+
+	send := func(client proto.TransferServerClient, sender string) error {
+		_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: sender, RecipientEmail: "alice@earth.com", Subject: "Verify me",
+		}})
+		return err
+	}
+	newClient := func(t *testing.T, policy string) proto.TransferServerClient {
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			VerifySenders:            true,
+			SenderVerificationPolicy: policy,
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		return startTestTransferServer(t, transferServerService)
+	}
+
+	t.Run("RegisteredAndUnregisteredSenders", func(t *testing.T) {
+		client := newClient(t, "")
+		if err := send(client, "bob@saturn.com"); err != nil {
+			t.Errorf("Expected mail from a registered sender to be accepted, got %v", err)
+		}
+		err := send(client, "mallory@saturn.com")
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+			t.Errorf("Expected PermissionDenied for an unregistered sender, got %v", err)
+		}
+	})
+
+	t.Run("FailClosed", func(t *testing.T) {
+		before := mockMailbox.receivedCount()
+		err := send(newClient(t, common.SenderVerificationFailClosed), "carol@saturn.com")
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.Unavailable {
+			t.Errorf("Expected Unavailable when the Nameserver is unreachable (fail-closed), got %v", err)
+		}
+		if mockMailbox.receivedCount() != before {
+			t.Errorf("Expected no delivery under fail-closed")
+		}
+	})
+
+	t.Run("FailOpen", func(t *testing.T) {
+		before := mockMailbox.receivedCount()
+		if err := send(newClient(t, common.SenderVerificationFailOpen), "carol@saturn.com"); err != nil {
+			t.Errorf("Expected mail to be accepted when the Nameserver is unreachable (fail-open), got %v", err)
+		}
+		if mockMailbox.receivedCount() != before+1 {
+			t.Errorf("Expected the mail to be delivered under fail-open")
+		}
+	})
+
+	t.Run("UnroutedDomainFailOpen", func(t *testing.T) {
+		mockNameserver.mu.Lock()
+		mockNameserver.managed = map[string]bool{"earth.com": true, "saturn.com": true}
+		mockNameserver.mu.Unlock()
+		t.Cleanup(func() {
+			mockNameserver.mu.Lock()
+			mockNameserver.managed = nil
+			mockNameserver.mu.Unlock()
+		})
+		before := mockMailbox.receivedCount()
+		err := send(newClient(t, common.SenderVerificationFailOpen), "bob@satrun.com")
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.PermissionDenied {
+			t.Errorf("Expected PermissionDenied for a sender of an unrouted domain even under fail-open, got %v", err)
+		}
+		if mockMailbox.receivedCount() != before {
+			t.Errorf("Expected no delivery for a sender of an unrouted domain")
+		}
+	})
+
+	t.Run("UnknownPolicy", func(t *testing.T) {
+		if _, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{SenderVerificationPolicy: "maybe"}); err == nil {
+			t.Errorf("Expected an error for an unknown sender verification policy")
+		}
+	})
+}
+
+// TestTransferServer_MessageSizeBudget tests that the total message budget is enforced even when
+// every part is within its individual limit, and that the stricter individual limit still applies.
+func TestTransferServer_MessageSizeBudget(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+		MaxSubjectBytes:    20,
+		MaxBodyBytes:       40,
+		MaxAttachmentBytes: 40,
+		MaxMessageBytes:    64,
+	})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	send := func(subject, body string, attachment []byte) error {
+		msg := &proto.MailMessage{SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: subject, Body: body}
+		if attachment != nil {
+			msg.Attachments = []*proto.Attachment{{Filename: "data.bin", Data: attachment}}
+		}
+		_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: msg})
+		return err
+	}
+
+	t.Run("WithinBudget", func(t *testing.T) {
+		if err := send("Hello", strings.Repeat("b", 20), make([]byte, 20)); err != nil {
+			t.Errorf("Expected message within budget to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("PartsPassTotalExceeds", func(t *testing.T) {
+		before := mockMailbox.receivedCount()
+		err := send(strings.Repeat("s", 20), strings.Repeat("b", 30), make([]byte, 30)) // 80 bytes total
+		s, ok := status.FromError(err)
+		if !ok || s.Code() != codes.InvalidArgument {
+			t.Fatalf("Expected InvalidArgument for a message over budget, got %v", err)
+		}
+		if !strings.Contains(s.Message(), "80 bytes") {
+			t.Errorf("Expected the error to name the total size, got '%s'", s.Message())
+		}
+		if mockMailbox.receivedCount() != before {
+			t.Errorf("Expected the oversized message not to be relayed")
+		}
+	})
+
+	t.Run("IndividualLimitStricter", func(t *testing.T) {
+		err := send("Hi", strings.Repeat("b", 41), nil) // 43 bytes total fits the budget, body does not
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument || !strings.Contains(s.Message(), "body size") {
+			t.Errorf("Expected InvalidArgument for the body limit, got %v", err)
+		}
+	})
+}
+
+// TestTransferServer_DailySenderQuota tests that a sender is capped per day, that sends without recipients
+// are not counted, that the cap resets when the (fake) clock advances a day, and that counters survive a restart.
+func TestTransferServer_DailySenderQuota(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	_, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+
+	cfg := common.TransferServerConfig{StateDir: t.TempDir(), DailySenderQuota: 2}
+	clock := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	newClient := func() proto.TransferServerClient {
+		transferServerService, err := NewServerWithConfig(mockNameserver, cfg)
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		transferServerService.now = func() time.Time { return clock }
+		return startTestTransferServer(t, transferServerService)
+	}
+	send := func(client proto.TransferServerClient, sender string) error {
+		_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: sender, RecipientEmail: "alice@earth.com", Subject: "Quota",
+		}})
+		return err
+	}
+	isExhausted := func(err error) bool {
+		s, ok := status.FromError(err)
+		return ok && s.Code() == codes.ResourceExhausted
+	}
+
+	client := newClient()
+	_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{SenderEmail: "bob@saturn.com", Subject: "Nobody"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for a send without recipients, got %v", err)
+	}
+	for _, recipients := range [][]string{nil, {""}} { // Neither do bulk sends without any recipient
+		stream, err := client.SendMailBulk(context.Background())
+		if err != nil {
+			t.Fatalf("SendMailBulk failed: %v", err)
+		}
+		stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_Message{Message: &proto.MailMessage{SenderEmail: "bob@saturn.com", Subject: "Nobody"}}})
+		for _, recipient := range recipients {
+			stream.Send(&proto.SendMailBulkRequest{Payload: &proto.SendMailBulkRequest_RecipientEmail{RecipientEmail: recipient}})
+		}
+		stream.CloseSend()
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Expected InvalidArgument for a bulk send without recipients %q, got %v", recipients, err)
+		}
+	}
+	for i := 0; i < 2; i++ { // The rejected sends did not count against the quota
+		if err := send(client, "bob@saturn.com"); err != nil {
+			t.Fatalf("SendMail %d within quota failed: %v", i+1, err)
+		}
+	}
+	if err := send(client, "bob@saturn.com"); !isExhausted(err) {
+		t.Errorf("Expected ResourceExhausted over the daily quota, got %v", err)
+	}
+	if err := send(client, "carol@saturn.com"); err != nil {
+		t.Errorf("Expected other senders to be unaffected, got %v", err)
+	}
+
+	// A restart on the same day keeps the counters
+	client = newClient()
+	if err := send(client, "bob@saturn.com"); !isExhausted(err) {
+		t.Errorf("Expected the quota to survive a restart, got %v", err)
+	}
+
+	clock = clock.Add(24 * time.Hour)
+	if err := send(client, "bob@saturn.com"); err != nil {
+		t.Errorf("Expected the quota to reset the next day, got %v", err)
+	}
+}
+
+// TestTransferServer_Info tests that Info reports the delivery mode and the delivery counters.
+func TestTransferServer_Info(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	_, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	client := startTestTransferServer(t, NewServer(mockNameserver))
+
+	_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Cc: []string{"nobody@earth.com"},
+	}})
+	if err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+
+	info, err := client.Info(context.Background(), &proto.TransferServerInfoRequest{})
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.GetAsyncDelivery() || info.GetMessagesAccepted() != 1 || info.GetDeliveriesSucceeded() != 1 || info.GetDeliveriesFailed() != 1 {
+		t.Errorf("Unexpected info: %v", info)
+	}
+}
+
+// TestTransferServer_Bounce tests that failed deliveries bounce to the sender, with the original message
+// echoed only when configured, and that bounces never bounce themselves.
+func TestTransferServer_Bounce(t *testing.T) {
+	original := &proto.MailMessage{
+		SenderEmail:    "bob@saturn.com",
+		RecipientEmail: "ghost@earth.com", // Not registered, delivery fails permanently
+		Subject:        "Lost letter",
+		Body:           "The original body text",
+	}
+
+	bounceFor := func(t *testing.T, includeOriginal bool) *proto.MailMessage {
+		t.Helper()
+		mockNameserver := NewMockNameserverClient()
+		senderMailbox, senderAddr := startMockMailbox(t, 0)
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "bob@saturn.com", MailboxAddress: senderAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			Bounces:               true,
+			BounceIncludeOriginal: includeOriginal,
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		client := startTestTransferServer(t, transferServerService)
+
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: protobuf.Clone(original).(*proto.MailMessage)})
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected delivery to fail, got %v (err %v)", resp, err)
+		}
+		transferServerService.Close() // Waits for the bounce
+		if senderMailbox.receivedCount() != 1 {
+			t.Fatalf("Expected one bounce in the sender's mailbox, got %d", senderMailbox.receivedCount())
+		}
+		bounce := senderMailbox.receivedMessages[0]
+		if bounce.GetSenderEmail() != "postmaster@saturn.com" || bounce.GetBounceForMessageId() != resp.GetMessageId() ||
+			!strings.Contains(bounce.GetBody(), "ghost@earth.com") {
+			t.Errorf("Unexpected bounce: %v", bounce)
+		}
+		return bounce
+	}
+
+	t.Run("Summary", func(t *testing.T) {
+		if bounce := bounceFor(t, false); strings.Contains(bounce.GetBody(), original.Body) {
+			t.Errorf("Expected a summary bounce without the original body, got:\n%s", bounce.GetBody())
+		}
+	})
+
+	t.Run("FullOriginal", func(t *testing.T) {
+		if bounce := bounceFor(t, true); !strings.Contains(bounce.GetBody(), original.Body) {
+			t.Errorf("Expected the bounce to contain the original body, got:\n%s", bounce.GetBody())
+		}
+	})
+
+	t.Run("Timestamp", func(t *testing.T) {
+		transferServerService := NewServer(NewMockNameserverClient())
+		t.Cleanup(transferServerService.Close)
+		clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		transferServerService.now = func() time.Time { return clock }
+		bounce := transferServerService.newBounce(original, recipientOutcome{RecipientEmail: original.RecipientEmail})
+		if bounce.GetTimestamp() != clock.Unix() {
+			t.Errorf("Expected the bounce to be timestamped by the server's clock (%d), got %d", clock.Unix(), bounce.GetTimestamp())
+		}
+	})
+
+	t.Run("NoBounceLoop", func(t *testing.T) {
+		mockNameserver := NewMockNameserverClient()
+		postmasterMailbox, postmasterAddr := startMockMailbox(t, 0)
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "postmaster@saturn.com", MailboxAddress: postmasterAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Bounces: true})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		client := startTestTransferServer(t, transferServerService)
+
+		// A failing bounce, and mail from an unresolvable sender, must not generate further bounces
+		for _, msg := range []*proto.MailMessage{
+			{SenderEmail: "postmaster@saturn.com", RecipientEmail: "ghost@earth.com", BounceForMessageId: "earlier"},
+			{SenderEmail: "nobody@nowhere.com", RecipientEmail: "ghost@earth.com"},
+		} {
+			if _, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: msg}); err != nil {
+				t.Fatalf("SendMail failed: %v", err)
+			}
+		}
+		transferServerService.Close()
+		if postmasterMailbox.receivedCount() != 0 {
+			t.Errorf("Expected no bounces to be generated, got %d", postmasterMailbox.receivedCount())
+		}
+	})
+
+	t.Run("SenderLookupFails", func(t *testing.T) {
+		mockNameserver := &unreachableNameserverClient{
+			MockNameserverClient: NewMockNameserverClient(),
+			unreachable:          map[string]bool{"bob@saturn.com": true},
+		}
+		senderMailbox, senderAddr := startMockMailbox(t, 0)
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "bob@saturn.com", MailboxAddress: senderAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Bounces: true})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		client := startTestTransferServer(t, transferServerService)
+
+		if _, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: protobuf.Clone(original).(*proto.MailMessage)}); err != nil {
+			t.Fatalf("SendMail failed: %v", err)
+		}
+		transferServerService.Close()
+		if n := atomic.LoadInt32(&senderMailbox.callCount); n != 0 {
+			t.Errorf("Expected no bounce to be attempted for a sender that cannot be resolved, got %d attempts", n)
+		}
+	})
+}
+
+// TestTransferServer_DeliveryWebhook tests that a delivery is posted to the webhook as an event, retrying a
+// failed post.
+func TestTransferServer_DeliveryWebhook(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		calls  int
+		events []deliveryEvent
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // The first call fails and must be retried
+			return
+		}
+		var event deliveryEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode delivery event: %v", err)
+		}
+		events = append(events, event)
+	}))
+	t.Cleanup(webhook.Close)
+
+	mockNameserver := NewMockNameserverClient()
+	_, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+		WebhookURL:          webhook.URL,
+		WebhookRetries:      2,
+		WebhookRetryBackoff: common.Duration(10 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+
+	before := time.Now().Unix()
+	resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Hook", Body: "Hi Alice",
+	}})
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+	}
+	transferServerService.Close() // Waits for the webhook
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(events) != 1 {
+		t.Fatalf("Expected one event after one retry, got %d calls and events %v", calls, events)
+	}
+	event := events[0]
+	if event.MessageID != resp.GetMessageId() || event.SenderEmail != "bob@saturn.com" ||
+		event.RecipientEmail != "alice@earth.com" || !event.Success || event.Timestamp < before {
+		t.Errorf("Unexpected delivery event: %+v", event)
+	}
+}
+
+// TestTransferServer_RecipientNotFoundRetry tests that the retry policy requires asynchronous delivery and keeps
+// mail for an unknown recipient queued until the recipient registers, failing it once the TTL expires.
+func TestTransferServer_RecipientNotFoundRetry(t *testing.T) {
+	t.Run("RequiresAsyncDelivery", func(t *testing.T) {
+		_, err := NewServerWithConfig(NewMockNameserverClient(), common.TransferServerConfig{RecipientNotFoundPolicy: common.RecipientNotFoundRetry})
+		if err == nil {
+			t.Error("Expected the retry policy without AsyncDelivery to be rejected")
+		}
+	})
+
+	newRetryingServer := func(t *testing.T, mockNameserver *MockNameserverClient, ttl time.Duration) proto.TransferServerClient {
+		t.Helper()
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			AsyncDelivery:                  true,
+			RecipientNotFoundPolicy:        common.RecipientNotFoundRetry,
+			RecipientNotFoundTTL:           common.Duration(ttl),
+			RecipientNotFoundRetryInterval: common.Duration(20 * time.Millisecond),
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		t.Cleanup(transferServerService.Close)
+		return startTestTransferServer(t, transferServerService)
+	}
+	send := func(t *testing.T, client proto.TransferServerClient) string {
+		t.Helper()
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "newhire@earth.com", Subject: "Welcome",
+		}})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+		}
+		return resp.GetMessageId()
+	}
+
+	t.Run("DeliveredAfterRegistration", func(t *testing.T) {
+		mockNameserver := NewMockNameserverClient()
+		mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+		client := newRetryingServer(t, mockNameserver, 5*time.Second)
+		messageID := send(t, client)
+
+		time.Sleep(100 * time.Millisecond) // Several re-resolutions while the recipient is unknown
+		report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: messageID})
+		if err == nil && len(report.GetResults()) > 0 {
+			t.Fatalf("Expected no final outcome before registration, got %v", report.GetResults())
+		}
+
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "newhire@earth.com", MailboxAddress: mailboxAddr})
+		if !waitFor(2*time.Second, func() bool { return mockMailbox.receivedCount() == 1 }) {
+			t.Fatalf("Expected the mail to be delivered after registration, got %d deliveries", mockMailbox.receivedCount())
+		}
+		waitFor(time.Second, func() bool {
+			report, err = client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: messageID})
+			return err == nil && len(report.GetResults()) == 1
+		})
+		if len(report.GetResults()) != 1 || !report.GetResults()[0].GetSuccess() {
+			t.Errorf("Expected a successful delivery report, got %v (err %v)", report, err)
+		}
+	})
+
+	t.Run("FailsAfterTTL", func(t *testing.T) {
+		client := newRetryingServer(t, NewMockNameserverClient(), 100*time.Millisecond)
+		messageID := send(t, client)
+
+		var report *proto.DeliveryReportResponse
+		var err error
+		waitFor(2*time.Second, func() bool {
+			report, err = client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: messageID})
+			return err == nil && len(report.GetResults()) == 1
+		})
+		if len(report.GetResults()) != 1 || report.GetResults()[0].GetSuccess() ||
+			!strings.Contains(report.GetResults()[0].GetMessage(), "not found") {
+			t.Errorf("Expected a not-found failure after the TTL, got %v (err %v)", report, err)
+		}
+	})
+}
+
+// referringNameserverClient is a MockNameserverClient that refers lookups of unknown addresses under the
+// domains in referrals to another Nameserver.
+type referringNameserverClient struct {
+	*MockNameserverClient
+	referrals map[string]string // domain -> Nameserver address
+}
+
+func (m *referringNameserverClient) LookupMailbox(ctx context.Context, in *proto.LookupMailboxRequest, opts ...grpc.CallOption) (*proto.LookupMailboxResponse, error) {
+	resp, err := m.MockNameserverClient.LookupMailbox(ctx, in, opts...)
+	if err == nil && !resp.GetFound() {
+		_, domain, _ := strings.Cut(in.GetEmailAddress(), "@")
+		resp.ReferralAddress = m.referrals[domain]
+	}
+	return resp, err
+}
+
+// referralNameserverServer serves the lookups of a referringNameserverClient over gRPC, acting as the
+// Nameserver a referral points to.
+type referralNameserverServer struct {
+	proto.UnimplementedNameserverServer
+	client *referringNameserverClient
+}
+
+func (s *referralNameserverServer) LookupMailbox(ctx context.Context, in *proto.LookupMailboxRequest) (*proto.LookupMailboxResponse, error) {
+	return s.client.LookupMailbox(ctx, in)
+}
+
+// TestTransferServer_NameserverReferral tests that SendMail follows a Nameserver referral to deliver mail
+// for a domain managed elsewhere, but stops after maxReferralHops referrals.
+func TestTransferServer_NameserverReferral(t *testing.T) {
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	startReferred := func(t *testing.T, ns *referringNameserverClient) string {
+		t.Helper()
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to listen for referred nameserver: %v", err)
+		}
+		s := grpc.NewServer()
+		proto.RegisterNameserverServer(s, &referralNameserverServer{client: ns})
+		go s.Serve(lis)
+		t.Cleanup(s.Stop)
+		return lis.Addr().String()
+	}
+	send := func(t *testing.T, ns proto.NameserverClient) *proto.SendMailResponse {
+		t.Helper()
+		client := startTestTransferServer(t, NewServer(ns))
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@earth.com", RecipientEmail: "zoe@mars.com", Subject: "Referred",
+		}})
+		if err != nil {
+			t.Fatalf("SendMail failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("FollowsReferral", func(t *testing.T) {
+		marsNameserver := &referringNameserverClient{MockNameserverClient: NewMockNameserverClient()}
+		marsNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "zoe@mars.com", MailboxAddress: mailboxAddr})
+		earthNameserver := &referringNameserverClient{
+			MockNameserverClient: NewMockNameserverClient(),
+			referrals:            map[string]string{"mars.com": startReferred(t, marsNameserver)},
+		}
+		if resp := send(t, earthNameserver); !resp.GetSuccess() {
+			t.Fatalf("Expected delivery through the referral, got %v", resp)
+		}
+		if mockMailbox.receivedCount() != 1 {
+			t.Errorf("Expected the mailbox to receive the mail, got %d messages", mockMailbox.receivedCount())
+		}
+	})
+
+	t.Run("HopLimit", func(t *testing.T) {
+		// Both Nameservers refer mars.com to each other
+		loop := &referringNameserverClient{MockNameserverClient: NewMockNameserverClient()}
+		loop.referrals = map[string]string{"mars.com": startReferred(t, loop)}
+		if resp := send(t, loop); resp.GetSuccess() {
+			t.Errorf("Expected delivery to fail once the hop limit is reached, got %v", resp)
+		}
+	})
+}
+
+// CapacityMockMailboxServer is a MockMailboxServer that advertises a fixed capacity in Info.
+type CapacityMockMailboxServer struct {
+	*MockMailboxServer
+	capacity, remaining int32
+}
+
+func (m *CapacityMockMailboxServer) Info(ctx context.Context, req *proto.MailboxInfoRequest) (*proto.MailboxInfoResponse, error) {
+	return &proto.MailboxInfoResponse{Capacity: m.capacity, RemainingCapacity: m.remaining}, nil
+}
+
+// TestTransferServer_ReplicaSelection tests that mail goes to the replica with the most remaining capacity, an
+// unlimited one before any limited one, and that unreachable replicas are skipped.
+func TestTransferServer_ReplicaSelection(t *testing.T) {
+	startReplica := func(t *testing.T, capacity, remaining int32) (*CapacityMockMailboxServer, string) {
+		t.Helper()
+		replica := &CapacityMockMailboxServer{MockMailboxServer: NewMockMailboxServer(0), capacity: capacity, remaining: remaining}
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to listen for mock mailbox: %v", err)
+		}
+		mailboxGrpc := grpc.NewServer()
+		proto.RegisterMailboxServer(mailboxGrpc, replica)
+		go mailboxGrpc.Serve(lis)
+		t.Cleanup(mailboxGrpc.Stop)
+		return replica, lis.Addr().String()
+	}
+	sendTo := func(t *testing.T, addrs ...string) {
+		t.Helper()
+		mockNameserver := NewMockNameserverClient()
+		for i, addr := range addrs {
+			mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: addr, Replica: i > 0})
+		}
+		client := startTestTransferServer(t, NewServer(mockNameserver))
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Replicated",
+		}})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+		}
+	}
+
+	t.Run("PrefersEmptierReplica", func(t *testing.T) {
+		full, fullAddr := startReplica(t, 100, 5)
+		empty, emptyAddr := startReplica(t, 100, 90)
+		sendTo(t, fullAddr, emptyAddr)
+		if full.receivedCount() != 0 || empty.receivedCount() != 1 {
+			t.Errorf("Expected delivery to the emptier replica, got %d (full) and %d (empty)", full.receivedCount(), empty.receivedCount())
+		}
+	})
+
+	t.Run("UnlimitedBeatsLimited", func(t *testing.T) {
+		limited, limitedAddr := startReplica(t, 100, 90)
+		unlimited, unlimitedAddr := startReplica(t, 0, 0)
+		sendTo(t, limitedAddr, unlimitedAddr)
+		if limited.receivedCount() != 0 || unlimited.receivedCount() != 1 {
+			t.Errorf("Expected delivery to the unlimited replica, got %d (limited) and %d (unlimited)", limited.receivedCount(), unlimited.receivedCount())
+		}
+	})
+
+	t.Run("SkipsUnreachableReplica", func(t *testing.T) {
+		primary, primaryAddr := startReplica(t, 100, 1)
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		deadAddr := lis.Addr().String()
+		lis.Close() // Nothing serves this replica anymore
+		sendTo(t, primaryAddr, deadAddr)
+		if primary.receivedCount() != 1 {
+			t.Errorf("Expected delivery to the reachable primary, got %d", primary.receivedCount())
+		}
+	})
+}
+
+// countingNameserverClient is a MockNameserverClient counting the lookups it answers.
+type countingNameserverClient struct {
+	*MockNameserverClient
+	lookups atomic.Int32
+}
+
+func (m *countingNameserverClient) LookupMailbox(ctx context.Context, in *proto.LookupMailboxRequest, opts ...grpc.CallOption) (*proto.LookupMailboxResponse, error) {
+	m.lookups.Add(1)
+	return m.MockNameserverClient.LookupMailbox(ctx, in, opts...)
+}
+
+// TestTransferServer_LookupCache tests that resolved mailbox addresses are reused until the TTL expires,
+// and that an unreachable cached address is looked up afresh.
+func TestTransferServer_LookupCache(t *testing.T) {
+	setup := func(t *testing.T, mailboxAddr string) (*countingNameserverClient, *server, proto.TransferServerClient) {
+		t.Helper()
+		mockNameserver := &countingNameserverClient{MockNameserverClient: NewMockNameserverClient()}
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{LookupCacheTTL: common.Duration(time.Minute)})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		return mockNameserver, transferServerService, startTestTransferServer(t, transferServerService)
+	}
+	send := func(t *testing.T, client proto.TransferServerClient) {
+		t.Helper()
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Cached",
+		}})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+		}
+	}
+
+	t.Run("HitAndExpiry", func(t *testing.T) {
+		_, mailboxAddr := startMockMailbox(t, 0)
+		mockNameserver, transferServerService, client := setup(t, mailboxAddr)
+		clock := time.Now()
+		transferServerService.now = func() time.Time { return clock }
+
+		send(t, client)
+		send(t, client)
+		if got := mockNameserver.lookups.Load(); got != 1 {
+			t.Errorf("Expected the second send to be served from the cache, got %d lookups", got)
+		}
+		clock = clock.Add(time.Minute)
+		send(t, client)
+		if got := mockNameserver.lookups.Load(); got != 2 {
+			t.Errorf("Expected a new lookup after the TTL expired, got %d lookups", got)
+		}
+	})
+
+	t.Run("StaleAddress", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to listen for mock mailbox: %v", err)
+		}
+		oldMailbox := grpc.NewServer()
+		proto.RegisterMailboxServer(oldMailbox, NewMockMailboxServer(0))
+		go oldMailbox.Serve(lis)
+		mockNameserver, _, client := setup(t, lis.Addr().String())
+		send(t, client)
+
+		// The user moves to another Mailbox and the old one goes away, while its address is still cached
+		oldMailbox.Stop()
+		newMailbox, newAddr := startMockMailbox(t, 0)
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: newAddr})
+		send(t, client)
+		if newMailbox.receivedCount() != 1 || mockNameserver.lookups.Load() != 2 {
+			t.Errorf("Expected a re-lookup and delivery to the new mailbox, got %d messages after %d lookups",
+				newMailbox.receivedCount(), mockNameserver.lookups.Load())
+		}
+	})
+}
+
+// TestTransferServer_Failover tests that delivery falls back to the recipient's next mailbox address when
+// the preferred one is down, both for synchronous and queued delivery.
+func TestTransferServer_Failover(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	deadAddr := lis.Addr().String()
+	lis.Close() // Nothing serves the primary mailbox anymore
+
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Async=%t", async), func(t *testing.T) {
+			backup, backupAddr := startMockMailbox(t, 0)
+			mockNameserver := NewMockNameserverClient()
+			mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: deadAddr})
+			mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: backupAddr, Replica: true})
+			transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: async})
+			if err != nil {
+				t.Fatalf("NewServerWithConfig failed: %v", err)
+			}
+			t.Cleanup(transferServerService.Close)
+			client := startTestTransferServer(t, transferServerService)
+
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Failover",
+			}})
+			if err != nil || !resp.GetSuccess() {
+				t.Fatalf("SendMail failed: %v (err %v)", resp, err)
+			}
+			if !waitFor(2*time.Second, func() bool { return backup.receivedCount() == 1 }) {
+				t.Errorf("Expected the backup mailbox to receive the mail, got %d messages", backup.receivedCount())
+			}
+		})
+	}
+}
+
+// healthNameserverClient is a MockNameserverClient answering Health with a fixed response or error.
+type healthNameserverClient struct {
+	*MockNameserverClient
+	resp *proto.NameserverHealthResponse
+	err  error
+}
+
+func (m *healthNameserverClient) Health(ctx context.Context, in *proto.NameserverHealthRequest, opts ...grpc.CallOption) (*proto.NameserverHealthResponse, error) {
+	return m.resp, m.err
+}
+
+// TestTransferServer_CheckNameserverHealth tests how the startup health check judges the Nameserver's answers.
+func TestTransferServer_CheckNameserverHealth(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *proto.NameserverHealthResponse
+		err  error
+		want bool
+	}{
+		{"Serving", &proto.NameserverHealthResponse{Serving: true, ManagedDomains: []string{"earth.com"}}, nil, true},
+		{"NotServing", &proto.NameserverHealthResponse{Detail: "disk full"}, nil, false},
+		{"Unreachable", nil, status.Errorf(codes.Unavailable, "connection refused"), false},
+		{"Unimplemented", nil, status.Errorf(codes.Unimplemented, "unknown method Health"), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &healthNameserverClient{MockNameserverClient: NewMockNameserverClient(), resp: tc.resp, err: tc.err}
+			if got := checkNameserverHealth(client); got != tc.want {
+				t.Errorf("checkNameserverHealth() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+// retries returns a pointer to n, for setting common.RetryPolicy.MaxRetries.
+func retries(n int) *int {
+	return &n
+}
+
+// TestTransferServer_RetryPolicy tests the defaults and validation of the retry policy, the backoff it
+// yields, and that SendMail honors a configured number of retries, including none.
+func TestTransferServer_RetryPolicy(t *testing.T) {
+	t.Run("DefaultBackoff", func(t *testing.T) {
+		policy, err := newRetryPolicy(common.RetryPolicy{})
+		if err != nil {
+			t.Fatalf("newRetryPolicy failed: %v", err)
+		}
+		want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+		for i, w := range want {
+			if got := policy.backoff(i + 1); got != w {
+				t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+			}
+		}
+		if policy.maxRetries != defaultMaxRetries {
+			t.Errorf("Expected %d retries by default, got %d", defaultMaxRetries, policy.maxRetries)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, cfg := range []common.RetryPolicy{
+			{MaxRetries: retries(-1)},
+			{InitialBackoff: common.Duration(-time.Second)},
+			{InitialBackoff: common.Duration(10 * time.Second), MaxBackoff: common.Duration(time.Second)},
+		} {
+			if _, err := NewServerWithConfig(NewMockNameserverClient(), common.TransferServerConfig{Retry: cfg}); err == nil {
+				t.Errorf("Expected an error for retry policy %+v", cfg)
+			}
+		}
+	})
+
+	t.Run("ConfiguredRetries", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 100) // Never succeeds
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: common.RetryPolicy{
+			MaxRetries: retries(1), InitialBackoff: common.Duration(10 * time.Millisecond), MaxBackoff: common.Duration(10 * time.Millisecond),
+		}})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		client := startTestTransferServer(t, transferServerService)
+		retriesBefore, failedBefore := testutil.ToFloat64(deliveryRetries), testutil.ToFloat64(mailFailed)
+
+		start := time.Now()
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Retried once",
+		}})
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected delivery to fail, got %v (err %v)", resp, err)
+		}
+		if got := testutil.ToFloat64(deliveryRetries) - retriesBefore; got < 1 {
+			t.Errorf("Expected the retry to be counted, got %v more retries", got)
+		}
+		if got := testutil.ToFloat64(mailFailed) - failedBefore; got < 1 {
+			t.Errorf("Expected the failed delivery to be counted, got %v more failures", got)
+		}
+		if mockMailbox.receivedCount() != 0 || atomic.LoadInt32(&mockMailbox.callCount) != 2 {
+			t.Errorf("Expected 2 delivery attempts, got %d", atomic.LoadInt32(&mockMailbox.callCount))
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the short configured backoff, delivery took %s", elapsed)
+		}
+	})
+
+	t.Run("NoRetries", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 100) // Never succeeds
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: common.RetryPolicy{MaxRetries: retries(0)}})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		client := startTestTransferServer(t, transferServerService)
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Not retried",
+		}})
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected delivery to fail, got %v (err %v)", resp, err)
+		}
+		if calls := atomic.LoadInt32(&mockMailbox.callCount); calls != 1 {
+			t.Errorf("Expected a single delivery attempt with retries disabled, got %d", calls)
+		}
+	})
+}
+
+// ScriptedMockMailboxServer answers every ReceiveMail with the same response or error.
+type ScriptedMockMailboxServer struct {
+	*MockMailboxServer
+	resp *proto.ReceiveMailResponse
+	err  error
+}
+
+func (m *ScriptedMockMailboxServer) ReceiveMail(ctx context.Context, req *proto.ReceiveMailRequest) (*proto.ReceiveMailResponse, error) {
+	atomic.AddInt32(&m.callCount, 1)
+	return m.resp, m.err
+}
+
+// TestTransferServer_RetryClassification tests that SendMail retries only transient failures and fails fast
+// on anything else, including a Mailbox reporting a full inbox.
+func TestTransferServer_RetryClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		resp      *proto.ReceiveMailResponse
+		err       error
+		wantCalls int32
+	}{
+		{"Unavailable", nil, status.Errorf(codes.Unavailable, "try later"), 4},
+		{"DeadlineExceeded", nil, status.Errorf(codes.DeadlineExceeded, "too slow"), 4},
+		{"ResourceExhausted", nil, status.Errorf(codes.ResourceExhausted, "busy"), 4},
+		{"PermissionDenied", nil, status.Errorf(codes.PermissionDenied, "sender is blocked"), 1},
+		{"Internal", nil, status.Errorf(codes.Internal, "failed to store mail"), 1},
+		{"TryAgain", &proto.ReceiveMailResponse{Message: "busy"}, nil, 4},
+		{"MailboxFull", &proto.ReceiveMailResponse{Message: "inbox is full", MailboxFull: true}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMailbox := &ScriptedMockMailboxServer{MockMailboxServer: NewMockMailboxServer(0), resp: tt.resp, err: tt.err}
+			lis, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("Failed to listen for mock mailbox: %v", err)
+			}
+			mailboxGrpc := grpc.NewServer()
+			proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+			go mailboxGrpc.Serve(lis)
+			t.Cleanup(mailboxGrpc.Stop)
+
+			mockNameserver := NewMockNameserverClient()
+			mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+			transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: common.RetryPolicy{
+				InitialBackoff: common.Duration(time.Millisecond), MaxBackoff: common.Duration(time.Millisecond),
+			}})
+			if err != nil {
+				t.Fatalf("NewServerWithConfig failed: %v", err)
+			}
+			client := startTestTransferServer(t, transferServerService)
+
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: tt.name,
+			}})
+			if err != nil || resp.GetSuccess() {
+				t.Fatalf("Expected delivery to fail, got %v (err %v)", resp, err)
+			}
+			if n := atomic.LoadInt32(&mockMailbox.callCount); n != tt.wantCalls {
+				t.Errorf("Expected %d delivery attempts, got %d", tt.wantCalls, n)
+			}
+		})
+	}
+}
+
+// countingMailboxServer wraps a Mailbox and counts its ReceiveMail calls.
+type countingMailboxServer struct {
+	proto.MailboxServer
+	callCount int32
+}
+
+func (m *countingMailboxServer) ReceiveMail(ctx context.Context, req *proto.ReceiveMailRequest) (*proto.ReceiveMailResponse, error) {
+	atomic.AddInt32(&m.callCount, 1)
+	return m.MailboxServer.ReceiveMail(ctx, req)
+}
+
+// TestTransferServer_RealMailboxFull tests that a delivery to a full inbox of a real Mailbox fails after a
+// single attempt instead of being retried.
+func TestTransferServer_RealMailboxFull(t *testing.T) {
+	mailboxService, err := mailbox.NewServerWithConfig(common.MailboxConfig{Domain: "earth.com", MaxMessagesPerUser: 1, AllowPasswordless: true})
+	if err != nil {
+		t.Fatalf("mailbox.NewServerWithConfig failed: %v", err)
+	}
+	counting := &countingMailboxServer{MailboxServer: mailboxService}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mailbox: %v", err)
+	}
+	mailboxGrpc := grpc.NewServer()
+	proto.RegisterMailboxServer(mailboxGrpc, counting)
+	go mailboxGrpc.Serve(lis)
+	t.Cleanup(mailboxGrpc.Stop)
+
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: common.RetryPolicy{
+		InitialBackoff: common.Duration(time.Millisecond), MaxBackoff: common.Duration(time.Millisecond),
+	}})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	client := startTestTransferServer(t, transferServerService)
+	send := func(subject string) *proto.SendMailResponse {
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: subject,
+		}})
+		if err != nil {
+			t.Fatalf("SendMail failed: %v", err)
+		}
+		return resp
+	}
+
+	if resp := send("First"); !resp.GetSuccess() {
+		t.Fatalf("Expected the first delivery to succeed, got %v", resp)
+	}
+	if resp := send("Second"); resp.GetSuccess() {
+		t.Fatalf("Expected the delivery to a full inbox to fail, got %v", resp)
+	}
+	if n := atomic.LoadInt32(&counting.callCount); n != 2 {
+		t.Errorf("Expected 2 delivery attempts in total (no retry for the full inbox), got %d", n)
+	}
+}
+
+// TestTransferServer_DeadLetters tests that deliveries whose retries were exhausted are dead-lettered, survive a
+// restart and can be re-driven by an operator or, within limits, in the background.
+func TestTransferServer_DeadLetters(t *testing.T) {
+	fastRetry := common.RetryPolicy{MaxRetries: retries(1), InitialBackoff: common.Duration(time.Millisecond), MaxBackoff: common.Duration(time.Millisecond)}
+	send := func(t *testing.T, client proto.TransferServerClient, subject string) {
+		t.Helper()
+		resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: subject,
+		}})
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected delivery to fail, got %v (err %v)", resp, err)
+		}
+	}
+
+	t.Run("RetryAfterRestart", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 2) // Both attempts of SendMail fail
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		cfg := common.TransferServerConfig{StateDir: t.TempDir(), Retry: fastRetry}
+		transferServerService, err := NewServerWithConfig(mockNameserver, cfg)
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		send(t, startTestTransferServer(t, transferServerService), "Dead letter")
+		transferServerService.Close()
+
+		// The dead letter is still there after a restart
+		transferServerService, err = NewServerWithConfig(mockNameserver, cfg)
+		if err != nil {
+			t.Fatalf("NewServerWithConfig after restart failed: %v", err)
+		}
+		t.Cleanup(transferServerService.Close)
+		client := startTestTransferServer(t, transferServerService)
+		list, err := client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+		if err != nil {
+			t.Fatalf("ListDeadLetters failed: %v", err)
+		}
+		if len(list.GetDeadLetters()) != 1 {
+			t.Fatalf("Expected 1 dead letter, got %v", list.GetDeadLetters())
+		}
+		letter := list.GetDeadLetters()[0]
+		if letter.GetRecipientEmail() != "alice@earth.com" || letter.GetMessage().GetSubject() != "Dead letter" || letter.GetLastError() == "" {
+			t.Errorf("Unexpected dead letter %v", letter)
+		}
+
+		resp, err := client.RetryDeadLetter(context.Background(), &proto.RetryDeadLetterRequest{Id: letter.GetId()})
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("Expected the retry to succeed, got %v (err %v)", resp, err)
+		}
+		if mockMailbox.receivedCount() != 1 {
+			t.Errorf("Expected the dead letter to be delivered, got %d deliveries", mockMailbox.receivedCount())
+		}
+		list, _ = client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+		if len(list.GetDeadLetters()) != 0 {
+			t.Errorf("Expected no dead letters after a successful retry, got %v", list.GetDeadLetters())
+		}
+		report, err := client.DeliveryReport(context.Background(), &proto.DeliveryReportRequest{MessageId: letter.GetMessage().GetMessageId()})
+		if err != nil {
+			t.Fatalf("DeliveryReport failed: %v", err)
+		}
+		if results := report.GetResults(); len(results) != 1 || results[0].GetRecipientEmail() != "alice@earth.com" || !results[0].GetSuccess() {
+			t.Errorf("Expected the re-drive to turn the recipient's failure into a single successful result, got %v", results)
+		}
+
+		_, err = client.RetryDeadLetter(context.Background(), &proto.RetryDeadLetterRequest{Id: letter.GetId()})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.NotFound {
+			t.Errorf("Expected NotFound for a removed dead letter, got %v", err)
+		}
+	})
+
+	// startScriptedMailbox serves a Mailbox for alice@earth.com that always fails with err.
+	startScriptedMailbox := func(t *testing.T, err error) (*ScriptedMockMailboxServer, *MockNameserverClient) {
+		t.Helper()
+		mockMailbox := &ScriptedMockMailboxServer{MockMailboxServer: NewMockMailboxServer(0), err: err}
+		lis, listenErr := net.Listen("tcp", "localhost:0")
+		if listenErr != nil {
+			t.Fatalf("Failed to listen for mock mailbox: %v", listenErr)
+		}
+		mailboxGrpc := grpc.NewServer()
+		proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+		go mailboxGrpc.Serve(lis)
+		t.Cleanup(mailboxGrpc.Stop)
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+		return mockMailbox, mockNameserver
+	}
+
+	t.Run("FailedRetryIsKept", func(t *testing.T) {
+		_, mockNameserver := startScriptedMailbox(t, status.Errorf(codes.Unavailable, "try later"))
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: fastRetry})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		t.Cleanup(transferServerService.Close)
+		client := startTestTransferServer(t, transferServerService)
+		send(t, client, "Unavailable")
+
+		list, _ := client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+		if len(list.GetDeadLetters()) != 1 {
+			t.Fatalf("Expected 1 dead letter, got %v", list.GetDeadLetters())
+		}
+		resp, err := client.RetryDeadLetter(context.Background(), &proto.RetryDeadLetterRequest{Id: list.GetDeadLetters()[0].GetId()})
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected the retry to fail, got %v (err %v)", resp, err)
+		}
+		if letter := resp.GetDeadLetter(); letter.GetAttempts() != 1 || letter.GetLastAttemptAt() == 0 || !strings.Contains(letter.GetLastError(), "try later") {
+			t.Errorf("Expected the failed attempt to be recorded, got %v", letter)
+		}
+	})
+
+	t.Run("PermanentFailureIsNotDeadLettered", func(t *testing.T) {
+		_, mockNameserver := startScriptedMailbox(t, status.Errorf(codes.PermissionDenied, "sender is blocked"))
+		client := startTestTransferServer(t, NewServer(mockNameserver))
+		send(t, client, "Blocked")
+
+		list, _ := client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+		if len(list.GetDeadLetters()) != 0 {
+			t.Errorf("Expected no dead letter for a permanent failure, got %v", list.GetDeadLetters())
+		}
+	})
+
+	t.Run("BackgroundRetryLimits", func(t *testing.T) {
+		for _, tt := range []struct {
+			name      string
+			cfg       common.TransferServerConfig
+			wantCalls int32
+		}{
+			{"MaxAttempts", common.TransferServerConfig{DeadLetterMaxAttempts: 2}, 2 + 2},
+			{"MaxAge", common.TransferServerConfig{DeadLetterMaxAge: common.Duration(time.Millisecond)}, 2},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				mockMailbox, mockNameserver := startScriptedMailbox(t, status.Errorf(codes.Unavailable, "try later"))
+				tt.cfg.Retry, tt.cfg.DeadLetterRetryInterval = fastRetry, common.Duration(5*time.Millisecond)
+				transferServerService, err := NewServerWithConfig(mockNameserver, tt.cfg)
+				if err != nil {
+					t.Fatalf("NewServerWithConfig failed: %v", err)
+				}
+				t.Cleanup(transferServerService.Close)
+				client := startTestTransferServer(t, transferServerService)
+				send(t, client, tt.name)
+
+				time.Sleep(100 * time.Millisecond) // Many intervals
+				if n := atomic.LoadInt32(&mockMailbox.callCount); n != tt.wantCalls {
+					t.Errorf("Expected %d delivery attempts in total, got %d", tt.wantCalls, n)
+				}
+				list, _ := client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+				if len(list.GetDeadLetters()) != 1 {
+					t.Errorf("Expected the dead letter to stay listed, got %v", list.GetDeadLetters())
+				}
+			})
+		}
+	})
+
+	t.Run("ServerClock", func(t *testing.T) {
+		clock := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC) // Far from the real time, so time.Now() would be noticed
+		transferServerService := NewServer(NewMockNameserverClient())
+		t.Cleanup(transferServerService.Close)
+		transferServerService.now = func() time.Time { return clock }
+		transferServerService.deadLetter(&proto.MailMessage{RecipientEmail: "alice@earth.com"}, "try later")
+
+		if due := transferServerService.deadLetters.due(clock.Add(time.Minute), 3, time.Hour); len(due) != 1 {
+			t.Fatalf("Expected the dead letter to be due within its maximum age, got %v", due)
+		}
+		if due := transferServerService.deadLetters.due(clock.Add(2*time.Hour), 3, time.Hour); len(due) != 0 {
+			t.Errorf("Expected the dead letter to be too old past its maximum age, got %v", due)
+		}
+		letter := transferServerService.deadLetters.list()[0]
+		if letter.GetDeadLetteredAt() != clock.Unix() {
+			t.Errorf("Expected the dead letter to be timestamped by the server's clock, got %d", letter.GetDeadLetteredAt())
+		}
+		clock = clock.Add(time.Minute)
+		if _, err := transferServerService.retryDeadLetter(context.Background(), letter.GetId()); err != nil {
+			t.Fatalf("retryDeadLetter failed: %v", err)
+		}
+		if letter := transferServerService.deadLetters.list()[0]; letter.GetLastAttemptAt() != clock.Unix() {
+			t.Errorf("Expected the re-drive attempt to be timestamped by the server's clock, got %d", letter.GetLastAttemptAt())
+		}
+	})
+
+	t.Run("CloseTwice", func(t *testing.T) {
+		transferServerService, err := NewServerWithConfig(NewMockNameserverClient(), common.TransferServerConfig{
+			StateDir: t.TempDir(), DeadLetterRetryInterval: common.Duration(time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		transferServerService.Close()
+		transferServerService.Close() // Must not panic
+	})
+
+	t.Run("BackgroundRetry", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 2)
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			Retry: fastRetry, DeadLetterRetryInterval: common.Duration(20 * time.Millisecond),
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		t.Cleanup(transferServerService.Close)
+		client := startTestTransferServer(t, transferServerService)
+		send(t, client, "Retried in the background")
+
+		if !waitFor(2*time.Second, func() bool { return mockMailbox.receivedCount() == 1 }) {
+			t.Fatalf("Expected the dead letter to be delivered in the background")
+		}
+		if !waitFor(time.Second, func() bool {
+			list, _ := client.ListDeadLetters(context.Background(), &proto.ListDeadLettersRequest{})
+			return len(list.GetDeadLetters()) == 0
+		}) {
+			t.Errorf("Expected the delivered dead letter to be removed")
+		}
+	})
+}
+
+// TestTransferServer_DeliveryStatus tests choosing the delivery mode per request and tracking a send's
+// progress with GetDeliveryStatus.
+func TestTransferServer_DeliveryStatus(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockMailbox, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: true})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+	client := startTestTransferServer(t, transferServerService)
+
+	send := func(client proto.TransferServerClient, recipient string, async *bool) (*proto.SendMailResponse, error) {
+		return client.SendMail(context.Background(), &proto.SendMailRequest{Async: async, Message: &proto.MailMessage{
+			SenderEmail: "bob@saturn.com", RecipientEmail: recipient, Subject: "Tracked",
+		}})
+	}
+	deliveryStatus := func(client proto.TransferServerClient, trackingID string) *proto.GetDeliveryStatusResponse {
+		resp, err := client.GetDeliveryStatus(context.Background(), &proto.GetDeliveryStatusRequest{TrackingId: trackingID})
+		if err != nil {
+			t.Fatalf("GetDeliveryStatus failed: %v", err)
+		}
+		return resp
+	}
+	syncMode, asyncMode := false, true
+
+	t.Run("QueuedUntilDelivered", func(t *testing.T) {
+		client.PauseDelivery(context.Background(), &proto.PauseDeliveryRequest{})
+		resp, err := send(client, "alice@earth.com", &asyncMode)
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("Expected the mail to be queued, got %v (err %v)", resp, err)
+		}
+		if st := deliveryStatus(client, resp.GetMessageId()); st.GetStatus() != proto.DeliveryStatus_DELIVERY_STATUS_PENDING || st.GetPending() != 1 {
+			t.Errorf("Expected the queued mail to be pending, got %v", st)
+		}
+		client.ResumeDelivery(context.Background(), &proto.ResumeDeliveryRequest{})
+		if !waitFor(2*time.Second, func() bool {
+			return deliveryStatus(client, resp.GetMessageId()).GetStatus() == proto.DeliveryStatus_DELIVERY_STATUS_DELIVERED
+		}) {
+			t.Errorf("Expected the mail to be delivered, got %v", deliveryStatus(client, resp.GetMessageId()))
+		}
+	})
+
+	t.Run("SyncOnAsyncServer", func(t *testing.T) {
+		before := mockMailbox.receivedCount()
+		resp, err := send(client, "alice@earth.com", &syncMode)
+		if err != nil || !resp.GetSuccess() {
+			t.Fatalf("Expected synchronous delivery to succeed, got %v (err %v)", resp, err)
+		}
+		if mockMailbox.receivedCount() != before+1 {
+			t.Errorf("Expected the mail to be delivered before SendMail returned")
+		}
+		if st := deliveryStatus(client, resp.GetMessageId()); st.GetStatus() != proto.DeliveryStatus_DELIVERY_STATUS_DELIVERED || st.GetDelivered() != 1 {
+			t.Errorf("Expected the mail to be delivered, got %v", st)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		resp, err := send(client, "nobody@earth.com", &syncMode)
+		if err != nil || resp.GetSuccess() {
+			t.Fatalf("Expected delivery to an unknown recipient to fail, got %v (err %v)", resp, err)
+		}
+		if st := deliveryStatus(client, resp.GetMessageId()); st.GetStatus() != proto.DeliveryStatus_DELIVERY_STATUS_FAILED || st.GetFailed() != 1 {
+			t.Errorf("Expected the delivery to be reported as failed, got %v", st)
+		}
+	})
+
+	t.Run("AsyncWithoutQueue", func(t *testing.T) {
+		_, err := send(startTestTransferServer(t, NewServer(mockNameserver)), "alice@earth.com", &asyncMode)
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition without async delivery, got %v", err)
+		}
+	})
+
+	t.Run("UnknownAndEmpty", func(t *testing.T) {
+		if st := deliveryStatus(client, "no-such-message"); st.GetStatus() != proto.DeliveryStatus_DELIVERY_STATUS_UNKNOWN {
+			t.Errorf("Expected an unknown status, got %v", st)
+		}
+		_, err := client.GetDeliveryStatus(context.Background(), &proto.GetDeliveryStatusRequest{})
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an empty tracking id, got %v", err)
+		}
+	})
+}
+
+// countingListener counts the connections accepted by a net.Listener.
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+// TestTransferServer_ConnectionPool tests that deliveries to the same Mailbox share one connection, that
+// closed connections are replaced while failing ones stay open for their users, and that shutdown closes the pool.
+func TestTransferServer_ConnectionPool(t *testing.T) {
+	inner, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for mock mailbox: %v", err)
+	}
+	lis := &countingListener{Listener: inner}
+	mockMailbox := NewMockMailboxServer(0)
+	mailboxGrpc := grpc.NewServer()
+	proto.RegisterMailboxServer(mailboxGrpc, mockMailbox)
+	go mailboxGrpc.Serve(lis)
+	t.Cleanup(mailboxGrpc.Stop)
+
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: lis.Addr().String()})
+	transferServerService := NewServer(mockNameserver)
+	client := startTestTransferServer(t, transferServerService)
+
+	t.Run("Reuse", func(t *testing.T) {
+		var sends sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			sends.Add(1)
+			go func() {
+				defer sends.Done()
+				resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+					SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Pooled",
+				}})
+				if err != nil || !resp.GetSuccess() {
+					t.Errorf("SendMail failed: %v (err %v)", resp, err)
+				}
+			}()
+		}
+		sends.Wait()
+		if n := mockMailbox.receivedCount(); n != 5 {
+			t.Errorf("Expected 5 deliveries, got %d", n)
+		}
+		if n := lis.accepted.Load(); n != 1 {
+			t.Errorf("Expected all deliveries to share 1 connection, got %d", n)
+		}
+		if n := transferServerService.conns.size(); n != 1 {
+			t.Errorf("Expected 1 pooled connection, got %d", n)
+		}
+	})
+
+	t.Run("ReplaceBroken", func(t *testing.T) {
+		pool := newConnPool(grpc.WithInsecure())
+		t.Cleanup(pool.close)
+		first, err := pool.get(lis.Addr().String())
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		first.Close() // Broken from the pool's point of view
+		second, err := pool.get(lis.Addr().String())
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if second == first {
+			t.Errorf("Expected the closed connection to be replaced")
+		}
+	})
+
+	t.Run("KeepTransientFailure", func(t *testing.T) {
+		dead, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		deadAddr := dead.Addr().String()
+		dead.Close() // Nothing listens there any more, so connecting fails
+		pool := newConnPool(grpc.WithInsecure())
+		t.Cleanup(pool.close)
+		first, err := pool.get(deadAddr)
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		first.Connect()
+		for state := first.GetState(); state != connectivity.TransientFailure; state = first.GetState() {
+			if !first.WaitForStateChange(ctx, state) {
+				t.Fatalf("Expected the connection to fail, stuck in %s", state)
+			}
+		}
+		second, err := pool.get(deadAddr)
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if second != first || first.GetState() == connectivity.Shutdown {
+			t.Errorf("Expected the connection in transient failure to stay pooled and open for its other users")
+		}
+	})
+
+	t.Run("CloseOnShutdown", func(t *testing.T) {
+		transferServerService.Close()
+		if n := transferServerService.conns.size(); n != 0 {
+			t.Errorf("Expected no pooled connections after shutdown, got %d", n)
+		}
+		_, err := transferServerService.conns.get(lis.Addr().String())
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.Unavailable {
+			t.Errorf("Expected Unavailable after shutdown, got %v", err)
+		}
+	})
+}
+
+// TestTransferServer_SenderRateLimit tests that a sender exceeding its burst is throttled until its bucket
+// refills, without affecting other senders, and that idle buckets are dropped.
+func TestTransferServer_SenderRateLimit(t *testing.T) {
+	const burst = 3
+	mockNameserver := NewMockNameserverClient()
+	_, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{SenderRateLimit: 1, SenderRateBurst: burst})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var clockMu sync.Mutex
+	transferServerService.now = func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		return clock
+	}
+	advance := func(d time.Duration) {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		clock = clock.Add(d)
+	}
+	client := startTestTransferServer(t, transferServerService)
+	send := func(sender string) error {
+		_, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+			SenderEmail: sender, RecipientEmail: "alice@earth.com", Subject: "Rate",
+		}})
+		return err
+	}
+	isExhausted := func(err error) bool {
+		s, ok := status.FromError(err)
+		return ok && s.Code() == codes.ResourceExhausted
+	}
+
+	for i := 0; i < burst; i++ {
+		if err := send("bob@saturn.com"); err != nil {
+			t.Fatalf("SendMail %d within the burst failed: %v", i+1, err)
+		}
+	}
+	if err := send("bob@saturn.com"); !isExhausted(err) {
+		t.Fatalf("Expected ResourceExhausted beyond the burst, got %v", err)
+	}
+	if err := send("carol@saturn.com"); err != nil {
+		t.Errorf("Expected another sender not to be throttled, got %v", err)
+	}
+
+	advance(time.Second) // Refills one token
+	if err := send("bob@saturn.com"); err != nil {
+		t.Errorf("Expected a message to be allowed after the bucket refilled, got %v", err)
+	}
+	if err := send("bob@saturn.com"); !isExhausted(err) {
+		t.Errorf("Expected ResourceExhausted again, got %v", err)
+	}
+
+	t.Run("IdleBucketsDropped", func(t *testing.T) {
+		if n := transferServerService.rateLimiter.size(); n != 2 {
+			t.Fatalf("Expected 2 tracked senders, got %d", n)
+		}
+		advance(rateLimitSweepInterval)
+		if err := send("dave@saturn.com"); err != nil {
+			t.Fatalf("SendMail failed: %v", err)
+		}
+		if n := transferServerService.rateLimiter.size(); n != 1 {
+			t.Errorf("Expected only the active sender to be tracked after a sweep, got %d", n)
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		if _, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{SenderRateLimit: -1}); err == nil {
+			t.Errorf("Expected an error for a negative rate limit")
+		}
+	})
+}
+
+// TestTransferServer_CircuitBreaker tests that a mailbox failing repeatedly trips its circuit breaker, that
+// deliveries then fail fast, and that a probe after the cooldown closes it again.
+func TestTransferServer_CircuitBreaker(t *testing.T) {
+	t.Run("SendMail", func(t *testing.T) {
+		mockMailbox, mailboxAddr := startMockMailbox(t, 2) // Down for two attempts, healthy afterwards
+		mockNameserver := NewMockNameserverClient()
+		mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+		transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			CircuitBreakerThreshold: 2,
+			CircuitBreakerCooldown:  common.Duration(time.Minute),
+			Retry:                   common.RetryPolicy{InitialBackoff: common.Duration(time.Millisecond), MaxBackoff: common.Duration(time.Millisecond)},
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		var clockMu sync.Mutex
+		transferServerService.now = func() time.Time {
+			clockMu.Lock()
+			defer clockMu.Unlock()
+			return clock
+		}
+		client := startTestTransferServer(t, transferServerService)
+		send := func() *proto.SendMailResponse {
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Breaker",
+			}})
+			if err != nil {
+				t.Fatalf("SendMail failed: %v", err)
+			}
+			return resp
+		}
+
+		if resp := send(); resp.GetSuccess() || !strings.Contains(resp.GetMessage(), "circuit breaker") {
+			t.Errorf("Expected the delivery to stop once the breaker opened, got %v", resp)
+		}
+		if n := atomic.LoadInt32(&mockMailbox.callCount); n != 2 {
+			t.Errorf("Expected 2 attempts before the breaker opened, got %d", n)
+		}
+		if resp := send(); resp.GetSuccess() {
+			t.Errorf("Expected an open breaker to fail the delivery, got %v", resp)
+		}
+		if n := atomic.LoadInt32(&mockMailbox.callCount); n != 2 {
+			t.Errorf("Expected no attempt while the breaker is open, got %d", n)
+		}
+
+		clockMu.Lock()
+		clock = clock.Add(time.Minute)
+		clockMu.Unlock()
+		if resp := send(); !resp.GetSuccess() {
+			t.Errorf("Expected the probe after the cooldown to deliver, got %v", resp)
+		}
+		if resp := send(); !resp.GetSuccess() || mockMailbox.receivedCount() != 2 {
+			t.Errorf("Expected the breaker to be closed after a successful probe, got %v", resp)
+		}
+	})
+
+	t.Run("HalfOpen", func(t *testing.T) {
+		clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		breakers := newCircuitBreakers(1, time.Second, func() time.Time { return clock })
+		down := status.Errorf(codes.Unavailable, "mailbox down")
+		breakers.record("mb:1", down)
+		if breakers.allow("mb:1") == nil {
+			t.Fatalf("Expected the breaker to open after 1 failure")
+		}
+		if breakers.allow("mb:2") != nil {
+			t.Errorf("Expected other addresses not to be affected")
+		}
+
+		clock = clock.Add(time.Second)
+		if err := breakers.allow("mb:1"); err != nil {
+			t.Fatalf("Expected a probe after the cooldown, got %v", err)
+		}
+		if breakers.allow("mb:1") == nil {
+			t.Errorf("Expected only one probe at a time")
+		}
+		breakers.record("mb:1", down)
+		if breakers.allow("mb:1") == nil {
+			t.Errorf("Expected a failed probe to reopen the breaker")
+		}
+
+		clock = clock.Add(time.Second)
+		breakers.allow("mb:1")
+		breakers.record("mb:1", status.Errorf(codes.PermissionDenied, "sender is blocked")) // Reachable, if refusing
+		if err := breakers.allow("mb:1"); err != nil {
+			t.Errorf("Expected an answering mailbox to close the breaker, got %v", err)
+		}
+	})
+}
+
+// TestTransferServer_Tracing tests that a delivery continues the caller's trace in a span carrying the recipient
+// and the number of retries, and passes it on to the Mailbox's ReceiveMail.
+func TestTransferServer_Tracing(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	_, mailboxAddr := startMockMailbox(t, 1) // Fails once, so the delivery is retried
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{Retry: common.RetryPolicy{
+		MaxRetries: retries(2), InitialBackoff: common.Duration(10 * time.Millisecond), MaxBackoff: common.Duration(10 * time.Millisecond),
+	}})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+
+	ctx, parent := common.StartSpan(context.Background(), "Client.send")
+	resp, err := transferServerService.SendMail(ctx, &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Traced",
+	}})
+	parent.End()
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("SendMail failed: %v, %v", resp, err)
+	}
+
+	var deliverSpan sdktrace.ReadOnlySpan
+	var receiveSpans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.Name() == "TransferServer.deliver":
+			deliverSpan = span
+		case strings.HasSuffix(span.Name(), "/ReceiveMail"):
+			receiveSpans = append(receiveSpans, span)
+		}
+	}
+	if deliverSpan == nil {
+		t.Fatal("Expected a TransferServer.deliver span")
+	}
+	if deliverSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the delivery span to continue the caller's span")
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range deliverSpan.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs[common.AttrRecipient].AsString(); got != "alice@earth.com" {
+		t.Errorf("Expected the recipient attribute 'alice@earth.com', got %q", got)
+	}
+	if got := attrs[common.AttrRetries].AsInt64(); got != 1 {
+		t.Errorf("Expected the retries attribute to be 1, got %d", got)
+	}
+	if len(receiveSpans) != 2 {
+		t.Fatalf("Expected a ReceiveMail span for each of the 2 attempts, got %d", len(receiveSpans))
+	}
+	for _, span := range receiveSpans {
+		if span.Parent().SpanID() != deliverSpan.SpanContext().SpanID() {
+			t.Errorf("Expected ReceiveMail to be called within the delivery span")
+		}
+	}
+}
+
+// TestTransferServer_TracingQueued tests that a queued delivery continues the trace of the SendMail call that
+// queued it, although it is attempted after the call has returned.
+func TestTransferServer_TracingQueued(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, mailboxAddr := startMockMailbox(t, 0)
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.RegisterMailbox(context.Background(), &proto.RegisterMailboxRequest{EmailAddress: "alice@earth.com", MailboxAddress: mailboxAddr})
+	transferServerService, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{AsyncDelivery: true})
+	if err != nil {
+		t.Fatalf("NewServerWithConfig failed: %v", err)
+	}
+	t.Cleanup(transferServerService.Close)
+
+	ctx, parent := common.StartSpan(context.Background(), "Client.send")
+	resp, err := transferServerService.SendMail(ctx, &proto.SendMailRequest{Message: &proto.MailMessage{
+		SenderEmail: "bob@saturn.com", RecipientEmail: "alice@earth.com", Subject: "Queued",
+	}})
+	parent.End()
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("SendMail failed: %v, %v", resp, err)
+	}
+
+	var attemptSpan sdktrace.ReadOnlySpan
+	attempted := waitFor(2*time.Second, func() bool {
+		for _, span := range recorder.Ended() {
+			if span.Name() == "TransferServer.attemptDelivery" {
+				attemptSpan = span
+				return true
+			}
+		}
+		return false
+	})
+	if !attempted {
+		t.Fatal("Expected a TransferServer.attemptDelivery span for the queued delivery")
+	}
+	if attemptSpan.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("Expected the queued delivery to be part of the caller's trace")
+	}
+	if attemptSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the queued delivery span to continue the caller's span")
+	}
+}
+
+// TestServeTransferServer tests that a TransferServer started on port 0 is reachable at the address of its handle
+// until it is stopped.
+func TestServeTransferServer(t *testing.T) {
+	// No Nameserver is running at the address, the TransferServer only warns about it
+	handle, err := ServeTransferServer("localhost:1", "localhost:0", common.TransferServerConfig{})
+	if err != nil {
+		t.Fatalf("ServeTransferServer failed: %v", err)
+	}
+	defer handle.Stop()
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, handle.Addr(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Could not connect to the TransferServer: %v", err)
+	}
+	defer conn.Close()
+	if _, err := proto.NewTransferServerClient(conn).Info(dialCtx, &proto.TransferServerInfoRequest{}); err != nil {
+		t.Fatalf("Expected the TransferServer to be serving, got %v", err)
+	}
+
+	handle.Stop()
+	handle.Stop() // Stopping again is harmless
+	if _, err := proto.NewTransferServerClient(conn).Info(context.Background(), &proto.TransferServerInfoRequest{}); err == nil {
+		t.Error("Expected the stopped TransferServer to refuse requests")
+	}
+}
+
+// TestStartTransferServerWithContext tests that the TransferServer serves at the address passed to ready until
+// its context is cancelled, and then stops and returns without a signal.
+func TestStartTransferServerWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		// No Nameserver is running at the address, the TransferServer only warns about it
+		StartTransferServerWithContext(ctx, "localhost:1", "localhost:0", common.TransferServerConfig{}, func(addr string) { ready <- addr })
+	}()
+
+	var addr string
+	select {
+	case addr = <-ready:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the TransferServer to report readiness")
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Could not connect to the TransferServer: %v", err)
+	}
+	defer conn.Close()
+	// No retry is needed: the TransferServer is serving once ready has been called
+	if _, err := proto.NewTransferServerClient(conn).Info(ctx, &proto.TransferServerInfoRequest{}); err != nil {
+		t.Fatalf("Expected the ready TransferServer to answer, got %v", err)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the TransferServer to stop once its context was cancelled")
+	}
+	if _, err := proto.NewTransferServerClient(conn).Info(context.Background(), &proto.TransferServerInfoRequest{}); err == nil {
+		t.Error("Expected the stopped TransferServer to refuse requests")
+	}
+}
+
+// TestTransferServer_UnroutedDomain tests that mail to an unknown user of a managed domain and mail to a
+// domain no Nameserver manages (e.g. a typo) fail with distinct messages, and that the latter is not retried.
+func TestTransferServer_UnroutedDomain(t *testing.T) {
+	mockNameserver := NewMockNameserverClient()
+	mockNameserver.managed = map[string]bool{"earth.com": true}
+	client := startTestTransferServer(t, NewServer(mockNameserver))
+
+	tests := []struct {
+		name, recipient, want string
+	}{
+		{"UnknownUser", "nobody@earth.com", "Recipient 'nobody@earth.com' not found"},
+		{"UnroutedDomain", "alice@eatrh.com", "Recipient domain 'eatrh.com' of 'alice@eatrh.com' is not routed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.SendMail(context.Background(), &proto.SendMailRequest{Message: &proto.MailMessage{
+				SenderEmail: "bob@saturn.com", RecipientEmail: tc.recipient, Subject: "Hi",
+			}})
+			if err != nil {
+				t.Fatalf("SendMail failed: %v", err)
+			}
+			if resp.GetSuccess() || !strings.HasPrefix(resp.GetMessage(), tc.want) {
+				t.Errorf("Expected a failure starting with %q, got %v", tc.want, resp)
+			}
+		})
+	}
+
+	t.Run("PermanentInQueue", func(t *testing.T) {
+		s, err := NewServerWithConfig(mockNameserver, common.TransferServerConfig{
+			AsyncDelivery: true, RecipientNotFoundPolicy: common.RecipientNotFoundRetry,
+		})
+		if err != nil {
+			t.Fatalf("NewServerWithConfig failed: %v", err)
+		}
+		t.Cleanup(s.Close)
+		permanent, err := s.attemptDelivery(context.Background(), &proto.MailMessage{RecipientEmail: "alice@eatrh.com"}, 0)
+		if !permanent || !isUnroutedDomain(err) {
+			t.Errorf("Expected a permanent unrouted domain error, got %v (permanent %v)", err, permanent)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package transferserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhookRetries = 3                      // Retries after a failed webhook call when none are configured
+	defaultWebhookBackoff = 500 * time.Millisecond // Initial delay between webhook retries when none is configured
+	webhookTimeout        = 5 * time.Second        // Timeout of a single webhook call
+)
+
+// deliveryEvent is the JSON payload posted to the webhook for each final delivery outcome.
+type deliveryEvent struct {
+	MessageID      string `json:"MessageId"`
+	SenderEmail    string `json:"SenderEmail"`
+	RecipientEmail string `json:"RecipientEmail"`
+	Success        bool   `json:"Success"`
+	Message        string `json:"Message"`
+	Timestamp      int64  `json:"Timestamp"`
+}
+
+// webhookNotifier posts delivery events to an HTTP endpoint in the background, retrying failed calls
+// with exponential backoff.
+type webhookNotifier struct {
+	url     string
+	retries int
+	backoff time.Duration
+	client  *http.Client
+	sending sync.WaitGroup
+}
+
+// newWebhookNotifier returns a notifier for url, or nil if url is empty. Non-positive retries and backoff
+// take their defaults.
+func newWebhookNotifier(url string, retries int, backoff time.Duration) *webhookNotifier {
+	if url == "" {
+		return nil
+	}
+	if retries <= 0 {
+		retries = defaultWebhookRetries
+	}
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+	return &webhookNotifier{url: url, retries: retries, backoff: backoff, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// notify posts event in the background. It is a no-op on a nil notifier.
+func (w *webhookNotifier) notify(event deliveryEvent) {
+	if w == nil {
+		return
+	}
+	w.sending.Add(1)
+	go func() {
+		defer w.sending.Done()
+		if err := w.post(event); err != nil {
+			logger().Warn("Giving up on delivery webhook", "message_id", event.MessageID, "recipient", event.RecipientEmail, "error", err)
+		}
+	}()
+}
+
+// post sends event, retrying network errors and server errors. Client errors (4xx) are not retried.
+func (w *webhookNotifier) post(event deliveryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery event: %w", err)
+	}
+	backoff := w.backoff
+	var lastErr error
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode < 500:
+			return fmt.Errorf("webhook rejected the event: %s", resp.Status)
+		}
+		lastErr = fmt.Errorf("webhook failed: %s", resp.Status)
+	}
+	return fmt.Errorf("%d attempts failed, last error: %w", w.retries+1, lastErr)
+}
+
+// wait blocks until all pending notifications are sent or given up. It is a no-op on a nil notifier.
+func (w *webhookNotifier) wait() {
+	if w == nil {
+		return
+	}
+	w.sending.Wait()
+}